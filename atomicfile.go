@@ -0,0 +1,74 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// DefaultOutputMode is the default permissions of the binaries written to files
+const DefaultOutputMode os.FileMode = 0o755
+
+// AtomicFile is written to a temporary file in the directory of its path, which is renamed to the path
+// when committed, so the path never has a partially written file
+type AtomicFile struct {
+	tmp  *os.File
+	path string
+	mode os.FileMode
+	done bool
+}
+
+// CreateAtomicFile creates an AtomicFile that replaces the file at path with the given permissions
+// when committed
+func CreateAtomicFile(path string, mode os.FileMode) (*AtomicFile, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"*")
+	if err != nil {
+		return nil, err
+	}
+
+	return &AtomicFile{tmp: tmp, path: path, mode: mode}, nil
+}
+
+// Write writes to the temporary file
+func (f *AtomicFile) Write(p []byte) (int, error) {
+	return f.tmp.Write(p)
+}
+
+// Name returns the path the file is committed to
+func (f *AtomicFile) Name() string {
+	return f.path
+}
+
+// Commit closes the temporary file, sets its permissions and renames it to the path,
+// replacing any existing file
+func (f *AtomicFile) Commit() error {
+	if f.done {
+		return os.ErrClosed
+	}
+	f.done = true
+
+	err := f.tmp.Close()
+	if err == nil {
+		err = os.Chmod(f.tmp.Name(), f.mode)
+	}
+	if err == nil {
+		err = os.Rename(f.tmp.Name(), f.path)
+	}
+	if err != nil {
+		_ = os.Remove(f.tmp.Name())
+	}
+
+	return err
+}
+
+// Abort closes and removes the temporary file, leaving the path untouched.
+// Does nothing if the file was already committed
+func (f *AtomicFile) Abort() {
+	if f.done {
+		return
+	}
+	f.done = true
+
+	_ = f.tmp.Close()
+	_ = os.Remove(f.tmp.Name())
+}
@@ -0,0 +1,99 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestAtomicFile(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title  string
+		commit bool
+		expect string
+	}{
+		{
+			title:  "commit",
+			commit: true,
+			expect: "new",
+		},
+		{
+			title:  "abort",
+			expect: "previous",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "k6")
+			if err := os.WriteFile(path, []byte("previous"), 0o600); err != nil {
+				t.Fatalf("setting up test %v", err)
+			}
+
+			file, err := CreateAtomicFile(path, 0o750)
+			if err != nil {
+				t.Fatalf("creating file %v", err)
+			}
+
+			if _, err = file.Write([]byte("new")); err != nil {
+				t.Fatalf("writing file %v", err)
+			}
+
+			// the path is not modified until committed
+			content, err := os.ReadFile(path)
+			if err != nil || string(content) != "previous" {
+				t.Fatalf("expected previous content got %q %v", content, err)
+			}
+
+			if tc.commit {
+				if err = file.Commit(); err != nil {
+					t.Fatalf("committing file %v", err)
+				}
+			}
+			file.Abort()
+
+			content, err = os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("reading file %v", err)
+			}
+
+			if string(content) != tc.expect {
+				t.Fatalf("expected %q got %q", tc.expect, content)
+			}
+
+			entries, err := os.ReadDir(filepath.Dir(path))
+			if err != nil {
+				t.Fatalf("reading directory %v", err)
+			}
+
+			if len(entries) != 1 {
+				t.Fatalf("expected only the file got %d entries", len(entries))
+			}
+
+			if !tc.commit {
+				return
+			}
+
+			stat, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("reading file %v", err)
+			}
+
+			if runtime.GOOS != "windows" && stat.Mode().Perm() != 0o750 {
+				t.Fatalf("expected mode %v got %v", os.FileMode(0o750), stat.Mode().Perm())
+			}
+
+			if err = file.Commit(); !errors.Is(err, os.ErrClosed) {
+				t.Fatalf("expected %v got %v", os.ErrClosed, err)
+			}
+		})
+	}
+}
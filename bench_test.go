@@ -0,0 +1,158 @@
+package k6foundry
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+// phaseTimer accumulates the duration of the phases of the builds
+type phaseTimer struct {
+	mu        sync.Mutex
+	durations map[Phase]time.Duration
+}
+
+func (p *phaseTimer) ObservePhase(phase Phase, _ string, duration time.Duration, _ string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.durations[phase] += duration
+}
+
+func (p *phaseTimer) ObserveBinarySize(string, int64) {}
+
+func (p *phaseTimer) ObserveCacheLookup(bool) {}
+
+// report reports the mean duration of the resolve and compile phases per operation
+func (p *phaseTimer) report(b *testing.B) {
+	b.Helper()
+
+	for _, phase := range []Phase{PhaseResolve, PhaseCompile} {
+		b.ReportMetric(float64(p.durations[phase].Milliseconds())/float64(b.N), string(phase)+"-ms/op")
+	}
+}
+
+// benchmarkOpts returns the options for building with the go proxy, using temporary caches for cold
+// builds or the caches in a directory shared by all the builds otherwise
+func benchmarkOpts(b *testing.B, proxyURL string, cold bool, metrics Metrics) NativeBuilderOpts {
+	b.Helper()
+
+	opts := NativeBuilderOpts{
+		GoOpts: GoOpts{
+			CopyGoEnv: true,
+			Env: map[string]string{
+				"GOPROXY":   proxyURL,
+				"GONOPROXY": "none",
+				"GOPRIVATE": "go.k6.io",
+				"GONOSUMDB": "go.k6.io",
+			},
+			TmpCache: cold,
+		},
+		Metrics: metrics,
+	}
+
+	if !cold {
+		opts.GoCacheDir = b.TempDir()
+		b.Cleanup(func() { _ = removeAll(opts.GoCacheDir) })
+	}
+
+	return opts
+}
+
+func benchmarkProxy(b *testing.B) string {
+	b.Helper()
+
+	proxy := goproxy.NewGoProxy()
+	for _, m := range []struct{ path, version, source string }{
+		{"go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")},
+		{"go.k6.io/k6ext", "v0.1.0", filepath.Join("testdata", "mods", "k6ext")},
+	} {
+		if err := proxy.AddModVersion(m.path, m.version, m.source); err != nil {
+			b.Fatalf("setup %v", err)
+		}
+	}
+
+	srv := httptest.NewServer(proxy)
+	b.Cleanup(srv.Close)
+
+	return srv.URL
+}
+
+func BenchmarkBuild(b *testing.B) {
+	proxyURL := benchmarkProxy(b)
+	mods := []Module{{Path: "go.k6.io/k6ext", Version: "v0.1.0"}}
+
+	for _, cold := range []bool{true, false} {
+		name := "warm"
+		if cold {
+			name = "cold"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			timer := &phaseTimer{durations: map[Phase]time.Duration{}}
+			builder, err := NewNativeBuilder(context.Background(), benchmarkOpts(b, proxyURL, cold, timer))
+			if err != nil {
+				b.Fatalf("setup %v", err)
+			}
+
+			// populates the caches
+			if !cold {
+				if _, err = builder.Build(context.Background(), RuntimePlatform(), "v0.1.0", mods, nil, io.Discard); err != nil {
+					b.Fatalf("setup %v", err)
+				}
+				timer.durations = map[Phase]time.Duration{}
+			}
+
+			b.ResetTimer()
+			for range b.N {
+				if _, err = builder.Build(context.Background(), RuntimePlatform(), "v0.1.0", mods, nil, io.Discard); err != nil {
+					b.Fatalf("build %v", err)
+				}
+			}
+			b.StopTimer()
+
+			timer.report(b)
+		})
+	}
+}
+
+func BenchmarkResolve(b *testing.B) {
+	proxyURL := benchmarkProxy(b)
+	mods := []Module{{Path: "go.k6.io/k6ext", Version: "v0.1.0"}}
+
+	for _, cold := range []bool{true, false} {
+		name := "warm"
+		if cold {
+			name = "cold"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			builder, err := NewNativeBuilder(context.Background(), benchmarkOpts(b, proxyURL, cold, nil))
+			if err != nil {
+				b.Fatalf("setup %v", err)
+			}
+
+			resolver := builder.(Resolver) //nolint:forcetypeassert
+
+			// populates the module cache
+			if !cold {
+				if _, err = resolver.Resolve(context.Background(), "v0.1.0", mods); err != nil {
+					b.Fatalf("setup %v", err)
+				}
+			}
+
+			b.ResetTimer()
+			for range b.N {
+				if _, err = resolver.Resolve(context.Background(), "v0.1.0", mods); err != nil {
+					b.Fatalf("resolve %v", err)
+				}
+			}
+		})
+	}
+}
@@ -8,8 +8,21 @@ import (
 
 // BuildInfo describes the binary
 type BuildInfo struct {
-	Platform    string
-	ModVersions map[string]string
+	Platform    string            `json:"platform"`
+	ModVersions map[string]string `json:"modVersions"`
+	// all the modules included in the binary, including transitive dependencies.
+	// Only reported if requested in the builder options.
+	Dependencies []Dependency `json:"dependencies,omitempty"`
+	// checksum of the binary in the format sha256:<hex digest>.
+	// Only reported if requested in the builder options.
+	Checksum string `json:"checksum,omitempty"`
+	// size of the binary in bytes
+	Size int64 `json:"size,omitempty"`
+	// file name of the k6 binary for the platform (k6, or k6.exe for windows)
+	Executable string `json:"executable,omitempty"`
+	// bytes used by the build in the work directory and temporary caches when the binary was compiled,
+	// not including the shared caches
+	DiskUsage int64 `json:"diskUsage,omitempty"`
 }
 
 // Builder defines the interface for building a k6 binary
@@ -0,0 +1,192 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+
+	"golang.org/x/mod/semver"
+)
+
+// builderConfig describes the configuration of a builder that affects the binaries it builds
+// and their build info
+type builderConfig struct {
+	Builder            string
+	Image              string
+	Env                map[string]string
+	GoVersion          string
+	CC                 string
+	CXX                string
+	Zig                bool
+	Hermetic           bool
+	GoFlags            []string
+	BuildTags          []string
+	LDFlags            []string
+	GCFlags            []string
+	ASMFlags           []string
+	TrimPath           bool
+	Race               bool
+	PGOProfile         string
+	K6Repo             string
+	K6RepoVersion      string
+	Replacements       []Replace
+	Pins               []Module
+	Excludes           []Module
+	Main               string
+	StrictK6Version    bool
+	ListDependencies   bool
+	DetectLicenses     bool
+	DeniedLicenses     []string
+	Checksum           bool
+	Reproducible       bool
+	VerifyReproducible bool
+	StripDebugInfo     bool
+	CompressWithUPX    bool
+	BuildMetadata      map[string]string
+}
+
+// ConfigDigest returns a digest of the options of the builder that affect the binaries and their build
+// info, such as the build tags, the linker flags or the k6 repository. Caches of binaries include it in
+// their keys, so builders with different options don't share the binaries (see Cacheable).
+// With CopyGoEnv, the current go environment is included, as it is copied to the builds
+func (o NativeBuilderOpts) ConfigDigest() (string, error) {
+	env := map[string]string{}
+	if o.CopyGoEnv {
+		goBin, err := goBinary(o.GoOpts)
+		if err != nil {
+			return "", err
+		}
+
+		if env, err = getGoEnv(goBin); err != nil {
+			return "", fmt.Errorf("%w: %w", ErrSettingGoEnv, err)
+		}
+
+		// the workspace of the current directory doesn't apply to the builds, and the flags for gcc are
+		// computed by go, with a random temporary directory
+		delete(env, "GOWORK")
+		delete(env, "GOGCCFLAGS")
+	}
+
+	return o.configDigest("native", "", env)
+}
+
+// ConfigDigest returns a digest of the options of the builder, as NativeBuilderOpts.ConfigDigest,
+// including the image used for building
+func (o ContainerBuilderOpts) ConfigDigest() (string, error) {
+	image := o.Image
+	if image == "" {
+		image = DefaultContainerImage
+	}
+
+	// the go environment of the host is not used in the container
+	return o.configDigest("container", image, map[string]string{})
+}
+
+// Cacheable returns false if the builds depend on content that can change between builds with the same
+// options, so their binaries can't be cached: local modules (Workspace, local replacements or a local
+// k6 repository), branches or commits, the lock file, which is written or verified by the build, and
+// the post-build hooks
+func (o NativeBuilderOpts) Cacheable() bool {
+	if len(o.Workspace) > 0 || len(o.PostBuildHooks) > 0 || o.LockFile != "" {
+		return false
+	}
+
+	if o.K6Repo != "" && !semver.IsValid(o.K6RepoVersion) {
+		return false
+	}
+
+	for _, replace := range o.Replacements {
+		if !semver.IsValid(replace.ReplaceVersion) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (o NativeBuilderOpts) configDigest(builder string, image string, env map[string]string) (string, error) {
+	maps.Copy(env, o.Env)
+
+	// the profile is identified by its content, as it can change in the same path
+	var profile string
+	if o.PGOProfile != "" {
+		content, err := os.ReadFile(o.PGOProfile)
+		if err != nil {
+			return "", fmt.Errorf("%w: %w", ErrInvalidPGOProfile, err)
+		}
+		hash := sha256.Sum256(content)
+		profile = hex.EncodeToString(hash[:])
+	}
+
+	content, err := json.Marshal(builderConfig{
+		Builder:            builder,
+		Image:              image,
+		Env:                env,
+		GoVersion:          o.GoVersion,
+		CC:                 o.CC,
+		CXX:                o.CXX,
+		Zig:                o.Zig,
+		Hermetic:           o.Hermetic,
+		GoFlags:            o.GoFlags,
+		BuildTags:          o.BuildTags,
+		LDFlags:            o.LDFlags,
+		GCFlags:            o.GCFlags,
+		ASMFlags:           o.ASMFlags,
+		TrimPath:           o.TrimPath,
+		Race:               o.Race,
+		PGOProfile:         profile,
+		K6Repo:             o.K6Repo,
+		K6RepoVersion:      o.K6RepoVersion,
+		Replacements:       o.Replacements,
+		Pins:               o.Pins,
+		Excludes:           o.Excludes,
+		Main:               templateSource(o.MainTemplate),
+		StrictK6Version:    o.StrictK6Version,
+		ListDependencies:   o.ListDependencies,
+		DetectLicenses:     o.DetectLicenses,
+		DeniedLicenses:     o.DeniedLicenses,
+		Checksum:           o.Checksum,
+		Reproducible:       o.Reproducible,
+		VerifyReproducible: o.VerifyReproducible,
+		StripDebugInfo:     o.StripDebugInfo,
+		CompressWithUPX:    o.CompressWithUPX,
+		BuildMetadata:      o.BuildMetadata,
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshalling builder configuration %w", err)
+	}
+
+	hash := sha256.Sum256(content)
+
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// templateSource returns the source of the template and its associated templates. Empty if not set
+func templateSource(tmpl *template.Template) string {
+	if tmpl == nil {
+		return ""
+	}
+
+	templates := tmpl.Templates()
+	slices.SortFunc(templates, func(a, b *template.Template) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+
+	source := &strings.Builder{}
+	for _, t := range templates {
+		source.WriteString("{{define \"" + t.Name() + "\"}}")
+		if t.Tree != nil && t.Tree.Root != nil {
+			source.WriteString(t.Tree.Root.String())
+		}
+		source.WriteString("{{end}}")
+	}
+
+	return source.String()
+}
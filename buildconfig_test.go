@@ -0,0 +1,175 @@
+package k6foundry
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+)
+
+func TestConfigDigest(t *testing.T) {
+	t.Parallel()
+
+	native, err := NativeBuilderOpts{}.ConfigDigest()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	profile := filepath.Join(t.TempDir(), "default.pgo")
+	if err = os.WriteFile(profile, []byte("profile"), 0o600); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	release, _ := ApplyPreset(NativeBuilderOpts{}, PresetRelease)
+
+	testCases := []struct {
+		title  string
+		digest func() (string, error)
+		equal  bool
+	}{
+		{
+			title:  "same options",
+			digest: NativeBuilderOpts{Stdout: &bytes.Buffer{}, ConcurrentBuilds: 2}.ConfigDigest,
+			equal:  true,
+		},
+		{
+			title:  "k6 repository",
+			digest: NativeBuilderOpts{K6Repo: "github.com/my-org/k6", K6RepoVersion: "v0.1.0"}.ConfigDigest,
+		},
+		{
+			title:  "environment",
+			digest: NativeBuilderOpts{GoOpts: GoOpts{Env: map[string]string{"GOFLAGS": "-mod=mod"}}}.ConfigDigest,
+		},
+		{
+			title:  "container builder",
+			digest: ContainerBuilderOpts{}.ConfigDigest,
+		},
+		{
+			title:  "build tags",
+			digest: NativeBuilderOpts{GoOpts: GoOpts{BuildTags: []string{"netgo"}}}.ConfigDigest,
+		},
+		{
+			title:  "linker flags",
+			digest: NativeBuilderOpts{GoOpts: GoOpts{LDFlags: []string{"-s"}}}.ConfigDigest,
+		},
+		{
+			title:  "preset",
+			digest: release.ConfigDigest,
+		},
+		{
+			title:  "pgo profile",
+			digest: NativeBuilderOpts{GoOpts: GoOpts{PGOProfile: profile}}.ConfigDigest,
+		},
+		{
+			title: "main template",
+			digest: NativeBuilderOpts{
+				MainTemplate: template.Must(template.New("main").Parse("package main")),
+			}.ConfigDigest,
+		},
+		{
+			title:  "build metadata",
+			digest: NativeBuilderOpts{BuildMetadata: map[string]string{"main.Version": "v1"}}.ConfigDigest,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			digest, err := tc.digest()
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if (digest == native) != tc.equal {
+				t.Fatalf("expected equal %t got %s and %s", tc.equal, native, digest)
+			}
+		})
+	}
+}
+
+func TestConfigDigestGoEnv(t *testing.T) {
+	t.Parallel()
+
+	opts := NativeBuilderOpts{GoOpts: GoOpts{CopyGoEnv: true}}
+
+	first, err := opts.ConfigDigest()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	second, err := opts.ConfigDigest()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if first != second {
+		t.Fatalf("expected the same digest got %s and %s", first, second)
+	}
+}
+
+func TestConfigDigestPGOProfile(t *testing.T) {
+	t.Parallel()
+
+	profile := filepath.Join(t.TempDir(), "default.pgo")
+	opts := NativeBuilderOpts{GoOpts: GoOpts{PGOProfile: profile}}
+
+	digests := []string{}
+	for _, content := range []string{"profile", "updated profile"} {
+		if err := os.WriteFile(profile, []byte(content), 0o600); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+
+		digest, err := opts.ConfigDigest()
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		digests = append(digests, digest)
+	}
+
+	if digests[0] == digests[1] {
+		t.Fatal("expected different digests for different profiles")
+	}
+}
+
+func TestCacheable(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title  string
+		opts   NativeBuilderOpts
+		expect bool
+	}{
+		{title: "default", opts: NativeBuilderOpts{}, expect: true},
+		{title: "fork", opts: NativeBuilderOpts{K6Repo: "github.com/my-org/k6", K6RepoVersion: "v0.1.0"}, expect: true},
+		{title: "fork branch", opts: NativeBuilderOpts{K6Repo: "github.com/my-org/k6", K6RepoVersion: "main"}},
+		{title: "local k6", opts: NativeBuilderOpts{K6Repo: "../k6"}},
+		{title: "workspace", opts: NativeBuilderOpts{Workspace: []string{"../xk6-ext"}}},
+		{
+			title: "module replacement",
+			opts: NativeBuilderOpts{
+				Replacements: []Replace{{Path: "go.k6.io/k6ext", ReplacePath: "github.com/my-org/k6ext", ReplaceVersion: "v0.1.0"}},
+			},
+			expect: true,
+		},
+		{
+			title: "local replacement",
+			opts:  NativeBuilderOpts{Replacements: []Replace{{Path: "go.k6.io/k6ext", ReplacePath: "../k6ext"}}},
+		},
+		{title: "pins", opts: NativeBuilderOpts{Pins: []Module{{Path: "go.k6.io/k6ext", Version: "v0.1.0"}}}, expect: true},
+		{title: "lock file", opts: NativeBuilderOpts{LockFile: "k6foundry.lock"}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.opts.Cacheable(); got != tc.expect {
+				t.Fatalf("expected %t got %t", tc.expect, got)
+			}
+		})
+	}
+}
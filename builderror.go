@@ -0,0 +1,221 @@
+package k6foundry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// maximum number of lines of the output of go included in a BuildError
+	maxOutputExcerptLines = 20
+	// maximum size of the output of a go command captured for reporting its error
+	maxCapturedOutput = 64 << 10
+)
+
+// BuildError is returned when a build fails. It describes the failure for reporting it to users or
+// processing it programmatically: the phase that failed, the module responsible for the failure, an excerpt
+// of the output of go and a suggestion for fixing it.
+// The error it wraps can be checked with errors.Is and errors.As
+type BuildError struct {
+	// Phase that failed
+	Phase Phase `json:"phase"`
+	// Platform being built when the build failed. Empty for the phases that don't target a platform
+	Platform string `json:"platform,omitempty"`
+	// Module responsible for the failure, such as the extension that failed to compile. Empty if unknown
+	Module string `json:"module,omitempty"`
+	// Class of the error (see ErrorClass)
+	Class string `json:"class"`
+	// Output is an excerpt of the output of the go command that failed, if any
+	Output string `json:"output,omitempty"`
+	// Suggestion on how to fix the failure, if any
+	Suggestion string `json:"suggestion,omitempty"`
+	// Err is the error that caused the failure
+	Err error `json:"-"`
+}
+
+func (e *BuildError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *BuildError) Unwrap() error {
+	return e.Err
+}
+
+// suggestions maps errors to a suggestion for fixing them. The first match is used, so more specific
+// errors go first
+var suggestions = []struct { //nolint:gochecknoglobals
+	err        error
+	suggestion string
+}{
+	{context.Canceled, ""},
+	{ErrBuildTimeout, "increase the build timeout, or use a persistent go cache for faster builds"},
+	{context.DeadlineExceeded, "increase the timeout of the go commands or check the network connection"},
+	{ErrBusy, "retry later or increase the number of concurrent builds"},
+	{ErrInsufficientDiskSpace, "free disk space or prune the caches of the builds"},
+	{ErrNoGit, "install git from https://git-scm.com/downloads or use a go proxy for downloading the modules"},
+	{ErrModuleNotFound, "check the path of the module and the credentials for accessing it if it is private" +
+		" (GOPRIVATE, netrc or git credentials)"},
+	{ErrVersionNotFound, "check the version of the module is published, or use a commit or branch of its repository"},
+	{ErrUnknownRevision, "check the version of the module is published, or use a commit or branch of its repository"},
+	{ErrChecksumMismatch, "clean the module cache, and check the go proxy (GOPROXY) and checksum database" +
+		" (GOSUMDB) serve the published module"},
+	{ErrBuildConstraints, "the module doesn't support the target platform, build for another platform or" +
+		" remove the module"},
+	{ErrIncompatibleExtension, "use a newer k6 version or an older version of the extension"},
+	{ErrConflictingDependencies, "request each module only once"},
+	{ErrLockMismatch, "update the lock file with the versions resolved"},
+	{ErrIncompatibleGoVersion, "use a newer go version for building"},
+}
+
+// compileSuggestion is the suggestion for compile errors, which are usually caused by an extension that
+// doesn't support the requested version of k6 or go
+const compileSuggestion = "check the extension supports the k6 and go versions used, or use another version of" +
+	" the extension"
+
+// errorSuggestion returns a suggestion for fixing the error, or an empty string if there is none
+func errorSuggestion(err error) string {
+	var compileErr *CompileError
+	if errors.As(err, &compileErr) {
+		return compileSuggestion
+	}
+
+	for _, s := range suggestions {
+		if errors.Is(err, s.err) {
+			return s.suggestion
+		}
+	}
+
+	return ""
+}
+
+// buildError returns the error of a phase of a build as a BuildError, for the platform (empty if the phase
+// doesn't target a platform) and the module responsible for it, if known.
+// Errors that are already a BuildError are returned as they are
+func buildError(phase Phase, platform string, module string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var buildErr *BuildError
+	if errors.As(err, &buildErr) {
+		return err
+	}
+
+	var compileErr *CompileError
+	if module == "" && errors.As(err, &compileErr) {
+		module = compileErr.Module
+	}
+
+	output := ""
+	var outErr *outputError
+	if errors.As(err, &outErr) {
+		output = outputExcerpt(outErr.output)
+	}
+
+	return &BuildError{
+		Phase:      phase,
+		Platform:   platform,
+		Module:     module,
+		Class:      ErrorClass(err),
+		Output:     output,
+		Suggestion: errorSuggestion(err),
+		Err:        err,
+	}
+}
+
+// outputError attaches the output of a failed go command to its error, for including it in a BuildError
+type outputError struct {
+	err    error
+	output string
+}
+
+func (e *outputError) Error() string {
+	return e.err.Error()
+}
+
+func (e *outputError) Unwrap() error {
+	return e.err
+}
+
+// outputExcerpt returns the last lines of the output of a go command, without the progress messages
+func outputExcerpt(output string) string {
+	lines := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "go: downloading ") ||
+			strings.HasPrefix(line, "go: finding ") || strings.HasPrefix(line, "go: extracting ") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	if len(lines) > maxOutputExcerptLines {
+		lines = lines[len(lines)-maxOutputExcerptLines:]
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// tailBuffer keeps the last bytes written to it, up to its size. The errors of go are at the end of
+// its output, so the beginning of a long output (e.g. the progress of downloads) is discarded
+type tailBuffer struct {
+	size int
+	buf  []byte
+}
+
+func newTailBuffer(size int) *tailBuffer {
+	return &tailBuffer{size: size}
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if n >= b.size {
+		b.buf = append(b.buf[:0], p[n-b.size:]...)
+		return n, nil
+	}
+
+	if drop := len(b.buf) + n - b.size; drop > 0 {
+		b.buf = append(b.buf[:0], b.buf[drop:]...)
+	}
+	b.buf = append(b.buf, p...)
+
+	return n, nil
+}
+
+func (b *tailBuffer) String() string {
+	return string(b.buf)
+}
+
+// FormatBuildError formats the error for users, including the details of a BuildError if available
+func FormatBuildError(err error) string {
+	var buildErr *BuildError
+	if !errors.As(err, &buildErr) {
+		return err.Error()
+	}
+
+	out := &strings.Builder{}
+	fmt.Fprintf(out, "%s\n", err.Error())
+	fmt.Fprintf(out, "  phase: %s", buildErr.Phase)
+	if buildErr.Platform != "" {
+		fmt.Fprintf(out, " (%s)", buildErr.Platform)
+	}
+	fmt.Fprintln(out)
+
+	if buildErr.Module != "" {
+		fmt.Fprintf(out, "  module: %s\n", buildErr.Module)
+	}
+
+	if buildErr.Output != "" {
+		fmt.Fprintf(out, "  go output:\n")
+		for _, line := range strings.Split(buildErr.Output, "\n") {
+			fmt.Fprintf(out, "    %s\n", line)
+		}
+	}
+
+	if buildErr.Suggestion != "" {
+		fmt.Fprintf(out, "  suggestion: %s\n", buildErr.Suggestion)
+	}
+
+	return strings.TrimSuffix(out.String(), "\n")
+}
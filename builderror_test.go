@@ -0,0 +1,166 @@
+package k6foundry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestBuildError(t *testing.T) {
+	t.Parallel()
+
+	goOutput := "go: downloading go.k6.io/k6 v0.1.0\n" +
+		"go: go.k6.io/k6ext@v0.2.0: invalid version: unknown revision v0.2.0\n"
+
+	testCases := []struct {
+		title            string
+		module           string
+		err              error
+		expectModule     string
+		expectClass      string
+		expectOutput     string
+		expectSuggestion bool
+	}{
+		{
+			title:            "version not found",
+			module:           "go.k6.io/k6ext",
+			err:              &outputError{err: fmt.Errorf("%w: %w", ErrResolvingDependency, ErrVersionNotFound), output: goOutput},
+			expectModule:     "go.k6.io/k6ext",
+			expectClass:      "version_not_found",
+			expectOutput:     "go: go.k6.io/k6ext@v0.2.0: invalid version: unknown revision v0.2.0",
+			expectSuggestion: true,
+		},
+		{
+			title:            "compile error",
+			err:              fmt.Errorf("%w: %w", ErrCompiling, &CompileError{Module: "go.k6.io/k6ext", Package: "go.k6.io/k6ext"}),
+			expectModule:     "go.k6.io/k6ext",
+			expectClass:      "compile",
+			expectSuggestion: true,
+		},
+		{
+			title:       "canceled",
+			err:         context.Canceled,
+			expectClass: "canceled",
+		},
+		{
+			title:       "unknown error",
+			err:         errors.New("unknown"),
+			expectClass: "other",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			err := buildError(PhaseResolve, "", tc.module, tc.err)
+
+			var buildErr *BuildError
+			if !errors.As(err, &buildErr) {
+				t.Fatalf("expected build error got %v", err)
+			}
+
+			if !errors.Is(err, tc.err) || err.Error() != tc.err.Error() {
+				t.Fatalf("expected %v wrapped got %v", tc.err, err)
+			}
+
+			if buildErr.Phase != PhaseResolve || buildErr.Module != tc.expectModule || buildErr.Class != tc.expectClass {
+				t.Fatalf("unexpected build error %#v", buildErr)
+			}
+
+			if buildErr.Output != tc.expectOutput {
+				t.Fatalf("expected output %q got %q", tc.expectOutput, buildErr.Output)
+			}
+
+			if (buildErr.Suggestion != "") != tc.expectSuggestion {
+				t.Fatalf("unexpected suggestion %q", buildErr.Suggestion)
+			}
+
+			// the details are kept when the error is reported by an outer phase
+			if outer := buildError(PhaseBuild, "linux/amd64", "", err); outer != err { //nolint:errorlint
+				t.Fatalf("expected error not wrapped again got %v", outer)
+			}
+		})
+	}
+}
+
+func TestOutputExcerpt(t *testing.T) {
+	t.Parallel()
+
+	lines := []string{"go: downloading go.k6.io/k6 v0.1.0", ""}
+	for i := range maxOutputExcerptLines + 5 {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+
+	excerpt := strings.Split(outputExcerpt(strings.Join(lines, "\n")), "\n")
+	if len(excerpt) != maxOutputExcerptLines {
+		t.Fatalf("expected %d lines got %d", maxOutputExcerptLines, len(excerpt))
+	}
+
+	if excerpt[0] != "line 5" || excerpt[len(excerpt)-1] != fmt.Sprintf("line %d", maxOutputExcerptLines+4) {
+		t.Fatalf("expected the last lines got %v", excerpt)
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title  string
+		writes []string
+		expect string
+	}{
+		{title: "fits", writes: []string{"abc", "de"}, expect: "abcde"},
+		{title: "exceeds", writes: []string{"abcdef", "ghij", "kl"}, expect: "cdefghijkl"},
+		{title: "larger write", writes: []string{"abc", "0123456789xyz"}, expect: "3456789xyz"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			buf := newTailBuffer(10)
+			for _, w := range tc.writes {
+				if n, err := buf.Write([]byte(w)); err != nil || n != len(w) {
+					t.Fatalf("unexpected write %d %v", n, err)
+				}
+			}
+
+			if got := buf.String(); got != tc.expect {
+				t.Fatalf("expected %q got %q", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestFormatBuildError(t *testing.T) {
+	t.Parallel()
+
+	err := errors.New("plain error")
+	if formatted := FormatBuildError(err); formatted != err.Error() {
+		t.Fatalf("expected %q got %q", err.Error(), formatted)
+	}
+
+	err = &BuildError{
+		Phase:      PhaseCompile,
+		Platform:   "linux/amd64",
+		Module:     "go.k6.io/k6ext",
+		Output:     "k6ext.go:3:1: syntax error",
+		Suggestion: "fix it",
+		Err:        errors.New("compile error"),
+	}
+
+	expect := "compile error\n" +
+		"  phase: compile (linux/amd64)\n" +
+		"  module: go.k6.io/k6ext\n" +
+		"  go output:\n" +
+		"    k6ext.go:3:1: syntax error\n" +
+		"  suggestion: fix it"
+	if formatted := FormatBuildError(err); formatted != expect {
+		t.Fatalf("expected %q got %q", expect, formatted)
+	}
+}
@@ -0,0 +1,228 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// FileBuilder is implemented by builders that can write the binary directly to a file. Binaries are
+// moved from the work directory when possible, avoiding copying large binaries through an io.Writer.
+type FileBuilder interface {
+	// BuildToFile builds a custom k6 binary for the given version including a set of dependencies
+	// into the file at outPath, replacing it if it exists
+	BuildToFile(
+		ctx context.Context,
+		platform Platform,
+		k6Version string,
+		mods []Module,
+		buildOpts []string,
+		outPath string,
+	) (*BuildInfo, error)
+}
+
+// BuildToFile builds a custom k6 binary into the file at outPath using the builder. If the builder
+// doesn't implement FileBuilder, the binary is written using Build to a temporary file that replaces
+// the file with DefaultOutputMode permissions when the build succeeds.
+func BuildToFile(
+	ctx context.Context,
+	builder Builder,
+	platform Platform,
+	k6Version string,
+	mods []Module,
+	buildOpts []string,
+	outPath string,
+) (*BuildInfo, error) {
+	if fb, ok := builder.(FileBuilder); ok {
+		return fb.BuildToFile(ctx, platform, k6Version, mods, buildOpts, outPath)
+	}
+
+	out, err := CreateAtomicFile(outPath, DefaultOutputMode)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Abort()
+
+	info, err := builder.Build(ctx, platform, k6Version, mods, buildOpts, out)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = out.Commit(); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// BuildToFile builds a custom k6 binary into the file at outPath, moving it from the work directory
+func (b *nativeBuilder) BuildToFile(
+	ctx context.Context,
+	platform Platform,
+	k6Version string,
+	exts []Module,
+	buildOpts []string,
+	outPath string,
+) (*BuildInfo, error) {
+	return b.buildWith(ctx, b.hostEnv, platform, k6Version, exts, buildOpts, binaryOutput{path: outPath})
+}
+
+// binaryOutput is the destination of a binary: it is copied to the writer or, if path is set,
+// moved to the path
+type binaryOutput struct {
+	writer io.Writer
+	path   string
+}
+
+// progressInterval is the number of bytes between the progress events while the binary is written
+const progressInterval = 4 << 20
+
+// writeBinary writes the binary compiled in the work directory to the output. Returns its checksum, if
+// requested in the options, and its size
+func (b *nativeBuilder) writeBinary(platform Platform, k6Binary string, out binaryOutput) (string, int64, error) {
+	var (
+		written  int64
+		checksum string
+		err      error
+	)
+
+	if out.path != "" {
+		written, checksum, err = b.moveBinary(k6Binary, out.path)
+	} else {
+		written, checksum, err = b.copyBinary(platform, k6Binary, out.writer)
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	b.emit(Event{Type: EventBinaryWritten, Platform: platform.String(), Bytes: written, Total: written})
+	if b.Metrics != nil {
+		b.Metrics.ObserveBinarySize(platform.String(), written)
+	}
+
+	return checksum, written, nil
+}
+
+// copyBinary copies the binary to the writer, reporting the progress, and computes its checksum if requested
+func (b *nativeBuilder) copyBinary(platform Platform, k6Binary string, out io.Writer) (int64, string, error) {
+	k6File, err := os.Open(k6Binary) //nolint:gosec
+	if err != nil {
+		return 0, "", err
+	}
+	defer k6File.Close() //nolint:errcheck
+
+	stat, err := k6File.Stat()
+	if err != nil {
+		return 0, "", err
+	}
+
+	b.log.Info(fmt.Sprintf("Writing binary (%d bytes)", stat.Size()))
+
+	hash := sha256.New()
+	if b.Checksum {
+		out = io.MultiWriter(out, hash)
+	}
+
+	progress := &progressWriter{
+		out:   out,
+		total: stat.Size(),
+		report: func(written int64, total int64) {
+			b.log.Debug(fmt.Sprintf("Written %d of %d bytes", written, total))
+			b.emit(Event{Type: EventBinaryWriting, Platform: platform.String(), Bytes: written, Total: total})
+		},
+	}
+	progress.report(0, progress.total)
+
+	written, err := io.Copy(progress, k6File)
+	if err != nil {
+		return 0, "", fmt.Errorf("copying binary %w", err)
+	}
+
+	if !b.Checksum {
+		return written, "", nil
+	}
+
+	return written, "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// progressWriter reports the bytes written each progressInterval bytes
+type progressWriter struct {
+	out      io.Writer
+	total    int64
+	written  int64
+	reported int64
+	report   func(written int64, total int64)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.out.Write(p)
+	w.written += int64(n)
+
+	if w.written-w.reported >= progressInterval {
+		w.reported = w.written
+		w.report(w.written, w.total)
+	}
+
+	return n, err
+}
+
+// moveBinary moves the binary to the path. If it can't be renamed (e.g. the work directory is in
+// another file system), it is copied to a temporary file next to the path that is renamed, so the
+// path never has a partial binary
+func (b *nativeBuilder) moveBinary(k6Binary string, path string) (int64, string, error) {
+	mode := b.OutputMode
+	if mode == 0 {
+		mode = DefaultOutputMode
+	}
+
+	if err := os.Chmod(k6Binary, mode); err != nil {
+		return 0, "", fmt.Errorf("setting binary permissions %w", err)
+	}
+
+	if err := os.Rename(k6Binary, path); err != nil {
+		if err = copyToFile(k6Binary, path, mode); err != nil {
+			return 0, "", fmt.Errorf("moving binary %w", err)
+		}
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if !b.Checksum {
+		return stat.Size(), "", nil
+	}
+
+	checksum, err := fileSHA256(path)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return stat.Size(), "sha256:" + checksum, nil
+}
+
+// copyToFile copies the file at src to dst using an AtomicFile
+func copyToFile(src string, dst string, mode os.FileMode) error {
+	in, err := os.Open(src) //nolint:gosec
+	if err != nil {
+		return err
+	}
+	defer in.Close() //nolint:errcheck
+
+	out, err := CreateAtomicFile(dst, mode)
+	if err != nil {
+		return err
+	}
+	defer out.Abort()
+
+	if _, err = io.Copy(out, in); err != nil {
+		return err
+	}
+
+	return out.Commit()
+}
@@ -0,0 +1,185 @@
+package k6foundry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestBuildToFile(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	if err := proxy.AddModVersion("go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	b, err := NewNativeBuilder(context.Background(), NativeBuilderOpts{
+		GoOpts: GoOpts{
+			CopyGoEnv: true,
+			Env: map[string]string{
+				"GOPROXY":   goproxySrv.URL,
+				"GONOPROXY": "none",
+				"GOPRIVATE": "go.k6.io",
+				"GONOSUMDB": "go.k6.io",
+			},
+			TmpCache: true,
+		},
+		Checksum:   true,
+		OutputMode: 0o700,
+	})
+	if err != nil {
+		t.Fatalf("setting up test %v", err)
+	}
+
+	outPath := filepath.Join(t.TempDir(), "k6")
+	// an existing file is replaced
+	if err = os.WriteFile(outPath, []byte("previous"), 0o600); err != nil {
+		t.Fatalf("setting up test %v", err)
+	}
+
+	info, err := BuildToFile(context.Background(), b, RuntimePlatform(), "v0.1.0", nil, nil, outPath)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	checksum, err := fileSHA256(outPath)
+	if err != nil {
+		t.Fatalf("reading binary %v", err)
+	}
+
+	if info.Checksum != "sha256:"+checksum {
+		t.Fatalf("expected checksum sha256:%s got %s", checksum, info.Checksum)
+	}
+
+	if _, err = InspectBinary(outPath); err != nil {
+		t.Fatalf("expected a go binary %v", err)
+	}
+
+	stat, err := os.Stat(outPath)
+	if err != nil {
+		t.Fatalf("reading binary %v", err)
+	}
+
+	if runtime.GOOS != "windows" && stat.Mode().Perm() != 0o700 {
+		t.Fatalf("expected mode %v got %v", os.FileMode(0o700), stat.Mode().Perm())
+	}
+}
+
+var errWriterBuild = errors.New("build failed")
+
+// writerBuilder only implements Builder, writing a fixed content
+type writerBuilder struct {
+	fail bool
+}
+
+func (b *writerBuilder) Build(
+	_ context.Context,
+	platform Platform,
+	_ string,
+	_ []Module,
+	_ []string,
+	out io.Writer,
+) (*BuildInfo, error) {
+	if _, err := out.Write([]byte("binary")); err != nil {
+		return nil, err
+	}
+
+	if b.fail {
+		return nil, errWriterBuild
+	}
+
+	return &BuildInfo{Platform: platform.String()}, nil
+}
+
+func TestBuildToFileWriter(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		fail        bool
+		expectError error
+	}{
+		{
+			title: "build",
+		},
+		{
+			title:       "failed build",
+			fail:        true,
+			expectError: errWriterBuild,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			outPath := filepath.Join(t.TempDir(), "k6")
+			// the existing file is only replaced if the build succeeds
+			if err := os.WriteFile(outPath, []byte("previous"), 0o600); err != nil {
+				t.Fatalf("setting up test %v", err)
+			}
+
+			_, err := BuildToFile(context.Background(), &writerBuilder{fail: tc.fail}, RuntimePlatform(), "v0.1.0", nil, nil, outPath)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			content, err := os.ReadFile(outPath)
+			if err != nil {
+				t.Fatalf("reading output %v", err)
+			}
+
+			expect := "binary"
+			if tc.expectError != nil {
+				expect = "previous"
+			}
+			if string(content) != expect {
+				t.Fatalf("expected %q got %q", expect, content)
+			}
+
+			entries, err := os.ReadDir(filepath.Dir(outPath))
+			if err != nil {
+				t.Fatalf("reading output directory %v", err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("expected no temporary files got %d entries", len(entries))
+			}
+		})
+	}
+}
+
+func TestProgressWriter(t *testing.T) {
+	t.Parallel()
+
+	reported := []int64{}
+	w := &progressWriter{
+		out:    io.Discard,
+		total:  10 << 20,
+		report: func(written int64, _ int64) { reported = append(reported, written) },
+	}
+
+	chunk := make([]byte, 1<<20)
+	for range 10 {
+		if _, err := w.Write(chunk); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+
+	expect := []int64{4 << 20, 8 << 20}
+	if !slices.Equal(reported, expect) {
+		t.Fatalf("expected %v got %v", expect, reported)
+	}
+}
@@ -0,0 +1,107 @@
+package k6foundry
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidBuildFlags is returned when the linker, compiler or assembler flags can't be passed to go
+var ErrInvalidBuildFlags = errors.New("invalid build flags")
+
+// buildFlags adds to the build opts the options for the structured build flags. The options are added
+// before the build opts, so the build opts take precedence when they set the same flag. The linker flags
+// are merged with any -ldflags in the build opts
+func (o GoOpts) buildFlags(buildOpts []string) ([]string, error) {
+	flags := []string{}
+
+	if o.TrimPath && !slices.Contains(buildOpts, "-trimpath") {
+		flags = append(flags, "-trimpath")
+	}
+
+	if o.Race && o.Env["CGO_ENABLED"] == "0" {
+		return nil, fmt.Errorf("%w: the race detector requires cgo", ErrInvalidBuildFlags)
+	}
+
+	if o.Race && !slices.Contains(buildOpts, "-race") {
+		flags = append(flags, "-race")
+	}
+
+	for _, flag := range []struct {
+		name string
+		args []string
+	}{
+		{name: "-gcflags", args: o.GCFlags},
+		{name: "-asmflags", args: o.ASMFlags},
+	} {
+		if len(flag.args) == 0 {
+			continue
+		}
+
+		value, err := quoteFlags(flag.name, flag.args)
+		if err != nil {
+			return nil, err
+		}
+
+		flags = append(flags, flag.name+"="+value)
+	}
+
+	buildOpts = append(flags, buildOpts...)
+
+	if len(o.LDFlags) > 0 {
+		value, err := quoteFlags("-ldflags", o.LDFlags)
+		if err != nil {
+			return nil, err
+		}
+
+		buildOpts = addLdFlags(buildOpts, value)
+	}
+
+	return buildOpts, nil
+}
+
+// quoteFlags joins the arguments of a flag. Arguments with a flag and its value separated by spaces
+// (e.g. "-X main.version=1.0") are passed as two arguments. The values containing spaces or starting
+// with a quote are quoted, as go splits the arguments by spaces, honoring single and double quotes
+// without escaping. Values containing both types of quotes can't be passed
+func quoteFlags(name string, args []string) (string, error) {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		if flag, value, found := strings.Cut(arg, " "); found && strings.HasPrefix(flag, "-") {
+			value, err := quoteArg(name, strings.TrimSpace(value))
+			if err != nil {
+				return "", err
+			}
+
+			quoted = append(quoted, flag+" "+value)
+			continue
+		}
+
+		value, err := quoteArg(name, arg)
+		if err != nil {
+			return "", err
+		}
+
+		quoted = append(quoted, value)
+	}
+
+	return strings.Join(quoted, " "), nil
+}
+
+// quoteArg quotes an argument if it is empty, contains spaces or starts with a quote
+func quoteArg(name string, arg string) (string, error) {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\r") && arg[0] != '\'' && arg[0] != '"' {
+		return arg, nil
+	}
+
+	quote := "'"
+	if strings.Contains(arg, quote) {
+		quote = `"`
+		if strings.Contains(arg, quote) {
+			return "", fmt.Errorf("%w: %s argument %q contains both types of quotes", ErrInvalidBuildFlags, name, arg)
+		}
+	}
+
+	return quote + arg + quote, nil
+}
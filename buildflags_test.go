@@ -0,0 +1,88 @@
+package k6foundry
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestBuildFlags(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		opts      GoOpts
+		buildOpts []string
+		expect    []string
+		expectErr error
+	}{
+		{
+			title:     "no flags",
+			buildOpts: []string{"-v"},
+			expect:    []string{"-v"},
+		},
+		{
+			title:  "trimpath and race",
+			opts:   GoOpts{TrimPath: true, Race: true},
+			expect: []string{"-trimpath", "-race"},
+		},
+		{
+			title:     "trimpath in build opts",
+			opts:      GoOpts{TrimPath: true},
+			buildOpts: []string{"-trimpath"},
+			expect:    []string{"-trimpath"},
+		},
+		{
+			title:     "compiler and assembler flags before build opts",
+			opts:      GoOpts{GCFlags: []string{"all=-N", "-l"}, ASMFlags: []string{"-spectre=all"}},
+			buildOpts: []string{"-gcflags=-m"},
+			expect:    []string{"-gcflags=all=-N -l", "-asmflags=-spectre=all", "-gcflags=-m"},
+		},
+		{
+			title:  "linker flags with spaces",
+			opts:   GoOpts{LDFlags: []string{"-s", "-X main.version=1.0", "-X main.name=it's k6", "-X  main.empty="}},
+			expect: []string{`-ldflags=-s -X main.version=1.0 -X "main.name=it's k6" -X main.empty=`},
+		},
+		{
+			title:     "linker flags merged with build opts",
+			opts:      GoOpts{LDFlags: []string{"-w"}},
+			buildOpts: []string{"-ldflags=-s"},
+			expect:    []string{"-ldflags=-w -s"},
+		},
+		{
+			title:  "empty and quoted arguments",
+			opts:   GoOpts{LDFlags: []string{"", `"quoted"`}},
+			expect: []string{`-ldflags='' '"quoted"'`},
+		},
+		{
+			title:     "race without cgo",
+			opts:      GoOpts{Race: true, Env: map[string]string{"CGO_ENABLED": "0"}},
+			expectErr: ErrInvalidBuildFlags,
+		},
+		{
+			title:     "both quotes",
+			opts:      GoOpts{LDFlags: []string{`-X main.name="it's k6"`}},
+			expectErr: ErrInvalidBuildFlags,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			buildOpts, err := tc.opts.buildFlags(tc.buildOpts)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(buildOpts, tc.expect) {
+				t.Fatalf("expected %v got %v", tc.expect, buildOpts)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package k6foundry
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidBuildTags is returned when a build tag is not valid
+var ErrInvalidBuildTags = errors.New("invalid build tags")
+
+// validateBuildTags checks the build tags contain only letters, digits, underscores and dots, as
+// required by go
+func validateBuildTags(tags []string) error {
+	for _, tag := range tags {
+		if tag == "" {
+			return fmt.Errorf("%w: empty tag", ErrInvalidBuildTags)
+		}
+
+		for _, c := range tag {
+			if !isTagChar(c) {
+				return fmt.Errorf("%w: %q contains %q", ErrInvalidBuildTags, tag, c)
+			}
+		}
+	}
+
+	return nil
+}
+
+func isTagChar(c rune) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.'
+}
+
+// addBuildTags adds the tags to the build opts, merging them with the tags of any -tags already present
+func addBuildTags(buildOpts []string, tags []string) []string {
+	if len(tags) == 0 {
+		return buildOpts
+	}
+
+	merged := make([]string, 0, len(buildOpts)+1)
+	found := false
+
+	for i := 0; i < len(buildOpts); i++ {
+		opt := buildOpts[i]
+		name, value, hasValue := strings.Cut(opt, "=")
+		if name != "-tags" && name != "--tags" {
+			merged = append(merged, opt)
+			continue
+		}
+
+		found = true
+
+		// value passed as the next argument
+		if !hasValue && i+1 < len(buildOpts) {
+			i++
+			value = buildOpts[i]
+		}
+
+		merged = append(merged, name+"="+mergeTags(value, tags))
+	}
+
+	if !found {
+		merged = append(merged, "-tags="+strings.Join(tags, ","))
+	}
+
+	return merged
+}
+
+// mergeTags adds the tags to a list of tags, separated by commas (or spaces, as accepted by older
+// go versions), without duplicates
+func mergeTags(list string, tags []string) string {
+	merged := strings.FieldsFunc(list, func(c rune) bool { return c == ',' || c == ' ' })
+	for _, tag := range tags {
+		if !slices.Contains(merged, tag) {
+			merged = append(merged, tag)
+		}
+	}
+
+	return strings.Join(merged, ",")
+}
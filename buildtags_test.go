@@ -0,0 +1,95 @@
+package k6foundry
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidateBuildTags(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		tags      []string
+		expectErr error
+	}{
+		{title: "no tags", tags: nil},
+		{title: "valid tags", tags: []string{"netgo", "sqlite_omit_load_extension", "go1.22"}},
+		{title: "empty tag", tags: []string{""}, expectErr: ErrInvalidBuildTags},
+		{title: "comma", tags: []string{"netgo,osusergo"}, expectErr: ErrInvalidBuildTags},
+		{title: "space", tags: []string{"net go"}, expectErr: ErrInvalidBuildTags},
+		{title: "negation", tags: []string{"!cgo"}, expectErr: ErrInvalidBuildTags},
+		{title: "dash", tags: []string{"no-cgo"}, expectErr: ErrInvalidBuildTags},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			if err := validateBuildTags(tc.tags); !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestAddBuildTags(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		buildOpts []string
+		tags      []string
+		expect    []string
+	}{
+		{
+			title:     "no tags",
+			buildOpts: []string{"-trimpath"},
+			expect:    []string{"-trimpath"},
+		},
+		{
+			title:     "no tags in build opts",
+			buildOpts: []string{"-trimpath"},
+			tags:      []string{"netgo", "osusergo"},
+			expect:    []string{"-trimpath", "-tags=netgo,osusergo"},
+		},
+		{
+			title:     "tags with value",
+			buildOpts: []string{"-tags=sqlite_omit_load_extension"},
+			tags:      []string{"netgo"},
+			expect:    []string{"-tags=sqlite_omit_load_extension,netgo"},
+		},
+		{
+			title:     "tags value in next argument",
+			buildOpts: []string{"-tags", "sqlite_omit_load_extension", "-trimpath"},
+			tags:      []string{"netgo"},
+			expect:    []string{"-tags=sqlite_omit_load_extension,netgo", "-trimpath"},
+		},
+		{
+			title:     "space separated tags",
+			buildOpts: []string{"--tags=a b"},
+			tags:      []string{"netgo"},
+			expect:    []string{"--tags=a,b,netgo"},
+		},
+		{
+			title:     "duplicated tags",
+			buildOpts: []string{"-tags=netgo"},
+			tags:      []string{"netgo"},
+			expect:    []string{"-tags=netgo"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			merged := addBuildTags(tc.buildOpts, tc.tags)
+			if !reflect.DeepEqual(merged, tc.expect) {
+				t.Fatalf("expected %v got %v", tc.expect, merged)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CacheStats describes the entries of a cache
+type CacheStats struct {
+	// number of entries
+	Entries int `json:"entries"`
+	// size of the entries in bytes
+	Size int64 `json:"size"`
+}
+
+// GoCacheInfo describes the go build and module caches in a cache directory
+type GoCacheInfo struct {
+	// cache directory
+	Dir string `json:"dir"`
+	// build cache (GOCACHE)
+	BuildCache CacheStats `json:"buildCache"`
+	// module cache (GOMODCACHE)
+	ModCache CacheStats `json:"modCache"`
+}
+
+// DefaultContainerCacheDir returns the directory of the go caches used by the container builder
+// when GoCacheDir is not set
+func DefaultContainerCacheDir() (string, error) {
+	userCache, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("%w: locating cache directory %w", ErrSettingGoEnv, err)
+	}
+
+	return filepath.Join(userCache, "k6foundry", "container"), nil
+}
+
+// InspectGoCache returns the entries of the go caches in a cache directory with the layout used for
+// GoCacheDir. A directory that doesn't exist has no entries
+func InspectGoCache(ctx context.Context, cacheDir string) (GoCacheInfo, error) {
+	info := GoCacheInfo{Dir: cacheDir}
+
+	unlock, err := lockCacheDir(ctx, cacheDir, false)
+	if err != nil || unlock == nil {
+		return info, err
+	}
+	defer unlock()
+
+	buildEntries, err := buildCacheEntries(filepath.Join(cacheDir, "gocache"))
+	if err != nil {
+		return info, err
+	}
+
+	modEntries, err := modCacheEntries(filepath.Join(cacheDir, "modcache"))
+	if err != nil {
+		return info, err
+	}
+
+	info.BuildCache = entriesStats(buildEntries)
+	info.ModCache = entriesStats(modEntries)
+
+	return info, nil
+}
+
+// PruneGoCache removes the entries of the go caches in a cache directory not used in the last maxAge,
+// and then the least recently used entries until the size of the caches is at most maxSize. A maxAge or
+// maxSize of zero or less disables the corresponding limit. Waits for the builds using the caches to
+// finish. Returns the removed entries
+func PruneGoCache(ctx context.Context, cacheDir string, maxAge time.Duration, maxSize int64) (CacheStats, error) {
+	unlock, err := lockCacheDir(ctx, cacheDir, true)
+	if err != nil || unlock == nil {
+		return CacheStats{}, err
+	}
+	defer unlock()
+
+	cutoff := time.Time{}
+	if maxAge > 0 {
+		cutoff = time.Now().Add(-maxAge)
+	}
+
+	return pruneCacheDir(cacheDir, cutoff, maxSize)
+}
+
+// CleanGoCache removes the content of the go caches in a cache directory. Waits for the builds using
+// the caches to finish
+func CleanGoCache(ctx context.Context, cacheDir string) error {
+	unlock, err := lockCacheDir(ctx, cacheDir, true)
+	if err != nil || unlock == nil {
+		return err
+	}
+	defer unlock()
+
+	if err = removeAll(filepath.Join(cacheDir, "gocache")); err != nil {
+		return err
+	}
+
+	// the lock file is kept, as other builds may be waiting for it
+	modCache := filepath.Join(cacheDir, "modcache")
+	lockPath := filepath.Join(modCache, "cache", cacheLockFileName)
+
+	return removeContent(modCache, func(path string) bool {
+		return path == lockPath || path == filepath.Dir(lockPath)
+	})
+}
+
+// lockCacheDir locks the module cache in the cache directory. Returns a nil function if the
+// directory doesn't exist
+func lockCacheDir(ctx context.Context, cacheDir string, exclusive bool) (func(), error) {
+	if _, err := os.Stat(cacheDir); os.IsNotExist(err) {
+		return nil, nil //nolint:nilnil
+	}
+
+	lockPath, err := cacheLockPath(filepath.Join(cacheDir, "modcache"))
+	if err != nil {
+		return nil, err
+	}
+
+	unlock, err := lockFile(ctx, lockPath, exclusive)
+	if err != nil {
+		return nil, fmt.Errorf("%w: locking cache %w", ErrSettingGoEnv, err)
+	}
+
+	return unlock, nil
+}
+
+// removeContent removes the content of the directory recursively, except the paths to keep
+func removeContent(dir string, keep func(path string) bool) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+
+		switch {
+		case !keep(path):
+			err = removeAll(path)
+		case entry.IsDir():
+			err = removeContent(path, keep)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func entriesStats(entries []cacheEntry) CacheStats {
+	stats := CacheStats{Entries: len(entries)}
+	for _, entry := range entries {
+		stats.Size += entry.size
+	}
+
+	return stats
+}
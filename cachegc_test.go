@@ -0,0 +1,145 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeGoCache creates a cache directory with an old and a recent entry in the build cache and in the
+// module cache, each of 100 bytes
+func writeGoCache(t *testing.T) string {
+	t.Helper()
+
+	cacheDir := t.TempDir()
+	t.Cleanup(func() { _ = removeAll(cacheDir) })
+
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+
+	writeCacheFile(t, filepath.Join(cacheDir, "gocache", "README"), 100, old)
+	writeCacheFile(t, filepath.Join(cacheDir, "gocache", "aa", "old-a"), 100, old)
+	writeCacheFile(t, filepath.Join(cacheDir, "gocache", "bb", "new-d"), 100, now)
+
+	download := filepath.Join(cacheDir, "modcache", "cache", "download")
+	writeCacheFile(t, filepath.Join(download, "example.com", "old", "@v", "v1.0.0.mod"), 10, old)
+	writeCacheFile(t, filepath.Join(download, "example.com", "old", "@v", "v1.0.0.zip"), 90, old)
+	writeCacheFile(t, filepath.Join(download, "example.com", "new", "@v", "v1.0.0.mod"), 10, now)
+	writeCacheFile(t, filepath.Join(download, "example.com", "new", "@v", "v1.0.0.zip"), 90, now)
+
+	return cacheDir
+}
+
+func TestInspectGoCache(t *testing.T) {
+	t.Parallel()
+
+	info, err := InspectGoCache(context.Background(), writeGoCache(t))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	expect := CacheStats{Entries: 2, Size: 200}
+	if info.BuildCache != expect || info.ModCache != expect {
+		t.Fatalf("expected %v in each cache got %v and %v", expect, info.BuildCache, info.ModCache)
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing")
+	info, err = InspectGoCache(context.Background(), missing)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if info.BuildCache != (CacheStats{}) || info.ModCache != (CacheStats{}) {
+		t.Fatalf("expected empty caches got %v and %v", info.BuildCache, info.ModCache)
+	}
+
+	if _, err = os.Stat(missing); !os.IsNotExist(err) {
+		t.Fatalf("expected cache directory not created")
+	}
+}
+
+func TestPruneGoCache(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title         string
+		maxAge        time.Duration
+		maxSize       int64
+		expectRemoved CacheStats
+	}{
+		{
+			title:         "no limits",
+			expectRemoved: CacheStats{},
+		},
+		{
+			title:         "max age",
+			maxAge:        24 * time.Hour,
+			expectRemoved: CacheStats{Entries: 2, Size: 200},
+		},
+		{
+			title:         "max size",
+			maxSize:       350,
+			expectRemoved: CacheStats{Entries: 1, Size: 100},
+		},
+		{
+			title:         "max age and size",
+			maxAge:        24 * time.Hour,
+			maxSize:       100,
+			expectRemoved: CacheStats{Entries: 3, Size: 300},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			cacheDir := writeGoCache(t)
+
+			removed, err := PruneGoCache(context.Background(), cacheDir, tc.maxAge, tc.maxSize)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if removed != tc.expectRemoved {
+				t.Fatalf("expected %v removed got %v", tc.expectRemoved, removed)
+			}
+
+			info, err := InspectGoCache(context.Background(), cacheDir)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if left := info.BuildCache.Size + info.ModCache.Size; left != 400-tc.expectRemoved.Size {
+				t.Fatalf("expected %d bytes left got %d", 400-tc.expectRemoved.Size, left)
+			}
+		})
+	}
+}
+
+func TestCleanGoCache(t *testing.T) {
+	t.Parallel()
+
+	cacheDir := writeGoCache(t)
+
+	if err := CleanGoCache(context.Background(), cacheDir); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	for _, path := range []string{
+		filepath.Join(cacheDir, "gocache"),
+		filepath.Join(cacheDir, "modcache", "cache", "download"),
+	} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("%s should be removed", path)
+		}
+	}
+
+	// builds waiting for the cache keep using the same lock file
+	if _, err := os.Stat(filepath.Join(cacheDir, "modcache", "cache", cacheLockFileName)); err != nil {
+		t.Fatalf("lock file should be kept: %v", err)
+	}
+}
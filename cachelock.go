@@ -0,0 +1,77 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// file used for locking a go module cache shared between builds
+	cacheLockFileName = ".k6foundry-cache.lock"
+
+	// interval for retrying a lock held by another build
+	lockRetryInterval = 100 * time.Millisecond
+)
+
+// cacheLockPath returns the path to the lock file of the module cache, creating its directory if needed
+func cacheLockPath(modCache string) (string, error) {
+	// the lock is kept in the download directory, where go keeps its own lock files
+	dir := filepath.Join(modCache, "cache")
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("%w: creating module cache %w", ErrSettingGoEnv, err)
+	}
+
+	return filepath.Join(dir, cacheLockFileName), nil
+}
+
+// lockCache locks the module cache of the environment, if it is shared with other builds, and returns
+// a function for releasing the lock. Exclusive access is required for downloading modules.
+// Waits until the lock is acquired or the context is done.
+func (e goEnv) lockCache(ctx context.Context, exclusive bool) (func(), error) {
+	if e.cacheLock == "" {
+		return func() {}, nil
+	}
+
+	release, err := lockFile(ctx, e.cacheLock, exclusive)
+	if err != nil {
+		return nil, fmt.Errorf("%w: locking cache %w", ErrSettingGoEnv, err)
+	}
+
+	return release, nil
+}
+
+// lockFile locks a file, creating it if needed, and returns a function for releasing the lock.
+// Waits until the lock is acquired or the context is done.
+func lockFile(ctx context.Context, path string, exclusive bool) (func(), error) {
+	// each lock uses its own file descriptor, so the lock also coordinates the builds in this process
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		locked, err := tryLockFile(file, exclusive)
+		if err != nil {
+			_ = file.Close()
+			return nil, err
+		}
+
+		if locked {
+			return func() {
+				_ = unlockFile(file)
+				_ = file.Close()
+			}, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			_ = file.Close()
+			return nil, ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
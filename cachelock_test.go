@@ -0,0 +1,136 @@
+package k6foundry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestCacheLock(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		held        bool
+		exclusive   bool
+		expectError error
+	}{
+		{
+			title:     "shared with shared",
+			held:      false,
+			exclusive: false,
+		},
+		{
+			title:       "exclusive with shared",
+			held:        false,
+			exclusive:   true,
+			expectError: context.DeadlineExceeded,
+		},
+		{
+			title:       "shared with exclusive",
+			held:        true,
+			exclusive:   false,
+			expectError: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			lock, err := cacheLockPath(t.TempDir())
+			if err != nil {
+				t.Fatalf("setup %v", err)
+			}
+			e := goEnv{cacheLock: lock}
+
+			release, err := e.lockCache(context.Background(), tc.held)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+			defer cancel()
+
+			unlock, err := e.lockCache(ctx, tc.exclusive)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			if err == nil {
+				unlock()
+			}
+
+			// the lock is acquired after it is released
+			release()
+			unlock, err = e.lockCache(context.Background(), tc.exclusive)
+			if err != nil {
+				t.Fatalf("unexpected error after release %v", err)
+			}
+			unlock()
+		})
+	}
+}
+
+func TestSharedGoCache(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	if err := proxy.AddModVersion("go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	cacheDir := t.TempDir()
+	t.Cleanup(func() {
+		// the module cache is read only
+		_ = removeAll(cacheDir)
+	})
+
+	b, err := NewNativeBuilder(context.Background(), NativeBuilderOpts{
+		GoOpts: GoOpts{
+			CopyGoEnv: true,
+			Env: map[string]string{
+				"GOPROXY":   goproxySrv.URL,
+				"GONOPROXY": "none",
+				"GOPRIVATE": "go.k6.io",
+				"GONOSUMDB": "go.k6.io",
+			},
+			GoCacheDir: cacheDir,
+		},
+	})
+	if err != nil {
+		t.Fatalf("setting up test %v", err)
+	}
+
+	// concurrent builds using the same cache
+	errs := make([]error, 2)
+	wg := sync.WaitGroup{}
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = b.Build(context.Background(), RuntimePlatform(), "v0.1.0", []Module{}, []string{}, &bytes.Buffer{})
+		}()
+	}
+	wg.Wait()
+
+	if err = errors.Join(errs...); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if _, err = os.Stat(filepath.Join(cacheDir, "modcache", "go.k6.io", "k6@v0.1.0")); err != nil {
+		t.Fatalf("module not in the cache %v", err)
+	}
+}
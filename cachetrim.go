@@ -0,0 +1,247 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/module"
+)
+
+// cacheEntry is a set of files in the go caches removed together when trimming the cache
+type cacheEntry struct {
+	paths   []string
+	size    int64
+	lastUse time.Time
+}
+
+// trimCache removes the least recently used entries of the caches of the environment until they fit
+// in MaxCacheSize. The modules used by the build in the work directory are marked as recently used
+func (b *nativeBuilder) trimCache(ctx context.Context, workDir string, buildEnv *goEnv) error {
+	if b.MaxCacheSize <= 0 || buildEnv.cacheDir == "" {
+		return nil
+	}
+
+	unlock, err := buildEnv.lockCache(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	modCache := filepath.Join(buildEnv.cacheDir, "modcache")
+	if err = touchModules(modCache, filepath.Join(workDir, "go.sum")); err != nil {
+		return err
+	}
+
+	removed, freed, err := trimCacheDir(buildEnv.cacheDir, b.MaxCacheSize)
+	if removed > 0 {
+		b.log.Info(fmt.Sprintf("Trimmed go cache: removed %d entries, %d bytes", removed, freed))
+	}
+
+	return err
+}
+
+// trimCacheDir removes the least recently used entries of the build and module caches in the directory
+// until their size is at most maxSize. Returns the number of entries removed and their size
+func trimCacheDir(cacheDir string, maxSize int64) (int, int64, error) {
+	removed, err := pruneCacheDir(cacheDir, time.Time{}, maxSize)
+
+	return removed.Entries, removed.Size, err
+}
+
+// pruneCacheDir removes the entries of the build and module caches in the directory last used before
+// the cutoff, and then the least recently used entries until their size is at most maxSize. A maxSize
+// of zero or less doesn't limit the size. Returns the removed entries
+func pruneCacheDir(cacheDir string, cutoff time.Time, maxSize int64) (CacheStats, error) {
+	removed := CacheStats{}
+
+	buildEntries, err := buildCacheEntries(filepath.Join(cacheDir, "gocache"))
+	if err != nil {
+		return removed, err
+	}
+
+	modEntries, err := modCacheEntries(filepath.Join(cacheDir, "modcache"))
+	if err != nil {
+		return removed, err
+	}
+
+	entries := append(buildEntries, modEntries...) //nolint:gocritic
+
+	total := int64(0)
+	for _, entry := range entries {
+		total += entry.size
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].lastUse.Before(entries[j].lastUse)
+	})
+
+	for _, entry := range entries {
+		if !entry.lastUse.Before(cutoff) && (maxSize <= 0 || total-removed.Size <= maxSize) {
+			break
+		}
+
+		for _, path := range entry.paths {
+			if err = removeAll(path); err != nil {
+				return removed, err
+			}
+		}
+
+		removed.Entries++
+		removed.Size += entry.size
+	}
+
+	return removed, nil
+}
+
+// buildCacheEntries returns the files of the build cache. Go updates their modification time when used
+func buildCacheEntries(goCache string) ([]cacheEntry, error) {
+	entries := []cacheEntry{}
+
+	err := filepath.WalkDir(goCache, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// the files in the root of the cache are not entries (e.g. README, trim.txt)
+		if d.IsDir() || filepath.Dir(path) == goCache {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, cacheEntry{paths: []string{path}, size: info.Size(), lastUse: info.ModTime()})
+
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading build cache: %w", err)
+	}
+
+	return entries, nil
+}
+
+// modCacheEntries returns the versions of the modules in the module cache, including their downloaded
+// files and extracted sources. The last use is the modification time of the go.mod of the version
+func modCacheEntries(modCache string) ([]cacheEntry, error) {
+	entries := []cacheEntry{}
+	download := filepath.Join(modCache, "cache", "download")
+
+	err := filepath.WalkDir(download, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || filepath.Base(filepath.Dir(path)) != "@v" || filepath.Ext(path) != ".mod" {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		versionDir := filepath.Dir(path)
+		modDir, err := filepath.Rel(download, filepath.Dir(versionDir))
+		if err != nil {
+			return err
+		}
+		version := strings.TrimSuffix(filepath.Base(path), ".mod")
+
+		entry := cacheEntry{lastUse: info.ModTime()}
+
+		files, err := filepath.Glob(filepath.Join(versionDir, version+".*"))
+		if err != nil {
+			return err
+		}
+		entry.paths = append(entry.paths, files...)
+
+		sources := filepath.Join(modCache, modDir+"@"+version)
+		if _, err = os.Stat(sources); err == nil {
+			entry.paths = append(entry.paths, sources)
+		}
+
+		for _, p := range entry.paths {
+			entry.size += diskUsage(p)
+		}
+
+		entries = append(entries, entry)
+
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading module cache: %w", err)
+	}
+
+	return entries, nil
+}
+
+// touchModules marks the module versions in the go.sum as used, updating the modification time of
+// their go.mod in the module cache
+func touchModules(modCache string, goSum string) error {
+	file, err := os.Open(goSum) //nolint:gosec
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close() //nolint:errcheck
+
+	now := time.Now()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+
+		path, err := module.EscapePath(fields[0])
+		if err != nil {
+			continue
+		}
+
+		version, err := module.EscapeVersion(strings.TrimSuffix(fields[1], "/go.mod"))
+		if err != nil {
+			continue
+		}
+
+		modFile := filepath.Join(modCache, "cache", "download", filepath.FromSlash(path), "@v", version+".mod")
+		// modules only listed in the go.sum may not be in the cache
+		if err = os.Chtimes(modFile, now, now); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+// diskUsage returns the size of the files in the path. Files that can't be read are ignored
+func diskUsage(path string) int64 {
+	size := int64(0)
+
+	_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil //nolint:nilerr
+		}
+
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	return size
+}
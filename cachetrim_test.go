@@ -0,0 +1,195 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+// writeCacheFile creates a file in the cache with the given size and modification time
+func writeCacheFile(t *testing.T, path string, size int, modTime time.Time) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0o400); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+}
+
+func TestTrimCacheDir(t *testing.T) {
+	t.Parallel()
+
+	cacheDir := t.TempDir()
+	now := time.Now()
+	old := now.Add(-time.Hour)
+
+	writeCacheFile(t, filepath.Join(cacheDir, "gocache", "README"), 100, old)
+	writeCacheFile(t, filepath.Join(cacheDir, "gocache", "aa", "old-a"), 100, old)
+	writeCacheFile(t, filepath.Join(cacheDir, "gocache", "bb", "new-d"), 100, now)
+
+	download := filepath.Join(cacheDir, "modcache", "cache", "download")
+	writeCacheFile(t, filepath.Join(download, "example.com", "old", "@v", "v1.0.0.mod"), 10, old)
+	writeCacheFile(t, filepath.Join(download, "example.com", "old", "@v", "v1.0.0.zip"), 90, old)
+	writeCacheFile(t, filepath.Join(download, "example.com", "new", "@v", "v1.0.0.mod"), 10, now)
+	writeCacheFile(t, filepath.Join(download, "example.com", "new", "@v", "v1.0.0.zip"), 90, now)
+
+	// extracted sources are read-only
+	oldSources := filepath.Join(cacheDir, "modcache", "example.com", "old@v1.0.0")
+	writeCacheFile(t, filepath.Join(oldSources, "go.mod"), 100, old)
+	if err := os.Chmod(oldSources, 0o500); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	removed, freed, err := trimCacheDir(cacheDir, 300)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if removed != 2 || freed != 300 {
+		t.Fatalf("expected 2 entries and 300 bytes removed got %d entries and %d bytes", removed, freed)
+	}
+
+	for _, path := range []string{
+		filepath.Join(cacheDir, "gocache", "aa", "old-a"),
+		filepath.Join(download, "example.com", "old", "@v", "v1.0.0.mod"),
+		filepath.Join(download, "example.com", "old", "@v", "v1.0.0.zip"),
+		oldSources,
+	} {
+		if _, err = os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("%s should be removed", path)
+		}
+	}
+
+	for _, path := range []string{
+		filepath.Join(cacheDir, "gocache", "README"),
+		filepath.Join(cacheDir, "gocache", "bb", "new-d"),
+		filepath.Join(download, "example.com", "new", "@v", "v1.0.0.zip"),
+	} {
+		if _, err = os.Stat(path); err != nil {
+			t.Fatalf("%s should be kept: %v", path, err)
+		}
+	}
+}
+
+func TestTouchModules(t *testing.T) {
+	t.Parallel()
+
+	modCache := t.TempDir()
+	old := time.Now().Add(-time.Hour)
+
+	modFile := filepath.Join(modCache, "cache", "download", "example.com", "!my!mod", "@v", "v1.0.0.mod")
+	writeCacheFile(t, modFile, 10, old)
+
+	goSum := filepath.Join(t.TempDir(), "go.sum")
+	content := "example.com/MyMod v1.0.0 h1:abc=\n" +
+		"example.com/MyMod v1.0.0/go.mod h1:def=\n" +
+		"example.com/missing v1.0.0/go.mod h1:ghi=\n"
+	if err := os.WriteFile(goSum, []byte(content), 0o600); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	if err := touchModules(modCache, goSum); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	info, err := os.Stat(modFile)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if !info.ModTime().After(old) {
+		t.Fatalf("expected module to be marked as used")
+	}
+}
+
+func TestDiskLimits(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	if err := proxy.AddModVersion("go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	testCases := []struct {
+		title        string
+		minFreeSpace int64
+		maxCacheSize int64
+		expectError  error
+		expectCached bool
+	}{
+		{
+			title:        "no limits",
+			expectCached: true,
+		},
+		{
+			title:        "insufficient disk space",
+			minFreeSpace: math.MaxInt64,
+			expectError:  ErrInsufficientDiskSpace,
+		},
+		{
+			title:        "cache trimmed",
+			maxCacheSize: 1,
+			expectCached: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			cacheDir := t.TempDir()
+			t.Cleanup(func() { _ = removeAll(cacheDir) })
+
+			b, err := NewNativeBuilder(context.Background(), NativeBuilderOpts{
+				GoOpts: GoOpts{
+					CopyGoEnv: true,
+					Env: map[string]string{
+						"GOPROXY":   goproxySrv.URL,
+						"GONOPROXY": "none",
+						"GOPRIVATE": "go.k6.io",
+						"GONOSUMDB": "go.k6.io",
+					},
+					GoCacheDir:       cacheDir,
+					MinFreeDiskSpace: tc.minFreeSpace,
+					MaxCacheSize:     tc.maxCacheSize,
+				},
+			})
+			if err != nil {
+				t.Fatalf("setting up test %v", err)
+			}
+
+			_, err = b.Build(context.Background(), RuntimePlatform(), "v0.1.0", []Module{}, []string{}, &bytes.Buffer{})
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			if tc.expectError != nil {
+				return
+			}
+
+			modFile := filepath.Join(cacheDir, "modcache", "cache", "download", "go.k6.io", "k6", "@v", "v0.1.0.mod")
+			_, err = os.Stat(modFile)
+			if cached := err == nil; cached != tc.expectCached {
+				t.Fatalf("expected cached %t got %t", tc.expectCached, cached)
+			}
+		})
+	}
+}
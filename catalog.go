@@ -0,0 +1,146 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrInvalidCatalog is returned when the catalog can't be loaded
+	ErrInvalidCatalog = errors.New("invalid catalog")
+	// ErrUnknownDependency is returned when a dependency is not in the catalog
+	ErrUnknownDependency = errors.New("unknown dependency")
+	// ErrInvalidConstraint is returned when a version constraint can't be parsed
+	ErrInvalidConstraint = errors.New("invalid version constraint")
+	// ErrNoMatchingVersion is returned when no version in the catalog satisfies the constraint
+	ErrNoMatchingVersion = errors.New("no version matches constraint")
+)
+
+// CatalogEntry describes a dependency in the catalog
+type CatalogEntry struct {
+	// Module path of the dependency
+	Module string `json:"module" yaml:"module"`
+	// Versions available for the dependency. If empty, any version is accepted.
+	Versions []string `json:"versions,omitempty" yaml:"versions,omitempty"`
+}
+
+// Catalog maps short dependency names (e.g. kafka) to their module and available versions
+//
+// Example (JSON):
+//
+//	{
+//	  "kafka": {"module": "github.com/mostafa/xk6-kafka", "versions": ["v0.25.0", "v0.26.0"]},
+//	  "sql": {"module": "github.com/grafana/xk6-sql"}
+//	}
+type Catalog map[string]CatalogEntry
+
+//go:embed catalog.yaml
+var builtinCatalog []byte
+
+// DefaultCatalog returns the built-in catalog with popular k6 extensions. Any version is accepted
+func DefaultCatalog() Catalog {
+	catalog := Catalog{}
+	if err := yaml.Unmarshal(builtinCatalog, &catalog); err != nil {
+		panic(fmt.Sprintf("parsing built-in catalog %v", err))
+	}
+
+	return catalog
+}
+
+// LoadCatalog loads a catalog from a JSON or YAML file. Files with .yaml or .yml extension
+// are parsed as YAML, others as JSON.
+func LoadCatalog(path string) (Catalog, error) {
+	content, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCatalog, err)
+	}
+
+	catalog := Catalog{}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &catalog)
+	default:
+		err = json.Unmarshal(content, &catalog)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCatalog, err)
+	}
+
+	for name, entry := range catalog {
+		if entry.Module == "" {
+			return nil, fmt.Errorf("%w: missing module for %q", ErrInvalidCatalog, name)
+		}
+	}
+
+	return catalog, nil
+}
+
+// Resolve returns the module for a dependency in the format name[@constraint].
+// If the entry in the catalog has versions, the highest one that satisfies the constraint is used.
+// Otherwise, the version or constraint is resolved when building, using the versions published in the module proxy.
+func (c Catalog) Resolve(dep string) (Module, error) {
+	name, constraint, _ := strings.Cut(dep, "@")
+
+	entry, found := c[name]
+	if !found {
+		return Module{}, fmt.Errorf("%w: %q", ErrUnknownDependency, name)
+	}
+
+	if len(entry.Versions) == 0 {
+		if constraint == "" {
+			constraint = "latest"
+		}
+
+		return ParseModule(entry.Module + "@" + constraint)
+	}
+
+	version, err := matchVersion(entry.Versions, constraint)
+	if err != nil {
+		return Module{}, fmt.Errorf("%w: %s@%s", err, name, constraint)
+	}
+
+	return ParseModule(entry.Module + "@" + version)
+}
+
+// matchVersion returns the highest version that satisfies the constraint
+func matchVersion(versions []string, constraint string) (string, error) {
+	if constraint == "" || constraint == "latest" {
+		constraint = "*"
+	}
+
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidConstraint, err)
+	}
+
+	candidates := []*semver.Version{}
+	for _, v := range versions {
+		version, err := semver.NewVersion(v)
+		if err != nil {
+			return "", fmt.Errorf("%w: invalid version %q", ErrInvalidCatalog, v)
+		}
+
+		if c.Check(version) {
+			candidates = append(candidates, version)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return "", ErrNoMatchingVersion
+	}
+
+	sort.Sort(semver.Collection(candidates))
+
+	return candidates[len(candidates)-1].Original(), nil
+}
@@ -0,0 +1,145 @@
+package k6foundry
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testCatalogJSON = `{
+  "kafka": {"module": "github.com/mostafa/xk6-kafka", "versions": ["v0.25.0", "v0.26.0", "v1.0.0"]},
+  "sql": {"module": "github.com/grafana/xk6-sql"}
+}`
+
+const testCatalogYAML = `
+kafka:
+  module: github.com/mostafa/xk6-kafka
+  versions: [v0.25.0, v0.26.0, v1.0.0]
+sql:
+  module: github.com/grafana/xk6-sql
+`
+
+func TestCatalogResolve(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	catalogs := map[string]string{
+		"catalog.json": testCatalogJSON,
+		"catalog.yaml": testCatalogYAML,
+	}
+
+	testCases := []struct {
+		title       string
+		dep         string
+		expectError error
+		expect      Module
+	}{
+		{
+			title:  "latest from versions",
+			dep:    "kafka",
+			expect: Module{Path: "github.com/mostafa/xk6-kafka", Version: "v1.0.0"},
+		},
+		{
+			title:  "constraint",
+			dep:    "kafka@<v1.0.0",
+			expect: Module{Path: "github.com/mostafa/xk6-kafka", Version: "v0.26.0"},
+		},
+		{
+			title:  "exact version",
+			dep:    "kafka@v0.25.0",
+			expect: Module{Path: "github.com/mostafa/xk6-kafka", Version: "v0.25.0"},
+		},
+		{
+			title:       "no matching version",
+			dep:         "kafka@>v1.0.0",
+			expectError: ErrNoMatchingVersion,
+		},
+		{
+			title:       "invalid constraint",
+			dep:         "kafka@>>v1",
+			expectError: ErrInvalidConstraint,
+		},
+		{
+			title:  "any version",
+			dep:    "sql",
+			expect: Module{Path: "github.com/grafana/xk6-sql", Version: "latest"},
+		},
+		{
+			title:  "any version with version",
+			dep:    "sql@v1.0.0",
+			expect: Module{Path: "github.com/grafana/xk6-sql", Version: "v1.0.0"},
+		},
+		{
+			title:  "any version with constraint",
+			dep:    "sql@>=v1.0.0 <v2.0.0",
+			expect: Module{Path: "github.com/grafana/xk6-sql", Version: ">=v1.0.0 <v2.0.0"},
+		},
+		{
+			title:       "unknown dependency",
+			dep:         "missing",
+			expectError: ErrUnknownDependency,
+		},
+	}
+
+	for file, content := range catalogs {
+		path := filepath.Join(dir, file)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+
+		catalog, err := LoadCatalog(path)
+		if err != nil {
+			t.Fatalf("loading %s: %v", file, err)
+		}
+
+		for _, tc := range testCases {
+			tc := tc
+			t.Run(file+"/"+tc.title, func(t *testing.T) {
+				t.Parallel()
+
+				mod, err := catalog.Resolve(tc.dep)
+				if !errors.Is(err, tc.expectError) {
+					t.Fatalf("expected %v got %v", tc.expectError, err)
+				}
+
+				if tc.expectError != nil {
+					return
+				}
+
+				if mod != tc.expect {
+					t.Fatalf("expected %v got %v", tc.expect, mod)
+				}
+			})
+		}
+	}
+}
+
+func TestLoadInvalidCatalog(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	if err := os.WriteFile(path, []byte(`{"kafka": {}}`), 0o600); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	_, err := LoadCatalog(path)
+	if !errors.Is(err, ErrInvalidCatalog) {
+		t.Fatalf("expected %v got %v", ErrInvalidCatalog, err)
+	}
+}
+
+func TestDefaultCatalog(t *testing.T) {
+	t.Parallel()
+
+	catalog := DefaultCatalog()
+	if len(catalog) == 0 {
+		t.Fatal("expected extensions in the default catalog")
+	}
+
+	for name := range catalog {
+		if _, err := catalog.Resolve(name); err != nil {
+			t.Fatalf("resolving %s: %v", name, err)
+		}
+	}
+}
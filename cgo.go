@@ -0,0 +1,69 @@
+package k6foundry
+
+// zig names for the go architectures and operating systems
+var (
+	zigArch = map[string]string{ //nolint:gochecknoglobals
+		"amd64":   "x86_64",
+		"arm64":   "aarch64",
+		"386":     "x86",
+		"arm":     "arm",
+		"riscv64": "riscv64",
+		"ppc64le": "powerpc64le",
+		"s390x":   "s390x",
+	}
+	zigOS = map[string]string{ //nolint:gochecknoglobals
+		"linux":   "linux-gnu",
+		"darwin":  "macos",
+		"windows": "windows-gnu",
+		"freebsd": "freebsd",
+	}
+)
+
+// zigTarget returns the zig target triple for the platform (e.g. aarch64-linux-gnu)
+func zigTarget(platform Platform) string {
+	arch, found := zigArch[platform.Arch]
+	if !found {
+		arch = platform.Arch
+	}
+
+	os, found := zigOS[platform.OS]
+	if !found {
+		os = platform.OS
+	}
+
+	return arch + "-" + os
+}
+
+// setCgoEnv configures cgo for building for the platform.
+//
+// If a C toolchain is configured (CC or Zig), cgo is enabled using it. Otherwise, cgo is disabled
+// when cross compiling (native is false), as the default C toolchain only targets the build platform.
+// The race detector enables cgo.
+// A CGO_ENABLED variable set explicitly in the options takes precedence.
+func setCgoEnv(env map[string]string, platform Platform, opts GoOpts, native bool) {
+	cgo := ""
+	switch {
+	case opts.Zig:
+		target := zigTarget(platform)
+		env["CC"] = "zig cc -target " + target
+		env["CXX"] = "zig c++ -target " + target
+		cgo = "1"
+	case opts.CC != "":
+		env["CC"] = opts.CC
+		if opts.CXX != "" {
+			env["CXX"] = opts.CXX
+		}
+		cgo = "1"
+	case opts.Race:
+		// the race detector requires cgo, with the default C toolchain if not cross compiling
+		cgo = "1"
+	case !native:
+		cgo = "0"
+	}
+
+	if _, explicit := opts.Env["CGO_ENABLED"]; explicit || cgo == "" {
+		return
+	}
+
+	env["CGO_ENABLED"] = cgo
+}
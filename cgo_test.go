@@ -0,0 +1,77 @@
+package k6foundry
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetCgoEnv(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title    string
+		platform Platform
+		opts     GoOpts
+		native   bool
+		expect   map[string]string
+	}{
+		{
+			title:    "native build",
+			platform: Platform{OS: "linux", Arch: "amd64"},
+			native:   true,
+			expect:   map[string]string{},
+		},
+		{
+			title:    "cross build",
+			platform: Platform{OS: "linux", Arch: "arm64"},
+			expect:   map[string]string{"CGO_ENABLED": "0"},
+		},
+		{
+			title:    "cross build with explicit CGO_ENABLED",
+			platform: Platform{OS: "linux", Arch: "arm64"},
+			opts:     GoOpts{Env: map[string]string{"CGO_ENABLED": "1"}},
+			expect:   map[string]string{},
+		},
+		{
+			title:    "race detector",
+			platform: Platform{OS: "linux", Arch: "amd64"},
+			opts:     GoOpts{Race: true},
+			native:   true,
+			expect:   map[string]string{"CGO_ENABLED": "1"},
+		},
+		{
+			title:    "cross build with CC",
+			platform: Platform{OS: "linux", Arch: "arm64"},
+			opts:     GoOpts{CC: "aarch64-linux-gnu-gcc", CXX: "aarch64-linux-gnu-g++"},
+			expect: map[string]string{
+				"CGO_ENABLED": "1",
+				"CC":          "aarch64-linux-gnu-gcc",
+				"CXX":         "aarch64-linux-gnu-g++",
+			},
+		},
+		{
+			title:    "cross build with zig",
+			platform: Platform{OS: "darwin", Arch: "arm64"},
+			opts:     GoOpts{Zig: true, CC: "gcc"},
+			expect: map[string]string{
+				"CGO_ENABLED": "1",
+				"CC":          "zig cc -target aarch64-macos",
+				"CXX":         "zig c++ -target aarch64-macos",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			env := map[string]string{}
+			setCgoEnv(env, tc.platform, tc.opts, tc.native)
+
+			if !reflect.DeepEqual(env, tc.expect) {
+				t.Fatalf("expected %v got %v", tc.expect, env)
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// prefix used for all the temporary directories created by k6foundry
+	tmpDirPrefix = "k6foundry"
+
+	// marker file written in each temporary directory identifying the process that owns it
+	lockFileName = ".k6foundry.lock"
+)
+
+// ErrInvalidCleanupStrategy is returned when the cleanup strategy is unknown
+var ErrInvalidCleanupStrategy = errors.New("invalid cleanup strategy")
+
+// CleanupStrategy defines when the work directory of a build is removed
+type CleanupStrategy string
+
+const (
+	// CleanupAlways removes the work directory after each build. This is the default
+	CleanupAlways CleanupStrategy = "always"
+	// CleanupOnSuccess removes the work directory of the successful builds and keeps the work directory
+	// of the failed builds for debugging. Interrupted builds are removed
+	CleanupOnSuccess CleanupStrategy = "on-success"
+	// CleanupNever keeps the work directory and the temporary caches of all the builds, as SkipCleanup
+	CleanupNever CleanupStrategy = "never"
+)
+
+// names of the temporary directories created by k6foundry, with the random suffix of os.MkdirTemp
+var tmpDirRegexp = regexp.MustCompile(`^` + tmpDirPrefix + `(-cache)?[0-9]+$`) //nolint:gochecknoglobals
+
+func validateCleanupStrategy(strategy CleanupStrategy) error {
+	switch strategy {
+	case "", CleanupAlways, CleanupOnSuccess, CleanupNever:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrInvalidCleanupStrategy, strategy)
+	}
+}
+
+// lockInfo is the content of the lock file
+type lockInfo struct {
+	PID     int       `json:"pid"`
+	Created time.Time `json:"created"`
+}
+
+// mkTempDir creates a temporary directory under root and marks it as owned by the current process
+func mkTempDir(root string, pattern string) (string, error) {
+	dir, err := os.MkdirTemp(root, pattern)
+	if err != nil {
+		return "", err
+	}
+
+	if err = writeLock(dir); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", err
+	}
+
+	return dir, nil
+}
+
+func writeLock(dir string) error {
+	content, err := json.Marshal(lockInfo{PID: os.Getpid(), Created: time.Now()})
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, lockFileName), content, 0o600)
+}
+
+func readLock(dir string) (lockInfo, error) {
+	lock := lockInfo{}
+
+	content, err := os.ReadFile(filepath.Join(dir, lockFileName)) //nolint:gosec
+	if err != nil {
+		return lock, err
+	}
+
+	err = json.Unmarshal(content, &lock)
+
+	return lock, err
+}
+
+// StaleDir is a temporary directory left by a previous build
+type StaleDir struct {
+	Path string `json:"path"`
+	// last modification of the directory
+	Modified time.Time `json:"modified"`
+	// size of the files in the directory in bytes
+	Size int64 `json:"size"`
+}
+
+// FindStale returns the temporary directories in the root directory not modified in the given age and
+// not owned by a running k6foundry process: the directories of processes that crashed or were killed,
+// and the directories kept by the cleanup strategy. Directories without a lock file are only considered
+// if their name is the name of a temporary directory created by k6foundry. A root directory that doesn't
+// exist has no stale directories.
+func FindStale(root string, age time.Duration) ([]StaleDir, error) {
+	entries, err := os.ReadDir(root)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading directory %w", err)
+	}
+
+	cutoff := time.Now().Add(-age)
+
+	stale := []StaleDir{}
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), tmpDirPrefix) {
+			continue
+		}
+
+		dir := filepath.Join(root, entry.Name())
+		lock, lockErr := readLock(dir)
+		switch {
+		case lockErr == nil && processAlive(lock.PID):
+			continue
+		case lockErr != nil && !tmpDirRegexp.MatchString(entry.Name()):
+			continue
+		}
+
+		info, infoErr := entry.Info()
+		if infoErr != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+
+		stale = append(stale, StaleDir{Path: dir, Modified: info.ModTime(), Size: diskUsage(dir)})
+	}
+
+	return stale, nil
+}
+
+// ReapStale removes the stale directories found by FindStale. Returns the directories removed
+func ReapStale(root string, age time.Duration) ([]StaleDir, error) {
+	stale, err := FindStale(root, age)
+	if err != nil {
+		return nil, err
+	}
+
+	reaped := []StaleDir{}
+	for _, dir := range stale {
+		if rmErr := removeAll(dir.Path); rmErr != nil {
+			err = errors.Join(err, rmErr)
+			continue
+		}
+
+		reaped = append(reaped, dir)
+	}
+
+	return reaped, err
+}
+
+// keepWorkDir returns if the temporary work directory of a build that finished with the error is kept
+func (b *nativeBuilder) keepWorkDir(buildErr error) bool {
+	switch {
+	case b.SkipCleanup || b.Cleanup == CleanupNever:
+		return true
+	case b.Cleanup == CleanupOnSuccess:
+		return buildErr != nil && !errors.Is(buildErr, context.Canceled)
+	default:
+		return false
+	}
+}
+
+// reapStale removes the stale directories in the build directory root, logging the result
+func (b *nativeBuilder) reapStale() {
+	reaped, err := ReapStale(b.buildDirRoot(), b.ReapStaleAfter)
+	if err != nil {
+		b.log.Warn(fmt.Sprintf("removing stale directories: %v", err))
+	}
+
+	for _, dir := range reaped {
+		b.log.Info(fmt.Sprintf("Removed stale directory %s", dir.Path))
+	}
+}
+
+// removeAll removes a directory tree, restoring write permissions before deletion.
+// This is needed for the go mod cache, which is read-only.
+func removeAll(dir string) error {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			return os.Chmod(path, 0o700) //nolint:gosec
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("removing %s: %w", dir, err)
+	}
+
+	return os.RemoveAll(dir)
+}
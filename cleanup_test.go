@@ -0,0 +1,134 @@
+package k6foundry
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// returns the pid of a process that has already finished
+func deadPID(t *testing.T) int {
+	t.Helper()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("running process %v", err)
+	}
+
+	return cmd.Process.Pid
+}
+
+func TestReapStale(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	t.Cleanup(func() { _ = removeAll(root) })
+
+	old := time.Now().Add(-48 * time.Hour)
+
+	// creates a directory in the root with the given lock and modification time
+	mkDir := func(name string, lock *lockInfo, modTime time.Time) string {
+		dir := filepath.Join(root, name)
+		if err := os.Mkdir(dir, 0o700); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+
+		if lock != nil {
+			content, _ := json.Marshal(lock)
+			if err := os.WriteFile(filepath.Join(dir, lockFileName), content, 0o600); err != nil {
+				t.Fatalf("setup %v", err)
+			}
+		}
+
+		if err := os.Chtimes(dir, modTime, modTime); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+
+		return dir
+	}
+
+	deadOwner := &lockInfo{PID: deadPID(t), Created: old}
+	liveOwner := &lockInfo{PID: os.Getpid(), Created: old}
+
+	crashed := mkDir(tmpDirPrefix+"123", deadOwner, old)
+
+	// the go mod cache is read-only
+	readOnly := filepath.Join(crashed, "modcache", "mod@v0.1.0")
+	if err := os.MkdirAll(readOnly, 0o700); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(readOnly, "go.mod"), []byte("module mod"), 0o400); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+	if err := os.Chmod(readOnly, 0o500); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+	if err := os.Chtimes(crashed, old, old); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+	kept := mkDir(tmpDirPrefix+"456", nil, old)
+	keptCache := mkDir(tmpDirPrefix+"-cache789", nil, old)
+	recent := mkDir(tmpDirPrefix+"111", nil, time.Now())
+	running := mkDir(tmpDirPrefix+"222", liveOwner, old)
+	other := mkDir(tmpDirPrefix+"-src", nil, old)
+
+	stale, err := FindStale(root, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if len(stale) != 3 {
+		t.Fatalf("expected 3 stale directories got %v", stale)
+	}
+
+	reaped, err := ReapStale(root, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if len(reaped) != 3 {
+		t.Fatalf("expected 3 directories removed got %v", reaped)
+	}
+
+	for _, dir := range []string{crashed, kept, keptCache} {
+		if _, err = os.Stat(dir); !os.IsNotExist(err) {
+			t.Fatalf("stale directory %s was not removed", dir)
+		}
+	}
+
+	for _, dir := range []string{recent, running, other} {
+		if _, err = os.Stat(dir); err != nil {
+			t.Fatalf("directory %s should not be removed: %v", dir, err)
+		}
+	}
+}
+
+func TestValidateCleanupStrategy(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		strategy  CleanupStrategy
+		expectErr error
+	}{
+		{strategy: ""},
+		{strategy: CleanupAlways},
+		{strategy: CleanupOnSuccess},
+		{strategy: CleanupNever},
+		{strategy: "sometimes", expectErr: ErrInvalidCleanupStrategy},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(string(tc.strategy), func(t *testing.T) {
+			t.Parallel()
+
+			if err := validateCleanupStrategy(tc.strategy); !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,365 @@
+// nolint:forbidigo,nolintlint
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/grafana/k6foundry"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// ErrInvalidBenchOptions is returned when the options of the bench command are not valid
+	ErrInvalidBenchOptions = errors.New("invalid bench options") //nolint:revive
+	// ErrPerformanceRegression is returned when a phase of the benchmark is slower than in the baseline
+	ErrPerformanceRegression = errors.New("performance regression") //nolint:revive
+)
+
+const benchLong = `
+benchmarks the builds, timing the resolution of the dependencies and the compilation of the binary
+with cold (empty) and warm go caches.
+
+Each scenario builds the binary the given number of iterations. The warm scenario uses caches
+populated by a build that is not timed. The result can be saved as JSON and used as baseline for
+detecting performance regressions: the command fails if the mean duration of any phase exceeds the
+baseline by more than the allowed regression.
+`
+
+const benchExample = `
+# benchmark the build of k6 v0.50.0 with xk6-kubernetes
+k6foundry bench -v v0.50.0 -d github.com/grafana/xk6-kubernetes
+
+# save a baseline and compare to it, allowing a regression of 10%
+k6foundry bench -v v0.50.0 --output-format json > baseline.json
+k6foundry bench -v v0.50.0 --baseline baseline.json --max-regression 0.1
+`
+
+const (
+	benchScenarioCold = "cold"
+	benchScenarioWarm = "warm"
+)
+
+// phases reported by the benchmark, in order
+var benchPhases = []k6foundry.Phase{ //nolint:gochecknoglobals
+	k6foundry.PhaseResolve,
+	k6foundry.PhaseCompile,
+	k6foundry.PhaseBuild,
+}
+
+// benchResult is the result of the bench command
+type benchResult struct {
+	K6Version  string          `json:"k6Version"`
+	Platform   string          `json:"platform"`
+	Iterations int             `json:"iterations"`
+	Scenarios  []benchScenario `json:"scenarios"`
+}
+
+// benchScenario is the duration of the phases of the builds of a scenario
+type benchScenario struct {
+	Name   string       `json:"name"`
+	Phases []benchPhase `json:"phases"`
+}
+
+// benchPhase summarizes the duration of a phase in the builds of a scenario
+type benchPhase struct {
+	Phase       k6foundry.Phase `json:"phase"`
+	MinSeconds  float64         `json:"minSeconds"`
+	MeanSeconds float64         `json:"meanSeconds"`
+	MaxSeconds  float64         `json:"maxSeconds"`
+}
+
+// benchMetrics collects the duration of the phases of the successful builds
+type benchMetrics struct {
+	mu        sync.Mutex
+	durations map[k6foundry.Phase][]time.Duration
+}
+
+func (m *benchMetrics) ObservePhase(phase k6foundry.Phase, _ string, duration time.Duration, errClass string) {
+	if errClass != "" {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.durations[phase] = append(m.durations[phase], duration)
+}
+
+func (m *benchMetrics) ObserveBinarySize(string, int64) {}
+
+func (m *benchMetrics) ObserveCacheLookup(bool) {}
+
+// NewBench returns a hidden command for benchmarking the builds
+func NewBench() *cobra.Command {
+	var (
+		opts          k6foundry.NativeBuilderOpts
+		deps          []string
+		k6Version     string
+		platformFlag  string
+		iterations    int
+		scenarios     []string
+		outputFormat  string
+		baselinePath  string
+		maxRegression float64
+	)
+
+	cmd := &cobra.Command{
+		Use:     "bench",
+		Short:   "benchmark the builds",
+		Long:    benchLong,
+		Example: benchExample,
+		Args:    cobra.NoArgs,
+		Hidden:  true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+
+			if outputFormat != outputFormatText && outputFormat != outputFormatJSON {
+				return fmt.Errorf("%w: %q", ErrInvalidOutputFormat, outputFormat)
+			}
+
+			if iterations < 1 {
+				return fmt.Errorf("%w: iterations must be at least 1", ErrInvalidBenchOptions)
+			}
+
+			if maxRegression < 0 {
+				return fmt.Errorf("%w: max regression can't be negative", ErrInvalidBenchOptions)
+			}
+
+			for _, name := range scenarios {
+				if name != benchScenarioCold && name != benchScenarioWarm {
+					return fmt.Errorf("%w: unknown scenario %q", ErrInvalidBenchOptions, name)
+				}
+			}
+
+			platform, err := k6foundry.ParsePlatform(platformFlag)
+			if err != nil {
+				return err
+			}
+
+			mods, err := parseModules(nil, nil, deps)
+			if err != nil {
+				return err
+			}
+
+			opts.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+			// all the iterations build the same version
+			k6Version, err = resolveK6Version(ctx, k6Version, opts, 0, false)
+			if err != nil {
+				return err
+			}
+
+			result := benchResult{K6Version: k6Version, Platform: platform.String(), Iterations: iterations}
+			for _, name := range scenarios {
+				var scenario benchScenario
+				scenario, err = runBenchScenario(ctx, name, opts, platform, k6Version, mods, iterations)
+				if err != nil {
+					return err
+				}
+				result.Scenarios = append(result.Scenarios, scenario)
+			}
+
+			if outputFormat == outputFormatJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+
+				if err = encoder.Encode(result); err != nil {
+					return err
+				}
+			} else {
+				writeBenchResult(os.Stdout, result)
+			}
+
+			if baselinePath == "" {
+				return nil
+			}
+
+			baseline, err := loadBenchResult(baselinePath)
+			if err != nil {
+				return err
+			}
+
+			return compareBenchResults(result, baseline, maxRegression)
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&deps, "dependency", "d", []string{}, "list of dependencies using go mod format:"+
+		" path[@version][replace@version]")
+	cmd.Flags().StringVarP(&k6Version, "k6-version", "v", "latest", "k6 version")
+	cmd.Flags().StringVarP(&platformFlag, "platform", "p", k6foundry.RuntimePlatform().String(), "target platform")
+	cmd.Flags().IntVarP(&iterations, "iterations", "n", 3, "number of builds timed in each scenario")
+	cmd.Flags().StringSliceVar(&scenarios, "scenario", []string{benchScenarioCold, benchScenarioWarm},
+		"scenarios benchmarked: cold (empty go caches) and warm (populated go caches)")
+	cmd.Flags().BoolVar(&opts.CopyGoEnv, "copy-go-env", true, "copy current go environment")
+	addGoDownloadFlags(cmd, &opts.GoOpts)
+	cmd.Flags().StringToStringVarP(&opts.Env, "env", "e", nil, "build environment variables")
+	cmd.Flags().StringVar(&opts.BuildDirRoot, "build-dir-root", "", "parent directory of the temporary work"+
+		" directories and caches (e.g. a tmpfs mount). Defaults to the system temporary directory")
+	cmd.Flags().StringVar(&outputFormat, "output-format", outputFormatText, "format of the output: text or json")
+	cmd.Flags().StringVar(&baselinePath, "baseline", "", "result of a previous benchmark (JSON) to compare to")
+	cmd.Flags().Float64Var(&maxRegression, "max-regression", 0.2, "maximum increase of the mean duration of"+
+		" any phase over the baseline, as a fraction (e.g. 0.2 for 20%)")
+
+	return cmd
+}
+
+// runBenchScenario times the builds of a scenario
+func runBenchScenario(
+	ctx context.Context,
+	name string,
+	opts k6foundry.NativeBuilderOpts,
+	platform k6foundry.Platform,
+	k6Version string,
+	mods []k6foundry.Module,
+	iterations int,
+) (benchScenario, error) {
+	metrics := &benchMetrics{durations: map[k6foundry.Phase][]time.Duration{}}
+
+	switch name {
+	case benchScenarioCold:
+		opts.TmpCache = true
+	case benchScenarioWarm:
+		cacheDir, err := os.MkdirTemp(opts.BuildDirRoot, "k6foundry-bench*")
+		if err != nil {
+			return benchScenario{}, err
+		}
+		defer removeCacheDir(cacheDir)
+
+		opts.TmpCache = false
+		opts.GoCacheDir = cacheDir
+	default:
+		return benchScenario{}, fmt.Errorf("%w: unknown scenario %q", ErrInvalidBenchOptions, name)
+	}
+
+	builder, err := k6foundry.NewNativeBuilder(ctx, opts)
+	if err != nil {
+		return benchScenario{}, err
+	}
+
+	// populates the caches without timing the build
+	if name == benchScenarioWarm {
+		if _, err = builder.Build(ctx, platform, k6Version, mods, nil, io.Discard); err != nil {
+			return benchScenario{}, err
+		}
+	}
+
+	opts.Metrics = metrics
+	builder, err = k6foundry.NewNativeBuilder(ctx, opts)
+	if err != nil {
+		return benchScenario{}, err
+	}
+
+	for range iterations {
+		if _, err = builder.Build(ctx, platform, k6Version, mods, nil, io.Discard); err != nil {
+			return benchScenario{}, err
+		}
+	}
+
+	scenario := benchScenario{Name: name}
+	for _, phase := range benchPhases {
+		scenario.Phases = append(scenario.Phases, summarizeDurations(phase, metrics.durations[phase]))
+	}
+
+	return scenario, nil
+}
+
+// removeCacheDir removes a go cache directory. The module cache is read-only, so the permissions
+// are restored before removing it
+func removeCacheDir(dir string) {
+	_ = filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			_ = os.Chmod(path, 0o700) //nolint:gosec
+		}
+		return nil
+	})
+
+	_ = os.RemoveAll(dir)
+}
+
+// summarizeDurations returns the min, mean and max of the durations of a phase
+func summarizeDurations(phase k6foundry.Phase, durations []time.Duration) benchPhase {
+	summary := benchPhase{Phase: phase}
+	if len(durations) == 0 {
+		return summary
+	}
+
+	var total time.Duration
+	minDuration, maxDuration := durations[0], durations[0]
+	for _, d := range durations {
+		total += d
+		minDuration = min(minDuration, d)
+		maxDuration = max(maxDuration, d)
+	}
+
+	summary.MinSeconds = minDuration.Seconds()
+	summary.MeanSeconds = (total / time.Duration(len(durations))).Seconds()
+	summary.MaxSeconds = maxDuration.Seconds()
+
+	return summary
+}
+
+// writeBenchResult writes the result of the benchmark in text format
+func writeBenchResult(out io.Writer, result benchResult) {
+	fmt.Fprintf(out, "k6 %s %s, %d iterations\n", result.K6Version, result.Platform, result.Iterations)
+	for _, scenario := range result.Scenarios {
+		fmt.Fprintf(out, "%s:\n", scenario.Name)
+		for _, phase := range scenario.Phases {
+			fmt.Fprintf(out, "  %-8s min %6.2fs  mean %6.2fs  max %6.2fs\n",
+				phase.Phase, phase.MinSeconds, phase.MeanSeconds, phase.MaxSeconds)
+		}
+	}
+}
+
+// loadBenchResult reads the JSON result of a previous benchmark
+func loadBenchResult(path string) (benchResult, error) {
+	content, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return benchResult{}, fmt.Errorf("reading baseline %w", err)
+	}
+
+	result := benchResult{}
+	if err = json.Unmarshal(content, &result); err != nil {
+		return benchResult{}, fmt.Errorf("parsing baseline %w", err)
+	}
+
+	return result, nil
+}
+
+// compareBenchResults returns an error if the mean duration of any phase of the result exceeds the
+// baseline by more than the max regression. Phases not in the baseline are ignored
+func compareBenchResults(result benchResult, baseline benchResult, maxRegression float64) error {
+	baselinePhases := map[string]benchPhase{}
+	for _, scenario := range baseline.Scenarios {
+		for _, phase := range scenario.Phases {
+			baselinePhases[scenario.Name+"/"+string(phase.Phase)] = phase
+		}
+	}
+
+	regressions := []error{}
+	for _, scenario := range result.Scenarios {
+		for _, phase := range scenario.Phases {
+			base, found := baselinePhases[scenario.Name+"/"+string(phase.Phase)]
+			if !found || base.MeanSeconds == 0 {
+				continue
+			}
+
+			if increase := phase.MeanSeconds/base.MeanSeconds - 1; increase > maxRegression {
+				regressions = append(regressions, fmt.Errorf("%w: %s %s mean %.2fs, baseline %.2fs (+%.0f%%)",
+					ErrPerformanceRegression, scenario.Name, phase.Phase, phase.MeanSeconds, base.MeanSeconds,
+					increase*100))
+			}
+		}
+	}
+
+	return errors.Join(regressions...)
+}
@@ -0,0 +1,269 @@
+// nolint:forbidigo,nolintlint
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/grafana/k6foundry"
+	"github.com/grafana/k6foundry/pkg/cache"
+	"github.com/grafana/k6foundry/pkg/util"
+
+	"github.com/spf13/cobra"
+)
+
+// ErrInvalidCacheOptions is returned when the options of a cache command are not valid
+var ErrInvalidCacheOptions = errors.New("invalid cache options") //nolint:revive
+
+const cacheLong = `
+manages the caches used by the builds: the go build and module caches in a cache directory
+(--go-cache-dir) and the cache of binaries (--cache-dir).
+
+The go caches default to the caches of the container builder. The caches of the go environment, used by
+the native builder when --go-cache-dir is not set, are managed by go (go clean -cache -modcache).
+
+Pruning and cleaning wait for the builds using the go caches to finish.
+`
+
+const cacheExample = `
+# show the size of the caches
+k6foundry cache info --go-cache-dir ~/.cache/k6foundry/go --cache-dir ~/.cache/k6foundry/binaries
+
+# remove the entries not used in the last week and keep the go caches under 10GB
+k6foundry cache prune --go-cache-dir ~/.cache/k6foundry/go --max-age 168h --max-size 10GB
+
+# remove all the binaries
+k6foundry cache clean --cache-dir ~/.cache/k6foundry/binaries --no-go-cache
+`
+
+// cacheDirs are the caches managed by the cache commands
+type cacheDirs struct {
+	goCacheDir string
+	cacheDir   string
+	noGoCache  bool
+}
+
+// goCache returns the directory of the go caches, or an empty string if they are not managed
+func (c cacheDirs) goCache() (string, error) {
+	if c.noGoCache {
+		return "", nil
+	}
+
+	if c.goCacheDir != "" {
+		return c.goCacheDir, nil
+	}
+
+	return k6foundry.DefaultContainerCacheDir()
+}
+
+// cacheInfo is the output of the info command
+type cacheInfo struct {
+	GoCache     *k6foundry.GoCacheInfo `json:"goCache,omitempty"`
+	BinaryCache *binaryCacheInfo       `json:"binaryCache,omitempty"`
+}
+
+type binaryCacheInfo struct {
+	Dir string `json:"dir"`
+	k6foundry.CacheStats
+}
+
+// NewCache returns a command for managing the caches
+func NewCache() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "cache",
+		Short:   "manage the build caches",
+		Long:    cacheLong,
+		Example: cacheExample,
+		Args:    cobra.NoArgs,
+	}
+
+	cmd.AddCommand(newCacheInfo())
+	cmd.AddCommand(newCachePrune())
+	cmd.AddCommand(newCacheClean())
+
+	return cmd
+}
+
+func addCacheDirFlags(cmd *cobra.Command, dirs *cacheDirs) {
+	cmd.Flags().StringVar(&dirs.goCacheDir, "go-cache-dir", "", "directory with the go module and build caches."+
+		" Defaults to the caches of the container builder")
+	cmd.Flags().StringVar(&dirs.cacheDir, "cache-dir", "", "directory with the cached binaries")
+	cmd.Flags().BoolVar(&dirs.noGoCache, "no-go-cache", false, "don't manage the go caches")
+}
+
+func newCacheInfo() *cobra.Command {
+	var (
+		dirs         cacheDirs
+		outputFormat string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "info",
+		Short: "show the entries and size of the caches",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if outputFormat != outputFormatText && outputFormat != outputFormatJSON {
+				return fmt.Errorf("%w: %q", ErrInvalidOutputFormat, outputFormat)
+			}
+
+			info := cacheInfo{}
+
+			goCacheDir, err := dirs.goCache()
+			if err != nil {
+				return err
+			}
+
+			if goCacheDir != "" {
+				var goCache k6foundry.GoCacheInfo
+				if goCache, err = k6foundry.InspectGoCache(cmd.Context(), goCacheDir); err != nil {
+					return err
+				}
+				info.GoCache = &goCache
+			}
+
+			if dirs.cacheDir != "" {
+				var stats k6foundry.CacheStats
+				if stats, err = cache.NewFileCache(dirs.cacheDir).Stats(); err != nil {
+					return err
+				}
+				info.BinaryCache = &binaryCacheInfo{Dir: dirs.cacheDir, CacheStats: stats}
+			}
+
+			if outputFormat == outputFormatJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+
+				return encoder.Encode(info)
+			}
+
+			writeCacheInfo(os.Stdout, info)
+
+			return nil
+		},
+	}
+
+	addCacheDirFlags(cmd, &dirs)
+	cmd.Flags().StringVar(&outputFormat, "output-format", outputFormatText, "format of the output: text or json")
+
+	return cmd
+}
+
+func newCachePrune() *cobra.Command {
+	var (
+		dirs    cacheDirs
+		maxAge  time.Duration
+		maxSize string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "remove the entries of the caches not recently used",
+		Long: `
+removes the entries of the caches not used in the last --max-age, and then the least recently used
+entries until each cache is at most --max-size.
+`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			size, err := util.ParseSize(maxSize)
+			if err != nil {
+				return err
+			}
+
+			if maxAge <= 0 && size <= 0 {
+				return fmt.Errorf("%w: --max-age or --max-size required", ErrInvalidCacheOptions)
+			}
+
+			goCacheDir, err := dirs.goCache()
+			if err != nil {
+				return err
+			}
+
+			if goCacheDir != "" {
+				var removed k6foundry.CacheStats
+				if removed, err = k6foundry.PruneGoCache(cmd.Context(), goCacheDir, maxAge, size); err != nil {
+					return err
+				}
+				writeRemoved(os.Stdout, goCacheDir, removed)
+			}
+
+			if dirs.cacheDir != "" {
+				var removed k6foundry.CacheStats
+				if removed, err = cache.NewFileCache(dirs.cacheDir).Prune(maxAge, size); err != nil {
+					return err
+				}
+				writeRemoved(os.Stdout, dirs.cacheDir, removed)
+			}
+
+			return nil
+		},
+	}
+
+	addCacheDirFlags(cmd, &dirs)
+	cmd.Flags().DurationVar(&maxAge, "max-age", 0, "remove the entries not used in this time (e.g. 168h)")
+	cmd.Flags().StringVar(&maxSize, "max-size", "", "maximum size of each cache (e.g. 10GB)."+
+		" The least recently used entries are removed")
+
+	return cmd
+}
+
+func newCacheClean() *cobra.Command {
+	var dirs cacheDirs
+
+	cmd := &cobra.Command{
+		Use:   "clean",
+		Short: "remove all the entries of the caches",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			goCacheDir, err := dirs.goCache()
+			if err != nil {
+				return err
+			}
+
+			if goCacheDir != "" {
+				if err = k6foundry.CleanGoCache(cmd.Context(), goCacheDir); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stdout, "%s: cleaned\n", goCacheDir)
+			}
+
+			if dirs.cacheDir != "" {
+				if err = cache.NewFileCache(dirs.cacheDir).Clean(); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stdout, "%s: cleaned\n", dirs.cacheDir)
+			}
+
+			return nil
+		},
+	}
+
+	addCacheDirFlags(cmd, &dirs)
+
+	return cmd
+}
+
+// writeCacheInfo writes the info of the caches in text format
+func writeCacheInfo(out io.Writer, info cacheInfo) {
+	if info.GoCache != nil {
+		fmt.Fprintf(out, "go caches: %s\n", info.GoCache.Dir)
+		writeCacheStats(out, "build cache", info.GoCache.BuildCache)
+		writeCacheStats(out, "module cache", info.GoCache.ModCache)
+	}
+
+	if info.BinaryCache != nil {
+		fmt.Fprintf(out, "binary cache: %s\n", info.BinaryCache.Dir)
+		writeCacheStats(out, "binaries", info.BinaryCache.CacheStats)
+	}
+}
+
+func writeCacheStats(out io.Writer, name string, stats k6foundry.CacheStats) {
+	fmt.Fprintf(out, "  %s: %d entries, %s\n", name, stats.Entries, util.FormatSize(stats.Size))
+}
+
+func writeRemoved(out io.Writer, dir string, removed k6foundry.CacheStats) {
+	fmt.Fprintf(out, "%s: removed %d entries, %s\n", dir, removed.Entries, util.FormatSize(removed.Size))
+}
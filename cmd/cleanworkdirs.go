@@ -0,0 +1,83 @@
+// nolint:forbidigo,nolintlint
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/grafana/k6foundry"
+	"github.com/grafana/k6foundry/pkg/util"
+
+	"github.com/spf13/cobra"
+)
+
+const cleanWorkDirsLong = `
+removes the stale directories left in the build directory root by previous builds: the work directories
+and temporary caches of builds that crashed or were killed, and the work directories kept by the
+--cleanup option.
+
+Only the directories not modified in --older-than are removed. Directories used by running builds are
+never removed.
+`
+
+const cleanWorkDirsExample = `
+# list the stale directories in the system temporary directory without removing them
+k6foundry clean-workdirs --dry-run
+
+# remove the directories not modified in the last hour from a build directory root
+k6foundry clean-workdirs --build-dir-root /mnt/builds --older-than 1h
+`
+
+// NewCleanWorkDirs returns a command for removing stale work directories
+func NewCleanWorkDirs() *cobra.Command {
+	var (
+		root      string
+		olderThan time.Duration
+		dryRun    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:     "clean-workdirs",
+		Short:   "remove stale work directories",
+		Long:    cleanWorkDirsLong,
+		Example: cleanWorkDirsExample,
+		Args:    cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if root == "" {
+				root = os.TempDir()
+			}
+
+			find := k6foundry.ReapStale
+			if dryRun {
+				find = k6foundry.FindStale
+			}
+
+			dirs, err := find(root, olderThan)
+
+			// the directories removed are reported even if others failed
+			freed := int64(0)
+			for _, dir := range dirs {
+				fmt.Fprintf(os.Stdout, "%s (%s, modified %s)\n", dir.Path, util.FormatSize(dir.Size),
+					dir.Modified.Format(time.RFC3339))
+				freed += dir.Size
+			}
+
+			action := "removed"
+			if dryRun {
+				action = "found"
+			}
+			fmt.Fprintf(os.Stdout, "%s %d stale directories, %s\n", action, len(dirs), util.FormatSize(freed))
+
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&root, "build-dir-root", "", "parent directory of the temporary work directories and"+
+		" caches. Defaults to the system temporary directory")
+	cmd.Flags().DurationVar(&olderThan, "older-than", 24*time.Hour, "remove the directories not modified in"+
+		" this time")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "list the stale directories without removing them")
+
+	return cmd
+}
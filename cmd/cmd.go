@@ -3,18 +3,48 @@
 package cmd
 
 import (
+	"cmp"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
 
+	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/grafana/k6foundry"
+	"github.com/grafana/k6foundry/pkg/cache"
+	"github.com/grafana/k6foundry/pkg/foundry"
+	"github.com/grafana/k6foundry/pkg/image"
+	"github.com/grafana/k6foundry/pkg/packaging"
+	"github.com/grafana/k6foundry/pkg/provenance"
+	"github.com/grafana/k6foundry/pkg/sbom"
+	"github.com/grafana/k6foundry/pkg/server"
+	"github.com/grafana/k6foundry/pkg/sign"
 	"github.com/grafana/k6foundry/pkg/util"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/mod/semver"
 )
 
-var ErrTargetPlatformUndefined = errors.New("target platform is required") //nolint:revive
+var (
+	ErrTargetPlatformUndefined = errors.New("target platform is required")                                         //nolint:revive
+	ErrInvalidBuilder          = errors.New("invalid builder")                                                     //nolint:revive
+	ErrMultiPlatformVendor     = errors.New("multiple platforms are not supported with --vendor or --from-vendor") //nolint:revive
+	ErrSignConflict            = errors.New("--sign-key and --sign-keyless are mutually exclusive")                //nolint:revive
+	ErrVendorConflict          = errors.New("--vendor and --from-vendor are mutually exclusive")                   //nolint:revive
+	ErrPackageVendor           = errors.New("--package is not supported with --vendor")                            //nolint:revive
+	ErrProvenanceVendor        = errors.New("--provenance is not supported with --vendor")                         //nolint:revive
+)
+
+// permissions of the vendor archives
+const vendorArchiveMode = 0o644
 
 const long = `
 builds a custom k6 binary with extensions.
@@ -22,9 +52,14 @@ builds a custom k6 binary with extensions.
 The extensions are specified using the go module format: path[@version][replace@version]
 
 The module's path must follow go conventions (e.g. github.com/my-module)
-If version is omitted, 'latest' is used.
-The replace path can be a mod path or a relative path (e.g. ../my-module).
-If a relative replacement path is specified, the replacement version cannot be specified.
+If version is omitted, 'latest' is used. The version can also be a branch or a commit (e.g. @main, @1a2b3c4),
+or a constraint resolved to the highest published version that satisfies it (e.g. "@>=v0.9.0 <v0.11.0", @~v0.9).
+The replace path can be a mod path or a local path: relative, absolute, in the home directory (e.g. ~/my-module)
+or a file:// URL. If a local replacement path is specified, the replacement version cannot be specified.
+
+If a catalog is specified, the extensions in the catalog can be referenced by name using the format
+name[@constraint] (e.g. kafka@>=v0.25.0). The highest version in the catalog that satisfies the
+constraint is used.
 `
 
 const example = `
@@ -38,32 +73,145 @@ k6foundry build -v v0.49.0 -d github.com/grafana/xk6-kubernetes \
 # build latest version of k6 with latest version of xk6-kubernetes v0.8.0
 k6foundry build -d github.com/grafana/xk6-kubernetes@v0.8.0
 
+# build latest version of k6 with the main branch of xk6-kubernetes
+k6foundry build -d github.com/grafana/xk6-kubernetes@main
+
 # build latest version of k6 and replace xk6-kubernetes with a local module
 k6foundry build -d github.com/grafana/xk6-kubernetes=../xk6-kubernetes
 
 # build k6 from a local repository
 k6foundry build -r ../k6
 
+# build k6 from a fork published as a go module
+k6foundry build -r github.com/my-org/k6@v0.51.0-custom
+
 # build k6 using a custom GOPROXY and force all modules from the proxy
 k6foundry build -e GOPROXY=http://localhost:8000 -e GONOPROXY=none
 
 # build k6 using a temporary go cache ignoring go mod cache and go cache
 k6foundry build --tmp-cache=true
+
+# build k6 and publish the binary to an S3 bucket
+k6foundry build -d github.com/grafana/xk6-kubernetes --publish s3://my-bucket/k6/custom
+
+# build k6 and publish the binary with its checksum to a Google Cloud Storage bucket
+GOOGLE_OAUTH_ACCESS_TOKEN=$(gcloud auth print-access-token) k6foundry build -d github.com/grafana/xk6-kubernetes --checksum --publish gs://my-bucket/k6/custom
+
+# build k6 in a container, without a local go toolchain
+k6foundry build --builder container -d github.com/grafana/xk6-kubernetes
+
+# build k6 showing the progress of the build
+k6foundry build --progress -d github.com/grafana/xk6-kubernetes
+
+# select the extensions, their versions and the platform in the terminal
+k6foundry build --interactive
+
+# build k6 reusing the binary from a previous build with the same versions, if available
+k6foundry build -v v0.50.0 -d github.com/grafana/xk6-kubernetes@v0.9.0 --cache-dir ~/.cache/k6foundry/binaries
+
+# build k6 with the highest version of kafka below v1.0.0 listed in a catalog
+k6foundry build --catalog catalog.json -d "kafka@<v1.0.0"
+
+# build k6 recording the resolved modules in a lock file. Following builds fail if the resolution differs
+k6foundry build -d github.com/grafana/xk6-kubernetes --lock k6foundry.lock
+
+# cross compile k6 with an extension that requires cgo, using zig as C compiler
+k6foundry build -p linux/arm64 -d github.com/grafana/xk6-sql --zig
+
+# build k6 and write a CycloneDX SBOM to k6.cdx.json
+k6foundry build -d github.com/grafana/xk6-kubernetes --sbom cyclonedx
+
+# build k6 and sign it with a private key, writing the signature to k6.sig
+k6foundry build -d github.com/grafana/xk6-kubernetes --sign-key cosign.key
+
+# build k6 for macOS, signing it with codesign and notarizing it (requires macOS)
+k6foundry build -p darwin/arm64 -d github.com/grafana/xk6-kubernetes --codesign-identity "Developer ID Application: My Org" --notary-profile k6foundry
+
+# build k6 printing the result as JSON (binary, checksum, resolved versions, go version and duration)
+k6foundry build -d github.com/grafana/xk6-kubernetes --output-format json
+
+# build k6 for linux/amd64 and package it as a tar.gz and a deb including the license
+k6foundry build -p linux/amd64 -d github.com/grafana/xk6-kubernetes --package tar.gz --package deb --package-file LICENSE
+
+# build k6 and push a container image with it, based on alpine
+k6foundry build -d github.com/grafana/xk6-kubernetes --output-type docker -o myrepo/k6:custom --push
+
+# build k6 and write a SLSA provenance attestation to k6.intoto.json
+k6foundry build -d github.com/grafana/xk6-kubernetes --provenance
+
+# build all the targets in a manifest, two at a time
+k6foundry build -f manifest.yaml --parallel 2
+
+# build the release of k6 before the latest one
+k6foundry build -v latest-1 -d github.com/grafana/xk6-kubernetes
+
+# build k6 for multiple platforms. Generates k6-linux-amd64 and k6-darwin-arm64
+k6foundry build -p linux/amd64 -p darwin/arm64 -d github.com/grafana/xk6-kubernetes
+
+# rebuild k6 each time the sources of a local extension change
+k6foundry build -d github.com/grafana/xk6-kubernetes=../xk6-kubernetes --watch
+
+# export the build environment with the vendored dependencies and build from it without network access
+k6foundry build --vendor -d github.com/grafana/xk6-kubernetes -o k6-vendor.tar.gz
+k6foundry build --from-vendor k6-vendor.tar.gz -p linux/arm64
 `
 
+// postBuildFunc processes a binary after it is built
+type postBuildFunc func(path string, info *k6foundry.BuildInfo, params provenance.Parameters) error
+
 // New creates new cobra command for build command.
 func New() *cobra.Command {
 	var (
-		opts         k6foundry.NativeBuilderOpts
-		deps         []string
-		k6Version    string
-		k6Repo       string
-		platformFlag string
-		outPath      string
-		buildOpts    []string
-		verbose      bool
-		logLevelText string
-		listVersions bool
+		preset          string
+		opts            k6foundry.NativeBuilderOpts
+		deps            []string
+		graph           graphFlags
+		hooks           hookFlags
+		k6Version       string
+		k6Repo          string
+		platformFlags   []string
+		outPath         string
+		outputModeText  string
+		buildOpts       []string
+		profiles        []string
+		profilesFile    string
+		verbose         bool
+		logLevelText    string
+		maxCacheSize    string
+		minFreeSpace    string
+		listVersions    bool
+		showProgress    bool
+		publishTo       []string
+		builderType     string
+		containerOpts   k6foundry.ContainerBuilderOpts
+		serverURL       string
+		vendor          bool
+		fromVendor      string
+		cacheDir        string
+		catalogPath     string
+		sbomFormat      string
+		signKey         string
+		signKeyless     bool
+		manifestPath    string
+		parallel        int
+		dryRun          bool
+		mainTemplate    string
+		outputFormat    string
+		checksum        bool
+		packages        []string
+		packageOpts     packaging.Options
+		outputType      string
+		provenanceOut   bool
+		builderID       string
+		imgOpts         imageOpts
+		netrcFile       string
+		watch           bool
+		resolveCacheTTL time.Duration
+		resolveCache    bool
+		noResolveCache  bool
+		interactive     bool
+		checkExtensions bool
+		registryURL     string
 	)
 
 	cmd := &cobra.Command{
@@ -73,23 +221,119 @@ func New() *cobra.Command {
 		Example: example,
 		RunE: func(cmd *cobra.Command, _ []string) error {
 			ctx := cmd.Context()
+			report := newBuildReport()
 
-			var err error
-			platform := k6foundry.RuntimePlatform()
-			if platformFlag != "" {
-				platform, err = k6foundry.ParsePlatform(platformFlag)
+			if outputFormat != outputFormatText && outputFormat != outputFormatJSON {
+				return fmt.Errorf("%w: %q", ErrInvalidOutputFormat, outputFormat)
+			}
+
+			if outputType != outputTypeBinary && outputType != outputTypeDocker {
+				return fmt.Errorf("%w: %q", ErrInvalidOutputType, outputType)
+			}
+
+			for _, format := range packages {
+				if !slices.Contains(packaging.Formats(), format) {
+					return fmt.Errorf("%w: %q", packaging.ErrUnsupportedFormat, format)
+				}
+			}
+
+			if vendor && len(packages) > 0 {
+				return ErrPackageVendor
+			}
+
+			if vendor && provenanceOut {
+				return ErrProvenanceVendor
+			}
+
+			// the extensions, their versions and the platform are selected in the terminal
+			if interactive {
+				sel, err2 := runInteractive(catalogPath, k6Version)
+				if err2 != nil {
+					return err2
+				}
+
+				deps = append(deps, sel.deps...)
+				k6Version = sel.k6Version
+				platformFlags = []string{sel.platform}
+				showProgress = true
+			}
+
+			platforms, err := k6foundry.ParsePlatforms(platformFlags...)
+			if err != nil {
+				return err
+			}
+
+			// the binaries for the image are built in a temporary directory
+			var imageRef name.Reference
+			if outputType == outputTypeDocker {
+				if vendor || manifestPath != "" {
+					return ErrDockerOutput
+				}
+
+				imageRef, err = image.ParseReference(outPath, imgOpts.Insecure)
 				if err != nil {
 					return err
 				}
-			}
 
-			mods := []k6foundry.Module{}
-			for _, d := range deps {
-				mod, err2 := k6foundry.ParseModule(d)
+				binDir, err2 := os.MkdirTemp("", "k6foundry-image*")
 				if err2 != nil {
 					return err2
 				}
-				mods = append(mods, mod)
+				defer os.RemoveAll(binDir) //nolint:errcheck
+
+				outPath = filepath.Join(binDir, "k6")
+
+				// images are linux images
+				if len(platforms) == 0 {
+					platforms = append(platforms, k6foundry.Platform{OS: "linux", Arch: k6foundry.RuntimePlatform().Arch})
+				}
+
+				// the binary must not depend on the C library of the base image
+				if _, explicit := opts.Env["CGO_ENABLED"]; !explicit && opts.CC == "" && !opts.Zig {
+					if opts.Env == nil {
+						opts.Env = map[string]string{}
+					}
+					opts.Env["CGO_ENABLED"] = "0"
+				}
+			}
+
+			platform := k6foundry.RuntimePlatform()
+			if len(platforms) > 0 {
+				platform = platforms[0]
+			}
+
+			// the default binary name has the extension of the platform (k6.exe for windows)
+			if !cmd.Flags().Changed("output") && !vendor && outputType == outputTypeBinary {
+				outPath = platform.BinaryName(outPath)
+			}
+
+			var catalog k6foundry.Catalog
+			if catalogPath != "" {
+				catalog, err = k6foundry.LoadCatalog(catalogPath)
+				if err != nil {
+					return err
+				}
+			}
+
+			profile, err := loadProfileSettings(profilesFile, profiles)
+			if err != nil {
+				return err
+			}
+
+			mods, err := parseModules(catalog, profile.deps, deps)
+			if err != nil {
+				return err
+			}
+
+			if checkExtensions {
+				if err = checkRegistry(ctx, registryURL, mods); err != nil {
+					return err
+				}
+			}
+
+			buildOpts = append(profile.buildOpts, buildOpts...)
+			if profile.k6Version != "" && !cmd.Flags().Changed("k6-version") {
+				k6Version = profile.k6Version
 			}
 
 			// set builder's output
@@ -104,9 +348,39 @@ func New() *cobra.Command {
 				return fmt.Errorf("parsing log level %w", err)
 			}
 
+			if err = parseDiskLimits(&opts.GoOpts, maxCacheSize, minFreeSpace); err != nil {
+				return err
+			}
+
+			if err = graph.apply(&opts); err != nil {
+				return err
+			}
+
+			if opts.PostBuildHooks, err = hooks.hooks(); err != nil {
+				return err
+			}
+
+			outputMode, err := util.ParseFileMode(outputModeText)
+			if err != nil {
+				return err
+			}
+			opts.OutputMode = outputMode
+
+			logOut := io.Writer(os.Stderr)
+
+			// the progress display replaces the logs. Fallback to logs if the output is not interactive
+			if showProgress && !verbose && isTerminal(os.Stderr) {
+				p := newProgress(os.Stderr)
+				p.Start()
+				defer p.Stop()
+
+				opts.OnEvent = p.Handle
+				logOut = io.Discard
+			}
+
 			log := slog.New(
 				slog.NewTextHandler(
-					os.Stderr,
+					logOut,
 					&slog.HandlerOptions{
 						Level: logLevel,
 					},
@@ -114,25 +388,237 @@ func New() *cobra.Command {
 			)
 
 			opts.Logger = log
-			opts.K6Repo = k6Repo
+			// with --verbose the output of go is written as is
+			opts.LogGoOutput = !verbose
+			opts.ListDependencies = sbomFormat != ""
+			opts.Checksum = checksum
+			opts.K6Repo, opts.K6RepoVersion = splitK6Repo(k6Repo)
+
+			if mainTemplate != "" {
+				opts.MainTemplate, err = template.ParseFiles(mainTemplate)
+				if err != nil {
+					return fmt.Errorf("parsing main template %w", err)
+				}
+			}
 
-			b, err := k6foundry.NewNativeBuilder(ctx, opts)
+			if netrcFile != "" {
+				var netrc []byte
+				netrc, err = os.ReadFile(netrcFile) //nolint:gosec
+				if err != nil {
+					return fmt.Errorf("reading netrc %w", err)
+				}
+				opts.Netrc = string(netrc)
+			}
+
+			opts, err = k6foundry.ApplyPreset(opts, k6foundry.Preset(preset))
 			if err != nil {
 				return err
 			}
 
-			// TODO: check file permissions
-			outFile, err := os.OpenFile(outPath, os.O_WRONLY|os.O_CREATE, 0o777) //nolint:gosec
+			k6Version, err = resolveK6Version(ctx, k6Version, opts, resolveCacheTTL, resolveCache && !noResolveCache)
+			if err != nil {
+				return err
+			}
+
+			// parameters of the build recorded in the provenance
+			params := provenance.Parameters{
+				K6Version:    k6Version,
+				Dependencies: mods,
+				BuildOpts:    buildOpts,
+				StartedOn:    report.start,
+			}
+
+			// create the signer before building, to fail early if it is not valid
+			signer, err := newSigner(signKey, signKeyless)
 			if err != nil {
 				return err
 			}
 
-			defer outFile.Close() //nolint:errcheck
-			buildInfo, err := b.Build(ctx, platform, k6Version, mods, buildOpts, outFile)
+			var b k6foundry.Builder
+			switch builderType {
+			case "native":
+				b, err = k6foundry.NewNativeBuilder(ctx, opts)
+			case "container":
+				containerOpts.NativeBuilderOpts = opts
+				b, err = k6foundry.NewContainerBuilder(ctx, containerOpts)
+			case "remote":
+				b, err = server.NewRemoteBuilder(serverURL, server.RemoteBuilderOpts{})
+			default:
+				err = fmt.Errorf("%w: %q", ErrInvalidBuilder, builderType)
+			}
 			if err != nil {
 				return err
 			}
 
+			// the cached binaries are identified by the configuration of the builder
+			var cacheConfig string
+			if cacheDir != "" && opts.Cacheable() {
+				cacheConfig, err = builderConfig(builderType, opts, containerOpts, serverURL)
+				if err != nil {
+					return err
+				}
+			}
+
+			// outputOpts defines the files generated for each binary and where the binaries are published
+			outputOpts := foundry.OutputOptions{
+				SBOMFormat:     sbomFormat,
+				Checksum:       checksum,
+				Provenance:     provenanceOut,
+				BuilderID:      builderID,
+				Signer:         signer,
+				Packages:       packages,
+				PackageOptions: packageOpts,
+				PublishTo:      publishTo,
+			}
+
+			// postBuild generates the SBOM, the checksum and the provenance, signs, packages and publishes a binary
+			postBuild := func(path string, info *k6foundry.BuildInfo, params provenance.Parameters) error {
+				// builders that don't write the file themselves (e.g. cached builds) use the default permissions
+				if !vendor {
+					if err := os.Chmod(path, outputMode); err != nil {
+						return fmt.Errorf("setting output permissions %w", err)
+					}
+				}
+
+				if _, err := foundry.Process(ctx, path, info, params, outputOpts); err != nil {
+					return err
+				}
+
+				if outputFormat == outputFormatJSON {
+					return report.add(path, info)
+				}
+
+				return nil
+			}
+
+			// outputImage adds the binaries to a container image, if the output type is docker
+			outputImage := func(binaries []image.Binary) error {
+				if outputType != outputTypeDocker {
+					return nil
+				}
+
+				digest, err := writeImage(ctx, imageRef, binaries, imgOpts)
+				if err != nil {
+					return err
+				}
+
+				log.Info("image created", "image", imageRef.Name(), "digest", digest, "pushed", imgOpts.push)
+				report.Image = &imageResult{Reference: imageRef.Name(), Digest: digest}
+
+				return nil
+			}
+
+			if dryRun {
+				return printResolution(ctx, b, k6Version, mods, os.Stdout)
+			}
+
+			if watch {
+				if manifestPath != "" || vendor || fromVendor != "" || len(platforms) > 1 || outputType != outputTypeBinary {
+					return ErrWatchConflict
+				}
+
+				return watchBuild(ctx, b, platform, k6Version, mods, buildOpts, outPath, outputMode, log)
+			}
+
+			if manifestPath != "" {
+				// binaries that depend on local modules or branches can't be cached (see Cacheable)
+				if cacheDir != "" && opts.Cacheable() {
+					cacheOpts := cache.CachedBuilderOpts{Config: cacheConfig}
+					b = cache.NewCachedBuilderWithOpts(b, cache.NewFileCache(cacheDir), cacheOpts)
+				}
+
+				// the status of the targets is not part of the machine-readable output
+				status := io.Writer(os.Stdout)
+				if outputFormat == outputFormatJSON {
+					status = os.Stderr
+				}
+
+				err = buildManifest(ctx, b, catalog, manifestPath, parallel, status, postBuild)
+				if err != nil || outputFormat != outputFormatJSON {
+					return err
+				}
+
+				return report.write(os.Stdout)
+			}
+
+			if len(platforms) > 1 {
+				if vendor || fromVendor != "" {
+					return ErrMultiPlatformVendor
+				}
+
+				buildInfos, err2 := buildMultiPlatform(ctx, b, platforms, k6Version, mods, buildOpts, outPath, outputMode)
+				if err2 != nil {
+					return err2
+				}
+
+				binaries := []image.Binary{}
+				for i, info := range buildInfos {
+					path := platformOutPath(outPath, platforms[i])
+					if err = postBuild(path, info, params); err != nil {
+						return err
+					}
+					binaries = append(binaries, image.Binary{Path: path, Info: info})
+				}
+
+				if err = outputImage(binaries); err != nil {
+					return err
+				}
+
+				if outputFormat == outputFormatJSON {
+					return report.write(os.Stdout)
+				}
+
+				if listVersions {
+					for m, v := range buildInfos[0].ModVersions {
+						fmt.Printf("%s: %s\n", m, v)
+					}
+				}
+
+				return nil
+			}
+
+			// the output is removed if the build fails, so no partial binary is left behind (e.g. build interrupted)
+			var buildInfo *k6foundry.BuildInfo
+			switch {
+			case vendor && fromVendor != "":
+				err = ErrVendorConflict
+			case vendor:
+				vb, ok := b.(k6foundry.VendorBuilder)
+				if !ok {
+					return fmt.Errorf("%w: vendoring not supported", ErrInvalidBuilder)
+				}
+				buildInfo, err = writeOutput(outPath, vendorArchiveMode, func(out io.Writer) (*k6foundry.BuildInfo, error) {
+					return vb.Vendor(ctx, k6Version, mods, out)
+				})
+			case fromVendor != "":
+				buildInfo, err = writeOutput(outPath, outputMode, func(out io.Writer) (*k6foundry.BuildInfo, error) {
+					return buildFromVendor(ctx, b, platform, fromVendor, buildOpts, out)
+				})
+			default:
+				// binaries that depend on local modules or branches can't be cached (see Cacheable)
+				if cacheDir != "" && opts.Cacheable() {
+					cacheOpts := cache.CachedBuilderOpts{Config: cacheConfig}
+					b = cache.NewCachedBuilderWithOpts(b, cache.NewFileCache(cacheDir), cacheOpts)
+				}
+				// the binary is moved to the output if the builder supports it
+				buildInfo, err = k6foundry.BuildToFile(ctx, b, platform, k6Version, mods, buildOpts, outPath)
+			}
+			if err != nil {
+				return err
+			}
+
+			if err = postBuild(outPath, buildInfo, params); err != nil {
+				return err
+			}
+
+			if err = outputImage([]image.Binary{{Path: outPath, Info: buildInfo}}); err != nil {
+				return err
+			}
+
+			if outputFormat == outputFormatJSON {
+				return report.write(os.Stdout)
+			}
+
 			if listVersions {
 				for m, v := range buildInfo.ModVersions {
 					fmt.Printf("%s: %s\n", m, v)
@@ -150,18 +636,556 @@ func New() *cobra.Command {
 		[]string{},
 		"list of dependencies using go mod format: path[@version][replace@version]",
 	)
-	cmd.Flags().StringVarP(&k6Version, "k6-version", "v", "latest", "k6 version")
-	cmd.Flags().StringVarP(&k6Repo, "k6-repository", "r", "", "k6 repository")
-	cmd.Flags().StringVarP(&platformFlag, "platform", "p", "", "target platform in the format os/arch")
-	cmd.Flags().StringVarP(&outPath, "output", "o", "k6", "path to output file")
+	cmd.Flags().StringVarP(&manifestPath, "manifest", "f", "", "manifest (YAML or JSON) with multiple build targets."+
+		" k6 version, dependencies, platform, output and build options flags are ignored")
+	cmd.Flags().IntVar(&parallel, "parallel", 1, "number of manifest targets built in parallel")
+	addProfileFlags(cmd, &profiles, &profilesFile)
+	addRegistryFlags(cmd, &checkExtensions, &registryURL)
+	cmd.Flags().StringVar(&catalogPath, "catalog", "", "catalog (JSON or YAML) mapping dependency names to modules."+
+		" Dependencies in the catalog can be specified as name[@constraint]")
+	cmd.Flags().StringVarP(&k6Version, "k6-version", "v", "latest", "k6 version."+
+		" Can be a version, latest, latest-N (e.g. latest-1) or a constraint (e.g. ~v0.50.0)")
+	cmd.Flags().BoolVar(&resolveCache, "resolve-cache", false, "reuse the versions resolved for latest and other"+
+		" version specifications in subsequent builds. latest is resolved querying the module proxy instead of go")
+	cmd.Flags().DurationVar(&resolveCacheTTL, "resolve-cache-ttl", k6foundry.DefaultResolveCacheTTL, "time the"+
+		" resolved versions are reused with --resolve-cache")
+	cmd.Flags().BoolVar(&noResolveCache, "no-resolve-cache", false, "don't reuse resolved versions, even with"+
+		" --resolve-cache. latest is resolved by go")
+	cmd.Flags().StringVarP(&k6Repo, "k6-repository", "r", "", "k6 repository. A local directory or"+
+		" the module path of a fork with its version (e.g. github.com/my-org/k6@v0.51.0-custom)")
+	cmd.Flags().StringSliceVarP(&platformFlags, "platform", "p", []string{}, "target platform in the format os/arch."+
+		" Can be repeated for building for multiple platforms. The platform is added as suffix to the output."+
+		" Can include a variant (e.g. linux/arm/v7). The os and the arch can be * for matching all the supported"+
+		" platforms (e.g. linux/* or */arm64)")
+	cmd.Flags().StringVarP(&outPath, "output", "o", "k6", "path to output file (k6.exe by default for windows)."+
+		" With --output-type docker, the image reference (e.g. myrepo/k6:custom)")
+	cmd.Flags().StringVar(&outputModeText, "output-mode", "0755", "permissions of the output binaries, in octal")
+	cmd.Flags().StringVar(&outputType, "output-type", outputTypeBinary, "type of output: binary or docker."+
+		" docker creates a container image with the binary")
+	cmd.Flags().StringVar(&imgOpts.BaseImage, "base-image", image.DefaultBaseImage, "base image for the docker output."+
+		" Use scratch for an image with only the binary")
+	cmd.Flags().BoolVar(&imgOpts.push, "push", false, "push the image to its registry."+
+		" Otherwise, the image is written as an archive that can be loaded with docker load")
+	cmd.Flags().StringVar(&imgOpts.archive, "image-archive", "", "path of the image archive."+
+		" Defaults to the name of the image repository with the .tar extension (e.g. k6.tar)")
+	cmd.Flags().BoolVar(&imgOpts.Insecure, "plain-http", false, "use http instead of https for the registries of the"+
+		" image and the base image")
 	cmd.Flags().BoolVar(&opts.CopyGoEnv, "copy-go-env", true, "copy current go environment")
+	addGoDownloadFlags(cmd, &opts.GoOpts)
 	cmd.Flags().StringVar(&logLevelText, "log-level", "INFO", "log level")
 	cmd.Flags().BoolVar(&verbose, "verbose", false, "verbose build output")
 	cmd.Flags().StringArrayVarP(&buildOpts, "build-opts", "b", []string{}, "go build opts. e.g. -ldflags='-w -s'")
+	addCompileFlags(cmd, &opts.GoOpts)
+	cmd.Flags().StringVar(&preset, "preset", "", "build preset: debug (keep the symbols and disable the optimizations),"+
+		" release (strip the symbols and debug information and trim the paths) or race (enable the race detector)")
 	cmd.Flags().StringToStringVarP(&opts.Env, "env", "e", nil, "build environment variables")
 	cmd.Flags().BoolVarP(&opts.TmpCache, "tmp-cache", "t", false, "use a temporary go cache."+
 		"Forces downloading all dependencies.")
+	cmd.Flags().StringVar(&opts.GoCacheDir, "go-cache-dir", "", "directory for the go module and build caches."+
+		" Access is coordinated between concurrent builds. Defaults to the caches of the go environment")
+	cmd.Flags().StringVar(&opts.BuildDirRoot, "build-dir-root", "", "parent directory of the temporary work"+
+		" directories and caches (e.g. a tmpfs mount). Defaults to the system temporary directory")
+	cmd.Flags().StringVar((*string)(&opts.Cleanup), "cleanup", "", "when the temporary work directory is removed:"+
+		" always (default), on-success (keeps the failed builds) or never")
+	cmd.Flags().DurationVar(&opts.ReapStaleAfter, "reap-stale-after", 0, "on start, remove the directories left in"+
+		" the build directory root by previous builds not modified in this time (e.g. 24h)")
+	cmd.Flags().BoolVar(&opts.DetectLicenses, "licenses", false, "detect the license of each module included in"+
+		" the binary and report it in the build info and SBOM")
+	cmd.Flags().StringArrayVar(&opts.DeniedLicenses, "deny-license", []string{}, "fail if a module has this"+
+		" license (SPDX identifier, e.g. GPL-3.0). Also denies its variants (e.g. GPL-3.0-only)")
+	cmd.Flags().BoolVar(&opts.Offline, "offline", false, "build without network access, using only the"+
+		" modules in the go module cache")
+	cmd.Flags().StringVar(&maxCacheSize, "max-cache-size", "", "maximum size of the caches in --go-cache-dir"+
+		" (e.g. 10GB). The least recently used entries are removed after each build")
+	cmd.Flags().StringVar(&minFreeSpace, "min-free-space", "", "minimum free disk space in the work and cache"+
+		" directories required for building (e.g. 1GB)")
+	cmd.Flags().StringVar(&opts.GoVersion, "go-version", "", "go toolchain version used for building (e.g. 1.22.5)."+
+		" Downloaded if it is not the installed one")
+	cmd.Flags().IntVar(&opts.GetRetries, "get-retries", 0, "number of retries when downloading modules fails"+
+		" due to a transient network failure")
+	cmd.Flags().DurationVar(&opts.GetRetryBackoff, "get-retry-backoff", time.Second, "delay before the first retry."+
+		" Doubles on each retry")
+	cmd.Flags().DurationVar(&opts.GoGetTimeout, "get-timeout", k6foundry.DefaultGoGetTimeout, "timeout for each go"+
+		" command that downloads modules. A negative value disables it")
+	cmd.Flags().DurationVar(&opts.GOBuildTimeout, "build-timeout", k6foundry.DefaultGoBuildTimeout, "timeout for"+
+		" compiling the binary. A negative value disables it")
+	cmd.Flags().StringVar(&opts.CC, "cc", "", "C compiler used for cgo. Enables cgo when cross compiling")
+	cmd.Flags().StringVar(&opts.CXX, "cxx", "", "C++ compiler used for cgo")
+	cmd.Flags().BoolVar(&opts.Zig, "zig", false, "use zig as C/C++ cross compiler for cgo")
+	cmd.Flags().StringVar(&opts.WorkDir, "work-dir", "", "directory used for the build environment instead of a"+
+		" temporary one. It is kept after the build")
+	cmd.Flags().BoolVar(&opts.ReuseWorkDir, "reuse-work-dir", false, "reuse the build environment prepared in"+
+		" --work-dir by a previous build with the same k6 version and extensions, skipping the dependency resolution")
+	cmd.Flags().BoolVar(&opts.Hermetic, "hermetic", false, "run go and git with an isolated home directory,"+
+		" ignoring the git configuration, netrc and go environment file of the host")
+	cmd.Flags().StringVar(&opts.GitSSHKey, "git-ssh-key", "", "private ssh key used by git for downloading"+
+		" private modules")
+	cmd.Flags().StringVar(&opts.GitKnownHosts, "git-known-hosts", "", "known_hosts file for verifying the ssh"+
+		" hosts. If not set, unknown hosts are accepted on first use")
+	cmd.Flags().StringArrayVar(&opts.GitSSHHosts, "git-ssh-host", []string{}, "host accessed using ssh instead"+
+		" of https (e.g. github.com). Requires --git-ssh-key")
+	cmd.Flags().StringVar(&netrcFile, "netrc", "", "netrc file with the credentials for downloading private"+
+		" modules using https")
+	cmd.Flags().StringVar(&opts.GitCredentialHelper, "git-credential-helper", "", "git credential helper for"+
+		" downloading private modules using https (e.g. 'store --file=/path/to/credentials')")
+	cmd.Flags().StringSliceVar(&opts.GoFlags, "goflags", []string{}, "flags passed to every go command (GOFLAGS),"+
+		" e.g. -mod=mod")
+	cmd.Flags().StringVar(&opts.VCS, "govcs", "", "version control systems allowed for downloading modules"+
+		" (GOVCS), e.g. '*:git'")
+	cmd.Flags().StringVar(&opts.SumDB, "gosumdb", "", "checksum database used for verifying the modules (GOSUMDB)."+
+		" 'off' disables the verification")
+	cmd.Flags().StringSliceVar(&opts.NoSumDB, "gonosumdb", []string{}, "module path patterns not verified"+
+		" using the checksum database (GONOSUMDB)")
+	cmd.Flags().BoolVar(&opts.RequireSumDB, "require-sumdb", false, "fail if the checksum database verification"+
+		" is disabled for any module, including by the go environment")
 	cmd.Flags().BoolVar(&listVersions, "list-versions", false, "list built versions")
+	cmd.Flags().StringVar(&outputFormat, "output-format", outputFormatText, "format of the build result: text or json."+
+		" json prints the binaries, their checksums, resolved versions, go version and build duration")
+	cmd.Flags().StringVar(&builderType, "builder", "native", "builder used for building: native, container or"+
+		" remote (a build service started with serve)")
+	cmd.Flags().StringVar(&serverURL, "server-url", "", "URL of the build service used by the remote builder"+
+		" (e.g. http://localhost:8000)")
+	cmd.Flags().StringVar(
+		&containerOpts.Engine,
+		"container-engine",
+		k6foundry.DefaultContainerEngine,
+		"container engine used by the container builder (docker or podman)",
+	)
+	cmd.Flags().StringVar(
+		&containerOpts.Image,
+		"container-image",
+		k6foundry.DefaultContainerImage,
+		"golang image used by the container builder",
+	)
+	cmd.Flags().StringArrayVar(&publishTo, "publish", []string{}, "publish the binary, its build info and"+
+		" the files generated for it to the target. Supported targets: directory, file://, http(s)://, s3://, gs://, oci://")
+	cmd.Flags().BoolVar(&showProgress, "progress", false, "show build progress instead of logs in interactive terminals")
+	cmd.Flags().BoolVar(&interactive, "interactive", false, "select the extensions, their versions and the platform"+
+		" in the terminal from the catalog (the built-in catalog if --catalog is not given) and show the build progress")
+	cmd.Flags().StringVar(&sbomFormat, "sbom", "", "write a SBOM next to the binary. Formats: "+
+		strings.Join(sbom.Formats(), ", "))
+	cmd.Flags().BoolVar(&checksum, "checksum", false, "write the SHA256 checksum of the binary next to it"+
+		" (<binary>.sha256)")
+	cmd.Flags().StringSliceVar(&packages, "package", []string{}, "package the binary next to it."+
+		" Formats: tar.gz, zip, deb, rpm (deb and rpm only for linux)")
+	cmd.Flags().StringArrayVar(&packageOpts.Files, "package-file", []string{}, "file included in the packages"+
+		" (e.g. LICENSE)")
+	cmd.Flags().BoolVar(&packageOpts.IncludeBuildInfo, "package-build-info", false, "include the build info"+
+		" (resolved versions) in the packages as buildinfo.json")
+	cmd.Flags().BoolVar(&provenanceOut, "provenance", false, "write a SLSA provenance attestation (in-toto statement)"+
+		" describing the build next to the binary (<binary>.intoto.json)")
+	cmd.Flags().StringVar(&builderID, "builder-id", provenance.DefaultBuilderID, "id of the builder"+
+		" recorded in the provenance")
+	cmd.Flags().StringVar(&signKey, "sign-key", "", "sign the binary with the private key (PEM),"+
+		" writing the signature next to it. Encrypted cosign keys require the cosign CLI")
+	cmd.Flags().BoolVar(&signKeyless, "sign-keyless", false, "sign the binary using the Sigstore keyless flow."+
+		" Requires the cosign CLI")
+	cmd.Flags().StringVar(&opts.LockFile, "lock", "", "lock file with the resolved modules and checksums."+
+		" Created if it doesn't exist, otherwise the build fails if the resolved modules differ")
+	cmd.Flags().BoolVar(&opts.StrictK6Version, "strict-k6-version", false, "fail if an extension requires"+
+		" a newer k6 version than the requested one, instead of upgrading k6")
+	cmd.Flags().BoolVar(&opts.Reproducible, "reproducible", false, "build with -trimpath, -buildvcs=false and an"+
+		" empty build ID using a pinned toolchain, so the same inputs produce identical binaries")
+	cmd.Flags().BoolVar(&opts.VerifyReproducible, "verify-reproducible", false, "build the binary twice and fail"+
+		" if the binaries differ. Implies --reproducible")
+	cmd.Flags().BoolVar(&opts.StripDebugInfo, "strip", false, "omit the symbol table and debug information"+
+		" from the binary (-ldflags \"-s -w\")")
+	cmd.Flags().BoolVar(&opts.CompressWithUPX, "upx", false, "compress the binary using UPX. Requires upx")
+	cmd.Flags().StringToStringVar(&opts.BuildMetadata, "build-metadata", nil, "set string variables in the binary"+
+		" (-ldflags -X), e.g. go.k6.io/k6/lib/consts.VersionDetails=custom")
+	cmd.Flags().StringVar(&cacheDir, "cache-dir", "", "directory for caching the binaries. Builds using"+
+		" specific versions of k6 and all dependencies are returned from the cache")
+	cmd.Flags().BoolVar(&vendor, "vendor", false, "write the build environment with the vendored dependencies"+
+		" as a tar.gz archive to the output instead of building")
+	addGraphFlags(cmd, &graph)
+	addHookFlags(cmd, &hooks)
+	cmd.Flags().StringArrayVar(&opts.Workspace, "workspace", []string{}, "local module directory added to a go"+
+		" workspace. Used instead of the required version of the module, also for transitive dependencies")
+	cmd.Flags().StringVar(&mainTemplate, "main-template", "", "go template file for generating the main.go."+
+		" Receives the k6 module path (.K6Module) and the extensions (.Extensions)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "resolve the dependencies and print the generated main.go,"+
+		" go.mod and go.sum without compiling")
+	cmd.Flags().StringVar(&fromVendor, "from-vendor", "", "build from a vendored build environment archive"+
+		" without accessing the network. k6 version and dependencies are ignored")
+	cmd.Flags().BoolVar(&watch, "watch", false, "rebuild the binary each time the local replacements, workspace"+
+		" modules or k6 repository change, until interrupted")
 
 	return cmd
 }
+
+func buildFromVendor(
+	ctx context.Context,
+	b k6foundry.Builder,
+	platform k6foundry.Platform,
+	archive string,
+	buildOpts []string,
+	out io.Writer,
+) (*k6foundry.BuildInfo, error) {
+	vb, ok := b.(k6foundry.VendorBuilder)
+	if !ok {
+		return nil, fmt.Errorf("%w: building from vendor not supported", ErrInvalidBuilder)
+	}
+
+	vendor, err := os.Open(archive) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	defer vendor.Close() //nolint:errcheck
+
+	return vb.BuildFromVendor(ctx, platform, vendor, buildOpts, out)
+}
+
+// printResolution resolves the dependencies and writes the generated build environment files to the out io.Writer
+func printResolution(ctx context.Context, b k6foundry.Builder, k6Version string, mods []k6foundry.Module, out io.Writer) error {
+	r, ok := b.(k6foundry.Resolver)
+	if !ok {
+		return fmt.Errorf("%w: dry run not supported", ErrInvalidBuilder)
+	}
+
+	resolution, err := r.Resolve(ctx, k6Version, mods)
+	if err != nil {
+		return err
+	}
+
+	// main.go first, then the imports of the extensions, go.mod and go.sum
+	rank := func(name string) int {
+		switch {
+		case name == "main.go":
+			return 0
+		case strings.HasSuffix(name, ".go"):
+			return 1
+		case name == "go.mod":
+			return 2
+		default:
+			return 3
+		}
+	}
+
+	names := make([]string, 0, len(resolution.Files))
+	for name := range resolution.Files {
+		names = append(names, name)
+	}
+	slices.SortFunc(names, func(a, b string) int {
+		return cmp.Or(cmp.Compare(rank(a), rank(b)), cmp.Compare(a, b))
+	})
+
+	for _, name := range names {
+		fmt.Fprintf(out, "// %s\n%s\n", name, resolution.Files[name])
+	}
+
+	return nil
+}
+
+// resolveK6Version resolves version specifications such as latest-1 or constraints. latest is resolved by go,
+// which honors the whole go configuration (e.g. GONOPROXY, retractions), unless the resolve cache is enabled.
+// Then, latest is resolved querying the module proxy, reusing the versions resolved by previous builds
+// within the TTL. Offline builds and k6 forks always let go resolve latest
+func resolveK6Version(
+	ctx context.Context,
+	spec string,
+	opts k6foundry.NativeBuilderOpts,
+	cacheTTL time.Duration,
+	useCache bool,
+) (string, error) {
+	if semver.IsValid(spec) {
+		return spec, nil
+	}
+
+	if spec == "latest" && (!useCache || opts.Offline || opts.K6Repo != "") {
+		return spec, nil
+	}
+
+	goProxy, err := opts.GoProxy()
+	if err != nil {
+		return "", err
+	}
+
+	resolverOpts := k6foundry.VersionResolverOpts{
+		VersionsOpts: k6foundry.VersionsOpts{GoProxy: goProxy},
+		TTL:          cacheTTL,
+	}
+
+	if !useCache {
+		resolverOpts.TTL = -1
+	} else if dir, dirErr := os.UserCacheDir(); dirErr == nil {
+		resolverOpts.CacheFile = filepath.Join(dir, "k6foundry", "versions.json")
+	}
+
+	return k6foundry.NewVersionResolver(resolverOpts).Resolve(ctx, spec)
+}
+
+// splitK6Repo splits the version from the module path of a k6 fork. Local directories have no version
+func splitK6Repo(repo string) (string, string) {
+	if strings.HasPrefix(repo, ".") || filepath.IsAbs(repo) {
+		return repo, ""
+	}
+
+	path, version, _ := strings.Cut(repo, "@")
+
+	return path, version
+}
+
+// parseDependency parses a dependency, resolving it from the catalog if its name is in the catalog
+func parseDependency(catalog k6foundry.Catalog, dep string) (k6foundry.Module, error) {
+	name, _, _ := strings.Cut(dep, "@")
+	if _, found := catalog[name]; found {
+		return catalog.Resolve(dep)
+	}
+
+	return k6foundry.ParseModule(dep)
+}
+
+// writeOutput writes the output file with the given permissions using the write function. The output is
+// written to a temporary file that replaces it if writing succeeds, so no partial file is left behind
+func writeOutput(
+	path string,
+	mode os.FileMode,
+	write func(out io.Writer) (*k6foundry.BuildInfo, error),
+) (*k6foundry.BuildInfo, error) {
+	out, err := k6foundry.CreateAtomicFile(path, mode)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Abort()
+
+	info, err := write(out)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = out.Commit(); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// buildMultiPlatform builds the binaries for all the platforms, naming each output with the platform as suffix
+func buildMultiPlatform(
+	ctx context.Context,
+	b k6foundry.Builder,
+	platforms []k6foundry.Platform,
+	k6Version string,
+	mods []k6foundry.Module,
+	buildOpts []string,
+	outPath string,
+	mode os.FileMode,
+) ([]*k6foundry.BuildInfo, error) {
+	mb, ok := b.(k6foundry.MultiPlatformBuilder)
+	if !ok {
+		return nil, fmt.Errorf("%w: multi-platform builds not supported", ErrInvalidBuilder)
+	}
+
+	var (
+		mutex    sync.Mutex
+		outFiles []*k6foundry.AtomicFile
+	)
+
+	// the binaries replace the outputs only if all the builds succeed
+	defer func() {
+		for _, f := range outFiles {
+			f.Abort()
+		}
+	}()
+
+	out := func(platform k6foundry.Platform) (io.Writer, error) {
+		outFile, err := k6foundry.CreateAtomicFile(platformOutPath(outPath, platform), mode)
+		if err != nil {
+			return nil, err
+		}
+
+		mutex.Lock()
+		outFiles = append(outFiles, outFile)
+		mutex.Unlock()
+
+		return outFile, nil
+	}
+
+	buildInfos, err := mb.BuildMultiPlatform(ctx, platforms, k6Version, mods, buildOpts, out)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range outFiles {
+		err = errors.Join(err, f.Commit())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return buildInfos, nil
+}
+
+// platformOutPath returns the output path for a platform (e.g. k6-linux-amd64, k6-linux-arm-v7
+// or k6-windows-amd64.exe)
+func platformOutPath(outPath string, platform k6foundry.Platform) string {
+	return platform.BinaryName(outPath + "-" + strings.ReplaceAll(platform.String(), "/", "-"))
+}
+
+// newSigner returns the signer for the signing options, or nil if signing is not requested
+func newSigner(key string, keyless bool) (sign.Signer, error) {
+	switch {
+	case key != "" && keyless:
+		return nil, ErrSignConflict
+	case key != "":
+		return sign.NewKeySigner(key)
+	case keyless:
+		return sign.NewKeylessSigner()
+	default:
+		return nil, nil //nolint:nilnil
+	}
+}
+
+// addGoDownloadFlags adds the flags for downloading the go toolchain if it is not installed
+func addGoDownloadFlags(cmd *cobra.Command, opts *k6foundry.GoOpts) {
+	cmd.Flags().BoolVar(&opts.DownloadGo, "download-go", false, "if go is not installed, download the go release"+
+		" of --go-version (or "+k6foundry.DefaultGoDownloadVersion+") and use it for building")
+	cmd.Flags().StringVar(&opts.GoDownloadDir, "go-download-dir", "", "directory the go toolchains are downloaded to."+
+		" Defaults to k6foundry/go in the user's cache directory")
+}
+
+// addCompileFlags adds the flags for the options passed to go build
+func addCompileFlags(cmd *cobra.Command, opts *k6foundry.GoOpts) {
+	cmd.Flags().StringSliceVar(&opts.BuildTags, "tags", []string{}, "build tags used for compiling the binary"+
+		" (e.g. netgo). Can be repeated or comma separated")
+	cmd.Flags().StringArrayVar(&opts.LDFlags, "ldflags", []string{}, "argument passed to the linker"+
+		" (e.g. -s or '-X main.version=1.0'). Can be repeated")
+	cmd.Flags().StringArrayVar(&opts.GCFlags, "gcflags", []string{}, "argument passed to the compiler"+
+		" (e.g. all=-N). Can be repeated")
+	cmd.Flags().StringArrayVar(&opts.ASMFlags, "asmflags", []string{}, "argument passed to the assembler."+
+		" Can be repeated")
+	cmd.Flags().BoolVar(&opts.TrimPath, "trimpath", false, "remove the file system paths from the binary")
+	cmd.Flags().BoolVar(&opts.Race, "race", false, "enable the data race detector. Requires cgo")
+	cmd.Flags().StringVar(&opts.PGOProfile, "pgo", "", "CPU profile used for profile-guided optimization")
+}
+
+// graphFlags are the flags for changing the modules in the build, including transitive dependencies
+type graphFlags struct {
+	replaces []string
+	pins     []string
+	excludes []string
+}
+
+// addGraphFlags adds the flags for changing the modules in the build
+func addGraphFlags(cmd *cobra.Command, flags *graphFlags) {
+	cmd.Flags().StringArrayVar(&flags.replaces, "replace", []string{}, "replace a module in the build, including"+
+		" transitive dependencies, using the format path[@version]=replacement[@version]"+
+		" (e.g. google.golang.org/grpc=github.com/my-org/grpc@v1.60.0). Can be repeated")
+	cmd.Flags().StringArrayVar(&flags.pins, "pin", []string{}, "require a module at a version after resolving"+
+		" the dependencies (e.g. golang.org/x/net@v0.23.0). Fails if a newer version is required. Can be repeated")
+	cmd.Flags().StringArrayVar(&flags.excludes, "exclude", []string{}, "exclude a version of a module when"+
+		" resolving the dependencies, using the next higher version (e.g. golang.org/x/net@v0.22.0). Can be repeated")
+}
+
+// apply sets the changes to the modules in the builder options
+func (f graphFlags) apply(opts *k6foundry.NativeBuilderOpts) error {
+	var err error
+
+	if opts.Replacements, err = k6foundry.ParseReplaces(f.replaces); err != nil {
+		return err
+	}
+
+	if opts.Pins, err = k6foundry.ParseModuleVersions(f.pins); err != nil {
+		return err
+	}
+
+	if opts.Excludes, err = k6foundry.ParseModuleVersions(f.excludes); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// hookFlags are the flags for processing the binaries after they are compiled (e.g. code signing)
+type hookFlags struct {
+	commands []string
+	codesign k6foundry.CodesignOpts
+	signtool k6foundry.SigntoolOpts
+}
+
+// addHookFlags adds the flags for the post-build hooks
+func addHookFlags(cmd *cobra.Command, flags *hookFlags) {
+	cmd.Flags().StringArrayVar(&flags.commands, "post-build-hook", []string{}, "command run with the path of the"+
+		" binary as last argument after it is compiled, before the checksum and signature (e.g. \"strip-tool -v\")."+
+		" Can be repeated")
+	cmd.Flags().StringVar(&flags.codesign.Identity, "codesign-identity", "", "sign the darwin binaries with codesign"+
+		" using the identity. Requires macOS")
+	cmd.Flags().StringVar(&flags.codesign.Entitlements, "codesign-entitlements", "", "entitlements file used by"+
+		" codesign")
+	cmd.Flags().StringVar(&flags.codesign.NotaryProfile, "notary-profile", "", "notarize the signed darwin binaries"+
+		" using the keychain profile of notarytool")
+	cmd.Flags().StringVar(&flags.signtool.CertFile, "signtool-cert", "", "sign the windows binaries with signtool"+
+		" using the certificate (PFX). Requires windows")
+	cmd.Flags().StringVar(&flags.signtool.Password, "signtool-password", "", "password of the signtool certificate."+
+		" Preferably set with K6FOUNDRY_SIGNTOOL_PASSWORD")
+	cmd.Flags().StringVar(&flags.signtool.CertSubject, "signtool-subject", "", "sign the windows binaries with"+
+		" signtool using the certificate with the subject from the certificate store. Requires windows")
+	cmd.Flags().StringVar(&flags.signtool.TimestampURL, "signtool-timestamp-url", k6foundry.DefaultTimestampURL,
+		"timestamp server used by signtool")
+}
+
+// hooks returns the post-build hooks for the flags, in order: the commands, codesign and signtool
+func (f hookFlags) hooks() ([]k6foundry.Hook, error) {
+	hooks := []k6foundry.Hook{}
+	for _, command := range f.commands {
+		hook, err := k6foundry.NewCommandHook(strings.Fields(command))
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+
+	if f.codesign.Identity != "" {
+		hook, err := k6foundry.NewCodesignHook(f.codesign)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+
+	if f.signtool.CertFile != "" || f.signtool.CertSubject != "" {
+		hook, err := k6foundry.NewSigntoolHook(f.signtool)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+
+	return hooks, nil
+}
+
+// builderConfig returns the digest of the configuration of the builder, for identifying its binaries
+// in the cache
+func builderConfig(
+	builderType string,
+	opts k6foundry.NativeBuilderOpts,
+	containerOpts k6foundry.ContainerBuilderOpts,
+	serverURL string,
+) (string, error) {
+	switch builderType {
+	case "container":
+		return containerOpts.ConfigDigest()
+	case "remote":
+		// the configuration of the build service is not known, but it is the same for all its builds
+		return "remote " + serverURL, nil
+	default:
+		return opts.ConfigDigest()
+	}
+}
+
+// parseDiskLimits sets the limits for the go cache size and the free disk space
+func parseDiskLimits(opts *k6foundry.GoOpts, maxCacheSize string, minFreeSpace string) error {
+	var err error
+
+	opts.MaxCacheSize, err = util.ParseSize(maxCacheSize)
+	if err != nil {
+		return fmt.Errorf("parsing max cache size %w", err)
+	}
+
+	opts.MinFreeDiskSpace, err = util.ParseSize(minFreeSpace)
+	if err != nil {
+		return fmt.Errorf("parsing min free space %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,191 @@
+//nolint:forbidigo
+package cmd
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidConfig is returned when the configuration file or the environment variables are not valid
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+const (
+	// prefix of the environment variables that set flags (e.g. K6FOUNDRY_K6_VERSION sets --k6-version)
+	envPrefix = "K6FOUNDRY_"
+	// name of the default configuration file, in the home directory
+	defaultConfigFile = ".k6foundry.yaml"
+	// flag for specifying the configuration file
+	configFlag = "config"
+)
+
+// ConfigFile returns the path of the configuration file: the path in the K6FOUNDRY_CONFIG environment variable,
+// or ~/.k6foundry.yaml
+func ConfigFile() string {
+	if path := os.Getenv(envPrefix + "CONFIG"); path != "" {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, defaultConfigFile)
+}
+
+// ApplyConfig sets the flags of the command that were not given in the command line from the
+// K6FOUNDRY_<FLAG> environment variables and, if not set in the environment, from the section
+// of the command in the configuration file. For example:
+//
+//	build:
+//	  k6-version: v0.50.0
+//	  platform: [linux/amd64, darwin/arm64]
+//
+// A missing configuration file is ignored unless it was given explicitly, with the --config flag or
+// the K6FOUNDRY_CONFIG environment variable.
+func ApplyConfig(cmd *cobra.Command, configFile string) error {
+	required := cmd.Flags().Changed(configFlag) || os.Getenv(envPrefix+"CONFIG") != ""
+
+	config, err := loadConfig(configFile, required)
+	if err != nil {
+		return err
+	}
+
+	section := map[string]any{}
+	if value, found := config[cmd.Name()]; found {
+		if section, err = toSection(value); err != nil {
+			return fmt.Errorf("%w: %q section %w", ErrInvalidConfig, cmd.Name(), err)
+		}
+	}
+
+	flags := cmd.Flags()
+	for key := range section {
+		if flags.Lookup(key) == nil {
+			return fmt.Errorf("%w: unknown flag %q in %q section", ErrInvalidConfig, key, cmd.Name())
+		}
+	}
+
+	flags.VisitAll(func(flag *pflag.Flag) {
+		if err != nil || flag.Changed || flag.Name == configFlag || flag.Name == "help" {
+			return
+		}
+
+		envVar := envPrefix + strings.ToUpper(strings.ReplaceAll(flag.Name, "-", "_"))
+		if value, found := os.LookupEnv(envVar); found {
+			if setErr := setFromEnv(flag, value); setErr != nil {
+				err = fmt.Errorf("%w: %s %w", ErrInvalidConfig, envVar, setErr)
+			}
+			return
+		}
+
+		if value, found := section[flag.Name]; found {
+			if setErr := setFromConfig(flag, value); setErr != nil {
+				err = fmt.Errorf("%w: %q in %q section %w", ErrInvalidConfig, flag.Name, cmd.Name(), setErr)
+			}
+		}
+	})
+
+	return err
+}
+
+// loadConfig reads the configuration file. Returns an empty configuration if the file doesn't
+// exist and is not required
+func loadConfig(path string, required bool) (map[string]any, error) {
+	config := map[string]any{}
+	if path == "" {
+		return config, nil
+	}
+
+	content, err := os.ReadFile(path) //nolint:gosec
+	if errors.Is(err, os.ErrNotExist) && !required {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading configuration file %w", err)
+	}
+
+	if err = yaml.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("%w: %s %w", ErrInvalidConfig, path, err)
+	}
+
+	return config, nil
+}
+
+func toSection(value any) (map[string]any, error) {
+	if value == nil {
+		return map[string]any{}, nil
+	}
+
+	section, ok := value.(map[string]any)
+	if !ok {
+		return nil, errors.New("expected a mapping of flags")
+	}
+
+	return section, nil
+}
+
+// setFromEnv sets the flag from an environment variable. Values of flags that accept multiple values
+// are separated by commas, quoting values that contain commas (e.g. "-ldflags=-X a=b,c",-trimpath)
+func setFromEnv(flag *pflag.Flag, value string) error {
+	slice, isSlice := flag.Value.(pflag.SliceValue)
+	if !isSlice {
+		return setFlag(flag, value)
+	}
+
+	values := []string{}
+	if value != "" {
+		var err error
+		values, err = csv.NewReader(strings.NewReader(value)).Read()
+		if err != nil {
+			return err
+		}
+	}
+
+	flag.Changed = true
+
+	return slice.Replace(values)
+}
+
+// setFromConfig sets the flag from a value in the configuration file, which can be a list for flags
+// that accept multiple values
+func setFromConfig(flag *pflag.Flag, value any) error {
+	list, isList := value.([]any)
+	slice, isSlice := flag.Value.(pflag.SliceValue)
+
+	switch {
+	case isList && isSlice:
+		values := make([]string, 0, len(list))
+		for _, v := range list {
+			values = append(values, fmt.Sprint(v))
+		}
+
+		flag.Changed = true
+
+		return slice.Replace(values)
+	case isList:
+		return errors.New("expected a single value")
+	case isSlice:
+		flag.Changed = true
+
+		return slice.Replace([]string{fmt.Sprint(value)})
+	default:
+		return setFlag(flag, fmt.Sprint(value))
+	}
+}
+
+func setFlag(flag *pflag.Flag, value string) error {
+	if err := flag.Value.Set(value); err != nil {
+		return err
+	}
+	flag.Changed = true
+
+	return nil
+}
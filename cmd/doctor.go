@@ -0,0 +1,109 @@
+// nolint:forbidigo,nolintlint
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/grafana/k6foundry"
+
+	"github.com/spf13/cobra"
+)
+
+// ErrDoctorFailed is returned when any check of the doctor command fails
+var ErrDoctorFailed = errors.New("the build environment has problems") //nolint:revive
+
+const doctorLong = `
+checks the local build environment: the go toolchain, git, the C toolchain (if cgo is enabled),
+the access to the module proxy and the free disk space, printing how to fix the problems found.
+
+The checks use the same options as the build command, so the environment can be checked for a
+specific build (e.g. --zig or --offline). The command fails if any check fails.
+`
+
+const doctorExample = `
+# check the build environment
+k6foundry doctor
+
+# check the build environment for cross compiling with zig, requiring 5GB free
+k6foundry doctor --zig --min-free-space 5GB
+`
+
+// NewDoctor returns a command for checking the build environment
+func NewDoctor() *cobra.Command {
+	var (
+		opts         k6foundry.DiagnoseOpts
+		minFreeSpace string
+		outputFormat string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "doctor",
+		Short:   "check the build environment",
+		Long:    doctorLong,
+		Example: doctorExample,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if outputFormat != outputFormatText && outputFormat != outputFormatJSON {
+				return fmt.Errorf("%w: %q", ErrInvalidOutputFormat, outputFormat)
+			}
+
+			if err := parseDiskLimits(&opts.GoOpts, "", minFreeSpace); err != nil {
+				return err
+			}
+
+			results := k6foundry.Diagnose(cmd.Context(), opts)
+
+			if outputFormat == outputFormatJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+
+				if err := encoder.Encode(results); err != nil {
+					return err
+				}
+			} else {
+				writeDiagnosis(os.Stdout, results)
+			}
+
+			if k6foundry.DiagnosisFailed(results) {
+				return ErrDoctorFailed
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.CopyGoEnv, "copy-go-env", true, "copy current go environment")
+	addGoDownloadFlags(cmd, &opts.GoOpts)
+	cmd.Flags().StringToStringVarP(&opts.Env, "env", "e", nil, "build environment variables")
+	cmd.Flags().StringVar(&opts.GoCacheDir, "go-cache-dir", "", "directory for the go module and build caches."+
+		" Defaults to the caches of the go environment")
+	cmd.Flags().StringVar(&opts.BuildDirRoot, "build-dir-root", "", "parent directory of the temporary work"+
+		" directories and caches (e.g. a tmpfs mount). Defaults to the system temporary directory")
+	cmd.Flags().BoolVarP(&opts.TmpCache, "tmp-cache", "t", false, "use a temporary go cache")
+	cmd.Flags().StringVar(&minFreeSpace, "min-free-space", "", "minimum free disk space in the work and cache"+
+		" directories required for building (e.g. 1GB)")
+	cmd.Flags().BoolVar(&opts.Offline, "offline", false, "build without network access")
+	cmd.Flags().StringVar(&opts.GoVersion, "go-version", "", "go toolchain version used for building (e.g. 1.22.5)")
+	cmd.Flags().StringVar(&opts.CC, "cc", "", "C compiler used for cgo")
+	cmd.Flags().BoolVar(&opts.Zig, "zig", false, "use zig as C/C++ cross compiler for cgo")
+	cmd.Flags().DurationVar(&opts.ProxyTimeout, "proxy-timeout", 0, "timeout for checking the module proxy."+
+		" Defaults to 10s")
+	cmd.Flags().StringVar(&outputFormat, "output-format", outputFormatText, "format of the output: text or json")
+
+	return cmd
+}
+
+// writeDiagnosis writes the results of the checks in text format
+func writeDiagnosis(out io.Writer, results []k6foundry.CheckResult) {
+	for _, r := range results {
+		fmt.Fprintf(out, "[%s] %s: %s\n", strings.ToUpper(string(r.Status)), r.Name, r.Message)
+		if r.Remediation != "" {
+			fmt.Fprintf(out, "    fix: %s\n", r.Remediation)
+		}
+	}
+}
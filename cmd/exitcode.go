@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/k6foundry"
+	"github.com/grafana/k6foundry/pkg/server"
+	"github.com/grafana/k6foundry/pkg/sign"
+	"github.com/grafana/k6foundry/pkg/util"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of the commands by class of failure. The run command exits with the exit code of k6, and
+// the commands interrupted by a signal exit with 128 plus the signal number
+const (
+	// ExitError is the exit code of the failures not in other classes
+	ExitError = 1
+	// ExitInvalidArguments is the exit code when the flags, arguments or options are not valid
+	ExitInvalidArguments = 2
+	// ExitEnvironment is the exit code when a tool required for building is missing (e.g. go or git) or
+	// too old, or there's not enough disk space
+	ExitEnvironment = 3
+	// ExitResolution is the exit code when the dependencies can't be resolved (e.g. a module or version not
+	// found, or incompatible extensions)
+	ExitResolution = 4
+	// ExitCompile is the exit code when the binary can't be compiled
+	ExitCompile = 5
+	// ExitTimeout is the exit code when a build times out
+	ExitTimeout = 6
+)
+
+// ErrInvalidArguments is returned when the flags or arguments of a command can't be parsed
+var ErrInvalidArguments = errors.New("invalid arguments") //nolint:revive
+
+// exitCodes maps errors to exit codes. The first match is used, so more specific errors go first
+var exitCodes = []struct { //nolint:gochecknoglobals
+	err  error
+	code int
+}{
+	{ErrInvalidArguments, ExitInvalidArguments},
+	{k6foundry.ErrBuildTimeout, ExitTimeout},
+	{context.DeadlineExceeded, ExitTimeout},
+	{k6foundry.ErrNoGoToolchain, ExitEnvironment},
+	{k6foundry.ErrNoGit, ExitEnvironment},
+	{k6foundry.ErrNoZig, ExitEnvironment},
+	{k6foundry.ErrNoUPX, ExitEnvironment},
+	{k6foundry.ErrNoContainerEngine, ExitEnvironment},
+	{k6foundry.ErrDownloadingGo, ExitEnvironment},
+	{k6foundry.ErrInsufficientDiskSpace, ExitEnvironment},
+	{k6foundry.ErrIncompatibleGoVersion, ExitEnvironment},
+	{sign.ErrNoCosign, ExitEnvironment},
+	{k6foundry.ErrNoSigningTool, ExitEnvironment},
+	{ErrDoctorFailed, ExitEnvironment},
+	{k6foundry.ErrBuildConstraints, ExitCompile},
+	{k6foundry.ErrCompiling, ExitCompile},
+	{k6foundry.ErrModuleNotFound, ExitResolution},
+	{k6foundry.ErrVersionNotFound, ExitResolution},
+	{k6foundry.ErrChecksumMismatch, ExitResolution},
+	{k6foundry.ErrUnknownRevision, ExitResolution},
+	{k6foundry.ErrIncompatibleExtension, ExitResolution},
+	{k6foundry.ErrConflictingDependencies, ExitResolution},
+	{k6foundry.ErrLockMismatch, ExitResolution},
+	{k6foundry.ErrMissingModules, ExitResolution},
+	{k6foundry.ErrNoMatchingVersion, ExitResolution},
+	{k6foundry.ErrUnknownDependency, ExitResolution},
+	{k6foundry.ErrUnknownExtension, ExitResolution},
+	{k6foundry.ErrResolvingDependency, ExitResolution},
+	{k6foundry.ErrInvalidDependencyFormat, ExitInvalidArguments},
+	{k6foundry.ErrInvalidReplace, ExitInvalidArguments},
+	{k6foundry.ErrInvalidHook, ExitInvalidArguments},
+	{k6foundry.ErrInvalidPlatform, ExitInvalidArguments},
+	{k6foundry.ErrDuplicatedPlatform, ExitInvalidArguments},
+	{k6foundry.ErrUnsupportedPlatform, ExitInvalidArguments},
+	{k6foundry.ErrInvalidBuildFlags, ExitInvalidArguments},
+	{k6foundry.ErrInvalidBuildTags, ExitInvalidArguments},
+	{k6foundry.ErrInvalidBuildMetadata, ExitInvalidArguments},
+	{k6foundry.ErrInvalidCleanupStrategy, ExitInvalidArguments},
+	{k6foundry.ErrInvalidPGOProfile, ExitInvalidArguments},
+	{k6foundry.ErrInvalidPreset, ExitInvalidArguments},
+	{k6foundry.ErrInvalidGoVersion, ExitInvalidArguments},
+	{k6foundry.ErrInvalidVerificationOpts, ExitInvalidArguments},
+	{k6foundry.ErrConflictingEnv, ExitInvalidArguments},
+	{k6foundry.ErrInvalidConstraint, ExitInvalidArguments},
+	{k6foundry.ErrInvalidProfiles, ExitInvalidArguments},
+	{k6foundry.ErrUnknownProfile, ExitInvalidArguments},
+	{util.ErrInvalidSize, ExitInvalidArguments},
+	{util.ErrInvalidFileMode, ExitInvalidArguments},
+	{server.ErrInvalidServerURL, ExitInvalidArguments},
+	{server.ErrInvalidRequest, ExitInvalidArguments},
+	{ErrInvalidConfig, ExitInvalidArguments},
+	{ErrInvalidOutputFormat, ExitInvalidArguments},
+	{ErrInvalidOutputType, ExitInvalidArguments},
+	{ErrInvalidBuilder, ExitInvalidArguments},
+	{ErrInvalidCacheOptions, ExitInvalidArguments},
+	{ErrInvalidBenchOptions, ExitInvalidArguments},
+	{ErrInvalidManifest, ExitInvalidArguments},
+	{ErrTargetPlatformUndefined, ExitInvalidArguments},
+	{ErrProfileConflict, ExitInvalidArguments},
+	{ErrVendorConflict, ExitInvalidArguments},
+	{ErrMultiPlatformVendor, ExitInvalidArguments},
+	{ErrPackageVendor, ExitInvalidArguments},
+	{ErrProvenanceVendor, ExitInvalidArguments},
+	{ErrSignConflict, ExitInvalidArguments},
+	{ErrDockerOutput, ExitInvalidArguments},
+	{ErrWatchConflict, ExitInvalidArguments},
+	{ErrNotInteractive, ExitInvalidArguments},
+}
+
+// ExitCode returns the exit code of a command that failed with the error
+func ExitCode(err error) int {
+	var compileErr *k6foundry.CompileError
+	if errors.As(err, &compileErr) {
+		return ExitCompile
+	}
+
+	for _, e := range exitCodes {
+		if errors.Is(err, e.err) {
+			return e.code
+		}
+	}
+
+	return ExitError
+}
+
+// WrapUsageErrors wraps the errors parsing the flags and validating the arguments of the command and its
+// subcommands with ErrInvalidArguments
+func WrapUsageErrors(cmd *cobra.Command) {
+	cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return fmt.Errorf("%w: %w", ErrInvalidArguments, err)
+	})
+
+	if args := cmd.Args; args != nil {
+		cmd.Args = func(cmd *cobra.Command, a []string) error {
+			if err := args(cmd, a); err != nil {
+				return fmt.Errorf("%w: %w", ErrInvalidArguments, err)
+			}
+
+			return nil
+		}
+	}
+
+	for _, sub := range cmd.Commands() {
+		WrapUsageErrors(sub)
+	}
+}
@@ -0,0 +1,71 @@
+// nolint:forbidigo,nolintlint
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/grafana/k6foundry/pkg/image"
+)
+
+const (
+	outputTypeBinary = "binary"
+	outputTypeDocker = "docker"
+)
+
+var (
+	// ErrInvalidOutputType is returned when the output type is not supported
+	ErrInvalidOutputType = errors.New("invalid output type")
+	// ErrDockerOutput is returned when the docker output is combined with options that don't produce a binary
+	ErrDockerOutput = errors.New("--output-type docker is not supported with --vendor or --manifest")
+)
+
+// imageOpts defines how the image is created and where it is written
+type imageOpts struct {
+	image.Options
+	// push the image to its registry instead of writing an archive
+	push bool
+	// path of the archive. Defaults to <repository name>.tar
+	archive string
+}
+
+// writeImage creates an image with the binaries and pushes it or writes it as an archive.
+// Returns the digest of the image
+func writeImage(ctx context.Context, ref name.Reference, binaries []image.Binary, opts imageOpts) (string, error) {
+	img, err := image.Build(ctx, binaries, opts.Options)
+	if err != nil {
+		return "", err
+	}
+
+	if opts.push {
+		if err = img.Push(ctx, ref); err != nil {
+			return "", err
+		}
+
+		return img.Digest()
+	}
+
+	archivePath := opts.archive
+	if archivePath == "" {
+		archivePath = path.Base(ref.Context().RepositoryStr()) + ".tar"
+	}
+
+	archive, err := os.Create(archivePath) //nolint:gosec
+	if err != nil {
+		return "", err
+	}
+
+	err = img.WriteArchive(ref, archive)
+	if closeErr := archive.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(archivePath)
+		return "", err
+	}
+
+	return img.Digest()
+}
@@ -0,0 +1,102 @@
+// nolint:forbidigo,nolintlint
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/grafana/k6foundry"
+
+	"github.com/spf13/cobra"
+)
+
+const inspectLong = `
+shows the k6 version and the extensions a k6 binary was built with, reading the build information
+embedded in the binary by the go toolchain. The binary is not executed, so binaries for any platform
+can be inspected.
+
+Extensions are identified by the naming convention of the k6 extensions (e.g. github.com/grafana/xk6-sql).
+Use --all for listing all the modules included in the binary.
+`
+
+const inspectExample = `
+# show the k6 version and extensions of the k6 binary
+k6foundry inspect ./k6
+
+# show the build information as JSON, including all the modules
+k6foundry inspect ./k6 --output-format json
+`
+
+// NewInspect returns a command for inspecting k6 binaries
+func NewInspect() *cobra.Command {
+	var (
+		outputFormat string
+		all          bool
+	)
+
+	cmd := &cobra.Command{
+		Use:     "inspect <binary>",
+		Short:   "show the k6 version and extensions of a k6 binary",
+		Long:    inspectLong,
+		Example: inspectExample,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if outputFormat != outputFormatText && outputFormat != outputFormatJSON {
+				return fmt.Errorf("%w: %q", ErrInvalidOutputFormat, outputFormat)
+			}
+
+			info, err := k6foundry.InspectBinary(args[0])
+			if err != nil {
+				return err
+			}
+
+			if outputFormat == outputFormatJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+
+				return encoder.Encode(info)
+			}
+
+			writeBinaryInfo(os.Stdout, info, all)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&outputFormat, "output-format", outputFormatText, "format of the output: text or json")
+	cmd.Flags().BoolVar(&all, "all", false, "list all the modules included in the binary")
+
+	return cmd
+}
+
+// writeBinaryInfo writes the binary info in text format
+func writeBinaryInfo(out io.Writer, info *k6foundry.BinaryInfo, all bool) {
+	fmt.Fprintf(out, "k6: %s\n", info.K6Version)
+	fmt.Fprintf(out, "go: %s\n", info.GoVersion)
+	if info.Platform != "" {
+		fmt.Fprintf(out, "platform: %s\n", info.Platform)
+	}
+
+	exts := make([]string, 0, len(info.Extensions))
+	for ext := range info.Extensions {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+
+	fmt.Fprintf(out, "extensions:\n")
+	for _, ext := range exts {
+		fmt.Fprintf(out, "  %s %s\n", ext, info.Extensions[ext])
+	}
+
+	if !all {
+		return
+	}
+
+	fmt.Fprintf(out, "modules:\n")
+	for _, dep := range info.Dependencies {
+		fmt.Fprintf(out, "  %s %s\n", dep.Path, dep.Version)
+	}
+}
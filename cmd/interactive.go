@@ -0,0 +1,207 @@
+// nolint:forbidigo,nolintlint
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/k6foundry"
+)
+
+var (
+	// ErrNotInteractive is returned when the interactive mode is used without a terminal
+	ErrNotInteractive = errors.New("--interactive requires an interactive terminal") //nolint:revive
+	// ErrInteractiveCanceled is returned when the build is not confirmed in the interactive mode
+	ErrInteractiveCanceled = errors.New("build canceled") //nolint:revive
+)
+
+// selection is the build selected interactively
+type selection struct {
+	// dependencies in the format path@version
+	deps      []string
+	k6Version string
+	platform  string
+}
+
+// prompter asks questions in a terminal, reading the answers line by line
+type prompter struct {
+	in  *bufio.Scanner
+	out io.Writer
+}
+
+// ask asks the question and returns the answer, or the default value if the answer is empty
+func (p *prompter) ask(question string, defaultValue string) (string, error) {
+	if defaultValue != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, defaultValue)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+
+	if !p.in.Scan() {
+		if err := p.in.Err(); err != nil {
+			return "", err
+		}
+		return "", ErrInteractiveCanceled
+	}
+
+	answer := strings.TrimSpace(p.in.Text())
+	if answer == "" {
+		return defaultValue, nil
+	}
+
+	return answer, nil
+}
+
+// askValid asks the question until the answer is accepted by the parse function
+func (p *prompter) askValid(question string, defaultValue string, parse func(answer string) error) (string, error) {
+	for {
+		answer, err := p.ask(question, defaultValue)
+		if err != nil {
+			return "", err
+		}
+
+		if err = parse(answer); err != nil {
+			fmt.Fprintf(p.out, "  %v\n", err)
+			continue
+		}
+
+		return answer, nil
+	}
+}
+
+// parseSelection returns the names selected by a list of numbers separated by commas, or none
+func parseSelection(answer string, names []string) ([]string, error) {
+	selected := []string{}
+	if answer == "none" {
+		return selected, nil
+	}
+
+	for _, field := range strings.Split(answer, ",") {
+		i, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || i < 1 || i > len(names) {
+			return nil, fmt.Errorf("invalid selection %q", strings.TrimSpace(field))
+		}
+
+		if !slices.Contains(selected, names[i-1]) {
+			selected = append(selected, names[i-1])
+		}
+	}
+
+	return selected, nil
+}
+
+// selectInteractively asks for the extensions in the catalog, their versions, the k6 version and the
+// platform of the build
+func selectInteractively(in io.Reader, out io.Writer, catalog k6foundry.Catalog, k6Version string) (selection, error) {
+	p := &prompter{in: bufio.NewScanner(in), out: out}
+	sel := selection{}
+
+	names := make([]string, 0, len(catalog))
+	for name := range catalog {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(out, "Extensions:")
+	for i, name := range names {
+		fmt.Fprintf(out, "  %2d) %-26s %s\n", i+1, name, catalog[name].Module)
+	}
+
+	var selected []string
+	_, err := p.askValid("Extensions (numbers separated by commas)", "none", func(answer string) error {
+		var parseErr error
+		selected, parseErr = parseSelection(answer, names)
+		return parseErr
+	})
+	if err != nil {
+		return sel, err
+	}
+
+	for _, name := range selected {
+		entry := catalog[name]
+		if len(entry.Versions) > 0 {
+			fmt.Fprintf(out, "Versions of %s: %s\n", name, strings.Join(entry.Versions, ", "))
+		}
+
+		var mod k6foundry.Module
+		_, err = p.askValid("Version of "+name, "latest", func(answer string) error {
+			var resolveErr error
+			mod, resolveErr = catalog.Resolve(name + "@" + answer)
+			return resolveErr
+		})
+		if err != nil {
+			return sel, err
+		}
+
+		sel.deps = append(sel.deps, mod.Path+"@"+mod.Version)
+	}
+
+	sel.k6Version, err = p.ask("k6 version", k6Version)
+	if err != nil {
+		return sel, err
+	}
+
+	supported := k6foundry.SupportedPlatforms()
+	fmt.Fprintln(out, "Platforms:")
+	for i, platform := range supported {
+		fmt.Fprintf(out, "  %2d) %s\n", i+1, platform)
+	}
+
+	sel.platform, err = p.askValid("Platform (number or os/arch)", k6foundry.RuntimePlatform().String(),
+		func(answer string) error {
+			if i, convErr := strconv.Atoi(answer); convErr == nil {
+				if i < 1 || i > len(supported) {
+					return fmt.Errorf("invalid platform number %d", i)
+				}
+				return nil
+			}
+			_, parseErr := k6foundry.ParsePlatform(answer)
+			return parseErr
+		})
+	if err != nil {
+		return sel, err
+	}
+	if i, convErr := strconv.Atoi(sel.platform); convErr == nil {
+		sel.platform = supported[i-1].String()
+	}
+
+	fmt.Fprintf(out, "\nk6 %s for %s\n", sel.k6Version, sel.platform)
+	for _, dep := range sel.deps {
+		fmt.Fprintf(out, "  %s\n", dep)
+	}
+
+	confirm, err := p.ask("Build? (y/n)", "y")
+	if err != nil {
+		return sel, err
+	}
+	if !strings.EqualFold(confirm, "y") && !strings.EqualFold(confirm, "yes") {
+		return sel, ErrInteractiveCanceled
+	}
+
+	return sel, nil
+}
+
+// runInteractive selects the build in the terminal, using the catalog in the path or the built-in catalog
+func runInteractive(catalogPath string, k6Version string) (selection, error) {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stderr) {
+		return selection{}, ErrNotInteractive
+	}
+
+	catalog := k6foundry.DefaultCatalog()
+	if catalogPath != "" {
+		var err error
+		catalog, err = k6foundry.LoadCatalog(catalogPath)
+		if err != nil {
+			return selection{}, err
+		}
+	}
+
+	return selectInteractively(os.Stdin, os.Stderr, catalog, k6Version)
+}
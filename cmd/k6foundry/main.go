@@ -2,20 +2,59 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 
+	"github.com/grafana/k6foundry"
 	"github.com/grafana/k6foundry/cmd"
 )
 
 //nolint:all
 func main() {
+	// cancel the context on interrupt, giving commands the chance to cleanup
+	ctx, signaled, stop := notifyContext(context.Background())
+
 	root := newRootCmd()
 	root.AddCommand(cmd.New())
+	root.AddCommand(cmd.NewServe())
+	root.AddCommand(cmd.NewInspect())
+	root.AddCommand(cmd.NewRun())
+	root.AddCommand(cmd.NewResolve())
+	root.AddCommand(cmd.NewProfiles())
+	root.AddCommand(cmd.NewExtensions())
+	root.AddCommand(cmd.NewPlatforms())
+	root.AddCommand(cmd.NewDoctor())
+	root.AddCommand(cmd.NewCache())
+	root.AddCommand(cmd.NewCleanWorkDirs())
+	root.AddCommand(cmd.NewBench())
+	cmd.WrapUsageErrors(root)
+
+	err := root.ExecuteContext(ctx)
+	stop()
+
+	if err == nil {
+		return
+	}
+
+	// k6 already reported its error, only its exit code is propagated
+	var runErr *cmd.RunExitError
+	if errors.As(err, &runErr) {
+		os.Exit(runErr.Code)
+	}
+
+	fmt.Printf("%s\n", k6foundry.FormatBuildError(err))
 
-	err := root.Execute()
-	if err != nil {
-		fmt.Printf("%s\n", err.Error())
-		os.Exit(1)
+	// problems with the build environment are diagnosed by the doctor command
+	if errors.Is(err, k6foundry.ErrNoGoToolchain) || errors.Is(err, k6foundry.ErrNoGit) ||
+		errors.Is(err, k6foundry.ErrNoZig) || errors.Is(err, k6foundry.ErrInsufficientDiskSpace) {
+		fmt.Printf("run 'k6foundry doctor' for how to fix the build environment\n")
 	}
+
+	if sig := signaled(); sig != nil {
+		os.Exit(exitCode(sig))
+	}
+
+	os.Exit(cmd.ExitCode(err))
 }
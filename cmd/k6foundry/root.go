@@ -1,12 +1,15 @@
 package main
 
 import (
+	"github.com/grafana/k6foundry/cmd"
 	"github.com/spf13/cobra"
 )
 
 // newCmd returns a cobra.Command for k6foundry command
 func newRootCmd() *cobra.Command {
-	cmd := &cobra.Command{
+	var configFile string
+
+	root := &cobra.Command{
 		Use:   "k6foundry",
 		Short: "k6 build tool",
 		Long:  "k6foundry is a CLI tool for building custom k6 binaries with extensions",
@@ -14,7 +17,19 @@ func newRootCmd() *cobra.Command {
 		SilenceUsage: true,
 		// this is needed to prevent cobra to print errors reported by subcommands in the stderr
 		SilenceErrors: true,
+		// flags not given in the command line are taken from the environment or the configuration file
+		PersistentPreRunE: func(c *cobra.Command, _ []string) error {
+			return cmd.ApplyConfig(c, configFile)
+		},
 	}
 
-	return cmd
+	root.PersistentFlags().StringVar(
+		&configFile,
+		"config",
+		cmd.ConfigFile(),
+		"configuration file with the default value of the flags of each command."+
+			" Can also be set with K6FOUNDRY_CONFIG",
+	)
+
+	return root
 }
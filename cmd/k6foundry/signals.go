@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// exit code when the command is interrupted by a signal, if the signal number is not known.
+// Otherwise, the exit code is 128 plus the signal number, as in shells
+const exitInterrupted = 130
+
+// notifyContext returns a context that is canceled when the process receives an interrupt or termination
+// signal, giving the commands the chance to stop the go commands and clean up the work directories and
+// caches. A second signal exits immediately, without waiting for the cleanup.
+// The returned function returns the signal received, if any. The stop function stops handling the signals.
+func notifyContext(parent context.Context) (context.Context, func() os.Signal, func()) {
+	ctx, cancel := context.WithCancel(parent)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	var (
+		mu       sync.Mutex
+		received os.Signal
+	)
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			mu.Lock()
+			received = sig
+			mu.Unlock()
+
+			fmt.Fprintf(os.Stderr, "received %s, cleaning up. Repeat to exit immediately\n", sig)
+			cancel()
+		case <-done:
+			return
+		}
+
+		select {
+		case sig := <-signals:
+			os.Exit(exitCode(sig))
+		case <-done:
+		}
+	}()
+
+	signaled := func() os.Signal {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return received
+	}
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+			cancel()
+		})
+	}
+
+	return ctx, signaled, stop
+}
+
+// exitCode returns the exit code for a process terminated by the signal
+func exitCode(sig os.Signal) int {
+	if s, ok := sig.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+
+	return exitInterrupted
+}
@@ -0,0 +1,188 @@
+// nolint:forbidigo,nolintlint
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/grafana/k6foundry"
+	"github.com/grafana/k6foundry/pkg/provenance"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrInvalidManifest     = errors.New("invalid manifest")      //nolint:revive
+	ErrManifestBuildFailed = errors.New("manifest build failed") //nolint:revive
+)
+
+// manifest defines multiple build targets
+//
+// Example:
+//
+//	targets:
+//	  - output: dist/k6-kafka-linux
+//	    platform: linux/amd64
+//	    k6Version: v0.50.0
+//	    dependencies: [github.com/mostafa/xk6-kafka@v0.26.0]
+//	    buildOpts: ["-ldflags=-s -w"]
+type manifest struct {
+	Targets []manifestTarget `json:"targets" yaml:"targets"`
+}
+
+type manifestTarget struct {
+	// path to the output binary. Required
+	Output string `json:"output" yaml:"output"`
+	// target platform. Defaults to the runtime platform
+	Platform string `json:"platform" yaml:"platform"`
+	// k6 version. Defaults to latest
+	K6Version string `json:"k6Version" yaml:"k6Version"`
+	// dependencies in the same format as the --dependency flag
+	Dependencies []string `json:"dependencies" yaml:"dependencies"`
+	// go build options
+	BuildOpts []string `json:"buildOpts" yaml:"buildOpts"`
+}
+
+// loadManifest loads a manifest from a YAML (or JSON) file
+func loadManifest(path string) (*manifest, error) {
+	content, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidManifest, err)
+	}
+
+	m := &manifest{}
+	if err = yaml.Unmarshal(content, m); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidManifest, err)
+	}
+
+	outputs := map[string]bool{}
+	for i, target := range m.Targets {
+		if target.Output == "" {
+			return nil, fmt.Errorf("%w: target %d has no output", ErrInvalidManifest, i)
+		}
+
+		if outputs[target.Output] {
+			return nil, fmt.Errorf("%w: duplicated output %q", ErrInvalidManifest, target.Output)
+		}
+		outputs[target.Output] = true
+	}
+
+	return m, nil
+}
+
+// buildManifest builds the targets in the manifest, running up to parallel builds at a time,
+// and reports the status of each target to the out io.Writer. Failed targets don't stop other builds.
+func buildManifest(
+	ctx context.Context,
+	b k6foundry.Builder,
+	catalog k6foundry.Catalog,
+	path string,
+	parallel int,
+	out io.Writer,
+	postBuild postBuildFunc,
+) error {
+	m, err := loadManifest(path)
+	if err != nil {
+		return err
+	}
+
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	var (
+		mutex  sync.Mutex
+		failed int
+		wg     sync.WaitGroup
+		slots  = make(chan struct{}, parallel)
+	)
+
+	for _, target := range m.Targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			start := time.Now()
+			err := buildTarget(ctx, b, catalog, target, postBuild)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			elapsed := time.Since(start).Round(100 * time.Millisecond)
+			if err != nil {
+				failed++
+				fmt.Fprintf(out, "%s %s (%s): %v\n", failMark, target.Output, elapsed, err)
+				return
+			}
+			fmt.Fprintf(out, "%s %s (%s)\n", doneMark, target.Output, elapsed)
+		}()
+	}
+	wg.Wait()
+
+	if failed > 0 {
+		return fmt.Errorf("%w: %d of %d targets failed", ErrManifestBuildFailed, failed, len(m.Targets))
+	}
+
+	return nil
+}
+
+func buildTarget(
+	ctx context.Context,
+	b k6foundry.Builder,
+	catalog k6foundry.Catalog,
+	target manifestTarget,
+	postBuild postBuildFunc,
+) error {
+	platform := k6foundry.RuntimePlatform()
+	if target.Platform != "" {
+		var err error
+		platform, err = k6foundry.ParsePlatform(target.Platform)
+		if err != nil {
+			return err
+		}
+	}
+
+	k6Version := target.K6Version
+	if k6Version == "" {
+		k6Version = "latest"
+	}
+
+	mods := []k6foundry.Module{}
+	for _, d := range target.Dependencies {
+		mod, err := parseDependency(catalog, d)
+		if err != nil {
+			return err
+		}
+		mods = append(mods, mod)
+	}
+
+	if dir := filepath.Dir(target.Output); dir != "" {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return err
+		}
+	}
+
+	params := provenance.Parameters{
+		K6Version:    k6Version,
+		Dependencies: mods,
+		BuildOpts:    target.BuildOpts,
+		StartedOn:    time.Now(),
+	}
+
+	// the file is removed if the build fails, so no partial binary is left behind
+	buildInfo, err := k6foundry.BuildToFile(ctx, b, platform, k6Version, mods, target.BuildOpts, target.Output)
+	if err != nil {
+		return err
+	}
+
+	return postBuild(target.Output, buildInfo, params)
+}
@@ -0,0 +1,150 @@
+// nolint:forbidigo,nolintlint
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+
+	"github.com/grafana/k6foundry"
+
+	"github.com/spf13/cobra"
+)
+
+const platformsLong = `
+lists the platforms the go toolchain used for building can target, in the format os/arch.
+Platforms supported by k6 are marked with an asterisk.
+
+The platforms can be filtered by operating system and architecture.
+`
+
+const platformsExample = `
+# list all the platforms
+k6foundry platforms
+
+# list the linux and darwin platforms for arm64 as JSON
+k6foundry platforms --os linux --os darwin --arch arm64 --output-format json
+
+# list the platforms of the go toolchain in the container image
+k6foundry platforms --builder container
+`
+
+// platformEntry is the JSON representation of a platform
+type platformEntry struct {
+	OS   string `json:"os"`
+	Arch string `json:"arch"`
+	// the platform is supported by k6
+	Supported bool `json:"supported"`
+}
+
+// NewPlatforms returns a command for listing the platforms the toolchain can build for
+func NewPlatforms() *cobra.Command {
+	var (
+		opts          k6foundry.NativeBuilderOpts
+		containerOpts k6foundry.ContainerBuilderOpts
+		builderType   string
+		outputFormat  string
+		osFilter      []string
+		archFilter    []string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "platforms",
+		Short:   "list the platforms the go toolchain can build for",
+		Long:    platformsLong,
+		Example: platformsExample,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+
+			if outputFormat != outputFormatText && outputFormat != outputFormatJSON {
+				return fmt.Errorf("%w: %q", ErrInvalidOutputFormat, outputFormat)
+			}
+
+			opts.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+			var (
+				b   k6foundry.Builder
+				err error
+			)
+			switch builderType {
+			case "native":
+				b, err = k6foundry.NewNativeBuilder(ctx, opts)
+			case "container":
+				containerOpts.NativeBuilderOpts = opts
+				b, err = k6foundry.NewContainerBuilder(ctx, containerOpts)
+			default:
+				err = fmt.Errorf("%w: %q", ErrInvalidBuilder, builderType)
+			}
+			if err != nil {
+				return err
+			}
+
+			lister, ok := b.(k6foundry.PlatformLister)
+			if !ok {
+				return fmt.Errorf("%w: listing platforms not supported", ErrInvalidBuilder)
+			}
+
+			platforms, err := lister.Platforms(ctx)
+			if err != nil {
+				return err
+			}
+
+			entries := []platformEntry{}
+			for _, p := range platforms {
+				if len(osFilter) > 0 && !slices.Contains(osFilter, p.OS) {
+					continue
+				}
+				if len(archFilter) > 0 && !slices.Contains(archFilter, p.Arch) {
+					continue
+				}
+				entries = append(entries, platformEntry{OS: p.OS, Arch: p.Arch, Supported: p.Supported()})
+			}
+
+			if outputFormat == outputFormatJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+
+				return encoder.Encode(entries)
+			}
+
+			for _, e := range entries {
+				mark := ""
+				if e.Supported {
+					mark = " *"
+				}
+				fmt.Printf("%s/%s%s\n", e.OS, e.Arch, mark)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&osFilter, "os", []string{}, "list only the platforms for the operating system."+
+		" Can be repeated")
+	cmd.Flags().StringSliceVar(&archFilter, "arch", []string{}, "list only the platforms for the architecture."+
+		" Can be repeated")
+	cmd.Flags().StringVar(&outputFormat, "output-format", outputFormatText, "format of the output: text or json")
+	cmd.Flags().BoolVar(&opts.CopyGoEnv, "copy-go-env", true, "copy current go environment")
+	addGoDownloadFlags(cmd, &opts.GoOpts)
+	cmd.Flags().StringToStringVarP(&opts.Env, "env", "e", nil, "build environment variables")
+	cmd.Flags().StringVar(&opts.GoVersion, "go-version", "", "go toolchain version (e.g. 1.22.5)."+
+		" Downloaded if it is not the installed one")
+	cmd.Flags().StringVar(&builderType, "builder", "native", "builder whose toolchain is listed: native or container")
+	cmd.Flags().StringVar(
+		&containerOpts.Engine,
+		"container-engine",
+		k6foundry.DefaultContainerEngine,
+		"container engine used by the container builder (docker or podman)",
+	)
+	cmd.Flags().StringVar(
+		&containerOpts.Image,
+		"container-image",
+		k6foundry.DefaultContainerImage,
+		"golang image used by the container builder",
+	)
+
+	return cmd
+}
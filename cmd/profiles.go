@@ -0,0 +1,172 @@
+// nolint:forbidigo,nolintlint
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/grafana/k6foundry"
+
+	"github.com/spf13/cobra"
+)
+
+// ErrProfileConflict is returned when the profiles of a build request different k6 versions
+var ErrProfileConflict = errors.New("profiles request different k6 versions") //nolint:revive
+
+const profilesLong = `
+lists the build profiles. A profile is a named set of extensions and build options that can be built
+using the --profile option of the build, run and resolve commands.
+
+The built-in profiles can be extended or overridden with a profiles file (YAML or JSON) given with --profiles-file.
+`
+
+const profilesExample = `
+# list the built-in profiles
+k6foundry profiles
+
+# list the built-in profiles and the profiles defined in a file as JSON
+k6foundry profiles --profiles-file profiles.yaml --output-format json
+`
+
+// profileSettings are the dependencies, build options and k6 version given by the profiles of a build
+type profileSettings struct {
+	deps      []string
+	buildOpts []string
+	k6Version string
+}
+
+// loadProfileSettings combines the settings of the profiles, in the given order
+func loadProfileSettings(profilesFile string, names []string) (profileSettings, error) {
+	settings := profileSettings{}
+	if len(names) == 0 {
+		return settings, nil
+	}
+
+	profiles, err := k6foundry.LoadProfiles(profilesFile)
+	if err != nil {
+		return settings, err
+	}
+
+	for _, name := range names {
+		profile, err := profiles.Get(name)
+		if err != nil {
+			return settings, err
+		}
+
+		settings.deps = append(settings.deps, profile.Dependencies...)
+		settings.buildOpts = append(settings.buildOpts, profile.BuildOpts...)
+
+		if profile.K6Version == "" {
+			continue
+		}
+		if settings.k6Version != "" && settings.k6Version != profile.K6Version {
+			return settings, fmt.Errorf("%w: %s and %s", ErrProfileConflict, settings.k6Version, profile.K6Version)
+		}
+		settings.k6Version = profile.K6Version
+	}
+
+	return settings, nil
+}
+
+// parseModules parses the dependencies of the profiles and the dependencies given explicitly, which replace
+// the dependencies of the profiles with the same module path
+func parseModules(catalog k6foundry.Catalog, profileDeps []string, deps []string) ([]k6foundry.Module, error) {
+	mods := []k6foundry.Module{}
+	for _, d := range deps {
+		mod, err := parseDependency(catalog, d)
+		if err != nil {
+			return nil, err
+		}
+		mods = append(mods, mod)
+	}
+
+	profileMods := []k6foundry.Module{}
+	for _, d := range profileDeps {
+		mod, err := parseDependency(catalog, d)
+		if err != nil {
+			return nil, err
+		}
+
+		explicit := slices.ContainsFunc(mods, func(m k6foundry.Module) bool { return m.Path == mod.Path })
+		duplicated := slices.ContainsFunc(profileMods, func(m k6foundry.Module) bool { return m.Path == mod.Path })
+		if !explicit && !duplicated {
+			profileMods = append(profileMods, mod)
+		}
+	}
+
+	return append(profileMods, mods...), nil
+}
+
+// addProfileFlags adds the flags for selecting the profiles of a build
+func addProfileFlags(cmd *cobra.Command, names *[]string, profilesFile *string) {
+	cmd.Flags().StringArrayVar(names, "profile", []string{}, "build profile adding a set of extensions and build"+
+		" options (e.g. kafka-suite). Can be repeated. List the profiles with the profiles command")
+	cmd.Flags().StringVar(profilesFile, "profiles-file", "", "file (YAML or JSON) with profiles that extend or"+
+		" override the built-in profiles")
+}
+
+// NewProfiles returns a command for listing the build profiles
+func NewProfiles() *cobra.Command {
+	var (
+		profilesFile string
+		outputFormat string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "profiles",
+		Short:   "list the build profiles",
+		Long:    profilesLong,
+		Example: profilesExample,
+		Args:    cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			if outputFormat != outputFormatText && outputFormat != outputFormatJSON {
+				return fmt.Errorf("%w: %q", ErrInvalidOutputFormat, outputFormat)
+			}
+
+			profiles, err := k6foundry.LoadProfiles(profilesFile)
+			if err != nil {
+				return err
+			}
+
+			if outputFormat == outputFormatJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+
+				return encoder.Encode(profiles)
+			}
+
+			names := make([]string, 0, len(profiles))
+			for name := range profiles {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				profile := profiles[name]
+				fmt.Printf("%s: %s\n", name, profile.Description)
+				if len(profile.Dependencies) > 0 {
+					fmt.Printf("  dependencies: %s\n", strings.Join(profile.Dependencies, ", "))
+				}
+				if len(profile.BuildOpts) > 0 {
+					fmt.Printf("  build opts: %s\n", strings.Join(profile.BuildOpts, " "))
+				}
+				if profile.K6Version != "" {
+					fmt.Printf("  k6 version: %s\n", profile.K6Version)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&profilesFile, "profiles-file", "", "file (YAML or JSON) with profiles that extend or"+
+		" override the built-in profiles")
+	cmd.Flags().StringVar(&outputFormat, "output-format", outputFormatText, "format of the output: text or json")
+
+	return cmd
+}
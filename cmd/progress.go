@@ -0,0 +1,168 @@
+// nolint:forbidigo,nolintlint
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/grafana/k6foundry"
+	"github.com/grafana/k6foundry/pkg/util"
+)
+
+const (
+	spinnerInterval = 100 * time.Millisecond
+	clearLine       = "\r\033[K"
+	doneMark        = "✓"
+	failMark        = "✗"
+)
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"} //nolint:gochecknoglobals
+
+// progress renders the progress of a build in a terminal. Each phase is shown in a line with a spinner
+// and its elapsed time, which is replaced by a mark when the phase completes.
+type progress struct {
+	mutex      sync.Mutex
+	out        io.Writer
+	start      time.Time
+	step       string
+	stepStart  time.Time
+	frame      int
+	done       chan struct{}
+	tickerDone chan struct{}
+}
+
+// isTerminal returns true if the file is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func newProgress(out io.Writer) *progress {
+	return &progress{
+		out:        out,
+		done:       make(chan struct{}),
+		tickerDone: make(chan struct{}),
+	}
+}
+
+// Start starts rendering the spinner of the current step
+func (p *progress) Start() {
+	go func() {
+		defer close(p.tickerDone)
+
+		ticker := time.NewTicker(spinnerInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-p.done:
+				return
+			case <-ticker.C:
+				p.mutex.Lock()
+				p.frame = (p.frame + 1) % len(spinnerFrames)
+				p.render()
+				p.mutex.Unlock()
+			}
+		}
+	}()
+}
+
+// Stop stops rendering the progress
+func (p *progress) Stop() {
+	close(p.done)
+	<-p.tickerDone
+}
+
+// Handle updates the progress from a build event
+func (p *progress) Handle(event k6foundry.Event) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	switch event.Type {
+	case k6foundry.EventBuildStarted:
+		p.start = event.Time
+		p.begin("Initializing build", event.Time)
+	case k6foundry.EventModuleResolving:
+		p.complete("", event.Time)
+		p.begin(fmt.Sprintf("Resolving %s@%s", event.Module, event.Version), event.Time)
+	case k6foundry.EventModuleResolved:
+		p.complete(fmt.Sprintf("Resolved %s %s", event.Module, event.Version), event.Time)
+	case k6foundry.EventCompiling:
+		p.complete("", event.Time)
+		p.begin("Compiling k6 for "+event.Platform, event.Time)
+	case k6foundry.EventCompiled:
+		p.complete("", event.Time)
+	case k6foundry.EventBinaryWriting:
+		step := fmt.Sprintf("Writing binary (%s of %s)", util.FormatSize(event.Bytes), util.FormatSize(event.Total))
+		if event.Bytes == 0 {
+			p.begin(step, event.Time)
+			return
+		}
+		p.step = step
+		p.render()
+	case k6foundry.EventBinaryWritten:
+		p.complete(fmt.Sprintf("Written binary (%s)", util.FormatSize(event.Bytes)), event.Time)
+	case k6foundry.EventBuildFinished:
+		if event.Err != nil {
+			p.fail(event.Time)
+			return
+		}
+		p.complete("", event.Time)
+		fmt.Fprintf(p.out, "%s Build completed in %s\n", doneMark, elapsed(p.start, event.Time))
+	}
+}
+
+func (p *progress) begin(step string, t time.Time) {
+	p.step = step
+	p.stepStart = t
+	p.render()
+}
+
+// complete marks the current step as completed, optionally replacing its description
+func (p *progress) complete(description string, t time.Time) {
+	if p.step == "" {
+		return
+	}
+
+	if description == "" {
+		description = p.step
+	}
+
+	fmt.Fprintf(p.out, "%s%s %s (%s)\n", clearLine, doneMark, description, elapsed(p.stepStart, t))
+	p.step = ""
+}
+
+func (p *progress) fail(t time.Time) {
+	if p.step == "" {
+		return
+	}
+
+	fmt.Fprintf(p.out, "%s%s %s (%s)\n", clearLine, failMark, p.step, elapsed(p.stepStart, t))
+	p.step = ""
+}
+
+func (p *progress) render() {
+	if p.step == "" {
+		return
+	}
+
+	fmt.Fprintf(
+		p.out,
+		"%s%s %s (%s)",
+		clearLine,
+		spinnerFrames[p.frame],
+		p.step,
+		elapsed(p.stepStart, time.Now()),
+	)
+}
+
+func elapsed(from time.Time, to time.Time) string {
+	return to.Sub(from).Round(100 * time.Millisecond).String()
+}
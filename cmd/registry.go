@@ -0,0 +1,131 @@
+// nolint:forbidigo,nolintlint
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/grafana/k6foundry"
+
+	"github.com/spf13/cobra"
+)
+
+const extensionsLong = `
+lists the extensions in the k6 extension registry with their descriptions.
+
+The optional argument filters the extensions whose module path or description contains it.
+`
+
+const extensionsExample = `
+# list the extensions in the registry
+k6foundry extensions
+
+# list the extensions related to kafka as JSON
+k6foundry extensions kafka --output-format json
+`
+
+// checkRegistry checks the modules are extensions in the registry. Modules replaced by a local
+// directory are not checked, as they are usually in development
+func checkRegistry(ctx context.Context, registryURL string, mods []k6foundry.Module) error {
+	registry, err := k6foundry.FetchRegistry(ctx, k6foundry.RegistryOpts{URL: registryURL})
+	if err != nil {
+		return err
+	}
+
+	errs := []error{}
+	for _, mod := range mods {
+		if mod.ReplacePath != "" && mod.ReplaceVersion == "" {
+			continue
+		}
+
+		if err := registry.Validate(mod.Path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// addRegistryFlags adds the flags for checking the extensions in the registry
+func addRegistryFlags(cmd *cobra.Command, check *bool, registryURL *string) {
+	cmd.Flags().BoolVar(check, "check-registry", false, "check the extensions are in the extension registry,"+
+		" suggesting similar extensions if not")
+	cmd.Flags().StringVar(registryURL, "registry-url", k6foundry.DefaultRegistryURL, "URL of the extension registry")
+}
+
+// NewExtensions returns a command for listing the extensions in the registry
+func NewExtensions() *cobra.Command {
+	var (
+		registryURL  string
+		outputFormat string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "extensions [filter]",
+		Short:   "list the extensions in the extension registry",
+		Long:    extensionsLong,
+		Example: extensionsExample,
+		Args:    cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if outputFormat != outputFormatText && outputFormat != outputFormatJSON {
+				return fmt.Errorf("%w: %q", ErrInvalidOutputFormat, outputFormat)
+			}
+
+			registry, err := k6foundry.FetchRegistry(cmd.Context(), k6foundry.RegistryOpts{URL: registryURL})
+			if err != nil {
+				return err
+			}
+
+			if len(args) > 0 {
+				registry = filterRegistry(registry, args[0])
+			}
+
+			if outputFormat == outputFormatJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+
+				return encoder.Encode(registry)
+			}
+
+			for _, ext := range registry {
+				fmt.Printf("%s", ext.Module)
+				if len(ext.Versions) > 0 {
+					fmt.Printf(" (%s)", ext.Versions[0])
+				}
+				if ext.Tier != "" {
+					fmt.Printf(" [%s]", ext.Tier)
+				}
+				fmt.Println()
+				if ext.Description != "" {
+					fmt.Printf("  %s\n", ext.Description)
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&registryURL, "registry-url", k6foundry.DefaultRegistryURL, "URL of the extension registry")
+	cmd.Flags().StringVar(&outputFormat, "output-format", outputFormatText, "format of the output: text or json")
+
+	return cmd
+}
+
+// filterRegistry returns the extensions whose module path or description contains the filter
+func filterRegistry(registry k6foundry.Registry, filter string) k6foundry.Registry {
+	filter = strings.ToLower(filter)
+
+	filtered := k6foundry.Registry{}
+	for _, ext := range registry {
+		if strings.Contains(strings.ToLower(ext.Module), filter) ||
+			strings.Contains(strings.ToLower(ext.Description), filter) {
+			filtered = append(filtered, ext)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,67 @@
+// nolint:forbidigo,nolintlint
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/grafana/k6foundry"
+)
+
+const (
+	outputFormatText = "text"
+	outputFormatJSON = "json"
+)
+
+// ErrInvalidOutputFormat is returned when the output format is not supported
+var ErrInvalidOutputFormat = errors.New("invalid output format")
+
+// imageResult describes the image produced by the build command
+type imageResult struct {
+	Reference string `json:"reference"`
+	Digest    string `json:"digest"`
+}
+
+// buildReport is the machine-readable result of the build command
+type buildReport struct {
+	mutex           sync.Mutex
+	start           time.Time
+	Builds          []k6foundry.BuildResult `json:"builds"`
+	Image           *imageResult            `json:"image,omitempty"`
+	DurationSeconds float64                 `json:"durationSeconds"`
+}
+
+func newBuildReport() *buildReport {
+	return &buildReport{start: time.Now(), Builds: []k6foundry.BuildResult{}}
+}
+
+// add records a binary in the report. It can be called concurrently.
+func (r *buildReport) add(path string, info *k6foundry.BuildInfo) error {
+	result, err := k6foundry.NewBuildResult(path, info)
+	if err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.Builds = append(r.Builds, result)
+
+	return nil
+}
+
+// write writes the report as JSON to the out io.Writer
+func (r *buildReport) write(out io.Writer) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.DurationSeconds = time.Since(r.start).Seconds()
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(r)
+}
@@ -0,0 +1,231 @@
+// nolint:forbidigo,nolintlint
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/grafana/k6foundry"
+	"github.com/grafana/k6foundry/pkg/util"
+
+	"github.com/spf13/cobra"
+)
+
+const resolveLong = `
+resolves the versions of k6 and the extensions without building the binary, printing the version of each
+module that would be included in it, including the transitive dependencies of k6 and the extensions.
+
+The versions are resolved as the build command would (e.g. latest or a constraint), so the result can be
+checked before starting a build.
+`
+
+const resolveExample = `
+# resolve the latest version of k6 and xk6-kubernetes
+k6foundry resolve -d github.com/grafana/xk6-kubernetes
+
+# resolve the versions as JSON
+k6foundry resolve -v ~v0.50.0 -d github.com/grafana/xk6-kubernetes --output-format json
+`
+
+// module path of k6 in the resolved versions
+const k6ModulePath = "go.k6.io/k6"
+
+// resolveResult is the machine-readable output of the resolve command
+type resolveResult struct {
+	K6Version  string            `json:"k6Version"`
+	Extensions map[string]string `json:"extensions"`
+	// all the modules, including k6, the extensions and their transitive dependencies, sorted by path
+	Dependencies []k6foundry.Dependency `json:"dependencies"`
+}
+
+// NewResolve creates a new cobra command for the resolve command.
+func NewResolve() *cobra.Command {
+	var (
+		opts            k6foundry.NativeBuilderOpts
+		graph           graphFlags
+		deps            []string
+		k6Version       string
+		k6Repo          string
+		verbose         bool
+		logLevelText    string
+		catalogPath     string
+		profiles        []string
+		profilesFile    string
+		outputFormat    string
+		resolveCacheTTL time.Duration
+		resolveCache    bool
+		noResolveCache  bool
+		checkExtensions bool
+		registryURL     string
+	)
+
+	cmd := &cobra.Command{
+		Use:     "resolve",
+		Short:   "resolve the versions of k6 and the extensions without building",
+		Long:    resolveLong,
+		Example: resolveExample,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+
+			if outputFormat != outputFormatText && outputFormat != outputFormatJSON {
+				return fmt.Errorf("%w: %q", ErrInvalidOutputFormat, outputFormat)
+			}
+
+			var err error
+			var catalog k6foundry.Catalog
+			if catalogPath != "" {
+				catalog, err = k6foundry.LoadCatalog(catalogPath)
+				if err != nil {
+					return err
+				}
+			}
+
+			profile, err := loadProfileSettings(profilesFile, profiles)
+			if err != nil {
+				return err
+			}
+
+			mods, err := parseModules(catalog, profile.deps, deps)
+			if err != nil {
+				return err
+			}
+
+			if checkExtensions {
+				if err = checkRegistry(ctx, registryURL, mods); err != nil {
+					return err
+				}
+			}
+
+			if profile.k6Version != "" && !cmd.Flags().Changed("k6-version") {
+				k6Version = profile.k6Version
+			}
+
+			if verbose {
+				opts.Stdout = os.Stderr
+				opts.Stderr = os.Stderr
+			}
+
+			logLevel, err := util.ParseLogLevel(logLevelText)
+			if err != nil {
+				return fmt.Errorf("parsing log level %w", err)
+			}
+
+			if err = graph.apply(&opts); err != nil {
+				return err
+			}
+
+			opts.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
+			opts.LogGoOutput = !verbose
+			opts.ListDependencies = true
+			opts.K6Repo, opts.K6RepoVersion = splitK6Repo(k6Repo)
+
+			k6Version, err = resolveK6Version(ctx, k6Version, opts, resolveCacheTTL, resolveCache && !noResolveCache)
+			if err != nil {
+				return err
+			}
+
+			b, err := k6foundry.NewNativeBuilder(ctx, opts)
+			if err != nil {
+				return err
+			}
+
+			resolution, err := b.(k6foundry.Resolver).Resolve(ctx, k6Version, mods)
+			if err != nil {
+				return err
+			}
+
+			result := newResolveResult(resolution.BuildInfo)
+
+			if outputFormat == outputFormatJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+
+				return encoder.Encode(result)
+			}
+
+			writeResolveResult(os.Stdout, result)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&deps, "dependency", "d", []string{}, "list of dependencies using go mod format:"+
+		" path[@version][replace@version]")
+	addGraphFlags(cmd, &graph)
+	addProfileFlags(cmd, &profiles, &profilesFile)
+	addRegistryFlags(cmd, &checkExtensions, &registryURL)
+	cmd.Flags().StringVar(&catalogPath, "catalog", "", "catalog (JSON or YAML) mapping dependency names to modules."+
+		" Dependencies in the catalog can be specified as name[@constraint]")
+	cmd.Flags().StringVarP(&k6Version, "k6-version", "v", "latest", "k6 version."+
+		" Can be a version, latest, latest-N (e.g. latest-1) or a constraint (e.g. ~v0.50.0)")
+	cmd.Flags().BoolVar(&resolveCache, "resolve-cache", false, "reuse the versions resolved for latest and other"+
+		" version specifications in subsequent builds. latest is resolved querying the module proxy instead of go")
+	cmd.Flags().DurationVar(&resolveCacheTTL, "resolve-cache-ttl", k6foundry.DefaultResolveCacheTTL, "time the"+
+		" resolved versions are reused with --resolve-cache")
+	cmd.Flags().BoolVar(&noResolveCache, "no-resolve-cache", false, "don't reuse resolved versions, even with"+
+		" --resolve-cache. latest is resolved by go")
+	cmd.Flags().StringVarP(&k6Repo, "k6-repository", "r", "", "k6 repository. A local directory or"+
+		" the module path of a fork with its version (e.g. github.com/my-org/k6@v0.51.0-custom)")
+	cmd.Flags().BoolVar(&opts.CopyGoEnv, "copy-go-env", true, "copy current go environment")
+	addGoDownloadFlags(cmd, &opts.GoOpts)
+	cmd.Flags().DurationVar(&opts.GoGetTimeout, "get-timeout", k6foundry.DefaultGoGetTimeout, "timeout for each go"+
+		" command that downloads modules. A negative value disables it")
+	cmd.Flags().StringVar(&logLevelText, "log-level", "WARN", "log level")
+	cmd.Flags().BoolVar(&verbose, "verbose", false, "verbose output of the go commands")
+	cmd.Flags().StringToStringVarP(&opts.Env, "env", "e", nil, "build environment variables")
+	cmd.Flags().StringVar(&opts.GoCacheDir, "go-cache-dir", "", "directory for the go module and build caches."+
+		" Defaults to the caches of the go environment")
+	cmd.Flags().BoolVar(&opts.Offline, "offline", false, "resolve without network access, using only the"+
+		" modules in the go module cache")
+	cmd.Flags().StringVar(&outputFormat, "output-format", outputFormatText, "format of the output: text or json")
+
+	return cmd
+}
+
+// newResolveResult returns the result of the resolution, separating k6 from the extensions
+func newResolveResult(info *k6foundry.BuildInfo) resolveResult {
+	result := resolveResult{
+		Extensions:   map[string]string{},
+		Dependencies: info.Dependencies,
+	}
+
+	for path, version := range info.ModVersions {
+		if path == k6ModulePath {
+			result.K6Version = version
+			continue
+		}
+		result.Extensions[path] = version
+	}
+
+	if result.Dependencies == nil {
+		result.Dependencies = []k6foundry.Dependency{}
+	}
+
+	return result
+}
+
+// writeResolveResult writes the result of the resolution in text format
+func writeResolveResult(out io.Writer, result resolveResult) {
+	fmt.Fprintf(out, "k6: %s\n", result.K6Version)
+
+	exts := make([]string, 0, len(result.Extensions))
+	for ext := range result.Extensions {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+
+	fmt.Fprintf(out, "extensions:\n")
+	for _, ext := range exts {
+		fmt.Fprintf(out, "  %s %s\n", ext, result.Extensions[ext])
+	}
+
+	fmt.Fprintf(out, "modules:\n")
+	for _, dep := range result.Dependencies {
+		fmt.Fprintf(out, "  %s %s\n", dep.Path, dep.Version)
+	}
+}
@@ -0,0 +1,238 @@
+// nolint:forbidigo,nolintlint
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/grafana/k6foundry"
+	"github.com/grafana/k6foundry/pkg/cache"
+	"github.com/grafana/k6foundry/pkg/util"
+
+	"github.com/spf13/cobra"
+)
+
+const runLong = `
+builds a temporary k6 binary with extensions and runs it with the arguments given after --.
+The binary is removed after k6 exits, and the exit code of k6 is returned.
+
+Interrupting the command (e.g. with ctrl-c) stops k6 gracefully.
+`
+
+const runExample = `
+# run a script with the latest version of k6 and xk6-kubernetes
+k6foundry run -d github.com/grafana/xk6-kubernetes -- run script.js
+
+# run a script with k6 v0.50.0 and a local extension
+k6foundry run -v v0.50.0 -d github.com/my-org/xk6-ext=../xk6-ext -- run script.js
+
+# reuse the binary between runs with the same versions
+k6foundry run -d github.com/grafana/xk6-kubernetes@v0.9.0 --cache-dir ~/.cache/k6foundry/binaries -- version
+`
+
+// RunExitError is returned by the run command when k6 exits with a non-zero exit code
+type RunExitError struct {
+	Code int
+}
+
+func (e *RunExitError) Error() string {
+	return fmt.Sprintf("k6 exited with code %d", e.Code)
+}
+
+// NewRun creates a new cobra command for the run command.
+func NewRun() *cobra.Command {
+	var (
+		preset          string
+		opts            k6foundry.NativeBuilderOpts
+		deps            []string
+		graph           graphFlags
+		k6Version       string
+		k6Repo          string
+		buildOpts       []string
+		verbose         bool
+		logLevelText    string
+		cacheDir        string
+		catalogPath     string
+		profiles        []string
+		profilesFile    string
+		resolveCacheTTL time.Duration
+		resolveCache    bool
+		noResolveCache  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:     "run [flags] -- [k6 arguments]",
+		Short:   "build a temporary k6 binary with extensions and run it",
+		Long:    runLong,
+		Example: runExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := cmd.Context()
+
+			var err error
+			var catalog k6foundry.Catalog
+			if catalogPath != "" {
+				catalog, err = k6foundry.LoadCatalog(catalogPath)
+				if err != nil {
+					return err
+				}
+			}
+
+			profile, err := loadProfileSettings(profilesFile, profiles)
+			if err != nil {
+				return err
+			}
+
+			mods, err := parseModules(catalog, profile.deps, deps)
+			if err != nil {
+				return err
+			}
+
+			buildOpts = append(profile.buildOpts, buildOpts...)
+			if profile.k6Version != "" && !cmd.Flags().Changed("k6-version") {
+				k6Version = profile.k6Version
+			}
+
+			if verbose {
+				opts.Stdout = os.Stderr
+				opts.Stderr = os.Stderr
+			}
+
+			logLevel, err := util.ParseLogLevel(logLevelText)
+			if err != nil {
+				return fmt.Errorf("parsing log level %w", err)
+			}
+
+			if err = graph.apply(&opts); err != nil {
+				return err
+			}
+
+			opts.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
+			opts.LogGoOutput = !verbose
+			opts.K6Repo, opts.K6RepoVersion = splitK6Repo(k6Repo)
+
+			opts, err = k6foundry.ApplyPreset(opts, k6foundry.Preset(preset))
+			if err != nil {
+				return err
+			}
+
+			k6Version, err = resolveK6Version(ctx, k6Version, opts, resolveCacheTTL, resolveCache && !noResolveCache)
+			if err != nil {
+				return err
+			}
+
+			b, err := k6foundry.NewNativeBuilder(ctx, opts)
+			if err != nil {
+				return err
+			}
+
+			// binaries that depend on local modules or branches can't be cached (see Cacheable)
+			if cacheDir != "" && opts.Cacheable() {
+				var config string
+				if config, err = opts.ConfigDigest(); err != nil {
+					return err
+				}
+				b = cache.NewCachedBuilderWithOpts(b, cache.NewFileCache(cacheDir), cache.CachedBuilderOpts{Config: config})
+			}
+
+			binDir, err := os.MkdirTemp("", "k6foundry-run*")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(binDir) //nolint:errcheck
+
+			binary := filepath.Join(binDir, k6foundry.RuntimePlatform().BinaryName("k6"))
+
+			if err = buildBinary(ctx, b, k6Version, mods, buildOpts, binary); err != nil {
+				return err
+			}
+
+			return runK6(ctx, binary, args)
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&deps, "dependency", "d", []string{}, "list of dependencies using go mod format:"+
+		" path[@version][replace@version]")
+	addProfileFlags(cmd, &profiles, &profilesFile)
+	cmd.Flags().StringVar(&catalogPath, "catalog", "", "catalog (JSON or YAML) mapping dependency names to modules."+
+		" Dependencies in the catalog can be specified as name[@constraint]")
+	cmd.Flags().StringVarP(&k6Version, "k6-version", "v", "latest", "k6 version."+
+		" Can be a version, latest, latest-N (e.g. latest-1) or a constraint (e.g. ~v0.50.0)")
+	cmd.Flags().BoolVar(&resolveCache, "resolve-cache", false, "reuse the versions resolved for latest and other"+
+		" version specifications in subsequent builds. latest is resolved querying the module proxy instead of go")
+	cmd.Flags().DurationVar(&resolveCacheTTL, "resolve-cache-ttl", k6foundry.DefaultResolveCacheTTL, "time the"+
+		" resolved versions are reused with --resolve-cache")
+	cmd.Flags().BoolVar(&noResolveCache, "no-resolve-cache", false, "don't reuse resolved versions, even with"+
+		" --resolve-cache. latest is resolved by go")
+	cmd.Flags().StringVarP(&k6Repo, "k6-repository", "r", "", "k6 repository. A local directory or"+
+		" the module path of a fork with its version (e.g. github.com/my-org/k6@v0.51.0-custom)")
+	cmd.Flags().BoolVar(&opts.CopyGoEnv, "copy-go-env", true, "copy current go environment")
+	addGoDownloadFlags(cmd, &opts.GoOpts)
+	cmd.Flags().DurationVar(&opts.GoGetTimeout, "get-timeout", k6foundry.DefaultGoGetTimeout, "timeout for each go"+
+		" command that downloads modules. A negative value disables it")
+	cmd.Flags().DurationVar(&opts.GOBuildTimeout, "build-timeout", k6foundry.DefaultGoBuildTimeout, "timeout for"+
+		" compiling the binary. A negative value disables it")
+	cmd.Flags().StringVar(&logLevelText, "log-level", "WARN", "log level")
+	cmd.Flags().BoolVar(&verbose, "verbose", false, "verbose build output")
+	cmd.Flags().StringArrayVarP(&buildOpts, "build-opts", "b", []string{}, "go build opts. e.g. -ldflags='-w -s'")
+	addCompileFlags(cmd, &opts.GoOpts)
+	cmd.Flags().StringVar(&preset, "preset", "", "build preset: debug (keep the symbols and disable the optimizations),"+
+		" release (strip the symbols and debug information and trim the paths) or race (enable the race detector)")
+	cmd.Flags().StringToStringVarP(&opts.Env, "env", "e", nil, "build environment variables")
+	cmd.Flags().StringVar(&opts.GoCacheDir, "go-cache-dir", "", "directory for the go module and build caches."+
+		" Defaults to the caches of the go environment")
+	cmd.Flags().StringVar(&opts.BuildDirRoot, "build-dir-root", "", "parent directory of the temporary work"+
+		" directories and caches (e.g. a tmpfs mount). Defaults to the system temporary directory")
+	cmd.Flags().StringVar((*string)(&opts.Cleanup), "cleanup", "", "when the temporary work directory is removed:"+
+		" always (default), on-success (keeps the failed builds) or never")
+	cmd.Flags().DurationVar(&opts.ReapStaleAfter, "reap-stale-after", 0, "on start, remove the directories left in"+
+		" the build directory root by previous builds not modified in this time (e.g. 24h)")
+	addGraphFlags(cmd, &graph)
+	cmd.Flags().StringArrayVar(&opts.Workspace, "workspace", []string{}, "local module directory added to a go"+
+		" workspace used for building")
+	cmd.Flags().StringVar(&cacheDir, "cache-dir", "", "directory for caching the binaries. Runs using"+
+		" the same versions reuse the binary instead of building it")
+
+	return cmd
+}
+
+// buildBinary builds the binary for the runtime platform into the given path
+func buildBinary(
+	ctx context.Context,
+	b k6foundry.Builder,
+	k6Version string,
+	mods []k6foundry.Module,
+	buildOpts []string,
+	path string,
+) error {
+	_, err := k6foundry.BuildToFile(ctx, b, k6foundry.RuntimePlatform(), k6Version, mods, buildOpts, path)
+
+	return err
+}
+
+// runK6 executes the binary with the arguments, forwarding the interruption of the context to k6
+func runK6(ctx context.Context, binary string, args []string) error {
+	k6 := exec.CommandContext(ctx, binary, args...) //nolint:gosec
+	k6.Stdin = os.Stdin
+	k6.Stdout = os.Stdout
+	k6.Stderr = os.Stderr
+
+	// give k6 the chance to stop the test and report the results
+	k6.Cancel = func() error {
+		return k6.Process.Signal(os.Interrupt)
+	}
+
+	err := k6.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+		return &RunExitError{Code: exitErr.ExitCode()}
+	}
+
+	return err
+}
@@ -0,0 +1,233 @@
+// nolint:forbidigo,nolintlint
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/grafana/k6foundry"
+	"github.com/grafana/k6foundry/pkg/cache"
+	"github.com/grafana/k6foundry/pkg/metrics"
+	"github.com/grafana/k6foundry/pkg/server"
+	"github.com/grafana/k6foundry/pkg/util"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// time for completing the in-flight builds when the server is stopped. The builds that don't complete
+	// are canceled
+	shutdownTimeout = 30 * time.Second
+	// time for reading the request headers
+	readHeaderTimeout = 10 * time.Second
+)
+
+const serveLong = `
+starts a build service that exposes the builder over an HTTP API.
+
+Builds are requested with a POST to /build with a JSON body:
+
+  {"apiVersion": "k6foundry/v1", "platform": "linux/amd64", "k6Version": "v0.50.0",
+   "dependencies": ["github.com/grafana/xk6-sql@v0.4.0"]}
+
+The apiVersion is optional. The response contains the binary and its build result (build info, checksum
+and duration) in the X-K6foundry-Build-Info header.
+`
+
+const serveExample = `
+# start the build service in port 8000
+k6foundry serve --addr :8000
+
+# request a build
+curl -X POST http://localhost:8000/build -o k6 \
+    -d '{"k6Version": "v0.50.0", "dependencies": ["github.com/grafana/xk6-kubernetes"]}'
+`
+
+// NewServe creates new cobra command for serve command.
+func NewServe() *cobra.Command {
+	var (
+		preset          string
+		opts            k6foundry.NativeBuilderOpts
+		addr            string
+		logLevelText    string
+		cacheDir        string
+		maxCacheSize    string
+		minFreeSpace    string
+		resolveCacheTTL time.Duration
+		resolveCache    bool
+		noResolveCache  bool
+	)
+
+	cmd := &cobra.Command{
+		Use:     "serve",
+		Short:   "start a build service",
+		Long:    serveLong,
+		Example: serveExample,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			ctx := cmd.Context()
+
+			logLevel, err := util.ParseLogLevel(logLevelText)
+			if err != nil {
+				return fmt.Errorf("parsing log level %w", err)
+			}
+
+			if err = parseDiskLimits(&opts.GoOpts, maxCacheSize, minFreeSpace); err != nil {
+				return err
+			}
+
+			log := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel}))
+			opts.Logger = log
+			opts.LogGoOutput = true
+
+			collector := metrics.NewCollector()
+			opts.Metrics = collector
+
+			opts, err = k6foundry.ApplyPreset(opts, k6foundry.Preset(preset))
+			if err != nil {
+				return err
+			}
+
+			b, err := k6foundry.NewNativeBuilder(ctx, opts)
+			if err != nil {
+				return err
+			}
+
+			if cacheDir != "" && opts.Cacheable() {
+				var config string
+				if config, err = opts.ConfigDigest(); err != nil {
+					return err
+				}
+				cacheOpts := cache.CachedBuilderOpts{Config: config, Metrics: collector}
+				b = cache.NewCachedBuilderWithOpts(b, cache.NewFileCache(cacheDir), cacheOpts)
+			}
+
+			// the resolved versions are used as cache keys, so builds of latest are also cached
+			if resolveCache && !noResolveCache && !opts.Offline {
+				var goProxy string
+				if goProxy, err = opts.GoProxy(); err != nil {
+					return err
+				}
+				resolver := k6foundry.NewVersionResolver(k6foundry.VersionResolverOpts{
+					VersionsOpts: k6foundry.VersionsOpts{GoProxy: goProxy},
+					TTL:          resolveCacheTTL,
+				})
+				b = k6foundry.NewResolvingBuilder(b, resolver)
+			}
+
+			mux := http.NewServeMux()
+			mux.Handle("/build", server.NewBuildHandler(b, log))
+			mux.Handle("/metrics", collector)
+
+			// the builds are not canceled when the service is stopped, so they can complete, unless they
+			// exceed the shutdown timeout
+			buildsCtx, cancelBuilds := context.WithCancel(context.WithoutCancel(ctx))
+			defer cancelBuilds()
+
+			requests := &sync.WaitGroup{}
+
+			srv := &http.Server{
+				Addr:              addr,
+				Handler:           trackRequests(mux, requests),
+				ReadHeaderTimeout: readHeaderTimeout,
+				BaseContext:       func(net.Listener) context.Context { return buildsCtx },
+			}
+
+			srvErr := make(chan error, 1)
+			go func() {
+				log.Info(fmt.Sprintf("starting build service at %s", addr))
+				srvErr <- srv.ListenAndServe()
+			}()
+
+			select {
+			case err = <-srvErr:
+				return err
+			case <-ctx.Done():
+			}
+
+			log.Info("stopping build service")
+
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+			defer cancel()
+
+			err = srv.Shutdown(shutdownCtx)
+
+			// the builds that didn't complete are canceled, waiting for them to clean up
+			if errors.Is(err, context.DeadlineExceeded) {
+				log.Warn("canceling the builds in progress")
+				cancelBuilds()
+				requests.Wait()
+			}
+
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+
+			return err
+		},
+	}
+
+	cmd.Flags().StringVar(&addr, "addr", ":8000", "listening address")
+	cmd.Flags().BoolVar(&opts.CopyGoEnv, "copy-go-env", true, "copy current go environment")
+	addGoDownloadFlags(cmd, &opts.GoOpts)
+	cmd.Flags().StringToStringVarP(&opts.Env, "env", "e", nil, "build environment variables")
+	addCompileFlags(cmd, &opts.GoOpts)
+	cmd.Flags().StringVar(&preset, "preset", "", "build preset: debug (keep the symbols and disable the optimizations),"+
+		" release (strip the symbols and debug information and trim the paths) or race (enable the race detector)")
+	cmd.Flags().IntVar(&opts.GetRetries, "get-retries", 0, "number of retries when downloading modules fails"+
+		" due to a transient network failure")
+	cmd.Flags().DurationVar(&opts.GetRetryBackoff, "get-retry-backoff", time.Second, "delay before the first retry."+
+		" Doubles on each retry")
+	cmd.Flags().DurationVar(&opts.GoGetTimeout, "get-timeout", k6foundry.DefaultGoGetTimeout, "timeout for each go"+
+		" command that downloads modules. A negative value disables it")
+	cmd.Flags().DurationVar(&opts.GOBuildTimeout, "build-timeout", k6foundry.DefaultGoBuildTimeout, "timeout for"+
+		" compiling the binary. A negative value disables it")
+	cmd.Flags().StringVar(&logLevelText, "log-level", "INFO", "log level")
+	cmd.Flags().BoolVar(&resolveCache, "resolve-cache", false, "reuse the versions resolved for latest and other"+
+		" version specifications in subsequent builds. latest is resolved querying the module proxy instead of go")
+	cmd.Flags().DurationVar(&resolveCacheTTL, "resolve-cache-ttl", k6foundry.DefaultResolveCacheTTL, "time the"+
+		" resolved versions are reused with --resolve-cache")
+	cmd.Flags().BoolVar(&noResolveCache, "no-resolve-cache", false, "don't reuse resolved versions, even with"+
+		" --resolve-cache. latest is resolved by go")
+	cmd.Flags().StringVar(&cacheDir, "cache-dir", "", "directory for caching the binaries")
+	cmd.Flags().StringVar(&opts.GoCacheDir, "go-cache-dir", "", "directory for the go module and build caches."+
+		" Access is coordinated between concurrent builds. Defaults to the caches of the go environment")
+	cmd.Flags().StringVar(&opts.BuildDirRoot, "build-dir-root", "", "parent directory of the temporary work"+
+		" directories and caches (e.g. a tmpfs mount). Defaults to the system temporary directory")
+	cmd.Flags().StringVar((*string)(&opts.Cleanup), "cleanup", "", "when the temporary work directory is removed:"+
+		" always (default), on-success (keeps the failed builds) or never")
+	cmd.Flags().DurationVar(&opts.ReapStaleAfter, "reap-stale-after", 0, "on start, remove the directories left in"+
+		" the build directory root by previous builds not modified in this time (e.g. 24h)")
+	cmd.Flags().BoolVar(&opts.DetectLicenses, "licenses", false, "detect the license of each module included in"+
+		" the binary and report it in the build info and SBOM")
+	cmd.Flags().StringArrayVar(&opts.DeniedLicenses, "deny-license", []string{}, "fail if a module has this"+
+		" license (SPDX identifier, e.g. GPL-3.0). Also denies its variants (e.g. GPL-3.0-only)")
+	cmd.Flags().BoolVar(&opts.Offline, "offline", false, "build without network access, using only the"+
+		" modules in the go module cache")
+	cmd.Flags().StringVar(&maxCacheSize, "max-cache-size", "", "maximum size of the caches in --go-cache-dir"+
+		" (e.g. 10GB). The least recently used entries are removed after each build")
+	cmd.Flags().StringVar(&minFreeSpace, "min-free-space", "", "minimum free disk space in the work and cache"+
+		" directories required for building (e.g. 1GB)")
+	cmd.Flags().IntVar(&opts.ConcurrentBuilds, "concurrent-builds", 0, "maximum number of concurrent builds."+
+		" 0 means no limit")
+	cmd.Flags().BoolVar(&opts.QueueBuilds, "queue-builds", false, "queue the builds exceeding --concurrent-builds"+
+		" instead of rejecting them with status 503")
+
+	return cmd
+}
+
+// trackRequests returns a handler that adds the requests in progress to the wait group
+func trackRequests(next http.Handler, requests *sync.WaitGroup) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		defer requests.Done()
+
+		next.ServeHTTP(w, r)
+	})
+}
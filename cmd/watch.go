@@ -0,0 +1,73 @@
+//nolint:forbidigo
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/grafana/k6foundry"
+)
+
+// ErrWatchConflict is returned when watching is combined with options that build more than one binary
+var ErrWatchConflict = errors.New("--watch only supports building a single binary") //nolint:revive
+
+// watchBuild rebuilds the binary at outPath each time the local modules change, until the context is done
+func watchBuild(
+	ctx context.Context,
+	b k6foundry.Builder,
+	platform k6foundry.Platform,
+	k6Version string,
+	mods []k6foundry.Module,
+	buildOpts []string,
+	outPath string,
+	mode os.FileMode,
+	log *slog.Logger,
+) error {
+	db, ok := b.(k6foundry.DevBuilder)
+	if !ok {
+		return fmt.Errorf("%w: watch not supported", ErrInvalidBuilder)
+	}
+
+	onBuild := func(binary string, _ *k6foundry.BuildInfo, err error) error {
+		// build errors are reported and the previous binary is kept until the sources are fixed
+		if err != nil {
+			log.Error("build failed", "error", err)
+			return nil
+		}
+
+		if err = replaceFile(binary, outPath, mode); err != nil {
+			return err
+		}
+
+		log.Info("binary updated", "path", outPath)
+
+		return nil
+	}
+
+	return db.Watch(ctx, platform, k6Version, mods, buildOpts, onBuild)
+}
+
+// replaceFile copies the file to the target path, replacing it atomically so it can be in use
+func replaceFile(source string, target string, mode os.FileMode) error {
+	src, err := os.Open(source) //nolint:gosec
+	if err != nil {
+		return err
+	}
+	defer src.Close() //nolint:errcheck
+
+	dst, err := k6foundry.CreateAtomicFile(target, mode)
+	if err != nil {
+		return err
+	}
+	defer dst.Abort()
+
+	if _, err = io.Copy(dst, src); err != nil {
+		return err
+	}
+
+	return dst.Commit()
+}
@@ -0,0 +1,134 @@
+package k6foundry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+var (
+	// ErrIncompatibleExtension is returned when an extension requires a newer k6 version than the requested one
+	ErrIncompatibleExtension = errors.New("extension requires a newer k6 version")
+	// ErrIncompatibleGoVersion is returned when k6 requires a newer go version than the go toolchain
+	ErrIncompatibleGoVersion = errors.New("k6 requires a newer go version")
+)
+
+// incompatibility records an extension that requires a newer k6 version than the requested one
+type incompatibility struct {
+	extension string
+	requires  string
+}
+
+// incompatibleExtensions returns the extensions that require a newer k6 version than k6Version,
+// according to the go.mod of the extensions.
+func incompatibleExtensions(ctx context.Context, e *goEnv, k6Version string, exts []Module) ([]incompatibility, error) {
+	graph, err := e.modGraph(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	extPaths := map[string]bool{}
+	for _, m := range exts {
+		extPaths[m.Path] = true
+	}
+
+	found := []incompatibility{}
+	for _, edge := range graph {
+		from, _, _ := strings.Cut(edge[0], "@")
+		to, version, _ := strings.Cut(edge[1], "@")
+		if !extPaths[from] || to != defaultK6ModulePath {
+			continue
+		}
+
+		if semver.Compare(version, k6Version) > 0 {
+			found = append(found, incompatibility{extension: from, requires: version})
+		}
+	}
+
+	return found, nil
+}
+
+// checkCompatibility verifies the extensions don't require a newer k6 version than the requested one.
+// If StrictK6Version is set, fails with ErrIncompatibleExtension. Otherwise, logs a warning, as k6 will be
+// upgraded to the version required by the extensions.
+func (b *nativeBuilder) checkCompatibility(ctx context.Context, e *goEnv, k6Version string, exts []Module) error {
+	// the version of a local k6 repository can't be compared
+	if b.K6Repo != "" || len(exts) == 0 {
+		return nil
+	}
+
+	found, err := incompatibleExtensions(ctx, e, k6Version, exts)
+	if err != nil {
+		return err
+	}
+
+	for _, i := range found {
+		if b.StrictK6Version {
+			return fmt.Errorf("%w: %s requires k6 %s, requested %s", ErrIncompatibleExtension, i.extension, i.requires, k6Version)
+		}
+
+		b.log.Warn(
+			"extension requires a newer k6 version, k6 will be upgraded",
+			"extension", i.extension,
+			"requires", i.requires,
+			"requested", k6Version,
+		)
+	}
+
+	return nil
+}
+
+// checkGoVersion verifies the go toolchain is not older than the go version required by k6.
+// The check is skipped if go can switch to the required toolchain (GOTOOLCHAIN=auto).
+func (b *nativeBuilder) checkGoVersion(ctx context.Context, e *goEnv, k6Version string) error {
+	// the go.mod of a local k6 repository is not downloaded
+	if b.K6Repo != "" {
+		return nil
+	}
+
+	version, switchable, err := e.toolchain(ctx)
+	if err != nil || switchable {
+		return err
+	}
+
+	if k6Version == "" {
+		k6Version = "latest"
+	}
+
+	required, err := e.modGoVersion(ctx, defaultK6ModulePath+"@"+k6Version)
+	if err != nil {
+		// the check is skipped if the version can't be determined, unless the build was canceled or timed out
+		if ctx.Err() != nil || errors.Is(err, ErrBuildTimeout) {
+			return err
+		}
+
+		b.log.Debug("can't determine the go version required by k6", "version", k6Version, "error", err)
+
+		return nil
+	}
+
+	if required == "" {
+		return nil
+	}
+
+	if compareGoVersions(required, version) > 0 {
+		return fmt.Errorf("%w: k6 %s requires go %s, toolchain is %s", ErrIncompatibleGoVersion, k6Version, required, version)
+	}
+
+	return nil
+}
+
+// compareGoVersions compares go versions (e.g. 1.22 and go1.22.5). Versions that can't be compared
+// (e.g. release candidates) are considered equal.
+func compareGoVersions(v, w string) int {
+	v = "v" + strings.TrimPrefix(v, "go")
+	w = "v" + strings.TrimPrefix(w, "go")
+	if !semver.IsValid(v) || !semver.IsValid(w) {
+		return 0
+	}
+
+	return semver.Compare(v, w)
+}
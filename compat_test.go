@@ -0,0 +1,104 @@
+package k6foundry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestIncompatibleGoVersion(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	for _, m := range []struct{ path, version, source string }{
+		{"go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")},
+		// requires go 1.99
+		{"go.k6.io/k6", "v0.3.0", filepath.Join("testdata", "mods", "k6go")},
+	} {
+		if err := proxy.AddModVersion(m.path, m.version, m.source); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	testCases := []struct {
+		title       string
+		k6Version   string
+		expectError error
+	}{
+		{
+			title:     "compatible go version",
+			k6Version: "v0.1.0",
+		},
+		{
+			title:       "k6 requires newer go version",
+			k6Version:   "v0.3.0",
+			expectError: ErrIncompatibleGoVersion,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			opts := NativeBuilderOpts{
+				GoOpts: GoOpts{
+					CopyGoEnv: true,
+					Env: map[string]string{
+						"GOPROXY":   goproxySrv.URL,
+						"GONOPROXY": "none",
+						"GOPRIVATE": "go.k6.io",
+						"GONOSUMDB": "go.k6.io",
+						// don't switch to the required toolchain
+						"GOTOOLCHAIN": "local",
+					},
+					TmpCache: true,
+				},
+			}
+
+			b, err := NewNativeBuilder(context.Background(), opts)
+			if err != nil {
+				t.Fatalf("setting up test %v", err)
+			}
+
+			_, err = b.Build(context.Background(), RuntimePlatform(), tc.k6Version, []Module{}, []string{}, &bytes.Buffer{})
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestCompareGoVersions(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		v      string
+		w      string
+		expect int
+	}{
+		{v: "1.22", w: "go1.22.5", expect: -1},
+		{v: "1.23", w: "go1.22.5", expect: 1},
+		{v: "1.22.5", w: "go1.22.5", expect: 0},
+		{v: "1.23rc1", w: "go1.22.5", expect: 0},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.v+"-"+tc.w, func(t *testing.T) {
+			t.Parallel()
+
+			if got := compareGoVersions(tc.v, tc.w); got != tc.expect {
+				t.Fatalf("expected %d got %d", tc.expect, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package k6foundry
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrBusy is returned when the builder is running the maximum number of concurrent builds
+// and builds are not queued
+var ErrBusy = errors.New("builder is busy")
+
+// acquireSlot reserves one of the ConcurrentBuilds slots of the builder and returns a function for releasing it.
+// If all the slots are in use, waits for one if QueueBuilds is set, or fails with ErrBusy otherwise.
+func (b *nativeBuilder) acquireSlot(ctx context.Context) (func(), error) {
+	if b.slots == nil {
+		return func() {}, nil
+	}
+
+	release := func() { <-b.slots }
+
+	select {
+	case b.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	if !b.QueueBuilds {
+		return nil, ErrBusy
+	}
+
+	b.log.Info("waiting for a build slot")
+
+	select {
+	case b.slots <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,115 @@
+package k6foundry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestConcurrentBuilds(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		concurrent  int
+		queue       bool
+		running     int
+		expectError error
+	}{
+		{
+			title:       "unlimited",
+			concurrent:  0,
+			running:     10,
+			expectError: nil,
+		},
+		{
+			title:       "slot available",
+			concurrent:  2,
+			running:     1,
+			expectError: nil,
+		},
+		{
+			title:       "busy",
+			concurrent:  2,
+			running:     2,
+			expectError: ErrBusy,
+		},
+		{
+			title:       "queued until timeout",
+			concurrent:  1,
+			queue:       true,
+			running:     1,
+			expectError: context.DeadlineExceeded,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			b := newNativeBuilder(NativeBuilderOpts{ConcurrentBuilds: tc.concurrent, QueueBuilds: tc.queue})
+
+			releases := []func(){}
+			for i := 0; i < tc.running; i++ {
+				release, err := b.acquireSlot(context.Background())
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				releases = append(releases, release)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			release, err := b.acquireSlot(ctx)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			if err != nil {
+				// a slot is available after a running build finishes
+				releases[0]()
+				release, err = b.acquireSlot(context.Background())
+				if err != nil {
+					t.Fatalf("unexpected error after release %v", err)
+				}
+			}
+
+			release()
+		})
+	}
+}
+
+func TestQueuedBuild(t *testing.T) {
+	t.Parallel()
+
+	b := newNativeBuilder(NativeBuilderOpts{ConcurrentBuilds: 1, QueueBuilds: true})
+
+	release, err := b.acquireSlot(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	acquired := make(chan error)
+	go func() {
+		queued, err := b.acquireSlot(context.Background())
+		if err == nil {
+			queued()
+		}
+		acquired <- err
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatalf("queued build started while the slot is in use")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	release()
+
+	if err = <-acquired; err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package k6foundry
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+// ErrConflictingDependencies is returned when a module is requested more than once,
+// or at different major versions
+var ErrConflictingDependencies = errors.New("conflicting dependencies")
+
+// ConflictingDependenciesError lists the dependencies that conflict with each other
+type ConflictingDependenciesError struct {
+	// each conflict lists the conflicting dependencies (path@version)
+	Conflicts [][]string
+}
+
+func (e *ConflictingDependenciesError) Error() string {
+	conflicts := make([]string, 0, len(e.Conflicts))
+	for _, conflict := range e.Conflicts {
+		conflicts = append(conflicts, strings.Join(conflict, " and "))
+	}
+
+	return fmt.Sprintf("%s: %s", ErrConflictingDependencies, strings.Join(conflicts, ", "))
+}
+
+// Is allows matching the error with ErrConflictingDependencies
+func (e *ConflictingDependenciesError) Is(target error) bool {
+	return target == ErrConflictingDependencies
+}
+
+// checkConflicts returns a ConflictingDependenciesError if a module path is specified more than once
+// or the same module is specified at different major versions (e.g. foo and foo/v2), as only one of
+// them would be used in the build
+func checkConflicts(mods []Module) error {
+	// modules by path without the major version suffix, in the order they were specified
+	byPrefix := map[string][]Module{}
+	prefixes := []string{}
+
+	for _, mod := range mods {
+		prefix, _, ok := module.SplitPathVersion(mod.Path)
+		if !ok {
+			prefix = mod.Path
+		}
+
+		if _, found := byPrefix[prefix]; !found {
+			prefixes = append(prefixes, prefix)
+		}
+		byPrefix[prefix] = append(byPrefix[prefix], mod)
+	}
+
+	conflicts := [][]string{}
+	for _, prefix := range prefixes {
+		if len(byPrefix[prefix]) < 2 {
+			continue
+		}
+
+		conflict := []string{}
+		for _, mod := range byPrefix[prefix] {
+			conflict = append(conflict, mod.Path+"@"+mod.Version)
+		}
+		conflicts = append(conflicts, conflict)
+	}
+
+	if len(conflicts) > 0 {
+		return &ConflictingDependenciesError{Conflicts: conflicts}
+	}
+
+	return nil
+}
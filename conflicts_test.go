@@ -0,0 +1,96 @@
+package k6foundry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCheckConflicts(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		mods        []Module
+		expectError error
+	}{
+		{
+			title: "no conflicts",
+			mods: []Module{
+				{Path: "github.com/grafana/xk6-foo", Version: "v0.1.0"},
+				{Path: "github.com/grafana/xk6-bar", Version: "v0.2.0"},
+			},
+		},
+		{
+			title: "same module with different versions",
+			mods: []Module{
+				{Path: "github.com/grafana/xk6-foo", Version: "v0.1.0"},
+				{Path: "github.com/grafana/xk6-foo", Version: "v0.2.0"},
+			},
+			expectError: ErrConflictingDependencies,
+		},
+		{
+			title: "same module twice",
+			mods: []Module{
+				{Path: "github.com/grafana/xk6-foo", Version: "v0.1.0"},
+				{Path: "github.com/grafana/xk6-foo", Version: "v0.1.0"},
+			},
+			expectError: ErrConflictingDependencies,
+		},
+		{
+			title: "different major versions",
+			mods: []Module{
+				{Path: "github.com/grafana/xk6-foo", Version: "v1.0.0"},
+				{Path: "github.com/grafana/xk6-foo/v2", Version: "v2.0.0"},
+			},
+			expectError: ErrConflictingDependencies,
+		},
+		{
+			title: "gopkg.in major versions",
+			mods: []Module{
+				{Path: "gopkg.in/foo.v1", Version: "v1.0.0"},
+				{Path: "gopkg.in/foo.v2", Version: "v2.0.0"},
+			},
+			expectError: ErrConflictingDependencies,
+		},
+		{
+			title: "module with a common prefix",
+			mods: []Module{
+				{Path: "github.com/grafana/xk6-foo", Version: "v1.0.0"},
+				{Path: "github.com/grafana/xk6-foo/bar", Version: "v1.0.0"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkConflicts(tc.mods)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestBuildConflictingDependencies(t *testing.T) {
+	t.Parallel()
+
+	b, err := NewNativeBuilder(context.TODO(), NativeBuilderOpts{GoOpts: GoOpts{TmpCache: true}})
+	if err != nil {
+		t.Fatalf("creating builder %v", err)
+	}
+
+	mods := []Module{
+		{Path: "go.k6.io/k6ext", Version: "v0.1.0"},
+		{Path: "go.k6.io/k6ext", Version: "v0.2.0"},
+	}
+
+	_, err = b.Build(context.TODO(), RuntimePlatform(), "v0.1.0", mods, nil, &bytes.Buffer{})
+	if !errors.Is(err, ErrConflictingDependencies) {
+		t.Fatalf("expected %v got %v", ErrConflictingDependencies, err)
+	}
+}
@@ -0,0 +1,488 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultContainerEngine is the container engine used by default by the container builder
+	DefaultContainerEngine = "docker"
+	// DefaultContainerImage is the golang image used by default by the container builder
+	DefaultContainerImage = "golang:1.23"
+
+	containerWorkDir    = "/k6foundry/work"
+	containerCacheDir   = "/k6foundry/cache"
+	containerReplaceDir = "/k6foundry/replace"
+
+	// time for the container engine to kill the container of a canceled command
+	containerKillTimeout = 15 * time.Second
+)
+
+// ErrNoContainerEngine is returned when the container engine is not installed
+var ErrNoContainerEngine = errors.New("container engine not found")
+
+// ContainerBuilderOpts defines the options for the container build environment
+type ContainerBuilderOpts struct {
+	// options for the build. CopyGoEnv is ignored, as the host's go environment
+	// doesn't apply inside the container
+	NativeBuilderOpts
+	// container engine command (docker or podman)
+	Engine string
+	// golang image used for building
+	Image string
+}
+
+// containerBuilder builds the binary executing the go commands in a container.
+// The work directory, the go caches and the local replacements are mounted in the container.
+type containerBuilder struct {
+	*nativeBuilder
+	engine string
+	image  string
+}
+
+// mount maps a host directory to a directory in the container
+type mount struct {
+	host      string
+	container string
+}
+
+// NewContainerBuilder creates a new build environment that runs go in a container
+func NewContainerBuilder(_ context.Context, opts ContainerBuilderOpts) (Builder, error) {
+	if opts.Engine == "" {
+		opts.Engine = DefaultContainerEngine
+	}
+
+	if opts.Image == "" {
+		opts.Image = DefaultContainerImage
+	}
+
+	engine, err := exec.LookPath(opts.Engine)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoContainerEngine, opts.Engine)
+	}
+
+	// the binary is compressed in the host, using the work directory mounted in the container
+	if err = validateOpts(opts.NativeBuilderOpts); err != nil {
+		return nil, err
+	}
+
+	return &containerBuilder{
+		nativeBuilder: newNativeBuilder(opts.NativeBuilderOpts),
+		engine:        engine,
+		image:         opts.Image,
+	}, nil
+}
+
+// Build builds a custom k6 binary for a target platform with the given dependencies into the out io.Writer
+func (b *containerBuilder) Build(
+	ctx context.Context,
+	platform Platform,
+	k6Version string,
+	exts []Module,
+	buildOpts []string,
+	binary io.Writer,
+) (*BuildInfo, error) {
+	mounts, err := b.replaceMounts(exts)
+	if err != nil {
+		return nil, err
+	}
+
+	newEnv := func(workDir string, platform Platform, opts GoOpts) (*goEnv, error) {
+		return b.containerEnv(workDir, platform, opts, mounts)
+	}
+
+	return b.buildWith(ctx, newEnv, platform, k6Version, exts, buildOpts, binaryOutput{writer: binary})
+}
+
+// BuildToFile builds a custom k6 binary for a target platform with the given dependencies into the file
+// at outPath. The work directory is mounted in the container, so the binary is moved from it
+func (b *containerBuilder) BuildToFile(
+	ctx context.Context,
+	platform Platform,
+	k6Version string,
+	exts []Module,
+	buildOpts []string,
+	outPath string,
+) (*BuildInfo, error) {
+	mounts, err := b.replaceMounts(exts)
+	if err != nil {
+		return nil, err
+	}
+
+	newEnv := func(workDir string, platform Platform, opts GoOpts) (*goEnv, error) {
+		return b.containerEnv(workDir, platform, opts, mounts)
+	}
+
+	return b.buildWith(ctx, newEnv, platform, k6Version, exts, buildOpts, binaryOutput{path: outPath})
+}
+
+// BuildMultiPlatform builds a custom k6 binary for each platform with the given dependencies
+func (b *containerBuilder) BuildMultiPlatform(
+	ctx context.Context,
+	platforms []Platform,
+	k6Version string,
+	exts []Module,
+	buildOpts []string,
+	out OutputFunc,
+) ([]*BuildInfo, error) {
+	mounts, err := b.replaceMounts(exts)
+	if err != nil {
+		return nil, err
+	}
+
+	newEnv := func(workDir string, platform Platform, opts GoOpts) (*goEnv, error) {
+		return b.containerEnv(workDir, platform, opts, mounts)
+	}
+
+	return b.buildMultiPlatformWith(ctx, newEnv, platforms, k6Version, exts, buildOpts, out)
+}
+
+// Watch rebuilds the binary each time the local modules change
+func (b *containerBuilder) Watch(
+	ctx context.Context,
+	platform Platform,
+	k6Version string,
+	exts []Module,
+	buildOpts []string,
+	onBuild RebuildFunc,
+) error {
+	mounts, err := b.replaceMounts(exts)
+	if err != nil {
+		return err
+	}
+
+	newEnv := func(workDir string, platform Platform, opts GoOpts) (*goEnv, error) {
+		return b.containerEnv(workDir, platform, opts, mounts)
+	}
+
+	return b.watchWith(ctx, newEnv, platform, k6Version, exts, buildOpts, onBuild)
+}
+
+// Platforms returns the platforms the go toolchain of the container image can build for
+func (b *containerBuilder) Platforms(ctx context.Context) ([]Platform, error) {
+	newEnv := func(workDir string, platform Platform, opts GoOpts) (*goEnv, error) {
+		return b.containerEnv(workDir, platform, opts, nil)
+	}
+
+	return b.platformsWith(ctx, newEnv)
+}
+
+// Vendor resolves k6 and the dependencies and writes the build environment into the out io.Writer
+func (b *containerBuilder) Vendor(ctx context.Context, k6Version string, exts []Module, out io.Writer) (*BuildInfo, error) {
+	mounts, err := b.replaceMounts(exts)
+	if err != nil {
+		return nil, err
+	}
+
+	newEnv := func(workDir string, platform Platform, opts GoOpts) (*goEnv, error) {
+		return b.containerEnv(workDir, platform, opts, mounts)
+	}
+
+	return b.vendorWith(ctx, newEnv, k6Version, exts, out)
+}
+
+// Resolve resolves k6 and the dependencies and returns the generated build environment
+func (b *containerBuilder) Resolve(ctx context.Context, k6Version string, exts []Module) (*Resolution, error) {
+	mounts, err := b.replaceMounts(exts)
+	if err != nil {
+		return nil, err
+	}
+
+	newEnv := func(workDir string, platform Platform, opts GoOpts) (*goEnv, error) {
+		return b.containerEnv(workDir, platform, opts, mounts)
+	}
+
+	return b.resolveWith(ctx, newEnv, k6Version, exts)
+}
+
+// BuildFromVendor builds a k6 binary from an archive created by Vendor
+func (b *containerBuilder) BuildFromVendor(
+	ctx context.Context,
+	platform Platform,
+	vendor io.Reader,
+	buildOpts []string,
+	out io.Writer,
+) (*BuildInfo, error) {
+	newEnv := func(workDir string, platform Platform, opts GoOpts) (*goEnv, error) {
+		return b.containerEnv(workDir, platform, opts, nil)
+	}
+
+	return b.buildFromVendorWith(ctx, newEnv, platform, vendor, buildOpts, out)
+}
+
+// replaceMounts returns the mounts required for the replacements that reference local directories
+// and for the workspace directories
+func (b *containerBuilder) replaceMounts(exts []Module) ([]mount, error) {
+	replaces := []string{b.K6Repo}
+	for _, ext := range exts {
+		replaces = append(replaces, ext.ReplacePath)
+	}
+	for _, r := range b.Replacements {
+		replaces = append(replaces, r.ReplacePath)
+	}
+
+	workspace, err := workspaceDirs(b.Workspace)
+	if err != nil {
+		return nil, err
+	}
+	replaces = append(replaces, workspace...)
+
+	mounts := []mount{}
+	for _, replace := range replaces {
+		if replace == "" {
+			continue
+		}
+
+		replacePath, err := resolvePath(replace)
+		if err != nil {
+			return nil, fmt.Errorf("resolving replace path: %w", err)
+		}
+
+		// module paths are not mounted
+		if !filepath.IsAbs(replacePath) {
+			continue
+		}
+
+		mounts = append(mounts, mount{
+			host:      replacePath,
+			container: path.Join(containerReplaceDir, strconv.Itoa(len(mounts))),
+		})
+	}
+
+	return mounts, nil
+}
+
+func (b *containerBuilder) containerEnv(
+	workDir string,
+	platform Platform,
+	opts GoOpts,
+	mounts []mount,
+) (*goEnv, error) {
+	env := map[string]string{}
+
+	if err := setToolchainEnv(env, opts.GoVersion); err != nil {
+		return nil, err
+	}
+
+	setVerificationEnv(env, opts)
+
+	var tmpDirs []string
+
+	// the go caches must be kept in the host, as each go command runs in a new container
+	cacheDir := ""
+	if opts.TmpCache {
+		dir, err := mkTempDir(opts.buildDirRoot(), tmpDirPrefix+"-cache*")
+		if err != nil {
+			return nil, fmt.Errorf("creating temporary cache %w", err)
+		}
+		cacheDir = dir
+		tmpDirs = append(tmpDirs, dir)
+	} else if opts.GoCacheDir != "" {
+		cacheDir = opts.GoCacheDir
+	} else {
+		dir, err := DefaultContainerCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		cacheDir = dir
+		if err = os.MkdirAll(cacheDir, 0o750); err != nil {
+			return nil, fmt.Errorf("%w: creating cache directory %w", ErrSettingGoEnv, err)
+		}
+	}
+
+	// the cache in the host is shared with other builds unless it is temporary
+	cacheLock := ""
+	sharedCacheDir := ""
+	if !opts.TmpCache {
+		sharedCacheDir = cacheDir
+		var err error
+		if cacheLock, err = cacheLockPath(filepath.Join(cacheDir, "modcache")); err != nil {
+			return nil, err
+		}
+	}
+
+	env["GOCACHE"] = path.Join(containerCacheDir, "gocache")
+	env["GOMODCACHE"] = path.Join(containerCacheDir, "modcache")
+	// the user running in the container may not have a home directory
+	env["HOME"] = "/tmp"
+
+	mounts = append(
+		[]mount{{host: workDir, container: containerWorkDir}, {host: cacheDir, container: containerCacheDir}},
+		mounts...,
+	)
+
+	// the home directory is mounted with the work directory
+	if opts.isolatedHome() {
+		if _, err := newHome(workDir, opts); err != nil {
+			for _, dir := range tmpDirs {
+				_ = removeAll(dir)
+			}
+			return nil, err
+		}
+
+		setHomeEnv(env, opts, path.Join(containerWorkDir, homeDirName))
+	}
+
+	// the variables set explicitly take precedence over the ones set by the options
+	maps.Copy(env, opts.Env)
+
+	if err := checkSumDBRequired(env, opts); err != nil {
+		for _, dir := range tmpDirs {
+			_ = removeAll(dir)
+		}
+		return nil, err
+	}
+
+	commandFor := func(platform Platform) goCommand {
+		platformEnv := maps.Clone(env)
+		platform.setEnv(platformEnv)
+
+		// the container's default C toolchain only targets its own platform
+		setCgoEnv(platformEnv, platform, opts, platform.OS == "linux" && platform.Arch == runtime.GOARCH)
+
+		args := []string{"--rm", "-w", containerWorkDir}
+		for _, m := range mounts {
+			args = append(args, "-v", m.host+":"+m.container)
+		}
+
+		envVars := mapToSlice(platformEnv)
+		slices.Sort(envVars)
+		for _, v := range envVars {
+			args = append(args, "-e", v)
+		}
+
+		// run as the current user, so the files created in the mounted directories can be removed
+		if runtime.GOOS != "windows" {
+			args = append(args, "--user", fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid()))
+		}
+
+		return containerGoCommand(b.engine, args, b.image, mounts)
+	}
+
+	return &goEnv{
+		command:      commandFor(platform),
+		commandFor:   commandFor,
+		platform:     platform,
+		workDir:      workDir,
+		stdout:       b.Stdout,
+		stderr:       b.Stderr,
+		buildTimeout: timeoutOrDefault(opts.GOBuildTimeout, DefaultGoBuildTimeout),
+		getTimeout:   timeoutOrDefault(opts.GoGetTimeout, DefaultGoGetTimeout),
+		retries:      opts.GetRetries,
+		retryBackoff: opts.GetRetryBackoff,
+		tmpDirs:      tmpDirs,
+		cacheLock:    cacheLock,
+		cacheDir:     sharedCacheDir,
+		modCache:     filepath.Join(cacheDir, "modcache"),
+		stop:         b.killContainer,
+	}, nil
+}
+
+// containerGoCommand returns a goCommand that executes go in a container, translating the
+// host paths in the arguments to the paths in the container. Each command runs in a container
+// with a unique name, so it can be killed if the command is canceled
+func containerGoCommand(engine string, runArgs []string, image string, mounts []mount) goCommand {
+	// nested directories are translated using their own mount
+	mounts = slices.Clone(mounts)
+	slices.SortStableFunc(mounts, func(a, b mount) int { return cmp.Compare(len(b.host), len(a.host)) })
+
+	return func(args ...string) *exec.Cmd {
+		cmdArgs := append([]string{"run", "--name", newContainerName()}, runArgs...)
+		cmdArgs = append(cmdArgs, image, "go")
+		for _, arg := range args {
+			cmdArgs = append(cmdArgs, translatePaths(arg, mounts))
+		}
+
+		cmd := exec.Command(engine, cmdArgs...) //nolint:gosec
+		cmd.Env = os.Environ()
+
+		return cmd
+	}
+}
+
+// translatePaths replaces the host paths in the argument with the paths in the container. A host path
+// is only replaced if it is a complete path or it is followed by a path separator, so /src/xk6 is not
+// replaced in /src/xk6-sql. The mounts are checked in order
+func translatePaths(arg string, mounts []mount) string {
+	translated := &strings.Builder{}
+	for i := 0; i < len(arg); {
+		m, found := mountAt(arg, i, mounts)
+		if !found {
+			translated.WriteByte(arg[i])
+			i++
+			continue
+		}
+
+		translated.WriteString(m.container)
+		i += len(m.host)
+	}
+
+	return translated.String()
+}
+
+// mountAt returns the mount whose host path is at position i of the argument and is not part of a longer path
+func mountAt(arg string, i int, mounts []mount) (mount, bool) {
+	if i > 0 && isPathByte(arg[i-1]) {
+		return mount{}, false
+	}
+
+	for _, m := range mounts {
+		rest, found := strings.CutPrefix(arg[i:], m.host)
+		if found && (rest == "" || rest[0] == '/' || rest[0] == filepath.Separator) {
+			return m, true
+		}
+	}
+
+	return mount{}, false
+}
+
+// isPathByte returns true if the byte can be part of a path, so a host path following it is
+// a subpath of another path
+func isPathByte(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return true
+	default:
+		return strings.IndexByte("/\\.-_~", c) >= 0
+	}
+}
+
+// newContainerName returns a unique name for the container of a command
+func newContainerName() string {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+
+	return tmpDirPrefix + "-" + hex.EncodeToString(id)
+}
+
+// killContainer kills the container of a command. Killing the container engine's process doesn't
+// stop the container, which would keep running the go command
+func (b *containerBuilder) killContainer(cmd *exec.Cmd) {
+	i := slices.Index(cmd.Args, "--name")
+	if i < 0 || i+1 >= len(cmd.Args) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), containerKillTimeout)
+	defer cancel()
+
+	// the container may have finished already
+	_ = exec.CommandContext(ctx, b.engine, "kill", cmd.Args[i+1]).Run() //nolint:gosec
+}
@@ -0,0 +1,75 @@
+package k6foundry
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestContainerGoCommand(t *testing.T) {
+	t.Parallel()
+
+	mounts := []mount{
+		{host: "/tmp/k6foundry123", container: containerWorkDir},
+		{host: "/home/user/xk6", container: containerReplaceDir + "/0"},
+		{host: "/home/user/xk6-sql", container: containerReplaceDir + "/1"},
+		{host: "/home/user/xk6/ext", container: containerReplaceDir + "/2"},
+	}
+
+	command := containerGoCommand("docker", []string{"--rm"}, "golang:1.23", mounts)
+
+	testCases := []struct {
+		title  string
+		args   []string
+		expect []string
+	}{
+		{
+			title:  "replace",
+			args:   []string{"mod", "edit", "-replace", "go.k6.io/k6ext=/home/user/xk6"},
+			expect: []string{"mod", "edit", "-replace", "go.k6.io/k6ext=/k6foundry/replace/0"},
+		},
+		{
+			title:  "output",
+			args:   []string{"build", "-o", "/tmp/k6foundry123/k6"},
+			expect: []string{"build", "-o", "/k6foundry/work/k6"},
+		},
+		{
+			title:  "host path prefix of another mount",
+			args:   []string{"mod", "edit", "-replace", "go.k6.io/sql=/home/user/xk6-sql"},
+			expect: []string{"mod", "edit", "-replace", "go.k6.io/sql=/k6foundry/replace/1"},
+		},
+		{
+			title:  "nested mount",
+			args:   []string{"work", "use", "/home/user/xk6/ext", "/home/user/xk6/other"},
+			expect: []string{"work", "use", "/k6foundry/replace/2", "/k6foundry/replace/0/other"},
+		},
+		{
+			title:  "not mounted",
+			args:   []string{"work", "use", "/home/user/xk6-other", "/src/home/user/xk6"},
+			expect: []string{"work", "use", "/home/user/xk6-other", "/src/home/user/xk6"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			cmd := command(tc.args...)
+
+			// each command runs in a container with a unique name
+			if len(cmd.Args) < 4 || cmd.Args[2] != "--name" || !strings.HasPrefix(cmd.Args[3], tmpDirPrefix+"-") {
+				t.Fatalf("expected container name got %v", cmd.Args)
+			}
+
+			expect := append([]string{"docker", "run", "--name", cmd.Args[3], "--rm", "golang:1.23", "go"}, tc.expect...)
+			if !slices.Equal(cmd.Args, expect) {
+				t.Fatalf("expected %v got %v", expect, cmd.Args)
+			}
+		})
+	}
+
+	if first, second := command("version"), command("version"); first.Args[3] == second.Args[3] {
+		t.Fatalf("expected unique container names got %s", first.Args[3])
+	}
+}
@@ -0,0 +1,51 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Dependency describes a module included in the binary
+type Dependency struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	// go.sum hash of the module's content (h1:...). Empty for replacements with local directories
+	Sum string `json:"sum,omitempty"`
+	// SPDX identifier of the license of the module (e.g. Apache-2.0). Several licenses are joined with AND.
+	// Only reported if requested in the builder options. Empty if not recognized
+	License string `json:"license,omitempty"`
+}
+
+// moduleDependencies returns all the modules required in the work directory with their go.sum hashes,
+// sorted by path
+func moduleDependencies(workDir string, requires map[string]string) ([]Dependency, error) {
+	content, err := os.ReadFile(filepath.Join(workDir, "go.sum")) //nolint:gosec
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("reading go.sum %w", err)
+	}
+
+	// go.sum lines have the format: path version[/go.mod] hash
+	sums := map[string]string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || strings.HasSuffix(fields[1], "/go.mod") {
+			continue
+		}
+		sums[fields[0]+"@"+fields[1]] = fields[2]
+	}
+
+	deps := make([]Dependency, 0, len(requires))
+	for path, version := range requires {
+		deps = append(deps, Dependency{Path: path, Version: version, Sum: sums[path+"@"+version]})
+	}
+
+	sort.Slice(deps, func(i, j int) bool { return deps[i].Path < deps[j].Path })
+
+	return deps, nil
+}
@@ -0,0 +1,36 @@
+package k6foundry
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestModuleDependencies(t *testing.T) {
+	t.Parallel()
+
+	workDir := t.TempDir()
+	goSum := "go.k6.io/k6 v0.1.0 h1:k6=\n" +
+		"go.k6.io/k6 v0.1.0/go.mod h1:k6mod=\n" +
+		"go.k6.io/k6ext v0.1.0/go.mod h1:k6extmod=\n"
+	if err := os.WriteFile(filepath.Join(workDir, "go.sum"), []byte(goSum), 0o600); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	deps, err := moduleDependencies(workDir, map[string]string{
+		"go.k6.io/k6ext": "v0.1.0",
+		"go.k6.io/k6":    "v0.1.0",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	expect := []Dependency{
+		{Path: "go.k6.io/k6", Version: "v0.1.0", Sum: "h1:k6="},
+		{Path: "go.k6.io/k6ext", Version: "v0.1.0"},
+	}
+	if !reflect.DeepEqual(deps, expect) {
+		t.Fatalf("expected %v got %v", expect, deps)
+	}
+}
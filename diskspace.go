@@ -0,0 +1,32 @@
+package k6foundry
+
+import (
+	"fmt"
+)
+
+// checkDiskSpace checks the free disk space in the directories is at least MinFreeDiskSpace
+func (b *nativeBuilder) checkDiskSpace(dirs ...string) error {
+	if b.MinFreeDiskSpace <= 0 {
+		return nil
+	}
+
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+
+		free, err := freeDiskSpace(dir)
+		if err != nil {
+			return fmt.Errorf("checking disk space in %s: %w", dir, err)
+		}
+
+		if free < uint64(b.MinFreeDiskSpace) {
+			return fmt.Errorf(
+				"%w: %d bytes free in %s, %d required",
+				ErrInsufficientDiskSpace, free, dir, b.MinFreeDiskSpace,
+			)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package k6foundry
+
+import "syscall"
+
+// freeDiskSpace returns the disk space in bytes available to the user in the file system of the path
+func freeDiskSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil //nolint:gosec,unconvert
+}
@@ -0,0 +1,343 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/module"
+	gosemver "golang.org/x/mod/semver"
+)
+
+// CheckStatus is the result of a check of the build environment
+type CheckStatus string
+
+const (
+	// CheckOK means the check passed
+	CheckOK CheckStatus = "ok"
+	// CheckWarning means the builds may fail or be degraded
+	CheckWarning CheckStatus = "warning"
+	// CheckFailed means the builds will fail
+	CheckFailed CheckStatus = "failed"
+	// CheckSkipped means the check doesn't apply to the options
+	CheckSkipped CheckStatus = "skipped"
+)
+
+const (
+	// go version required for downloading toolchains (GOTOOLCHAIN)
+	minToolchainGoVersion = "v1.21"
+	// free disk space recommended for a build with several extensions, including the caches
+	recommendedFreeDiskSpace = 2 << 30
+	// default timeout for checking the module proxy
+	defaultProxyCheckTimeout = 10 * time.Second
+)
+
+// CheckResult is the result of a check of the build environment
+type CheckResult struct {
+	// Name of the check (e.g. go, git)
+	Name string `json:"name"`
+	// Status of the check
+	Status CheckStatus `json:"status"`
+	// Message describing the result (e.g. the version of go found)
+	Message string `json:"message"`
+	// Remediation describes how to fix a warning or a failure
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// DiagnoseOpts defines the options for diagnosing the build environment
+type DiagnoseOpts struct {
+	GoOpts
+	// Client used for checking the module proxy. Defaults to http.DefaultClient
+	HTTPClient *http.Client
+	// Timeout for checking the module proxy. Defaults to 10s
+	ProxyTimeout time.Duration
+}
+
+// Diagnose checks the build environment of the native builder with the given options: the go toolchain,
+// git, the C toolchain (if cgo is enabled), the access to the module proxy and the free disk space
+func Diagnose(ctx context.Context, opts DiagnoseOpts) []CheckResult {
+	env := map[string]string{}
+	if opts.CopyGoEnv {
+		// a missing go toolchain is reported by its check
+		if goBin, err := goBinary(opts.GoOpts); err == nil {
+			if goEnv, err := getGoEnv(goBin); err == nil {
+				env = goEnv
+			}
+		}
+	}
+	maps.Copy(env, opts.Env)
+
+	return []CheckResult{
+		checkGoToolchain(opts.GoOpts),
+		checkGit(env, opts.GoOpts),
+		checkCgo(env, opts.GoOpts),
+		checkGoProxy(ctx, env, opts),
+		checkFreeDiskSpace(env, opts.GoOpts),
+	}
+}
+
+// DiagnosisFailed returns true if any check failed
+func DiagnosisFailed(results []CheckResult) bool {
+	for _, r := range results {
+		if r.Status == CheckFailed {
+			return true
+		}
+	}
+
+	return false
+}
+
+func checkGoToolchain(opts GoOpts) CheckResult {
+	result := CheckResult{Name: "go"}
+
+	goBin, err := goBinary(opts)
+	if errors.Is(err, ErrNoGoToolchain) && opts.DownloadGo {
+		result.Status = CheckOK
+		result.Message = "go not found, it will be downloaded"
+
+		return result
+	}
+
+	version, found := goVersion(goBin)
+	if err != nil || !found {
+		result.Status = CheckFailed
+		result.Message = ErrNoGoToolchain.Error()
+		result.Remediation = "install go from https://go.dev/dl/ and add it to the PATH," +
+			" download it with --download-go or use the container builder"
+
+		return result
+	}
+
+	result.Status = CheckOK
+	result.Message = "go " + version
+
+	if gosemver.Compare("v"+version, minToolchainGoVersion) < 0 {
+		result.Status = CheckWarning
+		if opts.GoVersion != "" {
+			result.Status = CheckFailed
+		}
+		result.Message += ", downloading go toolchains for --go-version requires go 1.21 or later"
+		result.Remediation = "upgrade go from https://go.dev/dl/"
+	}
+
+	return result
+}
+
+// checkGit checks git is installed. Git is only required if the modules are downloaded from their
+// repositories instead of the module proxy
+func checkGit(env map[string]string, opts GoOpts) CheckResult {
+	result := CheckResult{Name: "git"}
+
+	if !hasGit() {
+		result.Status = CheckWarning
+		result.Message = ErrNoGit.Error() + ", modules not available in the module proxy can't be downloaded"
+		if proxyIsDirect(env["GOPROXY"]) || opts.hasGitAuth() {
+			result.Status = CheckFailed
+			result.Message = ErrNoGit.Error() + ", modules are downloaded from their repositories"
+		}
+		result.Remediation = "install git from https://git-scm.com/downloads and add it to the PATH." +
+			" go uses git for downloading modules not available in the module proxy"
+
+		return result
+	}
+
+	out, _ := exec.Command("git", "version").Output()
+	result.Status = CheckOK
+	result.Message = strings.TrimSpace(string(out))
+
+	return result
+}
+
+// checkCgo checks the C compiler is available if cgo is enabled explicitly or by the C toolchain options
+func checkCgo(env map[string]string, opts GoOpts) CheckResult {
+	result := CheckResult{Name: "cgo"}
+
+	compiler := ""
+	switch {
+	case opts.Zig:
+		compiler = "zig"
+	case opts.CC != "":
+		compiler = strings.Fields(opts.CC)[0]
+	case env["CGO_ENABLED"] == "1":
+		compiler = "gcc"
+		if cc := strings.Fields(env["CC"]); len(cc) > 0 {
+			compiler = cc[0]
+		}
+	default:
+		result.Status = CheckSkipped
+		result.Message = "cgo is not enabled"
+
+		return result
+	}
+
+	if _, err := exec.LookPath(compiler); err != nil {
+		result.Status = CheckFailed
+		result.Message = fmt.Sprintf("C compiler %s not found", compiler)
+		result.Remediation = "install " + compiler + " and add it to the PATH, or disable cgo with CGO_ENABLED=0" +
+			" if the extensions don't require it"
+		if opts.Zig {
+			result.Remediation = "install zig from https://ziglang.org/download/ and add it to the PATH"
+		}
+
+		return result
+	}
+
+	result.Status = CheckOK
+	result.Message = "C compiler " + compiler + " found"
+
+	return result
+}
+
+// checkGoProxy checks the first module proxy in GOPROXY serves the k6 module
+func checkGoProxy(ctx context.Context, env map[string]string, opts DiagnoseOpts) CheckResult {
+	result := CheckResult{Name: "proxy"}
+
+	if opts.Offline {
+		result.Status = CheckSkipped
+		result.Message = "building offline"
+
+		return result
+	}
+
+	proxy := defaultGoProxy
+	if proxies := strings.FieldsFunc(env["GOPROXY"], func(r rune) bool { return r == ',' || r == '|' }); len(proxies) > 0 {
+		proxy = proxies[0]
+	}
+
+	switch proxy {
+	case "off":
+		result.Status = CheckSkipped
+		result.Message = "module proxy disabled (GOPROXY=off)"
+
+		return result
+	case "direct":
+		result.Status = CheckSkipped
+		result.Message = "modules are downloaded from their repositories (GOPROXY=direct)"
+
+		return result
+	}
+
+	timeout := opts.ProxyTimeout
+	if timeout <= 0 {
+		timeout = defaultProxyCheckTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	err := checkModuleProxy(ctx, opts.HTTPClient, proxy)
+	if err != nil {
+		result.Status = CheckFailed
+		result.Message = fmt.Sprintf("module proxy %s not reachable: %v", proxy, err)
+		result.Remediation = "check the network connection and the proxy configuration (GOPROXY, HTTPS_PROXY)," +
+			" or build offline using the modules in the module cache"
+
+		return result
+	}
+
+	result.Status = CheckOK
+	result.Message = "module proxy " + proxy + " reachable"
+
+	return result
+}
+
+// checkModuleProxy checks the module proxy lists the versions of the k6 module
+func checkModuleProxy(ctx context.Context, client *http.Client, proxy string) error {
+	escaped, err := module.EscapePath(defaultK6ModulePath)
+	if err != nil {
+		return err
+	}
+
+	url := strings.TrimSuffix(proxy, "/") + "/" + escaped + "/@v/list"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(resp.Status)
+	}
+
+	return nil
+}
+
+// checkFreeDiskSpace checks the free disk space in the build directory root, where the builds run,
+// and the module cache. The required space is MinFreeDiskSpace, if set
+func checkFreeDiskSpace(env map[string]string, opts GoOpts) CheckResult {
+	result := CheckResult{Name: "disk"}
+
+	dirs := []string{opts.buildDirRoot()}
+	switch {
+	case opts.GoCacheDir != "" && !opts.TmpCache:
+		dirs = append(dirs, opts.GoCacheDir)
+	case env["GOMODCACHE"] != "" && !opts.TmpCache:
+		dirs = append(dirs, env["GOMODCACHE"])
+	}
+
+	required := uint64(recommendedFreeDiskSpace)
+	status := CheckWarning
+	if opts.MinFreeDiskSpace > 0 {
+		required = uint64(opts.MinFreeDiskSpace)
+		status = CheckFailed
+	}
+
+	messages := []string{}
+	for _, dir := range dirs {
+		free, err := freeDiskSpace(existingParent(dir))
+		if err != nil {
+			result.Status = CheckWarning
+			result.Message = fmt.Sprintf("checking disk space in %s: %v", dir, err)
+
+			return result
+		}
+
+		messages = append(messages, fmt.Sprintf("%d MB free in %s", free>>20, dir))
+
+		if free < required {
+			result.Status = status
+			result.Message = fmt.Sprintf("%s: %d MB free in %s, %d MB required",
+				ErrInsufficientDiskSpace, free>>20, dir, required>>20)
+			result.Remediation = "free disk space in " + dir + " or trim the go caches (go clean -cache -modcache)"
+
+			return result
+		}
+	}
+
+	result.Status = CheckOK
+	result.Message = strings.Join(messages, ", ")
+
+	return result
+}
+
+// existingParent returns the directory or its closest parent that exists
+func existingParent(dir string) string {
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
@@ -0,0 +1,133 @@
+package k6foundry
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestDiagnose(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	if err := proxy.AddModVersion("go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	emptySrv := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(emptySrv.Close)
+
+	t.Run("proxy", func(t *testing.T) {
+		t.Parallel()
+
+		testCases := []struct {
+			title   string
+			env     map[string]string
+			offline bool
+			expect  CheckStatus
+		}{
+			{title: "reachable", env: map[string]string{"GOPROXY": goproxySrv.URL + ",direct"}, expect: CheckOK},
+			{title: "not found", env: map[string]string{"GOPROXY": emptySrv.URL}, expect: CheckFailed},
+			{title: "not reachable", env: map[string]string{"GOPROXY": "http://127.0.0.1:0"}, expect: CheckFailed},
+			{title: "off", env: map[string]string{"GOPROXY": "off"}, expect: CheckSkipped},
+			{title: "direct", env: map[string]string{"GOPROXY": "direct"}, expect: CheckSkipped},
+			{title: "offline", env: map[string]string{"GOPROXY": emptySrv.URL}, offline: true, expect: CheckSkipped},
+		}
+
+		for _, tc := range testCases {
+			opts := DiagnoseOpts{GoOpts: GoOpts{Offline: tc.offline}}
+			result := checkGoProxy(context.Background(), tc.env, opts)
+			if result.Status != tc.expect {
+				t.Fatalf("%s: expected %s got %s (%s)", tc.title, tc.expect, result.Status, result.Message)
+			}
+
+			if result.Status == CheckFailed && result.Remediation == "" {
+				t.Fatalf("%s: expected remediation", tc.title)
+			}
+		}
+	})
+
+	t.Run("cgo", func(t *testing.T) {
+		t.Parallel()
+
+		testCases := []struct {
+			title  string
+			env    map[string]string
+			opts   GoOpts
+			expect CheckStatus
+		}{
+			{title: "disabled", env: map[string]string{"CGO_ENABLED": "0"}, expect: CheckSkipped},
+			{title: "compiler found", opts: GoOpts{CC: "go"}, expect: CheckOK},
+			{title: "compiler not found", opts: GoOpts{CC: "no-such-cc -O2"}, expect: CheckFailed},
+			{
+				title:  "enabled without compiler",
+				env:    map[string]string{"CGO_ENABLED": "1", "CC": "no-such-cc"},
+				expect: CheckFailed,
+			},
+		}
+
+		for _, tc := range testCases {
+			result := checkCgo(tc.env, tc.opts)
+			if result.Status != tc.expect {
+				t.Fatalf("%s: expected %s got %s (%s)", tc.title, tc.expect, result.Status, result.Message)
+			}
+		}
+	})
+
+	t.Run("disk space", func(t *testing.T) {
+		t.Parallel()
+
+		testCases := []struct {
+			title   string
+			minFree int64
+			expect  CheckStatus
+		}{
+			{title: "enough space", minFree: 1, expect: CheckOK},
+			{title: "insufficient space", minFree: math.MaxInt64, expect: CheckFailed},
+		}
+
+		for _, tc := range testCases {
+			opts := GoOpts{GoCacheDir: filepath.Join(t.TempDir(), "missing"), MinFreeDiskSpace: tc.minFree}
+			result := checkFreeDiskSpace(map[string]string{}, opts)
+			if result.Status != tc.expect {
+				t.Fatalf("%s: expected %s got %s (%s)", tc.title, tc.expect, result.Status, result.Message)
+			}
+		}
+	})
+
+	t.Run("diagnose", func(t *testing.T) {
+		t.Parallel()
+
+		opts := DiagnoseOpts{
+			GoOpts: GoOpts{
+				CopyGoEnv:        true,
+				Env:              map[string]string{"GOPROXY": goproxySrv.URL, "CGO_ENABLED": "0"},
+				MinFreeDiskSpace: 1,
+			},
+		}
+
+		results := Diagnose(context.Background(), opts)
+		if DiagnosisFailed(results) {
+			t.Fatalf("unexpected failure %v", results)
+		}
+
+		names := []string{}
+		for _, r := range results {
+			names = append(names, r.Name)
+		}
+
+		expect := []string{"go", "git", "cgo", "proxy", "disk"}
+		if !reflect.DeepEqual(names, expect) {
+			t.Fatalf("expected checks %v got %v", expect, names)
+		}
+	})
+}
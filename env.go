@@ -0,0 +1,103 @@
+package k6foundry
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ErrConflictingEnv is returned when Env sets a variable also set by another option to a different value
+var ErrConflictingEnv = errors.New("conflicting environment settings")
+
+// optionEnv is an environment variable set by an option
+type optionEnv struct {
+	option string
+	name   string
+	// value set by the option. Empty if it is only known when the build environment is created,
+	// so any value set in Env conflicts with it
+	value string
+}
+
+// optionsEnv returns the environment variables set by the options
+func (o GoOpts) optionsEnv() []optionEnv {
+	vars := []optionEnv{
+		// the platform is given for each build
+		{option: "platform", name: "GOOS"},
+		{option: "platform", name: "GOARCH"},
+	}
+
+	if o.GoVersion != "" {
+		vars = append(vars, optionEnv{"GoVersion", "GOTOOLCHAIN", "go" + strings.TrimPrefix(o.GoVersion, "go")})
+	}
+
+	if len(o.GoFlags) > 0 {
+		vars = append(vars, optionEnv{"GoFlags", "GOFLAGS", strings.Join(o.GoFlags, " ")})
+	}
+
+	if o.VCS != "" {
+		vars = append(vars, optionEnv{"VCS", "GOVCS", o.VCS})
+	}
+
+	if o.SumDB != "" {
+		vars = append(vars, optionEnv{"SumDB", "GOSUMDB", o.SumDB})
+	}
+
+	if len(o.NoSumDB) > 0 {
+		vars = append(vars, optionEnv{"NoSumDB", "GONOSUMDB", strings.Join(o.NoSumDB, ",")})
+	}
+
+	switch {
+	case o.Zig:
+		vars = append(vars, optionEnv{option: "Zig", name: "CC"}, optionEnv{option: "Zig", name: "CXX"})
+	case o.CC != "":
+		vars = append(vars, optionEnv{"CC", "CC", o.CC})
+		if o.CXX != "" {
+			vars = append(vars, optionEnv{"CXX", "CXX", o.CXX})
+		}
+	}
+
+	switch {
+	case o.TmpCache:
+		vars = append(vars,
+			optionEnv{option: "TmpCache", name: "GOCACHE"},
+			optionEnv{option: "TmpCache", name: "GOMODCACHE"},
+		)
+	case o.GoCacheDir != "":
+		vars = append(vars,
+			optionEnv{"GoCacheDir", "GOCACHE", filepath.Join(o.GoCacheDir, "gocache")},
+			optionEnv{"GoCacheDir", "GOMODCACHE", filepath.Join(o.GoCacheDir, "modcache")},
+		)
+	}
+
+	if o.isolatedHome() {
+		vars = append(vars,
+			optionEnv{option: "isolated home", name: "HOME"},
+			optionEnv{option: "isolated home", name: "NETRC"},
+		)
+	}
+
+	if o.Hermetic {
+		vars = append(vars, optionEnv{"Hermetic", "GOENV", "off"})
+	}
+
+	if o.GitSSHKey != "" {
+		vars = append(vars, optionEnv{option: "GitSSHKey", name: "GIT_SSH_COMMAND"})
+	}
+
+	return vars
+}
+
+// validateEnv checks the variables in Env don't conflict with the variables set by the other options
+func (o GoOpts) validateEnv() error {
+	for _, v := range o.optionsEnv() {
+		value, found := o.Env[v.name]
+		if !found || (v.value != "" && value == v.value) {
+			continue
+		}
+
+		return fmt.Errorf("%w: %s=%q in Env conflicts with the %s option", ErrConflictingEnv, v.name, value, v.option)
+	}
+
+	return nil
+}
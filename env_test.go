@@ -0,0 +1,76 @@
+package k6foundry
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateEnv(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		opts        GoOpts
+		expectError error
+	}{
+		{
+			title: "no conflicts",
+			opts: GoOpts{
+				Env:       map[string]string{"GOPROXY": "direct", "CGO_ENABLED": "1"},
+				GoVersion: "1.22.5",
+				SumDB:     "off",
+			},
+		},
+		{
+			title: "same value",
+			opts: GoOpts{
+				Env:       map[string]string{"GOTOOLCHAIN": "go1.22.5", "GOSUMDB": "off", "CC": "clang"},
+				GoVersion: "1.22.5",
+				SumDB:     "off",
+				CC:        "clang",
+			},
+		},
+		{
+			title:       "different toolchain",
+			opts:        GoOpts{Env: map[string]string{"GOTOOLCHAIN": "local"}, GoVersion: "1.22.5"},
+			expectError: ErrConflictingEnv,
+		},
+		{
+			title:       "different go flags",
+			opts:        GoOpts{Env: map[string]string{"GOFLAGS": "-mod=vendor"}, GoFlags: []string{"-mod=mod"}},
+			expectError: ErrConflictingEnv,
+		},
+		{
+			title:       "compiler set by zig",
+			opts:        GoOpts{Env: map[string]string{"CC": "gcc"}, Zig: true},
+			expectError: ErrConflictingEnv,
+		},
+		{
+			title:       "temporary cache",
+			opts:        GoOpts{Env: map[string]string{"GOMODCACHE": "/tmp/modcache"}, TmpCache: true},
+			expectError: ErrConflictingEnv,
+		},
+		{
+			title:       "isolated home",
+			opts:        GoOpts{Env: map[string]string{"HOME": "/home/k6"}, Hermetic: true},
+			expectError: ErrConflictingEnv,
+		},
+		{
+			title:       "platform",
+			opts:        GoOpts{Env: map[string]string{"GOOS": "windows"}},
+			expectError: ErrConflictingEnv,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.opts.validateEnv()
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package k6foundry
+
+import (
+	"time"
+)
+
+// EventType identifies the type of a build event
+type EventType string
+
+const (
+	// EventBuildStarted signals the build has started
+	EventBuildStarted EventType = "build-started"
+	// EventModuleResolving signals the resolution of a module has started
+	EventModuleResolving EventType = "module-resolving"
+	// EventModuleResolved signals a module has been resolved. The event includes the resolved version
+	EventModuleResolved EventType = "module-resolved"
+	// EventCompiling signals the compilation of the binary has started
+	EventCompiling EventType = "compiling"
+	// EventCompiled signals the compilation of the binary has finished successfully
+	EventCompiled EventType = "compiled"
+	// EventBinaryWriting reports the progress of writing the binary to the output. The event includes the
+	// bytes written and the size of the binary. It is emitted when the writing starts and periodically after
+	EventBinaryWriting EventType = "binary-writing"
+	// EventBinaryWritten signals the binary has been written to the output. The event includes its size
+	EventBinaryWritten EventType = "binary-written"
+	// EventBuildFinished signals the build has finished. If the build failed, the event includes the error
+	EventBuildFinished EventType = "build-finished"
+)
+
+// Event describes a step in the progress of a build
+type Event struct {
+	Type EventType
+	Time time.Time
+	// Module path for module events
+	Module string
+	// Version of the module for module events. For EventModuleResolving is the requested version
+	Version string
+	// Target platform for compilation events
+	Platform string
+	// Bytes written for EventBinaryWriting and EventBinaryWritten
+	Bytes int64
+	// Size of the binary for EventBinaryWriting and EventBinaryWritten
+	Total int64
+	// Error for EventBuildFinished
+	Err error
+}
+
+// EventHandler receives the events emitted by a builder.
+// Events are delivered synchronously, so the handler should return promptly.
+// During multi-platform builds, the handler can be called concurrently.
+type EventHandler func(Event)
+
+// ChannelHandler returns an EventHandler that sends the events to a channel.
+// The channel must be consumed while the build runs, otherwise the build is blocked.
+// The channel is not closed when the build finishes: EventBuildFinished signals the end of the build.
+func ChannelHandler(ch chan<- Event) EventHandler {
+	return func(event Event) {
+		ch <- event
+	}
+}
@@ -0,0 +1,94 @@
+package k6foundry
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestBuildEvents(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	if err := proxy.AddModVersion("go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	events := make(chan Event, 100)
+
+	opts := NativeBuilderOpts{
+		GoOpts: GoOpts{
+			CopyGoEnv: true,
+			Env: map[string]string{
+				"GOPROXY":   goproxySrv.URL,
+				"GONOPROXY": "none",
+				"GOPRIVATE": "go.k6.io",
+				"GONOSUMDB": "go.k6.io",
+			},
+			TmpCache: true,
+		},
+		OnEvent: ChannelHandler(events),
+	}
+
+	b, err := NewNativeBuilder(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("setting up test %v", err)
+	}
+
+	platform, _ := ParsePlatform("linux/amd64")
+	out := &bytes.Buffer{}
+	_, err = b.Build(context.Background(), platform, "v0.1.0", []Module{}, []string{}, out)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	close(events)
+
+	types := []EventType{}
+	var written Event
+	var writing []Event
+	for event := range events {
+		switch event.Type {
+		case EventBinaryWritten:
+			written = event
+		case EventBinaryWriting:
+			writing = append(writing, event)
+			// the number of progress events depends on the size of the binary
+			if len(writing) > 1 {
+				continue
+			}
+		}
+		types = append(types, event.Type)
+	}
+
+	expect := []EventType{
+		EventBuildStarted,
+		EventModuleResolving,
+		EventModuleResolved,
+		EventCompiling,
+		EventCompiled,
+		EventBinaryWriting,
+		EventBinaryWritten,
+		EventBuildFinished,
+	}
+	if !reflect.DeepEqual(types, expect) {
+		t.Fatalf("expected %v got %v", expect, types)
+	}
+
+	if written.Bytes != int64(out.Len()) || written.Total != written.Bytes || written.Platform != "linux/amd64" {
+		t.Fatalf("unexpected event %v", written)
+	}
+
+	for _, event := range writing {
+		if event.Total != written.Total || event.Bytes > event.Total {
+			t.Fatalf("unexpected progress event %v", event)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package k6foundry
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// ErrInvalidExecutable is returned when the compiled binary is not an executable for the target platform
+var ErrInvalidExecutable = errors.New("invalid executable")
+
+// operating systems using ELF executables
+var elfOS = []string{ //nolint:gochecknoglobals
+	"android", "dragonfly", "freebsd", "illumos", "linux", "netbsd", "openbsd", "solaris",
+}
+
+// checkExecutable checks the binary has the executable format of the platform: PE for windows,
+// Mach-O for darwin and ELF for linux and the BSDs. Other formats are not checked
+func checkExecutable(path string, platform Platform) error {
+	var (
+		format string
+		closer io.Closer
+		err    error
+	)
+
+	switch {
+	case platform.OS == "windows":
+		format = "PE"
+		closer, err = pe.Open(path)
+	case platform.OS == "darwin" || platform.OS == "ios":
+		format = "Mach-O"
+		closer, err = macho.Open(path)
+	case slices.Contains(elfOS, platform.OS):
+		format = "ELF"
+		closer, err = elf.Open(path)
+	default:
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %s binary is not a %s executable: %w", ErrInvalidExecutable, platform, format, err)
+	}
+
+	return closer.Close()
+}
@@ -0,0 +1,71 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckExecutable(t *testing.T) {
+	t.Parallel()
+
+	// the test binary is an executable for the runtime platform
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatalf("locating test binary %v", err)
+	}
+
+	notExecutable := filepath.Join(t.TempDir(), "k6")
+	if err = os.WriteFile(notExecutable, []byte("not an executable"), 0o600); err != nil {
+		t.Fatalf("writing file %v", err)
+	}
+
+	other := Platform{OS: "windows", Arch: "amd64"}
+	if RuntimePlatform().OS == "windows" {
+		other = Platform{OS: "linux", Arch: "amd64"}
+	}
+
+	testCases := []struct {
+		title       string
+		path        string
+		platform    Platform
+		expectError error
+	}{
+		{
+			title:    "runtime platform",
+			path:     executable,
+			platform: RuntimePlatform(),
+		},
+		{
+			title:       "other platform",
+			path:        executable,
+			platform:    other,
+			expectError: ErrInvalidExecutable,
+		},
+		{
+			title:       "not an executable",
+			path:        notExecutable,
+			platform:    Platform{OS: "windows", Arch: "amd64"},
+			expectError: ErrInvalidExecutable,
+		},
+		{
+			title:    "format not checked",
+			path:     notExecutable,
+			platform: Platform{OS: "js", Arch: "wasm"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkExecutable(tc.path, tc.platform)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+//go:build windows
+
+package k6foundry
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+	errorLockViolation      = syscall.Errno(33)
+)
+
+var (
+	kernel32         = syscall.NewLazyDLL("kernel32.dll") //nolint:gochecknoglobals
+	procLockFileEx   = kernel32.NewProc("LockFileEx")     //nolint:gochecknoglobals
+	procUnlockFileEx = kernel32.NewProc("UnlockFileEx")   //nolint:gochecknoglobals
+)
+
+// tryLockFile locks the file without blocking. Returns false if the file is locked by another process
+// or file handle.
+func tryLockFile(file *os.File, exclusive bool) (bool, error) {
+	flags := uintptr(lockfileFailImmediately)
+	if exclusive {
+		flags |= lockfileExclusiveLock
+	}
+
+	overlapped := &syscall.Overlapped{}
+	r, _, err := procLockFileEx.Call(file.Fd(), flags, 0, 1, 0, uintptr(unsafe.Pointer(overlapped)))
+	if r != 0 {
+		return true, nil
+	}
+
+	if errors.Is(err, errorLockViolation) {
+		return false, nil
+	}
+
+	return false, err
+}
+
+func unlockFile(file *os.File) error {
+	overlapped := &syscall.Overlapped{}
+	r, _, err := procUnlockFileEx.Call(file.Fd(), 0, 1, 0, uintptr(unsafe.Pointer(overlapped)))
+	if r == 0 {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// home directory created in the work directory for isolating git from the host's configuration
+	homeDirName = ".home"
+
+	// name of the copy of the ssh key in the home directory
+	gitSSHKeyName = "id_k6foundry"
+
+	// the ssh command references the home directory, so it is valid also when the home is mounted in a container
+	gitSSHCommand = `ssh -i "$HOME/.ssh/` + gitSSHKeyName + `" -o IdentitiesOnly=yes` +
+		` -o UserKnownHostsFile="$HOME/.ssh/known_hosts" -o StrictHostKeyChecking=%s`
+)
+
+// hasGitAuth returns true if credentials for private git repositories are set
+func (o GoOpts) hasGitAuth() bool {
+	return o.GitSSHKey != "" || o.Netrc != "" || o.GitCredentialHelper != ""
+}
+
+// isolatedHome returns true if go and git must run with a home directory isolated from the host
+func (o GoOpts) isolatedHome() bool {
+	return o.Hermetic || o.hasGitAuth()
+}
+
+// newHome creates a home directory in the work directory with the git configuration and the credentials
+// for downloading private modules, so the configuration of the host is not used nor modified
+func newHome(workDir string, opts GoOpts) (string, error) {
+	// a reused work directory can have the home directory of a build that didn't finish cleanly
+	home := filepath.Join(workDir, homeDirName)
+	if err := removeAll(home); err != nil {
+		return "", fmt.Errorf("%w: removing home directory %w", ErrSettingGoEnv, err)
+	}
+
+	if err := os.Mkdir(home, 0o700); err != nil {
+		return "", fmt.Errorf("%w: creating home directory %w", ErrSettingGoEnv, err)
+	}
+
+	if err := writeHome(home, opts); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrSettingGoEnv, err)
+	}
+
+	return home, nil
+}
+
+func writeHome(home string, opts GoOpts) error {
+	gitConfig := &strings.Builder{}
+
+	if opts.GitCredentialHelper != "" {
+		fmt.Fprintf(gitConfig, "[credential]\n\thelper = %s\n", opts.GitCredentialHelper)
+	}
+
+	// go downloads the modules using https unless the url is rewritten
+	for _, host := range opts.GitSSHHosts {
+		fmt.Fprintf(gitConfig, "[url \"git@%s:\"]\n\tinsteadOf = https://%s/\n", host, host)
+	}
+
+	if err := os.WriteFile(filepath.Join(home, ".gitconfig"), []byte(gitConfig.String()), 0o600); err != nil {
+		return fmt.Errorf("writing git config %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(home, ".netrc"), []byte(opts.Netrc), 0o600); err != nil {
+		return fmt.Errorf("writing netrc %w", err)
+	}
+
+	if opts.GitSSHKey == "" {
+		return nil
+	}
+
+	sshDir := filepath.Join(home, ".ssh")
+	if err := os.Mkdir(sshDir, 0o700); err != nil {
+		return fmt.Errorf("creating ssh directory %w", err)
+	}
+
+	if err := copyFile(opts.GitSSHKey, filepath.Join(sshDir, gitSSHKeyName)); err != nil {
+		return fmt.Errorf("copying ssh key %w", err)
+	}
+
+	if opts.GitKnownHosts == "" {
+		return nil
+	}
+
+	if err := copyFile(opts.GitKnownHosts, filepath.Join(sshDir, "known_hosts")); err != nil {
+		return fmt.Errorf("copying known hosts %w", err)
+	}
+
+	return nil
+}
+
+// setHomeEnv sets the environment for using the home directory, given its path in the environment
+// where go runs
+func setHomeEnv(env map[string]string, opts GoOpts, home string) {
+	env["HOME"] = home
+	env["NETRC"] = home + "/.netrc"
+	// ignore the system wide git configuration
+	env["GIT_CONFIG_NOSYSTEM"] = "1"
+	// fail instead of prompting for credentials
+	env["GIT_TERMINAL_PROMPT"] = "0"
+
+	// ignore the go environment configuration file
+	if opts.Hermetic {
+		env["GOENV"] = "off"
+	}
+
+	if opts.GitSSHKey != "" {
+		// unknown hosts are accepted on first use if the known hosts are not given
+		strict := "accept-new"
+		if opts.GitKnownHosts != "" {
+			strict = "yes"
+		}
+		env["GIT_SSH_COMMAND"] = fmt.Sprintf(gitSSHCommand, strict)
+	}
+}
+
+func copyFile(from string, to string) error {
+	content, err := os.ReadFile(from) //nolint:gosec
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(to, content, 0o600)
+}
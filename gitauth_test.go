@@ -0,0 +1,204 @@
+package k6foundry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGitAuth(t *testing.T) {
+	t.Parallel()
+
+	keys := t.TempDir()
+	sshKey := filepath.Join(keys, "id_ed25519")
+	knownHosts := filepath.Join(keys, "known_hosts")
+	for _, file := range []string{sshKey, knownHosts} {
+		if err := os.WriteFile(file, []byte(filepath.Base(file)), 0o600); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	testCases := []struct {
+		title       string
+		opts        GoOpts
+		isolated    bool
+		expectError error
+		// expected git configuration
+		expectConfig map[string]string
+		// expected environment variables
+		expectEnv map[string]string
+		// expected files in the home directory
+		expectFiles map[string]string
+	}{
+		{
+			title:    "not isolated",
+			opts:     GoOpts{},
+			isolated: false,
+		},
+		{
+			title:        "hermetic",
+			opts:         GoOpts{Hermetic: true},
+			isolated:     true,
+			expectConfig: map[string]string{},
+			expectEnv: map[string]string{
+				"GOENV":               "off",
+				"GIT_CONFIG_NOSYSTEM": "1",
+			},
+			expectFiles: map[string]string{
+				".gitconfig": "",
+				".netrc":     "",
+			},
+		},
+		{
+			title:    "credential helper",
+			opts:     GoOpts{GitCredentialHelper: "store --file=/tmp/credentials"},
+			isolated: true,
+			expectConfig: map[string]string{
+				"credential.helper": "store --file=/tmp/credentials",
+			},
+			expectEnv: map[string]string{
+				"GIT_CONFIG_NOSYSTEM": "1",
+				"GIT_TERMINAL_PROMPT": "0",
+			},
+		},
+		{
+			title:    "netrc",
+			opts:     GoOpts{Netrc: "machine github.com login user password token"},
+			isolated: true,
+			expectFiles: map[string]string{
+				".netrc": "machine github.com login user password token",
+			},
+		},
+		{
+			title:    "ssh key",
+			opts:     GoOpts{GitSSHKey: sshKey, GitSSHHosts: []string{"github.com"}},
+			isolated: true,
+			expectConfig: map[string]string{
+				"url.git@github.com:.insteadof": "https://github.com/",
+			},
+			expectEnv: map[string]string{
+				"GIT_SSH_COMMAND": `ssh -i "$HOME/.ssh/id_k6foundry" -o IdentitiesOnly=yes` +
+					` -o UserKnownHostsFile="$HOME/.ssh/known_hosts" -o StrictHostKeyChecking=accept-new`,
+			},
+			expectFiles: map[string]string{
+				".ssh/id_k6foundry": "id_ed25519",
+			},
+		},
+		{
+			title:    "ssh key with known hosts",
+			opts:     GoOpts{GitSSHKey: sshKey, GitKnownHosts: knownHosts},
+			isolated: true,
+			expectEnv: map[string]string{
+				"GIT_SSH_COMMAND": `ssh -i "$HOME/.ssh/id_k6foundry" -o IdentitiesOnly=yes` +
+					` -o UserKnownHostsFile="$HOME/.ssh/known_hosts" -o StrictHostKeyChecking=yes`,
+			},
+			expectFiles: map[string]string{
+				".ssh/id_k6foundry": "id_ed25519",
+				".ssh/known_hosts":  "known_hosts",
+			},
+		},
+		{
+			title:       "missing ssh key",
+			opts:        GoOpts{GitSSHKey: filepath.Join(keys, "missing")},
+			expectError: ErrSettingGoEnv,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			opts := tc.opts
+			opts.CopyGoEnv = true
+			opts.TmpCache = true
+
+			workDir := t.TempDir()
+			e, err := newGoEnv(workDir, opts, RuntimePlatform(), io.Discard, io.Discard)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			t.Cleanup(func() {
+				_ = e.close(context.Background())
+			})
+
+			env := map[string]string{}
+			for _, v := range e.command("version").Env {
+				name, value, _ := strings.Cut(v, "=")
+				env[name] = value
+			}
+
+			home, found := env["HOME"]
+			if found != tc.isolated {
+				t.Fatalf("expected isolated home %t got %q", tc.isolated, home)
+			}
+
+			if tc.isolated && home != filepath.Join(workDir, homeDirName) {
+				t.Fatalf("expected home in work directory got %q", home)
+			}
+
+			for name, value := range tc.expectEnv {
+				if env[name] != value {
+					t.Fatalf("expected %s=%q got %q", name, value, env[name])
+				}
+			}
+
+			if tc.isolated {
+				cmd := exec.Command("git", "config", "--global", "--list")
+				cmd.Env = e.command("version").Env
+				out, err := cmd.Output()
+				if err != nil {
+					t.Fatalf("listing git config %v", err)
+				}
+
+				entries := []string{}
+				if config := strings.TrimSpace(string(out)); config != "" {
+					entries = strings.Split(config, "\n")
+				}
+
+				if len(entries) != len(tc.expectConfig) {
+					t.Fatalf("expected %d git config entries got %v", len(tc.expectConfig), entries)
+				}
+			}
+
+			for key, value := range tc.expectConfig {
+				cmd := exec.Command("git", "config", "--global", "--get", key)
+				cmd.Env = e.command("version").Env
+				out, err := cmd.Output()
+				if err != nil {
+					t.Fatalf("reading git config %s %v", key, err)
+				}
+
+				if strings.TrimSpace(string(out)) != value {
+					t.Fatalf("expected %s=%q got %q", key, value, string(out))
+				}
+			}
+
+			for file, content := range tc.expectFiles {
+				path := filepath.Join(home, filepath.FromSlash(file))
+				actual, err := os.ReadFile(path) //nolint:gosec
+				if err != nil {
+					t.Fatalf("reading %s %v", file, err)
+				}
+
+				if string(actual) != content {
+					t.Fatalf("expected %s content %q got %q", file, content, string(actual))
+				}
+			}
+
+			if tc.isolated && env["NETRC"] != home+"/.netrc" {
+				t.Fatalf("expected NETRC in home got %q", env["NETRC"])
+			}
+		})
+	}
+}
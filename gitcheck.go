@@ -0,0 +1,72 @@
+package k6foundry
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+// noProxyPatterns returns the module path patterns downloaded directly from their repositories
+// instead of the module proxy. GONOPROXY defaults to GOPRIVATE
+func noProxyPatterns(env map[string]string) string {
+	if noProxy, found := env["GONOPROXY"]; found {
+		return noProxy
+	}
+
+	return env["GOPRIVATE"]
+}
+
+// proxyIsDirect returns true if GOPROXY downloads the modules from their repositories before
+// trying any proxy
+func proxyIsDirect(goProxy string) bool {
+	proxies := strings.FieldsFunc(goProxy, func(r rune) bool { return r == ',' || r == '|' })
+
+	return len(proxies) > 0 && proxies[0] == "direct"
+}
+
+// gitRequirement returns why go needs git for downloading the module, or an empty string if the module
+// is downloaded from the module proxy or not downloaded at all
+func (e goEnv) gitRequirement(mod Module) string {
+	paths := []string{mod.Path}
+	switch {
+	// replacements by local directories are not downloaded
+	case mod.ReplacePath != "" && mod.ReplaceVersion == "":
+		return ""
+	case mod.ReplacePath != "":
+		paths = []string{mod.ReplacePath}
+	}
+
+	if proxyIsDirect(e.goProxy) {
+		return "GOPROXY=" + e.goProxy
+	}
+
+	for _, path := range paths {
+		if module.MatchPrefixPatterns(e.noProxy, path) {
+			return fmt.Sprintf("%s is downloaded from its repository (GONOPROXY=%s)", path, e.noProxy)
+		}
+	}
+
+	if e.gitAuth {
+		return "git credentials are set"
+	}
+
+	return ""
+}
+
+// checkGit checks git is installed if any module is downloaded from its repository. Modules downloaded
+// from the module proxy don't require git. If a module not found in the proxy falls back to its repository
+// (e.g. GOPROXY=https://proxy.golang.org,direct), the error of the go command reports git is missing
+func (e goEnv) checkGit(mods []Module) error {
+	if hasGit() {
+		return nil
+	}
+
+	for _, mod := range mods {
+		if reason := e.gitRequirement(mod); reason != "" {
+			return fmt.Errorf("%w: %s requires git: %s", ErrNoGit, mod.Path, reason)
+		}
+	}
+
+	return nil
+}
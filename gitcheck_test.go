@@ -0,0 +1,75 @@
+package k6foundry
+
+import "testing"
+
+func TestGitRequirement(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title   string
+		env     goEnv
+		mod     Module
+		require bool
+	}{
+		{
+			title: "module proxy",
+			env:   goEnv{goProxy: "https://proxy.golang.org,direct"},
+			mod:   Module{Path: "github.com/grafana/xk6-sql", Version: "v0.4.0"},
+		},
+		{
+			title: "module proxy off",
+			env:   goEnv{goProxy: "off"},
+			mod:   Module{Path: "github.com/grafana/xk6-sql", Version: "v0.4.0"},
+		},
+		{
+			title:   "direct",
+			env:     goEnv{goProxy: "direct"},
+			mod:     Module{Path: "github.com/grafana/xk6-sql", Version: "v0.4.0"},
+			require: true,
+		},
+		{
+			title:   "private module",
+			env:     goEnv{goProxy: "https://proxy.golang.org", noProxy: "github.com/my-org/*"},
+			mod:     Module{Path: "github.com/my-org/xk6-private", Version: "v0.1.0"},
+			require: true,
+		},
+		{
+			title: "public module with private patterns",
+			env:   goEnv{goProxy: "https://proxy.golang.org", noProxy: "github.com/my-org/*"},
+			mod:   Module{Path: "github.com/grafana/xk6-sql", Version: "v0.4.0"},
+		},
+		{
+			title: "replaced by private module",
+			env:   goEnv{goProxy: "https://proxy.golang.org", noProxy: "github.com/my-org/*"},
+			mod: Module{
+				Path:           "github.com/grafana/xk6-sql",
+				ReplacePath:    "github.com/my-org/xk6-sql",
+				ReplaceVersion: "v0.4.1",
+			},
+			require: true,
+		},
+		{
+			title: "replaced by local directory",
+			env:   goEnv{goProxy: "direct"},
+			mod:   Module{Path: "github.com/grafana/xk6-sql", ReplacePath: "../xk6-sql"},
+		},
+		{
+			title:   "git credentials",
+			env:     goEnv{goProxy: "https://proxy.golang.org", gitAuth: true},
+			mod:     Module{Path: "github.com/grafana/xk6-sql", Version: "v0.4.0"},
+			require: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			reason := tc.env.gitRequirement(tc.mod)
+			if (reason != "") != tc.require {
+				t.Fatalf("expected git required %t got %q", tc.require, reason)
+			}
+		})
+	}
+}
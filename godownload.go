@@ -0,0 +1,374 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultGoDownloadVersion is the go version downloaded when no go toolchain is found, if GoVersion is not set
+const DefaultGoDownloadVersion = "1.22.5"
+
+// URL of the official go releases
+const defaultGoDownloadURL = "https://go.dev/dl/"
+
+// ErrDownloadingGo is returned when the go toolchain can't be downloaded
+var ErrDownloadingGo = errors.New("downloading go toolchain")
+
+// GoDownloadOpts defines the options for downloading a go toolchain
+type GoDownloadOpts struct {
+	// Go version (e.g. 1.22.5). Defaults to DefaultGoDownloadVersion
+	Version string
+	// Directory the toolchains are downloaded to. Defaults to k6foundry/go in the user's cache directory
+	Dir string
+	// URL of the go releases, listing them as JSON with ?mode=json. Defaults to https://go.dev/dl/
+	URL string
+	// Client used for downloading. Defaults to http.DefaultClient
+	HTTPClient *http.Client
+}
+
+// goRelease is a release in the list of go releases
+type goRelease struct {
+	Version string          `json:"version"`
+	Files   []goReleaseFile `json:"files"`
+}
+
+// goReleaseFile is a file of a go release
+type goReleaseFile struct {
+	Filename string `json:"filename"`
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+	SHA256   string `json:"sha256"`
+	Kind     string `json:"kind"`
+}
+
+// DownloadGoToolchain downloads the official go release for the runtime platform, verifying its checksum,
+// and returns the directory it is installed in (its GOROOT). If the release was already downloaded,
+// the directory is returned without downloading it again
+func DownloadGoToolchain(ctx context.Context, opts GoDownloadOpts) (string, error) {
+	version := "go" + strings.TrimPrefix(opts.Version, "go")
+	if opts.Version == "" {
+		version = "go" + DefaultGoDownloadVersion
+	}
+
+	dir, err := goDownloadDir(opts.Dir)
+	if err != nil {
+		return "", err
+	}
+
+	if err = os.MkdirAll(dir, 0o750); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrDownloadingGo, err)
+	}
+
+	// concurrent builds wait for the download instead of downloading the same release
+	release, err := lockFile(ctx, filepath.Join(dir, ".lock"), true)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrDownloadingGo, err)
+	}
+	defer release()
+
+	goRoot := filepath.Join(dir, version)
+	if _, err = os.Stat(filepath.Join(goRoot, "bin", RuntimePlatform().BinaryName("go"))); err == nil {
+		return goRoot, nil
+	}
+
+	baseURL := opts.URL
+	if baseURL == "" {
+		baseURL = defaultGoDownloadURL
+	}
+	baseURL = strings.TrimSuffix(baseURL, "/") + "/"
+
+	client := opts.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	file, err := findGoReleaseFile(ctx, client, baseURL, version)
+	if err != nil {
+		return "", err
+	}
+
+	// download and extract to a temporary directory, so the release is never partially installed
+	tmpDir, err := os.MkdirTemp(dir, "."+version+"*")
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrDownloadingGo, err)
+	}
+	defer os.RemoveAll(tmpDir) //nolint:errcheck
+
+	archive, err := downloadGoArchive(ctx, client, baseURL, file, tmpDir)
+	if err != nil {
+		return "", err
+	}
+	defer archive.Close() //nolint:errcheck
+
+	if strings.HasSuffix(file.Filename, ".zip") {
+		err = extractZip(archive, tmpDir)
+	} else {
+		err = extractTarGz(archive, tmpDir)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: extracting %s: %w", ErrDownloadingGo, file.Filename, err)
+	}
+
+	// the archives have the toolchain in the go directory
+	if err = os.Rename(filepath.Join(tmpDir, "go"), goRoot); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrDownloadingGo, err)
+	}
+
+	return goRoot, nil
+}
+
+// goDownloadDir returns the directory for downloading go toolchains
+func goDownloadDir(dir string) (string, error) {
+	if dir != "" {
+		return dir, nil
+	}
+
+	userCache, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("%w: locating cache directory %w", ErrDownloadingGo, err)
+	}
+
+	return filepath.Join(userCache, "k6foundry", "go"), nil
+}
+
+// goBinary returns the go binary used for building: the one in the PATH or, if DownloadGo is set,
+// the downloaded toolchain
+func goBinary(opts GoOpts) (string, error) {
+	if bin, err := exec.LookPath("go"); err == nil {
+		return bin, nil
+	}
+
+	if !opts.DownloadGo {
+		return "", ErrNoGoToolchain
+	}
+
+	dir, err := goDownloadDir(opts.GoDownloadDir)
+	if err != nil {
+		return "", err
+	}
+
+	version := opts.GoVersion
+	if version == "" {
+		version = DefaultGoDownloadVersion
+	}
+
+	bin := filepath.Join(dir, "go"+strings.TrimPrefix(version, "go"), "bin", RuntimePlatform().BinaryName("go"))
+	if _, err = os.Stat(bin); err != nil {
+		return "", ErrNoGoToolchain
+	}
+
+	return bin, nil
+}
+
+// downloadGoIfMissing downloads the go toolchain if DownloadGo is set and go is not in the PATH
+func downloadGoIfMissing(ctx context.Context, opts GoOpts) error {
+	if !opts.DownloadGo {
+		return nil
+	}
+
+	if _, err := exec.LookPath("go"); err == nil {
+		return nil
+	}
+
+	_, err := DownloadGoToolchain(ctx, GoDownloadOpts{
+		Version: opts.GoVersion,
+		Dir:     opts.GoDownloadDir,
+		URL:     opts.GoDownloadURL,
+	})
+
+	return err
+}
+
+// findGoReleaseFile returns the archive of the go release for the runtime platform
+func findGoReleaseFile(ctx context.Context, client *http.Client, baseURL string, version string) (goReleaseFile, error) {
+	body, err := fetchGoDownload(ctx, client, baseURL+"?mode=json&include=all")
+	if err != nil {
+		return goReleaseFile{}, err
+	}
+	defer body.Close() //nolint:errcheck
+
+	releases := []goRelease{}
+	if err = json.NewDecoder(body).Decode(&releases); err != nil {
+		return goReleaseFile{}, fmt.Errorf("%w: listing releases: %w", ErrDownloadingGo, err)
+	}
+
+	platform := RuntimePlatform()
+	arch := platform.Arch
+	// arm releases are built for armv6l
+	if arch == "arm" {
+		arch = "armv6l"
+	}
+
+	for _, release := range releases {
+		if release.Version != version {
+			continue
+		}
+
+		for _, file := range release.Files {
+			if file.Kind == "archive" && file.OS == platform.OS && file.Arch == arch {
+				return file, nil
+			}
+		}
+
+		return goReleaseFile{}, fmt.Errorf("%w: %s has no release for %s", ErrDownloadingGo, version, platform)
+	}
+
+	return goReleaseFile{}, fmt.Errorf("%w: unknown version %s", ErrDownloadingGo, version)
+}
+
+// fetchGoDownload returns the body of a successful response to a GET request for the url.
+// The caller must close it
+func fetchGoDownload(ctx context.Context, client *http.Client, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDownloadingGo, err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDownloadingGo, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s %s", ErrDownloadingGo, url, resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// downloadGoArchive downloads the archive of a release into dir, verifying its checksum, and returns it
+// opened for reading. The archive is streamed to the file, as it is too large for keeping it in memory
+func downloadGoArchive(
+	ctx context.Context,
+	client *http.Client,
+	baseURL string,
+	file goReleaseFile,
+	dir string,
+) (*os.File, error) {
+	body, err := fetchGoDownload(ctx, client, baseURL+file.Filename)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close() //nolint:errcheck
+
+	archive, err := os.Create(filepath.Join(dir, filepath.Base(file.Filename)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDownloadingGo, err)
+	}
+
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(archive, hash), body)
+	if err == nil {
+		_, err = archive.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		_ = archive.Close()
+		return nil, fmt.Errorf("%w: %w", ErrDownloadingGo, err)
+	}
+
+	if hex.EncodeToString(hash.Sum(nil)) != file.SHA256 {
+		_ = archive.Close()
+		return nil, fmt.Errorf("%w: checksum mismatch for %s", ErrDownloadingGo, file.Filename)
+	}
+
+	return archive, nil
+}
+
+// archivePath returns the path in dir of a file in an archive, rejecting paths outside dir
+func archivePath(dir string, name string) (string, error) {
+	local := filepath.FromSlash(name)
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("invalid path %q", name)
+	}
+
+	return filepath.Join(dir, local), nil
+}
+
+// extractTarGz extracts the directories and regular files of a tar.gz archive into dir
+func extractTarGz(archive io.Reader, dir string) error {
+	gz, err := gzip.NewReader(archive)
+	if err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := archivePath(dir, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(target, 0o750)
+		case tar.TypeReg:
+			err = extractFile(tr, target, header.FileInfo().Mode().Perm())
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// extractZip extracts the directories and files of a zip archive into dir
+func extractZip(archive *os.File, dir string) error {
+	info, err := archive.Stat()
+	if err != nil {
+		return err
+	}
+
+	zr, err := zip.NewReader(archive, info.Size())
+	if err != nil {
+		return err
+	}
+
+	for _, f := range zr.File {
+		target, err := archivePath(dir, f.Name)
+		if err != nil {
+			return err
+		}
+
+		if f.FileInfo().IsDir() {
+			if err = os.MkdirAll(target, 0o750); err != nil {
+				return err
+			}
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+
+		err = extractFile(rc, target, f.Mode().Perm())
+		_ = rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package k6foundry
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+)
+
+// goArchive returns a tar.gz archive with a fake go toolchain
+func goArchive(t *testing.T) []byte {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+
+	files := []struct {
+		name    string
+		content string
+		mode    int64
+	}{
+		{name: "go/VERSION", content: "go1.22.5", mode: 0o644},
+		{name: "go/bin/go", content: "#!/bin/sh\necho go version go1.22.5 linux/amd64\n", mode: 0o755},
+	}
+
+	for _, f := range files {
+		header := &tar.Header{Name: f.name, Mode: f.mode, Size: int64(len(f.content)), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+		if _, err := tw.Write([]byte(f.content)); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestDownloadGoToolchain(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake toolchain is a shell script")
+	}
+
+	archive := goArchive(t)
+	sum := sha256.Sum256(archive)
+
+	filename := "go1.22.5." + runtime.GOOS + "-" + runtime.GOARCH + ".tar.gz"
+	releases := []goRelease{
+		{
+			Version: "go1.22.5",
+			Files: []goReleaseFile{
+				{Filename: "go1.22.5.src.tar.gz", Kind: "source"},
+				{
+					Filename: filename,
+					OS:       runtime.GOOS,
+					Arch:     runtime.GOARCH,
+					SHA256:   hex.EncodeToString(sum[:]),
+					Kind:     "archive",
+				},
+			},
+		},
+		{
+			Version: "go1.22.4",
+			Files: []goReleaseFile{
+				{Filename: "go1.22.4.tar.gz", OS: runtime.GOOS, Arch: runtime.GOARCH, SHA256: "bad", Kind: "archive"},
+			},
+		},
+	}
+
+	downloads := atomic.Int32{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/dl/", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(releases)
+	})
+	mux.HandleFunc("/dl/"+filename, func(w http.ResponseWriter, _ *http.Request) {
+		downloads.Add(1)
+		_, _ = w.Write(archive)
+	})
+	mux.HandleFunc("/dl/go1.22.4.tar.gz", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(archive)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	testCases := []struct {
+		title       string
+		version     string
+		expectError error
+	}{
+		{title: "download", version: "1.22.5"},
+		{title: "checksum mismatch", version: "1.22.4", expectError: ErrDownloadingGo},
+		{title: "unknown version", version: "1.0.0", expectError: ErrDownloadingGo},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			opts := GoDownloadOpts{Version: tc.version, Dir: dir, URL: srv.URL + "/dl/"}
+
+			goRoot, err := DownloadGoToolchain(context.Background(), opts)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			if tc.expectError != nil {
+				return
+			}
+
+			version, found := goVersion(filepath.Join(goRoot, "bin", "go"))
+			if !found || version != "1.22.5" {
+				t.Fatalf("expected go 1.22.5 got %q", version)
+			}
+
+			// the toolchain is reused
+			if _, err = DownloadGoToolchain(context.Background(), opts); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if n := downloads.Load(); n != 1 {
+				t.Fatalf("expected 1 download got %d", n)
+			}
+
+			entries, _ := os.ReadDir(dir)
+			if len(entries) != 2 {
+				t.Fatalf("expected only the toolchain and the lock in the directory got %v", entries)
+			}
+		})
+	}
+}
@@ -11,8 +11,13 @@ import (
 	"maps"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
+
+	"golang.org/x/mod/modfile"
 )
 
 var (
@@ -24,37 +29,184 @@ var (
 	ErrNoGoToolchain = errors.New("go toolchain notfound")
 	// Git is not installed
 	ErrNoGit = errors.New("git notfound")
+	// Zig is not installed
+	ErrNoZig = errors.New("zig notfound")
 	// Error resolving dependency
 	ErrResolvingDependency = errors.New("resolving dependency")
+	// Branch or commit of a dependency not found
+	ErrUnknownRevision = errors.New("unknown revision")
 	// Error initiailizing go build environment
 	ErrSettingGoEnv = errors.New("setting go environment")
+	// Invalid go toolchain version
+	ErrInvalidGoVersion = errors.New("invalid go version")
+	// Target platform is not supported by the go toolchain
+	ErrUnsupportedPlatform = errors.New("platform not supported by go toolchain")
+	// A go command exceeded GoGetTimeout or GOBuildTimeout
+	ErrBuildTimeout = errors.New("build timed out")
+	// Free disk space in the work or cache directories is below MinFreeDiskSpace
+	ErrInsufficientDiskSpace = errors.New("insufficient disk space")
+
+	// go toolchain names, e.g. go1.22.5 or go1.23rc1
+	goToolchainRegexp = regexp.MustCompile(`^go1\.\d+(\.\d+|rc\d+)?$`)
+)
+
+const (
+	// DefaultGoGetTimeout is the default timeout for the go commands that download modules
+	DefaultGoGetTimeout = 10 * time.Minute
+	// DefaultGoBuildTimeout is the default timeout for compiling the binary
+	DefaultGoBuildTimeout = 15 * time.Minute
 )
 
 // GoOpts defines the options for the go build environment
 type GoOpts struct {
-	// Environment variables passed to the build service
-	// Can override variables copied from the current go environment
+	// Environment variables passed to the build service. They take precedence over the variables set
+	// by other options (e.g. GoVersion sets GOTOOLCHAIN), which take precedence over the variables copied
+	// from the current go environment. Setting a variable also set by an option to a different value
+	// is rejected with ErrConflictingEnv
 	Env map[string]string
 	// Copy Environment variables to go build environment
 	CopyGoEnv bool
-	// Timeout for getting modules
+	// Timeout for each go command that downloads modules. Defaults to DefaultGoGetTimeout.
+	// A negative value disables the timeout
 	GoGetTimeout time.Duration
-	// Timeout for building binary
+	// Number of times the go commands that download modules are retried when they fail due to
+	// a transient network failure
+	GetRetries int
+	// Delay before the first retry. Doubles on each retry. Defaults to 1s
+	GetRetryBackoff time.Duration
+	// Timeout for compiling the binary. Defaults to DefaultGoBuildTimeout. A negative value disables the timeout
 	GOBuildTimeout time.Duration
 	// Use an ephemeral cache. Ignores GoModCache and GoCache
 	TmpCache bool
+	// Directory for the go module and build caches, shared by the builds of the builder.
+	// Defaults to the caches of the go environment. Ignored if TmpCache is set
+	GoCacheDir string
+	// Maximum size in bytes of the caches in GoCacheDir (or the default cache of the container builder).
+	// After each build, the least recently used modules and build outputs are removed until the caches
+	// fit. 0 means no limit
+	MaxCacheSize int64
+	// Minimum free disk space in bytes required in the work and cache directories for starting a build.
+	// 0 disables the check
+	MinFreeDiskSpace int64
+	// Build without network access, using only the modules in the module cache (GoCacheDir or the cache of
+	// the go environment). Fails with a MissingModulesError if any module is not in the cache.
+	// Can't be used with TmpCache
+	Offline bool
+	// C compiler used for cgo. Enables cgo also when cross compiling
+	CC string
+	// C++ compiler used for cgo
+	CXX string
+	// Go toolchain version used for building (e.g. 1.22.5). The toolchain is downloaded if it is
+	// not the installed one. Defaults to the installed toolchain
+	GoVersion string
+	// Use zig as C/C++ cross compiler for cgo, targeting the build platform. Overrides CC and CXX
+	Zig bool
+	// Run go and git with a home directory in the work directory, so the build doesn't depend on the
+	// configuration of the host (git configuration, netrc, go environment file). Only the environment
+	// copied with CopyGoEnv and the variables in Env are used
+	Hermetic bool
+	// Private ssh key used by git for downloading private modules. If any git credential is set,
+	// git runs with an isolated home directory, ignoring the git and ssh configuration of the host
+	GitSSHKey string
+	// known_hosts file for verifying the ssh hosts. If empty, unknown hosts are accepted on first use
+	GitKnownHosts string
+	// hosts accessed using ssh instead of https (e.g. github.com). Requires GitSSHKey
+	GitSSHHosts []string
+	// content of a netrc file with the credentials for downloading private modules using https
+	Netrc string
+	// git credential helper for downloading private modules using https (e.g. "store --file=/path/to/file")
+	GitCredentialHelper string
+	// flags passed to every go command (GOFLAGS), e.g. -mod=mod. Each flag must start with '-'
+	GoFlags []string
+	// build tags used for compiling the binary (e.g. netgo), added to any -tags in the build options
+	BuildTags []string
+	// arguments passed to the linker (-ldflags), e.g. -s, -w or "-X main.version=1.0". Each element is a
+	// flag and its optional value, which is quoted if it contains spaces
+	LDFlags []string
+	// arguments passed to the compiler (-gcflags), e.g. all=-N, -l. The first argument can specify the
+	// packages they apply to, as the -gcflags option does
+	GCFlags []string
+	// arguments passed to the assembler (-asmflags)
+	ASMFlags []string
+	// remove the file system paths from the binary (-trimpath)
+	TrimPath bool
+	// enable the data race detector (-race). Requires cgo
+	Race bool
+	// CPU profile used for profile-guided optimization. It is copied into the work directory as
+	// default.pgo and passed to go build with -pgo
+	PGOProfile string
+	// version control systems allowed for downloading modules (GOVCS), e.g. "*:git"
+	VCS string
+	// checksum database used for verifying the modules (GOSUMDB). "off" disables the verification
+	SumDB string
+	// module path patterns not verified using the checksum database (GONOSUMDB)
+	NoSumDB []string
+	// fail if the build environment skips the checksum database verification for any module,
+	// including the settings copied from the go environment (GOSUMDB=off, GONOSUMDB, GOPRIVATE)
+	RequireSumDB bool
+	// if no go toolchain is found in the PATH, download the official release of GoVersion
+	// (DefaultGoDownloadVersion if not set) and use it for building
+	DownloadGo bool
+	// directory the go toolchains are downloaded to. Defaults to k6foundry/go in the user's cache directory
+	GoDownloadDir string
+	// URL of the go releases. Defaults to https://go.dev/dl/
+	GoDownloadURL string
+	// Parent directory of the temporary work directories and caches (TmpCache), e.g. a tmpfs mount for
+	// keeping the IO of the builds in memory. Created if it doesn't exist. Defaults to the system
+	// temporary directory
+	BuildDirRoot string
 }
 
+// goCommand returns the command for executing go with the given arguments
+type goCommand func(args ...string) *exec.Cmd
+
 type goEnv struct {
-	env          []string
+	command goCommand
+	// commandFor returns the command for building for another platform using the same environment
+	commandFor   func(platform Platform) goCommand
 	workDir      string
 	platform     Platform
 	stdout       io.Writer
 	stderr       io.Writer
 	tmpDirs      []string
-	tmpCache     bool
 	buildTimeout time.Duration
 	getTimeout   time.Duration
+	retries      int
+	retryBackoff time.Duration
+	// lock file coordinating the access to the module cache shared with other builds. Empty if not shared
+	cacheLock string
+	// directory in the host with the go caches (gocache and modcache) set by GoCacheDir. Empty otherwise
+	cacheDir string
+	// module cache in the host
+	modCache string
+	// proxy settings, for checking which modules are downloaded with git
+	goProxy string
+	noProxy string
+	// git credentials are set for downloading private modules
+	gitAuth bool
+	// stops the processes of a canceled command that are not in its process tree (e.g. a container).
+	// Optional
+	stop func(cmd *exec.Cmd)
+}
+
+// buildDirRoot returns the parent directory of the temporary directories of the builds
+func (o GoOpts) buildDirRoot() string {
+	if o.BuildDirRoot != "" {
+		return o.BuildDirRoot
+	}
+
+	return os.TempDir()
+}
+
+// diskUsage returns the size of the files in the work directory and the temporary directories of the
+// environment, such as a temporary cache
+func (e goEnv) diskUsage(workDir string) int64 {
+	usage := diskUsage(workDir)
+	for _, dir := range e.tmpDirs {
+		usage += diskUsage(dir)
+	}
+
+	return usage
 }
 
 func newGoEnv(
@@ -69,106 +221,208 @@ func newGoEnv(
 		tmpDirs []string
 	)
 
-	if _, hasGo := goVersion(); !hasGo {
+	goBin, err := goBinary(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, hasGo := goVersion(goBin); !hasGo {
 		return nil, ErrNoGoToolchain
 	}
 
-	if !hasGit() {
-		return nil, ErrNoGit
+	if opts.Zig {
+		if _, err = exec.LookPath("zig"); err != nil {
+			return nil, ErrNoZig
+		}
 	}
 
 	env := map[string]string{}
 
 	// copy current go environment
 	if opts.CopyGoEnv {
-		env, err = getGoEnv()
+		env, err = getGoEnv(goBin)
 		if err != nil {
 			return nil, fmt.Errorf("copying go environment %w", err)
 		}
 	}
 
-	// set/override environment variables
-	maps.Copy(env, opts.Env)
+	// the workspace of the current directory doesn't apply to the work directory
+	delete(env, "GOWORK")
+
+	// the variables set by the options override the copied go environment
+	if err = setToolchainEnv(env, opts.GoVersion); err != nil {
+		return nil, err
+	}
+
+	setVerificationEnv(env, opts)
 
 	if opts.TmpCache {
-		// override caches with temporary files
-		var modCache, goCache string
-		modCache, err = os.MkdirTemp(os.TempDir(), "modcache*")
+		// override caches with temporary directories. Both are kept under a common directory
+		// marked as owned by this process, so it can be reclaimed if the process dies
+		var cacheDir string
+		cacheDir, err = mkTempDir(opts.buildDirRoot(), tmpDirPrefix+"-cache*")
 		if err != nil {
-			return nil, fmt.Errorf("creating mod cache %w", err)
+			return nil, fmt.Errorf("creating temporary cache %w", err)
 		}
 
-		goCache, err = os.MkdirTemp(os.TempDir(), "cache*")
+		env["GOCACHE"] = filepath.Join(cacheDir, "gocache")
+		env["GOMODCACHE"] = filepath.Join(cacheDir, "modcache")
+
+		// add to the list of directories for cleanup
+		tmpDirs = append(tmpDirs, cacheDir)
+	}
+
+	cacheDir := ""
+	if opts.GoCacheDir != "" && !opts.TmpCache {
+		cacheDir = opts.GoCacheDir
+		env["GOCACHE"] = filepath.Join(cacheDir, "gocache")
+		env["GOMODCACHE"] = filepath.Join(cacheDir, "modcache")
+	}
+
+	// ensure path is set, including the go toolchain if downloaded. Can be overridden by Env
+	env["PATH"] = os.Getenv("PATH")
+	if _, err = exec.LookPath("go"); err != nil {
+		env["PATH"] = filepath.Dir(goBin) + string(os.PathListSeparator) + env["PATH"]
+	}
+
+	if opts.isolatedHome() {
+		var home string
+		home, err = newHome(workDir, opts)
 		if err != nil {
-			return nil, fmt.Errorf("creating go cache %w", err)
+			for _, dir := range tmpDirs {
+				_ = removeAll(dir)
+			}
+			return nil, err
 		}
 
-		env["GOCACHE"] = goCache
-		env["GOMODCACHE"] = modCache
+		setHomeEnv(env, opts, home)
+	}
 
-		// add to the list of directories for cleanup
-		tmpDirs = append(tmpDirs, goCache, modCache)
+	// the variables set explicitly take precedence. validateEnv rejects the ones conflicting with the options
+	maps.Copy(env, opts.Env)
+
+	if err = checkSumDBRequired(env, opts); err != nil {
+		for _, dir := range tmpDirs {
+			_ = removeAll(dir)
+		}
+		return nil, err
 	}
 
-	// ensure path is set
-	env["PATH"] = os.Getenv("PATH")
+	// the module cache is shared with other builds unless it is temporary
+	cacheLock := ""
+	modCache := env["GOMODCACHE"]
+	if !opts.TmpCache {
+		if modCache == "" {
+			out, err := hostGoCommand(goBin, workDir, mapToSlice(env))("env", "GOMODCACHE").Output()
+			if err != nil {
+				return nil, fmt.Errorf("%w: locating module cache %w", ErrSettingGoEnv, err)
+			}
+			modCache = strings.TrimSpace(string(out))
+		}
+
+		if cacheLock, err = cacheLockPath(modCache); err != nil {
+			return nil, err
+		}
+	}
 
-	// override platform
-	env["GOOS"] = platform.OS
-	env["GOARCH"] = platform.Arch
+	commandFor := func(platform Platform) goCommand {
+		platformEnv := maps.Clone(env)
 
-	// disable CGO if target platform is different from host platform
-	if env["GOHOSTARCH"] != platform.Arch || env["GOHOSTOS"] != platform.OS {
-		env["CGO_ENABLED"] = "0"
+		// override platform
+		platform.setEnv(platformEnv)
+
+		native := platformEnv["GOHOSTARCH"] == platform.Arch && platformEnv["GOHOSTOS"] == platform.OS
+		setCgoEnv(platformEnv, platform, opts, native)
+
+		return hostGoCommand(goBin, workDir, mapToSlice(platformEnv))
 	}
 
 	return &goEnv{
-		env:          mapToSlice(env),
+		command:      commandFor(platform),
+		commandFor:   commandFor,
 		platform:     platform,
 		workDir:      workDir,
 		stdout:       stdout,
 		stderr:       stderr,
-		buildTimeout: opts.GOBuildTimeout,
-		getTimeout:   opts.GoGetTimeout,
+		buildTimeout: timeoutOrDefault(opts.GOBuildTimeout, DefaultGoBuildTimeout),
+		getTimeout:   timeoutOrDefault(opts.GoGetTimeout, DefaultGoGetTimeout),
+		retries:      opts.GetRetries,
+		retryBackoff: opts.GetRetryBackoff,
 		tmpDirs:      tmpDirs,
-		tmpCache:     opts.TmpCache,
+		cacheLock:    cacheLock,
+		cacheDir:     cacheDir,
+		modCache:     modCache,
+		goProxy:      env["GOPROXY"],
+		noProxy:      noProxyPatterns(env),
+		gitAuth:      opts.hasGitAuth(),
 	}, nil
 }
 
-func (e goEnv) close(ctx context.Context) error {
-	var err error
+// withPlatform returns a go environment for building for another platform sharing the
+// work directory and caches. The returned environment doesn't own the temporary directories.
+func (e goEnv) withPlatform(platform Platform) *goEnv {
+	e.platform = platform
+	e.command = e.commandFor(platform)
+	e.tmpDirs = nil
 
-	if e.tmpCache {
-		// clean caches, otherwirse directories can't be deleted
-		err = e.clean(ctx)
-	}
+	return &e
+}
 
-	// creal all temporary dirs
+func (e goEnv) close(_ context.Context) error {
+	var err error
+
+	// clear all temporary dirs. removeAll restores the write permissions of the mod cache
 	for _, dir := range e.tmpDirs {
 		err = errors.Join(
 			err,
-			os.RemoveAll(dir),
+			removeAll(dir),
 		)
 	}
 
 	return err
 }
 
+// unlock removes the lock from the temporary directories, so they are not reclaimed
+func (e goEnv) unlock() {
+	for _, dir := range e.tmpDirs {
+		_ = os.Remove(filepath.Join(dir, lockFileName))
+	}
+}
+
 func (e goEnv) runGo(ctx context.Context, timeout time.Duration, args ...string) error {
-	cmd := exec.Command("go", args...)
+	return e.execGo(ctx, timeout, e.stdout, args...)
+}
+
+// runGoOutput runs a go command as runGo, returning its standard output instead of writing it to stdout.
+// The output is returned also if the command fails, as some commands report the errors in it
+func (e goEnv) runGoOutput(ctx context.Context, timeout time.Duration, args ...string) ([]byte, error) {
+	out := &bytes.Buffer{}
+	err := e.execGo(ctx, timeout, out, args...)
 
-	cmd.Env = e.env
-	cmd.Dir = e.workDir
+	return out.Bytes(), err
+}
 
-	cmd.Stdout = e.stdout
-	cmd.Stderr = e.stderr
+// execGo runs a go command writing its standard output to stdout. The command is stopped, together with
+// the processes it spawned, if the context is canceled or the timeout expires
+func (e goEnv) execGo(ctx context.Context, timeout time.Duration, stdout io.Writer, args ...string) error {
+	cmd := e.command(args...)
 
+	// the end of the output is captured for reporting the error of the command
+	output := newTailBuffer(maxCapturedOutput)
+	cmd.Stdout = stdout
+	cmd.Stderr = io.MultiWriter(e.stderr, output)
+
+	// the parent context tells if the command timed out or was canceled
+	parent := ctx
 	if timeout > 0 {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, timeout)
 		defer cancel()
 	}
 
+	// the processes spawned by go are stopped together with it
+	setProcessGroup(cmd)
+
 	// start the command; if it fails to start, report error immediately
 	err := cmd.Start()
 	if err != nil {
@@ -184,8 +438,14 @@ func (e goEnv) runGo(ctx context.Context, timeout time.Duration, args ...string)
 	cmdErrChan := make(chan error)
 	go func() {
 		cmdErr := cmd.Wait()
+		flushOutput(stdout, e.stderr)
 		if cmdErr != nil {
-			cmdErr = fmt.Errorf("%w: %s", ErrExecutingGoCommand, cmdErr.Error())
+			if goErr := goError(output.String()); goErr != nil {
+				cmdErr = fmt.Errorf("%w: %w", ErrExecutingGoCommand, goErr)
+			} else {
+				cmdErr = fmt.Errorf("%w: %s", ErrExecutingGoCommand, cmdErr.Error())
+			}
+			cmdErr = &outputError{err: cmdErr, output: output.String()}
 		}
 		cmdErrChan <- cmdErr
 	}()
@@ -199,18 +459,43 @@ func (e goEnv) runGo(ctx context.Context, timeout time.Duration, args ...string)
 	case <-ctx.Done():
 		// context was canceled, either due to timeout or
 		// maybe a signal from higher up canceled the parent
-		// context; presumably, the OS also sent the signal
-		// to the child process, so wait for it to die
+		// context; the signal is not necessarily propagated to the
+		// child process (e.g. SIGTERM), so interrupt it and the processes it spawned and wait for them to die
+		if err = interruptProcessTree(cmd); err != nil {
+			// interrupt is not supported in all platforms (e.g. windows)
+			_ = killProcessTree(cmd)
+		}
+		if e.stop != nil {
+			e.stop(cmd)
+		}
 		select {
 		// TODO: check this magic timeout
 		case <-time.After(15 * time.Second):
-			_ = cmd.Process.Kill()
+			_ = killProcessTree(cmd)
+			<-cmdErrChan
 		case <-cmdErrChan:
+			// processes spawned by go may outlive it
+			_ = killProcessTree(cmd)
+		}
+		if parent.Err() == nil {
+			return fmt.Errorf("%w: go %s exceeded %s: %w", ErrBuildTimeout, args[0], timeout, ctx.Err())
 		}
 		return ctx.Err()
 	}
 }
 
+// timeoutOrDefault returns the default timeout if the timeout is not set. A negative timeout means no timeout
+func timeoutOrDefault(timeout time.Duration, defaultTimeout time.Duration) time.Duration {
+	switch {
+	case timeout == 0:
+		return defaultTimeout
+	case timeout < 0:
+		return 0
+	default:
+		return timeout
+	}
+}
+
 func (e goEnv) modInit(ctx context.Context) error {
 	// initialize the go module
 	// TODO: change magic constant in timeout
@@ -222,11 +507,30 @@ func (e goEnv) modInit(ctx context.Context) error {
 	return nil
 }
 
+// workInit creates a go.work including the given directories
+func (e goEnv) workInit(ctx context.Context, dirs ...string) error {
+	err := e.runGo(ctx, e.getTimeout, append([]string{"work", "init"}, dirs...)...)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrSettingGoEnv, err.Error())
+	}
+
+	return nil
+}
+
 // tidy the module to ensure go.mod will not have versions such as `latest`
 func (e goEnv) modTidy(ctx context.Context) error {
-	err := e.runGo(ctx, e.getTimeout, "mod", "tidy", "-compat=1.17")
+	err := e.runGoWithRetries(ctx, e.getTimeout, "mod", "tidy", "-compat=1.17")
 	if err != nil {
-		return fmt.Errorf("%w: %s", ErrResolvingDependency, err.Error())
+		return fmt.Errorf("%w: %w", ErrResolvingDependency, err)
+	}
+
+	return nil
+}
+
+func (e goEnv) modVendor(ctx context.Context) error {
+	err := e.runGoWithRetries(ctx, e.getTimeout, "mod", "vendor")
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrResolvingDependency, err)
 	}
 
 	return nil
@@ -239,7 +543,7 @@ func (e goEnv) modRequire(ctx context.Context, modulePath, moduleVersion string)
 
 	err := e.runGo(ctx, e.getTimeout, "mod", "edit", "-require", modulePath)
 	if err != nil {
-		return fmt.Errorf("%w: %s", ErrResolvingDependency, err.Error())
+		return fmt.Errorf("%w: %w", ErrResolvingDependency, err)
 	}
 
 	return nil
@@ -256,7 +560,7 @@ func (e goEnv) modReplace(ctx context.Context, modulePath, moduleVersion, replac
 
 	err := e.runGo(ctx, e.getTimeout, "mod", "edit", "-replace", fmt.Sprintf("%s=%s", modulePath, replacePath))
 	if err != nil {
-		return fmt.Errorf("%w: %s", ErrResolvingDependency, err.Error())
+		return fmt.Errorf("%w: %w", ErrResolvingDependency, err)
 	}
 
 	return nil
@@ -266,17 +570,21 @@ func (e goEnv) compile(ctx context.Context, outPath string, buildFlags ...string
 	args := append([]string{"build", "-o", outPath}, buildFlags...)
 
 	err := e.runGo(ctx, e.buildTimeout, args...)
-	if err != nil {
-		return fmt.Errorf("%w: %s", ErrCompiling, err.Error())
-	}
 
-	return err
-}
+	// attribute the error to the module of the package, such as an extension
+	var compileErr *CompileError
+	if errors.As(err, &compileErr) {
+		if requires, reqErr := e.modRequires(); reqErr == nil {
+			modules := []string{}
+			for mod := range requires {
+				modules = append(modules, mod)
+			}
+			compileErr.Module = packageModule(compileErr.Package, modules)
+		}
+	}
 
-func (e goEnv) clean(ctx context.Context) error {
-	err := e.runGo(ctx, e.buildTimeout, "clean", "-cache", "-modcache")
 	if err != nil {
-		return fmt.Errorf("cleaning: %s", err.Error())
+		return fmt.Errorf("%w: %w", ErrCompiling, err)
 	}
 
 	return err
@@ -284,9 +592,7 @@ func (e goEnv) clean(ctx context.Context) error {
 
 func (e goEnv) modVersion(_ context.Context, mod string) (string, error) {
 	// can't use runGo because we need the output
-	cmd := exec.Command("go", "list", "-f", "{{.Version}}", "-m", mod)
-	cmd.Env = e.env
-	cmd.Dir = e.workDir
+	cmd := e.command("list", "-f", "{{.Version}}", "-m", mod)
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return "", fmt.Errorf("list module %s", err.Error())
@@ -296,6 +602,195 @@ func (e goEnv) modVersion(_ context.Context, mod string) (string, error) {
 	return strings.Trim(string(out), "\n"), nil
 }
 
+// modQuery resolves a branch or commit of a module to its version (usually a pseudo-version)
+func (e goEnv) modQuery(ctx context.Context, mod string, query string) (string, error) {
+	out, err := e.runGoOutput(ctx, e.getTimeout, "list", "-f", "{{.Version}}", "-m", mod+"@"+query)
+	if err != nil {
+		if ctx.Err() != nil || errors.Is(err, ErrBuildTimeout) {
+			return "", err
+		}
+
+		return "", fmt.Errorf("%w: %w: %s@%s %w", ErrResolvingDependency, ErrUnknownRevision, mod, query, err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
+
+// modVersions returns the versions of a module published in the module proxy
+func (e goEnv) modVersions(ctx context.Context, mod string) ([]string, error) {
+	out, err := e.runGoOutput(ctx, e.getTimeout, "list", "-m", "-versions", "-f", "{{range .Versions}}{{.}} {{end}}", mod)
+	if err != nil {
+		if ctx.Err() != nil || errors.Is(err, ErrBuildTimeout) {
+			return nil, err
+		}
+
+		return nil, fmt.Errorf("%w: listing versions of %s: %w", ErrResolvingDependency, mod, err)
+	}
+
+	return strings.Fields(string(out)), nil
+}
+
+// modGoVersion returns the go version required by the go directive of the module (path@version).
+// Returns an empty string if the module has no go directive
+func (e goEnv) modGoVersion(ctx context.Context, mod string) (string, error) {
+	// the download fails if the module requires a newer toolchain, but the go.mod is downloaded anyway
+	out, downloadErr := e.runGoOutput(ctx, e.getTimeout, "mod", "download", "-json", mod)
+
+	download := struct{ GoMod string }{}
+	if err := json.Unmarshal(out, &download); err != nil || download.GoMod == "" {
+		return "", fmt.Errorf("%w: downloading go.mod of %s: %w", ErrResolvingDependency, mod, errors.Join(downloadErr, err))
+	}
+
+	out, err := e.command("mod", "edit", "-json", download.GoMod).Output()
+	if err != nil {
+		return "", fmt.Errorf("%w: reading go.mod of %s: %w", ErrExecutingGoCommand, mod, err)
+	}
+
+	goMod := struct{ Go string }{}
+	if err = json.Unmarshal(out, &goMod); err != nil {
+		return "", fmt.Errorf("%w: reading go.mod of %s: %w", ErrExecutingGoCommand, mod, err)
+	}
+
+	return goMod.Go, nil
+}
+
+// toolchain returns the version of the go toolchain and if go can switch to a newer toolchain when required
+func (e goEnv) toolchain(_ context.Context) (string, bool, error) {
+	// can't use runGo because we need the output
+	out, err := e.command("env", "GOVERSION", "GOTOOLCHAIN").Output()
+	if err != nil {
+		return "", false, fmt.Errorf("%w: getting toolchain version %w", ErrExecutingGoCommand, err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 2 {
+		return "", false, fmt.Errorf("%w: unexpected go env output %q", ErrExecutingGoCommand, out)
+	}
+
+	mode := strings.TrimSpace(lines[1])
+	switchable := mode == "auto" || mode == "path" || strings.HasSuffix(mode, "+auto") || strings.HasSuffix(mode, "+path")
+
+	return strings.TrimSpace(lines[0]), switchable, nil
+}
+
+// hostGoCommand returns a goCommand that executes the go toolchain installed in the host
+func hostGoCommand(goBin string, workDir string, env []string) goCommand {
+	return func(args ...string) *exec.Cmd {
+		cmd := exec.Command(goBin, args...) //nolint:gosec
+		cmd.Env = env
+		cmd.Dir = workDir
+
+		return cmd
+	}
+}
+
+// modGraph returns the requirements of each module in the build as pairs of module@version strings
+func (e goEnv) modGraph(_ context.Context) ([][2]string, error) {
+	// can't use runGo because we need the output
+	out, err := e.command("mod", "graph").Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: listing module graph %w", ErrExecutingGoCommand, err)
+	}
+
+	edges := [][2]string{}
+	for _, line := range strings.Split(string(out), "\n") {
+		from, to, found := strings.Cut(strings.TrimSpace(line), " ")
+		if !found {
+			continue
+		}
+		edges = append(edges, [2]string{from, to})
+	}
+
+	return edges, nil
+}
+
+// distList returns the platforms supported by the go toolchain
+func (e goEnv) distList(_ context.Context) ([]Platform, error) {
+	// can't use runGo because we need the output
+	out, err := e.command("tool", "dist", "list").Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: listing platforms %w", ErrExecutingGoCommand, err)
+	}
+
+	platforms := []Platform{}
+	for _, line := range strings.Fields(string(out)) {
+		platform, err := ParsePlatform(line)
+		if err != nil {
+			continue
+		}
+		platforms = append(platforms, platform)
+	}
+
+	return platforms, nil
+}
+
+// checkPlatforms verifies the go toolchain can build for the platforms
+func (e goEnv) checkPlatforms(ctx context.Context, platforms ...Platform) error {
+	// the platforms supported by k6 are known to be supported by the toolchain
+	unknown := []Platform{}
+	for _, platform := range platforms {
+		if !platform.Supported() {
+			unknown = append(unknown, platform)
+		}
+	}
+
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	valid, err := e.distList(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, platform := range unknown {
+		// the toolchain lists the platforms without variants
+		if !slices.Contains(valid, NewPlatform(platform.OS, platform.Arch)) {
+			return fmt.Errorf("%w: %s", ErrUnsupportedPlatform, platform)
+		}
+	}
+
+	return nil
+}
+
+// modRequires returns the versions of the modules required in the go.mod of the work directory
+func (e goEnv) modRequires() (map[string]string, error) {
+	goMod := filepath.Join(e.workDir, "go.mod")
+
+	content, err := os.ReadFile(goMod) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("reading go.mod %w", err)
+	}
+
+	modFile, err := modfile.ParseLax(goMod, content, nil)
+	if err != nil {
+		return nil, fmt.Errorf("parsing go.mod %w", err)
+	}
+
+	requires := map[string]string{}
+	for _, r := range modFile.Require {
+		requires[r.Mod.Path] = r.Mod.Version
+	}
+
+	return requires, nil
+}
+
+// setToolchainEnv forces the go toolchain version using GOTOOLCHAIN
+func setToolchainEnv(env map[string]string, goVersion string) error {
+	if goVersion == "" {
+		return nil
+	}
+
+	toolchain := "go" + strings.TrimPrefix(goVersion, "go")
+	if !goToolchainRegexp.MatchString(toolchain) {
+		return fmt.Errorf("%w: %q", ErrInvalidGoVersion, goVersion)
+	}
+
+	env["GOTOOLCHAIN"] = toolchain
+
+	return nil
+}
+
 func mapToSlice(m map[string]string) []string {
 	s := []string{}
 	for k, v := range m {
@@ -305,13 +800,8 @@ func mapToSlice(m map[string]string) []string {
 	return s
 }
 
-func goVersion() (string, bool) {
-	cmd, err := exec.LookPath("go")
-	if err != nil {
-		return "", false
-	}
-
-	out, err := exec.Command(cmd, "version").Output() //nolint:gosec
+func goVersion(goBin string) (string, bool) {
+	out, err := exec.Command(goBin, "version").Output() //nolint:gosec
 	if err != nil {
 		return "", false
 	}
@@ -328,13 +818,8 @@ func goVersion() (string, bool) {
 	return ver, true
 }
 
-func getGoEnv() (map[string]string, error) {
-	cmd, err := exec.LookPath("go")
-	if err != nil {
-		return nil, fmt.Errorf("getting go binary %w", err)
-	}
-
-	out, err := exec.Command(cmd, "env", "-json").Output() //nolint:gosec
+func getGoEnv(goBin string) (map[string]string, error) {
+	out, err := exec.Command(goBin, "env", "-json").Output() //nolint:gosec
 	if err != nil {
 		return nil, fmt.Errorf("getting go env %w", err)
 	}
@@ -0,0 +1,112 @@
+package k6foundry
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrModuleNotFound is returned when a module doesn't exist or can't be accessed
+	ErrModuleNotFound = errors.New("module not found")
+	// ErrVersionNotFound is returned when the requested version of a module doesn't exist
+	ErrVersionNotFound = errors.New("version not found")
+	// ErrChecksumMismatch is returned when a downloaded module doesn't match its expected checksum
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrBuildConstraints is returned when the build constraints exclude all the files of a package
+	// (e.g. a package that doesn't support the target platform)
+	ErrBuildConstraints = errors.New("build constraints exclude all files")
+)
+
+// goErrors maps the messages of the go toolchain to the errors they report. The first match is used,
+// so more specific messages go first
+var goErrors = []struct { //nolint:gochecknoglobals
+	pattern *regexp.Regexp
+	err     error
+}{
+	{regexp.MustCompile(`"git": executable file not found`), ErrNoGit},
+	{regexp.MustCompile(`checksum mismatch|SECURITY ERROR`), ErrChecksumMismatch},
+	{regexp.MustCompile(`build constraints exclude all Go files`), ErrBuildConstraints},
+	{regexp.MustCompile(`unknown revision|invalid version|no matching versions|@v/[^/]+\.(info|mod|zip): (404|410)`), ErrVersionNotFound},
+	{regexp.MustCompile(`@v/list: (404|410)|cannot find module providing package|[Rr]epository not found|module lookup disabled`), ErrModuleNotFound},
+}
+
+// CompileError is returned when a package fails to compile
+type CompileError struct {
+	// module of the package, such as the extension that failed to compile. Empty if unknown
+	Module string
+	// package that failed to compile
+	Package string
+	// errors reported by the compiler, in the format file:line:column: message
+	Errors []string
+}
+
+func (e *CompileError) Error() string {
+	source := e.Module
+	if source == "" {
+		source = e.Package
+	}
+
+	return fmt.Sprintf("compile error in %s: %s", source, strings.Join(e.Errors, "; "))
+}
+
+// goError returns the error reported in the output of a failed go command, or nil if it is not recognized
+func goError(output string) error {
+	if compileErr := parseCompileError(output); compileErr != nil {
+		return compileErr
+	}
+
+	if missingErr := missingModulesError(output); missingErr != nil {
+		return missingErr
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		for _, e := range goErrors {
+			if e.pattern.MatchString(line) {
+				return fmt.Errorf("%w: %s", e.err, strings.TrimPrefix(strings.TrimSpace(line), "go: "))
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseCompileError returns the errors of the first package that failed to compile, or nil if there are none
+func parseCompileError(output string) *CompileError {
+	var compileErr *CompileError
+
+	for _, line := range strings.Split(output, "\n") {
+		if pkg, found := strings.CutPrefix(line, "# "); found {
+			// only the first package is reported
+			if compileErr != nil {
+				break
+			}
+			compileErr = &CompileError{Package: strings.TrimSpace(pkg)}
+
+			continue
+		}
+
+		if compileErr != nil && compileErrorRegexp.MatchString(line) {
+			compileErr.Errors = append(compileErr.Errors, line)
+		}
+	}
+
+	if compileErr == nil || len(compileErr.Errors) == 0 {
+		return nil
+	}
+
+	return compileErr
+}
+
+// packageModule returns the module that contains the package, from a list of modules
+func packageModule(pkg string, modules []string) string {
+	found := ""
+	for _, mod := range modules {
+		if (pkg == mod || strings.HasPrefix(pkg, mod+"/")) && len(mod) > len(found) {
+			found = mod
+		}
+	}
+
+	return found
+}
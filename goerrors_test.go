@@ -0,0 +1,174 @@
+package k6foundry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestGoError(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		output      string
+		expectError error
+	}{
+		{
+			title: "module not found",
+			output: "go: finding module for package github.com/grafana/xk6-missing\n" +
+				"go: github.com/grafana/xk6-missing: reading https://proxy.golang.org/github.com/grafana/xk6-missing/@v/list: 404 Not Found\n",
+			expectError: ErrModuleNotFound,
+		},
+		{
+			title:       "package not provided",
+			output:      "main.go:4:2: cannot find module providing package github.com/grafana/xk6-missing\n",
+			expectError: ErrModuleNotFound,
+		},
+		{
+			title:       "version not found",
+			output:      "go: go.k6.io/k6ext@v0.2.0: reading http://127.0.0.1/go.k6.io/k6ext/@v/v0.2.0.info: 404 Not Found\n",
+			expectError: ErrVersionNotFound,
+		},
+		{
+			title:       "unknown revision",
+			output:      "go: github.com/grafana/xk6-sql@v9.9.9: invalid version: unknown revision v9.9.9\n",
+			expectError: ErrVersionNotFound,
+		},
+		{
+			title: "checksum mismatch",
+			output: "verifying go.k6.io/k6@v0.1.0: checksum mismatch\n" +
+				"\tdownloaded: h1:abc=\n\tgo.sum:     h1:def=\n\nSECURITY ERROR\n",
+			expectError: ErrChecksumMismatch,
+		},
+		{
+			title:       "build constraints",
+			output:      "package go.k6.io/k6ext: build constraints exclude all Go files in /tmp/k6ext\n",
+			expectError: ErrBuildConstraints,
+		},
+		{
+			title: "git not found",
+			output: "go: github.com/grafana/xk6-sql@main: invalid version: git ls-remote -q origin in /tmp/vcs: " +
+				"exec: \"git\": executable file not found in $PATH\n",
+			expectError: ErrNoGit,
+		},
+		{
+			title:       "unknown error",
+			output:      "go: something unexpected\n",
+			expectError: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			err := goError(tc.output)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestParseCompileError(t *testing.T) {
+	t.Parallel()
+
+	output := "# go.k6.io/k6ext/internal\n" +
+		"../k6ext/internal/ext.go:3:17: syntax error: unexpected EOF\n" +
+		"../k6ext/internal/ext.go:5:2: undefined: foo\n" +
+		"# go.k6.io/k6ext2\n" +
+		"../k6ext2/ext.go:1:1: expected 'package'\n"
+
+	compileErr := parseCompileError(output)
+	if compileErr == nil {
+		t.Fatalf("expected compile error")
+	}
+
+	expect := &CompileError{
+		Package: "go.k6.io/k6ext/internal",
+		Errors: []string{
+			"../k6ext/internal/ext.go:3:17: syntax error: unexpected EOF",
+			"../k6ext/internal/ext.go:5:2: undefined: foo",
+		},
+	}
+	if !reflect.DeepEqual(compileErr, expect) {
+		t.Fatalf("expected %v got %v", expect, compileErr)
+	}
+
+	if mod := packageModule(compileErr.Package, []string{"go.k6.io/k6", "go.k6.io/k6ext", "go.k6.io/k6ext2"}); mod != "go.k6.io/k6ext" {
+		t.Fatalf("expected go.k6.io/k6ext got %q", mod)
+	}
+}
+
+func TestExtensionCompileError(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	if err := proxy.AddModVersion("go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	broken := t.TempDir()
+	for name, content := range map[string]string{
+		"go.mod":   "module go.k6.io/k6ext\n\ngo 1.17\n",
+		"k6ext.go": "package k6ext\n\nfunc broken() {\n",
+	} {
+		if err := os.WriteFile(filepath.Join(broken, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	b, err := NewNativeBuilder(context.Background(), NativeBuilderOpts{
+		GoOpts: GoOpts{
+			CopyGoEnv: true,
+			Env: map[string]string{
+				"GOPROXY":   goproxySrv.URL,
+				"GONOPROXY": "none",
+				"GOPRIVATE": "go.k6.io",
+				"GONOSUMDB": "go.k6.io",
+			},
+			TmpCache: true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("setting up test %v", err)
+	}
+
+	mods := []Module{{Path: "go.k6.io/k6ext", ReplacePath: broken}}
+	_, err = b.Build(context.Background(), RuntimePlatform(), "v0.1.0", mods, []string{}, &bytes.Buffer{})
+	if !errors.Is(err, ErrCompiling) {
+		t.Fatalf("expected %v got %v", ErrCompiling, err)
+	}
+
+	var compileErr *CompileError
+	if !errors.As(err, &compileErr) {
+		t.Fatalf("expected compile error got %v", err)
+	}
+
+	if compileErr.Module != "go.k6.io/k6ext" || len(compileErr.Errors) == 0 {
+		t.Fatalf("unexpected compile error %#v", compileErr)
+	}
+
+	var buildErr *BuildError
+	if !errors.As(err, &buildErr) {
+		t.Fatalf("expected build error got %v", err)
+	}
+
+	if buildErr.Phase != PhaseCompile || buildErr.Module != "go.k6.io/k6ext" || buildErr.Class != "compile" ||
+		!strings.Contains(buildErr.Output, "k6ext.go") || buildErr.Suggestion == "" {
+		t.Fatalf("unexpected build error %#v", buildErr)
+	}
+}
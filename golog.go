@@ -0,0 +1,131 @@
+package k6foundry
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// compileErrorRegexp matches the errors reported by the compiler (file:line:column: message)
+var compileErrorRegexp = regexp.MustCompile(`^(\S+\.go):(\d+):(\d+): (.*)$`) //nolint:gochecknoglobals
+
+// goErrorRegexp matches the messages of go commands reporting errors
+var goErrorRegexp = regexp.MustCompile( //nolint:gochecknoglobals
+	`(?i)error|invalid|cannot|unknown|not found|missing|no matching|denied|failed|mismatch|\b(40[0-9]|410|5[0-9]{2})\b`,
+)
+
+// goLogWriter logs each line written by a go command using the logger
+type goLogWriter struct {
+	log    *slog.Logger
+	stream string
+	mu     sync.Mutex
+	buf    []byte
+	// level of the last line, used for the indented lines that continue it
+	last slog.Level
+}
+
+// newGoLogWriter returns a writer that logs the output of go commands written to the stream (stdout or stderr)
+func newGoLogWriter(log *slog.Logger, stream string) *goLogWriter {
+	return &goLogWriter{log: log, stream: stream}
+}
+
+// Write logs the complete lines and keeps the last partial line until it is completed
+func (w *goLogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexByte(w.buf, '\n')
+		if idx < 0 {
+			break
+		}
+
+		w.logLine(string(w.buf[:idx]))
+		w.buf = w.buf[idx+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush logs the last partial line. The last line written by a go command may not end with a newline,
+// so it is flushed when the command exits
+func (w *goLogWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.logLine(string(w.buf))
+		w.buf = nil
+	}
+}
+
+// flushOutput flushes the writers that keep partial lines, such as a goLogWriter
+func flushOutput(writers ...io.Writer) {
+	for _, w := range writers {
+		if f, ok := w.(interface{ Flush() }); ok {
+			f.Flush()
+		}
+	}
+}
+
+func (w *goLogWriter) logLine(line string) {
+	line = strings.TrimRight(line, "\r")
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+
+	level, msg, attrs := parseGoOutput(line)
+	if strings.HasPrefix(line, "\t") || strings.HasPrefix(line, " ") {
+		level, msg = w.last, strings.TrimSpace(line)
+	}
+	w.last = level
+	attrs = append(attrs, "stream", w.stream)
+
+	w.log.Log(context.Background(), level, msg, attrs...)
+}
+
+// parseGoOutput returns the level, message and attributes for logging a line of output of a go command.
+// Progress is logged at debug level, changes to the requirements at info level and errors at error level
+func parseGoOutput(line string) (slog.Level, string, []any) {
+	if match := compileErrorRegexp.FindStringSubmatch(line); match != nil {
+		return slog.LevelError, "compile error", []any{
+			"file", match[1], "line", match[2], "column", match[3], "error", match[4],
+		}
+	}
+
+	// compile errors are preceded by the package
+	if pkg, found := strings.CutPrefix(line, "# "); found {
+		return slog.LevelError, "compiling package failed", []any{"package", pkg}
+	}
+
+	msg, found := strings.CutPrefix(line, "go: ")
+	if !found {
+		return slog.LevelInfo, line, []any{}
+	}
+
+	fields := strings.Fields(msg)
+	switch {
+	case len(fields) == 3 && fields[0] == "downloading":
+		return slog.LevelDebug, "downloading module", []any{"module", fields[1], "version", fields[2]}
+	case len(fields) >= 5 && fields[0] == "finding" && fields[1] == "module":
+		return slog.LevelDebug, "finding module", []any{"package", fields[len(fields)-1]}
+	case len(fields) >= 5 && fields[0] == "found":
+		return slog.LevelDebug, "found module", []any{"package", fields[1], "module", fields[3], "version", fields[4]}
+	case len(fields) >= 3 && slices.Contains([]string{"added", "upgraded", "downgraded"}, fields[0]):
+		return slog.LevelInfo, fields[0] + " module", []any{"module", fields[1], "version", fields[len(fields)-1]}
+	case len(fields) >= 3 && fields[0] == "removed":
+		return slog.LevelInfo, "removed module", []any{"module", fields[1], "version", fields[2]}
+	case strings.HasPrefix(msg, "warning: "):
+		return slog.LevelWarn, strings.TrimPrefix(msg, "warning: "), []any{}
+	case goErrorRegexp.MatchString(msg):
+		return slog.LevelError, msg, []any{}
+	default:
+		return slog.LevelInfo, msg, []any{}
+	}
+}
@@ -0,0 +1,208 @@
+package k6foundry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestParseGoOutput(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		line        string
+		expectLevel slog.Level
+		expectMsg   string
+		expectAttrs []any
+	}{
+		{
+			line:        "go: downloading go.k6.io/k6 v0.1.0",
+			expectLevel: slog.LevelDebug,
+			expectMsg:   "downloading module",
+			expectAttrs: []any{"module", "go.k6.io/k6", "version", "v0.1.0"},
+		},
+		{
+			line:        "go: finding module for package go.k6.io/k6ext",
+			expectLevel: slog.LevelDebug,
+			expectMsg:   "finding module",
+			expectAttrs: []any{"package", "go.k6.io/k6ext"},
+		},
+		{
+			line:        "go: found go.k6.io/k6ext in go.k6.io/k6ext v0.1.0",
+			expectLevel: slog.LevelDebug,
+			expectMsg:   "found module",
+			expectAttrs: []any{"package", "go.k6.io/k6ext", "module", "go.k6.io/k6ext", "version", "v0.1.0"},
+		},
+		{
+			line:        "go: upgraded go.k6.io/k6 v0.1.0 => v0.2.0",
+			expectLevel: slog.LevelInfo,
+			expectMsg:   "upgraded module",
+			expectAttrs: []any{"module", "go.k6.io/k6", "version", "v0.2.0"},
+		},
+		{
+			line:        "go: warning: ignoring go.mod in $GOPATH",
+			expectLevel: slog.LevelWarn,
+			expectMsg:   "ignoring go.mod in $GOPATH",
+			expectAttrs: []any{},
+		},
+		{
+			line:        "go: go.k6.io/k6ext@v0.2.0: invalid version: unknown revision v0.2.0",
+			expectLevel: slog.LevelError,
+			expectMsg:   "go.k6.io/k6ext@v0.2.0: invalid version: unknown revision v0.2.0",
+			expectAttrs: []any{},
+		},
+		{
+			line:        "go: creating new go.mod: module k6",
+			expectLevel: slog.LevelInfo,
+			expectMsg:   "creating new go.mod: module k6",
+			expectAttrs: []any{},
+		},
+		{
+			line:        "# go.k6.io/k6ext",
+			expectLevel: slog.LevelError,
+			expectMsg:   "compiling package failed",
+			expectAttrs: []any{"package", "go.k6.io/k6ext"},
+		},
+		{
+			line:        "k6ext.go:3:17: syntax error: unexpected EOF",
+			expectLevel: slog.LevelError,
+			expectMsg:   "compile error",
+			expectAttrs: []any{"file", "k6ext.go", "line", "3", "column", "17", "error", "syntax error: unexpected EOF"},
+		},
+		{
+			line:        "go.k6.io/k6/cmd",
+			expectLevel: slog.LevelInfo,
+			expectMsg:   "go.k6.io/k6/cmd",
+			expectAttrs: []any{},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.line, func(t *testing.T) {
+			t.Parallel()
+
+			level, msg, attrs := parseGoOutput(tc.line)
+			if level != tc.expectLevel || msg != tc.expectMsg || !reflect.DeepEqual(attrs, tc.expectAttrs) {
+				t.Fatalf("expected %v %q %v got %v %q %v", tc.expectLevel, tc.expectMsg, tc.expectAttrs, level, msg, attrs)
+			}
+		})
+	}
+}
+
+func TestGoLogWriter(t *testing.T) {
+	t.Parallel()
+
+	out := &bytes.Buffer{}
+	log := slog.New(slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	w := newGoLogWriter(log, "stderr")
+
+	// lines split across writes, continuation lines and a last line without newline
+	chunks := []string{
+		"go: downloading go.k6", ".io/k6 v0.1.0\ngo: module go.k6.io/k6ext: 404",
+		"\n\tserver response: not found\n\n", "go: build failed",
+	}
+	for _, chunk := range chunks {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+
+	if strings.Contains(out.String(), "build failed") {
+		t.Fatal("partial line logged before flush")
+	}
+
+	w.Flush()
+
+	records := []map[string]any{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		record := map[string]any{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		records = append(records, record)
+	}
+
+	expect := []struct{ level, msg string }{
+		{"DEBUG", "downloading module"},
+		{"ERROR", "module go.k6.io/k6ext: 404"},
+		{"ERROR", "server response: not found"},
+		{"ERROR", "build failed"},
+	}
+
+	if len(records) != len(expect) {
+		t.Fatalf("expected %d records got %v", len(expect), records)
+	}
+
+	for i, e := range expect {
+		if records[i]["level"] != e.level || records[i]["msg"] != e.msg || records[i]["stream"] != "stderr" {
+			t.Fatalf("expected %v got %v", e, records[i])
+		}
+	}
+
+	if records[0]["module"] != "go.k6.io/k6" || records[0]["version"] != "v0.1.0" {
+		t.Fatalf("missing attributes %v", records[0])
+	}
+}
+
+func TestLogGoOutput(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	if err := proxy.AddModVersion("go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	broken := t.TempDir()
+	for name, content := range map[string]string{
+		"go.mod":   "module go.k6.io/k6ext\n\ngo 1.17\n",
+		"k6ext.go": "package k6ext\n\nfunc broken() {\n",
+	} {
+		if err := os.WriteFile(filepath.Join(broken, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	out := &bytes.Buffer{}
+	b, err := NewNativeBuilder(context.Background(), NativeBuilderOpts{
+		GoOpts: GoOpts{
+			CopyGoEnv: true,
+			Env: map[string]string{
+				"GOPROXY":   goproxySrv.URL,
+				"GONOPROXY": "none",
+				"GOPRIVATE": "go.k6.io",
+				"GONOSUMDB": "go.k6.io",
+			},
+			TmpCache: true,
+		},
+		Logger:      slog.New(slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		LogGoOutput: true,
+	})
+	if err != nil {
+		t.Fatalf("setting up test %v", err)
+	}
+
+	mods := []Module{{Path: "go.k6.io/k6ext", ReplacePath: broken}}
+	_, err = b.Build(context.Background(), RuntimePlatform(), "v0.1.0", mods, []string{}, &bytes.Buffer{})
+	if err == nil {
+		t.Fatalf("expected compile error")
+	}
+
+	for _, msg := range []string{`"msg":"downloading module"`, `"msg":"compile error"`} {
+		if !strings.Contains(out.String(), msg) {
+			t.Fatalf("expected %s in logs:\n%s", msg, out.String())
+		}
+	}
+}
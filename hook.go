@@ -0,0 +1,288 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+)
+
+// DefaultTimestampURL is the RFC 3161 timestamp server used by the signtool hook if none is given
+const DefaultTimestampURL = "http://timestamp.digicert.com"
+
+var (
+	// ErrPostBuildHook is returned when a post-build hook fails
+	ErrPostBuildHook = errors.New("post-build hook")
+	// ErrNoSigningTool is returned when the tool used by a signing hook is not installed
+	ErrNoSigningTool = errors.New("signing tool not found")
+	// ErrInvalidHook is returned when the options of a hook are not valid
+	ErrInvalidHook = errors.New("invalid hook")
+)
+
+// Hook processes a binary after it is compiled (and compressed) and before it is written to the output, for
+// example for code signing it. Hooks run in the order they are given and can modify the binary in place,
+// so the checksum and size in the BuildInfo correspond to the processed binary
+type Hook interface {
+	// Name identifies the hook in the logs and errors
+	Name() string
+	// Run processes the binary built for the platform. Hooks that don't apply to the platform do nothing
+	Run(ctx context.Context, binary string, platform Platform) error
+}
+
+// hookFunc is a Hook implemented by a function
+type hookFunc struct {
+	name string
+	f    func(ctx context.Context, binary string, platform Platform) error
+}
+
+// NewHook returns a Hook that calls the function
+func NewHook(name string, f func(ctx context.Context, binary string, platform Platform) error) Hook {
+	return &hookFunc{name: name, f: f}
+}
+
+func (h *hookFunc) Name() string {
+	return h.name
+}
+
+func (h *hookFunc) Run(ctx context.Context, binary string, platform Platform) error {
+	return h.f(ctx, binary, platform)
+}
+
+// commandHook runs a command with the path of the binary as its last argument
+type commandHook struct {
+	command []string
+	oses    []string
+}
+
+// NewCommandHook returns a Hook that runs the command with the path of the binary as its last argument, and
+// the target platform in the K6FOUNDRY_PLATFORM environment variable. If OSs are given, the command only runs
+// for binaries built for them (e.g. darwin)
+func NewCommandHook(command []string, oses ...string) (Hook, error) {
+	if len(command) == 0 || command[0] == "" {
+		return nil, fmt.Errorf("%w: missing command", ErrInvalidHook)
+	}
+
+	if _, err := exec.LookPath(command[0]); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidHook, err)
+	}
+
+	return &commandHook{command: command, oses: oses}, nil
+}
+
+func (h *commandHook) Name() string {
+	return h.command[0]
+}
+
+func (h *commandHook) Run(ctx context.Context, binary string, platform Platform) error {
+	if len(h.oses) > 0 && !slices.Contains(h.oses, platform.OS) {
+		return nil
+	}
+
+	args := append(append([]string{}, h.command[1:]...), binary)
+	env := append(os.Environ(), "K6FOUNDRY_PLATFORM="+platform.String())
+
+	return runHookCommand(ctx, env, h.command[0], args...)
+}
+
+// CodesignOpts defines the options for signing and notarizing darwin binaries
+type CodesignOpts struct {
+	// signing identity, as accepted by codesign --sign (e.g. "Developer ID Application: My Org (TEAMID)")
+	Identity string
+	// optional entitlements file
+	Entitlements string
+	// keychain profile with the credentials for notarizing the binary with notarytool
+	// (see xcrun notarytool store-credentials). The binary is not notarized if empty
+	NotaryProfile string
+}
+
+// codesignHook signs darwin binaries with codesign and optionally notarizes them
+type codesignHook struct {
+	CodesignOpts
+	codesign string
+	xcrun    string
+	ditto    string
+}
+
+// NewCodesignHook returns a Hook that signs the darwin binaries with codesign, using the hardened runtime and
+// a secure timestamp as required by Gatekeeper, and notarizes them if a NotaryProfile is given.
+// Requires the Xcode command line tools, so it can only be used in macOS
+func NewCodesignHook(opts CodesignOpts) (Hook, error) {
+	if opts.Identity == "" {
+		return nil, fmt.Errorf("%w: codesign requires an identity", ErrInvalidHook)
+	}
+
+	h := &codesignHook{CodesignOpts: opts}
+
+	var err error
+	if h.codesign, err = lookSigningTool("codesign"); err != nil {
+		return nil, err
+	}
+
+	if opts.NotaryProfile == "" {
+		return h, nil
+	}
+
+	if h.xcrun, err = lookSigningTool("xcrun"); err != nil {
+		return nil, err
+	}
+
+	if h.ditto, err = lookSigningTool("ditto"); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+func (h *codesignHook) Name() string {
+	return "codesign"
+}
+
+func (h *codesignHook) Run(ctx context.Context, binary string, platform Platform) error {
+	if platform.OS != "darwin" {
+		return nil
+	}
+
+	if err := runHookCommand(ctx, nil, h.codesign, codesignArgs(h.CodesignOpts, binary)...); err != nil {
+		return err
+	}
+
+	if h.NotaryProfile == "" {
+		return nil
+	}
+
+	// notarytool only accepts archives. Plain binaries can't be stapled, so Gatekeeper gets the
+	// notarization ticket online
+	archive := binary + ".zip"
+	defer os.Remove(archive) //nolint:errcheck
+
+	if err := runHookCommand(ctx, nil, h.ditto, "-c", "-k", "--keepParent", binary, archive); err != nil {
+		return err
+	}
+
+	return runHookCommand(ctx, nil, h.xcrun, notarytoolArgs(h.CodesignOpts, archive)...)
+}
+
+// codesignArgs returns the arguments of codesign for signing the binary
+func codesignArgs(opts CodesignOpts, binary string) []string {
+	args := []string{"--sign", opts.Identity, "--force", "--timestamp", "--options", "runtime"}
+	if opts.Entitlements != "" {
+		args = append(args, "--entitlements", opts.Entitlements)
+	}
+
+	return append(args, binary)
+}
+
+// notarytoolArgs returns the arguments of xcrun for notarizing the archive, waiting for the result
+func notarytoolArgs(opts CodesignOpts, archive string) []string {
+	return []string{"notarytool", "submit", archive, "--keychain-profile", opts.NotaryProfile, "--wait"}
+}
+
+// SigntoolOpts defines the options for signing windows binaries with signtool
+type SigntoolOpts struct {
+	// PFX file with the certificate and private key
+	CertFile string
+	// password of the PFX file
+	Password string
+	// subject name of the certificate in the certificate store, used if no CertFile is given
+	// (e.g. for certificates in a hardware token)
+	CertSubject string
+	// RFC 3161 timestamp server. Defaults to DefaultTimestampURL
+	TimestampURL string
+	// description of the signed content shown by the UAC prompt
+	Description string
+}
+
+// signtoolHook signs windows binaries with signtool
+type signtoolHook struct {
+	SigntoolOpts
+	signtool string
+}
+
+// NewSigntoolHook returns a Hook that signs the windows binaries with signtool, using SHA256 digests and
+// a timestamp, as expected by SmartScreen. Requires the Windows SDK, so it can only be used in windows
+func NewSigntoolHook(opts SigntoolOpts) (Hook, error) {
+	if (opts.CertFile == "") == (opts.CertSubject == "") {
+		return nil, fmt.Errorf("%w: signtool requires either a certificate file or subject", ErrInvalidHook)
+	}
+
+	if opts.TimestampURL == "" {
+		opts.TimestampURL = DefaultTimestampURL
+	}
+
+	signtool, err := lookSigningTool("signtool")
+	if err != nil {
+		return nil, err
+	}
+
+	return &signtoolHook{SigntoolOpts: opts, signtool: signtool}, nil
+}
+
+func (h *signtoolHook) Name() string {
+	return "signtool"
+}
+
+func (h *signtoolHook) Run(ctx context.Context, binary string, platform Platform) error {
+	if platform.OS != "windows" {
+		return nil
+	}
+
+	return runHookCommand(ctx, nil, h.signtool, signtoolArgs(h.SigntoolOpts, binary)...)
+}
+
+// signtoolArgs returns the arguments of signtool for signing the binary
+func signtoolArgs(opts SigntoolOpts, binary string) []string {
+	args := []string{"sign", "/fd", "SHA256", "/tr", opts.TimestampURL, "/td", "SHA256"}
+	if opts.CertFile != "" {
+		args = append(args, "/f", opts.CertFile)
+		if opts.Password != "" {
+			args = append(args, "/p", opts.Password)
+		}
+	} else {
+		args = append(args, "/n", opts.CertSubject)
+	}
+
+	if opts.Description != "" {
+		args = append(args, "/d", opts.Description)
+	}
+
+	return append(args, binary)
+}
+
+// lookSigningTool returns the path of the tool, or ErrNoSigningTool if it is not installed
+func lookSigningTool(tool string) (string, error) {
+	path, err := exec.LookPath(tool)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrNoSigningTool, tool)
+	}
+
+	return path, nil
+}
+
+// runHookCommand runs the command, attaching its output to the error if it fails
+func runHookCommand(ctx context.Context, env []string, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...) //nolint:gosec
+	cmd.Env = env
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return &outputError{err: fmt.Errorf("%s: %w", name, err), output: string(output)}
+	}
+
+	return nil
+}
+
+// runHooks runs the post-build hooks on the binary built for the platform
+func (b *nativeBuilder) runHooks(ctx context.Context, binary string, platform Platform) error {
+	for _, h := range b.PostBuildHooks {
+		b.log.Info(fmt.Sprintf("Running post-build hook %s", h.Name()))
+
+		if err := h.Run(ctx, binary, platform); err != nil {
+			return fmt.Errorf("%w %s: %w", ErrPostBuildHook, h.Name(), err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,206 @@
+package k6foundry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestHookArgs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title  string
+		args   []string
+		expect []string
+	}{
+		{
+			title:  "codesign",
+			args:   codesignArgs(CodesignOpts{Identity: "Developer ID"}, "k6"),
+			expect: []string{"--sign", "Developer ID", "--force", "--timestamp", "--options", "runtime", "k6"},
+		},
+		{
+			title: "codesign with entitlements",
+			args:  codesignArgs(CodesignOpts{Identity: "Developer ID", Entitlements: "k6.plist"}, "k6"),
+			expect: []string{
+				"--sign", "Developer ID", "--force", "--timestamp", "--options", "runtime",
+				"--entitlements", "k6.plist", "k6",
+			},
+		},
+		{
+			title:  "notarytool",
+			args:   notarytoolArgs(CodesignOpts{NotaryProfile: "k6"}, "k6.zip"),
+			expect: []string{"notarytool", "submit", "k6.zip", "--keychain-profile", "k6", "--wait"},
+		},
+		{
+			title: "signtool with certificate file",
+			args:  signtoolArgs(SigntoolOpts{CertFile: "k6.pfx", Password: "secret", TimestampURL: "http://ts"}, "k6.exe"),
+			expect: []string{
+				"sign", "/fd", "SHA256", "/tr", "http://ts", "/td", "SHA256", "/f", "k6.pfx", "/p", "secret", "k6.exe",
+			},
+		},
+		{
+			title: "signtool with certificate subject",
+			args:  signtoolArgs(SigntoolOpts{CertSubject: "My Org", TimestampURL: "http://ts", Description: "k6"}, "k6.exe"),
+			expect: []string{
+				"sign", "/fd", "SHA256", "/tr", "http://ts", "/td", "SHA256", "/n", "My Org", "/d", "k6", "k6.exe",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			if !slices.Equal(tc.args, tc.expect) {
+				t.Fatalf("expected %v got %v", tc.expect, tc.args)
+			}
+		})
+	}
+}
+
+func TestNewHookValidation(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title string
+		hook  func() (Hook, error)
+	}{
+		{
+			title: "codesign without identity",
+			hook:  func() (Hook, error) { return NewCodesignHook(CodesignOpts{}) },
+		},
+		{
+			title: "signtool without certificate",
+			hook:  func() (Hook, error) { return NewSigntoolHook(SigntoolOpts{}) },
+		},
+		{
+			title: "signtool with certificate file and subject",
+			hook:  func() (Hook, error) { return NewSigntoolHook(SigntoolOpts{CertFile: "k6.pfx", CertSubject: "k6"}) },
+		},
+		{
+			title: "command without command",
+			hook:  func() (Hook, error) { return NewCommandHook(nil) },
+		},
+		{
+			title: "command not found",
+			hook:  func() (Hook, error) { return NewCommandHook([]string{"k6foundry-missing-tool"}) },
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := tc.hook(); !errors.Is(err, ErrInvalidHook) {
+				t.Fatalf("expected %v got %v", ErrInvalidHook, err)
+			}
+		})
+	}
+}
+
+func TestPostBuildHooks(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	for _, m := range []struct{ path, version, source string }{
+		{"go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")},
+	} {
+		if err := proxy.AddModVersion(m.path, m.version, m.source); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	goVersion, err := NewCommandHook([]string{"go", "version"})
+	if err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	// the hook appends a signature to the binary, so the output must include it
+	signature := []byte("signed")
+	sign := NewHook("sign", func(_ context.Context, binary string, _ Platform) error {
+		f, err := os.OpenFile(binary, os.O_APPEND|os.O_WRONLY, 0) //nolint:forbidigo
+		if err != nil {
+			return err
+		}
+		defer f.Close() //nolint:errcheck
+
+		_, err = f.Write(signature)
+		return err
+	})
+
+	failed := NewHook("failed", func(_ context.Context, _ string, _ Platform) error {
+		return errors.New("failed")
+	})
+
+	testCases := []struct {
+		title       string
+		hooks       []Hook
+		expectError error
+		expectClass string
+	}{
+		{
+			title: "hooks run in order",
+			hooks: []Hook{goVersion, sign},
+		},
+		{
+			title:       "failed hook",
+			hooks:       []Hook{sign, failed},
+			expectError: ErrPostBuildHook,
+			expectClass: "post_build_hook",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := NewNativeBuilder(context.Background(), NativeBuilderOpts{
+				GoOpts: GoOpts{
+					CopyGoEnv: true,
+					Env: map[string]string{
+						"GOPROXY":   goproxySrv.URL,
+						"GONOPROXY": "none",
+						"GOPRIVATE": "go.k6.io",
+						"GONOSUMDB": "go.k6.io",
+					},
+					TmpCache: true,
+				},
+				PostBuildHooks: tc.hooks,
+			})
+			if err != nil {
+				t.Fatalf("setting up test %v", err)
+			}
+
+			binary := &bytes.Buffer{}
+			_, err = b.Build(context.Background(), RuntimePlatform(), "v0.1.0", nil, nil, binary)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			if tc.expectError != nil {
+				if class := ErrorClass(err); class != tc.expectClass {
+					t.Fatalf("expected class %s got %s", tc.expectClass, class)
+				}
+				return
+			}
+
+			if !bytes.HasSuffix(binary.Bytes(), signature) {
+				t.Fatalf("expected binary processed by the hooks")
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package k6foundry
+
+import (
+	"debug/buildinfo"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrInvalidBinary is returned when the build information can't be read from the binary
+	ErrInvalidBinary = errors.New("invalid binary")
+	// ErrNotK6Binary is returned when the binary doesn't include k6
+	ErrNotK6Binary = errors.New("not a k6 binary")
+)
+
+// BinaryInfo describes the k6 version and the extensions included in a k6 binary
+type BinaryInfo struct {
+	GoVersion string `json:"goVersion"`
+	Platform  string `json:"platform,omitempty"`
+	K6Version string `json:"k6Version"`
+	// extension modules and their versions. Extensions are identified by the xk6 naming convention
+	// (e.g. github.com/grafana/xk6-kubernetes)
+	Extensions map[string]string `json:"extensions"`
+	// all the modules included in the binary, sorted by path
+	Dependencies []Dependency `json:"dependencies"`
+}
+
+// InspectBinary reads the build information embedded in a k6 binary
+func InspectBinary(path string) (*BinaryInfo, error) {
+	info, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBinary, err)
+	}
+
+	return binaryInfo(info)
+}
+
+func binaryInfo(info *debug.BuildInfo) (*BinaryInfo, error) {
+	binInfo := &BinaryInfo{
+		GoVersion:    info.GoVersion,
+		Extensions:   map[string]string{},
+		Dependencies: []Dependency{},
+	}
+
+	settings := map[string]string{}
+	for _, setting := range info.Settings {
+		settings[setting.Key] = setting.Value
+	}
+	if settings["GOOS"] != "" && settings["GOARCH"] != "" {
+		binInfo.Platform = settings["GOOS"] + "/" + settings["GOARCH"]
+	}
+
+	for _, dep := range info.Deps {
+		version, sum := dep.Version, dep.Sum
+		if dep.Replace != nil {
+			// replacements with local directories have no version
+			version, sum = dep.Replace.Version, dep.Replace.Sum
+			if version == "" {
+				version = dep.Replace.Path
+			}
+		}
+
+		binInfo.Dependencies = append(binInfo.Dependencies, Dependency{Path: dep.Path, Version: version, Sum: sum})
+
+		switch {
+		case dep.Path == defaultK6ModulePath:
+			binInfo.K6Version = version
+		case isExtension(dep.Path):
+			binInfo.Extensions[dep.Path] = version
+		}
+	}
+
+	if binInfo.K6Version == "" {
+		return nil, ErrNotK6Binary
+	}
+
+	sort.Slice(binInfo.Dependencies, func(i, j int) bool {
+		return binInfo.Dependencies[i].Path < binInfo.Dependencies[j].Path
+	})
+
+	return binInfo, nil
+}
+
+// isExtension returns true if the module path follows the naming convention of the k6 extensions:
+// an element of the path starting with xk6-
+func isExtension(path string) bool {
+	for _, elem := range strings.Split(path, "/") {
+		if strings.HasPrefix(elem, "xk6-") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,118 @@
+package k6foundry
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"runtime/debug"
+	"testing"
+)
+
+func TestBinaryInfo(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		info        *debug.BuildInfo
+		expect      *BinaryInfo
+		expectError error
+	}{
+		{
+			title: "k6 with extensions",
+			info: &debug.BuildInfo{
+				GoVersion: "go1.22.5",
+				Deps: []*debug.Module{
+					{Path: "go.k6.io/k6", Version: "v0.50.0", Sum: "h1:k6"},
+					{Path: "github.com/grafana/xk6-kubernetes", Version: "v0.10.0", Sum: "h1:kubernetes"},
+					{Path: "github.com/dop251/goja", Version: "v0.0.1", Sum: "h1:goja"},
+				},
+				Settings: []debug.BuildSetting{
+					{Key: "GOOS", Value: "linux"},
+					{Key: "GOARCH", Value: "amd64"},
+				},
+			},
+			expect: &BinaryInfo{
+				GoVersion:  "go1.22.5",
+				Platform:   "linux/amd64",
+				K6Version:  "v0.50.0",
+				Extensions: map[string]string{"github.com/grafana/xk6-kubernetes": "v0.10.0"},
+				Dependencies: []Dependency{
+					{Path: "github.com/dop251/goja", Version: "v0.0.1", Sum: "h1:goja"},
+					{Path: "github.com/grafana/xk6-kubernetes", Version: "v0.10.0", Sum: "h1:kubernetes"},
+					{Path: "go.k6.io/k6", Version: "v0.50.0", Sum: "h1:k6"},
+				},
+			},
+		},
+		{
+			title: "replaced modules",
+			info: &debug.BuildInfo{
+				GoVersion: "go1.22.5",
+				Deps: []*debug.Module{
+					{
+						Path:    "go.k6.io/k6",
+						Version: "v0.50.0",
+						Replace: &debug.Module{Path: "github.com/fork/k6", Version: "v0.50.1", Sum: "h1:fork"},
+					},
+					{
+						Path:    "github.com/grafana/xk6-sql",
+						Version: "v0.1.0",
+						Replace: &debug.Module{Path: "../xk6-sql"},
+					},
+				},
+			},
+			expect: &BinaryInfo{
+				GoVersion:  "go1.22.5",
+				K6Version:  "v0.50.1",
+				Extensions: map[string]string{"github.com/grafana/xk6-sql": "../xk6-sql"},
+				Dependencies: []Dependency{
+					{Path: "github.com/grafana/xk6-sql", Version: "../xk6-sql"},
+					{Path: "go.k6.io/k6", Version: "v0.50.1", Sum: "h1:fork"},
+				},
+			},
+		},
+		{
+			title: "not a k6 binary",
+			info: &debug.BuildInfo{
+				GoVersion: "go1.22.5",
+				Deps: []*debug.Module{
+					{Path: "github.com/spf13/cobra", Version: "v1.8.0"},
+				},
+			},
+			expectError: ErrNotK6Binary,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			info, err := binaryInfo(tc.info)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			if tc.expectError == nil && !reflect.DeepEqual(info, tc.expect) {
+				t.Fatalf("expected %v got %v", tc.expect, info)
+			}
+		})
+	}
+}
+
+func TestInspectBinary(t *testing.T) {
+	t.Parallel()
+
+	// the test binary is a go binary without k6
+	testBinary, err := os.Executable()
+	if err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	if _, err = InspectBinary(testBinary); !errors.Is(err, ErrNotK6Binary) {
+		t.Fatalf("expected %v got %v", ErrNotK6Binary, err)
+	}
+
+	if _, err = InspectBinary("inspect_test.go"); !errors.Is(err, ErrInvalidBinary) {
+		t.Fatalf("expected %v got %v", ErrInvalidBinary, err)
+	}
+}
@@ -0,0 +1,101 @@
+package k6foundry
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// K6VersionDetails is the variable used by k6 for the details shown in the version output.
+// Can be used as key in the BuildMetadata for stamping the binary.
+const K6VersionDetails = "go.k6.io/k6/lib/consts.VersionDetails"
+
+// ErrInvalidBuildMetadata is returned when the build metadata can't be set using the linker flags
+var ErrInvalidBuildMetadata = errors.New("invalid build metadata")
+
+// addLdFlags adds the linker flags to the build opts, merging them with any -ldflags already present
+func addLdFlags(buildOpts []string, flags string) []string {
+	merged := make([]string, 0, len(buildOpts)+1)
+	found := false
+
+	for i := 0; i < len(buildOpts); i++ {
+		opt := buildOpts[i]
+		name, value, hasValue := strings.Cut(opt, "=")
+		if name != "-ldflags" && name != "--ldflags" {
+			merged = append(merged, opt)
+			continue
+		}
+
+		found = true
+
+		// value passed as the next argument
+		if !hasValue {
+			merged = append(merged, opt)
+			if i+1 < len(buildOpts) {
+				i++
+				value = buildOpts[i]
+			}
+			merged = append(merged, strings.TrimSpace(flags+" "+value))
+			continue
+		}
+
+		merged = append(merged, name+"="+strings.TrimSpace(flags+" "+value))
+	}
+
+	if !found {
+		merged = append(merged, "-ldflags="+flags)
+	}
+
+	return merged
+}
+
+// metadataLdFlags returns the linker flags for setting the variables in the metadata, which maps the
+// fully qualified name of the variable (import path and name) to its value
+func metadataLdFlags(metadata map[string]string) (string, error) {
+	names := make([]string, 0, len(metadata))
+	for name := range metadata {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	flags := []string{}
+	for _, name := range names {
+		value := metadata[name]
+
+		dot := strings.LastIndex(name, ".")
+		if dot <= 0 || dot == len(name)-1 || strings.ContainsAny(name, " '\"=") {
+			return "", fmt.Errorf("%w: invalid variable name %q", ErrInvalidBuildMetadata, name)
+		}
+
+		// the linker flags are split by spaces, honoring single and double quotes without escaping
+		quote := "'"
+		if strings.Contains(value, quote) {
+			quote = `"`
+			if strings.Contains(value, quote) {
+				return "", fmt.Errorf("%w: value of %q contains both types of quotes", ErrInvalidBuildMetadata, name)
+			}
+		}
+
+		flags = append(flags, "-X "+quote+name+"="+value+quote)
+	}
+
+	return strings.Join(flags, " "), nil
+}
+
+// linkerOpts adds to the build opts the linker flags required by the builder options
+func (b *nativeBuilder) linkerOpts(buildOpts []string) ([]string, error) {
+	if len(b.BuildMetadata) > 0 {
+		metadata, err := metadataLdFlags(b.BuildMetadata)
+		if err != nil {
+			return nil, err
+		}
+		buildOpts = addLdFlags(buildOpts, metadata)
+	}
+
+	if b.StripDebugInfo {
+		buildOpts = addLdFlags(buildOpts, stripLdFlags)
+	}
+
+	return buildOpts, nil
+}
@@ -0,0 +1,108 @@
+package k6foundry
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestAddLdFlags(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		buildOpts []string
+		expect    []string
+	}{
+		{
+			title:     "no ldflags",
+			buildOpts: []string{"-trimpath"},
+			expect:    []string{"-trimpath", "-ldflags=-s -w"},
+		},
+		{
+			title:     "ldflags with value",
+			buildOpts: []string{"-ldflags=-X main.version=1.0"},
+			expect:    []string{"-ldflags=-s -w -X main.version=1.0"},
+		},
+		{
+			title:     "double dash ldflags",
+			buildOpts: []string{"--ldflags=-X main.version=1.0"},
+			expect:    []string{"--ldflags=-s -w -X main.version=1.0"},
+		},
+		{
+			title:     "ldflags value in next argument",
+			buildOpts: []string{"-ldflags", "-X main.version=1.0", "-trimpath"},
+			expect:    []string{"-ldflags", "-s -w -X main.version=1.0", "-trimpath"},
+		},
+		{
+			title:     "empty ldflags",
+			buildOpts: []string{"-ldflags="},
+			expect:    []string{"-ldflags=-s -w"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			merged := addLdFlags(tc.buildOpts, stripLdFlags)
+			if !reflect.DeepEqual(merged, tc.expect) {
+				t.Fatalf("expected %v got %v", tc.expect, merged)
+			}
+		})
+	}
+}
+
+func TestMetadataLdFlags(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		metadata    map[string]string
+		expect      string
+		expectError error
+	}{
+		{
+			title:    "sorted variables",
+			metadata: map[string]string{"main.version": "v1.0.0", "main.commit": "abc"},
+			expect:   "-X 'main.commit=abc' -X 'main.version=v1.0.0'",
+		},
+		{
+			title:    "value with single quote",
+			metadata: map[string]string{K6VersionDetails: "it's custom"},
+			expect:   `-X "go.k6.io/k6/lib/consts.VersionDetails=it's custom"`,
+		},
+		{
+			title:       "value with both quotes",
+			metadata:    map[string]string{"main.version": `it's "custom"`},
+			expectError: ErrInvalidBuildMetadata,
+		},
+		{
+			title:       "variable without package",
+			metadata:    map[string]string{"version": "v1.0.0"},
+			expectError: ErrInvalidBuildMetadata,
+		},
+		{
+			title:       "variable with spaces",
+			metadata:    map[string]string{"main.my version": "v1.0.0"},
+			expectError: ErrInvalidBuildMetadata,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			flags, err := metadataLdFlags(tc.metadata)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			if flags != tc.expect {
+				t.Fatalf("expected %q got %q", tc.expect, flags)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+)
+
+// ErrDeniedLicense is returned when a dependency has a license in DeniedLicenses
+var ErrDeniedLicense = errors.New("denied license")
+
+// spdxHeaderRegexp matches the SPDX identifier declared in a license file
+var spdxHeaderRegexp = regexp.MustCompile(`SPDX-License-Identifier:\s*([A-Za-z0-9.+\- ()]+)`) //nolint:gochecknoglobals
+
+// licensePatterns identifies licenses by phrases of their text. The first match is used, so licenses
+// whose text mentions others (e.g. LGPL mentions GPL) go first
+var licensePatterns = []struct { //nolint:gochecknoglobals
+	id      string
+	phrases []string
+}{
+	{"AGPL-3.0", []string{"GNU AFFERO GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"LGPL-3.0", []string{"GNU LESSER GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"LGPL-2.1", []string{"GNU LESSER GENERAL PUBLIC LICENSE", "Version 2.1"}},
+	{"GPL-3.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"GPL-2.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 2"}},
+	{"MPL-2.0", []string{"Mozilla Public License", "Version 2.0"}},
+	{"Apache-2.0", []string{"Apache License", "Version 2.0"}},
+	{"BSD-3-Clause", []string{"Redistribution and use in source and binary forms", "Neither the name"}},
+	{"BSD-2-Clause", []string{"Redistribution and use in source and binary forms"}},
+	{"MIT", []string{"Permission is hereby granted, free of charge"}},
+	{"ISC", []string{"Permission to use, copy, modify, and/or distribute this software"}},
+	{"Unlicense", []string{"This is free and unencumbered software released into the public domain"}},
+	{"CC0-1.0", []string{"CC0 1.0 Universal"}},
+}
+
+// isLicenseFile returns true if the file name is a conventional name for a license file
+func isLicenseFile(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, prefix := range []string{"LICENSE", "LICENCE", "COPYING"} {
+		if strings.HasPrefix(upper, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// detectLicense returns the SPDX identifiers of the licenses in the root of the module directory,
+// joined with AND if there are several. Returns an empty string if no license is recognized
+func detectLicense(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+
+	ids := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !isLicenseFile(entry.Name()) {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name())) //nolint:gosec
+		if err != nil {
+			continue
+		}
+
+		if id := classifyLicense(string(content)); id != "" && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Strings(ids)
+
+	return strings.Join(ids, " AND ")
+}
+
+// classifyLicense returns the SPDX identifier of a license text, or an empty string if not recognized
+func classifyLicense(text string) string {
+	if match := spdxHeaderRegexp.FindStringSubmatch(text); match != nil {
+		return strings.TrimSpace(match[1])
+	}
+
+	// line breaks and indentation vary between copies of the same license
+	text = strings.Join(strings.Fields(text), " ")
+
+	for _, license := range licensePatterns {
+		found := true
+		for _, phrase := range license.phrases {
+			if !strings.Contains(strings.ToLower(text), strings.ToLower(phrase)) {
+				found = false
+				break
+			}
+		}
+
+		if found {
+			return license.id
+		}
+	}
+
+	return ""
+}
+
+// addLicenses sets the license of the dependencies, reading the license files of the modules in the
+// module cache or, for local replacements, in their directories
+func addLicenses(workDir string, modCache string, deps []Dependency) error {
+	content, err := os.ReadFile(filepath.Join(workDir, "go.mod")) //nolint:gosec
+	if err != nil {
+		return fmt.Errorf("reading go.mod %w", err)
+	}
+
+	modFile, err := modfile.Parse("go.mod", content, nil)
+	if err != nil {
+		return fmt.Errorf("parsing go.mod %w", err)
+	}
+
+	replaces := map[string]module.Version{}
+	for _, r := range modFile.Replace {
+		replaces[r.Old.Path] = r.New
+	}
+
+	for i, dep := range deps {
+		mod := module.Version{Path: dep.Path, Version: dep.Version}
+		if replace, found := replaces[dep.Path]; found {
+			mod = replace
+		}
+
+		// local replacements don't have a version
+		dir := mod.Path
+		if mod.Version == "" {
+			if !filepath.IsAbs(dir) {
+				dir = filepath.Join(workDir, dir)
+			}
+		} else {
+			dir, err = moduleCacheDir(modCache, mod)
+			if err != nil {
+				return err
+			}
+		}
+
+		deps[i].License = detectLicense(dir)
+	}
+
+	return nil
+}
+
+// moduleCacheDir returns the directory of the sources of a module version in the module cache
+func moduleCacheDir(modCache string, mod module.Version) (string, error) {
+	escPath, err := module.EscapePath(mod.Path)
+	if err != nil {
+		return "", err
+	}
+
+	escVersion, err := module.EscapeVersion(mod.Version)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(modCache, filepath.FromSlash(escPath)+"@"+escVersion), nil
+}
+
+// checkLicenses returns an error listing the dependencies with a license in the denied list.
+// A denied license also matches its variants (e.g. GPL-3.0 matches GPL-3.0-only)
+func checkLicenses(deps []Dependency, denied []string) error {
+	if len(denied) == 0 {
+		return nil
+	}
+
+	found := []string{}
+	for _, dep := range deps {
+		for _, id := range strings.Split(dep.License, " AND ") {
+			if isDenied(id, denied) {
+				found = append(found, fmt.Sprintf("%s@%s (%s)", dep.Path, dep.Version, id))
+			}
+		}
+	}
+
+	if len(found) > 0 {
+		return fmt.Errorf("%w: %s", ErrDeniedLicense, strings.Join(found, ", "))
+	}
+
+	return nil
+}
+
+func isDenied(id string, denied []string) bool {
+	id = strings.ToLower(strings.TrimSpace(id))
+	if id == "" {
+		return false
+	}
+
+	for _, d := range denied {
+		d = strings.ToLower(strings.TrimSpace(d))
+		if id == d || strings.HasPrefix(id, d+"-") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,211 @@
+package k6foundry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+const (
+	mitLicense = "MIT License\n\nCopyright (c) 2024 Someone\n\nPermission is hereby granted, free of charge,\n" +
+		"to any person obtaining a copy of this software"
+	gplLicense = "                    GNU GENERAL PUBLIC LICENSE\n                       Version 3, 29 June 2007\n"
+)
+
+func TestClassifyLicense(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title  string
+		text   string
+		expect string
+	}{
+		{title: "MIT", text: mitLicense, expect: "MIT"},
+		{title: "GPL", text: gplLicense, expect: "GPL-3.0"},
+		{
+			title:  "LGPL",
+			text:   "GNU LESSER GENERAL PUBLIC LICENSE\nVersion 3, 29 June 2007\n... GNU General Public License",
+			expect: "LGPL-3.0",
+		},
+		{
+			title:  "Apache",
+			text:   "                                 Apache License\n                           Version 2.0, January 2004",
+			expect: "Apache-2.0",
+		},
+		{
+			title: "BSD-3-Clause",
+			text: "Redistribution and use in source and binary\nforms, with or without modification...\n" +
+				"Neither the name of the copyright holder",
+			expect: "BSD-3-Clause",
+		},
+		{title: "SPDX header", text: "SPDX-License-Identifier: MPL-2.0\n", expect: "MPL-2.0"},
+		{title: "unknown", text: "All rights reserved", expect: ""},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			if id := classifyLicense(tc.text); id != tc.expect {
+				t.Fatalf("expected %q got %q", tc.expect, id)
+			}
+		})
+	}
+}
+
+func TestCheckLicenses(t *testing.T) {
+	t.Parallel()
+
+	deps := []Dependency{
+		{Path: "example.com/a", Version: "v1.0.0", License: "MIT"},
+		{Path: "example.com/b", Version: "v1.0.0", License: "Apache-2.0 AND GPL-3.0-only"},
+		{Path: "example.com/c", Version: "v1.0.0", License: "LGPL-3.0"},
+		{Path: "example.com/d", Version: "v1.0.0"},
+	}
+
+	testCases := []struct {
+		title       string
+		denied      []string
+		expectError error
+	}{
+		{title: "no denied licenses", denied: nil},
+		{title: "denied license not used", denied: []string{"AGPL-3.0"}},
+		{title: "denied variant", denied: []string{"gpl-3.0"}, expectError: ErrDeniedLicense},
+		{title: "denied license", denied: []string{"MIT"}, expectError: ErrDeniedLicense},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkLicenses(deps, tc.denied)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+// writeExtension writes the extension module with the license to a directory
+func writeExtension(t *testing.T, license string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"go.mod":   "module go.k6.io/k6ext\n\ngo 1.17\n",
+		"k6ext.go": "package k6ext\n",
+		"LICENSE":  license,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	return dir
+}
+
+func TestBuildLicenses(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	for _, mod := range []struct{ path, version, dir string }{
+		{"go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")},
+		{"go.k6.io/k6ext", "v0.1.0", writeExtension(t, mitLicense)},
+		{"go.k6.io/k6ext", "v0.2.0", writeExtension(t, gplLicense)},
+	} {
+		if err := proxy.AddModVersion(mod.path, mod.version, mod.dir); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	testCases := []struct {
+		title       string
+		mod         Module
+		denied      []string
+		expect      string
+		expectError error
+	}{
+		{
+			title:  "module in cache",
+			mod:    Module{Path: "go.k6.io/k6ext", Version: "v0.1.0"},
+			expect: "MIT",
+		},
+		{
+			title:  "local replacement",
+			mod:    Module{Path: "go.k6.io/k6ext", ReplacePath: writeExtension(t, gplLicense)},
+			expect: "GPL-3.0",
+		},
+		{
+			title:  "allowed license",
+			mod:    Module{Path: "go.k6.io/k6ext", Version: "v0.1.0"},
+			denied: []string{"GPL-3.0"},
+			expect: "MIT",
+		},
+		{
+			title:       "denied license",
+			mod:         Module{Path: "go.k6.io/k6ext", Version: "v0.2.0"},
+			denied:      []string{"GPL-3.0"},
+			expectError: ErrDeniedLicense,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := NewNativeBuilder(context.Background(), NativeBuilderOpts{
+				GoOpts: GoOpts{
+					CopyGoEnv: true,
+					Env: map[string]string{
+						"GOPROXY":   goproxySrv.URL,
+						"GONOPROXY": "none",
+						"GOPRIVATE": "go.k6.io",
+						"GONOSUMDB": "go.k6.io",
+					},
+					TmpCache: true,
+				},
+				DetectLicenses: true,
+				DeniedLicenses: tc.denied,
+			})
+			if err != nil {
+				t.Fatalf("setting up test %v", err)
+			}
+
+			mods := []Module{tc.mod}
+			info, err := b.Build(context.Background(), RuntimePlatform(), "v0.1.0", mods, []string{}, &bytes.Buffer{})
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			if tc.expectError != nil {
+				return
+			}
+
+			licenses := map[string]string{}
+			for _, dep := range info.Dependencies {
+				licenses[dep.Path] = dep.License
+			}
+
+			if licenses["go.k6.io/k6ext"] != tc.expect {
+				t.Fatalf("expected %q got %v", tc.expect, licenses)
+			}
+
+			// k6 doesn't include a license in the test data
+			if license, found := licenses["go.k6.io/k6"]; !found || license != "" {
+				t.Fatalf("expected k6 without license got %v", licenses)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package k6foundry
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Phase identifies a phase of a build for metrics and tracing
+type Phase string
+
+const (
+	// PhaseBuild is the complete build, from preparing the build environment to writing the binaries
+	PhaseBuild Phase = "build"
+	// PhaseResolve is the resolution of k6 and the extensions, including downloading the modules
+	PhaseResolve Phase = "resolve"
+	// PhaseCompile is the compilation of the binary for a platform, including its compression and the
+	// post-build hooks
+	PhaseCompile Phase = "compile"
+)
+
+// Metrics receives measurements of the builds, for example for exporting them to Prometheus.
+// Methods can be called concurrently and should return promptly.
+type Metrics interface {
+	// ObservePhase records the duration of a phase of a build. The platform is empty for the phases that
+	// don't target a platform. The error class is empty if the phase succeeded (see ErrorClass)
+	ObservePhase(phase Phase, platform string, duration time.Duration, errClass string)
+	// ObserveBinarySize records the size of a binary built for the platform
+	ObserveBinarySize(platform string, bytes int64)
+	// ObserveCacheLookup records a lookup of a binary in a build cache
+	ObserveCacheLookup(hit bool)
+}
+
+// Tracer starts a span for each phase of the builds, for example using OpenTelemetry
+type Tracer interface {
+	// Start starts a span for the phase, returning the context used by the phase and a function for
+	// ending the span with the error of the phase (nil if it succeeded)
+	Start(ctx context.Context, phase Phase) (context.Context, func(error))
+}
+
+// errorClasses maps errors to their class. The first match is used, so more specific errors go first
+var errorClasses = []struct { //nolint:gochecknoglobals
+	err   error
+	class string
+}{
+	{context.Canceled, "canceled"},
+	{ErrBuildTimeout, "timeout"},
+	{context.DeadlineExceeded, "timeout"},
+	{ErrBusy, "busy"},
+	{ErrInsufficientDiskSpace, "disk_space"},
+	{ErrModuleNotFound, "module_not_found"},
+	{ErrVersionNotFound, "version_not_found"},
+	{ErrChecksumMismatch, "checksum_mismatch"},
+	{ErrBuildConstraints, "build_constraints"},
+	{ErrIncompatibleExtension, "incompatible_extension"},
+	{ErrConflictingDependencies, "conflicting_dependencies"},
+	{ErrLockMismatch, "lock_mismatch"},
+	{ErrResolvingDependency, "resolution"},
+	{ErrCompiling, "compile"},
+	{ErrPostBuildHook, "post_build_hook"},
+}
+
+// ClassError returns the error of a class returned by ErrorClass, so the errors reported by their class
+// (e.g. by a build service) can be checked using errors.Is. Returns nil for unknown classes
+func ClassError(class string) error {
+	for _, e := range errorClasses {
+		if e.class == class {
+			return e.err
+		}
+	}
+
+	return nil
+}
+
+// ErrorClass returns a short name for the class of a build error, suitable as a metric label
+// (e.g. timeout, module_not_found, compile). Returns "other" for unknown errors and an empty string for nil
+func ErrorClass(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var compileErr *CompileError
+	if errors.As(err, &compileErr) {
+		return "compile"
+	}
+
+	for _, e := range errorClasses {
+		if errors.Is(err, e.err) {
+			return e.class
+		}
+	}
+
+	return "other"
+}
+
+// startPhase starts measuring a phase of a build. Returns the context for the phase and a function for
+// ending it with the error of the phase
+func (b *nativeBuilder) startPhase(ctx context.Context, phase Phase, platform string) (context.Context, func(error)) {
+	endSpan := func(error) {}
+	if b.Tracer != nil {
+		ctx, endSpan = b.Tracer.Start(ctx, phase)
+	}
+
+	start := time.Now()
+
+	return ctx, func(err error) {
+		endSpan(err)
+
+		if b.Metrics != nil {
+			b.Metrics.ObservePhase(phase, platform, time.Since(start), ErrorClass(err))
+		}
+	}
+}
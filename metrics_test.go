@@ -0,0 +1,187 @@
+package k6foundry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+// recordingMetrics records the phases and binary sizes observed
+type recordingMetrics struct {
+	mu     sync.Mutex
+	phases []string
+	sizes  []int64
+}
+
+func (m *recordingMetrics) ObservePhase(phase Phase, platform string, _ time.Duration, errClass string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.phases = append(m.phases, fmt.Sprintf("%s %s %s", phase, platform, errClass))
+}
+
+func (m *recordingMetrics) ObserveBinarySize(_ string, bytes int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sizes = append(m.sizes, bytes)
+}
+
+func (m *recordingMetrics) ObserveCacheLookup(bool) {}
+
+// recordingTracer records the spans started and ended
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []string
+}
+
+func (r *recordingTracer) Start(ctx context.Context, phase Phase) (context.Context, func(error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.spans = append(r.spans, "start "+string(phase))
+
+	return ctx, func(error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.spans = append(r.spans, "end "+string(phase))
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title  string
+		err    error
+		expect string
+	}{
+		{title: "no error", err: nil, expect: ""},
+		{title: "timeout", err: fmt.Errorf("%w: %w", ErrBuildTimeout, ErrResolvingDependency), expect: "timeout"},
+		{title: "canceled", err: fmt.Errorf("%w: %w", ErrCompiling, context.Canceled), expect: "canceled"},
+		{title: "module not found", err: fmt.Errorf("%w: %w", ErrResolvingDependency, ErrModuleNotFound), expect: "module_not_found"},
+		{title: "compile error", err: fmt.Errorf("%w: %w", ErrCompiling, &CompileError{Package: "p"}), expect: "compile"},
+		{title: "resolving", err: fmt.Errorf("%w: failed", ErrResolvingDependency), expect: "resolution"},
+		{title: "unknown", err: errors.New("unknown"), expect: "other"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			if class := ErrorClass(tc.err); class != tc.expect {
+				t.Fatalf("expected %q got %q", tc.expect, class)
+			}
+		})
+	}
+}
+
+func TestClassError(t *testing.T) {
+	t.Parallel()
+
+	for _, e := range errorClasses {
+		if class := ErrorClass(ClassError(e.class)); class != e.class {
+			t.Fatalf("expected class %q got %q", e.class, class)
+		}
+	}
+
+	if err := ClassError("other"); err != nil {
+		t.Fatalf("expected no error for unknown class got %v", err)
+	}
+}
+
+func TestBuildMetrics(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	if err := proxy.AddModVersion("go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	testCases := []struct {
+		title        string
+		k6Version    string
+		expectPhases []string
+		expectSpans  []string
+		expectSizes  int
+	}{
+		{
+			title:     "successful build",
+			k6Version: "v0.1.0",
+			expectPhases: []string{
+				"resolve  ",
+				"compile " + RuntimePlatform().String() + " ",
+				"build " + RuntimePlatform().String() + " ",
+			},
+			expectSpans: []string{
+				"start build", "start resolve", "end resolve", "start compile", "end compile", "end build",
+			},
+			expectSizes: 1,
+		},
+		{
+			title:     "failed resolution",
+			k6Version: "v0.2.0",
+			expectPhases: []string{
+				"resolve  version_not_found",
+				"build " + RuntimePlatform().String() + " version_not_found",
+			},
+			expectSpans: []string{"start build", "start resolve", "end resolve", "end build"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			metrics := &recordingMetrics{}
+			tracer := &recordingTracer{}
+
+			b, err := NewNativeBuilder(context.Background(), NativeBuilderOpts{
+				GoOpts: GoOpts{
+					CopyGoEnv: true,
+					Env: map[string]string{
+						"GOPROXY":   goproxySrv.URL,
+						"GONOPROXY": "none",
+						"GOPRIVATE": "go.k6.io",
+						"GONOSUMDB": "go.k6.io",
+					},
+					TmpCache: true,
+				},
+				Metrics: metrics,
+				Tracer:  tracer,
+			})
+			if err != nil {
+				t.Fatalf("setting up test %v", err)
+			}
+
+			_, _ = b.Build(context.Background(), RuntimePlatform(), tc.k6Version, []Module{}, []string{}, &bytes.Buffer{})
+
+			if !slices.Equal(metrics.phases, tc.expectPhases) {
+				t.Fatalf("expected phases %v got %v", tc.expectPhases, metrics.phases)
+			}
+
+			if !slices.Equal(tracer.spans, tc.expectSpans) {
+				t.Fatalf("expected spans %v got %v", tc.expectSpans, tracer.spans)
+			}
+
+			if len(metrics.sizes) != tc.expectSizes {
+				t.Fatalf("expected %d binary sizes got %v", tc.expectSizes, metrics.sizes)
+			}
+		})
+	}
+}
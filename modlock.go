@@ -0,0 +1,138 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ErrLockMismatch is returned when the resolved modules differ from the ones recorded in the lock file
+var ErrLockMismatch = errors.New("resolved modules don't match lock file")
+
+// ModuleLock records the modules resolved by a build and their checksums
+type ModuleLock struct {
+	// version of every module required by the build
+	Modules map[string]string `json:"modules"`
+	// go.sum entries of the build
+	Sums []string `json:"sums"`
+}
+
+// loadModuleLock reads the lock file. Returns nil if the file doesn't exist.
+func loadModuleLock(path string) (*ModuleLock, error) {
+	content, err := os.ReadFile(path) //nolint:gosec
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil //nolint:nilnil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading lock file %w", err)
+	}
+
+	lock := &ModuleLock{}
+	if err = json.Unmarshal(content, lock); err != nil {
+		return nil, fmt.Errorf("parsing lock file %w", err)
+	}
+
+	return lock, nil
+}
+
+// save writes the lock file
+func (l *ModuleLock) save(path string) error {
+	content, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling lock file %w", err)
+	}
+
+	if err = os.WriteFile(path, append(content, '\n'), 0o644); err != nil { //nolint:gosec
+		return fmt.Errorf("writing lock file %w", err)
+	}
+
+	return nil
+}
+
+// seed writes the checksums in the lock to the go.sum in the work directory, so go verifies the
+// downloaded modules against them
+func (l *ModuleLock) seed(workDir string) error {
+	content := strings.Join(l.Sums, "\n") + "\n"
+
+	if err := os.WriteFile(filepath.Join(workDir, "go.sum"), []byte(content), 0o600); err != nil {
+		return fmt.Errorf("writing go.sum %w", err)
+	}
+
+	return nil
+}
+
+// lockFromWorkDir creates the lock from the resolved modules and the go.sum in the work directory
+func lockFromWorkDir(workDir string, modules map[string]string) (*ModuleLock, error) {
+	content, err := os.ReadFile(filepath.Join(workDir, "go.sum")) //nolint:gosec
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("reading go.sum %w", err)
+	}
+
+	sums := []string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			sums = append(sums, line)
+		}
+	}
+	sort.Strings(sums)
+
+	return &ModuleLock{Modules: modules, Sums: slices.Compact(sums)}, nil
+}
+
+// verify checks the resolved modules match the ones in the lock
+func (l *ModuleLock) verify(resolved *ModuleLock) error {
+	for path, version := range resolved.Modules {
+		locked, found := l.Modules[path]
+		if !found {
+			return fmt.Errorf("%w: %s %s not in lock file", ErrLockMismatch, path, version)
+		}
+
+		if locked != version {
+			return fmt.Errorf("%w: %s resolved to %s, locked to %s", ErrLockMismatch, path, version, locked)
+		}
+	}
+
+	for path, version := range l.Modules {
+		if _, found := resolved.Modules[path]; !found {
+			return fmt.Errorf("%w: %s %s not resolved", ErrLockMismatch, path, version)
+		}
+	}
+
+	locked := map[string]bool{}
+	for _, sum := range l.Sums {
+		locked[sum] = true
+	}
+
+	for _, sum := range resolved.Sums {
+		if !locked[sum] {
+			return fmt.Errorf("%w: checksum not in lock file: %s", ErrLockMismatch, sum)
+		}
+	}
+
+	return nil
+}
+
+// lockModules records the resolved modules in the lock file, or verifies them if the lock file exists
+func (b *nativeBuilder) lockModules(workDir string, lock *ModuleLock, modules map[string]string) error {
+	resolved, err := lockFromWorkDir(workDir, maps.Clone(modules))
+	if err != nil {
+		return err
+	}
+
+	if lock == nil {
+		b.log.Info(fmt.Sprintf("Writing lock file %s", b.LockFile))
+		return resolved.save(b.LockFile)
+	}
+
+	b.log.Info(fmt.Sprintf("Verifying modules against lock file %s", b.LockFile))
+
+	return lock.verify(resolved)
+}
@@ -0,0 +1,75 @@
+package k6foundry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestLockFile(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	for _, version := range []string{"v0.1.0", "v0.2.0"} {
+		if err := proxy.AddModVersion("go.k6.io/k6", version, filepath.Join("testdata", "mods", "k6")); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	lockFile := filepath.Join(t.TempDir(), "k6foundry.lock.json")
+
+	opts := NativeBuilderOpts{
+		GoOpts: GoOpts{
+			CopyGoEnv: true,
+			Env: map[string]string{
+				"GOPROXY":   goproxySrv.URL,
+				"GONOPROXY": "none",
+				"GOPRIVATE": "go.k6.io",
+				"GONOSUMDB": "go.k6.io",
+			},
+			TmpCache: true,
+		},
+		LockFile: lockFile,
+	}
+
+	b, err := NewNativeBuilder(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("setting up test %v", err)
+	}
+
+	platform, _ := ParsePlatform("linux/amd64")
+
+	// sequential steps: creates the lock file, verifies it and detects a mismatch
+	steps := []struct {
+		k6Version   string
+		expectError error
+	}{
+		{k6Version: "latest"},
+		{k6Version: "v0.2.0"},
+		{k6Version: "v0.1.0", expectError: ErrLockMismatch},
+	}
+
+	for _, step := range steps {
+		_, err = b.Build(context.Background(), platform, step.k6Version, []Module{}, []string{}, &bytes.Buffer{})
+		if !errors.Is(err, step.expectError) {
+			t.Fatalf("building %s: expected %v got %v", step.k6Version, step.expectError, err)
+		}
+	}
+
+	lock, err := loadModuleLock(lockFile)
+	if err != nil {
+		t.Fatalf("reading lock file %v", err)
+	}
+
+	if lock.Modules["go.k6.io/k6"] != "v0.2.0" || len(lock.Sums) == 0 {
+		t.Fatalf("unexpected lock %v", lock)
+	}
+}
@@ -11,8 +11,16 @@ import (
 	"golang.org/x/mod/semver"
 )
 
+// fileURLPrefix is the prefix of the replacements that reference a local directory as a file URL
+const fileURLPrefix = "file://"
+
 var (
 	moduleVersionRegexp = regexp.MustCompile(`.+/v(\d+)$`)
+	// commit SHAs, abbreviated to at least 7 digits
+	commitRegexp = regexp.MustCompile(`^[0-9a-fA-F]{7,40}$`)
+	// branch names. Names that look like a version (v followed by a digit) must be semantic versions
+	branchRegexp  = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9._/-]*$`)
+	versionPrefix = regexp.MustCompile(`^v(\d|$)`)
 
 	ErrInvalidDependencyFormat = errors.New("invalid dependency format") //nolint:revive
 )
@@ -34,21 +42,48 @@ type Module struct {
 	ReplaceVersion string
 }
 
+// String returns the module in the format path[@version][=replace[@version]] accepted by ParseModule.
+// Parsing the string of a module returned by ParseModule returns the same module
 func (m Module) String() string {
-	replace := ""
+	sb := &strings.Builder{}
+	sb.WriteString(m.Path)
+	if m.Version != "" {
+		sb.WriteString("@" + m.Version)
+	}
+
 	if m.ReplacePath != "" {
-		replaceVer := ""
+		sb.WriteString("=" + m.ReplacePath)
 		if m.ReplaceVersion != "" {
-			replaceVer = "@" + m.ReplaceVersion
+			sb.WriteString("@" + m.ReplaceVersion)
 		}
-		replace = fmt.Sprintf(" => %s%s", m.ReplacePath, replaceVer)
 	}
-	return fmt.Sprintf("%s@%s%s", m.Path, m.Version, replace)
+
+	return sb.String()
+}
+
+// MarshalText marshals the module as its string, for example in JSON and YAML documents
+func (m Module) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText parses the module from its string (see ParseModule)
+func (m *Module) UnmarshalText(text []byte) error {
+	mod, err := ParseModule(string(text))
+	if err != nil {
+		return err
+	}
+
+	*m = mod
+
+	return nil
 }
 
 // ParseModule parses a module from a string of the form path[@version][=replace[@version]]
+// The version can be a semantic version (including pseudo-versions), latest, a branch name, a commit SHA or
+// a version constraint (e.g. >=v0.9.0 <v0.11.0 or ~v0.9). Branches and commits are resolved by the go
+// toolchain when building, and constraints to the highest published version that satisfies them.
 func ParseModule(modString string) (Module, error) {
-	mod, replaceMod, _ := strings.Cut(modString, "=")
+	mod, replaceMod := cutReplace(modString)
 
 	path, version, err := splitPathVersion(mod)
 	if err != nil {
@@ -82,6 +117,20 @@ func ParseModule(modString string) (Module, error) {
 	}, nil
 }
 
+// ParseModules parses a list of modules in the format accepted by ParseModule
+func ParseModules(modStrings []string) ([]Module, error) {
+	mods := make([]Module, 0, len(modStrings))
+	for _, modString := range modStrings {
+		mod, err := ParseModule(modString)
+		if err != nil {
+			return nil, err
+		}
+		mods = append(mods, mod)
+	}
+
+	return mods, nil
+}
+
 func replace(replaceMod string) (string, string, error) {
 	if replaceMod == "" {
 		return "", "", nil
@@ -92,15 +141,60 @@ func replace(replaceMod string) (string, string, error) {
 		return "", "", err
 	}
 
-	// is a relative path
+	// go.mod replacements require a version
+	if isConstraint(replaceVersion) {
+		return "", "", fmt.Errorf("%w: replace version can't be a constraint %q", ErrInvalidDependencyFormat, replaceMod)
+	}
+
+	if !isLocalPath(replacePath) {
+		return replacePath, replaceVersion, nil
+	}
+
+	if replaceVersion != "" {
+		return "", "", fmt.Errorf("%w: local replace path can't specify version", ErrInvalidDependencyFormat)
+	}
+
+	// relative paths are resolved when building, from the current directory
 	if strings.HasPrefix(replacePath, ".") {
-		if replaceVersion != "" {
-			return "", "", fmt.Errorf("%w: relative replace path can't specify version", ErrInvalidDependencyFormat)
+		return replacePath, "", nil
+	}
+
+	replacePath, err = resolvePath(replacePath)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %w", ErrInvalidDependencyFormat, err)
+	}
+
+	return replacePath, "", nil
+}
+
+// isLocalPath returns true if the replacement is a local directory instead of a module: a relative or
+// absolute path, a path in the home directory (~/path) or a file:// URL
+func isLocalPath(replacePath string) bool {
+	return strings.HasPrefix(replacePath, ".") || strings.HasPrefix(replacePath, "/") ||
+		replacePath == "~" || strings.HasPrefix(replacePath, "~/") ||
+		strings.HasPrefix(replacePath, fileURLPrefix) || filepath.IsAbs(replacePath)
+}
+
+// cutReplace splits a dependency into the module and its replacement, separated by the first = that is not
+// part of an operator of a version constraint (e.g. path@>=v0.9.0=replace)
+func cutReplace(dep string) (string, string) {
+	for i := range len(dep) {
+		if dep[i] != '=' {
+			continue
 		}
-		return replacePath, replaceVersion, nil
+
+		// operators such as >=, <=, != and =, or =< and => (e.g. path@=v1.0.0 or path@>=v1 <=v2)
+		if i > 0 && strings.ContainsRune("<>!=@ ,|", rune(dep[i-1])) {
+			continue
+		}
+		if i+1 < len(dep) && (dep[i+1] == '<' || dep[i+1] == '>') {
+			continue
+		}
+
+		return dep[:i], dep[i+1:]
 	}
 
-	return replacePath, replaceVersion, nil
+	return dep, ""
 }
 
 // splits a path[@version] string into its components
@@ -116,6 +210,9 @@ func splitPathVersion(mod string) (string, string, error) {
 	case "", "latest":
 		break
 	default:
+		if isRevision(version) || isConstraint(version) {
+			break
+		}
 		if !semver.IsValid(version) {
 			return "", "", fmt.Errorf("%w: invalid semantic version %q", ErrInvalidDependencyFormat, mod)
 		}
@@ -125,6 +222,15 @@ func splitPathVersion(mod string) (string, string, error) {
 	return path, version, nil
 }
 
+// isRevision returns true if the version references a branch or a commit SHA
+func isRevision(version string) bool {
+	if commitRegexp.MatchString(version) {
+		return true
+	}
+
+	return branchRegexp.MatchString(version) && !versionPrefix.MatchString(version) && version != "latest"
+}
+
 // VersionedPath returns a module path with the major component of version added,
 // if it is a valid semantic version and is > 1
 // Examples:
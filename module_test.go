@@ -1,13 +1,24 @@
 package k6foundry
 
 import (
+	"encoding/json"
 	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestParseModule(t *testing.T) {
 	t.Parallel()
 
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
 	testCases := []struct {
 		title       string
 		dependency  string
@@ -56,6 +67,48 @@ func TestParseModule(t *testing.T) {
 			dependency:  "github.com/path/module@v",
 			expectError: ErrInvalidDependencyFormat,
 		},
+		{
+			title:      "path with pseudo-version",
+			dependency: "github.com/path/module@v0.0.0-20240102150405-1a2b3c4d5e6f",
+			expect: Module{
+				Path:    "github.com/path/module",
+				Version: "v0.0.0-20240102150405-1a2b3c4d5e6f",
+			},
+		},
+		{
+			title:      "path with branch",
+			dependency: "github.com/path/module@master",
+			expect: Module{
+				Path:    "github.com/path/module",
+				Version: "master",
+			},
+		},
+		{
+			title:      "path with branch with slash",
+			dependency: "github.com/path/module@feature/new-api",
+			expect: Module{
+				Path:    "github.com/path/module",
+				Version: "feature/new-api",
+			},
+		},
+		{
+			title:      "path with commit",
+			dependency: "github.com/path/module@1a2b3c4",
+			expect: Module{
+				Path:    "github.com/path/module",
+				Version: "1a2b3c4",
+			},
+		},
+		{
+			title:       "path with short commit",
+			dependency:  "github.com/path/module@1a2b3",
+			expectError: ErrInvalidDependencyFormat,
+		},
+		{
+			title:       "path with invalid branch",
+			dependency:  "github.com/path/module@-master",
+			expectError: ErrInvalidDependencyFormat,
+		},
 		{
 			title:       "invalid path",
 			dependency:  "github.com/@v1",
@@ -105,6 +158,87 @@ func TestParseModule(t *testing.T) {
 			dependency:  "github.com/path/module=./another/module@v0.1.0",
 			expectError: ErrInvalidDependencyFormat,
 		},
+		{
+			title:      "version constraint",
+			dependency: "github.com/path/module@>=v0.9.0 <v0.11.0",
+			expect: Module{
+				Path:    "github.com/path/module",
+				Version: ">=v0.9.0 <v0.11.0",
+			},
+		},
+		{
+			title:      "tilde version constraint",
+			dependency: "github.com/path/module@~v0.9",
+			expect: Module{
+				Path:    "github.com/path/module",
+				Version: "~v0.9",
+			},
+		},
+		{
+			title:      "version constraint with replace",
+			dependency: "github.com/path/module@>=v0.9.0=./another/module",
+			expect: Module{
+				Path:        "github.com/path/module",
+				Version:     ">=v0.9.0",
+				ReplacePath: "./another/module",
+			},
+		},
+		{
+			title:       "invalid version constraint",
+			dependency:  "github.com/path/module@>=foo",
+			expectError: ErrInvalidDependencyFormat,
+		},
+		{
+			title:       "replace version constraint",
+			dependency:  "github.com/path/module=github.com/another/module@>=v0.1.0",
+			expectError: ErrInvalidDependencyFormat,
+		},
+		{
+			title:      "absolute replace",
+			dependency: "github.com/path/module=/another/module",
+			expect: Module{
+				Path:        "github.com/path/module",
+				Version:     "latest",
+				ReplacePath: "/another/module",
+			},
+		},
+		{
+			title:       "versioned absolute replace",
+			dependency:  "github.com/path/module=/another/module@v0.1.0",
+			expectError: ErrInvalidDependencyFormat,
+		},
+		{
+			title:      "home replace",
+			dependency: "github.com/path/module=~/another/module",
+			expect: Module{
+				Path:        "github.com/path/module",
+				Version:     "latest",
+				ReplacePath: filepath.Join(home, "another", "module"),
+			},
+		},
+		{
+			title:      "file URL replace",
+			dependency: "github.com/path/module@v0.1.0=file:///another/module",
+			expect: Module{
+				Path:        "github.com/path/module",
+				Version:     "v0.1.0",
+				ReplacePath: filepath.FromSlash("/another/module"),
+			},
+		},
+		{
+			title:      "localhost file URL replace",
+			dependency: "github.com/path/module=file://localhost/another/my%20module",
+			expect: Module{
+				Path:        "github.com/path/module",
+				Version:     "latest",
+				ReplacePath: filepath.FromSlash("/another/my module"),
+			},
+		},
+		{
+			title:       "remote file URL replace",
+			dependency:  "github.com/path/module=file://host/another/module",
+			expectError: ErrInvalidDependencyFormat,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -123,3 +257,147 @@ func TestParseModule(t *testing.T) {
 		})
 	}
 }
+
+func TestModuleRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title      string
+		dependency string
+		expect     string
+	}{
+		{
+			title:      "path without version",
+			dependency: "github.com/path/module",
+			expect:     "github.com/path/module@latest",
+		},
+		{
+			title:      "incomplete version",
+			dependency: "github.com/path/module@v0.1",
+			expect:     "github.com/path/module@v0.1.0",
+		},
+		{
+			title:      "major version",
+			dependency: "github.com/path/module@v2.0.0",
+			expect:     "github.com/path/module/v2@v2.0.0",
+		},
+		{
+			title:      "commit",
+			dependency: "github.com/path/module@1a2b3c4",
+			expect:     "github.com/path/module@1a2b3c4",
+		},
+		{
+			title:      "versioned replace",
+			dependency: "github.com/path/module@v0.1.0=github.com/another/module@v0.2",
+			expect:     "github.com/path/module@v0.1.0=github.com/another/module@v0.2.0",
+		},
+		{
+			title:      "relative replace",
+			dependency: "github.com/path/module=./another/module",
+			expect:     "github.com/path/module@latest=./another/module",
+		},
+		{
+			title:      "version constraint",
+			dependency: "github.com/path/module@>=v0.9.0 <v0.11.0=github.com/another/module@v0.2.0",
+			expect:     "github.com/path/module@>=v0.9.0 <v0.11.0=github.com/another/module@v0.2.0",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			mod, err := ParseModule(tc.dependency)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if mod.String() != tc.expect {
+				t.Fatalf("expected %q got %q", tc.expect, mod.String())
+			}
+
+			parsed, err := ParseModule(mod.String())
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if parsed != mod {
+				t.Fatalf("expected %v got %v", mod, parsed)
+			}
+		})
+	}
+}
+
+func TestParseModules(t *testing.T) {
+	t.Parallel()
+
+	mods, err := ParseModules([]string{"github.com/path/module@v0.1.0", "github.com/another/module"})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	expect := []Module{
+		{Path: "github.com/path/module", Version: "v0.1.0"},
+		{Path: "github.com/another/module", Version: "latest"},
+	}
+	if !reflect.DeepEqual(mods, expect) {
+		t.Fatalf("expected %v got %v", expect, mods)
+	}
+
+	_, err = ParseModules([]string{"github.com/path/module", "github.com/another/module@"})
+	if !errors.Is(err, ErrInvalidDependencyFormat) {
+		t.Fatalf("expected %v got %v", ErrInvalidDependencyFormat, err)
+	}
+}
+
+func TestModuleMarshalling(t *testing.T) {
+	t.Parallel()
+
+	type document struct {
+		Dependencies []Module `json:"dependencies" yaml:"dependencies"`
+	}
+
+	doc := document{Dependencies: []Module{
+		{Path: "github.com/path/module", Version: "v0.1.0"},
+		{Path: "github.com/another/module", Version: "latest", ReplacePath: "./another/module"},
+	}}
+
+	content, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	expect := `{"dependencies":["github.com/path/module@v0.1.0","github.com/another/module@latest=./another/module"]}`
+	if string(content) != expect {
+		t.Fatalf("expected %s got %s", expect, content)
+	}
+
+	fromJSON := document{}
+	if err = json.Unmarshal(content, &fromJSON); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if !reflect.DeepEqual(fromJSON, doc) {
+		t.Fatalf("expected %v got %v", doc, fromJSON)
+	}
+
+	content, err = yaml.Marshal(doc)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	fromYAML := document{}
+	if err = yaml.Unmarshal(content, &fromYAML); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if !reflect.DeepEqual(fromYAML, doc) {
+		t.Fatalf("expected %v got %v", doc, fromYAML)
+	}
+
+	err = yaml.Unmarshal([]byte("dependencies: [\"github.com/path/module@\"]"), &fromYAML)
+	if !errors.Is(err, ErrInvalidDependencyFormat) {
+		t.Fatalf("expected %v got %v", ErrInvalidDependencyFormat, err)
+	}
+}
@@ -0,0 +1,166 @@
+package k6foundry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// ErrDuplicatedPlatform is returned when a platform is requested more than once in a multi-platform build
+var ErrDuplicatedPlatform = errors.New("duplicated platform")
+
+// OutputFunc returns the io.Writer for the binary of a platform.
+// It can be called concurrently for different platforms.
+type OutputFunc func(platform Platform) (io.Writer, error)
+
+// MultiPlatformBuilder is implemented by builders that can build binaries for multiple platforms
+// in a single invocation. The dependencies are resolved once and the binaries are compiled concurrently.
+type MultiPlatformBuilder interface {
+	// BuildMultiPlatform builds a custom k6 binary for each platform with the given dependencies.
+	// Returns the build info of each platform, in the same order as the platforms.
+	BuildMultiPlatform(
+		ctx context.Context,
+		platforms []Platform,
+		k6Version string,
+		mods []Module,
+		buildOpts []string,
+		out OutputFunc,
+	) ([]*BuildInfo, error)
+}
+
+// BuildMultiPlatform builds a custom k6 binary for each platform with the given dependencies
+func (b *nativeBuilder) BuildMultiPlatform(
+	ctx context.Context,
+	platforms []Platform,
+	k6Version string,
+	exts []Module,
+	buildOpts []string,
+	out OutputFunc,
+) ([]*BuildInfo, error) {
+	return b.buildMultiPlatformWith(ctx, b.hostEnv, platforms, k6Version, exts, buildOpts, out)
+}
+
+func (b *nativeBuilder) buildMultiPlatformWith(
+	ctx context.Context,
+	newEnv envFactory,
+	platforms []Platform,
+	k6Version string,
+	exts []Module,
+	buildOpts []string,
+	out OutputFunc,
+) ([]*BuildInfo, error) {
+	seen := map[Platform]bool{}
+	for _, platform := range platforms {
+		if seen[platform] {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicatedPlatform, platform)
+		}
+		seen[platform] = true
+	}
+
+	if err := checkConflicts(exts); err != nil {
+		return nil, err
+	}
+
+	b.emit(Event{Type: EventBuildStarted})
+	ctx, endPhase := b.startPhase(ctx, PhaseBuild, "")
+
+	var buildInfos []*BuildInfo
+
+	// the dependencies are platform independent, so they are resolved using the runtime platform
+	err := b.withWorkDir(ctx, newEnv, RuntimePlatform(), b.GoOpts, func(workDir string, buildEnv *goEnv) error {
+		b.log.Info("Building new k6 binaries (multi-platform)")
+
+		err := buildEnv.checkPlatforms(ctx, platforms...)
+		if err != nil {
+			return err
+		}
+
+		buildInfo, err := b.prepareReusing(ctx, workDir, buildEnv, k6Version, exts)
+		if err != nil {
+			return err
+		}
+
+		buildInfos, err = b.compileAll(ctx, workDir, buildEnv, platforms, buildOpts, out)
+		if err != nil {
+			return err
+		}
+
+		// the work directory is shared by all the platforms
+		usage := buildEnv.diskUsage(workDir)
+		for _, info := range buildInfos {
+			info.ModVersions = maps.Clone(buildInfo.ModVersions)
+			info.Dependencies = slices.Clone(buildInfo.Dependencies)
+			info.DiskUsage = usage
+		}
+
+		return nil
+	})
+	err = buildError(PhaseBuild, "", "", err)
+
+	endPhase(err)
+	b.emit(Event{Type: EventBuildFinished, Err: err})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buildInfos, nil
+}
+
+// compileAll compiles the binaries for all the platforms concurrently.
+// If any compilation fails, the others are cancelled.
+func (b *nativeBuilder) compileAll(
+	ctx context.Context,
+	workDir string,
+	buildEnv *goEnv,
+	platforms []Platform,
+	buildOpts []string,
+	out OutputFunc,
+) ([]*BuildInfo, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	buildInfos := make([]*BuildInfo, len(platforms))
+	errs := make([]error, len(platforms))
+
+	wg := sync.WaitGroup{}
+	for i, platform := range platforms {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var (
+				checksum string
+				size     int64
+			)
+			binary, err := out(platform)
+			if err == nil {
+				checksum, size, err = b.compile(ctx, workDir, buildEnv.withPlatform(platform), buildOpts, binaryOutput{writer: binary})
+			}
+
+			if err != nil {
+				errs[i] = fmt.Errorf("%s: %w", platform, err)
+				cancel()
+				return
+			}
+
+			buildInfos[i] = &BuildInfo{
+				Platform:   platform.String(),
+				Checksum:   checksum,
+				Size:       size,
+				Executable: platform.BinaryName("k6"),
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return buildInfos, nil
+}
@@ -0,0 +1,114 @@
+package k6foundry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestBuildMultiPlatform(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	if err := proxy.AddModVersion("go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	opts := NativeBuilderOpts{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		GoOpts: GoOpts{
+			CopyGoEnv: true,
+			Env: map[string]string{
+				"GOPROXY":   goproxySrv.URL,
+				"GONOPROXY": "none",
+				"GOPRIVATE": "go.k6.io",
+				"GONOSUMDB": "go.k6.io",
+			},
+			TmpCache: true,
+		},
+	}
+
+	testCases := []struct {
+		title       string
+		platforms   []string
+		expectError error
+	}{
+		{
+			title:     "multiple platforms",
+			platforms: []string{"linux/amd64", "linux/arm64", "windows/amd64"},
+		},
+		{
+			title:       "duplicated platform",
+			platforms:   []string{"linux/amd64", "linux/amd64"},
+			expectError: ErrDuplicatedPlatform,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := NewNativeBuilder(context.Background(), opts)
+			if err != nil {
+				t.Fatalf("setting up test %v", err)
+			}
+
+			platforms := []Platform{}
+			for _, p := range tc.platforms {
+				platform, _ := ParsePlatform(p)
+				platforms = append(platforms, platform)
+			}
+
+			mutex := sync.Mutex{}
+			outputs := map[Platform]*bytes.Buffer{}
+			out := func(platform Platform) (io.Writer, error) {
+				mutex.Lock()
+				defer mutex.Unlock()
+
+				outputs[platform] = &bytes.Buffer{}
+
+				return outputs[platform], nil
+			}
+
+			buildInfos, err := b.(MultiPlatformBuilder).BuildMultiPlatform(
+				context.Background(),
+				platforms,
+				"v0.1.0",
+				[]Module{},
+				[]string{},
+				out,
+			)
+
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			if tc.expectError != nil {
+				return
+			}
+
+			for i, platform := range platforms {
+				if outputs[platform].Len() == 0 {
+					t.Fatalf("out file for %s is empty", platform)
+				}
+
+				if buildInfos[i].Platform != platform.String() || buildInfos[i].ModVersions["go.k6.io/k6"] != "v0.1.0" {
+					t.Fatalf("unexpected build info for %s: %v", platform, buildInfos[i])
+				}
+			}
+		})
+	}
+}
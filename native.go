@@ -2,24 +2,32 @@
 package k6foundry
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"text/template"
+	"time"
 )
 
+// ErrInvalidK6Repo is returned when the k6 repository options are not valid
+var ErrInvalidK6Repo = errors.New("invalid k6 repository")
+
 const (
 	defaultK6ModulePath = "go.k6.io/k6"
 
-	defaultWorkDir = "k6foundry*"
+	defaultWorkDir = tmpDirPrefix + "*"
 
 	mainModuleTemplate = `package main
 
 import (
-	k6cmd "%s/cmd"
+	k6cmd "{{ .K6Module }}/cmd"
 
 )
 
@@ -33,25 +41,123 @@ func main() {
 `
 )
 
+// defaultMainTemplate is the template used for generating the main.go if no MainTemplate is given
+var defaultMainTemplate = template.Must(template.New("main").Parse(mainModuleTemplate))
+
+// MainTemplateData is the data passed to the template that generates the main.go
+type MainTemplateData struct {
+	// path of the k6 module
+	K6Module string
+	// extensions included in the binary. Each extension is imported in its own file
+	Extensions []Module
+}
+
+// envFactory creates the go environment for building in a work directory
+type envFactory func(workDir string, platform Platform, opts GoOpts) (*goEnv, error)
+
 type nativeBuilder struct {
 	NativeBuilderOpts
 	log *slog.Logger
+	// slots for limiting the concurrent builds. nil if not limited
+	slots chan struct{}
 }
 
 // NativeBuilderOpts defines the options for the Native build environment
 type NativeBuilderOpts struct {
 	// options used for running go
 	GoOpts
-	// use alternative k6 repository
+	// use alternative k6 repository. Either a local directory or the module path of a fork
+	// (e.g. github.com/my-org/k6)
 	K6Repo string
+	// version of the fork used as k6 repository (e.g. v0.51.0-custom). Can also be a branch or a commit.
+	// Not supported for local directories
+	K6RepoVersion string
 	// don't cleanup work environment (useful for debugging)
 	SkipCleanup bool
+	// when the temporary work directory of a build is removed. Defaults to CleanupAlways. The kept
+	// directories can be removed with ReapStale
+	Cleanup CleanupStrategy
+	// if positive, remove the stale directories in the build directory root not modified in this time
+	// when the builder is created (see FindStale)
+	ReapStaleAfter time.Duration
 	// redirect stdout
 	Stdout io.Writer
 	// redirect stderr
 	Stderr io.Writer
 	// set log level (INFO, WARN, ERROR)
 	Logger *slog.Logger
+	// log each line of output of the go commands using the Logger instead of writing it to Stdout and
+	// Stderr. Downloads are logged at debug level, changes to the requirements at info level and
+	// errors (including compile errors) at error level
+	LogGoOutput bool
+	// receives build progress events
+	OnEvent EventHandler
+	// report all the modules included in the binary in the BuildInfo (e.g. for generating a SBOM)
+	ListDependencies bool
+	// detect the license of each module included in the binary from its license files, and report it in
+	// the Dependencies of the BuildInfo. Implies ListDependencies
+	DetectLicenses bool
+	// fail with ErrDeniedLicense if a module has one of these licenses (SPDX identifiers, e.g. GPL-3.0).
+	// An identifier also denies its variants (e.g. GPL-3.0-only). Implies DetectLicenses
+	DeniedLicenses []string
+	// report the SHA256 checksum of the binary in the BuildInfo
+	Checksum bool
+	// build with -trimpath, -buildvcs=false and an empty build ID, using the installed go toolchain unless
+	// GoVersion is set, so building the same inputs produces byte-identical binaries
+	Reproducible bool
+	// build the binary twice, without reusing compiled packages, and fail with ErrNotReproducible if the
+	// binaries differ. Implies Reproducible
+	VerifyReproducible bool
+	// path to the lock file with the resolved modules and their checksums. If the file exists, the
+	// build fails if the resolved modules differ. Otherwise, it is created after resolving the modules.
+	LockFile string
+	// template for generating the main.go, executed with a MainTemplateData. Defaults to a main
+	// that calls k6cmd.Execute()
+	MainTemplate *template.Template
+	// local module directories used as a go workspace. The modules in the workspace are used instead of
+	// the versions required by k6 and the extensions, including transitive dependencies
+	Workspace []string
+	// replacements of modules in the build, including transitive dependencies of k6 and the extensions
+	Replacements []Replace
+	// modules required at these versions after resolving k6 and the extensions (e.g. for upgrading a
+	// transitive dependency with a broken version). Fails with ErrConflictingDependencies if a newer version
+	// is required. Pins of modules not used by the build are ignored
+	Pins []Module
+	// versions of modules excluded when resolving k6 and the extensions, so the next higher version is used
+	Excludes []Module
+	// fail if an extension requires a newer k6 version than the requested one, instead of upgrading k6
+	StrictK6Version bool
+	// maximum number of concurrent builds (including vendoring and resolving the dependencies)
+	// for the builder. 0 means no limit
+	ConcurrentBuilds int
+	// wait for a build to finish when ConcurrentBuilds are running, instead of failing with ErrBusy.
+	// The wait can be limited using the context
+	QueueBuilds bool
+	// directory used as work directory instead of a temporary one. It is not removed after the build.
+	// Builds using the same directory wait for each other
+	WorkDir string
+	// reuse the build environment prepared in the WorkDir by a previous build with the same k6 version
+	// and extensions, skipping the resolution of the dependencies. Versions such as latest are not
+	// resolved again. Local replacements and workspace modules are compiled from their current content
+	ReuseWorkDir bool
+	// omit the symbol table and debug information from the binary (-ldflags "-s -w")
+	StripDebugInfo bool
+	// compress the binary using UPX. Requires upx to be installed
+	CompressWithUPX bool
+	// hooks that process the binary after it is compiled and compressed, in order (e.g. for signing it with
+	// NewCodesignHook or NewSigntoolHook)
+	PostBuildHooks []Hook
+	// values of string variables set at link time (-ldflags -X), mapping the fully qualified
+	// name of the variable (e.g. K6VersionDetails) to its value
+	BuildMetadata map[string]string
+	// interval for checking the local modules for changes in Watch. Defaults to DefaultWatchInterval
+	WatchInterval time.Duration
+	// permissions of the binaries written to files with BuildToFile. Defaults to DefaultOutputMode
+	OutputMode os.FileMode
+	// receives the duration and errors of the phases of the builds and the size of the binaries
+	Metrics Metrics
+	// starts a span for each phase of the builds
+	Tracer Tracer
 }
 
 // NewDefaultNativeBuilder creates a new native build environment with default options
@@ -67,7 +173,68 @@ func NewDefaultNativeBuilder() (Builder, error) {
 }
 
 // NewNativeBuilder creates a new native build environment with the given options
-func NewNativeBuilder(_ context.Context, opts NativeBuilderOpts) (Builder, error) {
+func NewNativeBuilder(ctx context.Context, opts NativeBuilderOpts) (Builder, error) {
+	if err := validateOpts(opts); err != nil {
+		return nil, err
+	}
+
+	if err := downloadGoIfMissing(ctx, opts.GoOpts); err != nil {
+		return nil, err
+	}
+
+	return newNativeBuilder(opts), nil
+}
+
+// validateOpts checks the options that would otherwise fail after resolving the dependencies
+func validateOpts(opts NativeBuilderOpts) error {
+	if opts.K6RepoVersion != "" && opts.K6Repo == "" {
+		return fmt.Errorf("%w: version requires a repository", ErrInvalidK6Repo)
+	}
+
+	if opts.Offline && opts.TmpCache {
+		return fmt.Errorf("%w: offline builds require a module cache", ErrSettingGoEnv)
+	}
+
+	if err := opts.validateVerification(); err != nil {
+		return err
+	}
+
+	if err := opts.validateEnv(); err != nil {
+		return err
+	}
+
+	if _, err := metadataLdFlags(opts.BuildMetadata); err != nil {
+		return err
+	}
+
+	if err := validateCleanupStrategy(opts.Cleanup); err != nil {
+		return err
+	}
+
+	if err := validateReplacements(opts.Replacements); err != nil {
+		return err
+	}
+
+	if err := validatePins(opts.Pins, opts.Excludes); err != nil {
+		return err
+	}
+
+	if err := validateBuildTags(opts.BuildTags); err != nil {
+		return err
+	}
+
+	if _, err := opts.buildFlags(nil); err != nil {
+		return err
+	}
+
+	if err := validatePGOProfile(opts.PGOProfile); err != nil {
+		return err
+	}
+
+	return checkPostProcessing(opts)
+}
+
+func newNativeBuilder(opts NativeBuilderOpts) *nativeBuilder {
 	if opts.Stderr == nil {
 		opts.Stderr = io.Discard
 	}
@@ -87,10 +254,28 @@ func NewNativeBuilder(_ context.Context, opts NativeBuilderOpts) (Builder, error
 		)
 	}
 
-	return &nativeBuilder{
+	// the output of the go commands replaces Stdout and Stderr
+	if opts.LogGoOutput {
+		opts.Stdout = newGoLogWriter(log, "stdout")
+		opts.Stderr = newGoLogWriter(log, "stderr")
+	}
+
+	var slots chan struct{}
+	if opts.ConcurrentBuilds > 0 {
+		slots = make(chan struct{}, opts.ConcurrentBuilds)
+	}
+
+	b := &nativeBuilder{
 		NativeBuilderOpts: opts,
 		log:               log,
-	}, nil
+		slots:             slots,
+	}
+
+	if opts.ReapStaleAfter > 0 {
+		b.reapStale()
+	}
+
+	return b
 }
 
 // Build builds a custom k6 binary for a target platform with the given dependencies into the out io.Writer
@@ -102,49 +287,169 @@ func (b *nativeBuilder) Build(
 	buildOpts []string,
 	binary io.Writer,
 ) (*BuildInfo, error) {
-	workDir, err := os.MkdirTemp(os.TempDir(), defaultWorkDir)
-	if err != nil {
-		return nil, fmt.Errorf("creating working directory: %w", err)
-	}
-
-	defer func() {
-		if b.SkipCleanup {
-			b.log.Info(fmt.Sprintf("Skipping cleanup. leaving directory %s intact", workDir))
-			return
-		}
-
-		b.log.Info(fmt.Sprintf("Cleaning up work directory %s", workDir))
-		_ = os.RemoveAll(workDir)
-	}()
-
-	// prepare the build environment
-	b.log.Info("Building new k6 binary (native)")
-
-	k6Binary := filepath.Join(workDir, "k6")
+	return b.buildWith(ctx, b.hostEnv, platform, k6Version, exts, buildOpts, binaryOutput{writer: binary})
+}
 
-	buildEnv, err := newGoEnv(
+// hostEnv creates a go environment that uses the go toolchain installed in the host
+func (b *nativeBuilder) hostEnv(workDir string, platform Platform, opts GoOpts) (*goEnv, error) {
+	return newGoEnv(
 		workDir,
-		b.GoOpts,
+		opts,
 		platform,
 		b.Stdout,
 		b.Stderr,
 	)
+}
+
+// buildWith builds the binary using the go environment created by newEnv
+func (b *nativeBuilder) buildWith(
+	ctx context.Context,
+	newEnv envFactory,
+	platform Platform,
+	k6Version string,
+	exts []Module,
+	buildOpts []string,
+	binary binaryOutput,
+) (*BuildInfo, error) {
+	b.emit(Event{Type: EventBuildStarted})
+	ctx, endPhase := b.startPhase(ctx, PhaseBuild, platform.String())
+
+	buildInfo, err := b.build(ctx, newEnv, platform, k6Version, exts, buildOpts, binary)
+	err = buildError(PhaseBuild, platform.String(), "", err)
+
+	endPhase(err)
+	b.emit(Event{Type: EventBuildFinished, Err: err})
+
+	return buildInfo, err
+}
+
+func (b *nativeBuilder) build(
+	ctx context.Context,
+	newEnv envFactory,
+	platform Platform,
+	k6Version string,
+	exts []Module,
+	buildOpts []string,
+	binary binaryOutput,
+) (*BuildInfo, error) {
+	if err := checkConflicts(exts); err != nil {
+		return nil, err
+	}
+
+	var buildInfo *BuildInfo
+
+	err := b.withWorkDir(ctx, newEnv, platform, b.GoOpts, func(workDir string, buildEnv *goEnv) error {
+		b.log.Info("Building new k6 binary (native)")
+
+		err := buildEnv.checkPlatforms(ctx, platform)
+		if err != nil {
+			return err
+		}
+
+		buildInfo, err = b.prepareReusing(ctx, workDir, buildEnv, k6Version, exts)
+		if err != nil {
+			return err
+		}
+
+		buildInfo.Checksum, buildInfo.Size, err = b.compile(ctx, workDir, buildEnv, buildOpts, binary)
+		buildInfo.DiskUsage = buildEnv.diskUsage(workDir)
+
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	return buildInfo, nil
+}
+
+// withWorkDir opens a work directory and creates a go environment for the platform, and runs f on them.
+// Both are cleaned up when f returns.
+func (b *nativeBuilder) withWorkDir(
+	ctx context.Context,
+	newEnv envFactory,
+	platform Platform,
+	opts GoOpts,
+	f func(workDir string, buildEnv *goEnv) error,
+) (err error) {
+	release, err := b.acquireSlot(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	if err = os.MkdirAll(opts.buildDirRoot(), 0o750); err != nil {
+		return fmt.Errorf("creating build directory root: %w", err)
+	}
+
+	workDir, closeWorkDir, err := b.openWorkDir(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { closeWorkDir(err) }()
+
+	if opts.Offline {
+		opts = offlineOpts(opts)
+	}
+
+	if b.Reproducible || b.VerifyReproducible {
+		opts = reproducibleGoOpts(opts)
+	}
+
+	buildEnv, err := newEnv(workDir, platform, opts)
+	if err != nil {
+		return err
+	}
+
 	defer func() {
-		if b.SkipCleanup {
+		if b.SkipCleanup || b.Cleanup == CleanupNever {
 			b.log.Info("Skipping go cleanup")
+			buildEnv.unlock()
 			return
 		}
 		_ = buildEnv.close(ctx)
 	}()
 
+	if err = b.checkDiskSpace(workDir, buildEnv.cacheDir); err != nil {
+		return err
+	}
+
+	err = f(workDir, buildEnv)
+
+	// the cache grows also when the build fails
+	if trimErr := b.trimCache(ctx, workDir, buildEnv); trimErr != nil && ctx.Err() == nil {
+		b.log.Warn(fmt.Sprintf("trimming go cache: %v", trimErr))
+	}
+
+	return err
+}
+
+// prepare initializes the go module in the work directory and resolves k6 and the extensions
+func (b *nativeBuilder) prepare(
+	ctx context.Context,
+	workDir string,
+	buildEnv *goEnv,
+	k6Version string,
+	exts []Module,
+) (_ *BuildInfo, err error) {
+	ctx, endPhase := b.startPhase(ctx, PhaseResolve, "")
+	defer func() {
+		err = buildError(PhaseResolve, "", "", err)
+		endPhase(err)
+	}()
+
 	buildInfo := &BuildInfo{
-		Platform:    platform.String(),
+		Platform:    buildEnv.platform.String(),
 		ModVersions: map[string]string{},
+		Executable:  buildEnv.platform.BinaryName("k6"),
+	}
+
+	// resolving the modules downloads them to the module cache
+	unlock, err := buildEnv.lockCache(ctx, true)
+	if err != nil {
+		return nil, err
 	}
+	defer unlock()
 
 	b.log.Info("Initializing Go module")
 	err = buildEnv.modInit(ctx)
@@ -152,24 +457,60 @@ func (b *nativeBuilder) Build(
 		return nil, err
 	}
 
+	var lock *ModuleLock
+	if b.LockFile != "" {
+		lock, err = loadModuleLock(b.LockFile)
+		if err != nil {
+			return nil, err
+		}
+
+		// go verifies the downloaded modules against the locked checksums
+		if lock != nil {
+			if err = lock.seed(workDir); err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	b.log.Info("Creating k6 main")
-	err = b.createMain(ctx, workDir)
+	err = b.createMain(ctx, workDir, exts)
 	if err != nil {
 		return nil, err
 	}
 
+	if err = b.addReplacements(ctx, buildEnv); err != nil {
+		return nil, err
+	}
+
+	if err = b.addExcludes(ctx, buildEnv); err != nil {
+		return nil, err
+	}
+
 	k6Mod := Module{
-		Path:        defaultK6ModulePath,
-		Version:     k6Version,
-		ReplacePath: b.K6Repo,
+		Path:           defaultK6ModulePath,
+		Version:        k6Version,
+		ReplacePath:    b.K6Repo,
+		ReplaceVersion: b.K6RepoVersion,
 	}
 
-	modVer, err := b.addMod(ctx, buildEnv, k6Mod)
-	if err != nil {
+	if b.Offline {
+		if err = checkCached(buildEnv.modCache, append([]Module{k6Mod}, exts...)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = buildEnv.checkGit(append([]Module{k6Mod}, exts...)); err != nil {
+		return nil, err
+	}
+
+	if err = b.checkGoVersion(ctx, buildEnv, k6Version); err != nil {
 		return nil, err
 	}
 
-	buildInfo.ModVersions[defaultK6ModulePath] = modVer
+	k6Resolved, err := b.addMod(ctx, buildEnv, k6Mod)
+	if err != nil {
+		return nil, buildError(PhaseResolve, "", k6Mod.Path, err)
+	}
 
 	b.log.Info("importing extensions")
 	for _, m := range exts {
@@ -178,38 +519,148 @@ func (b *nativeBuilder) Build(
 			return nil, err
 		}
 
-		modVer, err = b.addMod(ctx, buildEnv, m)
+		_, err = b.addMod(ctx, buildEnv, m)
 		if err != nil {
-			return nil, err
+			return nil, buildError(PhaseResolve, "", m.Path, err)
 		}
-		buildInfo.ModVersions[m.Path] = modVer
 	}
 
-	b.log.Info("Building k6")
-	err = buildEnv.compile(ctx, k6Binary, buildOpts...)
-	if err != nil {
+	if err = b.addPins(ctx, buildEnv); err != nil {
 		return nil, err
 	}
 
-	b.log.Info("Build complete")
-	k6File, err := os.Open(k6Binary) //nolint:gosec
-	if err != nil {
+	// check the extensions before the (slow) compilation
+	if err = b.checkCompatibility(ctx, buildEnv, k6Resolved, exts); err != nil {
 		return nil, err
 	}
 
-	_, err = io.Copy(binary, k6File)
+	// adding an extension can change the version of the modules added before it,
+	// so the versions are taken from the final go.mod
+	requires, err := buildEnv.modRequires()
 	if err != nil {
-		return nil, fmt.Errorf("copying binary %w", err)
+		return nil, err
+	}
+
+	buildInfo.ModVersions[defaultK6ModulePath] = requires[defaultK6ModulePath]
+	for _, m := range exts {
+		buildInfo.ModVersions[m.Path] = requires[m.Path]
+	}
+
+	detectLicenses := b.DetectLicenses || len(b.DeniedLicenses) > 0
+	if b.ListDependencies || detectLicenses {
+		buildInfo.Dependencies, err = moduleDependencies(workDir, requires)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if detectLicenses {
+		if err = addLicenses(workDir, buildEnv.modCache, buildInfo.Dependencies); err != nil {
+			return nil, err
+		}
+
+		if err = checkLicenses(buildInfo.Dependencies, b.DeniedLicenses); err != nil {
+			return nil, err
+		}
+	}
+
+	if b.LockFile != "" {
+		if err = b.lockModules(workDir, lock, requires); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = b.createWorkspace(ctx, buildEnv); err != nil {
+		return nil, err
 	}
 
 	return buildInfo, nil
 }
 
-func (b *nativeBuilder) createMain(_ context.Context, path string) error {
+// compile builds the binary in the work directory and writes it to the output. Returns the checksum of
+// the binary, if requested in the options, and its size
+func (b *nativeBuilder) compile(
+	ctx context.Context,
+	workDir string,
+	buildEnv *goEnv,
+	buildOpts []string,
+	binary binaryOutput,
+) (string, int64, error) {
+	// each platform is compiled to its own file, so they can be compiled concurrently
+	k6Binary := filepath.Join(workDir, "k6-"+buildEnv.platform.suffix())
+
+	buildOpts, err := b.linkerOpts(buildOpts)
+	if err != nil {
+		return "", 0, err
+	}
+
+	buildOpts, err = b.buildFlags(buildOpts)
+	if err != nil {
+		return "", 0, err
+	}
+
+	buildOpts = addBuildTags(buildOpts, b.BuildTags)
+
+	buildOpts, err = addPGOProfile(workDir, buildOpts, b.PGOProfile)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if b.Reproducible || b.VerifyReproducible {
+		buildOpts = reproducibleBuildOpts(buildOpts)
+	}
+
+	unlock, err := buildEnv.lockCache(ctx, false)
+	if err != nil {
+		return "", 0, err
+	}
+
+	b.log.Info(fmt.Sprintf("Building k6 for %s", buildEnv.platform))
+	b.emit(Event{Type: EventCompiling, Platform: buildEnv.platform.String()})
+	phaseCtx, endPhase := b.startPhase(ctx, PhaseCompile, buildEnv.platform.String())
+	err = buildEnv.compile(phaseCtx, k6Binary, buildOpts...)
+
+	if err == nil && b.VerifyReproducible {
+		err = b.verifyReproducible(phaseCtx, buildEnv, k6Binary, buildOpts)
+	}
+	unlock()
+
+	// the binary is compressed in the work directory, so the checksum corresponds to the compressed binary
+	if err == nil && b.CompressWithUPX {
+		b.log.Info("Compressing binary")
+		err = b.compress(phaseCtx, k6Binary)
+	}
+
+	// signing must be the last change to the binary
+	if err == nil {
+		err = b.runHooks(phaseCtx, k6Binary, buildEnv.platform)
+	}
+
+	if err == nil {
+		err = checkExecutable(k6Binary, buildEnv.platform)
+	}
+
+	err = buildError(PhaseCompile, buildEnv.platform.String(), "", err)
+	endPhase(err)
+	if err != nil {
+		return "", 0, err
+	}
+
+	b.log.Info("Build complete")
+	b.emit(Event{Type: EventCompiled, Platform: buildEnv.platform.String()})
+
+	return b.writeBinary(buildEnv.platform, k6Binary, binary)
+}
+
+func (b *nativeBuilder) createMain(_ context.Context, path string, exts []Module) error {
+	mainContent, err := b.renderMain(exts)
+	if err != nil {
+		return err
+	}
+
 	// write the main module file
 	mainPath := filepath.Join(path, "main.go")
-	mainContent := fmt.Sprintf(mainModuleTemplate, defaultK6ModulePath)
-	err := os.WriteFile(mainPath, []byte(mainContent), 0o600)
+	err = os.WriteFile(mainPath, mainContent, 0o600)
 	if err != nil {
 		return fmt.Errorf("writing main file %w", err)
 	}
@@ -217,9 +668,58 @@ func (b *nativeBuilder) createMain(_ context.Context, path string) error {
 	return nil
 }
 
+// renderMain returns the content of the main file that imports the extensions
+func (b *nativeBuilder) renderMain(exts []Module) ([]byte, error) {
+	mainTemplate := b.MainTemplate
+	if mainTemplate == nil {
+		mainTemplate = defaultMainTemplate
+	}
+
+	mainContent := &bytes.Buffer{}
+	err := mainTemplate.Execute(mainContent, MainTemplateData{K6Module: defaultK6ModulePath, Extensions: exts})
+	if err != nil {
+		return nil, fmt.Errorf("generating main file %w", err)
+	}
+
+	return mainContent.Bytes(), nil
+}
+
 func (b *nativeBuilder) addMod(ctx context.Context, e *goEnv, mod Module) (string, error) {
+	b.emit(Event{Type: EventModuleResolving, Module: mod.Path, Version: mod.Version})
+
+	version, err := b.resolveMod(ctx, e, mod)
+	if err != nil {
+		return "", err
+	}
+
+	b.emit(Event{Type: EventModuleResolved, Module: mod.Path, Version: version})
+
+	return version, nil
+}
+
+func (b *nativeBuilder) resolveMod(ctx context.Context, e *goEnv, mod Module) (string, error) {
 	b.log.Info(fmt.Sprintf("adding dependency %s", mod.String()))
 
+	// go.mod only accepts versions, so branches and commits are resolved first
+	var err error
+	if isRevision(mod.Version) {
+		if mod.Version, err = e.modQuery(ctx, mod.Path, mod.Version); err != nil {
+			return "", err
+		}
+	}
+
+	if isConstraint(mod.Version) {
+		if mod.Version, err = resolveConstraint(ctx, e, mod.Path, mod.Version); err != nil {
+			return "", err
+		}
+	}
+
+	if isRevision(mod.ReplaceVersion) {
+		if mod.ReplaceVersion, err = e.modQuery(ctx, mod.ReplacePath, mod.ReplaceVersion); err != nil {
+			return "", err
+		}
+	}
+
 	if mod.ReplacePath == "" {
 		if err := e.modRequire(ctx, mod.Path, mod.Version); err != nil {
 			return "", err
@@ -249,6 +749,18 @@ func (b *nativeBuilder) addMod(ctx context.Context, e *goEnv, mod Module) (strin
 	return e.modVersion(ctx, mod.Path)
 }
 
+func (b *nativeBuilder) emit(event Event) {
+	if b.OnEvent == nil {
+		return
+	}
+
+	event.Time = time.Now()
+	b.OnEvent(event)
+}
+
+// resolvePath returns the path of a local directory, expanding the environment variables, the home
+// directory (~) and file:// URLs. Relative paths are made absolute. Other paths, such as module paths, are
+// returned as they are
 func resolvePath(path string) (string, error) {
 	var err error
 	// expand environment variables
@@ -256,6 +768,21 @@ func resolvePath(path string) (string, error) {
 		path = os.ExpandEnv(path)
 	}
 
+	if strings.HasPrefix(path, fileURLPrefix) {
+		path, err = fileURLPath(path)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		var home string
+		if home, err = os.UserHomeDir(); err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, path[1:])
+	}
+
 	if strings.HasPrefix(path, ".") {
 		path, err = filepath.Abs(path)
 		if err != nil {
@@ -266,6 +793,27 @@ func resolvePath(path string) (string, error) {
 	return path, nil
 }
 
+// fileURLPath returns the local path of a file:// URL
+func fileURLPath(fileURL string) (string, error) {
+	u, err := url.Parse(fileURL)
+	if err != nil {
+		return "", err
+	}
+
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("file URL %q is not local", fileURL)
+	}
+
+	path := filepath.FromSlash(u.Path)
+
+	// in windows, the URL path of an absolute path starts with a slash before the volume (/C:/dir)
+	if len(path) > 1 && filepath.VolumeName(path[1:]) != "" {
+		path = path[1:]
+	}
+
+	return path, nil
+}
+
 func (b *nativeBuilder) createModuleImport(_ context.Context, path string, mod Module) error {
 	modImportFile := filepath.Join(path, strings.ReplaceAll(mod.Path, "/", "_")+".go")
 	modImportContent := fmt.Sprintf(modImportTemplate, mod.Path)
@@ -3,14 +3,19 @@ package k6foundry
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"debug/buildinfo"
 	"errors"
+	"fmt"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"text/template"
 
-	"github.com/grafana/k6foundry/pkg/testutils/goproxy"
+	"github.com/grafana/k6foundry/pkg/goproxy"
 )
 
 func TestBuild(t *testing.T) {
@@ -41,6 +46,16 @@ func TestBuild(t *testing.T) {
 			version: "v2.0.0",
 			source:  filepath.Join("testdata", "mods", "k6extV2"),
 		},
+		{
+			path:    "go.k6.io/k6ext2",
+			version: "v0.1.0",
+			source:  filepath.Join("testdata", "mods", "k6ext2"),
+		},
+		{
+			path:    "go.k6.io/k6fork",
+			version: "v0.1.0-custom",
+			source:  filepath.Join("testdata", "mods", "k6"),
+		},
 	}
 
 	// creates a goproxy that serves the given modules
@@ -52,14 +67,23 @@ func TestBuild(t *testing.T) {
 		}
 	}
 
+	if err := proxy.AddModQuery("go.k6.io/k6ext", "master", "v0.1.0"); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
 	goproxySrv := httptest.NewServer(proxy)
 
 	testCases := []struct {
-		title       string
-		k6Version   string
-		mods        []Module
-		expectError error
-		expect      *BuildInfo
+		title         string
+		platform      string
+		k6Version     string
+		mods          []Module
+		strict        bool
+		k6Repo        string
+		k6RepoVer     string
+		expectError   error
+		expectGoError error
+		expect        *BuildInfo
 	}{
 		{
 			title:       "compile k6 v0.1.0",
@@ -74,10 +98,42 @@ func TestBuild(t *testing.T) {
 			},
 		},
 		{
-			title:       "compile k6 missing version (v0.3.0)",
-			k6Version:   "v0.3.0",
+			title:     "compile k6 v0.1.0 for platform not supported by k6",
+			platform:  "linux/386",
+			k6Version: "v0.1.0",
+			mods:      []Module{},
+			expect: &BuildInfo{
+				Platform: "linux/386",
+				ModVersions: map[string]string{
+					"go.k6.io/k6": "v0.1.0",
+				},
+			},
+		},
+		{
+			title:     "compile k6 v0.1.0 for arm v6",
+			platform:  "linux/arm/v6",
+			k6Version: "v0.1.0",
+			mods:      []Module{},
+			expect: &BuildInfo{
+				Platform: "linux/arm/v6",
+				ModVersions: map[string]string{
+					"go.k6.io/k6": "v0.1.0",
+				},
+			},
+		},
+		{
+			title:       "compile k6 v0.1.0 for invalid platform",
+			platform:    "darwin/386",
+			k6Version:   "v0.1.0",
 			mods:        []Module{},
-			expectError: ErrResolvingDependency,
+			expectError: ErrUnsupportedPlatform,
+		},
+		{
+			title:         "compile k6 missing version (v0.3.0)",
+			k6Version:     "v0.3.0",
+			mods:          []Module{},
+			expectError:   ErrResolvingDependency,
+			expectGoError: ErrVersionNotFound,
 		},
 		{
 			title:       "compile k6 latest",
@@ -106,13 +162,59 @@ func TestBuild(t *testing.T) {
 				},
 			},
 		},
+		{
+			title:     "compile k6 v0.1.0 with k6ext branch",
+			k6Version: "v0.1.0",
+			mods: []Module{
+				{Path: "go.k6.io/k6ext", Version: "master"},
+			},
+			expectError: nil,
+			expect: &BuildInfo{
+				Platform: "linux/amd64",
+				ModVersions: map[string]string{
+					"go.k6.io/k6":    "v0.1.0",
+					"go.k6.io/k6ext": "v0.1.0",
+				},
+			},
+		},
+		{
+			title:     "compile k6 v0.1.0 with missing k6ext branch",
+			k6Version: "v0.1.0",
+			mods: []Module{
+				{Path: "go.k6.io/k6ext", Version: "develop"},
+			},
+			expectError: ErrUnknownRevision,
+		},
+		{
+			title:     "compile k6 v0.1.0 with k6ext version constraint",
+			k6Version: "v0.1.0",
+			mods: []Module{
+				{Path: "go.k6.io/k6ext", Version: ">=v0.1.0 <v1.0.0"},
+			},
+			expect: &BuildInfo{
+				Platform: "linux/amd64",
+				ModVersions: map[string]string{
+					"go.k6.io/k6":    "v0.1.0",
+					"go.k6.io/k6ext": "v0.1.0",
+				},
+			},
+		},
+		{
+			title:     "compile k6 v0.1.0 with unsatisfiable k6ext version constraint",
+			k6Version: "v0.1.0",
+			mods: []Module{
+				{Path: "go.k6.io/k6ext", Version: "~v0.2"},
+			},
+			expectError: ErrNoMatchingVersion,
+		},
 		{
 			title:     "compile k6 v0.1.0 with missing k6ext (v0.2.0)",
 			k6Version: "v0.2.0",
 			mods: []Module{
 				{Path: "go.k6.io/k6ext", Version: "v0.2.0"},
 			},
-			expectError: ErrResolvingDependency,
+			expectError:   ErrResolvingDependency,
+			expectGoError: ErrVersionNotFound,
 		},
 		{
 			title:     "compile k6 v0.2.0 with k6extV2 (v2.0.0)",
@@ -129,6 +231,30 @@ func TestBuild(t *testing.T) {
 				},
 			},
 		},
+		{
+			title:     "compile k6 v0.1.0 with k6ext2 requiring k6 v0.2.0",
+			k6Version: "v0.1.0",
+			mods: []Module{
+				{Path: "go.k6.io/k6ext2", Version: "v0.1.0"},
+			},
+			expectError: nil,
+			expect: &BuildInfo{
+				Platform: "linux/amd64",
+				ModVersions: map[string]string{
+					"go.k6.io/k6":     "v0.2.0",
+					"go.k6.io/k6ext2": "v0.1.0",
+				},
+			},
+		},
+		{
+			title:     "compile k6 v0.1.0 with k6ext2 requiring k6 v0.2.0 strict k6 version",
+			k6Version: "v0.1.0",
+			mods: []Module{
+				{Path: "go.k6.io/k6ext2", Version: "v0.1.0"},
+			},
+			strict:      true,
+			expectError: ErrIncompatibleExtension,
+		},
 		{
 			title:     "compile k6 v0.2.0 replace k6ext with local module",
 			k6Version: "v0.2.0",
@@ -145,6 +271,27 @@ func TestBuild(t *testing.T) {
 				},
 			},
 		},
+		{
+			title:     "compile k6 v0.1.0 replaced with fork version",
+			k6Version: "v0.1.0",
+			k6Repo:    "go.k6.io/k6fork",
+			k6RepoVer: "v0.1.0-custom",
+			mods:      []Module{},
+			expect: &BuildInfo{
+				Platform: "linux/amd64",
+				ModVersions: map[string]string{
+					"go.k6.io/k6": "v0.1.0",
+				},
+			},
+		},
+		{
+			title:       "compile k6 v0.1.0 replaced with missing fork version",
+			k6Version:   "v0.1.0",
+			k6Repo:      "go.k6.io/k6fork",
+			k6RepoVer:   "v0.2.0-custom",
+			mods:        []Module{},
+			expectError: ErrResolvingDependency,
+		},
 		{
 			title:     "compile k6 v0.2.0 replace k6ext with missing local module",
 			k6Version: "v0.2.0",
@@ -161,7 +308,10 @@ func TestBuild(t *testing.T) {
 		t.Run(tc.title, func(t *testing.T) {
 			t.Parallel()
 
-			platform, _ := ParsePlatform("linux/amd64")
+			if tc.platform == "" {
+				tc.platform = "linux/amd64"
+			}
+			platform, _ := ParsePlatform(tc.platform)
 			opts := NativeBuilderOpts{
 				Stdout: os.Stdout,
 				Stderr: os.Stderr,
@@ -175,6 +325,9 @@ func TestBuild(t *testing.T) {
 					},
 					TmpCache: true,
 				},
+				StrictK6Version: tc.strict,
+				K6Repo:          tc.k6Repo,
+				K6RepoVersion:   tc.k6RepoVer,
 			}
 
 			b, err := NewNativeBuilder(context.Background(), opts)
@@ -196,6 +349,10 @@ func TestBuild(t *testing.T) {
 				t.Fatalf("expected %v got %v", tc.expectError, err)
 			}
 
+			if tc.expectGoError != nil && !errors.Is(err, tc.expectGoError) {
+				t.Fatalf("expected %v got %v", tc.expectGoError, err)
+			}
+
 			if tc.expectError != nil {
 				return
 			}
@@ -204,8 +361,265 @@ func TestBuild(t *testing.T) {
 				t.Fatal("out file is empty")
 			}
 
-			if !reflect.DeepEqual(buildInfo, tc.expect) {
-				t.Fatalf("expected %v got %v", tc.expect, buildInfo)
+			if buildInfo.Size != int64(outFile.Len()) {
+				t.Fatalf("expected size %d got %d", outFile.Len(), buildInfo.Size)
+			}
+			buildInfo.Size = 0
+
+			if buildInfo.DiskUsage <= 0 {
+				t.Fatalf("expected disk usage got %d", buildInfo.DiskUsage)
+			}
+			buildInfo.DiskUsage = 0
+
+			expect := *tc.expect
+			expect.Executable = platform.BinaryName("k6")
+			if !reflect.DeepEqual(*buildInfo, expect) {
+				t.Fatalf("expected %v got %v", expect, buildInfo)
+			}
+		})
+	}
+}
+
+func TestMainTemplate(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	for _, m := range []struct{ path, version, source string }{
+		{"go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")},
+		{"go.k6.io/k6ext", "v0.1.0", filepath.Join("testdata", "mods", "k6ext")},
+	} {
+		if err := proxy.AddModVersion(m.path, m.version, m.source); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	testCases := []struct {
+		title       string
+		template    string
+		expectError bool
+		expect      string
+	}{
+		{
+			title:  "default template",
+			expect: "k6cmd.Execute()",
+		},
+		{
+			title: "custom template",
+			template: `package main
+
+import k6cmd "{{ .K6Module }}/cmd"
+
+// {{ range .Extensions }}{{ .Path }}{{ end }}
+var version = "custom"
+
+func main() {
+	k6cmd.Execute()
+}
+`,
+			expect: "// go.k6.io/k6ext\nvar version = \"custom\"",
+		},
+		{
+			title:       "invalid template",
+			template:    "{{ .Undefined }}",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			opts := NativeBuilderOpts{
+				GoOpts: GoOpts{
+					CopyGoEnv: true,
+					Env: map[string]string{
+						"GOPROXY":   goproxySrv.URL,
+						"GONOPROXY": "none",
+						"GOPRIVATE": "go.k6.io",
+						"GONOSUMDB": "go.k6.io",
+					},
+					TmpCache: true,
+				},
+			}
+
+			if tc.template != "" {
+				opts.MainTemplate = template.Must(template.New("main").Parse(tc.template))
+			}
+
+			b, err := NewNativeBuilder(context.Background(), opts)
+			if err != nil {
+				t.Fatalf("setting up test %v", err)
+			}
+
+			resolution, err := b.(Resolver).Resolve(
+				context.Background(),
+				"v0.1.0",
+				[]Module{{Path: "go.k6.io/k6ext", Version: "v0.1.0"}},
+			)
+			if tc.expectError {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if !strings.Contains(resolution.Files["main.go"], tc.expect) {
+				t.Fatalf("expected main.go to contain %q got %q", tc.expect, resolution.Files["main.go"])
+			}
+		})
+	}
+}
+
+func TestGoVersion(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	if err := proxy.AddModVersion("go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	installed, _ := goVersion("go")
+
+	testCases := []struct {
+		title       string
+		goVersion   string
+		expectError error
+	}{
+		{
+			title:     "installed version",
+			goVersion: installed,
+		},
+		{
+			title:       "unavailable version",
+			goVersion:   "1.99.0",
+			expectError: ErrSettingGoEnv,
+		},
+		{
+			title:       "invalid version",
+			goVersion:   "latest",
+			expectError: ErrInvalidGoVersion,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			opts := NativeBuilderOpts{
+				GoOpts: GoOpts{
+					CopyGoEnv: true,
+					Env: map[string]string{
+						"GOPROXY":   goproxySrv.URL,
+						"GONOPROXY": "none",
+						"GOPRIVATE": "go.k6.io",
+						"GONOSUMDB": "go.k6.io",
+					},
+					TmpCache:  true,
+					GoVersion: tc.goVersion,
+				},
+			}
+
+			b, err := NewNativeBuilder(context.Background(), opts)
+			if err != nil {
+				t.Fatalf("setting up test %v", err)
+			}
+
+			outFile := &bytes.Buffer{}
+			_, err = b.Build(context.Background(), RuntimePlatform(), "v0.1.0", []Module{}, []string{}, outFile)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			if tc.expectError != nil {
+				return
+			}
+
+			info, err := buildinfo.Read(bytes.NewReader(outFile.Bytes()))
+			if err != nil {
+				t.Fatalf("reading binary build info %v", err)
+			}
+
+			if info.GoVersion != "go"+tc.goVersion {
+				t.Fatalf("expected go%s got %s", tc.goVersion, info.GoVersion)
+			}
+		})
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	if err := proxy.AddModVersion("go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	testCases := []struct {
+		title    string
+		checksum bool
+	}{
+		{
+			title:    "checksum requested",
+			checksum: true,
+		},
+		{
+			title:    "checksum not requested",
+			checksum: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			opts := NativeBuilderOpts{
+				GoOpts: GoOpts{
+					CopyGoEnv: true,
+					Env: map[string]string{
+						"GOPROXY":   goproxySrv.URL,
+						"GONOPROXY": "none",
+						"GOPRIVATE": "go.k6.io",
+						"GONOSUMDB": "go.k6.io",
+					},
+					TmpCache: true,
+				},
+				Checksum: tc.checksum,
+			}
+
+			b, err := NewNativeBuilder(context.Background(), opts)
+			if err != nil {
+				t.Fatalf("setting up test %v", err)
+			}
+
+			outFile := &bytes.Buffer{}
+			buildInfo, err := b.Build(context.Background(), RuntimePlatform(), "v0.1.0", []Module{}, []string{}, outFile)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			expected := ""
+			if tc.checksum {
+				expected = fmt.Sprintf("sha256:%x", sha256.Sum256(outFile.Bytes()))
+			}
+
+			if buildInfo.Checksum != expected {
+				t.Fatalf("expected %q got %q", expected, buildInfo.Checksum)
 			}
 		})
 	}
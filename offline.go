@@ -0,0 +1,133 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/mod/module"
+)
+
+// ErrMissingModules is returned by offline builds when modules are not in the module cache
+var ErrMissingModules = errors.New("modules missing from module cache")
+
+// missingModuleRegexp matches the modules go failed to download because GOPROXY is off
+var missingModuleRegexp = regexp.MustCompile( //nolint:gochecknoglobals
+	`(?:go: |package |providing package )([^\s:]+): module lookup disabled by GOPROXY=off`,
+)
+
+// MissingModulesError lists the modules that are not in the module cache in an offline build
+type MissingModulesError struct {
+	// modules (path@version) or packages that could not be found in the module cache
+	Modules []string
+}
+
+func (e *MissingModulesError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrMissingModules, strings.Join(e.Modules, ", "))
+}
+
+// Is allows matching the error with ErrMissingModules and ErrModuleNotFound
+func (e *MissingModulesError) Is(target error) bool {
+	return target == ErrMissingModules || target == ErrModuleNotFound
+}
+
+// offlineOpts returns the options for running go without network access
+func offlineOpts(opts GoOpts) GoOpts {
+	opts.Env = maps.Clone(opts.Env)
+	if opts.Env == nil {
+		opts.Env = map[string]string{}
+	}
+
+	opts.Env["GOPROXY"] = "off"
+	opts.Env["GOFLAGS"] = "-mod=mod"
+	// the checksum database can't be accessed. The modules in the cache were verified when downloaded
+	opts.Env["GOSUMDB"] = "off"
+
+	return opts
+}
+
+// checkCached returns a MissingModulesError if the modules are not in the module cache. Version queries
+// (e.g. latest or a branch) are reported as missing, as they can't be resolved without network access.
+// Modules replaced by local directories are not checked
+func checkCached(modCache string, mods []Module) error {
+	missing := []string{}
+
+	for _, mod := range mods {
+		path, version := mod.Path, mod.Version
+		if mod.ReplacePath != "" {
+			if isLocalReplace(mod.ReplacePath) {
+				continue
+			}
+			path, version = mod.ReplacePath, mod.ReplaceVersion
+		}
+
+		if version == "" {
+			version = "latest"
+		}
+
+		if module.CanonicalVersion(version) != version || !isCached(modCache, path, version) {
+			missing = append(missing, path+"@"+version)
+		}
+	}
+
+	if len(missing) > 0 {
+		return &MissingModulesError{Modules: missing}
+	}
+
+	return nil
+}
+
+// isCached returns true if the go.mod and the sources of the module version are in the module cache
+func isCached(modCache string, path string, version string) bool {
+	escPath, err := module.EscapePath(path)
+	if err != nil {
+		return false
+	}
+
+	escVersion, err := module.EscapeVersion(version)
+	if err != nil {
+		return false
+	}
+
+	versionDir := filepath.Join(modCache, "cache", "download", filepath.FromSlash(escPath), "@v")
+	for _, ext := range []string{".mod", ".zip"} {
+		if _, err = os.Stat(filepath.Join(versionDir, escVersion+ext)); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isLocalReplace returns true if the replacement is a local directory instead of a module
+func isLocalReplace(replace string) bool {
+	path, err := resolvePath(replace)
+	if err != nil {
+		return true
+	}
+
+	return filepath.IsAbs(path)
+}
+
+// missingModulesError returns the modules reported as missing in the output of a go command
+// executed with GOPROXY=off, or nil if there are none
+func missingModulesError(output string) error {
+	missing := []string{}
+	for _, match := range missingModuleRegexp.FindAllStringSubmatch(output, -1) {
+		if !slices.Contains(missing, match[1]) {
+			missing = append(missing, match[1])
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return &MissingModulesError{Modules: missing}
+}
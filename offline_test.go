@@ -0,0 +1,158 @@
+package k6foundry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestOfflineBuild(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	for _, mod := range []struct{ path, dir string }{
+		{"go.k6.io/k6", "k6"},
+		{"go.k6.io/k6ext", "k6ext"},
+	} {
+		if err := proxy.AddModVersion(mod.path, "v0.1.0", filepath.Join("testdata", "mods", mod.dir)); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	cacheDir := t.TempDir()
+	t.Cleanup(func() { _ = removeAll(cacheDir) })
+
+	opts := NativeBuilderOpts{
+		GoOpts: GoOpts{
+			CopyGoEnv: true,
+			Env: map[string]string{
+				"GOPROXY":   goproxySrv.URL,
+				"GONOPROXY": "none",
+				"GOPRIVATE": "go.k6.io",
+				"GONOSUMDB": "go.k6.io",
+			},
+			GoCacheDir: cacheDir,
+		},
+	}
+
+	// download k6 to the cache
+	b, err := NewNativeBuilder(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	_, err = b.Build(context.Background(), RuntimePlatform(), "v0.1.0", []Module{}, []string{}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	testCases := []struct {
+		title         string
+		k6Version     string
+		mods          []Module
+		expectError   error
+		expectMissing []string
+	}{
+		{
+			title:     "cached modules",
+			k6Version: "v0.1.0",
+		},
+		{
+			title:         "version not cached",
+			k6Version:     "v0.2.0",
+			expectError:   ErrMissingModules,
+			expectMissing: []string{"go.k6.io/k6@v0.2.0"},
+		},
+		{
+			title:         "module not cached",
+			k6Version:     "v0.1.0",
+			mods:          []Module{{Path: "go.k6.io/k6ext", Version: "v0.1.0"}},
+			expectError:   ErrMissingModules,
+			expectMissing: []string{"go.k6.io/k6ext@v0.1.0"},
+		},
+		{
+			title:         "version queries",
+			k6Version:     "latest",
+			mods:          []Module{{Path: "go.k6.io/k6ext", Version: "v0.1"}},
+			expectError:   ErrMissingModules,
+			expectMissing: []string{"go.k6.io/k6@latest", "go.k6.io/k6ext@v0.1"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			offline := opts
+			offline.Offline = true
+
+			b, err := NewNativeBuilder(context.Background(), offline)
+			if err != nil {
+				t.Fatalf("setup %v", err)
+			}
+
+			_, err = b.Build(context.Background(), RuntimePlatform(), tc.k6Version, tc.mods, []string{}, &bytes.Buffer{})
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			if tc.expectError == nil {
+				return
+			}
+
+			var missingErr *MissingModulesError
+			if !errors.As(err, &missingErr) {
+				t.Fatalf("expected MissingModulesError got %v", err)
+			}
+
+			if !slices.Equal(missingErr.Modules, tc.expectMissing) {
+				t.Fatalf("expected missing %v got %v", tc.expectMissing, missingErr.Modules)
+			}
+		})
+	}
+}
+
+func TestOfflineTmpCache(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewNativeBuilder(context.Background(), NativeBuilderOpts{GoOpts: GoOpts{Offline: true, TmpCache: true}})
+	if !errors.Is(err, ErrSettingGoEnv) {
+		t.Fatalf("expected %v got %v", ErrSettingGoEnv, err)
+	}
+}
+
+func TestMissingModulesError(t *testing.T) {
+	t.Parallel()
+
+	output := "go: finding module for package go.k6.io/k6ext\n" +
+		"go: go.k6.io/k6@v0.2.0: module lookup disabled by GOPROXY=off\n" +
+		"go: k6 imports\n\tgo.k6.io/k6ext: cannot find module providing package go.k6.io/k6ext: " +
+		"module lookup disabled by GOPROXY=off\n" +
+		"go: go.k6.io/k6@v0.2.0: module lookup disabled by GOPROXY=off\n"
+
+	err := goError(output)
+
+	var missingErr *MissingModulesError
+	if !errors.As(err, &missingErr) {
+		t.Fatalf("expected MissingModulesError got %v", err)
+	}
+
+	expect := []string{"go.k6.io/k6@v0.2.0", "go.k6.io/k6ext"}
+	if !slices.Equal(missingErr.Modules, expect) {
+		t.Fatalf("expected %v got %v", expect, missingErr.Modules)
+	}
+
+	if !errors.Is(err, ErrModuleNotFound) {
+		t.Fatalf("expected %v to match %v", err, ErrModuleNotFound)
+	}
+}
@@ -0,0 +1,55 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrInvalidPGOProfile is returned when the profile for profile-guided optimization can't be used
+var ErrInvalidPGOProfile = errors.New("invalid PGO profile")
+
+// pgoProfileFile is the name of the profile in the work directory, the name go uses for the profile of
+// the main package
+const pgoProfileFile = "default.pgo"
+
+// validatePGOProfile checks the profile is a regular file
+func validatePGOProfile(profile string) error {
+	if profile == "" {
+		return nil
+	}
+
+	info, err := os.Stat(profile)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPGOProfile, err)
+	}
+
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%w: %s is not a file", ErrInvalidPGOProfile, profile)
+	}
+
+	return nil
+}
+
+// addPGOProfile copies the profile into the work directory as default.pgo and adds the -pgo option to the
+// build opts. The go commands run in the work directory, so the option uses a relative path, which is
+// also valid in a container. If the build opts set -pgo, they take precedence and the profile is ignored
+func addPGOProfile(workDir string, buildOpts []string, profile string) ([]string, error) {
+	if profile == "" || slices.ContainsFunc(buildOpts, isPGOFlag) {
+		return buildOpts, nil
+	}
+
+	if err := copyFile(profile, filepath.Join(workDir, pgoProfileFile)); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPGOProfile, err)
+	}
+
+	return append(slices.Clone(buildOpts), "-pgo="+pgoProfileFile), nil
+}
+
+func isPGOFlag(opt string) bool {
+	return opt == "-pgo" || strings.HasPrefix(opt, "-pgo=")
+}
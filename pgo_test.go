@@ -0,0 +1,193 @@
+package k6foundry
+
+import (
+	"bytes"
+	"context"
+	"debug/buildinfo"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime/pprof"
+	"testing"
+	"time"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestValidatePGOProfile(t *testing.T) {
+	t.Parallel()
+
+	profile := filepath.Join(t.TempDir(), "cpu.pprof")
+	if err := os.WriteFile(profile, []byte("profile"), 0o600); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	testCases := []struct {
+		title     string
+		profile   string
+		expectErr error
+	}{
+		{title: "no profile", profile: ""},
+		{title: "profile", profile: profile},
+		{title: "missing profile", profile: filepath.Join(t.TempDir(), "missing.pprof"), expectErr: ErrInvalidPGOProfile},
+		{title: "directory", profile: t.TempDir(), expectErr: ErrInvalidPGOProfile},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			if err := validatePGOProfile(tc.profile); !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestAddPGOProfile(t *testing.T) {
+	t.Parallel()
+
+	profile := filepath.Join(t.TempDir(), "cpu.pprof")
+	if err := os.WriteFile(profile, []byte("profile"), 0o600); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	testCases := []struct {
+		title      string
+		profile    string
+		buildOpts  []string
+		expect     []string
+		expectCopy bool
+		expectErr  error
+	}{
+		{
+			title:     "no profile",
+			buildOpts: []string{"-trimpath"},
+			expect:    []string{"-trimpath"},
+		},
+		{
+			title:      "profile",
+			profile:    profile,
+			buildOpts:  []string{"-trimpath"},
+			expect:     []string{"-trimpath", "-pgo=default.pgo"},
+			expectCopy: true,
+		},
+		{
+			title:     "pgo in build opts",
+			profile:   profile,
+			buildOpts: []string{"-pgo=off"},
+			expect:    []string{"-pgo=off"},
+		},
+		{
+			title:     "missing profile",
+			profile:   filepath.Join(t.TempDir(), "missing.pprof"),
+			expectErr: ErrInvalidPGOProfile,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			workDir := t.TempDir()
+			buildOpts, err := addPGOProfile(workDir, tc.buildOpts, tc.profile)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(buildOpts, tc.expect) {
+				t.Fatalf("expected %v got %v", tc.expect, buildOpts)
+			}
+
+			content, err := os.ReadFile(filepath.Join(workDir, pgoProfileFile))
+			if tc.expectCopy != (err == nil) {
+				t.Fatalf("expected profile copied %t got %v", tc.expectCopy, err)
+			}
+
+			if tc.expectCopy && string(content) != "profile" {
+				t.Fatalf("expected profile content got %q", content)
+			}
+		})
+	}
+}
+
+// writeCPUProfile writes a CPU profile of the test process
+func writeCPUProfile(t *testing.T) string {
+	t.Helper()
+
+	profile := filepath.Join(t.TempDir(), "cpu.pprof")
+	f, err := os.Create(profile) //nolint:forbidigo
+	if err != nil {
+		t.Fatalf("setup %v", err)
+	}
+	defer f.Close() //nolint:errcheck
+
+	if err = pprof.StartCPUProfile(f); err != nil {
+		t.Skipf("CPU profiling not available: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	pprof.StopCPUProfile()
+
+	return profile
+}
+
+func TestPGOBuild(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	if err := proxy.AddModVersion("go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	opts := NativeBuilderOpts{
+		GoOpts: GoOpts{
+			CopyGoEnv: true,
+			Env: map[string]string{
+				"GOPROXY":   goproxySrv.URL,
+				"GONOPROXY": "none",
+				"GOPRIVATE": "go.k6.io",
+				"GONOSUMDB": "go.k6.io",
+			},
+			TmpCache:   true,
+			PGOProfile: writeCPUProfile(t),
+		},
+	}
+
+	b, err := NewNativeBuilder(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("setting up test %v", err)
+	}
+
+	outFile := &bytes.Buffer{}
+	_, err = b.Build(context.Background(), RuntimePlatform(), "v0.1.0", []Module{}, []string{}, outFile)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	info, err := buildinfo.Read(bytes.NewReader(outFile.Bytes()))
+	if err != nil {
+		t.Fatalf("reading binary build info %v", err)
+	}
+
+	pgo := ""
+	for _, setting := range info.Settings {
+		if setting.Key == "-pgo" {
+			pgo = setting.Value
+		}
+	}
+
+	if filepath.Base(pgo) != pgoProfileFile {
+		t.Fatalf("expected build with %s got %q", pgoProfileFile, pgo)
+	}
+}
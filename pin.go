@@ -0,0 +1,133 @@
+package k6foundry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// ParseModuleVersion parses a module with an exact version from a string of the form path@version,
+// as used for pinning and excluding versions of modules
+func ParseModuleVersion(modString string) (Module, error) {
+	path, version, _ := strings.Cut(modString, "@")
+
+	mod := Module{Path: path, Version: semver.Canonical(version)}
+	if err := validateModuleVersion(mod); err != nil {
+		return Module{}, fmt.Errorf("%w: %q", err, modString)
+	}
+
+	return mod, nil
+}
+
+// ParseModuleVersions parses a list of modules in the format accepted by ParseModuleVersion
+func ParseModuleVersions(modStrings []string) ([]Module, error) {
+	mods := make([]Module, 0, len(modStrings))
+	for _, modString := range modStrings {
+		mod, err := ParseModuleVersion(modString)
+		if err != nil {
+			return nil, err
+		}
+		mods = append(mods, mod)
+	}
+
+	return mods, nil
+}
+
+// validateModuleVersion checks the module has a path and a version, and no replacement
+func validateModuleVersion(mod Module) error {
+	if err := module.CheckPath(mod.Path); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidDependencyFormat, err)
+	}
+
+	if !semver.IsValid(mod.Version) || semver.Canonical(mod.Version) != mod.Version {
+		return fmt.Errorf("%w: %s requires a version", ErrInvalidDependencyFormat, mod.Path)
+	}
+
+	if mod.ReplacePath != "" {
+		return fmt.Errorf("%w: %s can't have a replacement", ErrInvalidDependencyFormat, mod.Path)
+	}
+
+	return nil
+}
+
+// validatePins checks the pinned and excluded modules have a version, each module is pinned once and
+// pinned versions are not excluded
+func validatePins(pins []Module, excludes []Module) error {
+	pinned := map[string]string{}
+	for _, pin := range pins {
+		if err := validateModuleVersion(pin); err != nil {
+			return err
+		}
+
+		if version, found := pinned[pin.Path]; found && version != pin.Version {
+			return fmt.Errorf("%w: %s pinned at %s and %s", ErrConflictingDependencies, pin.Path, version, pin.Version)
+		}
+		pinned[pin.Path] = pin.Version
+	}
+
+	for _, exclude := range excludes {
+		if err := validateModuleVersion(exclude); err != nil {
+			return err
+		}
+
+		if pinned[exclude.Path] == exclude.Version {
+			return fmt.Errorf("%w: %s pinned and excluded", ErrConflictingDependencies, exclude)
+		}
+	}
+
+	return nil
+}
+
+// addExcludes adds the excluded versions to the go.mod, so they are skipped when resolving k6 and the extensions
+func (b *nativeBuilder) addExcludes(ctx context.Context, e *goEnv) error {
+	for _, exclude := range b.Excludes {
+		b.log.Info(fmt.Sprintf("excluding %s", exclude))
+
+		err := e.runGo(ctx, e.getTimeout, "mod", "edit", "-exclude", exclude.Path+"@"+exclude.Version)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrResolvingDependency, err)
+		}
+	}
+
+	return nil
+}
+
+// addPins requires the pinned versions after resolving k6 and the extensions. Pins of modules not used by
+// the build are ignored. Fails with ErrConflictingDependencies if a module requires a newer version
+// than the pinned one, as go uses the highest version required
+func (b *nativeBuilder) addPins(ctx context.Context, e *goEnv) error {
+	if len(b.Pins) == 0 {
+		return nil
+	}
+
+	for _, pin := range b.Pins {
+		b.log.Info(fmt.Sprintf("pinning %s", pin))
+
+		if err := e.modRequire(ctx, pin.Path, pin.Version); err != nil {
+			return err
+		}
+	}
+
+	if err := e.modTidy(ctx); err != nil {
+		return err
+	}
+
+	requires, err := e.modRequires()
+	if err != nil {
+		return err
+	}
+
+	for _, pin := range b.Pins {
+		if version, found := requires[pin.Path]; found && version != pin.Version {
+			return fmt.Errorf(
+				"%w: %s pinned at %s but %s is required. Use a replacement for forcing a version",
+				ErrConflictingDependencies, pin.Path, pin.Version, version,
+			)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package k6foundry
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestParseModuleVersion(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		mod         string
+		expectError error
+		expect      Module
+	}{
+		{
+			title:  "module version",
+			mod:    "golang.org/x/net@v0.23.0",
+			expect: Module{Path: "golang.org/x/net", Version: "v0.23.0"},
+		},
+		{
+			title:  "incomplete version",
+			mod:    "golang.org/x/net@v0.23",
+			expect: Module{Path: "golang.org/x/net", Version: "v0.23.0"},
+		},
+		{
+			title:       "missing version",
+			mod:         "golang.org/x/net",
+			expectError: ErrInvalidDependencyFormat,
+		},
+		{
+			title:       "latest",
+			mod:         "golang.org/x/net@latest",
+			expectError: ErrInvalidDependencyFormat,
+		},
+		{
+			title:       "invalid path",
+			mod:         "golang.org/x/net/@v0.23.0",
+			expectError: ErrInvalidDependencyFormat,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			mod, err := ParseModuleVersion(tc.mod)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			if tc.expectError == nil && mod != tc.expect {
+				t.Fatalf("expected %v got %v", tc.expect, mod)
+			}
+		})
+	}
+}
+
+func TestValidatePins(t *testing.T) {
+	t.Parallel()
+
+	net23 := Module{Path: "golang.org/x/net", Version: "v0.23.0"}
+	net22 := Module{Path: "golang.org/x/net", Version: "v0.22.0"}
+
+	testCases := []struct {
+		title       string
+		pins        []Module
+		excludes    []Module
+		expectError error
+	}{
+		{
+			title:    "pin and exclude",
+			pins:     []Module{net23},
+			excludes: []Module{net22},
+		},
+		{
+			title:       "pinned twice",
+			pins:        []Module{net23, net22},
+			expectError: ErrConflictingDependencies,
+		},
+		{
+			title:       "pinned and excluded",
+			pins:        []Module{net23},
+			excludes:    []Module{net23},
+			expectError: ErrConflictingDependencies,
+		},
+		{
+			title:       "replacement",
+			pins:        []Module{{Path: "golang.org/x/net", Version: "v0.23.0", ReplacePath: "../net"}},
+			expectError: ErrInvalidDependencyFormat,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			if err := validatePins(tc.pins, tc.excludes); !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestPinsAndExcludes(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	for _, m := range []struct{ path, version, source string }{
+		{"go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")},
+		{"go.k6.io/k6", "v0.2.0", filepath.Join("testdata", "mods", "k6")},
+		{"go.k6.io/k6ext", "v0.1.0", filepath.Join("testdata", "mods", "k6ext")},
+		{"go.k6.io/k6ext2", "v0.1.0", filepath.Join("testdata", "mods", "k6ext2")},
+	} {
+		if err := proxy.AddModVersion(m.path, m.version, m.source); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	testCases := []struct {
+		title       string
+		k6Version   string
+		ext         string
+		pins        []Module
+		excludes    []Module
+		expectError error
+		expectK6    string
+	}{
+		{
+			title:     "pin newer version",
+			k6Version: "v0.1.0",
+			ext:       "go.k6.io/k6ext",
+			pins:      []Module{{Path: "go.k6.io/k6", Version: "v0.2.0"}},
+			expectK6:  "v0.2.0",
+		},
+		{
+			title:       "pin older version than required",
+			k6Version:   "v0.1.0",
+			ext:         "go.k6.io/k6ext2",
+			pins:        []Module{{Path: "go.k6.io/k6", Version: "v0.1.0"}},
+			expectError: ErrConflictingDependencies,
+		},
+		{
+			title:     "pin of unused module",
+			k6Version: "v0.1.0",
+			ext:       "go.k6.io/k6ext",
+			pins:      []Module{{Path: "go.k6.io/unused", Version: "v0.1.0"}},
+			expectK6:  "v0.1.0",
+		},
+		{
+			title:     "exclude latest version",
+			k6Version: "latest",
+			ext:       "go.k6.io/k6ext",
+			excludes:  []Module{{Path: "go.k6.io/k6", Version: "v0.2.0"}},
+			expectK6:  "v0.1.0",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := NewNativeBuilder(context.Background(), NativeBuilderOpts{
+				GoOpts: GoOpts{
+					CopyGoEnv: true,
+					Env: map[string]string{
+						"GOPROXY":   goproxySrv.URL,
+						"GONOPROXY": "none",
+						"GOPRIVATE": "go.k6.io",
+						"GONOSUMDB": "go.k6.io",
+					},
+					TmpCache: true,
+				},
+				Pins:     tc.pins,
+				Excludes: tc.excludes,
+			})
+			if err != nil {
+				t.Fatalf("setting up test %v", err)
+			}
+
+			resolution, err := b.(Resolver).Resolve(
+				context.Background(),
+				tc.k6Version,
+				[]Module{{Path: tc.ext, Version: "v0.1.0"}},
+			)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			if tc.expectError != nil {
+				return
+			}
+
+			if k6 := resolution.BuildInfo.ModVersions["go.k6.io/k6"]; k6 != tc.expectK6 {
+				t.Fatalf("expected k6 %s got %s", tc.expectK6, k6)
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+// Package cache implements a cache for the binaries produced by a k6foundry.Builder
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/grafana/k6foundry"
+	"golang.org/x/mod/semver"
+)
+
+var (
+	// ErrNotFound is returned when the key is not in the cache
+	ErrNotFound = errors.New("not found in cache")
+	// ErrAccessingCache is returned when the cache can't be read or written
+	ErrAccessingCache = errors.New("accessing cache")
+)
+
+// Cache stores the binaries produced by builds, indexed by a key that identifies the build
+type Cache interface {
+	// Get writes the binary stored with the key into the out io.Writer and returns its build info.
+	// Returns ErrNotFound if the key is not in the cache.
+	Get(ctx context.Context, key string, out io.Writer) (*k6foundry.BuildInfo, error)
+	// Put stores the binary and its build info with the key
+	Put(ctx context.Context, key string, binary io.Reader, info *k6foundry.BuildInfo) error
+}
+
+// cachedBuilder returns the binaries from the cache when available, building them otherwise
+type cachedBuilder struct {
+	inner   k6foundry.Builder
+	cache   Cache
+	config  string
+	metrics k6foundry.Metrics
+}
+
+// CachedBuilderOpts defines the options of a cached builder
+type CachedBuilderOpts struct {
+	// digest of the configuration of the inner builder (see k6foundry.NativeBuilderOpts.ConfigDigest),
+	// included in the keys so builders with different configurations can share a cache
+	Config string
+	// records the hits and misses of the cache. Optional
+	Metrics k6foundry.Metrics
+}
+
+// NewCachedBuilder returns a Builder that returns previously built binaries from the cache instead
+// of building them using the inner builder.
+//
+// Builds that depend on mutable versions (latest or local replacements) are not cached.
+// The cache doesn't consider the configuration of the inner builder (e.g. environment variables), so
+// builders with different configurations should not share a cache. Use NewCachedBuilderWithOpts with
+// the digest of the configuration for sharing it.
+func NewCachedBuilder(inner k6foundry.Builder, cache Cache) k6foundry.Builder {
+	return NewCachedBuilderWithOpts(inner, cache, CachedBuilderOpts{})
+}
+
+// NewCachedBuilderWithMetrics returns a cached builder that records the hits and misses of the cache
+func NewCachedBuilderWithMetrics(inner k6foundry.Builder, cache Cache, metrics k6foundry.Metrics) k6foundry.Builder {
+	return NewCachedBuilderWithOpts(inner, cache, CachedBuilderOpts{Metrics: metrics})
+}
+
+// NewCachedBuilderWithOpts returns a cached builder with the given options
+func NewCachedBuilderWithOpts(inner k6foundry.Builder, cache Cache, opts CachedBuilderOpts) k6foundry.Builder {
+	return &cachedBuilder{inner: inner, cache: cache, config: opts.Config, metrics: opts.Metrics}
+}
+
+func (b *cachedBuilder) Build(
+	ctx context.Context,
+	platform k6foundry.Platform,
+	k6Version string,
+	mods []k6foundry.Module,
+	buildOpts []string,
+	out io.Writer,
+) (*k6foundry.BuildInfo, error) {
+	if !Cacheable(k6Version, mods) {
+		return b.inner.Build(ctx, platform, k6Version, mods, buildOpts, out)
+	}
+
+	key := Key(platform, k6Version, mods, buildOpts, b.config)
+
+	counter := &countingWriter{out: out}
+	info, err := b.cache.Get(ctx, key, counter)
+	if err == nil {
+		b.observeLookup(true)
+		// entries stored by previous versions don't have the size
+		info.Size = counter.written
+		return info, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	b.observeLookup(false)
+
+	// keep the binary in a temporary file to copy it to the output and to the cache
+	tmp, err := os.CreateTemp("", "k6foundry-cache*") //nolint:forbidigo
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAccessingCache, err)
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name()) //nolint:forbidigo
+	}()
+
+	info, err = b.inner.Build(ctx, platform, k6Version, mods, buildOpts, tmp)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAccessingCache, err)
+	}
+
+	if err = b.cache.Put(ctx, key, tmp, info); err != nil {
+		return nil, err
+	}
+
+	if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAccessingCache, err)
+	}
+
+	if _, err = io.Copy(out, tmp); err != nil {
+		return nil, fmt.Errorf("copying binary %w", err)
+	}
+
+	return info, nil
+}
+
+// countingWriter counts the bytes written
+type countingWriter struct {
+	out     io.Writer
+	written int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.out.Write(p)
+	w.written += int64(n)
+
+	return n, err
+}
+
+func (b *cachedBuilder) observeLookup(hit bool) {
+	if b.metrics != nil {
+		b.metrics.ObserveCacheLookup(hit)
+	}
+}
+
+// Cacheable returns true if the result of the build can be cached. Builds are cacheable only if
+// k6 and all the dependencies reference specific versions.
+func Cacheable(k6Version string, mods []k6foundry.Module) bool {
+	if !semver.IsValid(k6Version) {
+		return false
+	}
+
+	for _, mod := range mods {
+		if !semver.IsValid(mod.Version) {
+			return false
+		}
+
+		// local replacements or replacements without version can change between builds
+		if mod.ReplacePath != "" && !semver.IsValid(mod.ReplaceVersion) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Key returns the key that identifies a build with the configuration of the builder (see CachedBuilderOpts).
+// The key doesn't depend on the order of the modules.
+func Key(
+	platform k6foundry.Platform,
+	k6Version string,
+	mods []k6foundry.Module,
+	buildOpts []string,
+	config string,
+) string {
+	sorted := slices.Clone(mods)
+	slices.SortFunc(sorted, func(a, b k6foundry.Module) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
+	// marshalling these values can't fail
+	content, _ := json.Marshal(struct {
+		Platform  string
+		K6Version string
+		Mods      []k6foundry.Module
+		BuildOpts []string
+		Config    string `json:",omitempty"`
+	}{
+		Platform:  platform.String(),
+		K6Version: k6Version,
+		Mods:      sorted,
+		BuildOpts: buildOpts,
+		Config:    config,
+	})
+
+	hash := sha256.Sum256(content)
+
+	return hex.EncodeToString(hash[:])
+}
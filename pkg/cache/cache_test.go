@@ -0,0 +1,384 @@
+package cache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/k6foundry"
+)
+
+// countingBuilder returns a fixed binary and counts the builds
+type countingBuilder struct {
+	builds int
+}
+
+func (b *countingBuilder) Build(
+	_ context.Context,
+	platform k6foundry.Platform,
+	k6Version string,
+	_ []k6foundry.Module,
+	_ []string,
+	out io.Writer,
+) (*k6foundry.BuildInfo, error) {
+	b.builds++
+	_, err := out.Write([]byte("binary"))
+
+	return &k6foundry.BuildInfo{
+		Platform:    platform.String(),
+		ModVersions: map[string]string{"go.k6.io/k6": k6Version},
+	}, err
+}
+
+func TestCachedBuilder(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		k6Version string
+		mods      [][]k6foundry.Module
+		expect    int
+	}{
+		{
+			title:     "same build",
+			k6Version: "v0.1.0",
+			mods: [][]k6foundry.Module{
+				{{Path: "go.k6.io/k6ext", Version: "v0.1.0"}},
+				{{Path: "go.k6.io/k6ext", Version: "v0.1.0"}},
+			},
+			expect: 1,
+		},
+		{
+			title:     "different module versions",
+			k6Version: "v0.1.0",
+			mods: [][]k6foundry.Module{
+				{{Path: "go.k6.io/k6ext", Version: "v0.1.0"}},
+				{{Path: "go.k6.io/k6ext", Version: "v0.2.0"}},
+			},
+			expect: 2,
+		},
+		{
+			title:     "modules in different order",
+			k6Version: "v0.1.0",
+			mods: [][]k6foundry.Module{
+				{{Path: "go.k6.io/k6ext", Version: "v0.1.0"}, {Path: "go.k6.io/k6ext2", Version: "v0.1.0"}},
+				{{Path: "go.k6.io/k6ext2", Version: "v0.1.0"}, {Path: "go.k6.io/k6ext", Version: "v0.1.0"}},
+			},
+			expect: 1,
+		},
+		{
+			title:     "latest k6 is not cached",
+			k6Version: "latest",
+			mods:      [][]k6foundry.Module{{}, {}},
+			expect:    2,
+		},
+		{
+			title:     "local replace is not cached",
+			k6Version: "v0.1.0",
+			mods: [][]k6foundry.Module{
+				{{Path: "go.k6.io/k6ext", Version: "v0.1.0", ReplacePath: "../k6ext"}},
+				{{Path: "go.k6.io/k6ext", Version: "v0.1.0", ReplacePath: "../k6ext"}},
+			},
+			expect: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			inner := &countingBuilder{}
+			builder := NewCachedBuilder(inner, NewFileCache(t.TempDir()))
+			platform, _ := k6foundry.ParsePlatform("linux/amd64")
+
+			for _, mods := range tc.mods {
+				out := &bytes.Buffer{}
+				info, err := builder.Build(context.Background(), platform, tc.k6Version, mods, nil, out)
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+
+				if out.String() != "binary" {
+					t.Fatalf("unexpected binary %q", out.String())
+				}
+
+				if info.ModVersions["go.k6.io/k6"] != tc.k6Version {
+					t.Fatalf("unexpected build info %v", info)
+				}
+			}
+
+			if inner.builds != tc.expect {
+				t.Fatalf("expected %d builds got %d", tc.expect, inner.builds)
+			}
+		})
+	}
+}
+
+func TestCachedBuilderSize(t *testing.T) {
+	t.Parallel()
+
+	builder := NewCachedBuilder(&countingBuilder{}, NewFileCache(t.TempDir()))
+	platform, _ := k6foundry.ParsePlatform("linux/amd64")
+
+	// stores the binary in the cache
+	if _, err := builder.Build(context.Background(), platform, "v0.1.0", nil, nil, io.Discard); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	info, err := builder.Build(context.Background(), platform, "v0.1.0", nil, nil, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if info.Size != int64(len("binary")) {
+		t.Fatalf("expected size %d got %d", len("binary"), info.Size)
+	}
+}
+
+func TestFileCacheNotFound(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewFileCache(t.TempDir()).Get(context.Background(), "missing", io.Discard)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected %v got %v", ErrNotFound, err)
+	}
+}
+
+// writeFileCache creates a cache with the entries "old", "used" and "new", with binaries of the same
+// size. The "old" and "used" entries were stored two days ago, and the "used" entry was used since
+func writeFileCache(t *testing.T) *FileCache {
+	t.Helper()
+
+	cache := NewFileCache(t.TempDir())
+	for _, key := range []string{"old", "used", "new"} {
+		info := &k6foundry.BuildInfo{Platform: "linux/amd64"}
+		if err := cache.Put(context.Background(), key, bytes.NewReader(make([]byte, 1000)), info); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	stored := time.Now().Add(-48 * time.Hour)
+	for _, key := range []string{"old", "used"} {
+		if err := os.Chtimes(filepath.Join(cache.dir, key), stored, stored); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	if _, err := cache.Get(context.Background(), "used", io.Discard); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	return cache
+}
+
+func TestFileCachePrune(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title        string
+		maxAge       time.Duration
+		maxEntries   int
+		expectKept   []string
+		expectPruned []string
+	}{
+		{
+			title:      "no limits",
+			expectKept: []string{"old", "used", "new"},
+		},
+		{
+			title:        "max age",
+			maxAge:       24 * time.Hour,
+			expectKept:   []string{"used", "new"},
+			expectPruned: []string{"old"},
+		},
+		{
+			title:        "max size",
+			maxEntries:   1,
+			expectKept:   []string{"used"},
+			expectPruned: []string{"old", "new"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			cache := writeFileCache(t)
+
+			stats, err := cache.Stats()
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if stats.Entries != 3 {
+				t.Fatalf("expected 3 entries got %d", stats.Entries)
+			}
+
+			entrySize := stats.Size / 3
+			removed, err := cache.Prune(tc.maxAge, int64(tc.maxEntries)*entrySize)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if removed.Entries != len(tc.expectPruned) || removed.Size != int64(len(tc.expectPruned))*entrySize {
+				t.Fatalf("expected %d entries removed got %v", len(tc.expectPruned), removed)
+			}
+
+			for _, key := range tc.expectKept {
+				if _, err = cache.Get(context.Background(), key, io.Discard); err != nil {
+					t.Fatalf("expected %s kept got %v", key, err)
+				}
+			}
+
+			for _, key := range tc.expectPruned {
+				if _, err = cache.Get(context.Background(), key, io.Discard); !errors.Is(err, ErrNotFound) {
+					t.Fatalf("expected %s pruned got %v", key, err)
+				}
+			}
+		})
+	}
+}
+
+func TestFileCacheClean(t *testing.T) {
+	t.Parallel()
+
+	cache := writeFileCache(t)
+
+	// partial entry left by an interrupted build
+	if err := os.Mkdir(filepath.Join(cache.dir, tmpPrefix+"partial"), 0o750); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	if err := cache.Clean(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	entries, err := os.ReadDir(cache.dir)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if len(entries) != 0 {
+		t.Fatalf("expected empty cache got %d entries", len(entries))
+	}
+}
+
+// lookupMetrics counts the hits and misses of the cache
+type lookupMetrics struct {
+	hits   int
+	misses int
+}
+
+func (m *lookupMetrics) ObservePhase(k6foundry.Phase, string, time.Duration, string) {}
+
+func (m *lookupMetrics) ObserveBinarySize(string, int64) {}
+
+func (m *lookupMetrics) ObserveCacheLookup(hit bool) {
+	if hit {
+		m.hits++
+	} else {
+		m.misses++
+	}
+}
+
+func TestCachedBuilderMetrics(t *testing.T) {
+	t.Parallel()
+
+	metrics := &lookupMetrics{}
+	builder := NewCachedBuilderWithMetrics(&countingBuilder{}, NewFileCache(t.TempDir()), metrics)
+	platform, _ := k6foundry.ParsePlatform("linux/amd64")
+
+	for _, k6Version := range []string{"v0.1.0", "v0.1.0", "latest"} {
+		if _, err := builder.Build(context.Background(), platform, k6Version, nil, nil, io.Discard); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+
+	// builds that are not cacheable are not looked up
+	if metrics.hits != 1 || metrics.misses != 1 {
+		t.Fatalf("expected 1 hit and 1 miss got %d hits and %d misses", metrics.hits, metrics.misses)
+	}
+}
+
+func TestCachedBuilderConfig(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title  string
+		opts   []k6foundry.NativeBuilderOpts
+		expect int
+	}{
+		{
+			title:  "same configuration",
+			opts:   []k6foundry.NativeBuilderOpts{{}, {}},
+			expect: 1,
+		},
+		{
+			title: "k6 fork",
+			opts: []k6foundry.NativeBuilderOpts{
+				{},
+				{K6Repo: "github.com/my-org/k6", K6RepoVersion: "v0.1.0"},
+			},
+			expect: 2,
+		},
+		{
+			title: "build tags",
+			opts: []k6foundry.NativeBuilderOpts{
+				{GoOpts: k6foundry.GoOpts{BuildTags: []string{"netgo"}}},
+				{GoOpts: k6foundry.GoOpts{BuildTags: []string{"osusergo"}}},
+			},
+			expect: 2,
+		},
+		{
+			title: "linker flags",
+			opts: []k6foundry.NativeBuilderOpts{
+				{},
+				{GoOpts: k6foundry.GoOpts{LDFlags: []string{"-s"}}},
+			},
+			expect: 2,
+		},
+		{
+			title: "environment variables",
+			opts: []k6foundry.NativeBuilderOpts{
+				{GoOpts: k6foundry.GoOpts{Env: map[string]string{"CGO_ENABLED": "0"}}},
+				{GoOpts: k6foundry.GoOpts{Env: map[string]string{"CGO_ENABLED": "1"}}},
+			},
+			expect: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			inner := &countingBuilder{}
+			fileCache := NewFileCache(t.TempDir())
+			platform, _ := k6foundry.ParsePlatform("linux/amd64")
+
+			// the builders share the cache
+			for _, opts := range tc.opts {
+				config, err := opts.ConfigDigest()
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+
+				builder := NewCachedBuilderWithOpts(inner, fileCache, CachedBuilderOpts{Config: config})
+				if _, err = builder.Build(context.Background(), platform, "v0.1.0", nil, nil, io.Discard); err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			}
+
+			if inner.builds != tc.expect {
+				t.Fatalf("expected %d builds got %d", tc.expect, inner.builds)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+//nolint:forbidigo
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/k6foundry"
+)
+
+const (
+	binaryFile    = "k6"
+	buildInfoFile = "buildinfo.json"
+	tmpPrefix     = ".tmp-"
+)
+
+// FileCache is a Cache that stores the binaries in a directory of the local filesystem.
+// Each entry is a subdirectory named after the key with the binary and its build info.
+// The modification time of the subdirectory is the last time the entry was used.
+type FileCache struct {
+	dir string
+}
+
+// NewFileCache returns a FileCache that stores the binaries in the given directory
+func NewFileCache(dir string) *FileCache {
+	return &FileCache{dir: dir}
+}
+
+// Get writes the binary stored with the key into the out io.Writer and returns its build info
+func (c *FileCache) Get(_ context.Context, key string, out io.Writer) (*k6foundry.BuildInfo, error) {
+	entry := filepath.Join(c.dir, key)
+
+	content, err := os.ReadFile(filepath.Join(entry, buildInfoFile)) //nolint:gosec
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAccessingCache, err)
+	}
+
+	info := &k6foundry.BuildInfo{}
+	if err = json.Unmarshal(content, info); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAccessingCache, err)
+	}
+
+	binary, err := os.Open(filepath.Join(entry, binaryFile)) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAccessingCache, err)
+	}
+	defer binary.Close() //nolint:errcheck
+
+	if _, err = io.Copy(out, binary); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAccessingCache, err)
+	}
+
+	// marks the entry as used, so it is pruned after the least recently used ones
+	now := time.Now()
+	_ = os.Chtimes(entry, now, now)
+
+	return info, nil
+}
+
+// Put stores the binary and its build info with the key.
+// The entry is written in a temporary directory and then renamed, so concurrent readers never
+// see a partial entry.
+func (c *FileCache) Put(_ context.Context, key string, binary io.Reader, info *k6foundry.BuildInfo) error {
+	if err := os.MkdirAll(c.dir, 0o750); err != nil {
+		return fmt.Errorf("%w: %w", ErrAccessingCache, err)
+	}
+
+	tmpDir, err := os.MkdirTemp(c.dir, tmpPrefix+key)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrAccessingCache, err)
+	}
+	defer os.RemoveAll(tmpDir) //nolint:errcheck
+
+	if err = writeFile(filepath.Join(tmpDir, binaryFile), binary, 0o755); err != nil {
+		return fmt.Errorf("%w: %w", ErrAccessingCache, err)
+	}
+
+	content, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrAccessingCache, err)
+	}
+
+	if err = os.WriteFile(filepath.Join(tmpDir, buildInfoFile), content, 0o600); err != nil {
+		return fmt.Errorf("%w: %w", ErrAccessingCache, err)
+	}
+
+	entry := filepath.Join(c.dir, key)
+	if err = os.Rename(tmpDir, entry); err != nil {
+		// another build stored the same entry concurrently
+		if _, statErr := os.Stat(entry); statErr == nil {
+			return nil
+		}
+
+		return fmt.Errorf("%w: %w", ErrAccessingCache, err)
+	}
+
+	return nil
+}
+
+// Stats returns the number of entries in the cache and their size
+func (c *FileCache) Stats() (k6foundry.CacheStats, error) {
+	entries, err := c.entries()
+	if err != nil {
+		return k6foundry.CacheStats{}, err
+	}
+
+	stats := k6foundry.CacheStats{Entries: len(entries)}
+	for _, entry := range entries {
+		stats.Size += entry.size
+	}
+
+	return stats, nil
+}
+
+// Prune removes the entries not used in the last maxAge, and then the least recently used entries until
+// the size of the cache is at most maxSize. A maxAge or maxSize of zero or less disables the corresponding
+// limit. Returns the removed entries
+func (c *FileCache) Prune(maxAge time.Duration, maxSize int64) (k6foundry.CacheStats, error) {
+	removed := k6foundry.CacheStats{}
+
+	entries, err := c.entries()
+	if err != nil {
+		return removed, err
+	}
+
+	total := int64(0)
+	for _, entry := range entries {
+		total += entry.size
+	}
+
+	cutoff := time.Time{}
+	if maxAge > 0 {
+		cutoff = time.Now().Add(-maxAge)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].lastUse.Before(entries[j].lastUse)
+	})
+
+	for _, entry := range entries {
+		if !entry.lastUse.Before(cutoff) && (maxSize <= 0 || total-removed.Size <= maxSize) {
+			break
+		}
+
+		if err = os.RemoveAll(entry.path); err != nil {
+			return removed, fmt.Errorf("%w: %w", ErrAccessingCache, err)
+		}
+
+		removed.Entries++
+		removed.Size += entry.size
+	}
+
+	return removed, nil
+}
+
+// Clean removes all the entries of the cache, including the partial entries left by interrupted builds
+func (c *FileCache) Clean() error {
+	entries, err := os.ReadDir(c.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrAccessingCache, err)
+	}
+
+	for _, entry := range entries {
+		if err = os.RemoveAll(filepath.Join(c.dir, entry.Name())); err != nil {
+			return fmt.Errorf("%w: %w", ErrAccessingCache, err)
+		}
+	}
+
+	return nil
+}
+
+// fileEntry is an entry of the cache
+type fileEntry struct {
+	path    string
+	size    int64
+	lastUse time.Time
+}
+
+// entries returns the complete entries of the cache
+func (c *FileCache) entries() ([]fileEntry, error) {
+	dirs, err := os.ReadDir(c.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAccessingCache, err)
+	}
+
+	entries := []fileEntry{}
+	for _, dir := range dirs {
+		if !dir.IsDir() || strings.HasPrefix(dir.Name(), tmpPrefix) {
+			continue
+		}
+
+		var info fs.FileInfo
+		if info, err = dir.Info(); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrAccessingCache, err)
+		}
+
+		entry := fileEntry{path: filepath.Join(c.dir, dir.Name()), lastUse: info.ModTime()}
+		for _, name := range []string{binaryFile, buildInfoFile} {
+			if info, err = os.Stat(filepath.Join(entry.path, name)); err == nil {
+				entry.size += info.Size()
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func writeFile(path string, content io.Reader, mode fs.FileMode) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode) //nolint:gosec
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(file, content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
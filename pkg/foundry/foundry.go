@@ -0,0 +1,291 @@
+// Package foundry builds custom k6 binaries and post-processes them (SBOM, checksum, provenance,
+// signatures, packages) in a single call, so services don't need to wire the builder and
+// the output generators themselves.
+//
+//nolint:forbidigo
+package foundry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grafana/k6foundry"
+	"github.com/grafana/k6foundry/pkg/cache"
+	"github.com/grafana/k6foundry/pkg/packaging"
+	"github.com/grafana/k6foundry/pkg/provenance"
+	"github.com/grafana/k6foundry/pkg/sbom"
+	"golang.org/x/mod/semver"
+)
+
+// ErrInvalidOptions is returned when the foundry options are not valid
+var ErrInvalidOptions = errors.New("invalid options")
+
+const defaultName = "k6"
+
+// Options configures the Foundry
+type Options struct {
+	// options for the native builder. The ListDependencies and Checksum options are
+	// enabled if required by the output options
+	Builder k6foundry.NativeBuilderOpts
+	// files generated for each binary
+	Output OutputOptions
+	// directory the binaries and the generated files are written to
+	OutputDir string
+	// directory of the cache of binaries. No cache is used if empty
+	CacheDir string
+}
+
+// Request describes the binaries to build
+type Request struct {
+	// k6 version specification (see k6foundry.ResolveK6Version). Defaults to latest
+	K6Version string
+	// extensions and other dependencies included in the binary
+	Dependencies []k6foundry.Module
+	// platforms to build for. Defaults to the runtime platform
+	Platforms []k6foundry.Platform
+	// options passed to go build
+	BuildOpts []string
+	// name of the binary. Defaults to k6. When building for multiple platforms,
+	// the platform is appended to the name (e.g. k6-linux-amd64). Binaries for windows get the .exe extension
+	Name string
+}
+
+// Artifact is a binary built for a platform
+type Artifact struct {
+	Platform k6foundry.Platform
+	// path of the binary
+	Path string
+	Info *k6foundry.BuildInfo
+	// paths of the files generated for the binary
+	Files []string
+}
+
+// Result describes the outcome of a build request
+type Result struct {
+	// resolved k6 version
+	K6Version string
+	// an artifact for each requested platform, in the same order as the platforms
+	Artifacts []Artifact
+}
+
+// Foundry resolves, builds and post-processes k6 binaries
+type Foundry struct {
+	builder k6foundry.Builder
+	opts    Options
+}
+
+// New returns a Foundry that builds binaries using a native builder
+func New(ctx context.Context, opts Options) (*Foundry, error) {
+	if err := validateOptions(opts); err != nil {
+		return nil, err
+	}
+
+	// the SBOM requires the dependencies of the binary
+	if opts.Output.SBOMFormat != "" {
+		opts.Builder.ListDependencies = true
+	}
+	if opts.Output.Checksum {
+		opts.Builder.Checksum = true
+	}
+
+	var builder k6foundry.Builder
+	builder, err := k6foundry.NewNativeBuilder(ctx, opts.Builder)
+	if err != nil {
+		return nil, err
+	}
+
+	// binaries built from local modules depend on local changes, so they are not cached
+	if opts.CacheDir != "" && opts.Builder.Cacheable() {
+		var config string
+		if config, err = opts.Builder.ConfigDigest(); err != nil {
+			return nil, err
+		}
+		cacheOpts := cache.CachedBuilderOpts{Config: config, Metrics: opts.Builder.Metrics}
+		builder = cache.NewCachedBuilderWithOpts(builder, cache.NewFileCache(opts.CacheDir), cacheOpts)
+	}
+
+	return &Foundry{builder: builder, opts: opts}, nil
+}
+
+// NewWithBuilder returns a Foundry that builds binaries using the given builder (e.g. a container builder).
+// The builder options in opts are only used for resolving the k6 version.
+func NewWithBuilder(builder k6foundry.Builder, opts Options) (*Foundry, error) {
+	if err := validateOptions(opts); err != nil {
+		return nil, err
+	}
+
+	return &Foundry{builder: builder, opts: opts}, nil
+}
+
+func validateOptions(opts Options) error {
+	if opts.OutputDir == "" {
+		return fmt.Errorf("%w: output directory not specified", ErrInvalidOptions)
+	}
+
+	if opts.Output.SBOMFormat != "" && !slices.Contains(sbom.Formats(), opts.Output.SBOMFormat) {
+		return fmt.Errorf("%w: %w: %q", ErrInvalidOptions, sbom.ErrUnsupportedFormat, opts.Output.SBOMFormat)
+	}
+
+	for _, format := range opts.Output.Packages {
+		if !slices.Contains(packaging.Formats(), format) {
+			return fmt.Errorf("%w: %w: %q", ErrInvalidOptions, packaging.ErrUnsupportedFormat, format)
+		}
+	}
+
+	return nil
+}
+
+// Build resolves the k6 version, builds a binary for each platform in the request and
+// generates the files configured in the output options for each of them
+func (f *Foundry) Build(ctx context.Context, req Request) (*Result, error) {
+	startedOn := time.Now()
+
+	k6Version, err := f.resolveVersion(ctx, req.K6Version)
+	if err != nil {
+		return nil, err
+	}
+
+	name := req.Name
+	if name == "" {
+		name = defaultName
+	}
+
+	platforms := req.Platforms
+	if len(platforms) == 0 {
+		platforms = []k6foundry.Platform{k6foundry.RuntimePlatform()}
+	}
+
+	if err = os.MkdirAll(f.opts.OutputDir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating output directory %w", err)
+	}
+
+	paths := make([]string, len(platforms))
+	for i, platform := range platforms {
+		binary := name
+		if len(platforms) > 1 {
+			binary += "-" + strings.ReplaceAll(platform.String(), "/", "-")
+		}
+		paths[i] = filepath.Join(f.opts.OutputDir, platform.BinaryName(binary))
+	}
+
+	infos, err := f.build(ctx, platforms, paths, k6Version, req)
+	if err != nil {
+		return nil, err
+	}
+
+	params := provenance.Parameters{
+		K6Version:    k6Version,
+		Dependencies: req.Dependencies,
+		BuildOpts:    req.BuildOpts,
+		StartedOn:    startedOn,
+	}
+
+	result := &Result{K6Version: k6Version}
+	for i, platform := range platforms {
+		files, processErr := Process(ctx, paths[i], infos[i], params, f.opts.Output)
+		if processErr != nil {
+			return nil, fmt.Errorf("processing %s: %w", paths[i], processErr)
+		}
+
+		result.Artifacts = append(
+			result.Artifacts,
+			Artifact{Platform: platform, Path: paths[i], Info: infos[i], Files: files},
+		)
+	}
+
+	return result, nil
+}
+
+// resolveVersion resolves version specifications such as latest-1 or constraints. latest is resolved by go
+func (f *Foundry) resolveVersion(ctx context.Context, spec string) (string, error) {
+	if spec == "" {
+		return "latest", nil
+	}
+
+	if spec == "latest" || semver.IsValid(spec) {
+		return spec, nil
+	}
+
+	goProxy, err := f.opts.Builder.GoProxy()
+	if err != nil {
+		return "", err
+	}
+
+	return k6foundry.ResolveK6Version(ctx, spec, k6foundry.VersionsOpts{GoProxy: goProxy})
+}
+
+// build builds the binaries for the platforms into the given paths. If the builder supports it,
+// the binaries are built in a single invocation. Otherwise, they are built one at a time and moved
+// to the paths if the builder supports it.
+func (f *Foundry) build(
+	ctx context.Context,
+	platforms []k6foundry.Platform,
+	paths []string,
+	k6Version string,
+	req Request,
+) (_ []*k6foundry.BuildInfo, err error) {
+	mode := f.opts.Builder.OutputMode
+	if mode == 0 {
+		mode = k6foundry.DefaultOutputMode
+	}
+
+	// the binaries replace the files at the paths only if all the builds succeed
+	files := make([]*k6foundry.AtomicFile, len(platforms))
+	defer func() {
+		for _, file := range files {
+			if file != nil {
+				file.Abort()
+			}
+		}
+	}()
+
+	open := func(platform k6foundry.Platform) (io.Writer, error) {
+		i := slices.Index(platforms, platform)
+		file, openErr := k6foundry.CreateAtomicFile(paths[i], mode)
+		if openErr != nil {
+			return nil, openErr
+		}
+		files[i] = file
+
+		return file, nil
+	}
+
+	if mb, ok := f.builder.(k6foundry.MultiPlatformBuilder); ok && len(platforms) > 1 {
+		infos, buildErr := mb.BuildMultiPlatform(ctx, platforms, k6Version, req.Dependencies, req.BuildOpts, open)
+		if buildErr != nil {
+			return nil, buildErr
+		}
+
+		for _, file := range files {
+			if file == nil {
+				continue
+			}
+			if err = file.Commit(); err != nil {
+				return nil, err
+			}
+		}
+
+		return infos, nil
+	}
+
+	infos := make([]*k6foundry.BuildInfo, len(platforms))
+	for i, platform := range platforms {
+		infos[i], err = k6foundry.BuildToFile(ctx, f.builder, platform, k6Version, req.Dependencies, req.BuildOpts, paths[i])
+		if err != nil {
+			// the failed binary is removed by BuildToFile
+			for _, path := range paths[:i] {
+				_ = os.Remove(path)
+			}
+			return nil, err
+		}
+	}
+
+	return infos, nil
+}
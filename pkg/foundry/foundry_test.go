@@ -0,0 +1,183 @@
+package foundry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/grafana/k6foundry"
+	"github.com/grafana/k6foundry/pkg/packaging"
+	"github.com/grafana/k6foundry/pkg/sbom"
+)
+
+var errBuild = errors.New("build failed")
+
+type fakeBuilder struct {
+	fail bool
+}
+
+func (b *fakeBuilder) Build(
+	_ context.Context,
+	platform k6foundry.Platform,
+	k6Version string,
+	_ []k6foundry.Module,
+	_ []string,
+	out io.Writer,
+) (*k6foundry.BuildInfo, error) {
+	if _, err := out.Write([]byte("binary")); err != nil {
+		return nil, err
+	}
+
+	if b.fail {
+		return nil, errBuild
+	}
+
+	return &k6foundry.BuildInfo{
+		Platform:    platform.String(),
+		ModVersions: map[string]string{"go.k6.io/k6": k6Version},
+		Dependencies: []k6foundry.Dependency{
+			{Path: "go.k6.io/k6", Version: k6Version},
+		},
+	}, nil
+}
+
+func TestBuild(t *testing.T) {
+	t.Parallel()
+
+	linux := k6foundry.Platform{OS: "linux", Arch: "amd64"}
+	darwin := k6foundry.Platform{OS: "darwin", Arch: "arm64"}
+
+	testCases := []struct {
+		title       string
+		fail        bool
+		output      OutputOptions
+		request     Request
+		expectFiles []string
+		expectError error
+	}{
+		{
+			title:       "single platform",
+			request:     Request{K6Version: "v0.50.0", Platforms: []k6foundry.Platform{linux}},
+			expectFiles: []string{"k6"},
+		},
+		{
+			title:       "multiple platforms",
+			request:     Request{K6Version: "v0.50.0", Platforms: []k6foundry.Platform{linux, darwin}, Name: "custom"},
+			expectFiles: []string{"custom-linux-amd64", "custom-darwin-arm64"},
+		},
+		{
+			title:   "generated files",
+			output:  OutputOptions{SBOMFormat: sbom.CycloneDX, Checksum: true, Packages: []string{packaging.TarGz}},
+			request: Request{K6Version: "v0.50.0", Platforms: []k6foundry.Platform{linux}},
+			expectFiles: []string{
+				"k6",
+				"k6.cdx.json",
+				"k6.sha256",
+				"k6-v0.50.0-linux-amd64.tar.gz",
+			},
+		},
+		{
+			title:       "build error",
+			fail:        true,
+			request:     Request{K6Version: "v0.50.0", Platforms: []k6foundry.Platform{linux}},
+			expectFiles: []string{},
+			expectError: errBuild,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			outDir := t.TempDir()
+
+			f, err := NewWithBuilder(&fakeBuilder{fail: tc.fail}, Options{OutputDir: outDir, Output: tc.output})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			result, err := f.Build(context.TODO(), tc.request)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			entries, err := os.ReadDir(outDir)
+			if err != nil {
+				t.Fatalf("reading output dir %v", err)
+			}
+
+			files := []string{}
+			for _, entry := range entries {
+				files = append(files, entry.Name())
+			}
+
+			slices.Sort(files)
+			expected := slices.Clone(tc.expectFiles)
+			slices.Sort(expected)
+			if !slices.Equal(files, expected) {
+				t.Fatalf("expected files %v got %v", expected, files)
+			}
+
+			if tc.expectError != nil {
+				return
+			}
+
+			if len(result.Artifacts) != len(tc.request.Platforms) {
+				t.Fatalf("expected %d artifacts got %d", len(tc.request.Platforms), len(result.Artifacts))
+			}
+
+			for _, artifact := range result.Artifacts {
+				if filepath.Dir(artifact.Path) != outDir {
+					t.Fatalf("artifact %s not in output dir", artifact.Path)
+				}
+			}
+		})
+	}
+}
+
+func TestOptions(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		opts        Options
+		expectError error
+	}{
+		{
+			title: "valid options",
+			opts:  Options{OutputDir: "out", Output: OutputOptions{SBOMFormat: sbom.SPDX, Packages: []string{packaging.Zip}}},
+		},
+		{
+			title:       "missing output dir",
+			opts:        Options{},
+			expectError: ErrInvalidOptions,
+		},
+		{
+			title:       "invalid SBOM format",
+			opts:        Options{OutputDir: "out", Output: OutputOptions{SBOMFormat: "swid"}},
+			expectError: sbom.ErrUnsupportedFormat,
+		},
+		{
+			title:       "invalid package format",
+			opts:        Options{OutputDir: "out", Output: OutputOptions{Packages: []string{"msi"}}},
+			expectError: packaging.ErrUnsupportedFormat,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewWithBuilder(&fakeBuilder{}, tc.opts)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package foundry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/grafana/k6foundry"
+	"github.com/grafana/k6foundry/pkg/packaging"
+	"github.com/grafana/k6foundry/pkg/provenance"
+	"github.com/grafana/k6foundry/pkg/publish"
+	"github.com/grafana/k6foundry/pkg/sbom"
+	"github.com/grafana/k6foundry/pkg/sign"
+)
+
+// OutputOptions configures the files generated next to each binary and where the binary is published
+type OutputOptions struct {
+	// format of the SBOM written next to the binary (see sbom.Formats). No SBOM if empty
+	SBOMFormat string
+	// write the SHA256 checksum of the binary into <binary>.sha256, in the format used by sha256sum
+	Checksum bool
+	// write the SLSA provenance of the binary into <binary>.intoto.json
+	Provenance bool
+	// identifies the builder in the provenance. Defaults to provenance.DefaultBuilderID
+	BuilderID string
+	// signs the binary and the files generated for it. The binary is not signed if nil
+	Signer sign.Signer
+	// formats of the packages written next to the binary (see packaging.Formats)
+	Packages []string
+	// options for the packages
+	PackageOptions packaging.Options
+	// targets the binary and the files generated for it are published to (see publish.New)
+	PublishTo []string
+}
+
+// Process generates the SBOM, the checksum and the provenance of a binary, signs, packages and publishes
+// it together with the generated files, as configured in the options. Returns the paths of the files generated next to the binary.
+// The checksum is added to the build info if missing (e.g. binaries returned from a cache)
+func Process(
+	ctx context.Context,
+	binaryPath string,
+	info *k6foundry.BuildInfo,
+	params provenance.Parameters,
+	opts OutputOptions,
+) ([]string, error) {
+	files := []string{}
+
+	if opts.SBOMFormat != "" {
+		content, err := sbom.Generate(opts.SBOMFormat, filepath.Base(binaryPath), info)
+		if err != nil {
+			return files, err
+		}
+
+		path := binaryPath + sbom.Extension(opts.SBOMFormat)
+		if err = os.WriteFile(path, content, 0o644); err != nil { //nolint:gosec,forbidigo
+			return files, err
+		}
+		files = append(files, path)
+	}
+
+	if opts.Checksum {
+		path, err := writeChecksum(binaryPath, info)
+		if err != nil {
+			return files, err
+		}
+		files = append(files, path)
+	}
+
+	if opts.Provenance {
+		params.BuilderID = opts.BuilderID
+		content, err := provenance.Generate(binaryPath, info, params)
+		if err != nil {
+			return files, err
+		}
+
+		path := binaryPath + provenance.Extension
+		if err = os.WriteFile(path, content, 0o644); err != nil { //nolint:gosec,forbidigo
+			return files, err
+		}
+		files = append(files, path)
+	}
+
+	if opts.Signer != nil {
+		signatures, err := opts.Signer.Sign(ctx, binaryPath)
+		if err != nil {
+			return files, err
+		}
+		files = append(files, signatures...)
+	}
+
+	for _, format := range opts.Packages {
+		path, err := writePackage(format, binaryPath, info, opts.PackageOptions)
+		if err != nil {
+			return files, err
+		}
+		files = append(files, path)
+	}
+
+	artifact := publish.Artifact{Name: filepath.Base(binaryPath), Path: binaryPath, Files: files}
+	for _, target := range opts.PublishTo {
+		publisher, err := publish.New(target)
+		if err != nil {
+			return files, err
+		}
+
+		if err = publisher.Publish(ctx, artifact, info); err != nil {
+			return files, err
+		}
+	}
+
+	return files, nil
+}
+
+// writeChecksum writes the checksum of the binary into <binary>.sha256 and returns the path of the file
+func writeChecksum(binaryPath string, info *k6foundry.BuildInfo) (string, error) {
+	if info.Checksum == "" {
+		checksum, err := fileChecksum(binaryPath)
+		if err != nil {
+			return "", err
+		}
+		info.Checksum = checksum
+	}
+
+	path := binaryPath + ".sha256"
+	content := fmt.Sprintf("%s  %s\n", strings.TrimPrefix(info.Checksum, "sha256:"), filepath.Base(binaryPath))
+
+	return path, os.WriteFile(path, []byte(content), 0o644) //nolint:gosec,forbidigo
+}
+
+// writePackage packages the binary, writing the package next to it, and returns the path of the package
+func writePackage(format string, binaryPath string, info *k6foundry.BuildInfo, opts packaging.Options) (string, error) {
+	path := filepath.Join(filepath.Dir(binaryPath), packaging.FileName(format, opts.Name, info))
+
+	out, err := os.Create(path) //nolint:gosec,forbidigo
+	if err != nil {
+		return "", err
+	}
+
+	err = packaging.Package(format, binaryPath, info, opts, out)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path) //nolint:forbidigo
+		return "", err
+	}
+
+	return path, nil
+}
+
+// fileChecksum returns the sha256 checksum of a file in the format sha256:<hex digest>
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path) //nolint:gosec,forbidigo
+	if err != nil {
+		return "", err
+	}
+	defer file.Close() //nolint:errcheck
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("computing checksum %w", err)
+	}
+
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil)), nil
+}
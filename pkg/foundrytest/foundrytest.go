@@ -0,0 +1,209 @@
+// Package foundrytest provides a fake k6foundry.Builder for testing the services that build k6 binaries,
+// without requiring a go toolchain or network access.
+package foundrytest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/grafana/k6foundry"
+)
+
+const k6Module = "go.k6.io/k6"
+
+// ErrNoBuildInfo is returned when a BuildFunc returns neither a build info nor an error
+var ErrNoBuildInfo = errors.New("no build info returned")
+
+// DefaultBinary is the content of the binaries returned by a FakeBuilder if Binary is not set
+var DefaultBinary = []byte("fake k6 binary") //nolint:gochecknoglobals
+
+// BuildCall records the arguments of a build
+type BuildCall struct {
+	Platform  k6foundry.Platform
+	K6Version string
+	Mods      []k6foundry.Module
+	BuildOpts []string
+}
+
+// BuildFunc returns the binary and build info for a build, or the error the build fails with
+type BuildFunc func(ctx context.Context, call BuildCall) ([]byte, *k6foundry.BuildInfo, error)
+
+// FakeBuilder is a k6foundry.Builder that records the builds and returns canned binaries and build info.
+// It also implements k6foundry.MultiPlatformBuilder, building each platform as a separate call.
+//
+// The zero value is ready to use and writes DefaultBinary for any build. It is safe for concurrent use,
+// but its fields must not be changed while building.
+type FakeBuilder struct {
+	// content of the binaries. Defaults to DefaultBinary
+	Binary []byte
+	// build info returned by the builds. If nil, the build info is generated from the build arguments
+	// and the binary. The platform, checksum, size and executable are always set for the platform built
+	BuildInfo *k6foundry.BuildInfo
+	// error returned by the builds, without writing any binary
+	Err error
+	// if set, it is called for each build instead of using Binary, BuildInfo and Err
+	BuildFunc BuildFunc
+
+	mu    sync.Mutex
+	calls []BuildCall
+}
+
+// NewFakeBuilder returns a FakeBuilder that writes the binary for any build
+func NewFakeBuilder(binary []byte) *FakeBuilder {
+	return &FakeBuilder{Binary: binary}
+}
+
+// NewFailingBuilder returns a FakeBuilder that fails all the builds with the error
+func NewFailingBuilder(err error) *FakeBuilder {
+	return &FakeBuilder{Err: err}
+}
+
+// Build records the build and writes the canned binary into the out io.Writer
+func (b *FakeBuilder) Build(
+	ctx context.Context,
+	platform k6foundry.Platform,
+	k6Version string,
+	mods []k6foundry.Module,
+	buildOpts []string,
+	out io.Writer,
+) (*k6foundry.BuildInfo, error) {
+	call := BuildCall{
+		Platform:  platform,
+		K6Version: k6Version,
+		Mods:      slices.Clone(mods),
+		BuildOpts: slices.Clone(buildOpts),
+	}
+
+	b.mu.Lock()
+	b.calls = append(b.calls, call)
+	b.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	binary, info, err := b.build(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = out.Write(binary); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// BuildMultiPlatform records a build for each platform and writes the canned binaries into the outputs.
+// The platforms are built in order, so the calls are recorded in the order of the platforms
+func (b *FakeBuilder) BuildMultiPlatform(
+	ctx context.Context,
+	platforms []k6foundry.Platform,
+	k6Version string,
+	mods []k6foundry.Module,
+	buildOpts []string,
+	out k6foundry.OutputFunc,
+) ([]*k6foundry.BuildInfo, error) {
+	infos := make([]*k6foundry.BuildInfo, 0, len(platforms))
+	for _, platform := range platforms {
+		binary, err := out(platform)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", platform, err)
+		}
+
+		info, err := b.Build(ctx, platform, k6Version, mods, buildOpts, binary)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", platform, err)
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// Calls returns the builds recorded, in the order they were requested
+func (b *FakeBuilder) Calls() []BuildCall {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return slices.Clone(b.calls)
+}
+
+// LastCall returns the last build recorded. Returns false if no build was requested
+func (b *FakeBuilder) LastCall() (BuildCall, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.calls) == 0 {
+		return BuildCall{}, false
+	}
+
+	return b.calls[len(b.calls)-1], true
+}
+
+// Reset discards the builds recorded
+func (b *FakeBuilder) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.calls = nil
+}
+
+// build returns the binary and build info for the call
+func (b *FakeBuilder) build(ctx context.Context, call BuildCall) ([]byte, *k6foundry.BuildInfo, error) {
+	if b.BuildFunc != nil {
+		binary, info, err := b.BuildFunc(ctx, call)
+		if err != nil {
+			return nil, nil, err
+		}
+		if info == nil {
+			return nil, nil, ErrNoBuildInfo
+		}
+
+		return binary, info, nil
+	}
+
+	if b.Err != nil {
+		return nil, nil, b.Err
+	}
+
+	binary := b.Binary
+	if binary == nil {
+		binary = DefaultBinary
+	}
+
+	var info k6foundry.BuildInfo
+	if b.BuildInfo != nil {
+		info = *b.BuildInfo
+		info.ModVersions = maps.Clone(b.BuildInfo.ModVersions)
+		info.Dependencies = slices.Clone(b.BuildInfo.Dependencies)
+	} else {
+		info.ModVersions = modVersions(call)
+	}
+
+	sum := sha256.Sum256(binary)
+	info.Platform = call.Platform.String()
+	info.Checksum = "sha256:" + hex.EncodeToString(sum[:])
+	info.Size = int64(len(binary))
+	info.Executable = call.Platform.BinaryName("k6")
+
+	return binary, &info, nil
+}
+
+// modVersions returns the versions of k6 and the modules requested, as the build info reports them
+func modVersions(call BuildCall) map[string]string {
+	versions := map[string]string{k6Module: call.K6Version}
+	for _, mod := range call.Mods {
+		versions[mod.Path] = mod.Version
+	}
+
+	return versions
+}
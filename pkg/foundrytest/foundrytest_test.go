@@ -0,0 +1,173 @@
+package foundrytest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/grafana/k6foundry"
+)
+
+var errBuild = errors.New("build failed")
+
+func TestFakeBuilder(t *testing.T) {
+	t.Parallel()
+
+	linux := k6foundry.Platform{OS: "linux", Arch: "amd64"}
+	windows := k6foundry.Platform{OS: "windows", Arch: "amd64"}
+	mods := []k6foundry.Module{{Path: "github.com/grafana/xk6-sql", Version: "v0.1.0"}}
+
+	testCases := []struct {
+		title        string
+		builder      *FakeBuilder
+		platform     k6foundry.Platform
+		expectBinary []byte
+		expectInfo   *k6foundry.BuildInfo
+		expectErr    error
+	}{
+		{
+			title:        "default",
+			builder:      &FakeBuilder{},
+			platform:     linux,
+			expectBinary: DefaultBinary,
+			expectInfo: &k6foundry.BuildInfo{
+				Platform:    "linux/amd64",
+				ModVersions: map[string]string{"go.k6.io/k6": "v0.50.0", "github.com/grafana/xk6-sql": "v0.1.0"},
+				Size:        int64(len(DefaultBinary)),
+				Executable:  "k6",
+			},
+		},
+		{
+			title:        "canned build info",
+			builder:      &FakeBuilder{Binary: []byte("k6"), BuildInfo: &k6foundry.BuildInfo{ModVersions: map[string]string{"go.k6.io/k6": "v0.51.0"}}},
+			platform:     windows,
+			expectBinary: []byte("k6"),
+			expectInfo: &k6foundry.BuildInfo{
+				Platform:    "windows/amd64",
+				ModVersions: map[string]string{"go.k6.io/k6": "v0.51.0"},
+				Size:        2,
+				Executable:  "k6.exe",
+			},
+		},
+		{
+			title:     "failing",
+			builder:   NewFailingBuilder(errBuild),
+			platform:  linux,
+			expectErr: errBuild,
+		},
+		{
+			title: "build func",
+			builder: &FakeBuilder{
+				BuildFunc: func(_ context.Context, call BuildCall) ([]byte, *k6foundry.BuildInfo, error) {
+					return []byte(call.K6Version), &k6foundry.BuildInfo{Platform: call.Platform.String()}, nil
+				},
+			},
+			platform:     linux,
+			expectBinary: []byte("v0.50.0"),
+			expectInfo:   &k6foundry.BuildInfo{Platform: "linux/amd64"},
+		},
+		{
+			title: "build func without build info",
+			builder: &FakeBuilder{
+				BuildFunc: func(_ context.Context, _ BuildCall) ([]byte, *k6foundry.BuildInfo, error) {
+					return nil, nil, nil
+				},
+			},
+			platform:  linux,
+			expectErr: ErrNoBuildInfo,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			out := &bytes.Buffer{}
+			info, err := tc.builder.Build(context.Background(), tc.platform, "v0.50.0", mods, []string{"-trimpath"}, out)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			expectCall := BuildCall{Platform: tc.platform, K6Version: "v0.50.0", Mods: mods, BuildOpts: []string{"-trimpath"}}
+			if calls := tc.builder.Calls(); !reflect.DeepEqual(calls, []BuildCall{expectCall}) {
+				t.Fatalf("expected calls %v got %v", []BuildCall{expectCall}, calls)
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			if !bytes.Equal(out.Bytes(), tc.expectBinary) {
+				t.Fatalf("expected binary %q got %q", tc.expectBinary, out.Bytes())
+			}
+
+			// the build info generated by the fake builder has the checksum of the binary
+			if info.Checksum != "" {
+				sum := sha256.Sum256(out.Bytes())
+				if expect := "sha256:" + hex.EncodeToString(sum[:]); info.Checksum != expect {
+					t.Fatalf("expected checksum %s got %s", expect, info.Checksum)
+				}
+				info.Checksum = ""
+			}
+
+			if !reflect.DeepEqual(info, tc.expectInfo) {
+				t.Fatalf("expected build info %v got %v", tc.expectInfo, info)
+			}
+		})
+	}
+}
+
+func TestFakeBuilderMultiPlatform(t *testing.T) {
+	t.Parallel()
+
+	platforms := []k6foundry.Platform{{OS: "linux", Arch: "amd64"}, {OS: "darwin", Arch: "arm64"}}
+
+	builder := NewFakeBuilder([]byte("k6"))
+
+	outputs := map[string]*bytes.Buffer{}
+	infos, err := builder.BuildMultiPlatform(
+		context.Background(),
+		platforms,
+		"v0.50.0",
+		nil,
+		nil,
+		func(platform k6foundry.Platform) (io.Writer, error) {
+			outputs[platform.String()] = &bytes.Buffer{}
+			return outputs[platform.String()], nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	calls := builder.Calls()
+	if len(calls) != len(platforms) || len(infos) != len(platforms) {
+		t.Fatalf("expected %d builds got %d calls and %d build infos", len(platforms), len(calls), len(infos))
+	}
+
+	for i, platform := range platforms {
+		if calls[i].Platform != platform || infos[i].Platform != platform.String() {
+			t.Fatalf("expected build for %s got call %s and build info %s", platform, calls[i].Platform, infos[i].Platform)
+		}
+
+		if outputs[platform.String()].String() != "k6" {
+			t.Fatalf("expected binary for %s", platform)
+		}
+	}
+
+	last, ok := builder.LastCall()
+	if !ok || last.Platform != platforms[1] {
+		t.Fatalf("expected last call for %s got %v", platforms[1], last)
+	}
+
+	builder.Reset()
+	if _, ok = builder.LastCall(); ok {
+		t.Fatalf("expected no calls after reset")
+	}
+}
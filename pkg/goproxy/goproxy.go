@@ -0,0 +1,370 @@
+// Package goproxy implements a go module proxy for testing builds without access to the public proxies.
+//
+// The proxy serves the modules added in memory with AddModVersion, the modules in a directory with the
+// layout of the module download cache and, optionally, forwards the requests for other modules to an
+// upstream proxy. Latency and errors can be injected to test how clients handle slow or failing proxies.
+// See https://go.dev/ref/mod#goproxy-protocol
+//
+//nolint:forbidigo
+package goproxy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+	"golang.org/x/mod/zip"
+)
+
+const (
+	infoTemplate = "{\"Version\":\"%s\",\"Time\":\"%s\"}"
+)
+
+// Options defines the options of the go proxy
+type Options struct {
+	// directory with the layout of the module download cache ($GOMODCACHE/cache/download), served for
+	// the modules not added in memory. The files are read on each request
+	Dir string
+	// URL of a proxy the requests for the modules not found are forwarded to (e.g. https://proxy.golang.org)
+	Upstream string
+	// client used for the upstream proxy. Defaults to http.DefaultClient
+	HTTPClient *http.Client
+	// delay added to each response
+	Latency time.Duration
+	// if set, it is called for each request and, if it returns a status code other than 0, the request
+	// fails with that status
+	Fault func(r *http.Request) int
+}
+
+// GoProxy implements a Go proxy.
+// Uses a in memory representation of the mod cache to store a mod cache
+// Responds to GOPROXY protocol requests from the mod cache
+// See https://go.dev/ref/mod#goproxy-protocol)
+type GoProxy struct {
+	opts     Options
+	mu       sync.RWMutex
+	files    map[string][]byte
+	versions map[string][]string
+}
+
+// NewGoProxy creates a new GoProxy
+func NewGoProxy() *GoProxy {
+	return NewGoProxyWithOptions(Options{})
+}
+
+// NewGoProxyWithOptions creates a new GoProxy with the given options
+func NewGoProxyWithOptions(opts Options) *GoProxy {
+	return &GoProxy{
+		opts:     opts,
+		files:    map[string][]byte{},
+		versions: map[string][]string{},
+	}
+}
+
+// ServeHTTP handles GOPROXY requests
+func (p *GoProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if p.opts.Latency > 0 {
+		select {
+		case <-time.After(p.opts.Latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if p.opts.Fault != nil {
+		if status := p.opts.Fault(r); status != 0 {
+			w.WriteHeader(status)
+			return
+		}
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	content, found := p.file(r.URL.Path)
+	if !found {
+		if p.opts.Upstream != "" {
+			p.forward(w, r)
+			return
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch path.Ext(r.URL.Path) {
+	case ".zip":
+		w.Header().Add("Content-type", "application/zip")
+	case ".info":
+		w.Header().Add("Content-type", "application/json")
+	default:
+		w.Header().Add("Content-type", "text/plain")
+	}
+
+	_, _ = w.Write(content)
+}
+
+// file returns the content of the file at the url path, from memory or the directory
+func (p *GoProxy) file(urlPath string) ([]byte, bool) {
+	p.mu.RLock()
+	content, found := p.files[filepath.FromSlash(urlPath)]
+	p.mu.RUnlock()
+
+	if found || p.opts.Dir == "" {
+		return content, found
+	}
+
+	// only the files of the protocol are served, not the locks and hashes of the download cache
+	file, ok := protocolFile(p.opts.Dir, urlPath)
+	if !ok {
+		return nil, false
+	}
+
+	content, err := os.ReadFile(file) //nolint:gosec
+	if err != nil {
+		return nil, false
+	}
+
+	return content, true
+}
+
+// protocolFile returns the path in dir of a file of the proxy protocol (list, @latest and the .info, .mod
+// and .zip of the versions). Returns false if the url path is not a file of the protocol
+func protocolFile(dir string, urlPath string) (string, bool) {
+	switch path.Ext(urlPath) {
+	case ".info", ".mod", ".zip":
+	default:
+		if base := path.Base(urlPath); base != "list" && base != "@latest" {
+			return "", false
+		}
+	}
+
+	local := filepath.FromSlash(strings.TrimPrefix(urlPath, "/"))
+	if !filepath.IsLocal(local) {
+		return "", false
+	}
+
+	return filepath.Join(dir, local), true
+}
+
+// forward serves the request from the upstream proxy
+func (p *GoProxy) forward(w http.ResponseWriter, r *http.Request) {
+	client := p.opts.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	url := strings.TrimSuffix(p.opts.Upstream, "/") + r.URL.Path
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, url, nil)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if contentType := resp.Header.Get("Content-type"); contentType != "" {
+		w.Header().Add("Content-type", contentType)
+	}
+	w.WriteHeader(resp.StatusCode)
+
+	_, _ = io.Copy(w, resp.Body)
+}
+
+// AddModVersion adds a module version to the go proxy cache
+// Example: for module go.k6.io/k6 version v0.1.0
+//   - creates info file go.k6.io/k6/@v/v0.1.0.info
+//   - copies the mod file from into go.k6.io/k6/@v/v0.1.0.mod
+//   - compresses the gomod and source files into the file go.k6.io/k6/@v/v0.1.0.zip
+//   - updates the list of versions at go.k6.io/k6/@v/list, excluding pseudo-versions
+//   - updates the latest version for the module at go.k6.io/k6/@latest
+//
+// The latest version is selected as the go command does: the highest release or, if there are none,
+// the highest pre-release or pseudo-version, excluding the versions retracted in the go.mod of the
+// highest version.
+func (p *GoProxy) AddModVersion(
+	path string,
+	version string,
+	sourcePath string,
+) error {
+	// create modules for tests
+	sourceFiles, err := ReadDir(sourcePath)
+	if err != nil {
+		return fmt.Errorf("reading module source: %w", err)
+	}
+
+	// create gomod
+	gomod, found := sourceFiles["go.mod"]
+	if !found {
+		return fmt.Errorf("go.mod is required")
+	}
+
+	escaped, err := module.EscapePath(path)
+	if err != nil {
+		return fmt.Errorf("invalid module path: %w", err)
+	}
+
+	zipBuffer := &bytes.Buffer{}
+	err = zip.CreateFromDir(zipBuffer, module.Version{Path: path, Version: version}, sourcePath)
+	if err != nil {
+		return fmt.Errorf("creating zip file: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	modPath := filepath.Join("/", escaped, "@v")
+
+	zipFile := filepath.Join(modPath, version+".zip")
+	p.files[zipFile] = zipBuffer.Bytes()
+
+	// create version info. Pseudo-versions have the time of their commit
+	versionTime := time.Now()
+	if module.IsPseudoVersion(version) {
+		versionTime, _ = module.PseudoVersionTime(version)
+	}
+	infoFile := filepath.Join(modPath, version+".info")
+	verInfo := fmt.Sprintf(infoTemplate, version, versionTime.UTC().Format(time.RFC3339))
+	p.files[infoFile] = []byte(verInfo)
+
+	// copy mod file
+	modFile := filepath.Join(modPath, version+".mod")
+	p.files[modFile] = gomod
+
+	if !slices.Contains(p.versions[path], version) {
+		p.versions[path] = append(p.versions[path], version)
+	}
+	p.updateVersions(path, escaped)
+
+	return nil
+}
+
+// AddModQuery resolves a query for a module (e.g. a branch or commit) to a version added with AddModVersion
+// Example: for module go.k6.io/k6 query master
+//   - copies the info file go.k6.io/k6/@v/<version>.info to go.k6.io/k6/@v/master.info
+func (p *GoProxy) AddModQuery(path string, query string, version string) error {
+	escaped, err := module.EscapePath(path)
+	if err != nil {
+		return fmt.Errorf("invalid module path: %w", err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	modPath := filepath.Join("/", escaped, "@v")
+
+	verInfo, found := p.files[filepath.Join(modPath, version+".info")]
+	if !found {
+		return fmt.Errorf("version %s of %s not found", version, path)
+	}
+
+	p.files[filepath.Join(modPath, query+".info")] = verInfo
+
+	return nil
+}
+
+// updateVersions updates the list and the latest version of the module
+func (p *GoProxy) updateVersions(path string, escaped string) {
+	versions := p.versions[path]
+	semver.Sort(versions)
+
+	modPath := filepath.Join("/", escaped, "@v")
+
+	// proxies don't list pseudo-versions
+	listed := []string{}
+	for _, version := range versions {
+		if !module.IsPseudoVersion(version) {
+			listed = append(listed, version)
+		}
+	}
+	p.files[filepath.Join(modPath, "list")] = []byte(strings.Join(listed, "\n"))
+
+	// the retractions are defined in the go.mod of the latest version, even if it retracts itself
+	highest := latestVersion(versions, nil)
+	retractions := parseRetractions(p.files[filepath.Join(modPath, highest+".mod")])
+
+	latest := latestVersion(versions, func(version string) bool {
+		return isRetracted(version, retractions)
+	})
+	if latest == "" {
+		latest = highest
+	}
+
+	p.files[filepath.Join("/", escaped, "@latest")] = p.files[filepath.Join(modPath, latest+".info")]
+}
+
+// latestVersion returns the highest release of the versions or, if there are none, the highest
+// pre-release or pseudo-version, skipping the versions excluded. Returns "" if all are excluded.
+// The versions must be sorted
+func latestVersion(versions []string, excluded func(string) bool) string {
+	var prerelease, pseudo string
+	for i := len(versions) - 1; i >= 0; i-- {
+		version := versions[i]
+		if excluded != nil && excluded(version) {
+			continue
+		}
+
+		switch {
+		case module.IsPseudoVersion(version):
+			if pseudo == "" {
+				pseudo = version
+			}
+		case semver.Prerelease(version) != "":
+			if prerelease == "" {
+				prerelease = version
+			}
+		default:
+			return version
+		}
+	}
+
+	if prerelease != "" {
+		return prerelease
+	}
+
+	return pseudo
+}
+
+// parseRetractions returns the version intervals retracted in the go.mod
+func parseRetractions(gomod []byte) []modfile.VersionInterval {
+	file, err := modfile.ParseLax("go.mod", gomod, nil)
+	if err != nil {
+		return nil
+	}
+
+	retractions := []modfile.VersionInterval{}
+	for _, retract := range file.Retract {
+		retractions = append(retractions, retract.VersionInterval)
+	}
+
+	return retractions
+}
+
+// isRetracted returns if the version is in any of the retracted intervals
+func isRetracted(version string, retractions []modfile.VersionInterval) bool {
+	for _, interval := range retractions {
+		if semver.Compare(version, interval.Low) >= 0 && semver.Compare(version, interval.High) <= 0 {
+			return true
+		}
+	}
+
+	return false
+}
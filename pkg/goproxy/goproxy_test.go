@@ -0,0 +1,288 @@
+package goproxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeModule writes the source of a module with the given path into a temporary directory.
+// The directives are added to the go.mod
+func writeModule(t *testing.T, path string, directives ...string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":  "module " + path + "\n\ngo 1.20\n" + strings.Join(directives, "\n"),
+		"mod.go":  "package mod\n",
+		"LICENSE": "MIT\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	return dir
+}
+
+func get(t *testing.T, url string) (int, string) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatalf("creating request %v", err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request %v", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading response %v", err)
+	}
+
+	return resp.StatusCode, string(body)
+}
+
+func TestGoProxy(t *testing.T) {
+	t.Parallel()
+
+	memory := NewGoProxy()
+	for _, version := range []string{"v0.2.0", "v0.10.0"} {
+		if err := memory.AddModVersion("example.com/Mod", version, writeModule(t, "example.com/Mod")); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	memorySrv := httptest.NewServer(memory)
+	t.Cleanup(memorySrv.Close)
+
+	// download cache layout, with a lock file that must not be served
+	dir := t.TempDir()
+	cacheFiles := map[string]string{
+		"example.com/disk/@v/list":        "v1.0.0\n",
+		"example.com/disk/@v/v1.0.0.mod":  "module example.com/disk\n",
+		"example.com/disk/@v/v1.0.0.lock": "",
+	}
+	for name, content := range cacheFiles {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o750); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	withUpstream := NewGoProxyWithOptions(Options{Dir: dir, Upstream: memorySrv.URL})
+	withUpstreamSrv := httptest.NewServer(withUpstream)
+	t.Cleanup(withUpstreamSrv.Close)
+
+	failing := NewGoProxyWithOptions(Options{
+		Fault: func(r *http.Request) int {
+			if strings.HasSuffix(r.URL.Path, ".zip") {
+				return http.StatusInternalServerError
+			}
+			return 0
+		},
+	})
+	if err := failing.AddModVersion("example.com/mod", "v0.1.0", writeModule(t, "example.com/mod")); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+	failingSrv := httptest.NewServer(failing)
+	t.Cleanup(failingSrv.Close)
+
+	testCases := []struct {
+		title        string
+		url          string
+		expectStatus int
+		expectBody   string
+	}{
+		{
+			title:        "list sorted by semver",
+			url:          memorySrv.URL + "/example.com/!mod/@v/list",
+			expectStatus: http.StatusOK,
+			expectBody:   "v0.2.0\nv0.10.0",
+		},
+		{
+			title:        "latest",
+			url:          memorySrv.URL + "/example.com/!mod/@latest",
+			expectStatus: http.StatusOK,
+			expectBody:   `"Version":"v0.10.0"`,
+		},
+		{
+			title:        "mod",
+			url:          memorySrv.URL + "/example.com/!mod/@v/v0.2.0.mod",
+			expectStatus: http.StatusOK,
+			expectBody:   "module example.com/Mod",
+		},
+		{
+			title:        "unknown version",
+			url:          memorySrv.URL + "/example.com/!mod/@v/v0.3.0.info",
+			expectStatus: http.StatusNotFound,
+		},
+		{
+			title:        "download cache",
+			url:          withUpstreamSrv.URL + "/example.com/disk/@v/v1.0.0.mod",
+			expectStatus: http.StatusOK,
+			expectBody:   "module example.com/disk",
+		},
+		{
+			title:        "download cache lock file",
+			url:          withUpstreamSrv.URL + "/example.com/disk/@v/v1.0.0.lock",
+			expectStatus: http.StatusNotFound,
+		},
+		{
+			title:        "upstream",
+			url:          withUpstreamSrv.URL + "/example.com/!mod/@v/list",
+			expectStatus: http.StatusOK,
+			expectBody:   "v0.10.0",
+		},
+		{
+			title:        "upstream not found",
+			url:          withUpstreamSrv.URL + "/example.com/other/@v/list",
+			expectStatus: http.StatusNotFound,
+		},
+		{
+			title:        "fault",
+			url:          failingSrv.URL + "/example.com/mod/@v/v0.1.0.zip",
+			expectStatus: http.StatusInternalServerError,
+		},
+		{
+			title:        "no fault",
+			url:          failingSrv.URL + "/example.com/mod/@v/v0.1.0.mod",
+			expectStatus: http.StatusOK,
+			expectBody:   "module example.com/mod",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			status, body := get(t, tc.url)
+			if status != tc.expectStatus {
+				t.Fatalf("expected status %d got %d", tc.expectStatus, status)
+			}
+
+			if !strings.Contains(body, tc.expectBody) {
+				t.Fatalf("expected body containing %q got %q", tc.expectBody, body)
+			}
+		})
+	}
+}
+
+func TestGoProxyLatency(t *testing.T) {
+	t.Parallel()
+
+	latency := 100 * time.Millisecond
+	srv := httptest.NewServer(NewGoProxyWithOptions(Options{Latency: latency}))
+	t.Cleanup(srv.Close)
+
+	start := time.Now()
+	if status, _ := get(t, srv.URL+"/example.com/mod/@v/list"); status != http.StatusNotFound {
+		t.Fatalf("expected status %d got %d", http.StatusNotFound, status)
+	}
+
+	if elapsed := time.Since(start); elapsed < latency {
+		t.Fatalf("expected response after %s got %s", latency, elapsed)
+	}
+}
+
+func TestGoProxyLatest(t *testing.T) {
+	t.Parallel()
+
+	const pseudo = "v0.0.0-20240102150405-abcdefabcdef"
+
+	testCases := []struct {
+		title        string
+		versions     []string
+		directives   []string
+		expectList   string
+		expectLatest string
+	}{
+		{
+			title:        "semver order",
+			versions:     []string{"v0.10.0", "v0.9.0", "v0.2.0"},
+			expectList:   "v0.2.0\nv0.9.0\nv0.10.0",
+			expectLatest: "v0.10.0",
+		},
+		{
+			title:        "release preferred to pre-release",
+			versions:     []string{"v1.0.0", "v1.1.0-rc.1"},
+			expectList:   "v1.0.0\nv1.1.0-rc.1",
+			expectLatest: "v1.0.0",
+		},
+		{
+			title:        "only pre-releases",
+			versions:     []string{"v1.1.0-rc.1", "v1.1.0-rc.2", pseudo},
+			expectList:   "v1.1.0-rc.1\nv1.1.0-rc.2",
+			expectLatest: "v1.1.0-rc.2",
+		},
+		{
+			title:        "only pseudo-version",
+			versions:     []string{pseudo},
+			expectList:   "",
+			expectLatest: pseudo,
+		},
+		{
+			title:        "retracted",
+			versions:     []string{"v1.0.0", "v1.1.0", "v1.2.0"},
+			directives:   []string{"retract v1.1.0", "retract v1.2.0 // retracts itself"},
+			expectList:   "v1.0.0\nv1.1.0\nv1.2.0",
+			expectLatest: "v1.0.0",
+		},
+		{
+			title:        "retracted interval",
+			versions:     []string{"v1.0.0", "v1.1.0", "v1.2.0"},
+			directives:   []string{"retract [v1.1.0, v1.3.0]"},
+			expectList:   "v1.0.0\nv1.1.0\nv1.2.0",
+			expectLatest: "v1.0.0",
+		},
+		{
+			title:        "all retracted",
+			versions:     []string{"v1.0.0", "v1.1.0"},
+			directives:   []string{"retract [v1.0.0, v1.1.0]"},
+			expectList:   "v1.0.0\nv1.1.0",
+			expectLatest: "v1.1.0",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			proxy := NewGoProxy()
+			source := writeModule(t, "example.com/mod", tc.directives...)
+			for _, version := range tc.versions {
+				if err := proxy.AddModVersion("example.com/mod", version, source); err != nil {
+					t.Fatalf("setup %v", err)
+				}
+			}
+
+			srv := httptest.NewServer(proxy)
+			t.Cleanup(srv.Close)
+
+			if _, list := get(t, srv.URL+"/example.com/mod/@v/list"); list != tc.expectList {
+				t.Fatalf("expected list %q got %q", tc.expectList, list)
+			}
+
+			_, latest := get(t, srv.URL+"/example.com/mod/@latest")
+			if !strings.Contains(latest, `"Version":"`+tc.expectLatest+`"`) {
+				t.Fatalf("expected latest %s got %s", tc.expectLatest, latest)
+			}
+		})
+	}
+}
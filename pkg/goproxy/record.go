@@ -0,0 +1,98 @@
+//nolint:forbidigo
+package goproxy
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Recorder is a GOPROXY middleware that records the modules served by another proxy into a directory
+// with the layout of the module download cache, so the downloads of a build can be replayed by a
+// proxy created with NewReplayProxy.
+//
+// Only the successful responses for the files of the proxy protocol are recorded. Recorded files are
+// replaced when requested again.
+type Recorder struct {
+	next http.Handler
+	dir  string
+	// OnError is called when a response can't be recorded. The response is served anyway
+	OnError func(urlPath string, err error)
+}
+
+// NewRecorder returns a Recorder that records the responses of the next handler into dir.
+// To record the modules of a remote proxy, use a GoProxy with the Upstream option as next handler:
+//
+//	NewRecorder(NewGoProxyWithOptions(Options{Upstream: "https://proxy.golang.org"}), dir)
+func NewRecorder(next http.Handler, dir string) *Recorder {
+	return &Recorder{next: next, dir: dir}
+}
+
+// NewReplayProxy returns a GoProxy that serves the modules recorded in dir by a Recorder,
+// without forwarding any request
+func NewReplayProxy(dir string) *GoProxy {
+	return NewGoProxyWithOptions(Options{Dir: dir})
+}
+
+// ServeHTTP serves the request using the next handler, recording the response
+func (rec *Recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	file, ok := protocolFile(rec.dir, r.URL.Path)
+	if !ok || r.Method != http.MethodGet {
+		rec.next.ServeHTTP(w, r)
+		return
+	}
+
+	rw := &recordingWriter{ResponseWriter: w, status: http.StatusOK}
+	rec.next.ServeHTTP(rw, r)
+
+	if rw.status != http.StatusOK {
+		return
+	}
+
+	if err := writeRecording(file, rw.body.Bytes()); err != nil && rec.OnError != nil {
+		rec.OnError(r.URL.Path, err)
+	}
+}
+
+// recordingWriter is a http.ResponseWriter that keeps a copy of the response body
+type recordingWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *recordingWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordingWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// writeRecording writes the content to the file, replacing it atomically so concurrent requests for the
+// same file don't leave a partial file
+func writeRecording(file string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0o750); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+"*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+
+	if _, err = tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), file)
+}
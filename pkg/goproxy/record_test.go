@@ -0,0 +1,86 @@
+package goproxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordReplay(t *testing.T) {
+	t.Parallel()
+
+	upstream := NewGoProxy()
+	for _, version := range []string{"v0.1.0", "v0.2.0"} {
+		if err := upstream.AddModVersion("example.com/Mod", version, writeModule(t, "example.com/Mod")); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	dir := t.TempDir()
+	recorder := NewRecorder(upstream, dir)
+	recorder.OnError = func(urlPath string, err error) {
+		t.Errorf("recording %s: %v", urlPath, err)
+	}
+
+	recordSrv := httptest.NewServer(recorder)
+	t.Cleanup(recordSrv.Close)
+
+	replaySrv := httptest.NewServer(NewReplayProxy(dir))
+	t.Cleanup(replaySrv.Close)
+
+	requested := []string{
+		"/example.com/!mod/@v/list",
+		"/example.com/!mod/@latest",
+		"/example.com/!mod/@v/v0.1.0.info",
+		"/example.com/!mod/@v/v0.1.0.mod",
+		"/example.com/!mod/@v/v0.1.0.zip",
+	}
+
+	recorded := map[string]string{}
+	for _, urlPath := range requested {
+		status, body := get(t, recordSrv.URL+urlPath)
+		if status != http.StatusOK {
+			t.Fatalf("%s: expected status %d got %d", urlPath, http.StatusOK, status)
+		}
+		recorded[urlPath] = body
+	}
+
+	if status, _ := get(t, recordSrv.URL+"/example.com/!mod/@v/v0.3.0.info"); status != http.StatusNotFound {
+		t.Fatalf("expected status %d got %d", http.StatusNotFound, status)
+	}
+
+	testCases := []struct {
+		title        string
+		urlPath      string
+		expectStatus int
+	}{
+		{title: "list", urlPath: "/example.com/!mod/@v/list", expectStatus: http.StatusOK},
+		{title: "latest", urlPath: "/example.com/!mod/@latest", expectStatus: http.StatusOK},
+		{title: "zip", urlPath: "/example.com/!mod/@v/v0.1.0.zip", expectStatus: http.StatusOK},
+		{title: "not requested", urlPath: "/example.com/!mod/@v/v0.2.0.zip", expectStatus: http.StatusNotFound},
+		{title: "not found", urlPath: "/example.com/!mod/@v/v0.3.0.info", expectStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			status, body := get(t, replaySrv.URL+tc.urlPath)
+			if status != tc.expectStatus {
+				t.Fatalf("expected status %d got %d", tc.expectStatus, status)
+			}
+
+			if status == http.StatusOK && body != recorded[tc.urlPath] {
+				t.Fatalf("expected recorded response")
+			}
+		})
+	}
+
+	// the not found response is not recorded
+	if _, err := os.Stat(filepath.Join(dir, "example.com", "!mod", "@v", "v0.3.0.info")); !os.IsNotExist(err) {
+		t.Fatalf("expected response not recorded got %v", err)
+	}
+}
@@ -0,0 +1,412 @@
+// Package image creates container images with the binaries built by k6foundry
+package image
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/grafana/k6foundry"
+)
+
+const (
+	// Scratch is the base image for creating an image with only the binary
+	Scratch = "scratch"
+	// DefaultBaseImage is the base image used if none is specified
+	DefaultBaseImage = "alpine:latest"
+
+	defaultBinaryPath = "/usr/bin/k6"
+	k6ModulePath      = "go.k6.io/k6"
+
+	annotationVersion = "org.opencontainers.image.version"
+	annotationRefName = "org.opencontainers.image.ref.name"
+	// annotation used by docker and containerd for the name of the images in an archive
+	annotationImageName = "io.containerd.image.name"
+)
+
+var (
+	// ErrUnsupportedPlatform is returned when the platform of the binary is not supported by container images
+	ErrUnsupportedPlatform = errors.New("platform not supported by container images")
+	// ErrBaseImage is returned when the base image can't be obtained
+	ErrBaseImage = errors.New("base image")
+	// ErrImage is returned when the image can't be created
+	ErrImage = errors.New("creating image")
+)
+
+// Options defines how the image is created
+type Options struct {
+	// reference of the base image. Defaults to DefaultBaseImage. Use Scratch for an image without a base
+	BaseImage string
+	// pull the base image using http instead of https
+	Insecure bool
+	// options for the requests to the base image registry. The credentials from the docker config are
+	// used unless other credentials are given
+	RemoteOptions []remote.Option
+	// path of the binary in the image, used as entrypoint. Defaults to /usr/bin/k6
+	BinaryPath string
+	// labels added to the image config
+	Labels map[string]string
+	// creation time of the image. Defaults to the current time
+	Created time.Time
+}
+
+// Binary is a binary added to the image
+type Binary struct {
+	Path string
+	Info *k6foundry.BuildInfo
+}
+
+// Image is a container image with one manifest for each platform. The layers of the base image are not
+// downloaded until they are needed.
+type Image struct {
+	// image for a single platform. nil for multiple platforms
+	image v1.Image
+	// index for multiple platforms. nil for a single platform
+	index v1.ImageIndex
+}
+
+// ParseReference parses an image reference (e.g. myrepo/k6:custom). Insecure references use http
+func ParseReference(ref string, insecure bool) (name.Reference, error) {
+	opts := []name.Option{}
+	if insecure {
+		opts = append(opts, name.Insecure)
+	}
+
+	return name.ParseReference(ref, opts...)
+}
+
+// Build creates an image with the binaries, adding each one to the base image for its platform.
+// Multiple binaries (one for each platform) create a multi-platform image.
+func Build(ctx context.Context, binaries []Binary, opts Options) (*Image, error) {
+	if opts.BaseImage == "" {
+		opts.BaseImage = DefaultBaseImage
+	}
+
+	if opts.BinaryPath == "" {
+		opts.BinaryPath = defaultBinaryPath
+	}
+
+	if opts.Created.IsZero() {
+		opts.Created = time.Now()
+	}
+
+	var baseRef name.Reference
+	if opts.BaseImage != Scratch {
+		var err error
+		baseRef, err = ParseReference(opts.BaseImage, opts.Insecure)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrBaseImage, err)
+		}
+	}
+
+	images := []mutate.IndexAddendum{}
+	for _, binary := range binaries {
+		platform, err := imagePlatform(binary)
+		if err != nil {
+			return nil, err
+		}
+
+		base := mutate.ConfigMediaType(mutate.MediaType(empty.Image, types.OCIManifestSchema1), types.OCIConfigJSON)
+		if baseRef != nil {
+			remoteOpts := append(defaultRemoteOptions(ctx), opts.RemoteOptions...)
+			base, err = remote.Image(baseRef, append(remoteOpts, remote.WithPlatform(platform))...)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", ErrBaseImage, err)
+			}
+		}
+
+		img, err := addBinary(base, binary, platform, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrImage, err)
+		}
+
+		// the platform of the index includes the default arm variant set in the config
+		cfg, err := img.ConfigFile()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrImage, err)
+		}
+
+		images = append(images, mutate.IndexAddendum{Add: img, Descriptor: v1.Descriptor{Platform: cfg.Platform()}})
+	}
+
+	if len(images) == 1 {
+		return &Image{image: images[0].Add.(v1.Image)}, nil //nolint:forcetypeassert
+	}
+
+	// the index uses the media types of the images
+	indexType := types.OCIImageIndex
+	if manifestType, err := images[0].Add.MediaType(); err == nil && manifestType == types.DockerManifestSchema2 {
+		indexType = types.DockerManifestList
+	}
+
+	return &Image{index: mutate.AppendManifests(mutate.IndexMediaType(empty.Index, indexType), images...)}, nil
+}
+
+// imagePlatform returns the platform of the image for the binary
+func imagePlatform(binary Binary) (v1.Platform, error) {
+	platform, err := k6foundry.ParsePlatform(binary.Info.Platform)
+	if err != nil {
+		return v1.Platform{}, fmt.Errorf("%w: %w", ErrImage, err)
+	}
+
+	if platform.OS != "linux" {
+		return v1.Platform{}, fmt.Errorf("%w: %s", ErrUnsupportedPlatform, platform)
+	}
+
+	// base images can have an image for each arm version
+	return v1.Platform{OS: platform.OS, Architecture: platform.Arch, Variant: platform.Variant}, nil
+}
+
+// defaultRemoteOptions returns the options for the requests to the registries
+func defaultRemoteOptions(ctx context.Context) []remote.Option {
+	return []remote.Option{remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain)}
+}
+
+// addBinary adds the binary as a layer on top of the base image
+func addBinary(base v1.Image, binary Binary, platform v1.Platform, opts Options) (v1.Image, error) {
+	// the layer uses the media types of the base image, as registries may reject mixing them
+	manifestType, err := base.MediaType()
+	if err != nil {
+		return nil, err
+	}
+
+	layerType := types.OCILayer
+	if manifestType == types.DockerManifestSchema2 {
+		layerType = types.DockerLayer
+	}
+
+	layer, err := tarball.LayerFromOpener(
+		func() (io.ReadCloser, error) { return binaryLayer(binary.Path, opts.BinaryPath, opts.Created) },
+		tarball.WithMediaType(layerType),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	created := v1.Time{Time: opts.Created.UTC()}
+
+	img, err := mutate.Append(base, mutate.Addendum{
+		Layer:   layer,
+		History: v1.History{Created: created, CreatedBy: "k6foundry", Comment: "k6 binary"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := img.ConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	cfg = cfg.DeepCopy()
+
+	cfg.Created = created
+	cfg.OS = platform.OS
+	cfg.Architecture = platform.Architecture
+	cfg.Variant = ""
+	if platform.Architecture == "arm" {
+		cfg.Variant = platform.Variant
+		// go builds for arm v7 by default
+		if cfg.Variant == "" {
+			cfg.Variant = "v7"
+		}
+	}
+	cfg.Config.Entrypoint = []string{opts.BinaryPath}
+	cfg.Config.Cmd = nil
+
+	if cfg.Config.Labels == nil {
+		cfg.Config.Labels = map[string]string{}
+	}
+	if version := binary.Info.ModVersions[k6ModulePath]; version != "" {
+		cfg.Config.Labels[annotationVersion] = version
+	}
+	for k, v := range opts.Labels {
+		cfg.Config.Labels[k] = v
+	}
+
+	return mutate.ConfigFile(img, cfg)
+}
+
+// binaryLayer returns an uncompressed layer with the binary. The layer is written as it is read
+func binaryLayer(binaryPath string, imagePath string, modTime time.Time) (io.ReadCloser, error) {
+	binary, err := os.Open(binaryPath) //nolint:forbidigo,gosec
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := binary.Stat()
+	if err != nil {
+		_ = binary.Close()
+		return nil, err
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		defer binary.Close() //nolint:errcheck
+		_ = writer.CloseWithError(writeLayer(writer, binary, stat.Size(), imagePath, modTime))
+	}()
+
+	return reader, nil
+}
+
+// writeLayer writes a tar archive with the binary at the path and its parent directories
+func writeLayer(out io.Writer, binary io.Reader, size int64, imagePath string, modTime time.Time) error {
+	tw := tar.NewWriter(out)
+
+	name := strings.TrimPrefix(imagePath, "/")
+
+	// parent directories first
+	dirs := []string{}
+	for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+		dirs = append([]string{dir}, dirs...)
+	}
+	for _, dir := range dirs {
+		err := tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: dir + "/", Mode: 0o755, ModTime: modTime})
+		if err != nil {
+			return err
+		}
+	}
+
+	header := &tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0o755, Size: size, ModTime: modTime}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(tw, binary); err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+// Digest returns the digest of the image: the digest of the manifest for a single platform image
+// or of the index for a multi-platform image
+func (i *Image) Digest() (string, error) {
+	var digest v1.Hash
+	var err error
+	if i.index != nil {
+		digest, err = i.index.Digest()
+	} else {
+		digest, err = i.image.Digest()
+	}
+
+	return digest.String(), err
+}
+
+// Push pushes the image to the registry of the reference. The layers of the base image are copied from
+// its registry if they are not in the target repository. The credentials from the docker config are used
+// unless other credentials are given in the options
+func (i *Image) Push(ctx context.Context, ref name.Reference, opts ...remote.Option) error {
+	opts = append(defaultRemoteOptions(ctx), opts...)
+
+	if i.index != nil {
+		return remote.WriteIndex(ref, i.index, opts...)
+	}
+
+	return remote.Write(ref, i.image, opts...)
+}
+
+// WriteArchive writes the image as a tar archive in the OCI image layout, named after the reference.
+// The archive can be loaded using docker load.
+func (i *Image) WriteArchive(ref name.Reference, out io.Writer) error {
+	dir, err := os.MkdirTemp("", "k6foundry-image*") //nolint:forbidigo
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir) //nolint:errcheck,forbidigo
+
+	imageLayout, err := layout.Write(dir, empty.Index)
+	if err != nil {
+		return err
+	}
+
+	annotations := layout.WithAnnotations(map[string]string{
+		annotationRefName:   ref.Identifier(),
+		annotationImageName: imageName(ref),
+	})
+
+	if i.index != nil {
+		err = imageLayout.AppendIndex(i.index, annotations)
+	} else {
+		err = imageLayout.AppendImage(i.image, annotations)
+	}
+	if err != nil {
+		return err
+	}
+
+	return writeTar(out, dir)
+}
+
+// writeTar writes the content of the directory as a tar archive
+func writeTar(out io.Writer, dir string) error {
+	tw := tar.NewWriter(out)
+
+	err := filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		name, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		header := &tar.Header{Typeflag: tar.TypeReg, Name: filepath.ToSlash(name), Mode: 0o644, Size: info.Size()}
+		if err = tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		content, err := os.Open(file) //nolint:forbidigo,gosec
+		if err != nil {
+			return err
+		}
+		defer content.Close() //nolint:errcheck
+
+		_, err = io.Copy(tw, content)
+
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+// imageName returns the name of the image as used by docker (e.g. docker.io/grafana/k6:latest)
+func imageName(ref name.Reference) string {
+	repo := ref.Context()
+
+	registry := repo.RegistryStr()
+	if registry == name.DefaultRegistry {
+		registry = "docker.io"
+	}
+
+	sep := ":"
+	if _, isDigest := ref.(name.Digest); isDigest {
+		sep = "@"
+	}
+
+	return registry + "/" + repo.RepositoryStr() + sep + ref.Identifier()
+}
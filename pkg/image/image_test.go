@@ -0,0 +1,312 @@
+package image
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/grafana/k6foundry"
+)
+
+var testTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) //nolint:gochecknoglobals
+
+// pushBaseImage pushes a multi-platform base image with a layer containing the file etc/base.
+// The base image uses docker media types, as the images in docker hub
+func pushBaseImage(t *testing.T, ref name.Reference, platforms ...string) {
+	t.Helper()
+
+	base := filepath.Join(t.TempDir(), "base")
+	if err := os.WriteFile(base, []byte("base"), 0o600); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return binaryLayer(base, "/etc/base", testTime)
+	})
+	if err != nil {
+		t.Fatalf("creating base layer %v", err)
+	}
+
+	index := v1.ImageIndex(empty.Index)
+	for _, p := range platforms {
+		platform, _ := k6foundry.ParsePlatform(p)
+
+		img, err := mutate.AppendLayers(empty.Image, layer)
+		if err != nil {
+			t.Fatalf("creating base image %v", err)
+		}
+
+		cfg, _ := img.ConfigFile()
+		cfg = cfg.DeepCopy()
+		cfg.OS, cfg.Architecture = platform.OS, platform.Arch
+		cfg.Config = v1.Config{Env: []string{"PATH=/usr/bin"}, Cmd: []string{"/bin/sh"}}
+		if img, err = mutate.ConfigFile(img, cfg); err != nil {
+			t.Fatalf("creating base image %v", err)
+		}
+
+		index = mutate.AppendManifests(index, mutate.IndexAddendum{
+			Add:        img,
+			Descriptor: v1.Descriptor{Platform: cfg.Platform()},
+		})
+	}
+
+	if err = remote.WriteIndex(ref, index); err != nil {
+		t.Fatalf("pushing base image %v", err)
+	}
+}
+
+// files returns the files in the layers of the image
+func files(t *testing.T, img v1.Image) map[string]string {
+	t.Helper()
+
+	content := mutate.Extract(img)
+	defer content.Close() //nolint:errcheck
+
+	files := map[string]string{}
+	tr := tar.NewReader(content)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files
+		}
+		if err != nil {
+			t.Fatalf("invalid layer %v", err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			content, _ := io.ReadAll(tr)
+			files[header.Name] = string(content)
+		}
+	}
+}
+
+func binaries(t *testing.T, platforms ...string) []Binary {
+	t.Helper()
+
+	result := []Binary{}
+	for _, p := range platforms {
+		path := filepath.Join(t.TempDir(), "k6")
+		if err := os.WriteFile(path, []byte("k6 "+p), 0o600); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+		result = append(result, Binary{
+			Path: path,
+			Info: &k6foundry.BuildInfo{Platform: p, ModVersions: map[string]string{k6ModulePath: "v0.50.0"}},
+		})
+	}
+
+	return result
+}
+
+func TestPush(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		base        string
+		platforms   []string
+		expectFiles map[string]string
+		expectEnv   []string
+		expectError error
+	}{
+		{
+			title:       "scratch",
+			base:        Scratch,
+			platforms:   []string{"linux/amd64"},
+			expectFiles: map[string]string{"usr/bin/k6": "k6 linux/amd64"},
+		},
+		{
+			title:       "base image",
+			base:        "base:latest",
+			platforms:   []string{"linux/amd64"},
+			expectFiles: map[string]string{"etc/base": "base", "usr/bin/k6": "k6 linux/amd64"},
+			expectEnv:   []string{"PATH=/usr/bin"},
+		},
+		{
+			title:       "multi-platform",
+			base:        "base:latest",
+			platforms:   []string{"linux/amd64", "linux/arm64"},
+			expectFiles: map[string]string{"etc/base": "base", "usr/bin/k6": "k6 linux/arm64"},
+			expectEnv:   []string{"PATH=/usr/bin"},
+		},
+		{
+			title:       "platform not in base image",
+			base:        "base:latest",
+			platforms:   []string{"linux/s390x"},
+			expectError: ErrBaseImage,
+		},
+		{
+			title:       "base image not found",
+			base:        "base:missing",
+			platforms:   []string{"linux/amd64"},
+			expectError: ErrBaseImage,
+		},
+		{
+			title:       "darwin binary",
+			base:        Scratch,
+			platforms:   []string{"darwin/arm64"},
+			expectError: ErrUnsupportedPlatform,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+			defer srv.Close()
+
+			host := strings.TrimPrefix(srv.URL, "http://")
+
+			baseRef, _ := ParseReference(host+"/base:latest", true)
+			pushBaseImage(t, baseRef, "linux/amd64", "linux/arm64")
+
+			base := tc.base
+			if base != Scratch {
+				base = host + "/" + base
+			}
+
+			img, err := Build(
+				context.Background(),
+				binaries(t, tc.platforms...),
+				Options{BaseImage: base, Insecure: true, Created: testTime},
+			)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+			if tc.expectError != nil {
+				return
+			}
+
+			ref, _ := ParseReference(host+"/grafana/k6:custom", true)
+			if err = img.Push(context.Background(), ref); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			platform := tc.platforms[len(tc.platforms)-1]
+			parsed, _ := k6foundry.ParsePlatform(platform)
+
+			pushed, err := remote.Image(ref, remote.WithPlatform(v1.Platform{OS: parsed.OS, Architecture: parsed.Arch}))
+			if err != nil {
+				t.Fatalf("pulling image %v", err)
+			}
+
+			if got := files(t, pushed); !reflect.DeepEqual(got, tc.expectFiles) {
+				t.Fatalf("expected files %v got %v", tc.expectFiles, got)
+			}
+
+			cfg, err := pushed.ConfigFile()
+			if err != nil {
+				t.Fatalf("invalid config %v", err)
+			}
+
+			if cfg.OS+"/"+cfg.Architecture != platform {
+				t.Fatalf("expected platform %s got %s/%s", platform, cfg.OS, cfg.Architecture)
+			}
+
+			if !reflect.DeepEqual(cfg.Config.Entrypoint, []string{"/usr/bin/k6"}) || cfg.Config.Cmd != nil {
+				t.Fatalf("unexpected entrypoint %v cmd %v", cfg.Config.Entrypoint, cfg.Config.Cmd)
+			}
+
+			if !reflect.DeepEqual(cfg.Config.Env, tc.expectEnv) {
+				t.Fatalf("expected env %v got %v", tc.expectEnv, cfg.Config.Env)
+			}
+
+			if cfg.Config.Labels[annotationVersion] != "v0.50.0" {
+				t.Fatalf("expected version label got %v", cfg.Config.Labels)
+			}
+
+			digest, err := img.Digest()
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			desc, err := remote.Head(ref)
+			if err != nil || desc.Digest.String() != digest {
+				t.Fatalf("expected digest %s got %v %v", digest, desc, err)
+			}
+		})
+	}
+}
+
+func TestWriteArchive(t *testing.T) {
+	t.Parallel()
+
+	img, err := Build(context.Background(), binaries(t, "linux/amd64"), Options{BaseImage: Scratch})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	ref, _ := ParseReference("grafana/k6:custom", false)
+
+	out := &bytes.Buffer{}
+	if err = img.WriteArchive(ref, out); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// the archive is extracted to read it as an image layout
+	dir := t.TempDir()
+	tr := tar.NewReader(out)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid archive %v", err)
+		}
+
+		path := filepath.Join(dir, filepath.FromSlash(header.Name))
+		content, _ := io.ReadAll(tr)
+		if err = os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatalf("extracting archive %v", err)
+		}
+		if err = os.WriteFile(path, content, 0o600); err != nil {
+			t.Fatalf("extracting archive %v", err)
+		}
+	}
+
+	index, err := layout.ImageIndexFromPath(dir)
+	if err != nil {
+		t.Fatalf("invalid layout %v", err)
+	}
+
+	manifest, err := index.IndexManifest()
+	if err != nil || len(manifest.Manifests) != 1 {
+		t.Fatalf("invalid index %v %v", manifest, err)
+	}
+
+	digest, _ := img.Digest()
+	root := manifest.Manifests[0]
+	if root.Digest.String() != digest || root.Annotations[annotationImageName] != "docker.io/grafana/k6:custom" ||
+		root.Annotations[annotationRefName] != "custom" {
+		t.Fatalf("unexpected root descriptor %v", root)
+	}
+
+	archived, err := index.Image(root.Digest)
+	if err != nil {
+		t.Fatalf("reading image %v", err)
+	}
+
+	if got := files(t, archived); got["usr/bin/k6"] != "k6 linux/amd64" {
+		t.Fatalf("unexpected files %v", got)
+	}
+}
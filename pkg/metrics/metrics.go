@@ -0,0 +1,188 @@
+// Package metrics implements a k6foundry.Metrics collector exposed in the Prometheus text format
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/k6foundry"
+)
+
+// ContentType is the content type of the Prometheus text format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// bucket bounds of the histograms
+var (
+	durationBuckets = []float64{1, 5, 10, 30, 60, 120, 300, 600, 1800}              //nolint:gochecknoglobals
+	sizeBuckets     = []float64{32 << 20, 64 << 20, 96 << 20, 128 << 20, 256 << 20} //nolint:gochecknoglobals
+)
+
+// labelEscaper escapes the values of labels
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`) //nolint:gochecknoglobals
+
+// histogram counts the observations in buckets with upper bounds
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(bounds []float64, value float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(bounds))
+	}
+
+	for i, bound := range bounds {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += value
+}
+
+// Collector implements k6foundry.Metrics, keeping the metrics in memory. It is a http.Handler that
+// exposes the metrics in the Prometheus text format
+type Collector struct {
+	mu           sync.Mutex
+	durations    map[string]*histogram
+	results      map[string]uint64
+	sizes        map[string]*histogram
+	cacheLookups map[string]uint64
+}
+
+// NewCollector returns an empty Collector
+func NewCollector() *Collector {
+	return &Collector{
+		durations:    map[string]*histogram{},
+		results:      map[string]uint64{},
+		sizes:        map[string]*histogram{},
+		cacheLookups: map[string]uint64{},
+	}
+}
+
+// ObservePhase records the duration and result of a phase of a build
+func (c *Collector) ObservePhase(phase k6foundry.Phase, platform string, duration time.Duration, errClass string) {
+	result := errClass
+	if result == "" {
+		result = "success"
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := labels("phase", string(phase), "platform", platform)
+	if c.durations[key] == nil {
+		c.durations[key] = &histogram{}
+	}
+	c.durations[key].observe(durationBuckets, duration.Seconds())
+
+	c.results[labels("phase", string(phase), "platform", platform, "result", result)]++
+}
+
+// ObserveBinarySize records the size of a binary
+func (c *Collector) ObserveBinarySize(platform string, bytes int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := labels("platform", platform)
+	if c.sizes[key] == nil {
+		c.sizes[key] = &histogram{}
+	}
+	c.sizes[key].observe(sizeBuckets, float64(bytes))
+}
+
+// ObserveCacheLookup records a hit or miss of the build cache
+func (c *Collector) ObserveCacheLookup(hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cacheLookups[labels("result", result)]++
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format
+func (c *Collector) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	_ = c.Write(w)
+}
+
+// Write writes the metrics in the Prometheus text format
+func (c *Collector) Write(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := &strings.Builder{}
+
+	writeHistograms(out, "k6foundry_phase_duration_seconds", "Duration of the phases of the builds",
+		durationBuckets, c.durations)
+	writeCounters(out, "k6foundry_phases_total", "Phases of the builds by result (success or error class)",
+		c.results)
+	writeHistograms(out, "k6foundry_binary_size_bytes", "Size of the binaries", sizeBuckets, c.sizes)
+	writeCounters(out, "k6foundry_cache_lookups_total", "Lookups in the build cache by result (hit or miss)",
+		c.cacheLookups)
+
+	_, err := io.WriteString(w, out.String())
+
+	return err
+}
+
+// labels returns the labels in the Prometheus text format, from pairs of names and values
+func labels(pairs ...string) string {
+	formatted := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		formatted = append(formatted, fmt.Sprintf(`%s="%s"`, pairs[i], labelEscaper.Replace(pairs[i+1])))
+	}
+
+	return strings.Join(formatted, ",")
+}
+
+// sortedKeys returns the keys of the map in order, so the output is stable
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func writeCounters(out *strings.Builder, name string, help string, counters map[string]uint64) {
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+
+	for _, key := range sortedKeys(counters) {
+		fmt.Fprintf(out, "%s{%s} %d\n", name, key, counters[key])
+	}
+}
+
+func writeHistograms(
+	out *strings.Builder,
+	name string,
+	help string,
+	bounds []float64,
+	histograms map[string]*histogram,
+) {
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+
+	for _, key := range sortedKeys(histograms) {
+		h := histograms[key]
+		for i, bound := range bounds {
+			le := strconv.FormatFloat(bound, 'g', -1, 64)
+			fmt.Fprintf(out, "%s_bucket{%s,le=%q} %d\n", name, key, le, h.counts[i])
+		}
+		fmt.Fprintf(out, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, key, h.count)
+		fmt.Fprintf(out, "%s_sum{%s} %s\n", name, key, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(out, "%s_count{%s} %d\n", name, key, h.count)
+	}
+}
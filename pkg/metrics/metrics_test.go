@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/k6foundry"
+)
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+
+	c := NewCollector()
+	c.ObservePhase(k6foundry.PhaseBuild, "linux/amd64", 20*time.Second, "")
+	c.ObservePhase(k6foundry.PhaseBuild, "linux/amd64", 2*time.Second, "module_not_found")
+	c.ObservePhase(k6foundry.PhaseResolve, "", 500*time.Millisecond, "")
+	c.ObserveBinarySize("linux/amd64", 50<<20)
+	c.ObserveCacheLookup(true)
+	c.ObserveCacheLookup(false)
+	c.ObserveCacheLookup(false)
+
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != ContentType {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	output := rec.Body.String()
+
+	expected := []string{
+		"# TYPE k6foundry_phase_duration_seconds histogram",
+		`k6foundry_phase_duration_seconds_bucket{phase="build",platform="linux/amd64",le="5"} 1`,
+		`k6foundry_phase_duration_seconds_bucket{phase="build",platform="linux/amd64",le="30"} 2`,
+		`k6foundry_phase_duration_seconds_bucket{phase="build",platform="linux/amd64",le="+Inf"} 2`,
+		`k6foundry_phase_duration_seconds_sum{phase="build",platform="linux/amd64"} 22`,
+		`k6foundry_phase_duration_seconds_count{phase="resolve",platform=""} 1`,
+		`k6foundry_phases_total{phase="build",platform="linux/amd64",result="success"} 1`,
+		`k6foundry_phases_total{phase="build",platform="linux/amd64",result="module_not_found"} 1`,
+		`k6foundry_binary_size_bytes_bucket{platform="linux/amd64",le="3.3554432e+07"} 0`,
+		`k6foundry_binary_size_bytes_bucket{platform="linux/amd64",le="6.7108864e+07"} 1`,
+		`k6foundry_cache_lookups_total{result="hit"} 1`,
+		`k6foundry_cache_lookups_total{result="miss"} 2`,
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(output, line+"\n") {
+			t.Fatalf("expected %q in output:\n%s", line, output)
+		}
+	}
+}
+
+func TestLabelsEscaping(t *testing.T) {
+	t.Parallel()
+
+	got := labels("result", `a "b"\c`+"\n")
+	expected := `result="a \"b\"\\c\n"`
+	if got != expected {
+		t.Fatalf("expected %s got %s", expected, got)
+	}
+}
@@ -0,0 +1,74 @@
+package packaging
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"time"
+)
+
+func writeTarGz(out io.Writer, files []file, modTime time.Time) error {
+	gz := gzip.NewWriter(out)
+
+	if err := writeTar(gz, files, modTime); err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// writeTar writes the files as a tar archive. Files with a path (e.g. usr/bin/k6) must be preceded
+// by their directories.
+func writeTar(out io.Writer, files []file, modTime time.Time) error {
+	tw := tar.NewWriter(out)
+
+	for _, f := range files {
+		header := &tar.Header{
+			Name:    f.name,
+			Mode:    int64(f.mode.Perm()),
+			Size:    int64(len(f.content)),
+			ModTime: modTime,
+			Uname:   "root",
+			Gname:   "root",
+		}
+
+		if f.mode.IsDir() {
+			header.Typeflag = tar.TypeDir
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if _, err := tw.Write(f.content); err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+func writeZip(out io.Writer, files []file, modTime time.Time) error {
+	zw := zip.NewWriter(out)
+
+	for _, f := range files {
+		header := &zip.FileHeader{
+			Name:     f.name,
+			Method:   zip.Deflate,
+			Modified: modTime,
+		}
+		header.SetMode(f.mode)
+
+		w, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+
+		if _, err = w.Write(f.content); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
@@ -0,0 +1,150 @@
+package packaging
+
+import (
+	"bytes"
+	"crypto/md5" //nolint:gosec
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/grafana/k6foundry"
+)
+
+const defaultMaintainer = "k6foundry"
+
+// debArch maps the go architectures to Debian architectures
+var debArch = map[string]string{ //nolint:gochecknoglobals
+	"amd64": "amd64",
+	"arm64": "arm64",
+	"386":   "i386",
+	"arm":   "armhf",
+}
+
+// writeDeb writes a Debian package with the binary in /usr/bin and the docs in /usr/share/doc/<name>
+func writeDeb(
+	out io.Writer,
+	platform k6foundry.Platform,
+	info *k6foundry.BuildInfo,
+	binary file,
+	docs []file,
+	opts Options,
+) error {
+	docDir := path.Join("usr", "share", "doc", opts.Name)
+
+	data := []file{
+		{name: "./usr/", mode: os.ModeDir | 0o755},
+		{name: "./usr/bin/", mode: os.ModeDir | 0o755},
+		{name: "./usr/bin/" + binary.name, mode: binary.mode, content: binary.content},
+	}
+	if len(docs) > 0 {
+		data = append(data,
+			file{name: "./usr/share/", mode: os.ModeDir | 0o755},
+			file{name: "./usr/share/doc/", mode: os.ModeDir | 0o755},
+			file{name: "./" + docDir + "/", mode: os.ModeDir | 0o755},
+		)
+	}
+	for _, doc := range docs {
+		data = append(data, file{name: "./" + path.Join(docDir, doc.name), mode: doc.mode, content: doc.content})
+	}
+
+	size := 0
+	md5sums := &strings.Builder{}
+	for _, f := range data {
+		if f.mode.IsDir() {
+			continue
+		}
+		size += len(f.content)
+		fmt.Fprintf(md5sums, "%x  %s\n", md5.Sum(f.content), strings.TrimPrefix(f.name, "./")) //nolint:gosec
+	}
+
+	maintainer := opts.Maintainer
+	if maintainer == "" {
+		maintainer = defaultMaintainer
+	}
+
+	control := &strings.Builder{}
+	fmt.Fprintf(control, "Package: %s\n", opts.Name)
+	fmt.Fprintf(control, "Version: %s\n", packageVersion(info.ModVersions[k6ModulePath]))
+	fmt.Fprintf(control, "Architecture: %s\n", debArch[platform.Arch])
+	fmt.Fprintf(control, "Maintainer: %s\n", maintainer)
+	fmt.Fprintf(control, "Installed-Size: %d\n", (size+1023)/1024)
+	fmt.Fprintf(control, "Section: utils\n")
+	fmt.Fprintf(control, "Priority: optional\n")
+	fmt.Fprintf(control, "Description: %s\n", description(info, opts))
+
+	controlTar, err := tarGzBytes([]file{
+		{name: "./", mode: os.ModeDir | 0o755},
+		{name: "./control", mode: 0o644, content: []byte(control.String())},
+		{name: "./md5sums", mode: 0o644, content: []byte(md5sums.String())},
+	}, opts)
+	if err != nil {
+		return err
+	}
+
+	dataTar, err := tarGzBytes(append([]file{{name: "./", mode: os.ModeDir | 0o755}}, data...), opts)
+	if err != nil {
+		return err
+	}
+
+	ar := newArWriter(out, opts)
+	for _, member := range []file{
+		{name: "debian-binary", content: []byte("2.0\n")},
+		{name: "control.tar.gz", content: controlTar},
+		{name: "data.tar.gz", content: dataTar},
+	} {
+		if err = ar.add(member.name, member.content); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func tarGzBytes(files []file, opts Options) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	if err := writeTarGz(buffer, files, opts.ModTime); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// arWriter writes an ar archive, as used by Debian packages
+type arWriter struct {
+	out         io.Writer
+	opts        Options
+	wroteHeader bool
+}
+
+func newArWriter(out io.Writer, opts Options) *arWriter {
+	return &arWriter{out: out, opts: opts}
+}
+
+func (w *arWriter) add(name string, content []byte) error {
+	if !w.wroteHeader {
+		if _, err := io.WriteString(w.out, "!<arch>\n"); err != nil {
+			return err
+		}
+		w.wroteHeader = true
+	}
+
+	header := fmt.Sprintf("%-16s%-12d%-6d%-6d%-8s%-10d`\n", name, w.opts.ModTime.Unix(), 0, 0, "100644", len(content))
+	if _, err := io.WriteString(w.out, header); err != nil {
+		return err
+	}
+
+	if _, err := w.out.Write(content); err != nil {
+		return err
+	}
+
+	// members are aligned to 2 bytes
+	if len(content)%2 != 0 {
+		if _, err := io.WriteString(w.out, "\n"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
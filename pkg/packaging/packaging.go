@@ -0,0 +1,190 @@
+// Package packaging wraps the binaries built by k6foundry into distributable artifacts
+package packaging
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/grafana/k6foundry"
+)
+
+const (
+	// TarGz is a gzip compressed tar archive
+	TarGz = "tar.gz"
+	// Zip is a zip archive
+	Zip = "zip"
+	// Deb is a Debian package
+	Deb = "deb"
+	// RPM is a RPM package
+	RPM = "rpm"
+
+	defaultName = "k6"
+	// name of the file with the build info included in the package
+	buildInfoFile = "buildinfo.json"
+
+	k6ModulePath = "go.k6.io/k6"
+)
+
+var (
+	// ErrUnsupportedFormat is returned when the package format is not supported
+	ErrUnsupportedFormat = errors.New("unsupported package format")
+	// ErrUnsupportedPlatform is returned when the package format doesn't support the platform of the binary
+	ErrUnsupportedPlatform = errors.New("platform not supported by package format")
+	// ErrPackaging is returned when the package can't be created
+	ErrPackaging = errors.New("packaging")
+)
+
+// Options defines the content and metadata of a package
+type Options struct {
+	// name of the package and the binary in it. Defaults to k6
+	Name string
+	// additional files included in the package (e.g. license files)
+	Files []string
+	// include the build info in the package
+	IncludeBuildInfo bool
+	// maintainer of the package (deb and rpm)
+	Maintainer string
+	// description of the package (deb and rpm)
+	Description string
+	// modification time of the files. Defaults to the current time
+	ModTime time.Time
+}
+
+// file is a file included in a package
+type file struct {
+	name    string
+	mode    os.FileMode
+	content []byte
+}
+
+// Formats returns the supported package formats
+func Formats() []string {
+	return []string{TarGz, Zip, Deb, RPM}
+}
+
+// FileName returns the name of the package file for a build, following the conventions of the format.
+// For example k6-v0.50.0-linux-amd64.tar.gz, k6_0.50.0_amd64.deb or k6-0.50.0-1.x86_64.rpm
+func FileName(format string, name string, info *k6foundry.BuildInfo) string {
+	if name == "" {
+		name = defaultName
+	}
+
+	version := info.ModVersions[k6ModulePath]
+	platform, _ := k6foundry.ParsePlatform(info.Platform)
+
+	switch format {
+	case Deb:
+		return fmt.Sprintf("%s_%s_%s.deb", name, packageVersion(version), debArch[platform.Arch])
+	case RPM:
+		return fmt.Sprintf("%s-%s-%s.%s.rpm", name, packageVersion(version), rpmRelease, rpmArch[platform.Arch])
+	default:
+		return fmt.Sprintf("%s-%s-%s-%s.%s", name, version, platform.OS, platform.Arch, format)
+	}
+}
+
+// Package writes the package for the binary into the out io.Writer
+func Package(format string, binaryPath string, info *k6foundry.BuildInfo, opts Options, out io.Writer) error {
+	if opts.Name == "" {
+		opts.Name = defaultName
+	}
+
+	if opts.ModTime.IsZero() {
+		opts.ModTime = time.Now()
+	}
+
+	platform, err := k6foundry.ParsePlatform(info.Platform)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPackaging, err)
+	}
+
+	if format == Deb || format == RPM {
+		_, supported := debArch[platform.Arch]
+		if platform.OS != "linux" || !supported {
+			return fmt.Errorf("%w: %s %s", ErrUnsupportedPlatform, format, platform)
+		}
+	}
+
+	binary, err := readFile(binaryPath, platform.BinaryName(opts.Name), 0o755)
+	if err != nil {
+		return err
+	}
+
+	docs := []file{}
+	for _, path := range opts.Files {
+		f, err := readFile(path, filepath.Base(path), 0o644)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, f)
+	}
+
+	if opts.IncludeBuildInfo {
+		content, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return fmt.Errorf("%w: marshalling build info %w", ErrPackaging, err)
+		}
+		docs = append(docs, file{name: buildInfoFile, mode: 0o644, content: content})
+	}
+
+	switch format {
+	case TarGz:
+		err = writeTarGz(out, append([]file{binary}, docs...), opts.ModTime)
+	case Zip:
+		err = writeZip(out, append([]file{binary}, docs...), opts.ModTime)
+	case Deb:
+		err = writeDeb(out, platform, info, binary, docs, opts)
+	case RPM:
+		err = writeRPM(out, platform, info, binary, docs, opts)
+	default:
+		return fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPackaging, err)
+	}
+
+	return nil
+}
+
+func readFile(path string, name string, mode os.FileMode) (file, error) {
+	content, err := os.ReadFile(path) //nolint:forbidigo,gosec
+	if err != nil {
+		return file{}, fmt.Errorf("%w: reading %s %w", ErrPackaging, path, err)
+	}
+
+	return file{name: name, mode: mode, content: content}, nil
+}
+
+// packageVersion converts a module version to a version valid for deb and rpm packages,
+// removing the v prefix and replacing the hyphens (e.g. v0.0.0-20240101000000-abcdef -> 0.0.0~20240101000000~abcdef)
+func packageVersion(version string) string {
+	return strings.ReplaceAll(strings.TrimPrefix(version, "v"), "-", "~")
+}
+
+// description returns the description of the package, including the extensions in the binary
+func description(info *k6foundry.BuildInfo, opts Options) string {
+	if opts.Description != "" {
+		return opts.Description
+	}
+
+	exts := []string{}
+	for mod, version := range info.ModVersions {
+		if mod != k6ModulePath {
+			exts = append(exts, mod+" "+version)
+		}
+	}
+
+	if len(exts) == 0 {
+		return "k6 load testing tool"
+	}
+
+	slices.Sort(exts)
+
+	return "k6 load testing tool with extensions: " + strings.Join(exts, ", ")
+}
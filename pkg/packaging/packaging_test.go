@@ -0,0 +1,334 @@
+package packaging
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/md5" //nolint:gosec
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/grafana/k6foundry"
+)
+
+func setup(t *testing.T) (string, []string, *k6foundry.BuildInfo) {
+	t.Helper()
+
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "k6")
+	license := filepath.Join(dir, "LICENSE")
+
+	for path, content := range map[string]string{binary: "binary", license: "license"} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	return binary, []string{license}, &k6foundry.BuildInfo{
+		Platform:    "linux/amd64",
+		ModVersions: map[string]string{"go.k6.io/k6": "v0.50.0"},
+	}
+}
+
+func TestFileName(t *testing.T) {
+	t.Parallel()
+
+	info := &k6foundry.BuildInfo{
+		Platform:    "linux/arm64",
+		ModVersions: map[string]string{"go.k6.io/k6": "v0.50.0"},
+	}
+
+	testCases := []struct {
+		format string
+		expect string
+	}{
+		{format: TarGz, expect: "k6-v0.50.0-linux-arm64.tar.gz"},
+		{format: Zip, expect: "k6-v0.50.0-linux-arm64.zip"},
+		{format: Deb, expect: "k6_0.50.0_arm64.deb"},
+		{format: RPM, expect: "k6-0.50.0-1.aarch64.rpm"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.format, func(t *testing.T) {
+			t.Parallel()
+
+			if got := FileName(tc.format, "", info); got != tc.expect {
+				t.Fatalf("expected %q got %q", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestPackage(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		format string
+		read   func(*testing.T, []byte) map[string]string
+		expect map[string]string
+	}{
+		{
+			format: TarGz,
+			read:   readTarGz,
+			expect: map[string]string{"k6": "binary", "LICENSE": "license"},
+		},
+		{
+			format: Zip,
+			read:   readZip,
+			expect: map[string]string{"k6": "binary", "LICENSE": "license"},
+		},
+		{
+			format: Deb,
+			read:   readDeb,
+			expect: map[string]string{"./usr/bin/k6": "binary", "./usr/share/doc/k6/LICENSE": "license"},
+		},
+		{
+			format: RPM,
+			read:   readRPM,
+			expect: map[string]string{
+				"./usr/bin/k6":               "binary",
+				"./usr/share/doc/k6":         "",
+				"./usr/share/doc/k6/LICENSE": "license",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.format, func(t *testing.T) {
+			t.Parallel()
+
+			binary, files, info := setup(t)
+
+			out := &bytes.Buffer{}
+			err := Package(tc.format, binary, info, Options{Files: files}, out)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			got := tc.read(t, out.Bytes())
+			if !reflect.DeepEqual(got, tc.expect) {
+				t.Fatalf("expected %v got %v", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestPackageErrors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		format      string
+		platform    string
+		expectError error
+	}{
+		{
+			title:       "unsupported format",
+			format:      "dmg",
+			platform:    "linux/amd64",
+			expectError: ErrUnsupportedFormat,
+		},
+		{
+			title:       "deb for darwin",
+			format:      Deb,
+			platform:    "darwin/arm64",
+			expectError: ErrUnsupportedPlatform,
+		},
+		{
+			title:       "rpm for unsupported arch",
+			format:      RPM,
+			platform:    "linux/s390x",
+			expectError: ErrUnsupportedPlatform,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			binary, _, info := setup(t)
+			info.Platform = tc.platform
+
+			err := Package(tc.format, binary, info, Options{}, io.Discard)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func readTar(t *testing.T, in io.Reader) map[string]string {
+	t.Helper()
+
+	files := map[string]string{}
+	tr := tar.NewReader(in)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files
+		}
+		if err != nil {
+			t.Fatalf("reading tar %v", err)
+		}
+
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+
+		content, _ := io.ReadAll(tr)
+		files[header.Name] = string(content)
+	}
+}
+
+func readTarGz(t *testing.T, content []byte) map[string]string {
+	t.Helper()
+
+	gz, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("reading gzip %v", err)
+	}
+
+	return readTar(t, gz)
+}
+
+func readZip(t *testing.T, content []byte) map[string]string {
+	t.Helper()
+
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatalf("reading zip %v", err)
+	}
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		r, _ := f.Open()
+		content, _ := io.ReadAll(r)
+		files[f.Name] = string(content)
+	}
+
+	return files
+}
+
+// readDeb returns the files in the data archive of a Debian package
+func readDeb(t *testing.T, content []byte) map[string]string {
+	t.Helper()
+
+	if !bytes.HasPrefix(content, []byte("!<arch>\n")) {
+		t.Fatal("invalid ar archive")
+	}
+	content = content[8:]
+
+	members := map[string][]byte{}
+	for len(content) >= 60 {
+		name := strings.TrimSpace(string(content[:16]))
+		size, err := strconv.Atoi(strings.TrimSpace(string(content[48:58])))
+		if err != nil {
+			t.Fatalf("invalid ar header %v", err)
+		}
+		members[name] = content[60 : 60+size]
+		content = content[60+size+size%2:]
+	}
+
+	if string(members["debian-binary"]) != "2.0\n" {
+		t.Fatalf("invalid debian-binary %q", members["debian-binary"])
+	}
+
+	control := readTarGz(t, members["control.tar.gz"])
+	if !strings.Contains(control["./control"], "Version: 0.50.0\n") {
+		t.Fatalf("invalid control %q", control["./control"])
+	}
+
+	return readTarGz(t, members["data.tar.gz"])
+}
+
+// readRPM verifies the signature of a RPM package and returns the files in its payload
+func readRPM(t *testing.T, content []byte) map[string]string {
+	t.Helper()
+
+	if !bytes.HasPrefix(content, []byte{0xed, 0xab, 0xee, 0xdb}) {
+		t.Fatal("invalid lead")
+	}
+	content = content[96:]
+
+	signature, sigLen := readRPMHeader(t, content)
+	content = content[sigLen+(8-sigLen%8)%8:]
+
+	header, headerLen := readRPMHeader(t, content)
+	headerSHA256 := sha256.Sum256(content[:headerLen])
+	if digest, _, _ := bytes.Cut(signature[rpmSigSHA256], []byte{0}); string(digest) != hex.EncodeToString(headerSHA256[:]) {
+		t.Fatal("header digest doesn't match")
+	}
+
+	md5Sum := md5.Sum(content) //nolint:gosec
+	if !bytes.Equal(signature[rpmSigMD5][:16], md5Sum[:]) {
+		t.Fatal("package digest doesn't match")
+	}
+
+	for tag, expect := range map[uint32]string{rpmTagName: "k6", rpmTagVersion: "0.50.0", rpmTagArch: "x86_64"} {
+		if got, _, _ := bytes.Cut(header[tag], []byte{0}); string(got) != expect {
+			t.Fatalf("expected tag %d to be %q got %q", tag, expect, got)
+		}
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(content[headerLen:]))
+	if err != nil {
+		t.Fatalf("reading payload %v", err)
+	}
+	payload, _ := io.ReadAll(gz)
+
+	// cpio newc archive
+	files := map[string]string{}
+	for {
+		if string(payload[:6]) != "070701" {
+			t.Fatal("invalid cpio entry")
+		}
+		field := func(i int) int {
+			v, _ := strconv.ParseInt(string(payload[6+8*i:14+8*i]), 16, 64)
+			return int(v)
+		}
+		size, nameSize := field(6), field(11)
+		name := string(payload[110 : 110+nameSize-1])
+		dataStart := (110 + nameSize + 3) &^ 3
+		if name == "TRAILER!!!" {
+			return files
+		}
+		files[name] = string(payload[dataStart : dataStart+size])
+		payload = payload[(dataStart+size+3)&^3:]
+	}
+}
+
+// readRPMHeader returns the data of each tag in a RPM header structure and the length of the structure
+func readRPMHeader(t *testing.T, content []byte) (map[uint32][]byte, int) {
+	t.Helper()
+
+	if !bytes.HasPrefix(content, []byte{0x8e, 0xad, 0xe8, 0x01}) {
+		t.Fatal("invalid header")
+	}
+
+	nindex := int(binary.BigEndian.Uint32(content[8:]))
+	size := int(binary.BigEndian.Uint32(content[12:]))
+	store := content[16+16*nindex : 16+16*nindex+size]
+
+	entries := map[uint32][]byte{}
+	for i := 0; i < nindex; i++ {
+		entry := content[16+16*i:]
+		tag := binary.BigEndian.Uint32(entry)
+		offset := int(binary.BigEndian.Uint32(entry[8:]))
+		entries[tag] = store[offset:]
+	}
+
+	return entries, 16 + 16*nindex + size
+}
@@ -0,0 +1,432 @@
+package packaging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"  //nolint:gosec
+	"crypto/sha1" //nolint:gosec
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/grafana/k6foundry"
+)
+
+const (
+	rpmRelease = "1"
+	rpmLicense = "AGPL-3.0-only"
+
+	// data types of the header entries
+	rpmInt16       = 3
+	rpmInt32       = 4
+	rpmString      = 6
+	rpmBin         = 7
+	rpmStringArray = 8
+	rpmI18NString  = 9
+
+	// region tags of the signature and main headers
+	rpmTagHeaderSignatures = 62
+	rpmTagHeaderImmutable  = 63
+
+	// signature header tags
+	rpmSigSHA1        = 269
+	rpmSigSHA256      = 273
+	rpmSigSize        = 1000
+	rpmSigMD5         = 1004
+	rpmSigPayloadSize = 1007
+
+	// main header tags
+	rpmTagI18NTable         = 100
+	rpmTagName              = 1000
+	rpmTagVersion           = 1001
+	rpmTagRelease           = 1002
+	rpmTagSummary           = 1004
+	rpmTagDescription       = 1005
+	rpmTagBuildTime         = 1006
+	rpmTagSize              = 1009
+	rpmTagLicense           = 1014
+	rpmTagGroup             = 1016
+	rpmTagOS                = 1021
+	rpmTagArch              = 1022
+	rpmTagFileSizes         = 1028
+	rpmTagFileModes         = 1030
+	rpmTagFileRdevs         = 1033
+	rpmTagFileMtimes        = 1034
+	rpmTagFileDigests       = 1035
+	rpmTagFileLinkTos       = 1036
+	rpmTagFileFlags         = 1037
+	rpmTagFileUserName      = 1039
+	rpmTagFileGroupName     = 1040
+	rpmTagSourceRPM         = 1044
+	rpmTagProvideName       = 1047
+	rpmTagRequireFlags      = 1048
+	rpmTagRequireName       = 1049
+	rpmTagRequireVersion    = 1050
+	rpmTagFileDevices       = 1095
+	rpmTagFileInodes        = 1096
+	rpmTagFileLangs         = 1097
+	rpmTagProvideFlags      = 1112
+	rpmTagProvideVersion    = 1113
+	rpmTagDirIndexes        = 1116
+	rpmTagBaseNames         = 1117
+	rpmTagDirNames          = 1118
+	rpmTagPayloadFormat     = 1124
+	rpmTagPayloadCompressor = 1125
+	rpmTagPayloadFlags      = 1126
+	rpmTagFileDigestAlgo    = 5011
+
+	rpmFileDoc          = 1 << 1
+	rpmSenseLess        = 1 << 1
+	rpmSenseEqual       = 1 << 3
+	rpmSenseRPMLib      = 1 << 24
+	rpmDigestAlgoSHA256 = 8
+)
+
+// rpmArch maps the go architectures to RPM architectures
+var rpmArch = map[string]string{ //nolint:gochecknoglobals
+	"amd64": "x86_64",
+	"arm64": "aarch64",
+	"386":   "i386",
+	"arm":   "armv7hl",
+}
+
+// rpmArchNum maps the go architectures to the architecture numbers used in the RPM lead
+var rpmArchNum = map[string]uint16{ //nolint:gochecknoglobals
+	"amd64": 1,
+	"arm64": 19,
+	"386":   1,
+	"arm":   12,
+}
+
+// rpmFile is a file in the RPM payload
+type rpmFile struct {
+	dir  string
+	base string
+	file
+	flags uint32
+}
+
+// writeRPM writes a RPM package with the binary in /usr/bin and the docs in /usr/share/doc/<name>
+func writeRPM(
+	out io.Writer,
+	platform k6foundry.Platform,
+	info *k6foundry.BuildInfo,
+	binary file,
+	docs []file,
+	opts Options,
+) error {
+	docDir := path.Join("/usr/share/doc", opts.Name)
+
+	files := []rpmFile{{dir: "/usr/bin/", base: binary.name, file: binary}}
+	if len(docs) > 0 {
+		files = append(files, rpmFile{
+			dir:  "/usr/share/doc/",
+			base: opts.Name,
+			file: file{mode: os.ModeDir | 0o755},
+		})
+	}
+	for _, doc := range docs {
+		files = append(files, rpmFile{dir: docDir + "/", base: doc.name, file: doc, flags: rpmFileDoc})
+	}
+
+	payload, payloadSize, err := rpmPayload(files, opts)
+	if err != nil {
+		return err
+	}
+
+	header := rpmMainHeader(platform, info, files, opts).bytes(rpmTagHeaderImmutable)
+
+	signed := md5.New() //nolint:gosec
+	signed.Write(header)
+	signed.Write(payload)
+	headerSHA1 := sha1.Sum(header) //nolint:gosec
+	headerSHA256 := sha256.Sum256(header)
+
+	signature := &rpmHeader{}
+	signature.addString(rpmSigSHA1, hex.EncodeToString(headerSHA1[:]))
+	signature.addString(rpmSigSHA256, hex.EncodeToString(headerSHA256[:]))
+	signature.addInt32(rpmSigSize, uint32(len(header)+len(payload)))
+	signature.addBin(rpmSigMD5, signed.Sum(nil))
+	signature.addInt32(rpmSigPayloadSize, uint32(payloadSize))
+
+	sigBytes := signature.bytes(rpmTagHeaderSignatures)
+	// the signature header is aligned to 8 bytes
+	if pad := len(sigBytes) % 8; pad != 0 {
+		sigBytes = append(sigBytes, make([]byte, 8-pad)...)
+	}
+
+	version := packageVersion(info.ModVersions[k6ModulePath])
+
+	for _, part := range [][]byte{rpmLead(opts.Name+"-"+version+"-"+rpmRelease, platform), sigBytes, header, payload} {
+		if _, err = out.Write(part); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rpmLead returns the (legacy) lead of a binary RPM package
+func rpmLead(name string, platform k6foundry.Platform) []byte {
+	lead := &bytes.Buffer{}
+	lead.Write([]byte{0xed, 0xab, 0xee, 0xdb, 3, 0})
+	_ = binary.Write(lead, binary.BigEndian, uint16(0)) // binary package
+	_ = binary.Write(lead, binary.BigEndian, rpmArchNum[platform.Arch])
+
+	leadName := make([]byte, 66)
+	copy(leadName[:65], name)
+	lead.Write(leadName)
+
+	_ = binary.Write(lead, binary.BigEndian, uint16(1)) // linux
+	_ = binary.Write(lead, binary.BigEndian, uint16(5)) // signature in header format
+	lead.Write(make([]byte, 16))
+
+	return lead.Bytes()
+}
+
+func rpmMainHeader(platform k6foundry.Platform, info *k6foundry.BuildInfo, files []rpmFile, opts Options) *rpmHeader {
+	version := packageVersion(info.ModVersions[k6ModulePath])
+
+	var (
+		sizes, mtimes, flags, devices, inodes, dirIndexes []uint32
+		modes, rdevs                                      []uint16
+		digests, linkTos, users, groups, langs, bases     []string
+		dirs                                              []string
+		size                                              int
+	)
+
+	for i, f := range files {
+		mode := uint16(0o100000) | uint16(f.mode.Perm())
+		digest := ""
+		if f.mode.IsDir() {
+			mode = uint16(0o40000) | uint16(f.mode.Perm())
+		} else {
+			sum := sha256.Sum256(f.content)
+			digest = hex.EncodeToString(sum[:])
+		}
+
+		dirIndex := len(dirs)
+		for j, dir := range dirs {
+			if dir == f.dir {
+				dirIndex = j
+			}
+		}
+		if dirIndex == len(dirs) {
+			dirs = append(dirs, f.dir)
+		}
+
+		size += len(f.content)
+		sizes = append(sizes, uint32(len(f.content)))
+		mtimes = append(mtimes, uint32(opts.ModTime.Unix()))
+		flags = append(flags, f.flags)
+		devices = append(devices, 1)
+		inodes = append(inodes, uint32(i+1))
+		dirIndexes = append(dirIndexes, uint32(dirIndex))
+		modes = append(modes, mode)
+		rdevs = append(rdevs, 0)
+		digests = append(digests, digest)
+		linkTos = append(linkTos, "")
+		users = append(users, "root")
+		groups = append(groups, "root")
+		langs = append(langs, "")
+		bases = append(bases, f.base)
+	}
+
+	summary := description(info, opts)
+
+	h := &rpmHeader{}
+	h.addStrings(rpmTagI18NTable, "C")
+	h.addString(rpmTagName, opts.Name)
+	h.addString(rpmTagVersion, version)
+	h.addString(rpmTagRelease, rpmRelease)
+	h.addI18NString(rpmTagSummary, summary)
+	h.addI18NString(rpmTagDescription, summary)
+	h.addInt32(rpmTagBuildTime, uint32(opts.ModTime.Unix()))
+	h.addInt32(rpmTagSize, uint32(size))
+	h.addString(rpmTagLicense, rpmLicense)
+	h.addI18NString(rpmTagGroup, "Unspecified")
+	h.addString(rpmTagOS, "linux")
+	h.addString(rpmTagArch, rpmArch[platform.Arch])
+	h.addInt32(rpmTagFileSizes, sizes...)
+	h.addInt16(rpmTagFileModes, modes...)
+	h.addInt16(rpmTagFileRdevs, rdevs...)
+	h.addInt32(rpmTagFileMtimes, mtimes...)
+	h.addStrings(rpmTagFileDigests, digests...)
+	h.addStrings(rpmTagFileLinkTos, linkTos...)
+	h.addInt32(rpmTagFileFlags, flags...)
+	h.addStrings(rpmTagFileUserName, users...)
+	h.addStrings(rpmTagFileGroupName, groups...)
+	h.addString(rpmTagSourceRPM, fmt.Sprintf("%s-%s-%s.src.rpm", opts.Name, version, rpmRelease))
+	h.addStrings(rpmTagProvideName, opts.Name)
+	h.addInt32(rpmTagProvideFlags, rpmSenseEqual)
+	h.addStrings(rpmTagProvideVersion, version+"-"+rpmRelease)
+	h.addInt32(rpmTagRequireFlags,
+		rpmSenseLess|rpmSenseEqual|rpmSenseRPMLib,
+		rpmSenseLess|rpmSenseEqual|rpmSenseRPMLib,
+		rpmSenseLess|rpmSenseEqual|rpmSenseRPMLib,
+	)
+	h.addStrings(rpmTagRequireName,
+		"rpmlib(CompressedFileNames)",
+		"rpmlib(FileDigests)",
+		"rpmlib(PayloadFilesHavePrefix)",
+	)
+	h.addStrings(rpmTagRequireVersion, "3.0.4-1", "4.6.0-1", "4.0-1")
+	h.addInt32(rpmTagFileDevices, devices...)
+	h.addInt32(rpmTagFileInodes, inodes...)
+	h.addStrings(rpmTagFileLangs, langs...)
+	h.addInt32(rpmTagDirIndexes, dirIndexes...)
+	h.addStrings(rpmTagBaseNames, bases...)
+	h.addStrings(rpmTagDirNames, dirs...)
+	h.addString(rpmTagPayloadFormat, "cpio")
+	h.addString(rpmTagPayloadCompressor, "gzip")
+	h.addString(rpmTagPayloadFlags, "9")
+	h.addInt32(rpmTagFileDigestAlgo, rpmDigestAlgoSHA256)
+
+	return h
+}
+
+// rpmPayload returns the files as a gzip compressed cpio (newc) archive and the uncompressed size
+func rpmPayload(files []rpmFile, opts Options) ([]byte, int, error) {
+	archive := &bytes.Buffer{}
+
+	writeEntry := func(ino int, mode uint32, name string, content []byte) {
+		fmt.Fprintf(archive, "070701%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X",
+			ino, mode, 0, 0, 1, uint32(opts.ModTime.Unix()), len(content), 0, 0, 0, 0, len(name)+1, 0)
+		archive.WriteString(name)
+		archive.WriteByte(0)
+		pad(archive)
+		archive.Write(content)
+		pad(archive)
+	}
+
+	for i, f := range files {
+		mode := uint32(0o100000) | uint32(f.mode.Perm())
+		if f.mode.IsDir() {
+			mode = uint32(0o40000) | uint32(f.mode.Perm())
+		}
+		writeEntry(i+1, mode, "."+f.dir+f.base, f.content)
+	}
+	writeEntry(0, 0, "TRAILER!!!", nil)
+
+	compressed := &bytes.Buffer{}
+	gz, err := gzip.NewWriterLevel(compressed, gzip.BestCompression)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if _, err = gz.Write(archive.Bytes()); err != nil {
+		return nil, 0, err
+	}
+
+	if err = gz.Close(); err != nil {
+		return nil, 0, err
+	}
+
+	return compressed.Bytes(), archive.Len(), nil
+}
+
+// pad aligns the cpio archive to 4 bytes
+func pad(archive *bytes.Buffer) {
+	if n := archive.Len() % 4; n != 0 {
+		archive.Write(make([]byte, 4-n))
+	}
+}
+
+// rpmEntry is an entry of a RPM header
+type rpmEntry struct {
+	tag   uint32
+	typ   uint32
+	count uint32
+	data  []byte
+}
+
+// rpmHeader is a RPM header structure, used both for the signature and the main header
+type rpmHeader struct {
+	entries []rpmEntry
+}
+
+func (h *rpmHeader) add(tag uint32, typ uint32, count int, data []byte) {
+	h.entries = append(h.entries, rpmEntry{tag: tag, typ: typ, count: uint32(count), data: data})
+}
+
+func (h *rpmHeader) addString(tag uint32, value string) {
+	h.add(tag, rpmString, 1, append([]byte(value), 0))
+}
+
+func (h *rpmHeader) addI18NString(tag uint32, value string) {
+	h.add(tag, rpmI18NString, 1, append([]byte(value), 0))
+}
+
+func (h *rpmHeader) addStrings(tag uint32, values ...string) {
+	data := []byte{}
+	for _, v := range values {
+		data = append(append(data, v...), 0)
+	}
+	h.add(tag, rpmStringArray, len(values), data)
+}
+
+func (h *rpmHeader) addInt32(tag uint32, values ...uint32) {
+	data := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint32(data[4*i:], v)
+	}
+	h.add(tag, rpmInt32, len(values), data)
+}
+
+func (h *rpmHeader) addInt16(tag uint32, values ...uint16) {
+	data := make([]byte, 2*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint16(data[2*i:], v)
+	}
+	h.add(tag, rpmInt16, len(values), data)
+}
+
+func (h *rpmHeader) addBin(tag uint32, value []byte) {
+	h.add(tag, rpmBin, len(value), value)
+}
+
+// bytes returns the header structure, with a region tag covering all the entries
+func (h *rpmHeader) bytes(regionTag uint32) []byte {
+	entries := append([]rpmEntry{}, h.entries...)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].tag < entries[j].tag })
+
+	store := &bytes.Buffer{}
+	index := &bytes.Buffer{}
+
+	writeIndex := func(tag, typ, offset, count uint32) {
+		_ = binary.Write(index, binary.BigEndian, []uint32{tag, typ, offset, count})
+	}
+
+	for _, e := range entries {
+		// numeric values are aligned to their size
+		align := map[uint32]int{rpmInt16: 2, rpmInt32: 4}[e.typ]
+		if align > 0 && store.Len()%align != 0 {
+			store.Write(make([]byte, align-store.Len()%align))
+		}
+
+		writeIndex(e.tag, e.typ, uint32(store.Len()), e.count)
+		store.Write(e.data)
+	}
+
+	// the region entry goes first in the index and its trailer at the end of the store.
+	// The trailer references the number of entries in the region with a negative offset.
+	nindex := len(entries) + 1
+	region := &bytes.Buffer{}
+	_ = binary.Write(region, binary.BigEndian, []uint32{regionTag, rpmBin, uint32(store.Len()), 16})
+
+	_ = binary.Write(store, binary.BigEndian, []uint32{regionTag, rpmBin, uint32(-int32(nindex * 16)), 16})
+
+	header := &bytes.Buffer{}
+	header.Write([]byte{0x8e, 0xad, 0xe8, 0x01, 0, 0, 0, 0})
+	_ = binary.Write(header, binary.BigEndian, []uint32{uint32(nindex), uint32(store.Len())})
+	header.Write(region.Bytes())
+	header.Write(index.Bytes())
+	header.Write(store.Bytes())
+
+	return header.Bytes()
+}
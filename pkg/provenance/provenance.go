@@ -0,0 +1,258 @@
+// Package provenance generates SLSA provenance attestations for the binaries built by k6foundry
+package provenance
+
+import (
+	"crypto/sha256"
+	"debug/buildinfo"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/grafana/k6foundry"
+)
+
+const (
+	// Extension is the file extension of the provenance attestations
+	Extension = ".intoto.json"
+
+	// StatementType is the type of the in-toto statement
+	StatementType = "https://in-toto.io/Statement/v1"
+	// PredicateType is the type of the SLSA provenance predicate
+	PredicateType = "https://slsa.dev/provenance/v1"
+	// BuildType describes the parameters of the builds made by k6foundry
+	BuildType = "https://github.com/grafana/k6foundry/buildtypes/build/v1"
+	// DefaultBuilderID identifies the builder if no other id is specified
+	DefaultBuilderID = "https://github.com/grafana/k6foundry"
+
+	modulePath = "github.com/grafana/k6foundry"
+)
+
+var (
+	// ErrInvalidBinary is returned when the build information can't be read from the binary
+	ErrInvalidBinary = errors.New("invalid binary")
+	// ErrProvenance is returned when the provenance can't be generated
+	ErrProvenance = errors.New("generating provenance")
+)
+
+// Parameters describes the build, as requested to the builder
+type Parameters struct {
+	// requested k6 version (e.g. latest)
+	K6Version string
+	// requested dependencies
+	Dependencies []k6foundry.Module
+	// go build options
+	BuildOpts []string
+	// identifies the builder (e.g. the service embedding k6foundry). Defaults to DefaultBuilderID
+	BuilderID string
+	// start and end of the build. FinishedOn defaults to the current time
+	StartedOn  time.Time
+	FinishedOn time.Time
+}
+
+// Statement is an in-toto statement
+type Statement struct {
+	Type          string      `json:"_type"`
+	Subject       []Resource  `json:"subject"`
+	PredicateType string      `json:"predicateType"`
+	Predicate     *Provenance `json:"predicate"`
+}
+
+// Resource describes an artifact
+type Resource struct {
+	Name   string            `json:"name,omitempty"`
+	URI    string            `json:"uri,omitempty"`
+	Digest map[string]string `json:"digest,omitempty"`
+}
+
+// Provenance is a SLSA provenance predicate
+type Provenance struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+// BuildDefinition describes the inputs of the build
+type BuildDefinition struct {
+	BuildType            string             `json:"buildType"`
+	ExternalParameters   ExternalParameters `json:"externalParameters"`
+	InternalParameters   InternalParameters `json:"internalParameters"`
+	ResolvedDependencies []Resource         `json:"resolvedDependencies"`
+}
+
+// ExternalParameters are the parameters requested to the builder
+type ExternalParameters struct {
+	K6Version    string   `json:"k6Version,omitempty"`
+	Dependencies []string `json:"dependencies"`
+	Platform     string   `json:"platform"`
+	BuildOpts    []string `json:"buildOpts,omitempty"`
+}
+
+// InternalParameters are the parameters set by the builder
+type InternalParameters struct {
+	GoVersion string `json:"goVersion"`
+	// go build settings recorded in the binary (e.g. -ldflags, CGO_ENABLED)
+	Settings map[string]string `json:"settings,omitempty"`
+}
+
+// RunDetails describes the builder
+type RunDetails struct {
+	Builder  Builder  `json:"builder"`
+	Metadata Metadata `json:"metadata"`
+}
+
+// Builder identifies the builder
+type Builder struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version,omitempty"`
+}
+
+// Metadata describes the build execution
+type Metadata struct {
+	StartedOn  string `json:"startedOn,omitempty"`
+	FinishedOn string `json:"finishedOn,omitempty"`
+}
+
+// Generate returns the provenance of the binary as an (unsigned) in-toto statement.
+// The go version, build settings and resolved modules are read from the binary.
+func Generate(binaryPath string, info *k6foundry.BuildInfo, params Parameters) ([]byte, error) {
+	binaryInfo, err := buildinfo.ReadFile(binaryPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBinary, err)
+	}
+
+	digest, err := binaryDigest(binaryPath, info)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProvenance, err)
+	}
+
+	if params.BuilderID == "" {
+		params.BuilderID = DefaultBuilderID
+	}
+
+	if params.FinishedOn.IsZero() {
+		params.FinishedOn = time.Now()
+	}
+
+	deps := []string{}
+	for _, m := range params.Dependencies {
+		deps = append(deps, m.String())
+	}
+
+	settings := map[string]string{}
+	for _, s := range binaryInfo.Settings {
+		settings[s.Key] = s.Value
+	}
+
+	statement := Statement{
+		Type:          StatementType,
+		Subject:       []Resource{{Name: filepath.Base(binaryPath), Digest: map[string]string{"sha256": digest}}},
+		PredicateType: PredicateType,
+		Predicate: &Provenance{
+			BuildDefinition: BuildDefinition{
+				BuildType: BuildType,
+				ExternalParameters: ExternalParameters{
+					K6Version:    params.K6Version,
+					Dependencies: deps,
+					Platform:     info.Platform,
+					BuildOpts:    params.BuildOpts,
+				},
+				InternalParameters: InternalParameters{
+					GoVersion: binaryInfo.GoVersion,
+					Settings:  settings,
+				},
+				ResolvedDependencies: resolvedDependencies(binaryInfo),
+			},
+			RunDetails: RunDetails{
+				Builder: Builder{ID: params.BuilderID, Version: builderVersion()},
+				Metadata: Metadata{
+					StartedOn:  formatTime(params.StartedOn),
+					FinishedOn: formatTime(params.FinishedOn),
+				},
+			},
+		},
+	}
+
+	content, err := json.MarshalIndent(statement, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrProvenance, err)
+	}
+
+	return content, nil
+}
+
+// binaryDigest returns the hex encoded sha256 digest of the binary, using the checksum in the build info if available
+func binaryDigest(binaryPath string, info *k6foundry.BuildInfo) (string, error) {
+	if digest, found := strings.CutPrefix(info.Checksum, "sha256:"); found {
+		return digest, nil
+	}
+
+	file, err := os.Open(binaryPath) //nolint:forbidigo,gosec
+	if err != nil {
+		return "", err
+	}
+	defer file.Close() //nolint:errcheck
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// resolvedDependencies returns the modules linked in the binary, with their go.sum hashes
+func resolvedDependencies(binaryInfo *debug.BuildInfo) []Resource {
+	resources := []Resource{}
+	for _, dep := range binaryInfo.Deps {
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+
+		resource := Resource{URI: "pkg:golang/" + dep.Path + "@" + dep.Version}
+		// modules replaced with local directories don't have a version or hash
+		if dep.Version == "" {
+			resource.URI = "pkg:golang/" + dep.Path
+		}
+		if dep.Sum != "" {
+			resource.Digest = map[string]string{"dirHash": dep.Sum}
+		}
+
+		resources = append(resources, resource)
+	}
+
+	return resources
+}
+
+// builderVersion returns the version of the k6foundry module used by the running program
+func builderVersion() map[string]string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+
+	if info.Main.Path == modulePath {
+		return map[string]string{modulePath: info.Main.Version}
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			return map[string]string{modulePath: dep.Version}
+		}
+	}
+
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
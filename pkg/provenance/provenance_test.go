@@ -0,0 +1,119 @@
+package provenance
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/grafana/k6foundry"
+)
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	// the test binary is used as the built binary
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	notBinary := filepath.Join(t.TempDir(), "k6")
+	if err = os.WriteFile(notBinary, []byte("not a binary"), 0o600); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	params := Parameters{
+		K6Version:    "latest",
+		Dependencies: []k6foundry.Module{{Path: "github.com/grafana/xk6-kubernetes", Version: "v0.9.0"}},
+		BuildOpts:    []string{"-ldflags=-s"},
+		StartedOn:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	testCases := []struct {
+		title        string
+		binary       string
+		info         *k6foundry.BuildInfo
+		params       Parameters
+		expectDigest string
+		expectID     string
+		expectError  error
+	}{
+		{
+			title:    "binary",
+			binary:   executable,
+			info:     &k6foundry.BuildInfo{Platform: "linux/amd64"},
+			params:   params,
+			expectID: DefaultBuilderID,
+		},
+		{
+			title:        "checksum in build info",
+			binary:       executable,
+			info:         &k6foundry.BuildInfo{Platform: "linux/amd64", Checksum: "sha256:abcdef"},
+			params:       Parameters{BuilderID: "https://builder.example.com"},
+			expectDigest: "abcdef",
+			expectID:     "https://builder.example.com",
+		},
+		{
+			title:       "not a binary",
+			binary:      notBinary,
+			info:        &k6foundry.BuildInfo{Platform: "linux/amd64"},
+			expectError: ErrInvalidBinary,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			content, err := Generate(tc.binary, tc.info, tc.params)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			if tc.expectError != nil {
+				return
+			}
+
+			statement := Statement{}
+			if err = json.Unmarshal(content, &statement); err != nil {
+				t.Fatalf("unmarshalling %v", err)
+			}
+
+			if statement.Type != StatementType || statement.PredicateType != PredicateType {
+				t.Fatalf("unexpected statement type %q predicate %q", statement.Type, statement.PredicateType)
+			}
+
+			digest := statement.Subject[0].Digest["sha256"]
+			if tc.expectDigest != "" && digest != tc.expectDigest || len(digest) == 0 {
+				t.Fatalf("unexpected subject digest %q", digest)
+			}
+
+			definition := statement.Predicate.BuildDefinition
+			if definition.InternalParameters.GoVersion != runtime.Version() {
+				t.Fatalf("expected go version %s got %s", runtime.Version(), definition.InternalParameters.GoVersion)
+			}
+
+			expectDeps := []string{}
+			for _, m := range tc.params.Dependencies {
+				expectDeps = append(expectDeps, m.String())
+			}
+			if !reflect.DeepEqual(definition.ExternalParameters.Dependencies, expectDeps) {
+				t.Fatalf("expected dependencies %v got %v", expectDeps, definition.ExternalParameters.Dependencies)
+			}
+
+			if len(definition.ResolvedDependencies) == 0 {
+				t.Fatalf("expected resolved dependencies")
+			}
+
+			if statement.Predicate.RunDetails.Builder.ID != tc.expectID {
+				t.Fatalf("expected builder %q got %q", tc.expectID, statement.Predicate.RunDetails.Builder.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package publish
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/grafana/k6foundry"
+)
+
+// filePublisher copies the artifacts to a directory in the local filesystem
+type filePublisher struct {
+	dir string
+}
+
+func newFilePublisher(target *url.URL) (Publisher, error) {
+	dir := filepath.FromSlash(target.Path)
+	if target.Opaque != "" {
+		// relative paths such as file:dist
+		dir = filepath.FromSlash(target.Opaque)
+	}
+
+	if dir == "" {
+		return nil, fmt.Errorf("%w: missing directory", ErrInvalidTarget)
+	}
+
+	return &filePublisher{dir: dir}, nil
+}
+
+func (p *filePublisher) Publish(_ context.Context, artifact Artifact, info *k6foundry.BuildInfo) error {
+	if err := os.MkdirAll(p.dir, 0o750); err != nil { //nolint:forbidigo
+		return fmt.Errorf("%w: creating directory %w", ErrPublishing, err)
+	}
+
+	if err := copyFile(artifact.Path, filepath.Join(p.dir, artifact.Name)); err != nil {
+		return fmt.Errorf("%w: %w", ErrPublishing, err)
+	}
+
+	for _, file := range artifact.Files {
+		if err := copyFile(file, filepath.Join(p.dir, filepath.Base(file))); err != nil {
+			return fmt.Errorf("%w: %w", ErrPublishing, err)
+		}
+	}
+
+	metadata, err := marshalInfo(info)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPublishing, err)
+	}
+
+	err = os.WriteFile(filepath.Join(p.dir, metadataName(artifact)), metadata, 0o644) //nolint:forbidigo,gosec
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPublishing, err)
+	}
+
+	return nil
+}
+
+// copyFile copies a file preserving its permissions
+func copyFile(from string, to string) error {
+	src, err := os.Open(from) //nolint:forbidigo,gosec
+	if err != nil {
+		return err
+	}
+	defer src.Close() //nolint:errcheck
+
+	stat, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, stat.Mode().Perm()) //nolint:forbidigo,gosec
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
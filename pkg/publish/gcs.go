@@ -0,0 +1,46 @@
+package publish
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// defaultGCSEndpoint is the endpoint of the XML API of Google Cloud Storage
+const defaultGCSEndpoint = "https://storage.googleapis.com"
+
+// newGCSPublisher returns a publisher that uploads the artifacts to a Google Cloud Storage bucket using
+// the XML API, which accepts PUT requests like the http publisher.
+// The OAuth2 access token is taken from the GOOGLE_OAUTH_ACCESS_TOKEN environment variable
+// (e.g. the output of gcloud auth print-access-token). The endpoint can be changed with the endpoint
+// query parameter (e.g. for an emulator)
+func newGCSPublisher(target *url.URL) (Publisher, error) {
+	if target.Host == "" {
+		return nil, fmt.Errorf("%w: missing bucket", ErrInvalidTarget)
+	}
+
+	token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN") //nolint:forbidigo
+	if token == "" {
+		return nil, fmt.Errorf("%w: GOOGLE_OAUTH_ACCESS_TOKEN is required", ErrInvalidTarget)
+	}
+
+	endpoint := defaultGCSEndpoint
+	if e := target.Query().Get("endpoint"); e != "" {
+		endpoint = e
+	}
+
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || endpointURL.Host == "" {
+		return nil, fmt.Errorf("%w: invalid endpoint %q", ErrInvalidTarget, endpoint)
+	}
+
+	base := endpointURL.JoinPath(target.Host, strings.Trim(target.Path, "/"))
+
+	return &httpPublisher{
+		base:   base,
+		header: http.Header{"Authorization": []string{"Bearer " + token}},
+		client: http.DefaultClient,
+	}, nil
+}
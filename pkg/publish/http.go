@@ -0,0 +1,98 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/grafana/k6foundry"
+)
+
+// httpPublisher uploads the artifacts using PUT requests to a base URL
+type httpPublisher struct {
+	base *url.URL
+	// headers added to the requests (e.g. authorization)
+	header http.Header
+	client *http.Client
+}
+
+func newHTTPPublisher(target *url.URL) (Publisher, error) {
+	if target.Host == "" {
+		return nil, fmt.Errorf("%w: missing host", ErrInvalidTarget)
+	}
+
+	return &httpPublisher{base: target, client: http.DefaultClient}, nil
+}
+
+func (p *httpPublisher) Publish(ctx context.Context, artifact Artifact, info *k6foundry.BuildInfo) error {
+	err := p.putFile(ctx, artifact.Name, "application/octet-stream", artifact.Path)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range artifact.Files {
+		name := filepath.Base(file)
+		if err = p.putFile(ctx, name, contentType(name), file); err != nil {
+			return err
+		}
+	}
+
+	metadata, err := marshalInfo(info)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPublishing, err)
+	}
+
+	return p.put(ctx, metadataName(artifact), "application/json", bytes.NewReader(metadata), int64(len(metadata)))
+}
+
+// putFile uploads the content of the file
+func (p *httpPublisher) putFile(ctx context.Context, name string, contentType string, path string) error {
+	content, err := os.Open(path) //nolint:forbidigo,gosec
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPublishing, err)
+	}
+	defer content.Close() //nolint:errcheck
+
+	stat, err := content.Stat()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPublishing, err)
+	}
+
+	return p.put(ctx, name, contentType, content, stat.Size())
+}
+
+func (p *httpPublisher) put(ctx context.Context, name string, contentType string, body io.Reader, size int64) error {
+	target := p.base.JoinPath(name)
+	// preserve query parameters (e.g. access tokens)
+	target.RawQuery = p.base.RawQuery
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target.String(), body)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPublishing, err)
+	}
+
+	for name, values := range p.header {
+		req.Header[name] = values
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPublishing, err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%w: uploading %s: %s %s", ErrPublishing, name, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
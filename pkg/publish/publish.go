@@ -0,0 +1,147 @@
+// Package publish implements publishers that upload the artifacts produced by a build
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/grafana/k6foundry"
+)
+
+var (
+	// ErrUnsupportedScheme is returned when there's no publisher registered for the target's scheme
+	ErrUnsupportedScheme = errors.New("unsupported publisher scheme")
+	// ErrInvalidTarget is returned when the publish target can't be parsed
+	ErrInvalidTarget = errors.New("invalid publish target")
+	// ErrPublishing is returned when the artifact can't be published
+	ErrPublishing = errors.New("publishing artifact")
+)
+
+// Artifact describes a file produced by a build
+type Artifact struct {
+	// Name of the artifact (e.g. k6)
+	Name string
+	// Path to the file with the artifact's content
+	Path string
+	// Files generated for the artifact (e.g. checksum, signature, SBOM), published next to it using the
+	// name of the file
+	Files []string
+}
+
+// Publisher uploads an artifact to a destination, such as a directory, a bucket or a registry.
+// Publishers also upload the files generated for the artifact and the build info as its metadata.
+type Publisher interface {
+	Publish(ctx context.Context, artifact Artifact, info *k6foundry.BuildInfo) error
+}
+
+// Factory creates a Publisher for a target URL
+type Factory func(target *url.URL) (Publisher, error)
+
+var (
+	registryMutex sync.RWMutex          //nolint:gochecknoglobals
+	registry      = map[string]Factory{ //nolint:gochecknoglobals
+		"file":  newFilePublisher,
+		"http":  newHTTPPublisher,
+		"https": newHTTPPublisher,
+		"s3":    newS3Publisher,
+		"gs":    newGCSPublisher,
+		"oci":   newOCIPublisher,
+	}
+)
+
+// Register registers a publisher factory for the given URL scheme.
+// Registering a scheme that is already registered replaces the previous factory.
+func Register(scheme string, factory Factory) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	registry[scheme] = factory
+}
+
+// Schemes returns the list of registered schemes
+func Schemes() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	schemes := make([]string, 0, len(registry))
+	for scheme := range registry {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+
+	return schemes
+}
+
+// New returns a Publisher for the target URL using the factory registered for its scheme.
+// Targets without scheme and absolute paths (including windows paths with a volume, such as C:\dist)
+// are considered paths in the local filesystem.
+// Built-in schemes:
+//   - file:///path/to/dir
+//   - http(s)://host/path (uploads using PUT)
+//   - s3://bucket/prefix[?region=region&endpoint=url]
+//   - gs://bucket/prefix[?endpoint=url]
+//   - oci://registry/repository:tag[?plain-http=true]
+func New(target string) (Publisher, error) {
+	targetURL, err := parseTarget(target)
+	if err != nil {
+		return nil, err
+	}
+
+	registryMutex.RLock()
+	factory, found := registry[targetURL.Scheme]
+	registryMutex.RUnlock()
+
+	if !found {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedScheme, targetURL.Scheme)
+	}
+
+	return factory(targetURL)
+}
+
+// parseTarget returns the URL of the target. Local paths are returned as file URLs. Absolute paths are
+// checked before parsing, as the volume of a windows path would be parsed as the scheme
+func parseTarget(target string) (*url.URL, error) {
+	if filepath.IsAbs(target) || filepath.VolumeName(target) != "" {
+		return &url.URL{Scheme: "file", Path: target}, nil
+	}
+
+	targetURL, err := url.Parse(target)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidTarget, err)
+	}
+
+	if targetURL.Scheme == "" {
+		return &url.URL{Scheme: "file", Path: target}, nil
+	}
+
+	return targetURL, nil
+}
+
+// metadataName returns the name of the file holding the build info of an artifact
+func metadataName(artifact Artifact) string {
+	return artifact.Name + ".json"
+}
+
+// contentType returns the content type of a file published with an artifact
+func contentType(name string) string {
+	if t := mime.TypeByExtension(filepath.Ext(name)); t != "" {
+		return t
+	}
+
+	return "application/octet-stream"
+}
+
+func marshalInfo(info *k6foundry.BuildInfo) ([]byte, error) {
+	if info == nil {
+		info = &k6foundry.BuildInfo{}
+	}
+
+	return json.MarshalIndent(info, "", "  ")
+}
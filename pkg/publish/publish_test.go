@@ -0,0 +1,323 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	ociregistry "github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/grafana/k6foundry"
+)
+
+// fakeServer records the content uploaded using PUT requests
+type fakeServer struct {
+	mutex   sync.Mutex
+	objects map[string][]byte
+	headers map[string]http.Header
+}
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{objects: map[string][]byte{}, headers: map[string]http.Header{}}
+}
+
+func (s *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	content, _ := io.ReadAll(r.Body)
+	s.objects[r.URL.Path] = content
+	s.headers[r.URL.Path] = r.Header
+}
+
+func setup(t *testing.T) (Artifact, *k6foundry.BuildInfo) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "k6")
+	if err := os.WriteFile(path, []byte("binary"), 0o600); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	checksum := path + ".sha256"
+	if err := os.WriteFile(checksum, []byte("checksum"), 0o600); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	return Artifact{Name: "k6", Path: path, Files: []string{checksum}}, &k6foundry.BuildInfo{
+		Platform:    "linux/amd64",
+		ModVersions: map[string]string{"go.k6.io/k6": "v0.1.0"},
+	}
+}
+
+func checkInfo(t *testing.T, content []byte, expected *k6foundry.BuildInfo) {
+	t.Helper()
+
+	info := &k6foundry.BuildInfo{}
+	if err := json.Unmarshal(content, info); err != nil {
+		t.Fatalf("unmarshalling build info %v", err)
+	}
+
+	if info.Platform != expected.Platform || info.ModVersions["go.k6.io/k6"] != "v0.1.0" {
+		t.Fatalf("expected %v got %v", expected, info)
+	}
+}
+
+func TestFilePublisher(t *testing.T) {
+	t.Parallel()
+
+	artifact, info := setup(t)
+	dir := filepath.Join(t.TempDir(), "dist")
+
+	publisher, err := New(dir)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err = publisher.Publish(context.Background(), artifact, info); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "k6"))
+	if err != nil || string(content) != "binary" {
+		t.Fatalf("unexpected content %q %v", content, err)
+	}
+
+	content, err = os.ReadFile(filepath.Join(dir, "k6.sha256"))
+	if err != nil || string(content) != "checksum" {
+		t.Fatalf("unexpected checksum %q %v", content, err)
+	}
+
+	content, err = os.ReadFile(filepath.Join(dir, "k6.json"))
+	if err != nil {
+		t.Fatalf("reading build info %v", err)
+	}
+	checkInfo(t, content, info)
+}
+
+func TestHTTPPublisher(t *testing.T) {
+	t.Parallel()
+
+	artifact, info := setup(t)
+	server := newFakeServer()
+	srv := httptest.NewServer(server)
+	t.Cleanup(srv.Close)
+
+	publisher, err := New(srv.URL + "/builds")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err = publisher.Publish(context.Background(), artifact, info); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if string(server.objects["/builds/k6"]) != "binary" {
+		t.Fatalf("unexpected content %q", server.objects["/builds/k6"])
+	}
+
+	if string(server.objects["/builds/k6.sha256"]) != "checksum" {
+		t.Fatalf("unexpected checksum %q", server.objects["/builds/k6.sha256"])
+	}
+	checkInfo(t, server.objects["/builds/k6.json"], info)
+}
+
+func TestS3Publisher(t *testing.T) { //nolint:paralleltest
+	t.Setenv("AWS_ACCESS_KEY_ID", "key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	artifact, info := setup(t)
+	server := newFakeServer()
+	srv := httptest.NewServer(server)
+	t.Cleanup(srv.Close)
+
+	publisher, err := New("s3://bucket/prefix?region=eu-west-1&endpoint=" + srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err = publisher.Publish(context.Background(), artifact, info); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if string(server.objects["/bucket/prefix/k6"]) != "binary" {
+		t.Fatalf("unexpected content %q", server.objects["/bucket/prefix/k6"])
+	}
+
+	if string(server.objects["/bucket/prefix/k6.sha256"]) != "checksum" {
+		t.Fatalf("unexpected checksum %q", server.objects["/bucket/prefix/k6.sha256"])
+	}
+
+	auth := server.headers["/bucket/prefix/k6"].Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=key/") || !strings.Contains(auth, "/eu-west-1/s3/") {
+		t.Fatalf("unexpected authorization %q", auth)
+	}
+}
+
+func TestGCSPublisher(t *testing.T) { //nolint:paralleltest
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "token")
+
+	artifact, info := setup(t)
+	server := newFakeServer()
+	srv := httptest.NewServer(server)
+	t.Cleanup(srv.Close)
+
+	publisher, err := New("gs://bucket/prefix?endpoint=" + srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err = publisher.Publish(context.Background(), artifact, info); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if string(server.objects["/bucket/prefix/k6"]) != "binary" {
+		t.Fatalf("unexpected content %q", server.objects["/bucket/prefix/k6"])
+	}
+
+	if string(server.objects["/bucket/prefix/k6.sha256"]) != "checksum" {
+		t.Fatalf("unexpected checksum %q", server.objects["/bucket/prefix/k6.sha256"])
+	}
+	checkInfo(t, server.objects["/bucket/prefix/k6.json"], info)
+
+	if auth := server.headers["/bucket/prefix/k6"].Get("Authorization"); auth != "Bearer token" {
+		t.Fatalf("unexpected authorization %q", auth)
+	}
+}
+
+func TestGCSPublisherWithoutToken(t *testing.T) { //nolint:paralleltest
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "")
+
+	if _, err := New("gs://bucket/prefix"); !errors.Is(err, ErrInvalidTarget) {
+		t.Fatalf("expected %v got %v", ErrInvalidTarget, err)
+	}
+}
+
+func TestOCIPublisher(t *testing.T) {
+	t.Parallel()
+
+	artifact, info := setup(t)
+	srv := httptest.NewServer(ociregistry.New(ociregistry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(srv.Close)
+
+	target := strings.TrimPrefix(srv.URL, "http://") + "/grafana/k6:custom"
+	publisher, err := New("oci://" + target + "?plain-http=true")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err = publisher.Publish(context.Background(), artifact, info); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	ref, _ := name.ParseReference(target, name.Insecure)
+	img, err := remote.Image(ref)
+	if err != nil {
+		t.Fatalf("pulling artifact %v", err)
+	}
+
+	manifest, err := img.Manifest()
+	if err != nil {
+		t.Fatalf("reading manifest %v", err)
+	}
+
+	// the content of a blob of the artifact
+	blob := func(desc v1.Descriptor) string {
+		layer, err := remote.Layer(ref.Context().Digest(desc.Digest.String()))
+		if err != nil {
+			t.Fatalf("pulling blob %v", err)
+		}
+
+		content, err := layer.Compressed()
+		if err != nil {
+			t.Fatalf("pulling blob %v", err)
+		}
+		defer content.Close() //nolint:errcheck
+
+		data, _ := io.ReadAll(content)
+		return string(data)
+	}
+
+	if len(manifest.Layers) != 2 || blob(manifest.Layers[0]) != "binary" {
+		t.Fatalf("unexpected layers %v", manifest.Layers)
+	}
+
+	checksum := manifest.Layers[1]
+	if checksum.MediaType != MediaTypeFile || checksum.Annotations[annotationTitle] != "k6.sha256" ||
+		blob(checksum) != "checksum" {
+		t.Fatalf("unexpected checksum layer %v", checksum)
+	}
+
+	if manifest.Config.MediaType != MediaTypeBuildInfo {
+		t.Fatalf("unexpected config %v", manifest.Config)
+	}
+
+	config, err := img.RawConfigFile()
+	if err != nil {
+		t.Fatalf("pulling build info %v", err)
+	}
+	checkInfo(t, config, info)
+}
+
+func TestParseTarget(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title   string
+		target  string
+		windows bool
+		scheme  string
+		path    string
+	}{
+		{title: "relative path", target: "dist", scheme: "file", path: "dist"},
+		{title: "file url", target: "file:///dist", scheme: "file", path: "/dist"},
+		{title: "bucket", target: "s3://bucket/prefix", scheme: "s3", path: "/prefix"},
+		{title: "windows path", target: `C:\dist`, windows: true, scheme: "file", path: `C:\dist`},
+		{title: "windows path with slashes", target: "C:/dist", windows: true, scheme: "file", path: "C:/dist"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			if tc.windows && runtime.GOOS != "windows" {
+				t.Skip("windows paths")
+			}
+
+			targetURL, err := parseTarget(tc.target)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if targetURL.Scheme != tc.scheme || targetURL.Path != tc.path {
+				t.Fatalf("expected %s %s got %s %s", tc.scheme, tc.path, targetURL.Scheme, targetURL.Path)
+			}
+		})
+	}
+}
+
+func TestUnsupportedScheme(t *testing.T) {
+	t.Parallel()
+
+	_, err := New("ftp://host/path")
+	if !errors.Is(err, ErrUnsupportedScheme) {
+		t.Fatalf("expected %v got %v", ErrUnsupportedScheme, err)
+	}
+}
@@ -0,0 +1,182 @@
+package publish
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/grafana/k6foundry"
+)
+
+const defaultS3Region = "us-east-1"
+
+// s3Publisher uploads the artifacts to an S3 (or S3 compatible) bucket.
+// Credentials are taken from the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+// environment variables.
+type s3Publisher struct {
+	bucket      string
+	prefix      string
+	region      string
+	endpoint    *url.URL
+	credentials aws.Credentials
+	signer      *v4.Signer
+	client      *http.Client
+	now         func() time.Time
+}
+
+//nolint:forbidigo
+func newS3Publisher(target *url.URL) (Publisher, error) {
+	if target.Host == "" {
+		return nil, fmt.Errorf("%w: missing bucket", ErrInvalidTarget)
+	}
+
+	query := target.Query()
+
+	region := query.Get("region")
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = defaultS3Region
+	}
+
+	p := &s3Publisher{
+		bucket: target.Host,
+		prefix: strings.Trim(target.Path, "/"),
+		region: region,
+		credentials: aws.Credentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		},
+		// S3 doesn't escape the path twice when signing
+		signer: v4.NewSigner(func(opts *v4.SignerOptions) { opts.DisableURIPathEscaping = true }),
+		client: http.DefaultClient,
+		now:    time.Now,
+	}
+
+	if !p.credentials.HasKeys() {
+		return nil, fmt.Errorf("%w: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required", ErrInvalidTarget)
+	}
+
+	// custom endpoints (e.g. minio) use path style urls
+	if endpoint := query.Get("endpoint"); endpoint != "" {
+		endpointURL, err := url.Parse(endpoint)
+		if err != nil || endpointURL.Host == "" {
+			return nil, fmt.Errorf("%w: invalid endpoint %q", ErrInvalidTarget, endpoint)
+		}
+		p.endpoint = endpointURL
+	}
+
+	return p, nil
+}
+
+func (p *s3Publisher) Publish(ctx context.Context, artifact Artifact, info *k6foundry.BuildInfo) error {
+	content, err := os.Open(artifact.Path) //nolint:forbidigo
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPublishing, err)
+	}
+	defer content.Close() //nolint:errcheck
+
+	err = p.put(ctx, artifact.Name, "application/octet-stream", content)
+	if err != nil {
+		return err
+	}
+
+	for _, file := range artifact.Files {
+		if err = p.putFile(ctx, file); err != nil {
+			return err
+		}
+	}
+
+	metadata, err := marshalInfo(info)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPublishing, err)
+	}
+
+	return p.put(ctx, metadataName(artifact), "application/json", bytes.NewReader(metadata))
+}
+
+// putFile uploads a file generated for the artifact
+func (p *s3Publisher) putFile(ctx context.Context, path string) error {
+	content, err := os.Open(path) //nolint:forbidigo,gosec
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPublishing, err)
+	}
+	defer content.Close() //nolint:errcheck
+
+	name := filepath.Base(path)
+
+	return p.put(ctx, name, contentType(name), content)
+}
+
+// objectURL returns the url for an object key
+func (p *s3Publisher) objectURL(key string) *url.URL {
+	if p.endpoint != nil {
+		return p.endpoint.JoinPath(p.bucket, key)
+	}
+
+	return &url.URL{
+		Scheme: "https",
+		Host:   fmt.Sprintf("%s.s3.%s.amazonaws.com", p.bucket, p.region),
+		Path:   "/" + key,
+	}
+}
+
+func (p *s3Publisher) put(ctx context.Context, name string, contentType string, body io.ReadSeeker) error {
+	// the payload hash is part of the signature
+	hash := sha256.New()
+	size, err := io.Copy(hash, body)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPublishing, err)
+	}
+	if _, err = body.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("%w: %w", ErrPublishing, err)
+	}
+
+	target := p.objectURL(path.Join(p.prefix, name))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target.String(), body)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPublishing, err)
+	}
+
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+
+	payloadHash := hex.EncodeToString(hash.Sum(nil))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	err = p.signer.SignHTTP(ctx, p.credentials, req, payloadHash, "s3", p.region, p.now())
+	if err != nil {
+		return fmt.Errorf("%w: signing request %w", ErrPublishing, err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPublishing, err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%w: uploading %s: %s %s", ErrPublishing, name, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
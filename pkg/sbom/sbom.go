@@ -0,0 +1,269 @@
+// Package sbom generates Software Bills of Materials for the binaries built by k6foundry
+package sbom
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grafana/k6foundry"
+)
+
+const (
+	// CycloneDX format (JSON)
+	CycloneDX = "cyclonedx"
+	// SPDX format (JSON)
+	SPDX = "spdx"
+
+	toolName = "k6foundry"
+)
+
+var (
+	// ErrUnsupportedFormat is returned when the SBOM format is not supported
+	ErrUnsupportedFormat = errors.New("unsupported SBOM format")
+	// ErrNoDependencies is returned when the build info doesn't include the dependencies
+	ErrNoDependencies = errors.New("build info doesn't include dependencies")
+)
+
+// Formats returns the supported SBOM formats
+func Formats() []string {
+	return []string{CycloneDX, SPDX}
+}
+
+// Extension returns the file extension for the SBOM format (e.g. .cdx.json)
+func Extension(format string) string {
+	switch format {
+	case CycloneDX:
+		return ".cdx.json"
+	case SPDX:
+		return ".spdx.json"
+	default:
+		return ".json"
+	}
+}
+
+// Generate returns the SBOM in the given format for a binary. The build info must include
+// the dependencies (see k6foundry.NativeBuilderOpts.ListDependencies).
+func Generate(format string, name string, info *k6foundry.BuildInfo) ([]byte, error) {
+	if len(info.Dependencies) == 0 {
+		return nil, ErrNoDependencies
+	}
+
+	var doc any
+	switch format {
+	case CycloneDX:
+		doc = cycloneDX(name, info, time.Now().UTC())
+	case SPDX:
+		doc = spdx(name, info, time.Now().UTC())
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, format)
+	}
+
+	content, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshalling SBOM %w", err)
+	}
+
+	return content, nil
+}
+
+// purl returns the package URL of a go module
+func purl(dep k6foundry.Dependency) string {
+	return "pkg:golang/" + dep.Path + "@" + dep.Version
+}
+
+// documentID returns an identifier derived from the content of the build
+func documentID(name string, info *k6foundry.BuildInfo) string {
+	hash := sha256.New()
+	hash.Write([]byte(name + " " + info.Platform))
+	for _, dep := range info.Dependencies {
+		hash.Write([]byte(" " + dep.Path + "@" + dep.Version))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cdxLicense struct {
+	Expression string `json:"expression"`
+}
+
+type cdxComponent struct {
+	BOMRef     string        `json:"bom-ref"`
+	Type       string        `json:"type"`
+	Name       string        `json:"name"`
+	Version    string        `json:"version,omitempty"`
+	PURL       string        `json:"purl,omitempty"`
+	Licenses   []cdxLicense  `json:"licenses,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+type cdxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn"`
+}
+
+type cdxDocument struct {
+	BOMFormat    string `json:"bomFormat"`
+	SpecVersion  string `json:"specVersion"`
+	SerialNumber string `json:"serialNumber"`
+	Version      int    `json:"version"`
+	Metadata     struct {
+		Timestamp string         `json:"timestamp"`
+		Tools     []cdxComponent `json:"tools"`
+		Component cdxComponent   `json:"component"`
+	} `json:"metadata"`
+	Components   []cdxComponent  `json:"components"`
+	Dependencies []cdxDependency `json:"dependencies"`
+}
+
+func cycloneDX(name string, info *k6foundry.BuildInfo, now time.Time) cdxDocument {
+	id := documentID(name, info)
+
+	doc := cdxDocument{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.5",
+		// urn:uuid requires an uuid. Use the first 128 bits of the document id
+		SerialNumber: "urn:uuid:" + id[0:8] + "-" + id[8:12] + "-" + id[12:16] + "-" + id[16:20] + "-" + id[20:32],
+		Version:      1,
+	}
+
+	doc.Metadata.Timestamp = now.Format(time.RFC3339)
+	doc.Metadata.Tools = []cdxComponent{{BOMRef: toolName, Type: "application", Name: toolName}}
+	doc.Metadata.Component = cdxComponent{
+		BOMRef:     name,
+		Type:       "application",
+		Name:       name,
+		Properties: []cdxProperty{{Name: "k6foundry:platform", Value: info.Platform}},
+	}
+
+	deps := cdxDependency{Ref: name, DependsOn: []string{}}
+	for _, dep := range info.Dependencies {
+		component := cdxComponent{
+			BOMRef:  purl(dep),
+			Type:    "library",
+			Name:    dep.Path,
+			Version: dep.Version,
+			PURL:    purl(dep),
+		}
+		if dep.Sum != "" {
+			component.Properties = []cdxProperty{{Name: "k6foundry:gosum", Value: dep.Sum}}
+		}
+		if dep.License != "" {
+			component.Licenses = []cdxLicense{{Expression: dep.License}}
+		}
+
+		doc.Components = append(doc.Components, component)
+		deps.DependsOn = append(deps.DependsOn, component.BOMRef)
+	}
+	doc.Dependencies = []cdxDependency{deps}
+
+	return doc
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseDeclared  string            `json:"licenseDeclared,omitempty"`
+	Comment          string            `json:"comment,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+type spdxDocument struct {
+	SPDXVersion       string `json:"spdxVersion"`
+	DataLicense       string `json:"dataLicense"`
+	SPDXID            string `json:"SPDXID"`
+	Name              string `json:"name"`
+	DocumentNamespace string `json:"documentNamespace"`
+	CreationInfo      struct {
+		Created  string   `json:"created"`
+		Creators []string `json:"creators"`
+	} `json:"creationInfo"`
+	Packages      []spdxPackage      `json:"packages"`
+	Relationships []spdxRelationship `json:"relationships"`
+}
+
+// spdxID returns a valid SPDX identifier for a module
+func spdxID(dep k6foundry.Dependency) string {
+	replacer := strings.NewReplacer("/", "-", "@", "-", "_", "-", "+", "-", "~", "-")
+
+	return "SPDXRef-Package-" + replacer.Replace(dep.Path+"-"+dep.Version)
+}
+
+func spdx(name string, info *k6foundry.BuildInfo, now time.Time) spdxDocument {
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              name,
+		DocumentNamespace: "https://github.com/grafana/k6foundry/spdx/" + name + "-" + documentID(name, info),
+	}
+
+	doc.CreationInfo.Created = now.Format(time.RFC3339)
+	doc.CreationInfo.Creators = []string{"Tool: " + toolName}
+
+	binaryID := "SPDXRef-Package-binary"
+	doc.Packages = append(doc.Packages, spdxPackage{
+		SPDXID:           binaryID,
+		Name:             name,
+		DownloadLocation: "NOASSERTION",
+		Comment:          "platform: " + info.Platform,
+	})
+	doc.Relationships = append(doc.Relationships, spdxRelationship{
+		SPDXElementID:      doc.SPDXID,
+		RelationshipType:   "DESCRIBES",
+		RelatedSPDXElement: binaryID,
+	})
+
+	for _, dep := range info.Dependencies {
+		pkg := spdxPackage{
+			SPDXID:           spdxID(dep),
+			Name:             dep.Path,
+			VersionInfo:      dep.Version,
+			DownloadLocation: "NOASSERTION",
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  purl(dep),
+			}},
+		}
+		if dep.Sum != "" {
+			pkg.Comment = "go.sum: " + dep.Sum
+		}
+		if dep.License != "" {
+			pkg.LicenseDeclared = dep.License
+		}
+
+		doc.Packages = append(doc.Packages, pkg)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID:      binaryID,
+			RelationshipType:   "CONTAINS",
+			RelatedSPDXElement: pkg.SPDXID,
+		})
+	}
+
+	return doc
+}
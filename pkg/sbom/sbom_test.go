@@ -0,0 +1,87 @@
+package sbom
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/grafana/k6foundry"
+)
+
+func testInfo() *k6foundry.BuildInfo {
+	return &k6foundry.BuildInfo{
+		Platform:    "linux/amd64",
+		ModVersions: map[string]string{"go.k6.io/k6": "v0.1.0"},
+		Dependencies: []k6foundry.Dependency{
+			{Path: "go.k6.io/k6", Version: "v0.1.0", Sum: "h1:abc=", License: "AGPL-3.0"},
+			{Path: "go.k6.io/k6ext", Version: "v0.1.0"},
+		},
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		format         string
+		info           *k6foundry.BuildInfo
+		expectError    error
+		expectPURLs    int
+		expectLicenses int
+	}{
+		{format: CycloneDX, info: testInfo(), expectPURLs: 2, expectLicenses: 1},
+		{format: SPDX, info: testInfo(), expectPURLs: 2, expectLicenses: 1},
+		{format: "swid", info: testInfo(), expectError: ErrUnsupportedFormat},
+		{format: CycloneDX, info: &k6foundry.BuildInfo{Platform: "linux/amd64"}, expectError: ErrNoDependencies},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.format, func(t *testing.T) {
+			t.Parallel()
+
+			content, err := Generate(tc.format, "k6", tc.info)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			if tc.expectError != nil {
+				return
+			}
+
+			purls, licenses := 0, 0
+			switch tc.format {
+			case CycloneDX:
+				doc := cdxDocument{}
+				if err = json.Unmarshal(content, &doc); err != nil {
+					t.Fatalf("unmarshalling %v", err)
+				}
+				for _, c := range doc.Components {
+					if c.PURL != "" {
+						purls++
+					}
+					licenses += len(c.Licenses)
+				}
+			case SPDX:
+				doc := spdxDocument{}
+				if err = json.Unmarshal(content, &doc); err != nil {
+					t.Fatalf("unmarshalling %v", err)
+				}
+				for _, p := range doc.Packages {
+					purls += len(p.ExternalRefs)
+					if p.LicenseDeclared != "" {
+						licenses++
+					}
+				}
+			}
+
+			if purls != tc.expectPURLs {
+				t.Fatalf("expected %d packages got %d", tc.expectPURLs, purls)
+			}
+
+			if licenses != tc.expectLicenses {
+				t.Fatalf("expected %d licenses got %d", tc.expectLicenses, licenses)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/grafana/k6foundry"
+)
+
+var (
+	// ErrRemoteBuild is returned when the build service fails without reporting the class of the error
+	ErrRemoteBuild = errors.New("remote build")
+	// ErrInvalidServerURL is returned when the URL of the build service is not valid
+	ErrInvalidServerURL = errors.New("invalid server url")
+)
+
+// RemoteBuilderOpts defines the options of a RemoteBuilder
+type RemoteBuilderOpts struct {
+	// client used for the requests. Defaults to http.DefaultClient
+	Client *http.Client
+	// headers added to the requests (e.g. authorization required by a proxy in front of the service)
+	Header http.Header
+}
+
+// RemoteBuilder is a k6foundry.Builder that delegates the builds to a build service (see NewBuildHandler),
+// so clients without a go toolchain can build using a central build infrastructure.
+// The binary is streamed to the output as it is received.
+type RemoteBuilder struct {
+	url    *url.URL
+	client *http.Client
+	header http.Header
+}
+
+// NewRemoteBuilder returns a RemoteBuilder for the build service at the URL (e.g. http://localhost:8000)
+func NewRemoteBuilder(serverURL string, opts RemoteBuilderOpts) (*RemoteBuilder, error) {
+	parsed, err := url.Parse(serverURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidServerURL, err)
+	}
+
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidServerURL, serverURL)
+	}
+
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &RemoteBuilder{url: parsed.JoinPath("build"), client: client, header: opts.Header}, nil
+}
+
+// Build requests the build of a custom k6 binary to the build service and writes it to the out io.Writer.
+// The service rejects build options and replacements, as they depend on its environment.
+// Errors reported by the service are returned as a k6foundry.BuildError that wraps the error of its class
+// (see k6foundry.ClassError), so they can be checked with errors.Is
+func (b *RemoteBuilder) Build(
+	ctx context.Context,
+	platform k6foundry.Platform,
+	k6Version string,
+	mods []k6foundry.Module,
+	buildOpts []string,
+	out io.Writer,
+) (*k6foundry.BuildInfo, error) {
+	body, err := json.Marshal(k6foundry.BuildRequest{
+		Platform:     platform.String(),
+		K6Version:    k6Version,
+		Dependencies: mods,
+		BuildOpts:    buildOpts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range b.header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrRemoteBuild, err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, responseError(resp)
+	}
+
+	result := k6foundry.BuildResult{}
+	if err = json.Unmarshal([]byte(resp.Header.Get(BuildInfoHeader)), &result); err != nil {
+		return nil, fmt.Errorf("%w: invalid build result %w", ErrRemoteBuild, err)
+	}
+	buildInfo := &result.BuildInfo
+
+	// the binary is verified against the checksum reported by the service, if any
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(out, hash), resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: receiving binary %w", ErrRemoteBuild, err)
+	}
+
+	checksum := "sha256:" + hex.EncodeToString(hash.Sum(nil))
+	if buildInfo.Checksum != "" && buildInfo.Checksum != checksum {
+		return nil, fmt.Errorf("%w: binary checksum %s expected %s", ErrRemoteBuild, checksum, buildInfo.Checksum)
+	}
+
+	buildInfo.Size = size
+
+	return buildInfo, nil
+}
+
+// BuildMultiPlatform builds the binaries for the platforms one after the other, so the builds don't
+// exceed the concurrency limits of the service
+func (b *RemoteBuilder) BuildMultiPlatform(
+	ctx context.Context,
+	platforms []k6foundry.Platform,
+	k6Version string,
+	mods []k6foundry.Module,
+	buildOpts []string,
+	out k6foundry.OutputFunc,
+) ([]*k6foundry.BuildInfo, error) {
+	buildInfos := make([]*k6foundry.BuildInfo, 0, len(platforms))
+	for _, platform := range platforms {
+		binary, err := out(platform)
+		if err != nil {
+			return nil, err
+		}
+
+		buildInfo, err := b.Build(ctx, platform, k6Version, mods, buildOpts, binary)
+		if err != nil {
+			return nil, err
+		}
+
+		buildInfos = append(buildInfos, buildInfo)
+	}
+
+	return buildInfos, nil
+}
+
+// responseError returns the error reported in the ErrorResponse of a failed request
+func responseError(resp *http.Response) error {
+	content, _ := io.ReadAll(io.LimitReader(resp.Body, maxRequestSize))
+
+	response := ErrorResponse{}
+	if err := json.Unmarshal(content, &response); err != nil || response.Error == "" {
+		return fmt.Errorf("%w: %s %s", ErrRemoteBuild, resp.Status, strings.TrimSpace(string(content)))
+	}
+
+	err := &remoteError{msg: response.Error, err: statusError(resp.StatusCode)}
+	if response.Details == nil {
+		return err
+	}
+
+	if classErr := k6foundry.ClassError(response.Details.Class); classErr != nil {
+		err.err = classErr
+	}
+	response.Details.Err = err
+
+	return response.Details
+}
+
+// statusError returns the error for the status of a failed request, inverting buildErrorStatus
+func statusError(status int) error {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrInvalidRequest
+	case http.StatusUnprocessableEntity:
+		return k6foundry.ErrResolvingDependency
+	case http.StatusServiceUnavailable:
+		return k6foundry.ErrBusy
+	default:
+		return ErrRemoteBuild
+	}
+}
+
+// remoteError is an error reported by the build service. It keeps the message of the service and wraps
+// the error corresponding to its class
+type remoteError struct {
+	msg string
+	err error
+}
+
+func (e *remoteError) Error() string {
+	return e.msg
+}
+
+func (e *remoteError) Unwrap() error {
+	return e.err
+}
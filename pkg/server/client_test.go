@@ -0,0 +1,137 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafana/k6foundry"
+)
+
+func TestRemoteBuilder(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.Handle("/build", NewBuildHandler(fakeBuilder{}, nil))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	b, err := NewRemoteBuilder(srv.URL, RemoteBuilderOpts{})
+	if err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	platform := k6foundry.NewPlatform("linux", "amd64")
+
+	testCases := []struct {
+		title       string
+		k6Version   string
+		mods        []k6foundry.Module
+		buildOpts   []string
+		expectError error
+		expectPhase k6foundry.Phase
+	}{
+		{
+			title:     "build",
+			k6Version: "v0.1.0",
+			mods:      []k6foundry.Module{{Path: "go.k6.io/k6ext", Version: "v0.1.0"}},
+		},
+		{
+			title:       "unresolved version",
+			k6Version:   "v0.2.0",
+			expectError: k6foundry.ErrResolvingDependency,
+			expectPhase: k6foundry.PhaseResolve,
+		},
+		{
+			title:       "module not found",
+			k6Version:   "missing",
+			expectError: k6foundry.ErrModuleNotFound,
+			expectPhase: k6foundry.PhaseResolve,
+		},
+		{
+			title:       "builder busy",
+			k6Version:   "busy",
+			expectError: k6foundry.ErrBusy,
+		},
+		{
+			title:       "replacement not allowed",
+			k6Version:   "v0.1.0",
+			mods:        []k6foundry.Module{{Path: "go.k6.io/k6ext", ReplacePath: "/etc"}},
+			expectError: ErrInvalidRequest,
+		},
+		{
+			title:       "build options not supported",
+			k6Version:   "v0.1.0",
+			buildOpts:   []string{"-race"},
+			expectError: ErrInvalidRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			binary := &bytes.Buffer{}
+			info, err := b.Build(context.Background(), platform, tc.k6Version, tc.mods, tc.buildOpts, binary)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			if tc.expectError != nil {
+				phase := k6foundry.Phase("")
+				var buildErr *k6foundry.BuildError
+				if errors.As(err, &buildErr) {
+					phase = buildErr.Phase
+				}
+
+				if phase != tc.expectPhase {
+					t.Fatalf("expected error of phase %q got %v", tc.expectPhase, err)
+				}
+
+				return
+			}
+
+			if binary.String() != "binary" {
+				t.Fatalf("unexpected binary %q", binary.String())
+			}
+
+			if info.Platform != "linux/amd64" || info.ModVersions["go.k6.io/k6"] != "v0.1.0" || info.Size != 6 {
+				t.Fatalf("unexpected build info %v", info)
+			}
+		})
+	}
+}
+
+func TestRemoteBuilderChecksum(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(BuildInfoHeader, `{"platform": "linux/amd64", "checksum": "sha256:0000"}`)
+		_, _ = w.Write([]byte("binary"))
+	}))
+	t.Cleanup(srv.Close)
+
+	b, err := NewRemoteBuilder(srv.URL, RemoteBuilderOpts{})
+	if err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	_, err = b.Build(context.Background(), k6foundry.NewPlatform("linux", "amd64"), "v0.1.0", nil, nil, &bytes.Buffer{})
+	if !errors.Is(err, ErrRemoteBuild) {
+		t.Fatalf("expected %v got %v", ErrRemoteBuild, err)
+	}
+}
+
+func TestNewRemoteBuilder(t *testing.T) {
+	t.Parallel()
+
+	for _, serverURL := range []string{"", "localhost:8000", "ftp://localhost", "http://"} {
+		if _, err := NewRemoteBuilder(serverURL, RemoteBuilderOpts{}); !errors.Is(err, ErrInvalidServerURL) {
+			t.Fatalf("expected %v for %q got %v", ErrInvalidServerURL, serverURL, err)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Package server implements an HTTP API for building custom k6 binaries
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/grafana/k6foundry"
+)
+
+const (
+	// BuildInfoHeader is the response header with the build info of the binary, in JSON format
+	BuildInfoHeader = "X-K6foundry-Build-Info"
+
+	// max size of a build request
+	maxRequestSize = 1 << 20
+)
+
+// ErrInvalidRequest is returned when the build request is not valid
+var ErrInvalidRequest = errors.New("invalid build request")
+
+// BuildRequest defines the parameters of a build. Replacements and build options are not allowed, as they
+// depend on the server's environment
+type BuildRequest = k6foundry.BuildRequest
+
+// ErrorResponse is returned when the build fails
+type ErrorResponse struct {
+	Error string `json:"error"`
+	// Details of the build failure, if the build failed
+	Details *k6foundry.BuildError `json:"details,omitempty"`
+}
+
+// buildHandler handles build requests
+type buildHandler struct {
+	builder k6foundry.Builder
+	log     *slog.Logger
+}
+
+// NewBuildHandler returns a http.Handler that builds k6 binaries using the builder.
+//
+// The handler expects a POST request with a BuildRequest in the body, and returns the binary in the
+// body of the response and its k6foundry.BuildResult in the BuildInfoHeader header.
+// If the build fails, it returns an ErrorResponse.
+func NewBuildHandler(builder k6foundry.Builder, log *slog.Logger) http.Handler {
+	if log == nil {
+		log = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
+	return &buildHandler{builder: builder, log: log}
+}
+
+func (h *buildHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	req := BuildRequest{}
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxRequestSize)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("%w: %w", ErrInvalidRequest, err))
+		return
+	}
+
+	platform, k6Version, mods, err := parseRequest(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	h.log.Info(fmt.Sprintf("building k6 %s for %s with %v", k6Version, platform, req.Dependencies))
+	start := time.Now()
+
+	// the binary is kept in a temporary file, so build errors can be reported before sending the response
+	binary, err := os.CreateTemp("", "k6foundry-server*") //nolint:forbidigo
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer func() {
+		_ = binary.Close()
+		_ = os.Remove(binary.Name()) //nolint:forbidigo
+	}()
+
+	buildInfo, err := h.builder.Build(r.Context(), platform, k6Version, mods, nil, binary)
+	if err != nil {
+		h.log.Error(fmt.Sprintf("build failed: %v", err))
+		writeError(w, buildErrorStatus(err), err)
+		return
+	}
+
+	// the checksum lets the clients verify the binary received
+	result, err := k6foundry.NewBuildResult(binary.Name(), buildInfo)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	result.Binary = ""
+	result.DurationSeconds = time.Since(start).Seconds()
+
+	info, err := json.Marshal(result)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if _, err = binary.Seek(0, io.SeekStart); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(result.Size, 10))
+	w.Header().Set(BuildInfoHeader, string(info))
+	w.WriteHeader(http.StatusOK)
+
+	if _, err = io.Copy(w, binary); err != nil {
+		h.log.Error(fmt.Sprintf("sending binary: %v", err))
+	}
+}
+
+func parseRequest(req BuildRequest) (k6foundry.Platform, string, []k6foundry.Module, error) {
+	platform := k6foundry.RuntimePlatform()
+	if req.Platform != "" {
+		var err error
+		platform, err = k6foundry.ParsePlatform(req.Platform)
+		if err != nil {
+			return platform, "", nil, fmt.Errorf("%w: %w", ErrInvalidRequest, err)
+		}
+	}
+
+	k6Version := req.K6Version
+	if k6Version == "" {
+		k6Version = "latest"
+	}
+
+	// build options could run arbitrary commands in the server (e.g. -toolexec)
+	if len(req.BuildOpts) > 0 {
+		return platform, "", nil, fmt.Errorf("%w: build options not allowed", ErrInvalidRequest)
+	}
+
+	mods := req.Dependencies
+	for _, mod := range mods {
+		// replacements could reference the server's filesystem
+		if mod.ReplacePath != "" {
+			return platform, "", nil, fmt.Errorf("%w: replacements not allowed %q", ErrInvalidRequest, mod)
+		}
+	}
+
+	return platform, k6Version, mods, nil
+}
+
+// buildErrorStatus returns the response status for a build error
+func buildErrorStatus(err error) int {
+	if errors.Is(err, k6foundry.ErrConflictingDependencies) {
+		return http.StatusBadRequest
+	}
+
+	if errors.Is(err, k6foundry.ErrResolvingDependency) {
+		return http.StatusUnprocessableEntity
+	}
+
+	if errors.Is(err, k6foundry.ErrBusy) {
+		return http.StatusServiceUnavailable
+	}
+
+	return http.StatusInternalServerError
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	response := ErrorResponse{Error: err.Error()}
+	_ = errors.As(err, &response.Details)
+
+	_ = json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,186 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafana/k6foundry"
+)
+
+// fakeBuilder returns a fixed binary, failing with a BuildError for unknown k6 versions.
+// The "busy" version fails with ErrBusy and the "missing" version with ErrModuleNotFound
+type fakeBuilder struct{}
+
+func (b fakeBuilder) Build(
+	_ context.Context,
+	platform k6foundry.Platform,
+	k6Version string,
+	_ []k6foundry.Module,
+	_ []string,
+	out io.Writer,
+) (*k6foundry.BuildInfo, error) {
+	if k6Version == "busy" {
+		return nil, k6foundry.ErrBusy
+	}
+
+	if k6Version == "missing" {
+		err := fmt.Errorf("%w: %w", k6foundry.ErrResolvingDependency, k6foundry.ErrModuleNotFound)
+		return nil, &k6foundry.BuildError{
+			Phase:  k6foundry.PhaseResolve,
+			Module: "go.k6.io/k6",
+			Class:  k6foundry.ErrorClass(err),
+			Err:    err,
+		}
+	}
+
+	if k6Version != "v0.1.0" {
+		return nil, &k6foundry.BuildError{
+			Phase:  k6foundry.PhaseResolve,
+			Module: "go.k6.io/k6",
+			Err:    fmt.Errorf("%w: k6 %s", k6foundry.ErrResolvingDependency, k6Version),
+		}
+	}
+
+	_, err := out.Write([]byte("binary"))
+
+	return &k6foundry.BuildInfo{
+		Platform:    platform.String(),
+		ModVersions: map[string]string{"go.k6.io/k6": k6Version},
+	}, err
+}
+
+func TestBuildHandler(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(NewBuildHandler(fakeBuilder{}, nil))
+	t.Cleanup(srv.Close)
+
+	testCases := []struct {
+		title        string
+		method       string
+		request      string
+		expectStatus int
+		expectPhase  k6foundry.Phase
+	}{
+		{
+			title:        "build",
+			method:       http.MethodPost,
+			request:      `{"platform": "linux/amd64", "k6Version": "v0.1.0", "dependencies": ["go.k6.io/k6ext@v0.1.0"]}`,
+			expectStatus: http.StatusOK,
+		},
+		{
+			title:        "invalid method",
+			method:       http.MethodGet,
+			expectStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			title:        "invalid json",
+			method:       http.MethodPost,
+			request:      `{`,
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			title:        "invalid platform",
+			method:       http.MethodPost,
+			request:      `{"platform": "linux", "k6Version": "v0.1.0"}`,
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			title:        "replacement not allowed",
+			method:       http.MethodPost,
+			request:      `{"k6Version": "v0.1.0", "dependencies": ["go.k6.io/k6ext=/etc"]}`,
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			title:        "build options not allowed",
+			method:       http.MethodPost,
+			request:      `{"k6Version": "v0.1.0", "buildOpts": ["-toolexec=/bin/sh"]}`,
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			title:        "unsupported api version",
+			method:       http.MethodPost,
+			request:      `{"apiVersion": "k6foundry/v0", "k6Version": "v0.1.0"}`,
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			title:        "unresolved version",
+			method:       http.MethodPost,
+			request:      `{"k6Version": "v0.2.0"}`,
+			expectStatus: http.StatusUnprocessableEntity,
+			expectPhase:  k6foundry.PhaseResolve,
+		},
+		{
+			title:        "builder busy",
+			method:       http.MethodPost,
+			request:      `{"k6Version": "busy"}`,
+			expectStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequestWithContext(
+				context.Background(),
+				tc.method,
+				srv.URL,
+				bytes.NewBufferString(tc.request),
+			)
+			if err != nil {
+				t.Fatalf("creating request %v", err)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("sending request %v", err)
+			}
+			defer resp.Body.Close() //nolint:errcheck
+
+			if resp.StatusCode != tc.expectStatus {
+				t.Fatalf("expected status %d got %d", tc.expectStatus, resp.StatusCode)
+			}
+
+			if tc.expectStatus != http.StatusOK {
+				errResp := ErrorResponse{}
+				if err = json.NewDecoder(resp.Body).Decode(&errResp); err != nil {
+					t.Fatalf("parsing error response %v", err)
+				}
+
+				phase := k6foundry.Phase("")
+				if errResp.Details != nil {
+					phase = errResp.Details.Phase
+				}
+
+				if phase != tc.expectPhase {
+					t.Fatalf("expected details of phase %q got %v", tc.expectPhase, errResp.Details)
+				}
+
+				return
+			}
+
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != "binary" {
+				t.Fatalf("unexpected binary %q", body)
+			}
+
+			result := k6foundry.BuildResult{}
+			if err = json.Unmarshal([]byte(resp.Header.Get(BuildInfoHeader)), &result); err != nil {
+				t.Fatalf("parsing build result %v", err)
+			}
+
+			if result.APIVersion != k6foundry.APIVersion || result.Platform != "linux/amd64" ||
+				result.ModVersions["go.k6.io/k6"] != "v0.1.0" || result.Checksum == "" || result.Size != 6 {
+				t.Fatalf("unexpected build result %v", result)
+			}
+		})
+	}
+}
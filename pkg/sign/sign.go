@@ -0,0 +1,191 @@
+// Package sign implements signers that produce detached signatures for the built binaries
+package sign
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	// SignatureExt is the extension of the detached signature file
+	SignatureExt = ".sig"
+	// CertificateExt is the extension of the signing certificate file (keyless signing)
+	CertificateExt = ".pem"
+)
+
+var (
+	// ErrInvalidKey is returned when the signing key can't be parsed
+	ErrInvalidKey = errors.New("invalid signing key")
+	// ErrSigning is returned when the file can't be signed
+	ErrSigning = errors.New("signing")
+	// ErrNoCosign is returned when cosign is required but not installed
+	ErrNoCosign = errors.New("cosign not found")
+)
+
+// Signer produces a detached signature for a file
+type Signer interface {
+	// Sign signs the file and returns the paths of the files generated (signature and certificate, if any)
+	Sign(ctx context.Context, path string) ([]string, error)
+}
+
+// NewKeySigner returns a Signer that uses the private key in the PEM file.
+//
+// Unencrypted PKCS#8, EC and PKCS#1 keys are supported natively, producing a base64 encoded signature
+// of the SHA-256 digest of the file (compatible with cosign verify-blob --key).
+// Encrypted cosign keys are signed using the cosign CLI, which prompts for the password or takes it from
+// the COSIGN_PASSWORD environment variable.
+func NewKeySigner(keyPath string) (Signer, error) {
+	content, err := os.ReadFile(keyPath) //nolint:forbidigo,gosec
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf("%w: not a PEM file", ErrInvalidKey)
+	}
+
+	if strings.HasPrefix(block.Type, "ENCRYPTED") {
+		return newCosignSigner("--key", keyPath)
+	}
+
+	key, err := parsePrivateKey(block)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidKey, err)
+	}
+
+	return &keySigner{key: key}, nil
+}
+
+// NewKeylessSigner returns a Signer that uses the Sigstore keyless flow of the cosign CLI.
+// The signature and the signing certificate are written next to the file.
+func NewKeylessSigner() (Signer, error) {
+	return newCosignSigner()
+}
+
+func parsePrivateKey(block *pem.Block) (crypto.Signer, error) {
+	var (
+		key any
+		err error
+	)
+
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+
+	return signer, nil
+}
+
+// keySigner signs using a private key
+type keySigner struct {
+	key crypto.Signer
+}
+
+func (s *keySigner) Sign(_ context.Context, path string) ([]string, error) {
+	file, err := os.Open(path) //nolint:forbidigo,gosec
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSigning, err)
+	}
+	defer file.Close() //nolint:errcheck
+
+	var signature []byte
+
+	switch key := s.key.(type) {
+	case ed25519.PrivateKey:
+		// ed25519 signs the message, not a digest
+		content, err := io.ReadAll(file)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrSigning, err)
+		}
+		signature = ed25519.Sign(key, content)
+	case *ecdsa.PrivateKey, *rsa.PrivateKey:
+		hash := sha256.New()
+		if _, err = io.Copy(hash, file); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrSigning, err)
+		}
+		signature, err = key.Sign(rand.Reader, hash.Sum(nil), crypto.SHA256)
+	default:
+		err = fmt.Errorf("unsupported key type %T", key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSigning, err)
+	}
+
+	sigPath := path + SignatureExt
+	encoded := base64.StdEncoding.EncodeToString(signature)
+	if err = os.WriteFile(sigPath, []byte(encoded), 0o644); err != nil { //nolint:forbidigo,gosec
+		return nil, fmt.Errorf("%w: %w", ErrSigning, err)
+	}
+
+	return []string{sigPath}, nil
+}
+
+// cosignSigner signs using the cosign CLI
+type cosignSigner struct {
+	cosign string
+	args   []string
+}
+
+func newCosignSigner(args ...string) (Signer, error) {
+	cosign, err := exec.LookPath("cosign")
+	if err != nil {
+		return nil, ErrNoCosign
+	}
+
+	return &cosignSigner{cosign: cosign, args: args}, nil
+}
+
+func (s *cosignSigner) Sign(ctx context.Context, path string) ([]string, error) {
+	sigPath := path + SignatureExt
+	certPath := path + CertificateExt
+
+	args := append([]string{"sign-blob", "--yes", "--output-signature", sigPath}, s.args...)
+
+	// keyless signing generates a certificate
+	keyless := len(s.args) == 0
+	if keyless {
+		args = append(args, "--output-certificate", certPath)
+	}
+	args = append(args, path)
+
+	cmd := exec.CommandContext(ctx, s.cosign, args...) //nolint:gosec
+	cmd.Stdin = os.Stdin
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%w: cosign %w", ErrSigning, err)
+	}
+
+	if keyless {
+		return []string{sigPath, certPath}, nil
+	}
+
+	return []string{sigPath}, nil
+}
@@ -0,0 +1,118 @@
+package sign
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeKey(t *testing.T, key crypto.PrivateKey) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshalling key %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	content := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	if err = os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatalf("writing key %v", err)
+	}
+
+	return path
+}
+
+func TestKeySigner(t *testing.T) {
+	t.Parallel()
+
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	content := []byte("binary")
+	digest := sha256.Sum256(content)
+
+	testCases := []struct {
+		title  string
+		key    crypto.PrivateKey
+		verify func(sig []byte) bool
+	}{
+		{
+			title:  "ecdsa",
+			key:    ecKey,
+			verify: func(sig []byte) bool { return ecdsa.VerifyASN1(&ecKey.PublicKey, digest[:], sig) },
+		},
+		{
+			title: "ed25519",
+			key:   edKey,
+			verify: func(sig []byte) bool {
+				return ed25519.Verify(edKey.Public().(ed25519.PublicKey), content, sig) //nolint:forcetypeassert
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			signer, err := NewKeySigner(writeKey(t, tc.key))
+			if err != nil {
+				t.Fatalf("creating signer %v", err)
+			}
+
+			path := filepath.Join(t.TempDir(), "k6")
+			if err = os.WriteFile(path, content, 0o600); err != nil {
+				t.Fatalf("setup %v", err)
+			}
+
+			files, err := signer.Sign(context.Background(), path)
+			if err != nil {
+				t.Fatalf("signing %v", err)
+			}
+
+			if len(files) != 1 || files[0] != path+SignatureExt {
+				t.Fatalf("unexpected files %v", files)
+			}
+
+			encoded, err := os.ReadFile(files[0])
+			if err != nil {
+				t.Fatalf("reading signature %v", err)
+			}
+
+			sig, err := base64.StdEncoding.DecodeString(string(encoded))
+			if err != nil {
+				t.Fatalf("decoding signature %v", err)
+			}
+
+			if !tc.verify(sig) {
+				t.Fatal("invalid signature")
+			}
+		})
+	}
+}
+
+func TestInvalidKey(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, []byte("not a key"), 0o600); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	_, err := NewKeySigner(path)
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("expected %v got %v", ErrInvalidKey, err)
+	}
+}
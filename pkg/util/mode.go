@@ -0,0 +1,24 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidFileMode is returned when file permissions can't be parsed
+var ErrInvalidFileMode = errors.New("invalid file mode")
+
+// ParseFileMode parses file permissions in octal notation, with or without leading zeros (e.g. 0755, 750, 0o700)
+func ParseFileMode(modeString string) (fs.FileMode, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(modeString), "0o")
+
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode > uint64(fs.ModePerm) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidFileMode, modeString)
+	}
+
+	return fs.FileMode(mode), nil
+}
@@ -0,0 +1,43 @@
+package util
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestParseFileMode(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		mode      string
+		expect    fs.FileMode
+		expectErr error
+	}{
+		{title: "leading zero", mode: "0755", expect: 0o755},
+		{title: "no leading zero", mode: "750", expect: 0o750},
+		{title: "go notation", mode: "0o700", expect: 0o700},
+		{title: "spaces", mode: " 0644 ", expect: 0o644},
+		{title: "empty", mode: "", expectErr: ErrInvalidFileMode},
+		{title: "not octal", mode: "0789", expectErr: ErrInvalidFileMode},
+		{title: "too large", mode: "1777", expectErr: ErrInvalidFileMode},
+		{title: "symbolic", mode: "u+x", expectErr: ErrInvalidFileMode},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			mode, err := ParseFileMode(tc.mode)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if mode != tc.expect {
+				t.Fatalf("expected %v got %v", tc.expect, mode)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidSize is returned when a size can't be parsed
+var ErrInvalidSize = errors.New("invalid size")
+
+// sizeUnits maps the suffixes of sizes to their multipliers, longest suffixes first
+var sizeUnits = []struct { //nolint:gochecknoglobals
+	suffix     string
+	multiplier int64
+}{
+	{"TB", 1 << 40},
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"T", 1 << 40},
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+	{"B", 1},
+}
+
+// ParseSize parses a size in bytes with an optional unit (B, KB, MB, GB, TB), using 1024 based multiples.
+// e.g. 512MB, 10G, 1024
+func ParseSize(sizeString string) (int64, error) {
+	s := strings.ToUpper(strings.TrimSpace(sizeString))
+	if s == "" {
+		return 0, nil
+	}
+
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if number, found := strings.CutSuffix(s, unit.suffix); found {
+			s, multiplier = strings.TrimSpace(number), unit.multiplier
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidSize, sizeString)
+	}
+
+	return int64(value * float64(multiplier)), nil
+}
+
+// FormatSize formats a size in bytes using the largest unit (KB, MB, GB, TB) it contains, with one decimal.
+// e.g. 1.5MB, 512B
+func FormatSize(size int64) string {
+	for _, unit := range sizeUnits[:4] {
+		if size >= unit.multiplier {
+			return fmt.Sprintf("%.1f%s", float64(size)/float64(unit.multiplier), unit.suffix)
+		}
+	}
+
+	return fmt.Sprintf("%dB", size)
+}
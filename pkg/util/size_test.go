@@ -0,0 +1,71 @@
+package util
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		size      string
+		expect    int64
+		expectErr error
+	}{
+		{title: "empty", size: "", expect: 0},
+		{title: "bytes", size: "1024", expect: 1024},
+		{title: "bytes with unit", size: "10B", expect: 10},
+		{title: "kilobytes", size: "2KB", expect: 2048},
+		{title: "short unit", size: "1g", expect: 1 << 30},
+		{title: "fractional", size: "1.5MB", expect: 3 << 19},
+		{title: "spaces", size: " 10 GB ", expect: 10 << 30},
+		{title: "terabytes", size: "1TB", expect: 1 << 40},
+		{title: "invalid", size: "ten MB", expectErr: ErrInvalidSize},
+		{title: "negative", size: "-1MB", expectErr: ErrInvalidSize},
+		{title: "unknown unit", size: "1PB", expectErr: ErrInvalidSize},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			size, err := ParseSize(tc.size)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if size != tc.expect {
+				t.Fatalf("expected %d got %d", tc.expect, size)
+			}
+		})
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		size   int64
+		expect string
+	}{
+		{size: 0, expect: "0B"},
+		{size: 512, expect: "512B"},
+		{size: 1536, expect: "1.5KB"},
+		{size: 150 << 20, expect: "150.0MB"},
+		{size: 2 << 30, expect: "2.0GB"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.expect, func(t *testing.T) {
+			t.Parallel()
+
+			if size := FormatSize(tc.size); size != tc.expect {
+				t.Fatalf("expected %s got %s", tc.expect, size)
+			}
+		})
+	}
+}
@@ -1,9 +1,11 @@
 package k6foundry
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"runtime"
+	"slices"
 	"strings"
 )
 
@@ -13,6 +15,31 @@ var ErrInvalidPlatform = errors.New("invalid platform") //nolint:revive
 type Platform struct {
 	OS   string
 	Arch string
+	// optional sub-architecture, such as the ARM version (v5, v6, v7), the amd64 microarchitecture
+	// level (v1 to v4), the floating point mode of mips (hardfloat, softfloat) or 386 (sse2, softfloat)
+	Variant string
+}
+
+// variantEnv maps each architecture with variants to the go environment variable that selects the variant
+var variantEnv = map[string]string{ //nolint:gochecknoglobals
+	"arm":      "GOARM",
+	"amd64":    "GOAMD64",
+	"386":      "GO386",
+	"mips":     "GOMIPS",
+	"mipsle":   "GOMIPS",
+	"mips64":   "GOMIPS64",
+	"mips64le": "GOMIPS64",
+}
+
+// variants lists the valid variants of each architecture
+var variants = map[string][]string{ //nolint:gochecknoglobals
+	"arm":      {"v5", "v6", "v7"},
+	"amd64":    {"v1", "v2", "v3", "v4"},
+	"386":      {"sse2", "softfloat"},
+	"mips":     {"hardfloat", "softfloat"},
+	"mipsle":   {"hardfloat", "softfloat"},
+	"mips64":   {"hardfloat", "softfloat"},
+	"mips64le": {"hardfloat", "softfloat"},
 }
 
 // RuntimePlatform returns the Platform of the current executable
@@ -25,21 +52,157 @@ func NewPlatform(os, arch string) Platform {
 	return Platform{OS: os, Arch: arch}
 }
 
-// ParsePlatform parses a string of the format os/arch and returns the corresponding platform
+// wildcard matches any os, arch or variant in a platform expression
+const wildcard = "*"
+
+// ParsePlatform parses a string of the format os/arch[/variant] (e.g. linux/arm/v7) and returns the
+// corresponding platform
 func ParsePlatform(str string) (Platform, error) {
-	idx := strings.IndexRune(str, '/')
-	if idx <= 0 || idx == len(str)-1 {
+	parts := strings.Split(str, "/")
+	if len(parts) < 2 || len(parts) > 3 || slices.Contains(parts, "") {
 		return Platform{}, fmt.Errorf("%w: %s", ErrInvalidPlatform, str)
 	}
 
-	return NewPlatform(str[:idx], str[idx+1:]), nil
+	if slices.Contains(parts, wildcard) {
+		return Platform{}, fmt.Errorf("%w: wildcards are only supported by ParsePlatforms %s", ErrInvalidPlatform, str)
+	}
+
+	platform := NewPlatform(parts[0], parts[1])
+	if len(parts) == 3 {
+		if !slices.Contains(variants[platform.Arch], parts[2]) {
+			return Platform{}, fmt.Errorf("%w: unknown variant %s", ErrInvalidPlatform, str)
+		}
+		platform.Variant = parts[2]
+	}
+
+	return platform, nil
 }
 
-// String returns the platform in the format os/arch
+// ParsePlatforms parses platform expressions and returns the set of platforms they match, in the order
+// they are given and without duplicates. Expressions are platforms in the format os/arch[/variant] where
+// the os and the arch can be a wildcard matching the supported platforms (e.g. linux/* or */arm64), and
+// the variant can be a wildcard matching all the variants of the arch (e.g. linux/amd64/*), or the
+// platform itself if the arch has no variants
+func ParsePlatforms(exprs ...string) ([]Platform, error) {
+	platforms := []Platform{}
+
+	for _, expr := range exprs {
+		matches, err := expandPlatform(expr)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, platform := range matches {
+			if !slices.Contains(platforms, platform) {
+				platforms = append(platforms, platform)
+			}
+		}
+	}
+
+	return platforms, nil
+}
+
+// expandPlatform returns the platforms matching a platform expression
+func expandPlatform(expr string) ([]Platform, error) {
+	parts := strings.Split(expr, "/")
+	if len(parts) < 2 || len(parts) > 3 || slices.Contains(parts, "") {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidPlatform, expr)
+	}
+
+	if !slices.Contains(parts, wildcard) {
+		platform, err := ParsePlatform(expr)
+		if err != nil {
+			return nil, err
+		}
+
+		return []Platform{platform}, nil
+	}
+
+	osName, arch := parts[0], parts[1]
+
+	candidates := []Platform{}
+	if osName != wildcard && arch != wildcard {
+		candidates = append(candidates, NewPlatform(osName, arch))
+	} else {
+		for _, platform := range supported {
+			if (osName == wildcard || osName == platform.OS) && (arch == wildcard || arch == platform.Arch) {
+				candidates = append(candidates, platform)
+			}
+		}
+	}
+
+	if len(parts) == 2 {
+		if len(candidates) == 0 {
+			return nil, fmt.Errorf("%w: no supported platform matches %s", ErrInvalidPlatform, expr)
+		}
+
+		return candidates, nil
+	}
+
+	matches := []Platform{}
+	for _, platform := range candidates {
+		// a wildcard also matches architectures without variants
+		if parts[2] == wildcard && len(variants[platform.Arch]) == 0 {
+			matches = append(matches, platform)
+			continue
+		}
+
+		for _, variant := range variants[platform.Arch] {
+			if parts[2] == wildcard || parts[2] == variant {
+				platform.Variant = variant
+				matches = append(matches, platform)
+			}
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("%w: no platform variant matches %s", ErrInvalidPlatform, expr)
+	}
+
+	return matches, nil
+}
+
+// String returns the platform in the format os/arch[/variant]
 func (p Platform) String() string {
+	if p.Variant != "" {
+		return p.OS + "/" + p.Arch + "/" + p.Variant
+	}
+
 	return p.OS + "/" + p.Arch
 }
 
+// setEnv sets the GOOS, GOARCH and the variable selecting the variant of the architecture, if any
+func (p Platform) setEnv(env map[string]string) {
+	env["GOOS"] = p.OS
+	env["GOARCH"] = p.Arch
+
+	if p.Variant == "" {
+		return
+	}
+
+	value := p.Variant
+	// GOARM takes the version number
+	if p.Arch == "arm" {
+		value = strings.TrimPrefix(value, "v")
+	}
+	env[variantEnv[p.Arch]] = value
+}
+
+// BinaryName returns the file name of an executable for the platform, adding the .exe extension
+// for windows if the name doesn't have it
+func (p Platform) BinaryName(name string) string {
+	if p.OS == "windows" && !strings.HasSuffix(name, ".exe") {
+		return name + ".exe"
+	}
+
+	return name
+}
+
+// suffix returns the platform as a suffix for file names (e.g. linux-arm-v7)
+func (p Platform) suffix() string {
+	return strings.ReplaceAll(p.String(), "/", "-")
+}
+
 // Supported indicates is the given platform is supported
 func (p Platform) Supported() bool {
 	for _, plat := range supported {
@@ -51,6 +214,34 @@ func (p Platform) Supported() bool {
 	return false
 }
 
+// PlatformLister is implemented by builders that can list the platforms their go toolchain can build for
+type PlatformLister interface {
+	// Platforms returns the platforms the go toolchain can build for, without variants
+	Platforms(ctx context.Context) ([]Platform, error)
+}
+
+// Platforms returns the platforms the go toolchain can build for
+func (b *nativeBuilder) Platforms(ctx context.Context) ([]Platform, error) {
+	return b.platformsWith(ctx, b.hostEnv)
+}
+
+// platformsWith lists the platforms of the go environment created by newEnv
+func (b *nativeBuilder) platformsWith(ctx context.Context, newEnv envFactory) ([]Platform, error) {
+	var platforms []Platform
+
+	err := b.withWorkDir(ctx, newEnv, RuntimePlatform(), b.GoOpts, func(_ string, buildEnv *goEnv) error {
+		var err error
+		platforms, err = buildEnv.distList(ctx)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return platforms, nil
+}
+
 // SupportedPlatforms returns a list of supported platforms
 func SupportedPlatforms() []Platform {
 	return supported
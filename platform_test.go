@@ -0,0 +1,253 @@
+package k6foundry
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestParsePlatform(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		platform    string
+		expect      Platform
+		expectEnv   map[string]string
+		expectError error
+	}{
+		{
+			title:     "os and arch",
+			platform:  "linux/amd64",
+			expect:    Platform{OS: "linux", Arch: "amd64"},
+			expectEnv: map[string]string{"GOOS": "linux", "GOARCH": "amd64"},
+		},
+		{
+			title:     "arm version",
+			platform:  "linux/arm/v6",
+			expect:    Platform{OS: "linux", Arch: "arm", Variant: "v6"},
+			expectEnv: map[string]string{"GOOS": "linux", "GOARCH": "arm", "GOARM": "6"},
+		},
+		{
+			title:     "amd64 level",
+			platform:  "linux/amd64/v3",
+			expect:    Platform{OS: "linux", Arch: "amd64", Variant: "v3"},
+			expectEnv: map[string]string{"GOOS": "linux", "GOARCH": "amd64", "GOAMD64": "v3"},
+		},
+		{
+			title:     "mips floating point",
+			platform:  "linux/mipsle/softfloat",
+			expect:    Platform{OS: "linux", Arch: "mipsle", Variant: "softfloat"},
+			expectEnv: map[string]string{"GOOS": "linux", "GOARCH": "mipsle", "GOMIPS": "softfloat"},
+		},
+		{
+			title:     "mips64 floating point",
+			platform:  "linux/mips64/hardfloat",
+			expect:    Platform{OS: "linux", Arch: "mips64", Variant: "hardfloat"},
+			expectEnv: map[string]string{"GOOS": "linux", "GOARCH": "mips64", "GOMIPS64": "hardfloat"},
+		},
+		{
+			title:     "386 floating point",
+			platform:  "linux/386/softfloat",
+			expect:    Platform{OS: "linux", Arch: "386", Variant: "softfloat"},
+			expectEnv: map[string]string{"GOOS": "linux", "GOARCH": "386", "GO386": "softfloat"},
+		},
+		{
+			title:       "unknown variant",
+			platform:    "linux/arm/v9",
+			expectError: ErrInvalidPlatform,
+		},
+		{
+			title:       "variant of arch without variants",
+			platform:    "linux/arm64/v8",
+			expectError: ErrInvalidPlatform,
+		},
+		{
+			title:       "missing arch",
+			platform:    "linux/",
+			expectError: ErrInvalidPlatform,
+		},
+		{
+			title:       "too many elements",
+			platform:    "linux/arm/v7/extra",
+			expectError: ErrInvalidPlatform,
+		},
+		{
+			title:       "wildcard",
+			platform:    "linux/*",
+			expectError: ErrInvalidPlatform,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			platform, err := ParsePlatform(tc.platform)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			if tc.expectError != nil {
+				return
+			}
+
+			if platform != tc.expect {
+				t.Fatalf("expected %v got %v", tc.expect, platform)
+			}
+
+			if platform.String() != tc.platform {
+				t.Fatalf("expected %s got %s", tc.platform, platform.String())
+			}
+
+			env := map[string]string{}
+			platform.setEnv(env)
+			if !reflect.DeepEqual(env, tc.expectEnv) {
+				t.Fatalf("expected %v got %v", tc.expectEnv, env)
+			}
+		})
+	}
+}
+
+func TestParsePlatforms(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		exprs       []string
+		expect      []string
+		expectError error
+	}{
+		{
+			title:  "platforms",
+			exprs:  []string{"linux/amd64", "linux/arm/v7"},
+			expect: []string{"linux/amd64", "linux/arm/v7"},
+		},
+		{
+			title:  "os wildcard",
+			exprs:  []string{"*/arm64"},
+			expect: []string{"linux/arm64", "windows/arm64", "darwin/arm64"},
+		},
+		{
+			title:  "arch wildcard",
+			exprs:  []string{"linux/*"},
+			expect: []string{"linux/amd64", "linux/arm64"},
+		},
+		{
+			title: "all supported platforms",
+			exprs: []string{"*/*"},
+			expect: []string{
+				"linux/amd64", "linux/arm64", "windows/amd64", "windows/arm64", "darwin/amd64", "darwin/arm64",
+			},
+		},
+		{
+			title:  "variant wildcard",
+			exprs:  []string{"linux/arm/*"},
+			expect: []string{"linux/arm/v5", "linux/arm/v6", "linux/arm/v7"},
+		},
+		{
+			title:  "variant wildcard without variants",
+			exprs:  []string{"darwin/*/*"},
+			expect: []string{"darwin/amd64/v1", "darwin/amd64/v2", "darwin/amd64/v3", "darwin/amd64/v4", "darwin/arm64"},
+		},
+		{
+			title:  "variant with wildcards",
+			exprs:  []string{"*/amd64/v3"},
+			expect: []string{"linux/amd64/v3", "windows/amd64/v3", "darwin/amd64/v3"},
+		},
+		{
+			title:  "duplicated platforms",
+			exprs:  []string{"linux/arm64", "linux/*", "*/arm64"},
+			expect: []string{"linux/arm64", "linux/amd64", "windows/arm64", "darwin/arm64"},
+		},
+		{
+			title:       "no matching platform",
+			exprs:       []string{"plan9/*"},
+			expectError: ErrInvalidPlatform,
+		},
+		{
+			title:       "no matching variant",
+			exprs:       []string{"linux/*/v7"},
+			expectError: ErrInvalidPlatform,
+		},
+		{
+			title:       "invalid platform",
+			exprs:       []string{"linux/amd64", "linux"},
+			expectError: ErrInvalidPlatform,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			platforms, err := ParsePlatforms(tc.exprs...)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			if tc.expectError != nil {
+				return
+			}
+
+			got := []string{}
+			for _, platform := range platforms {
+				got = append(got, platform.String())
+			}
+
+			if !slices.Equal(got, tc.expect) {
+				t.Fatalf("expected %v got %v", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestBinaryName(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		platform Platform
+		name     string
+		expect   string
+	}{
+		{platform: Platform{OS: "linux", Arch: "amd64"}, name: "k6", expect: "k6"},
+		{platform: Platform{OS: "windows", Arch: "amd64"}, name: "k6", expect: "k6.exe"},
+		{platform: Platform{OS: "windows", Arch: "arm64"}, name: "k6.exe", expect: "k6.exe"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.platform.String()+"/"+tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.platform.BinaryName(tc.name); got != tc.expect {
+				t.Fatalf("expected %s got %s", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestPlatforms(t *testing.T) {
+	t.Parallel()
+
+	b, err := NewNativeBuilder(context.Background(), NativeBuilderOpts{GoOpts: GoOpts{CopyGoEnv: true}})
+	if err != nil {
+		t.Fatalf("setting up test %v", err)
+	}
+
+	platforms, err := b.(PlatformLister).Platforms(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// the toolchain supports more platforms than k6
+	for _, platform := range append(SupportedPlatforms(), Platform{OS: "linux", Arch: "arm"}) {
+		if !slices.Contains(platforms, platform) {
+			t.Fatalf("expected %s in %v", platform, platforms)
+		}
+	}
+}
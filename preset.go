@@ -0,0 +1,61 @@
+package k6foundry
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ErrInvalidPreset is returned when a build preset is unknown or conflicts with the options
+var ErrInvalidPreset = errors.New("invalid build preset")
+
+// Preset is a curated set of build options for a kind of build
+type Preset string
+
+const (
+	// PresetDebug builds a binary for debugging: keeps the symbols and disables the optimizations and
+	// inlining of all the packages
+	PresetDebug Preset = "debug"
+	// PresetRelease builds a binary for distribution: strips the symbols and debug information and removes
+	// the file system paths
+	PresetRelease Preset = "release"
+	// PresetRace builds a binary with the data race detector, enabling cgo
+	PresetRace Preset = "race"
+)
+
+// Presets returns the available build presets
+func Presets() []Preset {
+	return []Preset{PresetDebug, PresetRelease, PresetRace}
+}
+
+// compiler flags that disable the optimizations and inlining
+var debugGCFlags = []string{"all=-N", "-l"} //nolint:gochecknoglobals
+
+// ApplyPreset returns the options with the settings of the preset. An empty preset returns the options
+// unchanged. Options that contradict the preset (e.g. stripping the debug information in a debug build or
+// disabling cgo in a race build) fail with ErrInvalidPreset
+func ApplyPreset(opts NativeBuilderOpts, preset Preset) (NativeBuilderOpts, error) {
+	switch preset {
+	case "":
+	case PresetDebug:
+		if opts.StripDebugInfo {
+			return opts, fmt.Errorf("%w: %s keeps the debug information, it can't be stripped", ErrInvalidPreset, preset)
+		}
+		if len(opts.GCFlags) > 0 {
+			return opts, fmt.Errorf("%w: %s sets the compiler flags", ErrInvalidPreset, preset)
+		}
+		opts.GCFlags = slices.Clone(debugGCFlags)
+	case PresetRelease:
+		opts.StripDebugInfo = true
+		opts.TrimPath = true
+	case PresetRace:
+		if opts.Env["CGO_ENABLED"] == "0" {
+			return opts, fmt.Errorf("%w: %s requires cgo", ErrInvalidPreset, preset)
+		}
+		opts.Race = true
+	default:
+		return opts, fmt.Errorf("%w: unknown preset %q", ErrInvalidPreset, preset)
+	}
+
+	return opts, nil
+}
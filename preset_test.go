@@ -0,0 +1,84 @@
+package k6foundry
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApplyPreset(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		opts      NativeBuilderOpts
+		preset    Preset
+		expect    NativeBuilderOpts
+		expectErr error
+	}{
+		{
+			title:  "no preset",
+			opts:   NativeBuilderOpts{StripDebugInfo: true},
+			expect: NativeBuilderOpts{StripDebugInfo: true},
+		},
+		{
+			title:  "debug",
+			preset: PresetDebug,
+			expect: NativeBuilderOpts{GoOpts: GoOpts{GCFlags: []string{"all=-N", "-l"}}},
+		},
+		{
+			title:     "debug stripped",
+			opts:      NativeBuilderOpts{StripDebugInfo: true},
+			preset:    PresetDebug,
+			expectErr: ErrInvalidPreset,
+		},
+		{
+			title:     "debug with compiler flags",
+			opts:      NativeBuilderOpts{GoOpts: GoOpts{GCFlags: []string{"-m"}}},
+			preset:    PresetDebug,
+			expectErr: ErrInvalidPreset,
+		},
+		{
+			title:  "release",
+			opts:   NativeBuilderOpts{GoOpts: GoOpts{BuildTags: []string{"netgo"}}},
+			preset: PresetRelease,
+			expect: NativeBuilderOpts{StripDebugInfo: true, GoOpts: GoOpts{TrimPath: true, BuildTags: []string{"netgo"}}},
+		},
+		{
+			title:  "race",
+			preset: PresetRace,
+			expect: NativeBuilderOpts{GoOpts: GoOpts{Race: true}},
+		},
+		{
+			title:     "race without cgo",
+			opts:      NativeBuilderOpts{GoOpts: GoOpts{Env: map[string]string{"CGO_ENABLED": "0"}}},
+			preset:    PresetRace,
+			expectErr: ErrInvalidPreset,
+		},
+		{
+			title:     "unknown",
+			preset:    Preset("fast"),
+			expectErr: ErrInvalidPreset,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			opts, err := ApplyPreset(tc.opts, tc.preset)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(opts, tc.expect) {
+				t.Fatalf("expected %+v got %+v", tc.expect, opts)
+			}
+		})
+	}
+}
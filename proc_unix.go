@@ -0,0 +1,20 @@
+//go:build !windows
+
+package k6foundry
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processAlive returns true if a process with the given pid is running
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	err := syscall.Kill(pid, 0)
+
+	// EPERM means the process exists but belongs to another user
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,61 @@
+//go:build !windows
+
+package k6foundry
+
+import (
+	"errors"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestKillProcessTree(t *testing.T) {
+	t.Parallel()
+
+	// the shell prints the pid of a child process that outlives it unless the group is killed
+	cmd := exec.Command("sh", "-c", "sleep 60 & echo $!; wait")
+	setProcessGroup(cmd)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	if err = cmd.Start(); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	buf := make([]byte, 32)
+	n, err := stdout.Read(buf)
+	if err != nil {
+		t.Fatalf("reading child pid %v", err)
+	}
+
+	child, err := strconv.Atoi(strings.TrimSpace(string(buf[:n])))
+	if err != nil {
+		t.Fatalf("parsing child pid %v", err)
+	}
+
+	if err = killProcessTree(cmd); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	_ = cmd.Wait()
+
+	// the child is killed, but it can take a moment
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		// a killed process can remain as a zombie until it is reaped
+		state, _ := exec.Command("ps", "-o", "stat=", "-p", strconv.Itoa(child)).Output()
+		if err = syscall.Kill(child, 0); errors.Is(err, syscall.ESRCH) || strings.HasPrefix(string(state), "Z") {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("child process %d still running", child)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,41 @@
+//go:build windows
+
+package k6foundry
+
+import (
+	"errors"
+	"os/exec"
+	"strconv"
+	"syscall"
+)
+
+// errInterruptNotSupported is returned when interrupting a process, which is not supported in windows
+var errInterruptNotSupported = errors.New("interrupt not supported")
+
+// createNewProcessGroup is the CREATE_NEW_PROCESS_GROUP process creation flag
+const createNewProcessGroup = 0x00000200
+
+// setProcessGroup starts the command in its own process group, so the processes it spawns
+// (e.g. the compiler or git) can be killed together
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= createNewProcessGroup
+}
+
+// interruptProcessTree is not supported in windows, the process tree must be killed
+func interruptProcessTree(_ *exec.Cmd) error {
+	return errInterruptNotSupported
+}
+
+// killProcessTree kills the process of the command and all its descendants
+func killProcessTree(cmd *exec.Cmd) error {
+	err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run() //nolint:gosec
+	if err != nil {
+		// fallback to killing only the process
+		return cmd.Process.Kill()
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrInvalidProfiles is returned when the profiles can't be loaded
+	ErrInvalidProfiles = errors.New("invalid profiles")
+	// ErrUnknownProfile is returned when a profile is not defined
+	ErrUnknownProfile = errors.New("unknown profile")
+)
+
+//go:embed profiles.yaml
+var builtinProfiles []byte
+
+// Profile is a named set of extensions and build options for common builds
+type Profile struct {
+	// Description of the profile
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Dependencies in the format path[@version][replace@version], or names in the catalog (name[@constraint])
+	Dependencies []string `json:"dependencies,omitempty" yaml:"dependencies,omitempty"`
+	// Options passed to go build
+	BuildOpts []string `json:"buildOpts,omitempty" yaml:"buildOpts,omitempty"`
+	// k6 version used unless a version is requested
+	K6Version string `json:"k6Version,omitempty" yaml:"k6Version,omitempty"`
+}
+
+// Profiles maps profile names to profiles
+//
+// Example (YAML):
+//
+//	kafka-suite:
+//	  description: load testing Kafka clusters
+//	  dependencies:
+//	    - github.com/mostafa/xk6-kafka@v0.26.0
+//	  buildOpts:
+//	    - -ldflags=-s -w
+type Profiles map[string]Profile
+
+// DefaultProfiles returns the built-in profiles
+func DefaultProfiles() Profiles {
+	profiles, err := parseProfiles(builtinProfiles, true)
+	if err != nil {
+		panic(fmt.Sprintf("parsing built-in profiles %v", err))
+	}
+
+	return profiles
+}
+
+// LoadProfiles loads the built-in profiles and, if path is not empty, the profiles in the file, which
+// override the built-in profiles with the same name. Files with .json extension are parsed as JSON,
+// others as YAML.
+func LoadProfiles(path string) (Profiles, error) {
+	profiles := DefaultProfiles()
+	if path == "" {
+		return profiles, nil
+	}
+
+	content, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidProfiles, err)
+	}
+
+	custom, err := parseProfiles(content, strings.ToLower(filepath.Ext(path)) != ".json")
+	if err != nil {
+		return nil, err
+	}
+
+	maps.Copy(profiles, custom)
+
+	return profiles, nil
+}
+
+func parseProfiles(content []byte, isYAML bool) (Profiles, error) {
+	profiles := Profiles{}
+
+	var err error
+	if isYAML {
+		err = yaml.Unmarshal(content, &profiles)
+	} else {
+		err = json.Unmarshal(content, &profiles)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidProfiles, err)
+	}
+
+	for name, profile := range profiles {
+		if len(profile.Dependencies) == 0 && len(profile.BuildOpts) == 0 && profile.K6Version == "" {
+			return nil, fmt.Errorf("%w: profile %q is empty", ErrInvalidProfiles, name)
+		}
+
+		for _, dep := range profile.Dependencies {
+			if strings.TrimSpace(dep) == "" {
+				return nil, fmt.Errorf("%w: empty dependency in profile %q", ErrInvalidProfiles, name)
+			}
+		}
+	}
+
+	return profiles, nil
+}
+
+// Get returns the profile with the given name
+func (p Profiles) Get(name string) (Profile, error) {
+	profile, found := p[name]
+	if !found {
+		return Profile{}, fmt.Errorf("%w: %q", ErrUnknownProfile, name)
+	}
+
+	return profile, nil
+}
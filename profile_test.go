@@ -0,0 +1,137 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestDefaultProfiles(t *testing.T) {
+	t.Parallel()
+
+	for name, profile := range DefaultProfiles() {
+		for _, dep := range profile.Dependencies {
+			if _, err := ParseModule(dep); err != nil {
+				t.Fatalf("profile %s: invalid dependency %q: %v", name, dep, err)
+			}
+		}
+	}
+}
+
+func TestLoadProfiles(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"profiles.yaml": `
+kafka-suite:
+  dependencies: [github.com/mostafa/xk6-kafka@v0.26.0]
+custom:
+  description: custom profile
+  dependencies: [github.com/grafana/xk6-faker]
+  k6Version: v0.50.0
+`,
+		"profiles.json": `{"custom": {"dependencies": ["github.com/grafana/xk6-faker"], "k6Version": "v0.50.0"}}`,
+		"empty.yaml":    `custom: {description: nothing}`,
+		"invalid.json":  `[`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("setting up test %v", err)
+		}
+	}
+
+	testCases := []struct {
+		title       string
+		file        string
+		profile     string
+		expectError error
+		expect      []string
+	}{
+		{
+			title:   "built-in",
+			profile: "kafka-suite",
+			expect:  []string{"github.com/mostafa/xk6-kafka"},
+		},
+		{
+			title:   "built-in overridden",
+			file:    "profiles.yaml",
+			profile: "kafka-suite",
+			expect:  []string{"github.com/mostafa/xk6-kafka@v0.26.0"},
+		},
+		{
+			title:   "custom YAML",
+			file:    "profiles.yaml",
+			profile: "custom",
+			expect:  []string{"github.com/grafana/xk6-faker"},
+		},
+		{
+			title:   "custom JSON",
+			file:    "profiles.json",
+			profile: "custom",
+			expect:  []string{"github.com/grafana/xk6-faker"},
+		},
+		{
+			title:   "built-in with custom file",
+			file:    "profiles.json",
+			profile: "sql",
+			expect: []string{
+				"github.com/grafana/xk6-sql",
+				"github.com/grafana/xk6-sql-driver-mysql",
+				"github.com/grafana/xk6-sql-driver-postgres",
+				"github.com/grafana/xk6-sql-driver-sqlite3",
+			},
+		},
+		{
+			title:       "unknown profile",
+			profile:     "unknown",
+			expectError: ErrUnknownProfile,
+		},
+		{
+			title:       "empty profile",
+			file:        "empty.yaml",
+			expectError: ErrInvalidProfiles,
+		},
+		{
+			title:       "invalid file",
+			file:        "invalid.json",
+			expectError: ErrInvalidProfiles,
+		},
+		{
+			title:       "missing file",
+			file:        "missing.yaml",
+			expectError: ErrInvalidProfiles,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			path := ""
+			if tc.file != "" {
+				path = filepath.Join(dir, tc.file)
+			}
+
+			profiles, err := LoadProfiles(path)
+			if err == nil {
+				_, err = profiles.Get(tc.profile)
+			}
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			if tc.expectError != nil {
+				return
+			}
+
+			if deps := profiles[tc.profile].Dependencies; !slices.Equal(deps, tc.expect) {
+				t.Fatalf("expected %v got %v", tc.expect, deps)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package k6foundry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DefaultRegistryURL is the URL of the official k6 extension registry
+const DefaultRegistryURL = "https://registry.k6.io/registry.json"
+
+// maximum number of suggestions for an unknown extension
+const maxSuggestions = 3
+
+var (
+	// ErrFetchingRegistry is returned when the extension registry can't be retrieved
+	ErrFetchingRegistry = errors.New("fetching extension registry")
+	// ErrUnknownExtension is returned when an extension is not in the registry
+	ErrUnknownExtension = errors.New("extension not found in registry")
+)
+
+// RegistryOpts defines the options for fetching the extension registry
+type RegistryOpts struct {
+	// URL of the registry. Defaults to DefaultRegistryURL
+	URL string
+	// Client used for fetching the registry. Defaults to http.DefaultClient
+	HTTPClient *http.Client
+}
+
+// RegistryExtension describes an extension in the registry
+type RegistryExtension struct {
+	// Module path of the extension
+	Module string `json:"module"`
+	// Description of the extension
+	Description string `json:"description,omitempty"`
+	// JavaScript modules provided by the extension (e.g. k6/x/kubernetes)
+	Imports []string `json:"imports,omitempty"`
+	// Outputs provided by the extension (e.g. prometheus-remote)
+	Outputs []string `json:"outputs,omitempty"`
+	// Support tier (official, partner or community)
+	Tier string `json:"tier,omitempty"`
+	// Categories of the extension (e.g. data, messaging)
+	Categories []string `json:"categories,omitempty"`
+	// Versions of the extension, sorted from newest to oldest
+	Versions []string `json:"versions,omitempty"`
+}
+
+// Name returns the short name of the extension: the last element of its module path without
+// the xk6- prefix (e.g. kubernetes for github.com/grafana/xk6-kubernetes)
+func (e RegistryExtension) Name() string {
+	return strings.TrimPrefix(path.Base(e.Module), "xk6-")
+}
+
+// Registry is the list of extensions in the extension registry
+type Registry []RegistryExtension
+
+// FetchRegistry retrieves the extension registry
+func FetchRegistry(ctx context.Context, opts RegistryOpts) (Registry, error) {
+	url := opts.URL
+	if url == "" {
+		url = DefaultRegistryURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFetchingRegistry, err)
+	}
+
+	client := opts.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFetchingRegistry, err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s", ErrFetchingRegistry, resp.Status)
+	}
+
+	registry := Registry{}
+	if err = json.NewDecoder(resp.Body).Decode(&registry); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrFetchingRegistry, err)
+	}
+
+	return registry, nil
+}
+
+// Get returns the extension with the module path
+func (r Registry) Get(modulePath string) (RegistryExtension, bool) {
+	for _, ext := range r {
+		if ext.Module == modulePath {
+			return ext, true
+		}
+	}
+
+	return RegistryExtension{}, false
+}
+
+// Validate checks the module is an extension in the registry. If not, the error suggests the
+// extensions with similar module paths or names
+func (r Registry) Validate(modulePath string) error {
+	if _, found := r.Get(modulePath); found {
+		return nil
+	}
+
+	suggestions := r.Suggest(modulePath)
+	if len(suggestions) == 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownExtension, modulePath)
+	}
+
+	return fmt.Errorf("%w: %s (did you mean %s?)", ErrUnknownExtension, modulePath, strings.Join(suggestions, " or "))
+}
+
+// Suggest returns the module paths of the extensions similar to the given module path or name,
+// from the most to the least similar
+func (r Registry) Suggest(name string) []string {
+	type candidate struct {
+		module   string
+		distance int
+	}
+
+	candidates := []candidate{}
+	for _, ext := range r {
+		// the distance to the whole path catches typos in the owner, the distance to the name
+		// catches wrong owners or just the name of the extension
+		distance := min(
+			editDistance(name, ext.Module),
+			editDistance(strings.TrimPrefix(path.Base(name), "xk6-"), ext.Name()),
+		)
+
+		// allow about one typo every four characters
+		if distance <= max(1, len(ext.Name())/4) {
+			candidates = append(candidates, candidate{module: ext.Module, distance: distance})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].module < candidates[j].module
+	})
+
+	suggestions := []string{}
+	for _, c := range candidates[:min(len(candidates), maxSuggestions)] {
+		suggestions = append(suggestions, c.module)
+	}
+
+	return suggestions
+}
+
+// Catalog returns a catalog with the extensions in the registry, referenced by their name.
+// If several extensions have the same name, the official one is used. k6 is not included
+func (r Registry) Catalog() Catalog {
+	catalog := Catalog{}
+	for _, ext := range r {
+		if ext.Module == defaultK6ModulePath {
+			continue
+		}
+
+		if _, found := catalog[ext.Name()]; found && ext.Tier != "official" {
+			continue
+		}
+
+		catalog[ext.Name()] = CatalogEntry{Module: ext.Module, Versions: ext.Versions}
+	}
+
+	return catalog
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(b)]
+}
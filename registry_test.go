@@ -0,0 +1,119 @@
+package k6foundry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testRegistry = `[
+  {"module": "go.k6.io/k6", "description": "k6", "tier": "official", "versions": ["v0.50.0"]},
+  {
+    "module": "github.com/grafana/xk6-kubernetes",
+    "description": "Interact with Kubernetes clusters",
+    "imports": ["k6/x/kubernetes"],
+    "tier": "official",
+    "versions": ["v0.9.0", "v0.8.0"]
+  },
+  {"module": "github.com/mostafa/xk6-kafka", "description": "Load test Apache Kafka", "tier": "community"},
+  {"module": "github.com/grafana/xk6-sql", "description": "Use SQL databases", "tier": "official"},
+  {"module": "github.com/someone/xk6-sql", "description": "Fork of xk6-sql", "tier": "community"}
+]`
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/registry.json", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(testRegistry))
+	})
+	mux.HandleFunc("/invalid.json", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("{"))
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	t.Run("fetch", func(t *testing.T) {
+		t.Parallel()
+
+		testCases := []struct {
+			title       string
+			url         string
+			expectError error
+		}{
+			{title: "registry", url: srv.URL + "/registry.json"},
+			{title: "not found", url: srv.URL + "/missing.json", expectError: ErrFetchingRegistry},
+			{title: "invalid", url: srv.URL + "/invalid.json", expectError: ErrFetchingRegistry},
+		}
+
+		for _, tc := range testCases {
+			registry, err := FetchRegistry(context.Background(), RegistryOpts{URL: tc.url})
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("%s: expected %v got %v", tc.title, tc.expectError, err)
+			}
+
+			if tc.expectError == nil && len(registry) != 5 {
+				t.Fatalf("%s: expected 5 extensions got %d", tc.title, len(registry))
+			}
+		}
+	})
+
+	registry, err := FetchRegistry(context.Background(), RegistryOpts{URL: srv.URL + "/registry.json"})
+	if err != nil {
+		t.Fatalf("fetching registry %v", err)
+	}
+
+	t.Run("validate", func(t *testing.T) {
+		t.Parallel()
+
+		testCases := []struct {
+			module      string
+			expectError error
+			suggest     string
+		}{
+			{module: "github.com/grafana/xk6-kubernetes"},
+			{module: "github.com/grafana/xk6-kubernets", expectError: ErrUnknownExtension, suggest: "xk6-kubernetes"},
+			{module: "github.com/grafana/xk6-kafka", expectError: ErrUnknownExtension, suggest: "mostafa/xk6-kafka"},
+			{module: "github.com/grafana/xk6-unknown", expectError: ErrUnknownExtension},
+		}
+
+		for _, tc := range testCases {
+			err := registry.Validate(tc.module)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("%s: expected %v got %v", tc.module, tc.expectError, err)
+			}
+
+			if tc.suggest != "" && !strings.Contains(err.Error(), tc.suggest) {
+				t.Fatalf("%s: expected suggestion %q in %v", tc.module, tc.suggest, err)
+			}
+		}
+	})
+
+	t.Run("suggest", func(t *testing.T) {
+		t.Parallel()
+
+		expect := []string{"github.com/grafana/xk6-sql", "github.com/someone/xk6-sql"}
+		if suggestions := registry.Suggest("sq"); !reflect.DeepEqual(suggestions, expect) {
+			t.Fatalf("expected %v got %v", expect, suggestions)
+		}
+	})
+
+	t.Run("catalog", func(t *testing.T) {
+		t.Parallel()
+
+		expect := Catalog{
+			"kubernetes": {Module: "github.com/grafana/xk6-kubernetes", Versions: []string{"v0.9.0", "v0.8.0"}},
+			"kafka":      {Module: "github.com/mostafa/xk6-kafka"},
+			"sql":        {Module: "github.com/grafana/xk6-sql"},
+		}
+
+		if catalog := registry.Catalog(); !reflect.DeepEqual(catalog, expect) {
+			t.Fatalf("expected %v got %v", expect, catalog)
+		}
+	})
+}
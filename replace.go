@@ -0,0 +1,150 @@
+package k6foundry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/module"
+	"golang.org/x/mod/semver"
+)
+
+// ErrInvalidReplace is returned when a replacement is not valid
+var ErrInvalidReplace = errors.New("invalid replace")
+
+// Replace replaces a module in the build by another module or a local directory, as a replace directive of
+// the go.mod. Unlike the replacement of an extension, any module can be replaced, including the transitive
+// dependencies of k6 and the extensions (e.g. using a fork of a library)
+type Replace struct {
+	// module path of the module replaced
+	Path string
+	// version of the module replaced. If empty, all the versions are replaced
+	Version string
+	// module path or local directory of the replacement
+	ReplacePath string
+	// version of the replacement module. Can be a branch or a commit. Required for modules and not
+	// allowed for local directories
+	ReplaceVersion string
+}
+
+// String returns the replacement in the format path[@version]=replacement[@version] accepted by ParseReplace
+func (r Replace) String() string {
+	sb := &strings.Builder{}
+	sb.WriteString(r.Path)
+	if r.Version != "" {
+		sb.WriteString("@" + r.Version)
+	}
+
+	sb.WriteString("=" + r.ReplacePath)
+	if r.ReplaceVersion != "" {
+		sb.WriteString("@" + r.ReplaceVersion)
+	}
+
+	return sb.String()
+}
+
+// ParseReplace parses a replacement from a string of the form path[@version]=replacement[@version].
+// The replacement can be a module, with a version, or a local directory (see ParseModule)
+func ParseReplace(replaceString string) (Replace, error) {
+	mod, replaceMod, found := strings.Cut(replaceString, "=")
+	if !found || replaceMod == "" {
+		return Replace{}, fmt.Errorf("%w: missing replacement %q", ErrInvalidReplace, replaceString)
+	}
+
+	path, version, _ := strings.Cut(mod, "@")
+
+	replacePath, replaceVersion, err := replace(replaceMod)
+	if err != nil {
+		return Replace{}, fmt.Errorf("%w: %w", ErrInvalidReplace, err)
+	}
+
+	r := Replace{Path: path, Version: version, ReplacePath: replacePath, ReplaceVersion: replaceVersion}
+	if err = r.validate(); err != nil {
+		return Replace{}, err
+	}
+
+	return r, nil
+}
+
+// ParseReplaces parses a list of replacements in the format accepted by ParseReplace
+func ParseReplaces(replaceStrings []string) ([]Replace, error) {
+	replaces := make([]Replace, 0, len(replaceStrings))
+	for _, replaceString := range replaceStrings {
+		r, err := ParseReplace(replaceString)
+		if err != nil {
+			return nil, err
+		}
+		replaces = append(replaces, r)
+	}
+
+	return replaces, nil
+}
+
+// validate checks the replacement can be used as a replace directive
+func (r Replace) validate() error {
+	if err := module.CheckPath(r.Path); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidReplace, err)
+	}
+
+	if r.Version != "" && !semver.IsValid(r.Version) {
+		return fmt.Errorf("%w: invalid version %q of %s", ErrInvalidReplace, r.Version, r.Path)
+	}
+
+	switch {
+	case r.ReplacePath == "":
+		return fmt.Errorf("%w: missing replacement of %s", ErrInvalidReplace, r.Path)
+	case isLocalPath(r.ReplacePath) && r.ReplaceVersion != "":
+		return fmt.Errorf("%w: local replacement of %s can't specify version", ErrInvalidReplace, r.Path)
+	case !isLocalPath(r.ReplacePath) && r.ReplaceVersion == "":
+		return fmt.Errorf("%w: replacement of %s requires a version", ErrInvalidReplace, r.Path)
+	}
+
+	return nil
+}
+
+// validateReplacements checks the replacements are valid and each module (and version) is replaced only once
+func validateReplacements(replacements []Replace) error {
+	seen := map[string]bool{}
+	for _, r := range replacements {
+		if err := r.validate(); err != nil {
+			return err
+		}
+
+		key := r.Path + "@" + r.Version
+		if seen[key] {
+			return fmt.Errorf("%w: %s replaced more than once", ErrInvalidReplace, strings.TrimSuffix(key, "@"))
+		}
+		seen[key] = true
+	}
+
+	return nil
+}
+
+// addReplacements adds the replacements to the go.mod, so they are used when resolving k6 and the extensions
+func (b *nativeBuilder) addReplacements(ctx context.Context, e *goEnv) error {
+	for _, r := range b.Replacements {
+		b.log.Info(fmt.Sprintf("replacing %s", r))
+
+		// go.mod only accepts versions, so branches and commits are resolved first
+		replaceVersion := r.ReplaceVersion
+		if replaceVersion != "" && !semver.IsValid(replaceVersion) {
+			var err error
+			if replaceVersion, err = e.modQuery(ctx, r.ReplacePath, replaceVersion); err != nil {
+				return err
+			}
+		}
+
+		// local directories are resolved because the replace occurs in the work directory
+		replacePath, err := resolvePath(r.ReplacePath)
+		if err != nil {
+			return fmt.Errorf("resolving replace path: %w", err)
+		}
+
+		if err = e.modReplace(ctx, r.Path, r.Version, replacePath, replaceVersion); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,202 @@
+package k6foundry
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestParseReplace(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		replace     string
+		expectError error
+		expect      Replace
+	}{
+		{
+			title:   "module replacement",
+			replace: "google.golang.org/grpc=github.com/my-org/grpc@v1.60",
+			expect: Replace{
+				Path:           "google.golang.org/grpc",
+				ReplacePath:    "github.com/my-org/grpc",
+				ReplaceVersion: "v1.60.0",
+			},
+		},
+		{
+			title:   "versioned module replacement",
+			replace: "google.golang.org/grpc@v1.59.0=github.com/my-org/grpc@main",
+			expect: Replace{
+				Path:           "google.golang.org/grpc",
+				Version:        "v1.59.0",
+				ReplacePath:    "github.com/my-org/grpc",
+				ReplaceVersion: "main",
+			},
+		},
+		{
+			title:   "local replacement",
+			replace: "google.golang.org/grpc=../grpc",
+			expect: Replace{
+				Path:        "google.golang.org/grpc",
+				ReplacePath: "../grpc",
+			},
+		},
+		{
+			title:       "missing replacement",
+			replace:     "google.golang.org/grpc",
+			expectError: ErrInvalidReplace,
+		},
+		{
+			title:       "empty replacement",
+			replace:     "google.golang.org/grpc=",
+			expectError: ErrInvalidReplace,
+		},
+		{
+			title:       "invalid path",
+			replace:     "google.golang.org/grpc/=../grpc",
+			expectError: ErrInvalidReplace,
+		},
+		{
+			title:       "invalid version",
+			replace:     "google.golang.org/grpc@latest=../grpc",
+			expectError: ErrInvalidReplace,
+		},
+		{
+			title:       "unversioned module replacement",
+			replace:     "google.golang.org/grpc=github.com/my-org/grpc",
+			expectError: ErrInvalidReplace,
+		},
+		{
+			title:       "versioned local replacement",
+			replace:     "google.golang.org/grpc=../grpc@v1.60.0",
+			expectError: ErrInvalidReplace,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := ParseReplace(tc.replace)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			if tc.expectError != nil {
+				return
+			}
+
+			if r != tc.expect {
+				t.Fatalf("expected %v got %v", tc.expect, r)
+			}
+
+			parsed, err := ParseReplace(r.String())
+			if err != nil || parsed != r {
+				t.Fatalf("expected %v from %q got %v (%v)", r, r.String(), parsed, err)
+			}
+		})
+	}
+}
+
+func TestValidateReplacements(t *testing.T) {
+	t.Parallel()
+
+	grpc := Replace{Path: "google.golang.org/grpc", ReplacePath: "../grpc"}
+	grpcVersion := Replace{Path: "google.golang.org/grpc", Version: "v1.59.0", ReplacePath: "../grpc"}
+
+	if err := validateReplacements([]Replace{grpc, grpcVersion}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if err := validateReplacements([]Replace{grpc, grpc}); !errors.Is(err, ErrInvalidReplace) {
+		t.Fatalf("expected %v got %v", ErrInvalidReplace, err)
+	}
+}
+
+func TestReplacements(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	for _, m := range []struct{ path, version, source string }{
+		{"go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")},
+		{"go.k6.io/k6ext", "v0.1.0", filepath.Join("testdata", "mods", "k6ext")},
+		{"go.k6.io/k6fork", "v0.1.0-custom", filepath.Join("testdata", "mods", "k6")},
+	} {
+		if err := proxy.AddModVersion(m.path, m.version, m.source); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	localK6, err := filepath.Abs(filepath.Join("testdata", "mods", "k6"))
+	if err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	testCases := []struct {
+		title   string
+		replace Replace
+		expect  string
+	}{
+		{
+			title:   "module replacement",
+			replace: Replace{Path: "go.k6.io/k6", ReplacePath: "go.k6.io/k6fork", ReplaceVersion: "v0.1.0-custom"},
+			expect:  "replace go.k6.io/k6 => go.k6.io/k6fork v0.1.0-custom",
+		},
+		{
+			title:   "local replacement",
+			replace: Replace{Path: "go.k6.io/k6", Version: "v0.1.0", ReplacePath: localK6},
+			expect:  "replace go.k6.io/k6 v0.1.0 => " + localK6,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := NewNativeBuilder(context.Background(), NativeBuilderOpts{
+				Stdout: os.Stdout,
+				Stderr: os.Stderr,
+				GoOpts: GoOpts{
+					CopyGoEnv: true,
+					Env: map[string]string{
+						"GOPROXY":   goproxySrv.URL,
+						"GONOPROXY": "none",
+						"GOPRIVATE": "go.k6.io",
+						"GONOSUMDB": "go.k6.io",
+					},
+					TmpCache: true,
+				},
+				Replacements: []Replace{tc.replace},
+			})
+			if err != nil {
+				t.Fatalf("setting up test %v", err)
+			}
+
+			// k6 is replaced also as a dependency of the extension
+			resolution, err := b.(Resolver).Resolve(
+				context.Background(),
+				"v0.1.0",
+				[]Module{{Path: "go.k6.io/k6ext", Version: "v0.1.0"}},
+			)
+			if err != nil {
+				t.Fatalf("resolving %v", err)
+			}
+
+			if !strings.Contains(resolution.Files["go.mod"], tc.expect) {
+				t.Fatalf("expected %q in go.mod got\n%s", tc.expect, resolution.Files["go.mod"])
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+)
+
+// ErrNotReproducible is returned when building the same inputs twice produces different binaries
+var ErrNotReproducible = errors.New("build is not reproducible")
+
+// linker flags that clear the build ID
+const reproducibleLdFlags = "-buildid="
+
+// reproducibleBuildOpts adds the build flags that remove the paths, the version control information and
+// the build ID from the binary
+func reproducibleBuildOpts(buildOpts []string) []string {
+	flags := []string{}
+	for _, flag := range []string{"-trimpath", "-buildvcs=false"} {
+		if !slices.Contains(buildOpts, flag) {
+			flags = append(flags, flag)
+		}
+	}
+
+	return addLdFlags(append(flags, buildOpts...), reproducibleLdFlags)
+}
+
+// reproducibleGoOpts pins the go toolchain, unless a version is requested, so the toolchain is not
+// switched depending on the requirements of the modules
+func reproducibleGoOpts(opts GoOpts) GoOpts {
+	if opts.GoVersion != "" || opts.Env["GOTOOLCHAIN"] != "" {
+		return opts
+	}
+
+	opts.Env = maps.Clone(opts.Env)
+	if opts.Env == nil {
+		opts.Env = map[string]string{}
+	}
+	opts.Env["GOTOOLCHAIN"] = "local"
+
+	return opts
+}
+
+// verifyReproducible builds the binary again, without reusing the compiled packages, and checks it is
+// identical to the binary
+func (b *nativeBuilder) verifyReproducible(
+	ctx context.Context,
+	buildEnv *goEnv,
+	binary string,
+	buildOpts []string,
+) error {
+	b.log.Info("Verifying the build is reproducible")
+
+	rebuilt := binary + "-verify"
+	defer os.Remove(rebuilt) //nolint:errcheck
+
+	if err := buildEnv.compile(ctx, rebuilt, append([]string{"-a"}, buildOpts...)...); err != nil {
+		return err
+	}
+
+	expected, err := fileSHA256(binary)
+	if err != nil {
+		return err
+	}
+
+	actual, err := fileSHA256(rebuilt)
+	if err != nil {
+		return err
+	}
+
+	if expected != actual {
+		return fmt.Errorf("%w: rebuilt binary sha256:%s differs from sha256:%s", ErrNotReproducible, actual, expected)
+	}
+
+	return nil
+}
+
+// fileSHA256 returns the hex encoded SHA256 digest of the file
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path) //nolint:gosec
+	if err != nil {
+		return "", err
+	}
+	defer file.Close() //nolint:errcheck
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("reading binary %w", err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
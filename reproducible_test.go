@@ -0,0 +1,94 @@
+package k6foundry
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestReproducibleBuildOpts(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		buildOpts []string
+		expect    []string
+	}{
+		{
+			title:     "no build opts",
+			buildOpts: []string{},
+			expect:    []string{"-trimpath", "-buildvcs=false", "-ldflags=-buildid="},
+		},
+		{
+			title:     "merge ldflags",
+			buildOpts: []string{"-trimpath", "-ldflags=-s -w"},
+			expect:    []string{"-buildvcs=false", "-trimpath", "-ldflags=-buildid= -s -w"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			opts := reproducibleBuildOpts(tc.buildOpts)
+			if !slices.Equal(opts, tc.expect) {
+				t.Fatalf("expected %v got %v", tc.expect, opts)
+			}
+		})
+	}
+}
+
+func TestReproducibleBuild(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	if err := proxy.AddModVersion("go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	opts := NativeBuilderOpts{
+		GoOpts: GoOpts{
+			CopyGoEnv: true,
+			Env: map[string]string{
+				"GOPROXY":   goproxySrv.URL,
+				"GONOPROXY": "none",
+				"GOPRIVATE": "go.k6.io",
+				"GONOSUMDB": "go.k6.io",
+			},
+			TmpCache: true,
+		},
+		Reproducible: true,
+		Checksum:     true,
+	}
+
+	// each build uses its own work directory and caches
+	checksums := []string{}
+	for _, verify := range []bool{false, true} {
+		opts.VerifyReproducible = verify
+
+		b, err := NewNativeBuilder(context.Background(), opts)
+		if err != nil {
+			t.Fatalf("setting up test %v", err)
+		}
+
+		info, err := b.Build(context.Background(), RuntimePlatform(), "v0.1.0", []Module{}, []string{}, &bytes.Buffer{})
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		checksums = append(checksums, info.Checksum)
+	}
+
+	if checksums[0] != checksums[1] {
+		t.Fatalf("expected identical binaries got %v", checksums)
+	}
+}
@@ -0,0 +1,83 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Resolver is implemented by builders that can resolve the dependencies of a build without compiling it.
+// This allows auditing the build environment that would be used for building the binary.
+type Resolver interface {
+	// Resolve resolves k6 and the dependencies and returns the generated build environment
+	Resolve(ctx context.Context, k6Version string, mods []Module) (*Resolution, error)
+}
+
+// Resolution is the build environment generated for building a k6 binary
+type Resolution struct {
+	// build info with the resolved versions. The platform is not set, as the resolution is platform independent
+	BuildInfo *BuildInfo `json:"buildInfo"`
+	// content of the generated files (main.go, the imports of the extensions, go.mod and go.sum) by name
+	Files map[string]string `json:"files"`
+}
+
+// Resolve resolves k6 and the dependencies and returns the generated build environment
+func (b *nativeBuilder) Resolve(ctx context.Context, k6Version string, exts []Module) (*Resolution, error) {
+	return b.resolveWith(ctx, b.hostEnv, k6Version, exts)
+}
+
+func (b *nativeBuilder) resolveWith(
+	ctx context.Context,
+	newEnv envFactory,
+	k6Version string,
+	exts []Module,
+) (*Resolution, error) {
+	if err := checkConflicts(exts); err != nil {
+		return nil, err
+	}
+
+	resolution := &Resolution{Files: map[string]string{}}
+
+	err := b.withWorkDir(ctx, newEnv, RuntimePlatform(), b.GoOpts, func(workDir string, buildEnv *goEnv) error {
+		b.log.Info("Resolving k6 build environment")
+
+		if err := b.resetWorkDir(workDir); err != nil {
+			return err
+		}
+
+		buildInfo, err := b.prepare(ctx, workDir, buildEnv, k6Version, exts)
+		if err != nil {
+			return err
+		}
+		buildInfo.Platform = ""
+		buildInfo.Executable = ""
+		resolution.BuildInfo = buildInfo
+
+		entries, err := os.ReadDir(workDir)
+		if err != nil {
+			return fmt.Errorf("reading work directory %w", err)
+		}
+
+		for _, entry := range entries {
+			name := entry.Name()
+			if !entry.Type().IsRegular() || name == lockFileName || name == workDirLockFileName {
+				continue
+			}
+
+			content, err := os.ReadFile(filepath.Join(workDir, name)) //nolint:gosec
+			if err != nil {
+				return fmt.Errorf("reading %s %w", name, err)
+			}
+			resolution.Files[name] = string(content)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resolution, nil
+}
@@ -0,0 +1,85 @@
+package k6foundry
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestResolve(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	for _, m := range []struct{ path, version, source string }{
+		{"go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")},
+		{"go.k6.io/k6ext", "v0.1.0", filepath.Join("testdata", "mods", "k6ext")},
+	} {
+		if err := proxy.AddModVersion(m.path, m.version, m.source); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	opts := NativeBuilderOpts{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		GoOpts: GoOpts{
+			CopyGoEnv: true,
+			Env: map[string]string{
+				"GOPROXY":   goproxySrv.URL,
+				"GONOPROXY": "none",
+				"GOPRIVATE": "go.k6.io",
+				"GONOSUMDB": "go.k6.io",
+			},
+			TmpCache: true,
+		},
+	}
+
+	b, err := NewNativeBuilder(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("setting up test %v", err)
+	}
+
+	r, ok := b.(Resolver)
+	if !ok {
+		t.Fatal("native builder doesn't support resolving")
+	}
+
+	resolution, err := r.Resolve(
+		context.Background(),
+		"v0.1.0",
+		[]Module{{Path: "go.k6.io/k6ext", Version: "v0.1.0"}},
+	)
+	if err != nil {
+		t.Fatalf("resolving %v", err)
+	}
+
+	expected := &BuildInfo{
+		ModVersions: map[string]string{
+			"go.k6.io/k6":    "v0.1.0",
+			"go.k6.io/k6ext": "v0.1.0",
+		},
+	}
+	if !reflect.DeepEqual(resolution.BuildInfo, expected) {
+		t.Fatalf("expected %v got %v", expected, resolution.BuildInfo)
+	}
+
+	for name, content := range map[string]string{
+		"main.go":           "k6cmd.Execute()",
+		"go.k6.io_k6ext.go": `import _ "go.k6.io/k6ext"`,
+		"go.mod":            "go.k6.io/k6ext v0.1.0",
+		"go.sum":            "go.k6.io/k6 v0.1.0",
+	} {
+		if !strings.Contains(resolution.Files[name], content) {
+			t.Fatalf("expected %s to contain %q got %q", name, content, resolution.Files[name])
+		}
+	}
+}
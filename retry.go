@@ -0,0 +1,69 @@
+package k6foundry
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRetryBackoff = time.Second
+	maxRetryBackoff     = 30 * time.Second
+)
+
+// transientErrors are fragments of the go output that indicate a network failure that may succeed if retried
+var transientErrors = []string{ //nolint:gochecknoglobals
+	"connection refused",
+	"connection reset by peer",
+	"i/o timeout",
+	"TLS handshake timeout",
+	"unexpected EOF",
+	"temporary failure in name resolution",
+	"429 Too Many Requests",
+	"500 Internal Server Error",
+	"502 Bad Gateway",
+	"503 Service Unavailable",
+	"504 Gateway Timeout",
+}
+
+// isTransient returns true if the output of a go command reports a transient network failure
+func isTransient(output string) bool {
+	for _, msg := range transientErrors {
+		if strings.Contains(output, msg) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// runGoWithRetries executes a go command that downloads modules, retrying it if it fails due to a
+// transient network failure. The delay between retries doubles on each retry.
+func (e goEnv) runGoWithRetries(ctx context.Context, timeout time.Duration, args ...string) error {
+	backoff := e.retryBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		// capture the output for detecting transient failures
+		output := &bytes.Buffer{}
+		env := e
+		env.stderr = io.MultiWriter(e.stderr, output)
+
+		err := env.runGo(ctx, timeout, args...)
+		if err == nil || attempt >= e.retries || ctx.Err() != nil || !isTransient(output.String()) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff = min(2*backoff, maxRetryBackoff)
+	}
+}
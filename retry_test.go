@@ -0,0 +1,123 @@
+package k6foundry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestIsTransient(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title  string
+		output string
+		expect bool
+	}{
+		{
+			title:  "proxy unavailable",
+			output: "go: go.k6.io/k6@v0.1.0: reading http://proxy/go.k6.io/k6/@v/v0.1.0.mod: 503 Service Unavailable",
+			expect: true,
+		},
+		{
+			title:  "connection reset",
+			output: "read tcp 127.0.0.1:50000->127.0.0.1:443: read: connection reset by peer",
+			expect: true,
+		},
+		{
+			title:  "unknown revision",
+			output: "go: go.k6.io/k6@v9.9.9: reading http://proxy/go.k6.io/k6/@v/v9.9.9.info: 404 Not Found",
+			expect: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			if got := isTransient(tc.output); got != tc.expect {
+				t.Fatalf("expected %v got %v", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestRetries(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	if err := proxy.AddModVersion("go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	testCases := []struct {
+		title       string
+		failures    int32
+		retries     int
+		expectError error
+	}{
+		{
+			title:    "recover from transient failures",
+			failures: 2,
+			retries:  2,
+		},
+		{
+			title:       "retries exhausted",
+			failures:    3,
+			retries:     2,
+			expectError: ErrResolvingDependency,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			// fail the first requests as if the proxy was temporarily unavailable
+			var requests atomic.Int32
+			goproxySrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if requests.Add(1) <= tc.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				proxy.ServeHTTP(w, r)
+			}))
+			t.Cleanup(goproxySrv.Close)
+
+			opts := NativeBuilderOpts{
+				GoOpts: GoOpts{
+					CopyGoEnv: true,
+					Env: map[string]string{
+						"GOPROXY":   goproxySrv.URL,
+						"GONOPROXY": "none",
+						"GOPRIVATE": "go.k6.io",
+						"GONOSUMDB": "go.k6.io",
+					},
+					TmpCache:        true,
+					GetRetries:      tc.retries,
+					GetRetryBackoff: 10 * time.Millisecond,
+				},
+			}
+
+			b, err := NewNativeBuilder(context.Background(), opts)
+			if err != nil {
+				t.Fatalf("setting up test %v", err)
+			}
+
+			_, err = b.Build(context.Background(), RuntimePlatform(), "v0.1.0", []Module{}, []string{}, &bytes.Buffer{})
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"debug/buildinfo"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// APIVersion is the version of the JSON encoding of BuildRequest and BuildResult. Adding optional fields
+// keeps the version, removing fields or changing their meaning requires a new one
+const APIVersion = "k6foundry/v1"
+
+// ErrUnsupportedAPIVersion is returned when decoding a request or result with an unknown API version
+var ErrUnsupportedAPIVersion = errors.New("unsupported api version")
+
+// BuildRequest describes a build, as sent to the build service. It is the wire format shared by the
+// integrations requesting builds, so it only has the parameters that don't depend on the builder's environment
+type BuildRequest struct {
+	// version of the encoding. Defaults to APIVersion when marshalling, and it is optional when unmarshalling
+	APIVersion string `json:"apiVersion,omitempty"`
+	// target platform in the format os/arch[/variant]. Defaults to the platform of the builder
+	Platform string `json:"platform,omitempty"`
+	// k6 version, or a specification resolved by a VersionResolver (e.g. latest or ~v0.50.0). Defaults to latest
+	K6Version string `json:"k6Version,omitempty"`
+	// dependencies in the format accepted by ParseModule. Versions can be constraints
+	Dependencies []Module `json:"dependencies,omitempty"`
+	// flags passed to go build
+	BuildOpts []string `json:"buildOpts,omitempty"`
+}
+
+// MarshalJSON marshals the request setting the current API version if none is set
+func (r BuildRequest) MarshalJSON() ([]byte, error) {
+	type plain BuildRequest
+
+	if r.APIVersion == "" {
+		r.APIVersion = APIVersion
+	}
+
+	return json.Marshal(plain(r))
+}
+
+// UnmarshalJSON unmarshals the request, failing with ErrUnsupportedAPIVersion if the version is not known
+func (r *BuildRequest) UnmarshalJSON(data []byte) error {
+	type plain BuildRequest
+
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+
+	return checkAPIVersion(r.APIVersion)
+}
+
+// BuildResult describes a binary produced by a build, as reported by the CLI and the build service.
+// It includes the fields of the BuildInfo, so it can be decoded also as a BuildInfo
+type BuildResult struct {
+	// version of the encoding. Defaults to APIVersion when marshalling, and it is optional when unmarshalling
+	APIVersion string `json:"apiVersion,omitempty"`
+	BuildInfo
+	// path of the binary, if written to a file
+	Binary string `json:"binary,omitempty"`
+	// version of go used for compiling the binary
+	GoVersion string `json:"goVersion,omitempty"`
+	// duration of the build, if known
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+}
+
+// NewBuildResult returns the result for the binary at the path built with the build info. The checksum and
+// the size of the binary are taken from the file if not reported in the build info
+func NewBuildResult(binary string, info *BuildInfo) (BuildResult, error) {
+	result := BuildResult{APIVersion: APIVersion, Binary: binary}
+	if info != nil {
+		result.BuildInfo = *info
+	}
+
+	if result.Checksum == "" {
+		digest, err := fileSHA256(binary)
+		if err != nil {
+			return BuildResult{}, err
+		}
+		result.Checksum = "sha256:" + digest
+	}
+
+	if result.Size == 0 {
+		stat, err := os.Stat(binary)
+		if err != nil {
+			return BuildResult{}, err
+		}
+		result.Size = stat.Size()
+	}
+
+	// the output is not a binary when vendoring
+	if binaryInfo, err := buildinfo.ReadFile(binary); err == nil {
+		result.GoVersion = binaryInfo.GoVersion
+	}
+
+	return result, nil
+}
+
+// MarshalJSON marshals the result setting the current API version if none is set
+func (r BuildResult) MarshalJSON() ([]byte, error) {
+	type plain BuildResult
+
+	if r.APIVersion == "" {
+		r.APIVersion = APIVersion
+	}
+
+	return json.Marshal(plain(r))
+}
+
+// UnmarshalJSON unmarshals the result, failing with ErrUnsupportedAPIVersion if the version is not known
+func (r *BuildResult) UnmarshalJSON(data []byte) error {
+	type plain BuildResult
+
+	if err := json.Unmarshal(data, (*plain)(r)); err != nil {
+		return err
+	}
+
+	return checkAPIVersion(r.APIVersion)
+}
+
+// checkAPIVersion checks the API version is known. Encodings without version predate the versioning and
+// are compatible with the first version
+func checkAPIVersion(version string) error {
+	if version != "" && version != APIVersion {
+		return fmt.Errorf("%w: %q", ErrUnsupportedAPIVersion, version)
+	}
+
+	return nil
+}
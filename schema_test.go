@@ -0,0 +1,137 @@
+package k6foundry
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBuildRequestJSON(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		json        string
+		expectError error
+		expect      BuildRequest
+	}{
+		{
+			title: "current version",
+			json: `{"apiVersion": "k6foundry/v1", "platform": "linux/amd64", "k6Version": "~v0.50.0",` +
+				` "dependencies": ["go.k6.io/k6ext@>=v0.1.0 <v0.2.0"], "buildOpts": ["-trimpath"]}`,
+			expect: BuildRequest{
+				APIVersion:   APIVersion,
+				Platform:     "linux/amd64",
+				K6Version:    "~v0.50.0",
+				Dependencies: []Module{{Path: "go.k6.io/k6ext", Version: ">=v0.1.0 <v0.2.0"}},
+				BuildOpts:    []string{"-trimpath"},
+			},
+		},
+		{
+			title:  "without version",
+			json:   `{"k6Version": "v0.50.0", "dependencies": ["go.k6.io/k6ext"]}`,
+			expect: BuildRequest{K6Version: "v0.50.0", Dependencies: []Module{{Path: "go.k6.io/k6ext", Version: "latest"}}},
+		},
+		{
+			title:       "unsupported version",
+			json:        `{"apiVersion": "k6foundry/v2"}`,
+			expectError: ErrUnsupportedAPIVersion,
+		},
+		{
+			title:       "invalid dependency",
+			json:        `{"dependencies": ["go.k6.io/k6ext@"]}`,
+			expectError: ErrInvalidDependencyFormat,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			req := BuildRequest{}
+			err := json.Unmarshal([]byte(tc.json), &req)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			if tc.expectError != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(req, tc.expect) {
+				t.Fatalf("expected %v got %v", tc.expect, req)
+			}
+
+			// the encoding always has the version
+			data, err := json.Marshal(req)
+			if err != nil {
+				t.Fatalf("marshalling %v", err)
+			}
+
+			decoded := BuildRequest{}
+			if err = json.Unmarshal(data, &decoded); err != nil {
+				t.Fatalf("unmarshalling %v", err)
+			}
+
+			expect := tc.expect
+			expect.APIVersion = APIVersion
+			if !reflect.DeepEqual(decoded, expect) {
+				t.Fatalf("expected %v got %v", expect, decoded)
+			}
+		})
+	}
+}
+
+func TestBuildResult(t *testing.T) {
+	t.Parallel()
+
+	binary := filepath.Join(t.TempDir(), "k6")
+	if err := os.WriteFile(binary, []byte("binary"), 0o600); err != nil { //nolint:forbidigo
+		t.Fatalf("setup %v", err)
+	}
+
+	info := &BuildInfo{Platform: "linux/amd64", ModVersions: map[string]string{"go.k6.io/k6": "v0.1.0"}}
+
+	result, err := NewBuildResult(binary, info)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// sha256 of "binary"
+	checksum := "sha256:9a3a45d01531a20e89ac6ae10b0b0beb0492acd7216a368aa062d1a5fecaf9cd"
+	if result.APIVersion != APIVersion || result.Checksum != checksum || result.Size != 6 || result.Binary != binary {
+		t.Fatalf("unexpected result %#v", result)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("marshalling %v", err)
+	}
+
+	// the result can be decoded as a build info
+	decodedInfo := BuildInfo{}
+	if err = json.Unmarshal(data, &decodedInfo); err != nil {
+		t.Fatalf("unmarshalling build info %v", err)
+	}
+
+	if !reflect.DeepEqual(decodedInfo, result.BuildInfo) {
+		t.Fatalf("expected %v got %v", result.BuildInfo, decodedInfo)
+	}
+
+	decoded := BuildResult{}
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshalling result %v", err)
+	}
+
+	if !reflect.DeepEqual(decoded, result) {
+		t.Fatalf("expected %v got %v", result, decoded)
+	}
+
+	if err = json.Unmarshal([]byte(`{"apiVersion": "v2"}`), &decoded); !errors.Is(err, ErrUnsupportedAPIVersion) {
+		t.Fatalf("expected %v got %v", ErrUnsupportedAPIVersion, err)
+	}
+}
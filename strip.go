@@ -0,0 +1,45 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// linker flags that omit the symbol table and the DWARF debug information
+const stripLdFlags = "-s -w"
+
+var (
+	// UPX is not installed
+	ErrNoUPX = errors.New("upx notfound")
+	// Error compressing the binary
+	ErrCompressing = errors.New("compressing binary")
+)
+
+// checkPostProcessing checks the tools required for post-processing the binaries are installed
+func checkPostProcessing(opts NativeBuilderOpts) error {
+	if !opts.CompressWithUPX {
+		return nil
+	}
+
+	if _, err := exec.LookPath("upx"); err != nil {
+		return ErrNoUPX
+	}
+
+	return nil
+}
+
+// compress compresses the binary in place using UPX
+func (b *nativeBuilder) compress(ctx context.Context, binary string) error {
+	cmd := exec.CommandContext(ctx, "upx", "--best", "-q", binary) //nolint:gosec
+	cmd.Stdout = b.Stdout
+	cmd.Stderr = b.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%w: %w", ErrCompressing, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package k6foundry
+
+import (
+	"bytes"
+	"context"
+	"debug/buildinfo"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestPostProcessing(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	if err := proxy.AddModVersion("go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	testCases := []struct {
+		title         string
+		strip         bool
+		compress      bool
+		metadata      map[string]string
+		expectLdFlags string
+	}{
+		{
+			title:         "strip debug info",
+			strip:         true,
+			expectLdFlags: "-s -w",
+		},
+		{
+			title:    "compress with upx",
+			compress: true,
+		},
+		{
+			title:         "build metadata",
+			strip:         true,
+			metadata:      map[string]string{K6VersionDetails: "with xk6-foo v0.1.0"},
+			expectLdFlags: "-s -w -X 'go.k6.io/k6/lib/consts.VersionDetails=with xk6-foo v0.1.0'",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			opts := NativeBuilderOpts{
+				GoOpts: GoOpts{
+					CopyGoEnv: true,
+					Env: map[string]string{
+						"GOPROXY":   goproxySrv.URL,
+						"GONOPROXY": "none",
+						"GOPRIVATE": "go.k6.io",
+						"GONOSUMDB": "go.k6.io",
+					},
+					TmpCache: true,
+				},
+				StripDebugInfo:  tc.strip,
+				CompressWithUPX: tc.compress,
+				BuildMetadata:   tc.metadata,
+			}
+
+			b, err := NewNativeBuilder(context.Background(), opts)
+			if tc.compress && err != nil {
+				t.Skipf("upx not available: %v", err)
+			}
+			if err != nil {
+				t.Fatalf("setting up test %v", err)
+			}
+
+			outFile := &bytes.Buffer{}
+			_, err = b.Build(context.Background(), RuntimePlatform(), "v0.1.0", []Module{}, []string{}, outFile)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			binary := filepath.Join(t.TempDir(), "k6")
+			if err = os.WriteFile(binary, outFile.Bytes(), 0o700); err != nil {
+				t.Fatalf("writing binary %v", err)
+			}
+
+			info, err := buildinfo.ReadFile(binary)
+
+			// the build info of a compressed binary is not readable
+			if tc.compress {
+				if err == nil {
+					t.Fatalf("expected compressed binary")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("reading binary build info %v", err)
+			}
+
+			ldflags := ""
+			for _, setting := range info.Settings {
+				if setting.Key == "-ldflags" {
+					ldflags = setting.Value
+				}
+			}
+
+			if ldflags != tc.expectLdFlags {
+				t.Fatalf("expected ldflags %q got %q", tc.expectLdFlags, ldflags)
+			}
+		})
+	}
+}
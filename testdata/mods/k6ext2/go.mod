@@ -0,0 +1,5 @@
+module go.k6.io/k6ext2
+
+go 1.17
+
+require go.k6.io/k6 v0.2.0
@@ -0,0 +1 @@
+package k6ext2
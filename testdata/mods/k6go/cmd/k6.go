@@ -0,0 +1,4 @@
+package cmd
+
+func Execute() {
+}
@@ -0,0 +1,89 @@
+package k6foundry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestTimeouts(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	if err := proxy.AddModVersion("go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	testCases := []struct {
+		title        string
+		getTimeout   time.Duration
+		buildTimeout time.Duration
+		expectError  []error
+	}{
+		{
+			title:        "default timeouts",
+			getTimeout:   0,
+			buildTimeout: 0,
+		},
+		{
+			title:        "timeouts disabled",
+			getTimeout:   -1,
+			buildTimeout: -1,
+		},
+		{
+			title:       "get timeout exceeded",
+			getTimeout:  time.Nanosecond,
+			expectError: []error{ErrBuildTimeout, ErrResolvingDependency, context.DeadlineExceeded},
+		},
+		{
+			title:        "build timeout exceeded",
+			buildTimeout: time.Nanosecond,
+			expectError:  []error{ErrBuildTimeout, ErrCompiling, context.DeadlineExceeded},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			b, err := NewNativeBuilder(context.Background(), NativeBuilderOpts{
+				GoOpts: GoOpts{
+					CopyGoEnv: true,
+					Env: map[string]string{
+						"GOPROXY":   goproxySrv.URL,
+						"GONOPROXY": "none",
+						"GOPRIVATE": "go.k6.io",
+						"GONOSUMDB": "go.k6.io",
+					},
+					TmpCache:       true,
+					GoGetTimeout:   tc.getTimeout,
+					GOBuildTimeout: tc.buildTimeout,
+				},
+			})
+			if err != nil {
+				t.Fatalf("setting up test %v", err)
+			}
+
+			_, err = b.Build(context.Background(), RuntimePlatform(), "v0.1.0", []Module{}, []string{}, &bytes.Buffer{})
+			if len(tc.expectError) == 0 && err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			for _, expected := range tc.expectError {
+				if !errors.Is(err, expected) {
+					t.Fatalf("expected %v got %v", expected, err)
+				}
+			}
+		})
+	}
+}
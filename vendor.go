@@ -0,0 +1,297 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// file in the vendor archive with the build info of the vendored modules
+	vendorBuildInfoFile = "k6foundry.json"
+)
+
+// ErrInvalidVendorArchive is returned when the vendor archive can't be extracted
+var ErrInvalidVendorArchive = errors.New("invalid vendor archive")
+
+// VendorBuilder is implemented by builders that can export the build environment with the
+// vendored dependencies and later build from it without accessing the network.
+type VendorBuilder interface {
+	// Vendor resolves k6 and the dependencies and writes the build environment (main module, go.mod,
+	// go.sum and vendor directory) as a tar.gz archive into the out io.Writer
+	Vendor(ctx context.Context, k6Version string, mods []Module, out io.Writer) (*BuildInfo, error)
+	// BuildFromVendor builds a k6 binary from an archive created by Vendor
+	BuildFromVendor(
+		ctx context.Context,
+		platform Platform,
+		vendor io.Reader,
+		buildOpts []string,
+		out io.Writer,
+	) (*BuildInfo, error)
+}
+
+// Vendor resolves k6 and the dependencies and writes the build environment into the out io.Writer
+func (b *nativeBuilder) Vendor(ctx context.Context, k6Version string, exts []Module, out io.Writer) (*BuildInfo, error) {
+	return b.vendorWith(ctx, b.hostEnv, k6Version, exts, out)
+}
+
+// BuildFromVendor builds a k6 binary from an archive created by Vendor
+func (b *nativeBuilder) BuildFromVendor(
+	ctx context.Context,
+	platform Platform,
+	vendor io.Reader,
+	buildOpts []string,
+	out io.Writer,
+) (*BuildInfo, error) {
+	return b.buildFromVendorWith(ctx, b.hostEnv, platform, vendor, buildOpts, out)
+}
+
+func (b *nativeBuilder) vendorWith(
+	ctx context.Context,
+	newEnv envFactory,
+	k6Version string,
+	exts []Module,
+	out io.Writer,
+) (*BuildInfo, error) {
+	// the workspace modules are local to this host
+	if len(b.Workspace) > 0 {
+		return nil, ErrWorkspaceVendor
+	}
+
+	if err := checkConflicts(exts); err != nil {
+		return nil, err
+	}
+
+	var buildInfo *BuildInfo
+
+	// vendoring is platform independent
+	err := b.withWorkDir(ctx, newEnv, RuntimePlatform(), b.GoOpts, func(workDir string, buildEnv *goEnv) error {
+		b.log.Info("Vendoring k6 build environment")
+
+		err := b.resetWorkDir(workDir)
+		if err != nil {
+			return err
+		}
+
+		buildInfo, err = b.prepare(ctx, workDir, buildEnv, k6Version, exts)
+		if err != nil {
+			return err
+		}
+		// the platform is set when building
+		buildInfo.Platform = ""
+		buildInfo.Executable = ""
+
+		unlock, err := buildEnv.lockCache(ctx, false)
+		if err != nil {
+			return err
+		}
+
+		err = buildEnv.modVendor(ctx)
+		unlock()
+		if err != nil {
+			return err
+		}
+
+		content, err := json.Marshal(buildInfo)
+		if err != nil {
+			return fmt.Errorf("marshalling build info %w", err)
+		}
+
+		err = os.WriteFile(filepath.Join(workDir, vendorBuildInfoFile), content, 0o600)
+		if err != nil {
+			return fmt.Errorf("writing build info %w", err)
+		}
+
+		return tarDir(workDir, out)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return buildInfo, nil
+}
+
+func (b *nativeBuilder) buildFromVendorWith(
+	ctx context.Context,
+	newEnv envFactory,
+	platform Platform,
+	vendor io.Reader,
+	buildOpts []string,
+	out io.Writer,
+) (*BuildInfo, error) {
+	// ensure the build doesn't access the network
+	opts := b.GoOpts
+	opts.Env = maps.Clone(opts.Env)
+	if opts.Env == nil {
+		opts.Env = map[string]string{}
+	}
+	opts.Env["GOPROXY"] = "off"
+	opts.Env["GOFLAGS"] = "-mod=vendor"
+	// the vendored modules are used instead of the module cache
+	opts.Offline = false
+
+	buildInfo := &BuildInfo{}
+
+	err := b.withWorkDir(ctx, newEnv, platform, opts, func(workDir string, buildEnv *goEnv) error {
+		b.log.Info("Building k6 from vendored environment")
+
+		if err := buildEnv.checkPlatforms(ctx, platform); err != nil {
+			return err
+		}
+
+		if err := b.resetWorkDir(workDir); err != nil {
+			return err
+		}
+
+		if err := untar(vendor, workDir); err != nil {
+			return err
+		}
+
+		content, err := os.ReadFile(filepath.Join(workDir, vendorBuildInfoFile)) //nolint:gosec
+		if err != nil {
+			return fmt.Errorf("%w: reading build info %w", ErrInvalidVendorArchive, err)
+		}
+
+		if err = json.Unmarshal(content, buildInfo); err != nil {
+			return fmt.Errorf("%w: parsing build info %w", ErrInvalidVendorArchive, err)
+		}
+		buildInfo.Platform = platform.String()
+		buildInfo.Executable = platform.BinaryName("k6")
+
+		buildInfo.Checksum, buildInfo.Size, err = b.compile(ctx, workDir, buildEnv, buildOpts, binaryOutput{writer: out})
+		buildInfo.DiskUsage = buildEnv.diskUsage(workDir)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return buildInfo, nil
+}
+
+// tarDir writes the content of a directory as a tar.gz archive, excluding the lock file and the
+// home directory, which can contain credentials
+func tarDir(dir string, out io.Writer) error {
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		name, _ := filepath.Rel(dir, path)
+		if name == "." || name == lockFileName || name == workDirLockFileName {
+			return nil
+		}
+
+		if name == homeDirName {
+			return filepath.SkipDir
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(name)
+
+		if err = tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(path) //nolint:gosec
+		if err != nil {
+			return err
+		}
+		defer file.Close() //nolint:errcheck
+
+		_, err = io.Copy(tw, file)
+
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("archiving build environment %w", err)
+	}
+
+	if err = tw.Close(); err != nil {
+		return fmt.Errorf("archiving build environment %w", err)
+	}
+
+	return gz.Close()
+}
+
+// untar extracts a tar.gz archive into a directory.
+// Only regular files and directories are extracted.
+func untar(in io.Reader, dir string) error {
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidVendorArchive, err)
+	}
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidVendorArchive, err)
+		}
+
+		name := filepath.FromSlash(header.Name)
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("%w: invalid path %q", ErrInvalidVendorArchive, header.Name)
+		}
+		path := filepath.Join(dir, name)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = os.MkdirAll(path, 0o750)
+		case tar.TypeReg:
+			err = extractFile(tr, path, header.FileInfo().Mode().Perm())
+		default:
+			err = fmt.Errorf("%w: unsupported entry %q", ErrInvalidVendorArchive, header.Name)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func extractFile(r io.Reader, path string, mode fs.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode|0o600) //nolint:gosec
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(file, r) //nolint:gosec
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
@@ -0,0 +1,131 @@
+package k6foundry
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestVendor(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	for _, m := range []struct{ path, version, source string }{
+		{"go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")},
+		{"go.k6.io/k6ext", "v0.1.0", filepath.Join("testdata", "mods", "k6ext")},
+	} {
+		if err := proxy.AddModVersion(m.path, m.version, m.source); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+
+	opts := NativeBuilderOpts{
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+		GoOpts: GoOpts{
+			CopyGoEnv: true,
+			Env: map[string]string{
+				"GOPROXY":   goproxySrv.URL,
+				"GONOPROXY": "none",
+				"GOPRIVATE": "go.k6.io",
+				"GONOSUMDB": "go.k6.io",
+			},
+			TmpCache: true,
+			Hermetic: true,
+		},
+	}
+
+	b, err := NewNativeBuilder(context.Background(), opts)
+	if err != nil {
+		t.Fatalf("setting up test %v", err)
+	}
+
+	vb, ok := b.(VendorBuilder)
+	if !ok {
+		t.Fatal("native builder doesn't support vendoring")
+	}
+
+	archive := &bytes.Buffer{}
+	_, err = vb.Vendor(
+		context.Background(),
+		"v0.1.0",
+		[]Module{{Path: "go.k6.io/k6ext", Version: "v0.1.0"}},
+		archive,
+	)
+	if err != nil {
+		t.Fatalf("vendoring %v", err)
+	}
+
+	// the home directory of the hermetic build is not archived
+	gz, err := gzip.NewReader(bytes.NewReader(archive.Bytes()))
+	if err != nil {
+		t.Fatalf("reading archive %v", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading archive %v", err)
+		}
+		if strings.HasPrefix(header.Name, homeDirName) {
+			t.Fatalf("home directory archived %s", header.Name)
+		}
+	}
+
+	// the build from the vendored environment must not access the proxy
+	goproxySrv.Close()
+
+	platform, _ := ParsePlatform("linux/arm64")
+	outFile := &bytes.Buffer{}
+	buildInfo, err := vb.BuildFromVendor(context.Background(), platform, archive, []string{}, outFile)
+	if err != nil {
+		t.Fatalf("building from vendor %v", err)
+	}
+
+	if outFile.Len() == 0 {
+		t.Fatal("out file is empty")
+	}
+
+	if buildInfo.Size != int64(outFile.Len()) {
+		t.Fatalf("expected size %d got %d", outFile.Len(), buildInfo.Size)
+	}
+	buildInfo.Size = 0
+
+	if buildInfo.DiskUsage <= 0 {
+		t.Fatalf("expected disk usage got %d", buildInfo.DiskUsage)
+	}
+	buildInfo.DiskUsage = 0
+
+	expect := &BuildInfo{
+		Platform: "linux/arm64",
+		ModVersions: map[string]string{
+			"go.k6.io/k6":    "v0.1.0",
+			"go.k6.io/k6ext": "v0.1.0",
+		},
+		Executable: "k6",
+	}
+	if !reflect.DeepEqual(buildInfo, expect) {
+		t.Fatalf("expected %v got %v", expect, buildInfo)
+	}
+
+	_, err = vb.BuildFromVendor(context.Background(), platform, bytes.NewBufferString("invalid"), []string{}, outFile)
+	if !errors.Is(err, ErrInvalidVendorArchive) {
+		t.Fatalf("expected %v got %v", ErrInvalidVendorArchive, err)
+	}
+}
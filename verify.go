@@ -0,0 +1,112 @@
+package k6foundry
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+var (
+	// Invalid GoFlags, VCS, SumDB or NoSumDB option
+	ErrInvalidVerificationOpts = errors.New("invalid module verification options")
+	// RequireSumDB is set but the build environment disables the checksum database
+	ErrSumDBDisabled = errors.New("checksum database verification disabled")
+
+	// version control systems accepted in GOVCS
+	govcsSchemes = []string{"git", "hg", "svn", "bzr", "fossil", "all", "off"}
+)
+
+// validateVerification checks the syntax of the module verification options
+func (o GoOpts) validateVerification() error {
+	for _, flag := range o.GoFlags {
+		if !strings.HasPrefix(flag, "-") || strings.ContainsAny(flag, " \t\n") {
+			return fmt.Errorf("%w: go flag %q must start with '-' and have no spaces", ErrInvalidVerificationOpts, flag)
+		}
+	}
+
+	if o.VCS != "" {
+		if err := validateGOVCS(o.VCS); err != nil {
+			return err
+		}
+	}
+
+	if o.SumDB != "" && o.SumDB != "off" && len(strings.Fields(o.SumDB)) > 2 {
+		return fmt.Errorf("%w: sumdb must be 'off' or 'name[+key] [url]': %q", ErrInvalidVerificationOpts, o.SumDB)
+	}
+
+	for _, pattern := range o.NoSumDB {
+		if _, err := path.Match(pattern, ""); err != nil || strings.Contains(pattern, ",") {
+			return fmt.Errorf("%w: nosumdb pattern %q", ErrInvalidVerificationOpts, pattern)
+		}
+	}
+
+	if o.RequireSumDB && (o.SumDB == "off" || len(o.NoSumDB) > 0) {
+		return fmt.Errorf("%w: sumdb can't be disabled when it is required", ErrInvalidVerificationOpts)
+	}
+
+	return nil
+}
+
+// validateGOVCS checks a GOVCS value: a comma-separated list of pattern:vcs|vcs rules
+func validateGOVCS(govcs string) error {
+	for _, rule := range strings.Split(govcs, ",") {
+		pattern, schemes, found := strings.Cut(strings.TrimSpace(rule), ":")
+		if !found || pattern == "" || schemes == "" {
+			return fmt.Errorf("%w: govcs rule %q must be pattern:vcs", ErrInvalidVerificationOpts, rule)
+		}
+
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%w: govcs pattern %q", ErrInvalidVerificationOpts, pattern)
+		}
+
+		for _, scheme := range strings.Split(schemes, "|") {
+			if !slices.Contains(govcsSchemes, scheme) {
+				return fmt.Errorf("%w: unknown vcs %q in govcs rule %q", ErrInvalidVerificationOpts, scheme, rule)
+			}
+		}
+	}
+
+	return nil
+}
+
+// setVerificationEnv sets the module verification options in the build environment
+func setVerificationEnv(env map[string]string, opts GoOpts) {
+	if len(opts.GoFlags) > 0 {
+		env["GOFLAGS"] = strings.Join(opts.GoFlags, " ")
+	}
+
+	if opts.VCS != "" {
+		env["GOVCS"] = opts.VCS
+	}
+
+	if opts.SumDB != "" {
+		env["GOSUMDB"] = opts.SumDB
+	}
+
+	if len(opts.NoSumDB) > 0 {
+		env["GONOSUMDB"] = strings.Join(opts.NoSumDB, ",")
+	}
+}
+
+// checkSumDBRequired fails if RequireSumDB is set and the build environment skips the checksum
+// database for any module
+func checkSumDBRequired(env map[string]string, opts GoOpts) error {
+	if !opts.RequireSumDB {
+		return nil
+	}
+
+	if env["GOSUMDB"] == "off" {
+		return fmt.Errorf("%w: GOSUMDB=off", ErrSumDBDisabled)
+	}
+
+	// GONOSUMDB defaults to GOPRIVATE
+	for _, name := range []string{"GONOSUMDB", "GOPRIVATE"} {
+		if env[name] != "" {
+			return fmt.Errorf("%w: %s=%s", ErrSumDBDisabled, name, env[name])
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,177 @@
+package k6foundry
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidateVerification(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		opts        GoOpts
+		expectError error
+	}{
+		{
+			title: "no options",
+		},
+		{
+			title: "valid options",
+			opts: GoOpts{
+				GoFlags: []string{"-mod=mod", "-trimpath"},
+				VCS:     "github.com:git,private:git|hg,*:off",
+				SumDB:   "sum.golang.org https://sum.golang.org",
+				NoSumDB: []string{"go.k6.io/*"},
+			},
+		},
+		{
+			title: "sumdb off",
+			opts:  GoOpts{SumDB: "off"},
+		},
+		{
+			title:       "go flag without dash",
+			opts:        GoOpts{GoFlags: []string{"mod=mod"}},
+			expectError: ErrInvalidVerificationOpts,
+		},
+		{
+			title:       "go flag with spaces",
+			opts:        GoOpts{GoFlags: []string{"-ldflags=-s -w"}},
+			expectError: ErrInvalidVerificationOpts,
+		},
+		{
+			title:       "govcs rule without vcs",
+			opts:        GoOpts{VCS: "github.com"},
+			expectError: ErrInvalidVerificationOpts,
+		},
+		{
+			title:       "govcs unknown vcs",
+			opts:        GoOpts{VCS: "*:git|cvs"},
+			expectError: ErrInvalidVerificationOpts,
+		},
+		{
+			title:       "govcs invalid pattern",
+			opts:        GoOpts{VCS: "[:git"},
+			expectError: ErrInvalidVerificationOpts,
+		},
+		{
+			title:       "invalid sumdb",
+			opts:        GoOpts{SumDB: "sum.golang.org https://sum.golang.org extra"},
+			expectError: ErrInvalidVerificationOpts,
+		},
+		{
+			title:       "invalid nosumdb pattern",
+			opts:        GoOpts{NoSumDB: []string{"a,b"}},
+			expectError: ErrInvalidVerificationOpts,
+		},
+		{
+			title:       "required sumdb disabled",
+			opts:        GoOpts{RequireSumDB: true, SumDB: "off"},
+			expectError: ErrInvalidVerificationOpts,
+		},
+		{
+			title:       "required sumdb with exclusions",
+			opts:        GoOpts{RequireSumDB: true, NoSumDB: []string{"go.k6.io"}},
+			expectError: ErrInvalidVerificationOpts,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.opts.validateVerification()
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+		})
+	}
+}
+
+func TestSetVerificationEnv(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		env         map[string]string
+		opts        GoOpts
+		expect      map[string]string
+		expectError error
+	}{
+		{
+			title:  "no options",
+			env:    map[string]string{"GOSUMDB": "off"},
+			expect: map[string]string{"GOSUMDB": "off"},
+		},
+		{
+			title: "options override environment",
+			env:   map[string]string{"GOFLAGS": "-mod=vendor", "GOSUMDB": "off"},
+			opts: GoOpts{
+				GoFlags: []string{"-mod=mod", "-trimpath"},
+				VCS:     "*:git",
+				SumDB:   "sum.golang.org",
+				NoSumDB: []string{"go.k6.io", "example.com/*"},
+			},
+			expect: map[string]string{
+				"GOFLAGS":   "-mod=mod -trimpath",
+				"GOVCS":     "*:git",
+				"GOSUMDB":   "sum.golang.org",
+				"GONOSUMDB": "go.k6.io,example.com/*",
+			},
+		},
+		{
+			title:  "required sumdb",
+			env:    map[string]string{"GOPROXY": "direct"},
+			opts:   GoOpts{RequireSumDB: true},
+			expect: map[string]string{"GOPROXY": "direct"},
+		},
+		{
+			title:  "required sumdb overrides environment",
+			env:    map[string]string{"GOSUMDB": "off"},
+			opts:   GoOpts{RequireSumDB: true, SumDB: "sum.golang.org"},
+			expect: map[string]string{"GOSUMDB": "sum.golang.org"},
+		},
+		{
+			title:       "required sumdb disabled in environment",
+			env:         map[string]string{"GOSUMDB": "off"},
+			opts:        GoOpts{RequireSumDB: true},
+			expectError: ErrSumDBDisabled,
+		},
+		{
+			title:       "required sumdb with exclusions in environment",
+			env:         map[string]string{"GONOSUMDB": "go.k6.io"},
+			opts:        GoOpts{RequireSumDB: true},
+			expectError: ErrSumDBDisabled,
+		},
+		{
+			title:       "required sumdb with private modules",
+			env:         map[string]string{"GOPRIVATE": "go.k6.io"},
+			opts:        GoOpts{RequireSumDB: true},
+			expectError: ErrSumDBDisabled,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			setVerificationEnv(tc.env, tc.opts)
+
+			err := checkSumDBRequired(tc.env, tc.opts)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+
+			if tc.expectError != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(tc.env, tc.expect) {
+				t.Fatalf("expected %v got %v", tc.expect, tc.env)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	gosemver "golang.org/x/mod/semver"
+)
+
+// DefaultResolveCacheTTL is the default time the resolved k6 versions are reused
+const DefaultResolveCacheTTL = 5 * time.Minute
+
+// VersionResolverOpts defines the options of a VersionResolver
+type VersionResolverOpts struct {
+	// options for querying the module proxy
+	VersionsOpts
+	// time the resolved versions are reused. Defaults to DefaultResolveCacheTTL.
+	// A negative value disables the cache
+	TTL time.Duration
+	// file the resolved versions are stored in, for sharing them between processes (e.g. consecutive
+	// builds from the command line). Optional
+	CacheFile string
+}
+
+// resolvedVersion is a cached resolution of a version specification
+type resolvedVersion struct {
+	Version    string    `json:"version"`
+	ResolvedAt time.Time `json:"resolvedAt"`
+}
+
+// VersionResolver resolves k6 version specifications (see ResolveK6Version), caching the resolved
+// versions, so frequent builds don't query the module proxy each time and get a consistent version
+// of latest within the TTL. It is safe for concurrent use.
+type VersionResolver struct {
+	opts     VersionResolverOpts
+	mutex    sync.Mutex
+	resolved map[string]resolvedVersion
+	now      func() time.Time
+}
+
+// NewVersionResolver returns a VersionResolver
+func NewVersionResolver(opts VersionResolverOpts) *VersionResolver {
+	if opts.TTL == 0 {
+		opts.TTL = DefaultResolveCacheTTL
+	}
+
+	return &VersionResolver{
+		opts:     opts,
+		resolved: map[string]resolvedVersion{},
+		now:      time.Now,
+	}
+}
+
+// Resolve resolves a k6 version specification, including latest, to a version.
+// Versions are returned as is.
+func (r *VersionResolver) Resolve(ctx context.Context, spec string) (string, error) {
+	if spec == "" {
+		spec = "latest"
+	}
+
+	if gosemver.IsValid(spec) && gosemver.Canonical(spec) == spec {
+		return spec, nil
+	}
+
+	if r.opts.TTL < 0 {
+		return ResolveK6Version(ctx, spec, r.opts.VersionsOpts)
+	}
+
+	// the same specification can resolve to different versions in different proxies
+	key := goProxyURL(r.opts.GoProxy) + " " + spec
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.opts.CacheFile != "" {
+		r.load()
+	}
+
+	if resolved, found := r.resolved[key]; found && r.now().Sub(resolved.ResolvedAt) < r.opts.TTL {
+		return resolved.Version, nil
+	}
+
+	version, err := ResolveK6Version(ctx, spec, r.opts.VersionsOpts)
+	if err != nil {
+		return "", err
+	}
+
+	r.resolved[key] = resolvedVersion{Version: version, ResolvedAt: r.now()}
+
+	if r.opts.CacheFile != "" {
+		r.store()
+	}
+
+	return version, nil
+}
+
+// load adds the unexpired versions in the cache file. The cache file is only an optimization,
+// so errors reading it are ignored
+func (r *VersionResolver) load() {
+	content, err := os.ReadFile(r.opts.CacheFile)
+	if err != nil {
+		return
+	}
+
+	stored := map[string]resolvedVersion{}
+	if err = json.Unmarshal(content, &stored); err != nil {
+		return
+	}
+
+	for key, resolved := range stored {
+		if r.now().Sub(resolved.ResolvedAt) >= r.opts.TTL {
+			continue
+		}
+
+		if current, found := r.resolved[key]; !found || resolved.ResolvedAt.After(current.ResolvedAt) {
+			r.resolved[key] = resolved
+		}
+	}
+}
+
+// store writes the unexpired versions to the cache file, replacing it atomically so concurrent
+// processes don't read a partial file. Errors are ignored, as in load
+func (r *VersionResolver) store() {
+	stored := map[string]resolvedVersion{}
+	for key, resolved := range r.resolved {
+		if r.now().Sub(resolved.ResolvedAt) < r.opts.TTL {
+			stored[key] = resolved
+		}
+	}
+
+	content, err := json.Marshal(stored)
+	if err != nil {
+		return
+	}
+
+	dir := filepath.Dir(r.opts.CacheFile)
+	if err = os.MkdirAll(dir, 0o700); err != nil {
+		return
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(r.opts.CacheFile)+"*")
+	if err != nil {
+		return
+	}
+
+	_, err = tmp.Write(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), r.opts.CacheFile)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+}
+
+// resolvingBuilder resolves the k6 version before building
+type resolvingBuilder struct {
+	inner    Builder
+	resolver *VersionResolver
+}
+
+// NewResolvingBuilder returns a Builder that resolves the requested k6 version using the resolver
+// and builds the resolved version with the inner builder. Wrapping a cached builder allows caching
+// the builds of latest, as they use a specific version.
+func NewResolvingBuilder(inner Builder, resolver *VersionResolver) Builder {
+	return &resolvingBuilder{inner: inner, resolver: resolver}
+}
+
+func (b *resolvingBuilder) Build(
+	ctx context.Context,
+	platform Platform,
+	k6Version string,
+	mods []Module,
+	buildOpts []string,
+	out io.Writer,
+) (*BuildInfo, error) {
+	version, err := b.resolver.Resolve(ctx, k6Version)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.inner.Build(ctx, platform, version, mods, buildOpts, out)
+}
@@ -0,0 +1,207 @@
+package k6foundry
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestVersionResolver(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title         string
+		ttl           time.Duration
+		spec          string
+		elapsed       time.Duration
+		expectFirst   string
+		expectSecond  string
+		expectQueries int64
+	}{
+		{
+			title:         "cached latest",
+			spec:          "latest",
+			elapsed:       time.Minute,
+			expectFirst:   "v0.2.0",
+			expectSecond:  "v0.2.0",
+			expectQueries: 1,
+		},
+		{
+			title:         "expired latest",
+			spec:          "latest",
+			elapsed:       DefaultResolveCacheTTL,
+			expectFirst:   "v0.2.0",
+			expectSecond:  "v0.3.0",
+			expectQueries: 2,
+		},
+		{
+			title:         "cached constraint",
+			ttl:           time.Hour,
+			spec:          "~v0.2.0",
+			elapsed:       time.Minute,
+			expectFirst:   "v0.2.0",
+			expectSecond:  "v0.2.0",
+			expectQueries: 1,
+		},
+		{
+			title:         "cache disabled",
+			ttl:           -1,
+			spec:          "latest",
+			expectFirst:   "v0.2.0",
+			expectSecond:  "v0.3.0",
+			expectQueries: 2,
+		},
+		{
+			title:         "version",
+			spec:          "v0.1.0",
+			expectFirst:   "v0.1.0",
+			expectSecond:  "v0.1.0",
+			expectQueries: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			proxy, queries := newVersionsProxy(t, "v0.1.0", "v0.2.0")
+
+			now := time.Now()
+			resolver := NewVersionResolver(VersionResolverOpts{
+				VersionsOpts: VersionsOpts{GoProxy: proxy.URL},
+				TTL:          tc.ttl,
+			})
+			resolver.now = func() time.Time { return now }
+
+			version, err := resolver.Resolve(context.TODO(), tc.spec)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if version != tc.expectFirst {
+				t.Fatalf("expected %s got %s", tc.expectFirst, version)
+			}
+
+			// a new release is published
+			proxy.add(t, "v0.3.0")
+			now = now.Add(tc.elapsed)
+
+			version, err = resolver.Resolve(context.TODO(), tc.spec)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if version != tc.expectSecond {
+				t.Fatalf("expected %s got %s", tc.expectSecond, version)
+			}
+
+			if queries.Load() != tc.expectQueries {
+				t.Fatalf("expected %d queries got %d", tc.expectQueries, queries.Load())
+			}
+		})
+	}
+}
+
+func TestVersionResolverCacheFile(t *testing.T) {
+	t.Parallel()
+
+	proxy, queries := newVersionsProxy(t, "v0.1.0", "v0.2.0")
+	opts := VersionResolverOpts{
+		VersionsOpts: VersionsOpts{GoProxy: proxy.URL},
+		CacheFile:    filepath.Join(t.TempDir(), "cache", "versions.json"),
+	}
+
+	for range 2 {
+		// each resolver simulates a different process
+		version, err := NewVersionResolver(opts).Resolve(context.TODO(), "latest")
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if version != "v0.2.0" {
+			t.Fatalf("expected v0.2.0 got %s", version)
+		}
+	}
+
+	if queries.Load() != 1 {
+		t.Fatalf("expected 1 query got %d", queries.Load())
+	}
+}
+
+type versionsProxy struct {
+	*httptest.Server
+	proxy *goproxy.GoProxy
+}
+
+func (p *versionsProxy) add(t *testing.T, versions ...string) {
+	t.Helper()
+
+	for _, version := range versions {
+		if err := p.proxy.AddModVersion("go.k6.io/k6", version, filepath.Join("testdata", "mods", "k6")); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+}
+
+// newVersionsProxy returns a go proxy with the k6 versions, and the counter of the queries for the versions
+func newVersionsProxy(t *testing.T, versions ...string) (*versionsProxy, *atomic.Int64) {
+	t.Helper()
+
+	queries := &atomic.Int64{}
+	proxy := goproxy.NewGoProxy()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/@v/list") {
+			queries.Add(1)
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	p := &versionsProxy{Server: srv, proxy: proxy}
+	p.add(t, versions...)
+
+	return p, queries
+}
+
+// versionBuilder records the requested k6 version
+type versionBuilder struct {
+	k6Version string
+}
+
+func (b *versionBuilder) Build(
+	_ context.Context,
+	platform Platform,
+	k6Version string,
+	_ []Module,
+	_ []string,
+	_ io.Writer,
+) (*BuildInfo, error) {
+	b.k6Version = k6Version
+
+	return &BuildInfo{Platform: platform.String()}, nil
+}
+
+func TestResolvingBuilder(t *testing.T) {
+	t.Parallel()
+
+	proxy, _ := newVersionsProxy(t, "v0.1.0", "v0.2.0")
+
+	inner := &versionBuilder{}
+	resolver := NewVersionResolver(VersionResolverOpts{VersionsOpts: VersionsOpts{GoProxy: proxy.URL}})
+
+	_, err := NewResolvingBuilder(inner, resolver).Build(context.TODO(), RuntimePlatform(), "latest", nil, nil, io.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if inner.k6Version != "v0.2.0" {
+		t.Fatalf("expected v0.2.0 got %s", inner.k6Version)
+	}
+}
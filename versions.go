@@ -0,0 +1,205 @@
+package k6foundry
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"golang.org/x/mod/module"
+	gosemver "golang.org/x/mod/semver"
+)
+
+const defaultGoProxy = "https://proxy.golang.org"
+
+var (
+	// ErrListingVersions is returned when the versions of a module can't be retrieved
+	ErrListingVersions = errors.New("listing versions")
+
+	latestOffsetRegexp = regexp.MustCompile(`^latest-(\d+)$`)
+)
+
+// VersionsOpts defines the options for listing the versions of a module
+type VersionsOpts struct {
+	// GOPROXY of the builds (see GoOpts.GoProxy). The first proxy in the list is queried.
+	// Defaults to proxy.golang.org
+	GoProxy string
+	// Constraint for filtering the versions (e.g. >=v0.50.0). If empty, all release versions are returned
+	Constraint string
+	// Client used for querying the module proxy. Defaults to http.DefaultClient
+	HTTPClient *http.Client
+}
+
+// ListK6Versions returns the k6 versions that satisfy the constraint, sorted from oldest to newest
+func ListK6Versions(ctx context.Context, opts VersionsOpts) ([]string, error) {
+	return ListModuleVersions(ctx, defaultK6ModulePath, opts)
+}
+
+// ListModuleVersions queries the module proxy and returns the versions of the module that satisfy
+// the constraint, sorted from oldest to newest. Pre-release versions are only returned if the
+// constraint includes a pre-release.
+func ListModuleVersions(ctx context.Context, modulePath string, opts VersionsOpts) ([]string, error) {
+	constraint := opts.Constraint
+	if constraint == "" {
+		constraint = "*"
+	}
+
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConstraint, err)
+	}
+
+	escaped, err := module.EscapePath(modulePath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrListingVersions, err)
+	}
+
+	url := strings.TrimSuffix(goProxyURL(opts.GoProxy), "/") + "/" + escaped + "/@v/list"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrListingVersions, err)
+	}
+
+	client := opts.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrListingVersions, err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %s %s", ErrListingVersions, modulePath, resp.Status)
+	}
+
+	versions := []*semver.Version{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		version, err := semver.NewVersion(strings.TrimSpace(scanner.Text()))
+		if err != nil {
+			continue
+		}
+
+		if c.Check(version) {
+			versions = append(versions, version)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrListingVersions, err)
+	}
+
+	sort.Sort(semver.Collection(versions))
+
+	result := make([]string, 0, len(versions))
+	for _, v := range versions {
+		result = append(result, v.Original())
+	}
+
+	return result, nil
+}
+
+// ResolveK6Version resolves a k6 version specification to a version. The specification can be:
+//   - a version (e.g. v0.50.0), returned as is
+//   - latest, the newest release
+//   - latest-N, the Nth release before the newest (e.g. latest-1)
+//   - a constraint (e.g. ~v0.50.0), the newest release that satisfies it
+func ResolveK6Version(ctx context.Context, spec string, opts VersionsOpts) (string, error) {
+	// complete versions are returned as is. Partial versions (e.g. v0.50) are considered constraints
+	if gosemver.IsValid(spec) && gosemver.Canonical(spec) == spec {
+		return spec, nil
+	}
+
+	offset := 0
+	switch match := latestOffsetRegexp.FindStringSubmatch(spec); {
+	case spec == "latest" || spec == "":
+	case match != nil:
+		offset, _ = strconv.Atoi(match[1])
+	default:
+		opts.Constraint = spec
+	}
+
+	versions, err := ListK6Versions(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+
+	if offset >= len(versions) {
+		return "", fmt.Errorf("%w: %q", ErrNoMatchingVersion, spec)
+	}
+
+	return versions[len(versions)-1-offset], nil
+}
+
+// isConstraint returns true if the version is a constraint using operators (e.g. >=v0.9.0 <v0.11.0 or ~v0.9)
+// instead of a version, latest or a revision. Partial versions such as v0.9 are considered versions
+func isConstraint(version string) bool {
+	if !strings.ContainsAny(version, "<>=!~^*") || isRevision(version) {
+		return false
+	}
+
+	_, err := semver.NewConstraint(version)
+
+	return err == nil
+}
+
+// resolveConstraint returns the highest version of the module published in the module proxy that satisfies
+// the constraint
+func resolveConstraint(ctx context.Context, e *goEnv, mod string, constraint string) (string, error) {
+	versions, err := e.modVersions(ctx, mod)
+	if err != nil {
+		return "", err
+	}
+
+	version, err := matchVersion(versions, constraint)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w: %s@%s", ErrResolvingDependency, err, mod, constraint)
+	}
+
+	return version, nil
+}
+
+// GoProxy returns the GOPROXY used by the builds with the options: the one set in Env or, with CopyGoEnv,
+// the one in the current go environment, including the settings made with go env -w. Empty if not set
+func (o GoOpts) GoProxy() (string, error) {
+	if proxy, found := o.Env["GOPROXY"]; found {
+		return proxy, nil
+	}
+
+	if !o.CopyGoEnv {
+		return "", nil
+	}
+
+	goBin, err := goBinary(o)
+	if err != nil {
+		return "", err
+	}
+
+	env, err := getGoEnv(goBin)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrSettingGoEnv, err)
+	}
+
+	return env["GOPROXY"], nil
+}
+
+// goProxyURL returns the URL of the first proxy in the GOPROXY list, or proxy.golang.org if it has none
+func goProxyURL(goProxy string) string {
+	// GOPROXY is a list of proxies separated by comma or pipe. direct and off are not proxies
+	for _, p := range strings.FieldsFunc(goProxy, func(r rune) bool { return r == ',' || r == '|' }) {
+		if p != "direct" && p != "off" {
+			return p
+		}
+	}
+
+	return defaultGoProxy
+}
@@ -0,0 +1,139 @@
+package k6foundry
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestK6Versions(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	for _, version := range []string{"v0.1.0", "v0.2.0", "v0.2.1", "v0.3.0-rc1", "v1.0.0"} {
+		if err := proxy.AddModVersion("go.k6.io/k6", version, filepath.Join("testdata", "mods", "k6")); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	t.Run("list", func(t *testing.T) {
+		t.Parallel()
+
+		testCases := []struct {
+			constraint  string
+			expect      []string
+			expectError error
+		}{
+			{constraint: "", expect: []string{"v0.1.0", "v0.2.0", "v0.2.1", "v1.0.0"}},
+			{constraint: ">=v0.2.0, <v1.0.0", expect: []string{"v0.2.0", "v0.2.1"}},
+			{constraint: ">=v0.3.0-0", expect: []string{"v0.3.0-rc1", "v1.0.0"}},
+			{constraint: ">>v1", expectError: ErrInvalidConstraint},
+		}
+
+		for _, tc := range testCases {
+			versions, err := ListK6Versions(
+				context.Background(),
+				VersionsOpts{GoProxy: goproxySrv.URL, Constraint: tc.constraint},
+			)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("%q: expected %v got %v", tc.constraint, tc.expectError, err)
+			}
+
+			if tc.expectError == nil && !reflect.DeepEqual(versions, tc.expect) {
+				t.Fatalf("%q: expected %v got %v", tc.constraint, tc.expect, versions)
+			}
+		}
+	})
+
+	t.Run("resolve", func(t *testing.T) {
+		t.Parallel()
+
+		testCases := []struct {
+			spec        string
+			expect      string
+			expectError error
+		}{
+			{spec: "v0.1.0", expect: "v0.1.0"},
+			{spec: "latest", expect: "v1.0.0"},
+			{spec: "latest-1", expect: "v0.2.1"},
+			{spec: "~v0.2.0", expect: "v0.2.1"},
+			{spec: "v0.2", expect: "v0.2.1"},
+			{spec: "latest-4", expectError: ErrNoMatchingVersion},
+		}
+
+		for _, tc := range testCases {
+			version, err := ResolveK6Version(context.Background(), tc.spec, VersionsOpts{GoProxy: goproxySrv.URL})
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("%q: expected %v got %v", tc.spec, tc.expectError, err)
+			}
+
+			if version != tc.expect {
+				t.Fatalf("%q: expected %v got %v", tc.spec, tc.expect, version)
+			}
+		}
+	})
+}
+
+func TestGoProxy(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		opts      GoOpts
+		expect    string
+		expectURL string
+	}{
+		{
+			title:     "not set",
+			opts:      GoOpts{},
+			expect:    "",
+			expectURL: defaultGoProxy,
+		},
+		{
+			title:     "set in env",
+			opts:      GoOpts{Env: map[string]string{"GOPROXY": "https://proxy.example.com"}},
+			expect:    "https://proxy.example.com",
+			expectURL: "https://proxy.example.com",
+		},
+		{
+			title:     "list",
+			opts:      GoOpts{Env: map[string]string{"GOPROXY": "direct,https://proxy.example.com|https://proxy.golang.org"}},
+			expect:    "direct,https://proxy.example.com|https://proxy.golang.org",
+			expectURL: "https://proxy.example.com",
+		},
+		{
+			title:     "no proxy",
+			opts:      GoOpts{Env: map[string]string{"GOPROXY": "off"}, CopyGoEnv: true},
+			expect:    "off",
+			expectURL: defaultGoProxy,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			goProxy, err := tc.opts.GoProxy()
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if goProxy != tc.expect {
+				t.Fatalf("expected %q got %q", tc.expect, goProxy)
+			}
+
+			if url := goProxyURL(goProxy); url != tc.expectURL {
+				t.Fatalf("expected url %q got %q", tc.expectURL, url)
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultWatchInterval is the default interval for checking the local modules for changes
+const DefaultWatchInterval = time.Second
+
+// ErrNoLocalModules is returned when watching a build without local modules
+var ErrNoLocalModules = errors.New("no local modules to watch")
+
+// RebuildFunc receives the result of each build in Watch. The binary is only valid until the function
+// returns. If the build failed, err contains the error and binary is empty.
+// Returning an error stops watching.
+type RebuildFunc func(binary string, info *BuildInfo, err error) error
+
+// DevBuilder defines the interface of builders that rebuild the binary when local modules change
+type DevBuilder interface {
+	// Watch builds a custom k6 binary and rebuilds it each time the sources of the local replacements,
+	// workspace modules or k6 repository change, reusing the build environment between builds.
+	// Returns when the context is done or onBuild returns an error
+	Watch(
+		ctx context.Context,
+		platform Platform,
+		k6Version string,
+		mods []Module,
+		buildOpts []string,
+		onBuild RebuildFunc,
+	) error
+}
+
+// fileStamp identifies a version of a watched file
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watch rebuilds the binary each time the local modules change
+func (b *nativeBuilder) Watch(
+	ctx context.Context,
+	platform Platform,
+	k6Version string,
+	exts []Module,
+	buildOpts []string,
+	onBuild RebuildFunc,
+) error {
+	return b.watchWith(ctx, b.hostEnv, platform, k6Version, exts, buildOpts, onBuild)
+}
+
+// watchWith watches the local modules using the go environment created by newEnv
+func (b *nativeBuilder) watchWith(
+	ctx context.Context,
+	newEnv envFactory,
+	platform Platform,
+	k6Version string,
+	exts []Module,
+	buildOpts []string,
+	onBuild RebuildFunc,
+) error {
+	if err := checkConflicts(exts); err != nil {
+		return err
+	}
+
+	dirs, err := b.localModuleDirs(exts)
+	if err != nil {
+		return err
+	}
+
+	if len(dirs) == 0 {
+		return ErrNoLocalModules
+	}
+
+	interval := b.WatchInterval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	return b.withWorkDir(ctx, newEnv, platform, b.GoOpts, func(workDir string, buildEnv *goEnv) error {
+		b.log.Info("Watching local modules for changes")
+
+		err := buildEnv.checkPlatforms(ctx, platform)
+		if err != nil {
+			return err
+		}
+
+		buildInfo, err := b.prepareReusing(ctx, workDir, buildEnv, k6Version, exts)
+		if err != nil {
+			return err
+		}
+
+		// changes made while compiling are detected in the next check
+		snapshot := snapshotDirs(dirs)
+
+		for {
+			binary, info, buildErr := b.rebuild(ctx, workDir, buildEnv, buildInfo, buildOpts)
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			if err = onBuild(binary, info, buildErr); err != nil {
+				return err
+			}
+
+			var changed []string
+			changed, snapshot = waitChanges(ctx, dirs, snapshot, interval)
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			b.log.Info(fmt.Sprintf("Rebuilding after changes in %s", strings.Join(changed, ", ")))
+
+			if err = b.tidyChanged(ctx, buildEnv, changed); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// rebuild compiles the binary into a file in the work directory
+func (b *nativeBuilder) rebuild(
+	ctx context.Context,
+	workDir string,
+	buildEnv *goEnv,
+	buildInfo *BuildInfo,
+	buildOpts []string,
+) (string, *BuildInfo, error) {
+	b.emit(Event{Type: EventBuildStarted})
+	phaseCtx, endPhase := b.startPhase(ctx, PhaseBuild, buildEnv.platform.String())
+
+	binary, info, err := b.compileFile(phaseCtx, workDir, buildEnv, buildInfo, buildOpts)
+
+	endPhase(err)
+	b.emit(Event{Type: EventBuildFinished, Err: err})
+
+	return binary, info, err
+}
+
+// compileFile compiles the binary into a file and returns its path and the build info of the binary
+func (b *nativeBuilder) compileFile(
+	ctx context.Context,
+	workDir string,
+	buildEnv *goEnv,
+	buildInfo *BuildInfo,
+	buildOpts []string,
+) (string, *BuildInfo, error) {
+	binary := filepath.Join(workDir, buildEnv.platform.BinaryName("k6-dev"))
+
+	file, err := os.OpenFile(binary, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o755) //nolint:gosec
+	if err != nil {
+		return "", nil, err
+	}
+
+	info := *buildInfo
+	info.Checksum, info.Size, err = b.compile(ctx, workDir, buildEnv, buildOpts, binaryOutput{writer: file})
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	return binary, &info, nil
+}
+
+// tidyChanged updates the requirements of the main module if the go.mod of a local module changed
+func (b *nativeBuilder) tidyChanged(ctx context.Context, buildEnv *goEnv, changed []string) error {
+	tidy := false
+	for _, path := range changed {
+		name := filepath.Base(path)
+		if name == "go.mod" || name == "go.sum" {
+			tidy = true
+			break
+		}
+	}
+
+	if !tidy {
+		return nil
+	}
+
+	unlock, err := buildEnv.lockCache(ctx, true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	return buildEnv.modTidy(ctx)
+}
+
+// localModuleDirs returns the directories of the local replacements, workspace modules and k6 repository
+func (b *nativeBuilder) localModuleDirs(exts []Module) ([]string, error) {
+	replaces := []string{b.K6Repo}
+	for _, ext := range exts {
+		replaces = append(replaces, ext.ReplacePath)
+	}
+	for _, r := range b.Replacements {
+		replaces = append(replaces, r.ReplacePath)
+	}
+
+	dirs, err := workspaceDirs(b.Workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, replace := range replaces {
+		if replace == "" {
+			continue
+		}
+
+		path, err := resolvePath(replace)
+		if err != nil {
+			return nil, fmt.Errorf("resolving replace path: %w", err)
+		}
+
+		// module paths are not watched
+		if !filepath.IsAbs(path) {
+			continue
+		}
+
+		dirs = append(dirs, path)
+	}
+
+	return dirs, nil
+}
+
+// waitChanges checks the directories every interval until a file changes or the context is done.
+// Returns the changed files and the new snapshot of the directories
+func waitChanges(
+	ctx context.Context,
+	dirs []string,
+	snapshot map[string]fileStamp,
+	interval time.Duration,
+) ([]string, map[string]fileStamp) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, snapshot
+		case <-ticker.C:
+		}
+
+		current := snapshotDirs(dirs)
+		if changed := changedFiles(snapshot, current); len(changed) > 0 {
+			return changed, current
+		}
+	}
+}
+
+// snapshotDirs returns the stamps of the files in the directories, skipping hidden directories
+func snapshotDirs(dirs []string) map[string]fileStamp {
+	snapshot := map[string]fileStamp{}
+
+	for _, dir := range dirs {
+		// files that can't be read are ignored, as they may be changing
+		_ = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return nil //nolint:nilerr
+			}
+
+			if entry.IsDir() {
+				if path != dir && strings.HasPrefix(entry.Name(), ".") {
+					return filepath.SkipDir
+				}
+
+				return nil
+			}
+
+			info, err := entry.Info()
+			if err != nil {
+				return nil //nolint:nilerr
+			}
+
+			snapshot[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+
+			return nil
+		})
+	}
+
+	return snapshot
+}
+
+// changedFiles returns the files added, removed or modified between two snapshots
+func changedFiles(previous, current map[string]fileStamp) []string {
+	changed := []string{}
+
+	for path, stamp := range current {
+		if prev, found := previous[path]; !found || !prev.modTime.Equal(stamp.modTime) || prev.size != stamp.size {
+			changed = append(changed, path)
+		}
+	}
+
+	for path := range previous {
+		if _, found := current[path]; !found {
+			changed = append(changed, path)
+		}
+	}
+
+	sort.Strings(changed)
+
+	return changed
+}
@@ -0,0 +1,100 @@
+package k6foundry
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	if err := proxy.AddModVersion("go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	ext := t.TempDir()
+	writeSource := func(content string) {
+		if err := os.WriteFile(filepath.Join(ext, "k6ext.go"), []byte(content), 0o600); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(ext, "go.mod"), []byte("module go.k6.io/k6ext\n\ngo 1.17\n"), 0o600); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+	writeSource("package k6ext\n")
+
+	b, err := NewNativeBuilder(context.Background(), NativeBuilderOpts{
+		GoOpts: GoOpts{
+			CopyGoEnv: true,
+			Env: map[string]string{
+				"GOPROXY":   goproxySrv.URL,
+				"GONOPROXY": "none",
+				"GOPRIVATE": "go.k6.io",
+				"GONOSUMDB": "go.k6.io",
+			},
+			TmpCache: true,
+		},
+		WatchInterval: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("setting up test %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	// each build changes the source: breaks it, fixes it and stops watching
+	results := []error{}
+	onBuild := func(binary string, _ *BuildInfo, err error) error {
+		results = append(results, err)
+
+		if err == nil {
+			if _, statErr := os.Stat(binary); statErr != nil {
+				t.Errorf("binary not found %v", statErr)
+			}
+		}
+
+		switch len(results) {
+		case 1:
+			writeSource("package k6ext\n\nfunc broken() {\n")
+		case 2:
+			writeSource("package k6ext\n\nfunc Fixed() {}\n")
+		default:
+			cancel()
+		}
+
+		return nil
+	}
+
+	mods := []Module{{Path: "go.k6.io/k6ext", ReplacePath: ext}}
+	err = b.(DevBuilder).Watch(ctx, RuntimePlatform(), "v0.1.0", mods, []string{}, onBuild)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if len(results) != 3 {
+		t.Fatalf("expected 3 builds got %d", len(results))
+	}
+
+	if results[0] != nil || !errors.Is(results[1], ErrCompiling) || results[2] != nil {
+		t.Fatalf("unexpected build results %v", results)
+	}
+
+	// without local modules there is nothing to watch
+	err = b.(DevBuilder).Watch(context.Background(), RuntimePlatform(), "v0.1.0", []Module{}, []string{}, onBuild)
+	if !errors.Is(err, ErrNoLocalModules) {
+		t.Fatalf("expected %v got %v", ErrNoLocalModules, err)
+	}
+}
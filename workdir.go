@@ -0,0 +1,223 @@
+//nolint:forbidigo
+package k6foundry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// file used for locking a work directory shared between builds
+	workDirLockFileName = ".k6foundry-workdir.lock"
+	// file in a reusable work directory describing the prepared build environment
+	workDirStateFileName = ".k6foundry-workdir.json"
+)
+
+// workDirState describes the build environment prepared in a work directory
+type workDirState struct {
+	// identifies the inputs used for preparing the environment
+	Key       string     `json:"key"`
+	BuildInfo *BuildInfo `json:"buildInfo"`
+}
+
+// openWorkDir returns the work directory for a build and a function for releasing it, given the result
+// of the build. If the WorkDir option is set, the directory is locked, so it is not used by concurrent
+// builds, and it is not removed when released. Otherwise, a temporary directory is created in BuildDirRoot,
+// which is removed when released unless the cleanup strategy keeps it.
+func (b *nativeBuilder) openWorkDir(ctx context.Context) (string, func(error), error) {
+	if b.WorkDir == "" {
+		workDir, err := mkTempDir(b.buildDirRoot(), defaultWorkDir)
+		if err != nil {
+			return "", nil, fmt.Errorf("creating working directory: %w", err)
+		}
+
+		return workDir, func(buildErr error) {
+			if b.keepWorkDir(buildErr) {
+				b.log.Info(fmt.Sprintf("Skipping cleanup. leaving directory %s intact", workDir))
+				// remove the lock to prevent the directory from being reclaimed
+				_ = os.Remove(filepath.Join(workDir, lockFileName))
+				// the home directory can contain credentials
+				_ = removeAll(filepath.Join(workDir, homeDirName))
+				return
+			}
+
+			b.log.Info(fmt.Sprintf("Cleaning up work directory %s", workDir))
+			_ = removeAll(workDir)
+		}, nil
+	}
+
+	workDir, err := filepath.Abs(b.WorkDir)
+	if err != nil {
+		return "", nil, fmt.Errorf("creating working directory: %w", err)
+	}
+
+	if err = os.MkdirAll(workDir, 0o750); err != nil {
+		return "", nil, fmt.Errorf("creating working directory: %w", err)
+	}
+
+	b.log.Info(fmt.Sprintf("Waiting for work directory %s", workDir))
+	unlock, err := lockFile(ctx, filepath.Join(workDir, workDirLockFileName), true)
+	if err != nil {
+		return "", nil, fmt.Errorf("locking working directory: %w", err)
+	}
+
+	return workDir, func(error) {
+		// the home directory can contain credentials
+		_ = removeAll(filepath.Join(workDir, homeDirName))
+		unlock()
+	}, nil
+}
+
+// resetWorkDir removes the content of the work directory left by previous builds, except the
+// home directory of the current build
+func (b *nativeBuilder) resetWorkDir(workDir string) error {
+	// temporary directories are always new
+	if b.WorkDir == "" {
+		return nil
+	}
+
+	entries, err := os.ReadDir(workDir)
+	if err != nil {
+		return fmt.Errorf("cleaning working directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		switch entry.Name() {
+		case homeDirName, workDirLockFileName:
+			continue
+		}
+
+		if err = removeAll(filepath.Join(workDir, entry.Name())); err != nil {
+			return fmt.Errorf("cleaning working directory: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// prepareReusing prepares the build environment in the work directory, reusing the environment
+// prepared by a previous build with the same inputs if ReuseWorkDir is set
+func (b *nativeBuilder) prepareReusing(
+	ctx context.Context,
+	workDir string,
+	buildEnv *goEnv,
+	k6Version string,
+	exts []Module,
+) (*BuildInfo, error) {
+	if b.WorkDir == "" {
+		return b.prepare(ctx, workDir, buildEnv, k6Version, exts)
+	}
+
+	key, err := b.workDirKey(k6Version, exts)
+	if err != nil {
+		return nil, err
+	}
+
+	statePath := filepath.Join(workDir, workDirStateFileName)
+
+	if b.ReuseWorkDir {
+		state, err := loadWorkDirState(statePath)
+		if err != nil {
+			return nil, err
+		}
+
+		if state != nil && state.Key == key {
+			b.log.Info(fmt.Sprintf("Reusing work directory %s", workDir))
+			state.BuildInfo.Platform = buildEnv.platform.String()
+			return state.BuildInfo, nil
+		}
+	}
+
+	if err = b.resetWorkDir(workDir); err != nil {
+		return nil, err
+	}
+
+	buildInfo, err := b.prepare(ctx, workDir, buildEnv, k6Version, exts)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := json.Marshal(workDirState{Key: key, BuildInfo: buildInfo})
+	if err != nil {
+		return nil, fmt.Errorf("marshalling work directory state %w", err)
+	}
+
+	if err = os.WriteFile(statePath, content, 0o600); err != nil {
+		return nil, fmt.Errorf("writing work directory state %w", err)
+	}
+
+	return buildInfo, nil
+}
+
+// workDirKey returns a key that identifies the inputs used for preparing the build environment
+func (b *nativeBuilder) workDirKey(k6Version string, exts []Module) (string, error) {
+	mainContent, err := b.renderMain(exts)
+	if err != nil {
+		return "", err
+	}
+
+	inputs, err := json.Marshal(struct {
+		K6Version        string
+		K6Repo           string
+		K6RepoVersion    string
+		Extensions       []Module
+		Workspace        []string
+		Replacements     []Replace
+		Pins             []Module
+		Excludes         []Module
+		Main             string
+		LockFile         string
+		StrictK6Version  bool
+		ListDependencies bool
+		DetectLicenses   bool
+		DeniedLicenses   []string
+	}{
+		K6Version:        k6Version,
+		K6Repo:           b.K6Repo,
+		K6RepoVersion:    b.K6RepoVersion,
+		Extensions:       exts,
+		Workspace:        b.Workspace,
+		Replacements:     b.Replacements,
+		Pins:             b.Pins,
+		Excludes:         b.Excludes,
+		Main:             string(mainContent),
+		LockFile:         b.LockFile,
+		StrictK6Version:  b.StrictK6Version,
+		ListDependencies: b.ListDependencies,
+		DetectLicenses:   b.DetectLicenses,
+		DeniedLicenses:   b.DeniedLicenses,
+	})
+	if err != nil {
+		return "", fmt.Errorf("marshalling work directory key %w", err)
+	}
+
+	hash := sha256.Sum256(inputs)
+
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// loadWorkDirState returns the state of the work directory. Returns nil if it doesn't exist
+func loadWorkDirState(path string) (*workDirState, error) {
+	content, err := os.ReadFile(path) //nolint:gosec
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil //nolint:nilnil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading work directory state %w", err)
+	}
+
+	state := &workDirState{}
+	if err = json.Unmarshal(content, state); err != nil || state.BuildInfo == nil {
+		// the environment is prepared again
+		return nil, nil //nolint:nilnil,nilerr
+	}
+
+	return state, nil
+}
@@ -0,0 +1,207 @@
+package k6foundry
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestReuseWorkDir(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	for _, version := range []string{"v0.1.0", "v0.2.0"} {
+		if err := proxy.AddModVersion("go.k6.io/k6", version, filepath.Join("testdata", "mods", "k6")); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	testCases := []struct {
+		title         string
+		reuse         bool
+		k6Version     string
+		expectReused  bool
+		expectVersion string
+	}{
+		{
+			title:         "reuse with same inputs",
+			reuse:         true,
+			k6Version:     "v0.1.0",
+			expectReused:  true,
+			expectVersion: "v0.1.0",
+		},
+		{
+			title:         "reuse with different inputs",
+			reuse:         true,
+			k6Version:     "v0.2.0",
+			expectReused:  false,
+			expectVersion: "v0.2.0",
+		},
+		{
+			title:         "no reuse",
+			reuse:         false,
+			k6Version:     "v0.1.0",
+			expectReused:  false,
+			expectVersion: "v0.1.0",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			resolved := atomic.Int32{}
+			workDir := filepath.Join(t.TempDir(), "work")
+
+			b, err := NewNativeBuilder(context.Background(), NativeBuilderOpts{
+				GoOpts: GoOpts{
+					CopyGoEnv: true,
+					Env: map[string]string{
+						"GOPROXY":   goproxySrv.URL,
+						"GONOPROXY": "none",
+						"GOPRIVATE": "go.k6.io",
+						"GONOSUMDB": "go.k6.io",
+					},
+					TmpCache: true,
+				},
+				WorkDir:      workDir,
+				ReuseWorkDir: tc.reuse,
+				OnEvent: func(e Event) {
+					if e.Type == EventModuleResolving {
+						resolved.Add(1)
+					}
+				},
+			})
+			if err != nil {
+				t.Fatalf("setting up test %v", err)
+			}
+
+			_, err = b.Build(context.Background(), RuntimePlatform(), "v0.1.0", []Module{}, []string{}, &bytes.Buffer{})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if _, err = os.Stat(filepath.Join(workDir, "go.mod")); err != nil {
+				t.Fatalf("work directory not kept %v", err)
+			}
+
+			resolved.Store(0)
+
+			outFile := &bytes.Buffer{}
+			buildInfo, err := b.Build(context.Background(), RuntimePlatform(), tc.k6Version, []Module{}, []string{}, outFile)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if reused := resolved.Load() == 0; reused != tc.expectReused {
+				t.Fatalf("expected reused %t got %t", tc.expectReused, reused)
+			}
+
+			if outFile.Len() == 0 {
+				t.Fatal("out file is empty")
+			}
+
+			if version := buildInfo.ModVersions[defaultK6ModulePath]; version != tc.expectVersion {
+				t.Fatalf("expected k6 %s got %s", tc.expectVersion, version)
+			}
+
+			if buildInfo.Platform != RuntimePlatform().String() {
+				t.Fatalf("expected platform %s got %s", RuntimePlatform(), buildInfo.Platform)
+			}
+		})
+	}
+}
+
+func TestBuildDirRoot(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	if err := proxy.AddModVersion("go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")); err != nil {
+		t.Fatalf("setup %v", err)
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	testCases := []struct {
+		title       string
+		skipCleanup bool
+		cleanup     CleanupStrategy
+		k6Version   string
+		expectError bool
+		// directories expected in the root after the build: the work directory and the temporary cache
+		expectDirs int
+	}{
+		{title: "cleanup", skipCleanup: false, k6Version: "v0.1.0", expectDirs: 0},
+		{title: "skip cleanup", skipCleanup: true, k6Version: "v0.1.0", expectDirs: 2},
+		{title: "cleanup never", cleanup: CleanupNever, k6Version: "v0.1.0", expectDirs: 2},
+		{title: "cleanup on success", cleanup: CleanupOnSuccess, k6Version: "v0.1.0", expectDirs: 0},
+		{
+			title:       "failed build with cleanup on success",
+			cleanup:     CleanupOnSuccess,
+			k6Version:   "v0.2.0",
+			expectError: true,
+			expectDirs:  1,
+		},
+		{title: "failed build", k6Version: "v0.2.0", expectError: true, expectDirs: 0},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			// the root is created if it doesn't exist
+			root := filepath.Join(t.TempDir(), "builds")
+			// the module cache left by skipping the cleanup is read-only
+			t.Cleanup(func() { _ = removeAll(root) })
+
+			b, err := NewNativeBuilder(context.Background(), NativeBuilderOpts{
+				GoOpts: GoOpts{
+					CopyGoEnv: true,
+					Env: map[string]string{
+						"GOPROXY":   goproxySrv.URL,
+						"GONOPROXY": "none",
+						"GOPRIVATE": "go.k6.io",
+						"GONOSUMDB": "go.k6.io",
+					},
+					TmpCache:     true,
+					BuildDirRoot: root,
+				},
+				SkipCleanup: tc.skipCleanup,
+				Cleanup:     tc.cleanup,
+			})
+			if err != nil {
+				t.Fatalf("creating builder %v", err)
+			}
+
+			buildInfo, err := b.Build(context.Background(), RuntimePlatform(), tc.k6Version, nil, nil, &bytes.Buffer{})
+			if (err != nil) != tc.expectError {
+				t.Fatalf("expected error %t got %v", tc.expectError, err)
+			}
+
+			if !tc.expectError && buildInfo.DiskUsage <= 0 {
+				t.Fatalf("expected disk usage got %d", buildInfo.DiskUsage)
+			}
+
+			entries, err := os.ReadDir(root)
+			if err != nil {
+				t.Fatalf("reading build directory root %v", err)
+			}
+
+			if len(entries) != tc.expectDirs {
+				t.Fatalf("expected %d directories in the root got %v", tc.expectDirs, entries)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package k6foundry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+// ErrWorkspaceVendor is returned when vendoring a build that uses a workspace
+var ErrWorkspaceVendor = errors.New("workspace not supported when vendoring")
+
+// createWorkspace generates a go.work in the work directory that uses the local modules of the workspace.
+// The local modules take precedence over the versions required in the go.mod, including transitive dependencies.
+func (b *nativeBuilder) createWorkspace(ctx context.Context, e *goEnv) error {
+	if len(b.Workspace) == 0 {
+		return nil
+	}
+
+	b.log.Info("Creating go workspace")
+
+	dirs, err := workspaceDirs(b.Workspace)
+	if err != nil {
+		return err
+	}
+
+	return e.workInit(ctx, append([]string{"."}, dirs...)...)
+}
+
+// workspaceDirs returns the absolute paths of the workspace directories
+func workspaceDirs(workspace []string) ([]string, error) {
+	dirs := []string{}
+	for _, dir := range workspace {
+		path, err := resolvePath(dir)
+		if err != nil {
+			return nil, fmt.Errorf("resolving workspace path: %w", err)
+		}
+
+		path, err = filepath.Abs(path)
+		if err != nil {
+			return nil, fmt.Errorf("resolving workspace path: %w", err)
+		}
+
+		dirs = append(dirs, path)
+	}
+
+	return dirs, nil
+}
@@ -0,0 +1,97 @@
+package k6foundry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/grafana/k6foundry/pkg/goproxy"
+)
+
+func TestWorkspace(t *testing.T) {
+	t.Parallel()
+
+	proxy := goproxy.NewGoProxy()
+	for _, m := range []struct{ path, version, source string }{
+		{"go.k6.io/k6", "v0.1.0", filepath.Join("testdata", "mods", "k6")},
+		{"go.k6.io/k6ext", "v0.1.0", filepath.Join("testdata", "mods", "k6ext")},
+	} {
+		if err := proxy.AddModVersion(m.path, m.version, m.source); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	goproxySrv := httptest.NewServer(proxy)
+	t.Cleanup(goproxySrv.Close)
+
+	// a local version of k6ext that doesn't compile
+	broken := t.TempDir()
+	for name, content := range map[string]string{
+		"go.mod":   "module go.k6.io/k6ext\n\ngo 1.17\n",
+		"k6ext.go": "package k6ext\n\nfunc broken() {\n",
+	} {
+		if err := os.WriteFile(filepath.Join(broken, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("setup %v", err)
+		}
+	}
+
+	testCases := []struct {
+		title       string
+		workspace   []string
+		expectError error
+	}{
+		{
+			title:     "local module",
+			workspace: []string{filepath.Join("testdata", "mods", "k6ext")},
+		},
+		{
+			title:       "local module replaces required version",
+			workspace:   []string{broken},
+			expectError: ErrCompiling,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			opts := NativeBuilderOpts{
+				GoOpts: GoOpts{
+					CopyGoEnv: true,
+					Env: map[string]string{
+						"GOPROXY":   goproxySrv.URL,
+						"GONOPROXY": "none",
+						"GOPRIVATE": "go.k6.io",
+						"GONOSUMDB": "go.k6.io",
+						// -mod=mod is not allowed in workspace mode
+						"GOFLAGS": "",
+					},
+					TmpCache: true,
+				},
+				Workspace: tc.workspace,
+			}
+
+			b, err := NewNativeBuilder(context.Background(), opts)
+			if err != nil {
+				t.Fatalf("setting up test %v", err)
+			}
+
+			_, err = b.Build(
+				context.Background(),
+				RuntimePlatform(),
+				"v0.1.0",
+				[]Module{{Path: "go.k6.io/k6ext", Version: "v0.1.0"}},
+				[]string{},
+				&bytes.Buffer{},
+			)
+			if !errors.Is(err, tc.expectError) {
+				t.Fatalf("expected %v got %v", tc.expectError, err)
+			}
+		})
+	}
+}